@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/drewdunne/familiar/internal/config"
+)
+
+// adminClient calls a running server's admin API, over the admin socket or
+// over HTTP with the admin token.
+type adminClient struct {
+	baseURL string
+	token   string // empty on the socket
+	http    *http.Client
+}
+
+// newAdminClient connects to serverURL, or to the server described by cfg
+// if it is empty: its admin socket if one is configured, and otherwise its
+// host and port. A "unix:" URL names a socket path.
+func newAdminClient(cfg *config.Config, serverURL string) (*adminClient, error) {
+	socket, isSocket := strings.CutPrefix(serverURL, "unix:")
+	if serverURL == "" && cfg.Server.AdminSocket != "" {
+		socket, isSocket = cfg.Server.AdminSocket, true
+	}
+	if isSocket {
+		return &adminClient{
+			baseURL: "http://familiar",
+			http: &http.Client{Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+				},
+			}},
+		}, nil
+	}
+
+	if cfg.Server.AdminToken == "" {
+		return nil, fmt.Errorf("reaching the server requires server.admin_token or server.admin_socket in the config")
+	}
+	if serverURL == "" {
+		serverURL = localServerURL(cfg)
+	}
+	return &adminClient{
+		baseURL: strings.TrimSuffix(serverURL, "/"),
+		token:   cfg.Server.AdminToken,
+		http:    http.DefaultClient,
+	}, nil
+}
+
+// do sends a request to path and returns the response if it succeeded. The
+// caller closes the body.
+func (c *adminClient) do(ctx context.Context, method, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// localServerURL returns the URL of the server described by cfg, as reached
+// from the same host.
+func localServerURL(cfg *config.Config) string {
+	host := cfg.Server.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	scheme := "http"
+	if cfg.Server.TLS.CertFile != "" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, host, cfg.Server.Port)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/server"
+)
+
+func printAgentsUsage() {
+	fmt.Println("Usage: familiar agents <command> [options]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  list              List running agents")
+	fmt.Println("  stop <agent-id>   Stop a running agent")
+	fmt.Println("  logs <agent-id>   Print a running agent's output")
+}
+
+func runAgentsCmd(args []string) {
+	if len(args) < 1 {
+		printAgentsUsage()
+		os.Exit(1)
+	}
+
+	var err error
+	switch args[0] {
+	case "list":
+		err = runAgentsList(args[1:])
+	case "stop":
+		err = runAgentsStop(args[1:])
+	case "logs":
+		err = runAgentsLogs(args[1:])
+	default:
+		fmt.Printf("Unknown agents command: %s\n", args[0])
+		printAgentsUsage()
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "familiar agents %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+}
+
+// agentsFlags are the options shared by the agents commands.
+type agentsFlags struct {
+	flags      *flag.FlagSet
+	configPath *string
+	envFile    *string
+	serverURL  *string
+}
+
+func newAgentsFlags(fs *flag.FlagSet) agentsFlags {
+	return agentsFlags{
+		flags:      fs,
+		configPath: fs.String("config", defaultConfigPath, "Path to config file"),
+		envFile:    fs.String("env-file", "", "Path to .env file (optional)"),
+		serverURL:  fs.String("server", "", "Familiar server URL or unix:<socket> (default: from the config)"),
+	}
+}
+
+// client returns a client for the server's admin API.
+func (f agentsFlags) client() (*adminClient, error) {
+	loadEnv(*f.envFile)
+	cfg, err := config.Load(configFile(f.flags, *f.configPath))
+	if err != nil {
+		return nil, err
+	}
+	return newAdminClient(cfg, *f.serverURL)
+}
+
+func runAgentsList(args []string) error {
+	fs := flag.NewFlagSet("agents list", flag.ExitOnError)
+	common := newAgentsFlags(fs)
+	asJSON := fs.Bool("json", false, "Print the admin API's JSON")
+	fs.Parse(args)
+
+	client, err := common.client()
+	if err != nil {
+		return err
+	}
+	resp, err := client.do(context.Background(), http.MethodGet, "/admin/sessions")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var sessions []server.SessionInfo
+	if err := json.NewDecoder(resp.Body).Decode(&sessions); err != nil {
+		return fmt.Errorf("decoding sessions: %w", err)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sessions)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "AGENT\tREPO\tMR\tEVENT\tPERSONA\tSTATUS\tAGE")
+	for _, s := range sessions {
+		persona := s.Persona
+		if persona == "" {
+			persona = "-"
+		}
+		status := s.Status
+		if s.Interactive {
+			status += " (interactive)"
+		}
+		age := (time.Duration(s.AgeSeconds) * time.Second).String()
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			s.ID, s.Repo, s.MRNumber, s.EventType, persona, status, age)
+	}
+	return w.Flush()
+}
+
+func runAgentsStop(args []string) error {
+	fs := flag.NewFlagSet("agents stop", flag.ExitOnError)
+	common := newAgentsFlags(fs)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: familiar agents stop [options] <agent-id>")
+	}
+	agentID := fs.Arg(0)
+
+	client, err := common.client()
+	if err != nil {
+		return err
+	}
+	resp, err := client.do(context.Background(), http.MethodDelete, "/admin/sessions/"+url.PathEscape(agentID))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	// The server captures the logs and removes the worktree in the background
+	fmt.Printf("Stopping agent %s\n", agentID)
+	return nil
+}
+
+func runAgentsLogs(args []string) error {
+	fs := flag.NewFlagSet("agents logs", flag.ExitOnError)
+	common := newAgentsFlags(fs)
+	follow := fs.Bool("f", false, "Keep streaming until the agent finishes")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: familiar agents logs [options] <agent-id>")
+	}
+
+	client, err := common.client()
+	if err != nil {
+		return err
+	}
+	return streamAgentLogs(client, fs.Arg(0), *follow, os.Stdout)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/agent"
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/docker"
+	"github.com/drewdunne/familiar/internal/logging"
+	"github.com/drewdunne/familiar/internal/server"
+)
+
+func runCleanup(args []string) {
+	if err := cleanup(args); err != nil {
+		fmt.Fprintf(os.Stderr, "familiar cleanup: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// cleanup removes what a crashed or killed server leaves behind: agent
+// containers no server is tracking, their worktrees, and logs past
+// retention. Every step runs even if an earlier one fails.
+func cleanup(args []string) error {
+	fs := flag.NewFlagSet("cleanup", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "Path to config file")
+	envFile := fs.String("env-file", "", "Path to .env file (optional)")
+	serverURL := fs.String("server", "", "Familiar server URL or unix:<socket> whose agents are kept (default: from the config)")
+	worktreeAge := fs.Duration("worktree-age", 10*time.Minute, "Only prune worktrees untouched for this long")
+	force := fs.Bool("force", false, "Remove running agent containers even if no server can be asked whether they're in use")
+	fs.Parse(args)
+
+	loadEnv(*envFile)
+
+	cfg, err := config.Load(configFile(fs, *configPath))
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	ctx := context.Background()
+
+	// Agents a running server is tracking are in use, whatever state their
+	// containers are in
+	active, err := serverSessions(ctx, cfg, *serverURL)
+	asked := err == nil
+	if !asked {
+		fmt.Printf("Could not ask the server which agents are running (%v); running containers are kept.\n", err)
+		active = make(map[string]bool)
+	}
+
+	var errs []error
+	var reclaimed int64
+
+	removed, size, err := removeOrphanedContainers(ctx, cfg, active, asked || *force)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("removing containers: %w", err))
+	}
+	if removed > 0 || err == nil {
+		fmt.Printf("Removed %d orphaned agent container(s), %s\n", removed, formatBytes(size))
+		reclaimed += size
+	}
+
+	cacheBefore := dirUsage(cfg.RepoCache.Dir)
+	pruned, err := newRepoCache(cfg).PruneWorktrees(ctx, *worktreeAge, func(id string) bool { return active[id] })
+	if err != nil {
+		errs = append(errs, fmt.Errorf("pruning worktrees: %w", err))
+	}
+	size = max(cacheBefore-dirUsage(cfg.RepoCache.Dir), 0)
+	fmt.Printf("Pruned %d stale worktree(s), %s\n", pruned, formatBytes(size))
+	reclaimed += size
+
+	logsBefore := dirUsage(cfg.Logging.Dir)
+	cleaner := logging.NewCleaner(cfg.Logging.Dir, cfg.Logging.RetentionDays,
+		logging.WithCompressAfter(cfg.Logging.CompressAfterDays),
+		logging.WithMaxSize(int64(cfg.Logging.MaxSizeMB)<<20),
+		logging.WithCompactIndex(logging.NewIndex(cfg.Logging.Dir)))
+	deleted, err := cleaner.Cleanup()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("cleaning up logs: %w", err))
+	}
+	size = max(logsBefore-dirUsage(cfg.Logging.Dir), 0)
+	fmt.Printf("Deleted %d log file(s) past retention, %s\n", deleted, formatBytes(size))
+	reclaimed += size
+
+	fmt.Printf("\nReclaimed %s\n", formatBytes(reclaimed))
+	return errors.Join(errs...)
+}
+
+// serverSessions asks the running server for the IDs of the agents it is
+// tracking.
+func serverSessions(ctx context.Context, cfg *config.Config, serverURL string) (map[string]bool, error) {
+	client, err := newAdminClient(cfg, serverURL)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	resp, err := client.do(ctx, http.MethodGet, "/admin/sessions")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var sessions []server.SessionInfo
+	if err := json.NewDecoder(resp.Body).Decode(&sessions); err != nil {
+		return nil, fmt.Errorf("decoding sessions: %w", err)
+	}
+	active := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		active[s.ID] = true
+	}
+	return active, nil
+}
+
+// removeOrphanedContainers removes agent containers whose agent isn't in
+// active. Running containers are only removed if removeRunning is set;
+// those kept are added to active so their worktrees survive too. Returns
+// the number removed and the size of their writable layers.
+func removeOrphanedContainers(ctx context.Context, cfg *config.Config, active map[string]bool, removeRunning bool) (int, int64, error) {
+	client, err := docker.NewClient(docker.WithConnection(dockerConnection(cfg)))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer client.Close()
+
+	containers, err := client.ListContainers(ctx, agent.LabelAgent+"=true")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	removed := 0
+	var size int64
+	var errs []error
+	for _, c := range containers {
+		id := c.Labels[agent.LabelAgentID]
+		if active[id] {
+			continue
+		}
+		if c.State == "running" && !removeRunning {
+			fmt.Printf("Keeping running container %s; pass --force to remove it\n", c.Name)
+			active[id] = true
+			continue
+		}
+		if err := client.RemoveContainer(ctx, c.ID, true); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Name, err))
+			continue
+		}
+		removed++
+		size += c.SizeRw
+	}
+	return removed, size, errors.Join(errs...)
+}
+
+// dirUsage returns the total size of regular files under dir, or 0 if it
+// can't be read.
+func dirUsage(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// formatBytes renders n in binary units, e.g. "1.5 GiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
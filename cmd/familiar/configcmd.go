@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/drewdunne/familiar/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+func printConfigUsage() {
+	fmt.Println("Usage: familiar config <command> [options]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  print    Print the config in effect, with secrets masked")
+	fmt.Println("  schema   Print a JSON Schema for the config file")
+}
+
+func runConfig(args []string) {
+	if len(args) < 1 {
+		printConfigUsage()
+		os.Exit(1)
+	}
+
+	var err error
+	switch args[0] {
+	case "print":
+		err = runConfigPrint(args[1:])
+	case "schema":
+		err = runConfigSchema()
+	default:
+		fmt.Printf("Unknown config command: %s\n", args[0])
+		printConfigUsage()
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "familiar config %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+}
+
+// runConfigPrint prints the config as loaded from the file and the
+// environment, over the defaults. With --repo, the settings events for that
+// repo run with are printed too.
+func runConfigPrint(args []string) error {
+	fs := flag.NewFlagSet("config print", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "Path to config file")
+	envFile := fs.String("env-file", "", "Path to .env file (optional)")
+	repoDir := fs.String("repo", "", "Path to a repo checkout whose .familiar/config.yaml is merged in")
+	fs.Parse(args)
+
+	loadEnv(*envFile)
+
+	cfg, err := config.Load(configFile(fs, *configPath))
+	if err != nil {
+		return err
+	}
+	repoCfg := &config.RepoConfig{}
+	if *repoDir != "" {
+		repoCfg, err = config.LoadRepoConfig(context.Background(), dirReader(*repoDir), "", "", "")
+		if err != nil {
+			return err
+		}
+	}
+
+	effective, err := config.NewEffective(cfg, repoCfg)
+	if err != nil {
+		return err
+	}
+
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(effective); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// dirReader reads repo files from a local checkout.
+type dirReader string
+
+func (d dirReader) ReadFile(ctx context.Context, owner, repo, path, ref string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(string(d), path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, config.ErrConfigNotFound
+	}
+	return data, err
+}
+
+func runConfigSchema() error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(config.Schema())
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/docker"
+	"github.com/drewdunne/familiar/internal/registry"
+)
+
+// doctorTimeout bounds each check that calls out to Docker or a provider.
+const doctorTimeout = 15 * time.Second
+
+// findingStatus is how a doctor check came out.
+type findingStatus string
+
+const (
+	statusOK   findingStatus = "ok"
+	statusWarn findingStatus = "warn"
+	statusFail findingStatus = "FAIL"
+)
+
+// finding is the outcome of one doctor check.
+type finding struct {
+	check  string
+	status findingStatus
+	detail string
+	fix    string // what to do about a warning or failure
+}
+
+// whoamier is implemented by providers that can report who their token
+// authenticates as.
+type whoamier interface {
+	Whoami(ctx context.Context) (string, error)
+}
+
+// runDoctor checks the environment Familiar runs in and prints what is
+// wrong and how to fix it. It exits non-zero if any check fails.
+func runDoctor(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "Path to config file")
+	envFile := fs.String("env-file", "", "Path to .env file (optional)")
+	fs.Parse(args)
+
+	loadEnv(*envFile)
+
+	cfg, err := config.Load(configFile(fs, *configPath))
+	if err != nil {
+		printFindings([]finding{{
+			check:  "config",
+			status: statusFail,
+			detail: err.Error(),
+			fix:    "fix the config; familiar validate lists every problem",
+		}})
+		os.Exit(1)
+	}
+
+	findings := doctorFindings(cfg)
+	printFindings(findings)
+	for _, f := range findings {
+		if f.status == statusFail {
+			os.Exit(1)
+		}
+	}
+}
+
+// doctorFindings runs every check against cfg.
+func doctorFindings(cfg *config.Config) []finding {
+	findings := []finding{checkConfig(cfg)}
+	if err := translateHostPaths(cfg); err != nil {
+		findings = append(findings, finding{check: "host paths", status: statusFail, detail: err.Error(),
+			fix: "check agents.host_path_style and agents.host_mount_prefix"})
+		return findings
+	}
+
+	client, dockerFinding := checkDocker(cfg)
+	findings = append(findings, dockerFinding)
+	imageFinding, imageReady := checkAgentImage(cfg, client)
+	findings = append(findings, imageFinding)
+	if !imageReady {
+		client = nil // Mount checks need the image
+	}
+	if client != nil {
+		defer client.Close()
+	}
+	findings = append(findings,
+		checkClaudeAuth(cfg, client),
+		checkGit(),
+		checkRepoCache(cfg))
+
+	reg := registry.New(cfg)
+	findings = append(findings, checkProviders(reg)...)
+	findings = append(findings, checkWebhookSecrets(cfg)...)
+	return findings
+}
+
+func printFindings(findings []finding) {
+	problems := 0
+	for _, f := range findings {
+		fmt.Printf("[%-4s] %s: %s\n", f.status, f.check, f.detail)
+		if f.fix != "" && f.status != statusOK {
+			fmt.Printf("       -> %s\n", f.fix)
+		}
+		if f.status == statusFail {
+			problems++
+		}
+	}
+	fmt.Println()
+	if problems == 0 {
+		fmt.Println("No problems found.")
+	} else {
+		fmt.Printf("%d problem(s) found.\n", problems)
+	}
+}
+
+func checkConfig(cfg *config.Config) finding {
+	problems := validationProblems(cfg)
+	if len(problems) == 0 {
+		return finding{check: "config", status: statusOK, detail: "valid"}
+	}
+	return finding{check: "config", status: statusFail,
+		detail: fmt.Sprintf("%d problem(s), starting with: %s", len(problems), problems[0]),
+		fix:    "run familiar validate to list them all"}
+}
+
+// checkDocker returns a client for the configured daemon if it responds.
+func checkDocker(cfg *config.Config) (*docker.Client, finding) {
+	f := finding{check: "docker"}
+	host := cfg.Agents.Docker.Host
+	if host == "" {
+		host = "the default daemon (DOCKER_HOST or the local socket)"
+	}
+
+	client, err := docker.NewClient(docker.WithConnection(dockerConnection(cfg)))
+	if err != nil {
+		f.status, f.detail = statusFail, err.Error()
+		f.fix = "check agents.docker.host and agents.docker.cert_path"
+		return nil, f
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		client.Close()
+		f.status, f.detail = statusFail, fmt.Sprintf("cannot reach %s: %v", host, err)
+		f.fix = "start Docker, or make sure this user can use it (e.g. membership in the docker group)"
+		return nil, f
+	}
+	f.status, f.detail = statusOK, "connected to "+host
+	return client, f
+}
+
+// checkAgentImage reports whether the agent image is present and matches
+// its pinned digest, if any.
+func checkAgentImage(cfg *config.Config, client *docker.Client) (finding, bool) {
+	f := finding{check: "agent image"}
+	image := cfg.Agents.Image
+	if client == nil {
+		f.status, f.detail = statusWarn, "not checked without Docker"
+		return f, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+	ok, err := client.ImageExists(ctx, image)
+	if err != nil {
+		f.status, f.detail = statusFail, fmt.Sprintf("checking %s: %v", image, err)
+		return f, false
+	}
+	if !ok {
+		f.status, f.detail = statusWarn, image+" is not present; the server pulls it on startup"
+		f.fix = "docker pull " + image
+		if policy, _ := docker.ParsePullPolicy(cfg.Agents.PullPolicy); policy == docker.PullNever {
+			f.status, f.detail = statusFail, image+" is not present and agents.pull_policy is never"
+		}
+		return f, false
+	}
+
+	digest, _ := docker.ParseDigest(cfg.Agents.ImageDigest)
+	if digest == "" {
+		digest = docker.PinnedDigest(image)
+	}
+	if digest != "" {
+		id, err := client.InspectImage(ctx, image)
+		if err != nil || !id.Matches(digest) {
+			f.status, f.detail = statusFail, fmt.Sprintf("%s does not match the pinned digest %s", image, digest)
+			f.fix = "pull the pinned image, or update agents.image_digest"
+			return f, true
+		}
+	}
+	f.status, f.detail = statusOK, image+" is present"
+	return f, true
+}
+
+// checkClaudeAuth checks that the Claude auth directory holds credentials,
+// looking through the Docker daemon when the path isn't visible here (e.g.
+// when Familiar itself runs in a container).
+func checkClaudeAuth(cfg *config.Config, client *docker.Client) finding {
+	f := finding{check: "claude auth", fix: "log in with the claude CLI, then copy ~/.claude/.credentials.json into agents.claude_auth_dir"}
+	dir := cfg.Agents.ClaudeAuthDir
+	if dir == "" {
+		f.status, f.detail = statusWarn, "agents.claude_auth_dir is not set, so agents hit Claude's first-run prompts"
+		return f
+	}
+
+	if _, err := os.Stat(dir); err == nil {
+		creds := filepath.Join(dir, ".credentials.json")
+		if _, err := os.Stat(creds); err != nil {
+			f.status, f.detail = statusFail, fmt.Sprintf("%s has no .credentials.json", dir)
+			return f
+		}
+		f.status, f.detail = statusOK, creds+" found"
+		return f
+	}
+
+	if client == nil {
+		f.status, f.detail = statusFail, fmt.Sprintf("%s is not visible here and Docker can't check it", dir)
+		return f
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+	if err := client.CheckMount(ctx, cfg.Agents.Image, dir, ".credentials.json"); err != nil {
+		f.status, f.detail = statusFail, err.Error()
+		return f
+	}
+	f.status, f.detail = statusOK, dir+" holds .credentials.json, as seen by the Docker daemon"
+	return f
+}
+
+func checkGit() finding {
+	f := finding{check: "git"}
+	out, err := exec.Command("git", "--version").Output()
+	if err != nil {
+		f.status, f.detail = statusFail, fmt.Sprintf("running git: %v", err)
+		f.fix = "install git; the repo cache clones and fetches with it"
+		return f
+	}
+	f.status, f.detail = statusOK, strings.TrimSpace(string(out))
+	return f
+}
+
+func checkRepoCache(cfg *config.Config) finding {
+	f := finding{check: "repo cache"}
+	dir := cfg.RepoCache.Dir
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		f.status, f.detail = statusWarn, dir+" does not exist yet; it is created on the first clone"
+		return f
+	}
+	if err := newRepoCache(cfg).CheckWritable(); err != nil {
+		f.status, f.detail = statusFail, err.Error()
+		f.fix = "make repo_cache.dir writable by the user Familiar runs as"
+		return f
+	}
+	f.status, f.detail = statusOK, dir+" is writable"
+	return f
+}
+
+// checkProviders confirms each provider's token works and says who it
+// authenticates as, which is also who git clones and pushes as.
+func checkProviders(reg *registry.Registry) []finding {
+	names := reg.List()
+	if len(names) == 0 {
+		return []finding{{check: "providers", status: statusFail, detail: "no provider token is configured",
+			fix: "set providers.github.token or providers.gitlab.token"}}
+	}
+	slices.Sort(names)
+
+	var findings []finding
+	for _, name := range names {
+		f := finding{check: name + " token"}
+		p, ok := reg.Get(name).(whoamier)
+		if !ok {
+			f.status, f.detail = statusWarn, "not checked"
+			findings = append(findings, f)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+		user, err := p.Whoami(ctx)
+		cancel()
+		if err != nil {
+			f.status, f.detail = statusFail, err.Error()
+			f.fix = fmt.Sprintf("check providers.%s.token; it may be revoked, expired, or missing the api scope", name)
+		} else {
+			username, _ := reg.Get(name).GitCredentials()
+			f.status, f.detail = statusOK, fmt.Sprintf("authenticated as %s; git uses it over HTTPS as %s", user, username)
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// checkWebhookSecrets reports providers that can't receive webhooks, or
+// receive them without a token to act on them.
+func checkWebhookSecrets(cfg *config.Config) []finding {
+	providers := []struct {
+		name   string
+		token  string
+		secret string
+	}{
+		{"github", cfg.Providers.GitHub.Token, cfg.Providers.GitHub.WebhookSecret},
+		{"gitlab", cfg.Providers.GitLab.Token, cfg.Providers.GitLab.WebhookSecret},
+	}
+
+	var findings []finding
+	for _, p := range providers {
+		f := finding{check: p.name + " webhook secret"}
+		switch {
+		case p.token == "" && p.secret == "":
+			continue
+		case p.secret == "":
+			f.status, f.detail = statusFail, "not set, so /webhook/"+p.name+" is not served"
+			f.fix = fmt.Sprintf("set providers.%s.webhook_secret to the secret on the webhook", p.name)
+		case p.token == "":
+			f.status, f.detail = statusWarn, "set, but without a token agents can't clone or comment"
+			f.fix = fmt.Sprintf("set providers.%s.token", p.name)
+		default:
+			f.status, f.detail = statusOK, "set"
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/docker"
+	"github.com/drewdunne/familiar/internal/hostpath"
+)
+
+// mountCheckFile is written into the repo cache so a throwaway container can
+// confirm the host path resolves to the same directory.
+const mountCheckFile = ".familiar-mount-check"
+
+// translateHostPaths rewrites configured host paths into the form the Docker
+// daemon expects for bind mounts (e.g. Docker Desktop on Windows) and logs
+// platform caveats.
+func translateHostPaths(cfg *config.Config) error {
+	style, err := hostpath.ParseStyle(cfg.Agents.HostPathStyle)
+	if err != nil {
+		return err
+	}
+	tr := hostpath.Translator{Style: style, Prefix: cfg.Agents.HostMountPrefix}
+
+	for _, p := range []*string{&cfg.RepoCache.HostDir, &cfg.Agents.ClaudeAuthDir} {
+		translated, err := tr.Translate(*p)
+		if err != nil {
+			return err
+		}
+		if translated != *p {
+			slog.Info("translated host path", "from", *p, "to", translated)
+		}
+		*p = translated
+		for _, w := range tr.Warnings(translated) {
+			slog.Warn(w)
+		}
+	}
+	return nil
+}
+
+// checkHostMounts confirms that each configured host path, mounted into a
+// container of the agent image, resolves to the expected directory.
+// Returns one error per mount that does not.
+func checkHostMounts(ctx context.Context, client *docker.Client, cfg *config.Config) []error {
+	var errs []error
+
+	if cfg.RepoCache.HostDir != "" {
+		marker := filepath.Join(cfg.RepoCache.Dir, mountCheckFile)
+		if err := os.WriteFile(marker, nil, 0644); err != nil {
+			errs = append(errs, fmt.Errorf("repo_cache: writing check file: %w", err))
+		} else {
+			defer os.Remove(marker)
+			if err := client.CheckMount(ctx, cfg.Agents.Image, cfg.RepoCache.HostDir, mountCheckFile); err != nil {
+				errs = append(errs, fmt.Errorf("repo_cache.host_dir: %w", err))
+			}
+		}
+	}
+
+	if cfg.Agents.ClaudeAuthDir != "" {
+		if err := client.CheckMount(ctx, cfg.Agents.Image, cfg.Agents.ClaudeAuthDir, ".credentials.json"); err != nil {
+			errs = append(errs, fmt.Errorf("agents.claude_auth_dir: %w", err))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+func runInit(args []string) {
+	if err := initConfig(args, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "familiar init: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// initOptions are the choices a starter config is generated from.
+type initOptions struct {
+	providers     []string
+	gitlabURL     string
+	publicURL     string
+	botUsername   string
+	cacheDir      string
+	logDir        string
+	claudeAuthDir string
+}
+
+// initConfig writes a starter config.yaml and .env, asking for anything not
+// given by flags when run at a terminal, and prints how to point each
+// provider's webhook at Familiar.
+func initConfig(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	dir := fs.String("dir", ".", "Directory to write config.yaml and .env to")
+	providers := fs.String("providers", "", "Comma-separated providers to set up (github, gitlab)")
+	gitlabURL := fs.String("gitlab-url", "", "GitLab instance URL, for self-hosted GitLab (default: https://gitlab.com)")
+	publicURL := fs.String("url", "", "URL the providers reach Familiar at, for webhooks")
+	botUsername := fs.String("bot-username", "", "Account Familiar posts as, so it ignores its own comments")
+	cacheDir := fs.String("cache-dir", "", "Directory for cached repos (default: <dir>/cache)")
+	logDir := fs.String("log-dir", "", "Directory for agent logs (default: <dir>/logs)")
+	claudeAuthDir := fs.String("claude-auth-dir", "", "Claude CLI auth directory (default: ~/.claude)")
+	yes := fs.Bool("yes", false, "Use defaults for anything not given by flags instead of asking")
+	force := fs.Bool("force", false, "Overwrite existing files")
+	fs.Parse(args)
+
+	absDir, err := filepath.Abs(*dir)
+	if err != nil {
+		return err
+	}
+	configPath := filepath.Join(absDir, "config.yaml")
+	envPath := filepath.Join(absDir, ".env")
+	if !*force {
+		for _, path := range []string{configPath, envPath} {
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("%s already exists; pass --force to overwrite it", path)
+			}
+		}
+	}
+
+	home, _ := os.UserHomeDir()
+	interactive := !*yes && isTerminal(stdin)
+	in := bufio.NewReader(stdin)
+	ask := func(value *string, question, def string) {
+		if *value != "" {
+			return
+		}
+		*value = def
+		if interactive {
+			fmt.Fprintf(stdout, "%s [%s]: ", question, def)
+			line, _ := in.ReadString('\n')
+			if line = strings.TrimSpace(line); line != "" {
+				*value = line
+			}
+		}
+	}
+
+	ask(providers, "Providers (github, gitlab)", "github")
+	opts := initOptions{}
+	for _, p := range strings.Split(*providers, ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p != "github" && p != "gitlab" {
+			return fmt.Errorf("unknown provider %q (want github, gitlab)", p)
+		}
+		if !slices.Contains(opts.providers, p) {
+			opts.providers = append(opts.providers, p)
+		}
+	}
+	if slices.Contains(opts.providers, "gitlab") {
+		ask(gitlabURL, "GitLab URL", "https://gitlab.com")
+	}
+	ask(publicURL, "URL the providers reach Familiar at", "https://familiar.example.com")
+	ask(botUsername, "Account Familiar posts as", "Familiar")
+	ask(cacheDir, "Repo cache directory", filepath.Join(absDir, "cache"))
+	ask(logDir, "Agent log directory", filepath.Join(absDir, "logs"))
+	ask(claudeAuthDir, "Claude auth directory", filepath.Join(home, ".claude"))
+	if interactive {
+		fmt.Fprintln(stdout)
+	}
+
+	opts.gitlabURL = strings.TrimSuffix(*gitlabURL, "/")
+	opts.publicURL = strings.TrimSuffix(*publicURL, "/")
+	opts.botUsername = *botUsername
+	opts.cacheDir = *cacheDir
+	opts.logDir = *logDir
+	opts.claudeAuthDir = *claudeAuthDir
+
+	secrets := make(map[string]string)
+	for _, p := range opts.providers {
+		if secrets[p], err = webhookSecret(); err != nil {
+			return fmt.Errorf("generating webhook secret: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(absDir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(configPath, []byte(starterConfig(opts)), 0644); err != nil {
+		return err
+	}
+	// The .env holds the webhook secrets and, once filled in, the tokens
+	if err := os.WriteFile(envPath, []byte(starterEnv(opts, secrets)), 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps an existing file's mode, as under --force
+	if err := os.Chmod(envPath, 0600); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Wrote %s\nWrote %s\n", configPath, envPath)
+
+	printWebhookSetup(stdout, opts, secrets)
+
+	fmt.Fprintln(stdout, "\nNext steps:")
+	fmt.Fprintf(stdout, "  1. Fill in the provider tokens in %s\n", envPath)
+	fmt.Fprintln(stdout, "  2. Build the agent image: docker build -t familiar-agent:latest -f docker/agent/Dockerfile .")
+	fmt.Fprintln(stdout, "  3. Check everything is in place: familiar doctor")
+	fmt.Fprintln(stdout, "  4. Start the server: familiar serve")
+	return nil
+}
+
+// isTerminal reports whether r is an interactive terminal.
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// webhookSecret returns a random 256-bit secret, hex-encoded.
+func webhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// starterConfig renders a config.yaml for opts. Secrets are left to the
+// .env file.
+func starterConfig(opts initOptions) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `# Familiar configuration, generated by familiar init.
+# See config.example.yaml for every setting.
+
+# The account Familiar posts as; its own comments never trigger agents
+bot_username: %q
+
+server:
+  host: "0.0.0.0"
+  port: 7000
+
+logging:
+  dir: %q
+  retention_days: 30
+
+repo_cache:
+  dir: %q
+
+agents:
+  image: "familiar-agent:latest"
+  # Holds .credentials.json from logging in with the claude CLI
+  claude_auth_dir: %q
+
+providers:
+`, opts.botUsername, opts.logDir, opts.cacheDir, opts.claudeAuthDir)
+
+	for _, p := range opts.providers {
+		upper := strings.ToUpper(p)
+		fmt.Fprintf(&b, "  %s:\n", p)
+		fmt.Fprintf(&b, "    token: \"${%s_TOKEN}\"\n", upper)
+		fmt.Fprintf(&b, "    webhook_secret: \"${%s_WEBHOOK_SECRET}\"\n", upper)
+		if p == "gitlab" && opts.gitlabURL != "https://gitlab.com" {
+			fmt.Fprintf(&b, "    base_url: %q\n", opts.gitlabURL)
+		}
+	}
+
+	b.WriteString(`
+events:
+  mr_opened: true
+  mr_comment: true
+  mr_updated: true
+  mention: true
+
+prompts:
+  mr_opened: |
+    Review this merge request for bugs, security issues, and code quality.
+    Provide actionable feedback as inline comments.
+  mr_comment: |
+    A user has commented on this merge request.
+    Address their question or request directly.
+  mr_updated: |
+    New commits have been pushed to this merge request.
+    Review the changes since your last review.
+  mention: |
+    You were mentioned in a comment.
+    Follow the user's instructions precisely.
+
+permissions:
+  merge: "never"
+  approve: "never"
+  push_commits: "on_request"
+  dismiss_reviews: "never"
+`)
+	return b.String()
+}
+
+// starterEnv renders a .env file with the generated webhook secrets and
+// empty tokens to fill in.
+func starterEnv(opts initOptions, secrets map[string]string) string {
+	var b strings.Builder
+	b.WriteString("# Secrets for Familiar, generated by familiar init. Never commit this file.\n")
+	for _, p := range opts.providers {
+		upper := strings.ToUpper(p)
+		b.WriteString("\n")
+		switch p {
+		case "github":
+			b.WriteString("# Personal access token with the repo scope:\n# https://github.com/settings/tokens\n")
+		case "gitlab":
+			fmt.Fprintf(&b, "# Personal access token with the api scope:\n# %s/-/user_settings/personal_access_tokens\n", opts.gitlabURL)
+		}
+		fmt.Fprintf(&b, "%s_TOKEN=\n", upper)
+		b.WriteString("# Enter the same secret in the webhook's settings\n")
+		fmt.Fprintf(&b, "%s_WEBHOOK_SECRET=%s\n", upper, secrets[p])
+	}
+	return b.String()
+}
+
+// printWebhookSetup tells the user how to add each provider's webhook.
+func printWebhookSetup(w io.Writer, opts initOptions, secrets map[string]string) {
+	for _, p := range opts.providers {
+		fmt.Fprintln(w)
+		switch p {
+		case "github":
+			fmt.Fprintln(w, "GitHub webhook (repo or organization Settings > Webhooks > Add webhook):")
+			fmt.Fprintf(w, "  Payload URL:   %s/webhook/github\n", opts.publicURL)
+			fmt.Fprintln(w, "  Content type:  application/json")
+			fmt.Fprintf(w, "  Secret:        %s\n", secrets[p])
+			fmt.Fprintln(w, "  Events:        Let me select individual events: Pull requests, Issue comments")
+		case "gitlab":
+			fmt.Fprintf(w, "GitLab webhook (%s, project or group Settings > Webhooks > Add new webhook):\n", opts.gitlabURL)
+			fmt.Fprintf(w, "  URL:           %s/webhook/gitlab\n", opts.publicURL)
+			fmt.Fprintf(w, "  Secret token:  %s\n", secrets[p])
+			fmt.Fprintln(w, "  Trigger:       Merge request events, Comments")
+		}
+	}
+	if len(opts.providers) > 0 {
+		fmt.Fprintln(w, "\nThe secrets are also in .env. Once the server runs, familiar test-webhook")
+		fmt.Fprintln(w, "checks a delivery gets through.")
+	}
+}
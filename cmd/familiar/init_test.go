@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drewdunne/familiar/internal/config"
+)
+
+func TestInitConfig(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	args := []string{"--yes", "--dir", dir, "--providers", "github,gitlab", "--gitlab-url", "https://gitlab.example.com/"}
+	if err := initConfig(args, strings.NewReader(""), &out); err != nil {
+		t.Fatalf("initConfig() error: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, ".env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf(".env mode = %o, want 600", mode)
+	}
+
+	env, err := os.ReadFile(filepath.Join(dir, ".env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(env), "\n") {
+		name, value, ok := strings.Cut(line, "=")
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if value == "" {
+			value = "token-" + name
+		}
+		t.Setenv(name, value)
+	}
+	cfg, err := config.Load(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("generated config does not load: %v", err)
+	}
+	if cfg.Providers.GitLab.BaseURL != "https://gitlab.example.com" {
+		t.Errorf("gitlab base_url = %q, want https://gitlab.example.com", cfg.Providers.GitLab.BaseURL)
+	}
+	if secret := cfg.Providers.GitHub.WebhookSecret; len(secret) != 64 || !strings.Contains(out.String(), secret) {
+		t.Errorf("github webhook secret %q should be generated and printed", secret)
+	}
+	if cfg.Logging.Dir != filepath.Join(dir, "logs") {
+		t.Errorf("logging.dir = %q, want %q", cfg.Logging.Dir, filepath.Join(dir, "logs"))
+	}
+}
+
+func TestInitConfig_ExistingFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		force   bool
+		wantErr bool
+	}{
+		{name: "existing config", file: "config.yaml", wantErr: true},
+		{name: "existing env", file: ".env", wantErr: true},
+		{name: "force overwrites config", file: "config.yaml", force: true},
+		{name: "force overwrites env", file: ".env", force: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, []byte("keep"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			args := []string{"--yes", "--dir", dir}
+			if tt.force {
+				args = append(args, "--force")
+			}
+			err := initConfig(args, strings.NewReader(""), &bytes.Buffer{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("initConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kept := string(data) == "keep"; kept != tt.wantErr {
+				t.Errorf("%s kept = %v, want %v", tt.file, kept, tt.wantErr)
+			}
+			if info, err := os.Stat(filepath.Join(dir, ".env")); tt.force && (err != nil || info.Mode().Perm() != 0600) {
+				t.Errorf(".env = %v, %v; want mode 600", info, err)
+			}
+		})
+	}
+}
+
+func TestInitConfig_UnknownProvider(t *testing.T) {
+	err := initConfig([]string{"--yes", "--dir", t.TempDir(), "--providers", "bitbucket"}, strings.NewReader(""), &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "bitbucket") {
+		t.Errorf("initConfig() error = %v, want unknown provider", err)
+	}
+}
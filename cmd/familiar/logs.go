@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/logging"
+)
+
+func printLogsUsage() {
+	fmt.Println("Usage: familiar logs <command> [options]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  list              List agent logs, newest first")
+	fmt.Println("  tail <agent-id>   Print the end of an agent's log")
+}
+
+func runLogs(args []string) {
+	if len(args) < 1 {
+		printLogsUsage()
+		os.Exit(1)
+	}
+
+	var err error
+	switch args[0] {
+	case "list":
+		err = runLogsList(args[1:])
+	case "tail":
+		err = runLogsTail(args[1:])
+	default:
+		fmt.Printf("Unknown logs command: %s\n", args[0])
+		printLogsUsage()
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "familiar logs %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+}
+
+// logsFlags are the options shared by the logs commands.
+type logsFlags struct {
+	flags      *flag.FlagSet
+	configPath *string
+	envFile    *string
+	logDir     *string
+}
+
+func newLogsFlags(fs *flag.FlagSet) logsFlags {
+	return logsFlags{
+		flags:      fs,
+		configPath: fs.String("config", defaultConfigPath, "Path to config file"),
+		envFile:    fs.String("env-file", "", "Path to .env file (optional)"),
+		logDir:     fs.String("log-dir", "", "Agent log directory (default: logging.dir from the config)"),
+	}
+}
+
+// config loads the config file.
+func (f logsFlags) config() (*config.Config, error) {
+	loadEnv(*f.envFile)
+	return config.Load(configFile(f.flags, *f.configPath))
+}
+
+// index returns the index of the agent log directory, which is read from
+// the config unless --log-dir is given.
+func (f logsFlags) index() (*logging.Index, error) {
+	if *f.logDir != "" {
+		return logging.NewIndex(*f.logDir), nil
+	}
+	cfg, err := f.config()
+	if err != nil {
+		return nil, err
+	}
+	return logging.NewIndex(cfg.Logging.Dir), nil
+}
+
+func runLogsList(args []string) error {
+	fs := flag.NewFlagSet("logs list", flag.ExitOnError)
+	common := newLogsFlags(fs)
+	repo := fs.String("repo", "", "Only logs for this repo (owner/name)")
+	mr := fs.Int("mr", 0, "Only logs for this MR number")
+	eventType := fs.String("event", "", "Only logs for this event type")
+	outcome := fs.String("outcome", "", "Only runs with this outcome: succeeded, failed, or timed_out")
+	limit := fs.Int("limit", 20, "Maximum number of logs to list (0 for all)")
+	fs.Parse(args)
+
+	idx, err := common.index()
+	if err != nil {
+		return err
+	}
+	entries, err := idx.Find(logging.IndexQuery{
+		Repo:      *repo,
+		MR:        *mr,
+		EventType: *eventType,
+		Outcome:   *outcome,
+		Limit:     *limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tREPO\tMR\tEVENT\tAGENT\tOUTCOME\tPATH")
+	for _, e := range entries {
+		outcome := e.Outcome
+		if outcome == "" {
+			outcome = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			e.StartedAt.Local().Format("2006-01-02 15:04:05"), e.Repo, e.MR, e.EventType, e.AgentID, outcome, e.Path)
+	}
+	return w.Flush()
+}
+
+func runLogsTail(args []string) error {
+	fs := flag.NewFlagSet("logs tail", flag.ExitOnError)
+	common := newLogsFlags(fs)
+	lines := fs.Int("n", 50, "Number of lines to print (0 for the whole log)")
+	follow := fs.Bool("f", false, "Stream a running agent's output from the server's admin API")
+	serverURL := fs.String("server", "", "Familiar server URL or unix:<socket> for -f (default: from the config)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: familiar logs tail [options] <agent-id>")
+	}
+	agentID := fs.Arg(0)
+
+	if *follow {
+		cfg, err := common.config()
+		if err != nil {
+			return err
+		}
+		client, err := newAdminClient(cfg, *serverURL)
+		if err != nil {
+			return err
+		}
+		return streamAgentLogs(client, agentID, true, os.Stdout)
+	}
+
+	idx, err := common.index()
+	if err != nil {
+		return err
+	}
+	entries, err := idx.Find(logging.IndexQuery{AgentID: agentID})
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no log found for agent %s", agentID)
+	}
+
+	rc, err := logging.OpenLog(entries[0].Path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return tailLines(rc, *lines, os.Stdout)
+}
+
+// tailLines copies the last n lines of r to w, or all of r if n is 0.
+func tailLines(r io.Reader, n int, w io.Writer) error {
+	if n <= 0 {
+		_, err := io.Copy(w, r)
+		return err
+	}
+
+	ring := make([]string, n)
+	var count int
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		ring[count%n] = scanner.Text()
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	start := max(count-n, 0)
+	for i := start; i < count; i++ {
+		if _, err := fmt.Fprintln(w, ring[i%n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamAgentLogs writes a running agent's output from the admin API to w.
+// With follow it streams until the agent finishes or the user interrupts.
+func streamAgentLogs(client *adminClient, agentID string, follow bool, w io.Writer) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	path := "/agents/" + url.PathEscape(agentID) + "/logs"
+	if follow {
+		path += "?follow=true"
+	}
+	resp, err := client.do(ctx, http.MethodGet, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(w, resp.Body); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
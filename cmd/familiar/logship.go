@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/logship"
+)
+
+// newLogShipper returns a shipper for the configured sinks, or nil if none
+// are configured.
+func newLogShipper(cfg config.ShipConfig) (*logship.Shipper, error) {
+	if len(cfg.Sinks) == 0 {
+		return nil, nil
+	}
+	sinks := make([]logship.Sink, 0, len(cfg.Sinks))
+	for i, sc := range cfg.Sinks {
+		sink, err := newLogSink(sc)
+		if err != nil {
+			return nil, fmt.Errorf("logging.ship.sinks[%d]: %w", i, err)
+		}
+		sinks = append(sinks, sink)
+	}
+	return logship.New(sinks,
+		logship.WithBatchSize(cfg.BatchSize),
+		logship.WithFlushInterval(time.Duration(cfg.FlushIntervalSeconds)*time.Second),
+		logship.WithMaxRetries(cfg.MaxRetries),
+	), nil
+}
+
+// newLogSink creates the sink a config entry describes.
+func newLogSink(sc config.SinkConfig) (logship.Sink, error) {
+	switch sc.Type {
+	case "syslog":
+		tag := sc.Tag
+		if tag == "" {
+			tag = "familiar"
+		}
+		return logship.NewSyslogSink(sc.Address, tag)
+	case "loki":
+		return logship.NewLokiSink(logship.LokiConfig{
+			URL:      sc.URL,
+			TenantID: sc.TenantID,
+			Username: sc.Username,
+			Password: sc.Password,
+			Labels:   sc.Labels,
+		})
+	case "cloudwatch":
+		return logship.NewCloudWatchSink(logship.CloudWatchConfig{
+			Region:    sc.Region,
+			LogGroup:  sc.LogGroup,
+			LogStream: sc.LogStream,
+		})
+	default:
+		return nil, fmt.Errorf("unknown sink type %q: use syslog, loki, or cloudwatch", sc.Type)
+	}
+}
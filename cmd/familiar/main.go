@@ -1,24 +1,39 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/drewdunne/familiar/internal/agent"
 	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/docker"
 	"github.com/drewdunne/familiar/internal/event"
 	"github.com/drewdunne/familiar/internal/handler"
+	"github.com/drewdunne/familiar/internal/hooks"
+	"github.com/drewdunne/familiar/internal/intent"
+	"github.com/drewdunne/familiar/internal/intent/api"
+	"github.com/drewdunne/familiar/internal/logging"
+	"github.com/drewdunne/familiar/internal/logship"
+	"github.com/drewdunne/familiar/internal/metrics"
+	metricspush "github.com/drewdunne/familiar/internal/metrics/push"
 	"github.com/drewdunne/familiar/internal/registry"
 	"github.com/drewdunne/familiar/internal/repocache"
+	"github.com/drewdunne/familiar/internal/secrets" // Also registers secret store references
 	"github.com/drewdunne/familiar/internal/server"
+	"github.com/drewdunne/familiar/internal/tracing"
 	"github.com/joho/godotenv"
 )
 
-var version = "0.1.0"
-
 func main() {
 	if len(os.Args) < 2 {
 		printUsage()
@@ -26,10 +41,34 @@ func main() {
 	}
 
 	switch os.Args[1] {
+	case "init":
+		runInit(os.Args[2:])
 	case "serve":
 		runServe(os.Args[2:])
+	case "run":
+		runRun(os.Args[2:])
+	case "agents":
+		runAgentsCmd(os.Args[2:])
+	case "queue":
+		runQueue(os.Args[2:])
+	case "replay":
+		runReplay(os.Args[2:])
+	case "prompt":
+		runPrompt(os.Args[2:])
+	case "test-webhook":
+		runTestWebhook(os.Args[2:])
+	case "logs":
+		runLogs(os.Args[2:])
+	case "validate":
+		runValidate(os.Args[2:])
+	case "cleanup":
+		runCleanup(os.Args[2:])
+	case "doctor":
+		runDoctor(os.Args[2:])
+	case "config":
+		runConfig(os.Args[2:])
 	case "version":
-		fmt.Printf("familiar v%s\n", version)
+		runVersion(os.Args[2:])
 	default:
 		fmt.Printf("Unknown command: %s\n", os.Args[1])
 		printUsage()
@@ -41,72 +80,735 @@ func printUsage() {
 	fmt.Println("Usage: familiar <command> [options]")
 	fmt.Println()
 	fmt.Println("Commands:")
-	fmt.Println("  serve    Start the webhook server")
-	fmt.Println("  version  Print version information")
+	fmt.Println("  init          Generate a starter config.yaml and .env")
+	fmt.Println("  serve         Start the webhook server")
+	fmt.Println("  run           Run an agent for an MR without a webhook")
+	fmt.Println("  agents        List, stop, and read running agents")
+	fmt.Println("  queue         Show queued spawns and debounced events")
+	fmt.Println("  replay        Route a saved webhook payload locally")
+	fmt.Println("  prompt        Preview the prompt an event's agents would get")
+	fmt.Println("  test-webhook  Send a signed sample webhook to a running server")
+	fmt.Println("  logs          List and read agent logs")
+	fmt.Println("  validate      Check a config file without starting the server")
+	fmt.Println("  doctor        Check Docker, credentials, and the environment")
+	fmt.Println("  cleanup       Remove orphaned agent containers, worktrees, and old logs")
+	fmt.Println("  config        Print the config in effect or its JSON Schema")
+	fmt.Println("  version       Print version information")
+}
+
+// defaultConfigPath is the config file read when --config isn't given.
+const defaultConfigPath = "config.yaml"
+
+// configFile returns the config file to load: path if --config was given,
+// and otherwise the default file if it exists. An empty result means
+// Familiar is configured by FAMILIAR_* environment variables alone.
+func configFile(flags *flag.FlagSet, path string) string {
+	explicit := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "config" {
+			explicit = true
+		}
+	})
+	if explicit {
+		return path
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return ""
+	}
+	return path
+}
+
+// loadEnv loads envFile, or the .env files in the default locations if it
+// is empty.
+func loadEnv(envFile string) {
+	if envFile != "" {
+		if err := godotenv.Load(envFile); err != nil {
+			slog.Warn("could not load env file", "path", envFile, "error", err)
+		}
+		return
+	}
+	// Try default locations
+	godotenv.Load(".env")
+	godotenv.Load("/etc/familiar/familiar.env")
 }
 
 func runServe(args []string) {
 	fs := flag.NewFlagSet("serve", flag.ExitOnError)
-	configPath := fs.String("config", "config.yaml", "Path to config file")
+	configPath := fs.String("config", defaultConfigPath, "Path to config file")
 	envFile := fs.String("env-file", "", "Path to .env file (optional)")
+	dryRun := fs.Bool("dry-run", false, "Log the agents events would start instead of starting them (agents.dry_run)")
 	fs.Parse(args)
 
-	// Load .env file if specified or exists
-	if *envFile != "" {
-		if err := godotenv.Load(*envFile); err != nil {
-			log.Printf("Warning: could not load env file %s: %v", *envFile, err)
-		}
-	} else {
-		// Try default locations
-		godotenv.Load(".env")
-		godotenv.Load("/etc/familiar/familiar.env")
-	}
+	loadEnv(*envFile)
 
 	// Load config
+	*configPath = configFile(fs, *configPath)
 	cfg, err := config.Load(*configPath)
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fatal("failed to load config", "error", err)
+	}
+
+	// Forward logs to external sinks, if any are configured
+	shipper, err := newLogShipper(cfg.Logging.Ship)
+	if err != nil {
+		fatal("invalid log shipping config", "error", err)
+	}
+	var logOutput io.Writer = os.Stderr
+	if cfg.Logging.File.Path != "" {
+		logFile, err := logging.NewRotatingFile(cfg.Logging.File.Path,
+			int64(cfg.Logging.File.MaxSizeMB)<<20, cfg.Logging.File.MaxBackups)
+		if err != nil {
+			fatal("invalid logging.file", "error", err)
+		}
+		defer logFile.Close()
+		logOutput = logFile
+	}
+	if shipper != nil {
+		shipper.Start()
+		defer shipper.Stop()
+		if cfg.Logging.Ship.ServerLogs {
+			logOutput = io.MultiWriter(logOutput, shipper.Writer(logship.SourceServer, nil))
+		}
+	}
+
+	logger, err := logging.NewServerLogger(logOutput, cfg.Logging.Level, cfg.Logging.Format)
+	if err != nil {
+		fatal("invalid logging config", "error", err)
+	}
+	slog.SetDefault(logger)
+
+	// Export traces of the webhook pipeline, if a collector is configured
+	if cfg.Tracing.Endpoint != "" {
+		shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+			Endpoint:    cfg.Tracing.Endpoint,
+			Headers:     cfg.Tracing.Headers,
+			SampleRatio: cfg.Tracing.SampleRatio,
+			ServiceName: cfg.Tracing.ServiceName,
+		})
+		if err != nil {
+			fatal("invalid tracing config", "error", err)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			shutdownTracing(ctx)
+		}()
+		slog.Info("exporting traces", "endpoint", cfg.Tracing.Endpoint, "sample_ratio", cfg.Tracing.SampleRatio)
+	}
+
+	// Reloads are diffed against the config as written, before host paths
+	// are translated
+	loaded := *cfg
+	if *dryRun {
+		cfg.Agents.DryRun = true
+	}
+	if err := translateHostPaths(cfg); err != nil {
+		fatal("invalid host path", "error", err)
 	}
 
 	// Create repo cache
-	var repoCache *repocache.Cache
-	if cfg.RepoCache.HostDir != "" {
-		// Running in container with separate host/container paths
-		repoCache = repocache.NewWithHostDir(cfg.RepoCache.Dir, cfg.RepoCache.HostDir)
-	} else {
-		// Running directly on host
-		repoCache = repocache.New(cfg.RepoCache.Dir)
+	repoCache := newRepoCache(cfg)
+
+	// Index agent logs for the log browser and CLI
+	logIndex := logging.NewIndex(cfg.Logging.Dir)
+
+	// Compress aged agent logs and delete those past retention or the size cap
+	logCleaner := logging.NewCleaner(cfg.Logging.Dir, cfg.Logging.RetentionDays,
+		logging.WithCompressAfter(cfg.Logging.CompressAfterDays),
+		logging.WithMaxSize(int64(cfg.Logging.MaxSizeMB)<<20),
+		logging.WithCompactIndex(logIndex))
+	logCleanup := logging.NewCleanupScheduler(logCleaner, time.Hour)
+	logCleanup.Start()
+	defer logCleanup.Stop()
+
+	// Report cache disk usage in /metrics and /health
+	usage := repocache.NewUsageScheduler(repoCache, 5*time.Minute)
+	usage.Start()
+	defer usage.Stop()
+
+	// Keep recently used repos fetched so spawns don't wait on the network
+	if cfg.RepoCache.FetchIntervalMinutes > 0 {
+		fetcher := repocache.NewFetchScheduler(repoCache,
+			time.Duration(cfg.RepoCache.FetchIntervalMinutes)*time.Minute)
+		fetcher.Start()
+		defer fetcher.Stop()
+	}
+
+	// Repack cached repos periodically, off-peak if a window is configured
+	if cfg.RepoCache.Maintenance.IntervalHours > 0 {
+		window, err := repocache.ParseWindow(cfg.RepoCache.Maintenance.Window)
+		if err != nil {
+			fatal("invalid repo_cache.maintenance.window", "error", err)
+		}
+		maintainer := repocache.NewMaintenanceScheduler(repoCache,
+			time.Duration(cfg.RepoCache.Maintenance.IntervalHours)*time.Hour, window)
+		maintainer.Start()
+		defer maintainer.Stop()
 	}
 
 	// Create provider registry
 	reg := registry.New(cfg)
 
 	// Create agent spawner
-	spawner, err := agent.NewSpawner(agent.SpawnerConfig{
-		Image:            cfg.Agents.Image,
-		ClaudeAuthDir:    cfg.Agents.ClaudeAuthDir,
-		MaxAgents:        cfg.Concurrency.MaxAgents,
-		TimeoutMinutes:   cfg.Agents.TimeoutMinutes,
-		NetworkMode:      cfg.Agents.NetworkMode,
-		RepoCacheHostDir: cfg.RepoCache.HostDir,
-	})
+	spawner, err := newSpawner(cfg)
 	if err != nil {
-		log.Fatalf("Failed to create agent spawner: %v", err)
+		fatal("failed to create agent spawner", "error", err)
 	}
 	defer spawner.Close()
 
+	// Prune worktrees left behind by crashed or interrupted runs
+	if cfg.RepoCache.WorktreeMaxAgeHours > 0 {
+		pruner := repocache.NewPruneScheduler(repoCache, time.Hour,
+			time.Duration(cfg.RepoCache.WorktreeMaxAgeHours)*time.Hour,
+			func(worktreeID string) bool {
+				_, ok := spawner.GetSession(worktreeID)
+				return ok
+			})
+		pruner.Start()
+		defer pruner.Stop()
+	}
+
+	// Create agent manager so excess spawns queue instead of failing
+	manager := agent.NewManager(agent.ManagerConfig{
+		MaxConcurrent: cfg.Concurrency.MaxAgents,
+		QueueSize:     cfg.Concurrency.QueueSize,
+	})
+	defer manager.Shutdown()
+
 	// Create agent handler
-	agentHandler := handler.NewAgentHandler(spawner, repoCache, reg, cfg.Logging.Dir, cfg.Logging.HostDir)
+	handlerOpts := []handler.Option{
+		handler.WithQueue(manager),
+		handler.WithHooks(hookRunner(cfg)),
+		handler.WithLogIndex(logIndex),
+		handler.WithAcknowledge(cfg.Agents.Acknowledge),
+		handler.WithSummary(cfg.Agents.Summary),
+		handler.WithBusyPolicy(cfg.Agents.BusyPolicy),
+		handler.WithCredentials(cfg.Agents.Credentials),
+	}
+	if cfg.Agents.DryRun {
+		handlerOpts = append(handlerOpts, handler.WithDryRun())
+		slog.Warn("dry run: events are handled up to spawning, but no agents will start")
+	}
+	if shipper != nil && cfg.Logging.Ship.AgentLogs {
+		handlerOpts = append(handlerOpts, handler.WithLogShipper(shipper))
+	}
+	if cfg.Logging.AuditFile != "" {
+		handlerOpts = append(handlerOpts, handler.WithAuditLog(logging.NewAuditLog(cfg.Logging.AuditFile)))
+	}
+	agentHandler := handler.NewAgentHandler(spawner, repoCache, reg, cfg.Logging.Dir, cfg.Logging.HostDir, handlerOpts...)
+	spawner.OnTimeout = agentHandler.HandleTimeout
+	spawner.OnExit = agentHandler.HandleExit
+	spawner.OnFailure = agentHandler.HandleFailure
 
 	// Create event router
 	router := event.NewRouter(cfg, agentHandler.Handle, nil)
 
+	// Pre-pull the agent image so the first spawn doesn't wait on it
+	pullPolicy, err := docker.ParsePullPolicy(cfg.Agents.PullPolicy)
+	if err != nil {
+		fatal("invalid agents.pull_policy", "error", err)
+	}
+	dockerClient, err := docker.NewClient(
+		docker.WithConnection(dockerConnection(cfg)),
+		docker.WithRegistryAuth(docker.RegistryAuth{
+			Username:         cfg.Agents.Registry.Username,
+			Password:         cfg.Agents.Registry.Password,
+			CredentialHelper: cfg.Agents.Registry.CredentialHelper,
+			ServerAddress:    cfg.Agents.Registry.ServerAddress,
+		}))
+	if err != nil {
+		fatal("failed to create docker client", "error", err)
+	}
+	defer dockerClient.Close()
+	images := docker.NewImageWarmer(dockerClient, docker.WithPullPolicy(pullPolicy))
+	go func() {
+		images.Warm(context.Background(), cfg.Agents.Image)
+		if !images.Ready() {
+			return
+		}
+		// Catch bind mounts the daemon can't resolve before an agent needs them
+		for _, err := range checkHostMounts(context.Background(), dockerClient, cfg) {
+			slog.Warn("mount check failed", "error", err)
+		}
+	}()
+
+	// Carry metric totals across restarts
+	if cfg.Metrics.StateFile != "" {
+		if err := metrics.Restore(cfg.Metrics.StateFile); err != nil {
+			slog.Warn("failed to restore metrics", "error", err)
+		}
+		interval := time.Duration(cfg.Metrics.SnapshotIntervalSeconds) * time.Second
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		snapshotter := metrics.NewSnapshotter(cfg.Metrics.StateFile, interval)
+		snapshotter.Start()
+		defer snapshotter.Stop()
+	}
+
+	// Push metrics to a collector, if configured
+	if push := cfg.Metrics.Push; push.Type != "" {
+		pusher, err := metricspush.New(context.Background(), metricspush.Config{
+			Type:     push.Type,
+			Endpoint: push.Endpoint,
+			Interval: time.Duration(push.IntervalSeconds) * time.Second,
+			Prefix:   push.Prefix,
+			Headers:  push.Headers,
+		})
+		if err != nil {
+			fatal("invalid metrics.push config", "error", err)
+		}
+		pusher.Start()
+		defer pusher.Stop()
+	}
+
+	// Report running and queued agents in /metrics and /health
+	metrics.TrackAgents(spawner.ActiveCount, manager.QueueLength)
+
+	// Concurrency limits can be changed at runtime via the admin API, and
+	// the config reloaded via SIGHUP or the admin API
+	limits := &concurrencyLimits{manager: manager, spawner: spawner}
+	reloader := &configReloader{
+		path:     *configPath,
+		limits:   limits,
+		spawner:  spawner,
+		images:   images,
+		router:   router,
+		registry: reg,
+		started:  cfg,
+		current:  &loaded,
+		image:    cfg.Agents.Image,
+	}
+	go reloadOnSIGHUP(reloader)
+	if cfg.Reload.Watch && *configPath != "" {
+		interval := time.Duration(cfg.Reload.IntervalSeconds) * time.Second
+		if interval <= 0 {
+			interval = 5 * time.Second
+		}
+		watcher := config.NewWatcher(*configPath, interval, func() {
+			if err := reloader.Reload(context.Background()); err != nil {
+				slog.Error("config reload failed", "error", err)
+			}
+		})
+		watcher.Start()
+		defer watcher.Stop()
+	}
+
 	// Create and start server with router
-	srv := server.NewWithRouter(cfg, router)
-	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+	build := buildInfo()
+	srvOpts := []server.Option{
+		server.WithConcurrency(limits),
+		server.WithAgentStats(spawner),
+		server.WithAgentLogs(spawner),
+		server.WithLogIndex(logIndex),
+		server.WithSessions(spawner),
+		server.WithReloader(reloader),
+		server.WithConfigSource(reloader),
+		server.WithHealthCheck("docker", spawner.Ping),
+		server.WithHealthCheck("agent_image", spawner.CheckImage),
+		server.WithHealthCheck("repo_cache", func(context.Context) error { return repoCache.CheckWritable() }),
+		server.WithQueue(manager),
+		server.WithCleaner(&adminCleanup{
+			logs:   logCleaner,
+			cache:  repoCache,
+			maxAge: time.Duration(cfg.RepoCache.WorktreeMaxAgeHours) * time.Hour,
+			active: func(worktreeID string) bool {
+				_, ok := spawner.GetSession(worktreeID)
+				return ok
+			},
+		}),
+	}
+	// No agent runs in a dry run, so webhooks needn't wait for its image
+	if !cfg.Agents.DryRun {
+		srvOpts = append(srvOpts, server.WithImages(images))
+	}
+	srvOpts = append(srvOpts, server.WithBuildInfo(build))
+	srvOpts = append(srvOpts, dependencyChecks(cfg, reg)...)
+
+	// Serve /health and /metrics apart from the webhooks, and the admin API
+	// on a local socket, if configured
+	var opsSrv, adminSrv *http.Server
+	srvOpts = append(srvOpts,
+		server.WithShutdownHook(notifyStopping),
+		server.WithShutdownHook(func(ctx context.Context) {
+			if opsSrv != nil {
+				opsSrv.Shutdown(ctx)
+			}
+			if adminSrv != nil {
+				adminSrv.Shutdown(ctx)
+			}
+		}),
+		// Release queued spawns' worktrees, then stop running agents. The
+		// deferred schedulers and manager stop once serving returns.
+		server.WithShutdownHook(func(ctx context.Context) {
+			if drained := manager.Drain(); len(drained) > 0 {
+				slog.Info("dropped queued agents on shutdown", "count", len(drained))
+			}
+			spawner.StopAll(ctx)
+		}))
+	srv := server.NewWithRouter(cfg, router, srvOpts...)
+
+	// Under systemd, report readiness and keep the watchdog fed while the
+	// router, spawn queue, and spawner respond; a deadlock in any of them
+	// stops the pings and systemd restarts the service
+	stopWatchdog := notifySystemd(srv, func(context.Context) error {
+		router.Debounced()
+		manager.QueueLength()
+		spawner.ActiveCount()
+		return nil
+	})
+	defer stopWatchdog()
+
+	if ops := srv.OpsHandler(); ops != nil {
+		// Served with the main listener's TLS, so scrapers can present
+		// client certificates and the ops token isn't sent in the clear
+		tlsCfg, err := server.TLSConfig(cfg.Server.TLS)
+		if err != nil {
+			fatal("invalid server.tls", "error", err)
+		}
+		opsSrv = server.NewHTTPServer(cfg.Server.OpsAddr, ops, cfg.Server.Timeouts)
+		opsSrv.TLSConfig = tlsCfg
+		go func() {
+			slog.Info("starting ops server", "addr", cfg.Server.OpsAddr, "tls", tlsCfg != nil)
+			serve := opsSrv.ListenAndServe
+			if tlsCfg != nil {
+				serve = func() error { return opsSrv.ListenAndServeTLS("", "") }
+			}
+			if err := serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fatal("ops server error", "error", err)
+			}
+		}()
+	}
+
+	if cfg.Server.AdminSocket != "" {
+		ln, err := server.ListenUnix(cfg.Server.AdminSocket)
+		if err != nil {
+			fatal("invalid server.admin_socket", "error", err)
+		}
+		adminSrv = server.NewHTTPServer("", srv.AdminSocketHandler(), cfg.Server.Timeouts)
+		go func() {
+			slog.Info("serving admin API on socket", "path", cfg.Server.AdminSocket)
+			if err := adminSrv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fatal("admin socket error", "error", err)
+			}
+		}()
+	}
+
+	slog.Info("starting Familiar server", "addr", fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port), "tls", cfg.Server.TLS.CertFile != "",
+		"version", build.Version, "commit", build.Commit)
+	if err := srv.ListenAndServeWithShutdown(); err != nil {
+		fatal("server error", "error", err)
+	}
+}
+
+// fatal logs an error and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// newRepoCache creates the repo cache described by cfg.
+func newRepoCache(cfg *config.Config) *repocache.Cache {
+	opts := []repocache.Option{
+		repocache.WithMaxSize(int64(cfg.RepoCache.MaxSizeMB) << 20),
+		repocache.WithCloneDepth(cfg.RepoCache.CloneDepth),
+		repocache.WithCloneFilter(cfg.RepoCache.CloneFilter),
+	}
+	if cfg.RepoCache.HostDir != "" {
+		// Running in container with separate host/container paths
+		return repocache.NewWithHostDir(cfg.RepoCache.Dir, cfg.RepoCache.HostDir, opts...)
+	}
+	// Running directly on host
+	return repocache.New(cfg.RepoCache.Dir, opts...)
+}
+
+// newSpawner creates the agent spawner described by cfg.
+func newSpawner(cfg *config.Config) (*agent.Spawner, error) {
+	imageDigest, err := docker.ParseDigest(cfg.Agents.ImageDigest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid agents.image_digest: %w", err)
+	}
+
+	redactor, err := newRedactor(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return agent.NewSpawner(agent.SpawnerConfig{
+		Image:              cfg.Agents.Image,
+		ImageDigest:        imageDigest,
+		ClaudeAuthDir:      cfg.Agents.ClaudeAuthDir,
+		MaxAgents:          cfg.Concurrency.MaxAgents,
+		TimeoutMinutes:     cfg.Agents.TimeoutMinutes,
+		InteractiveMinutes: cfg.Agents.InteractiveTimeoutMinutes,
+		IdleMinutes:        cfg.Agents.IdleTimeoutMinutes,
+		NetworkMode:        cfg.Agents.NetworkMode,
+		RepoCacheHostDir:   cfg.RepoCache.HostDir,
+		Env:                envFilter(cfg),
+		Docker:             dockerConnection(cfg),
+		Healthcheck:        agentHealthcheck(cfg),
+		Redactor:           redactor,
+	})
+}
+
+// envFilter returns the filter for env vars passed to agents, withholding
+// the server's secrets by value.
+func envFilter(cfg *config.Config) agent.EnvFilter {
+	return agent.EnvFilter{
+		Allow:   cfg.Agents.Env.Allow,
+		Deny:    cfg.Agents.Env.Deny,
+		Secrets: serverSecrets(cfg),
+	}
+}
+
+// hookRunner returns the configured pre- and post-agent hooks.
+func hookRunner(cfg *config.Config) *hooks.Runner {
+	return &hooks.Runner{
+		Pre:     cfg.Hooks.PreAgent,
+		Post:    cfg.Hooks.PostAgent,
+		Timeout: time.Duration(cfg.Hooks.TimeoutSeconds) * time.Second,
+	}
+}
+
+// dockerConnection returns the configured Docker daemon connection.
+func dockerConnection(cfg *config.Config) docker.Connection {
+	return docker.Connection{
+		Host:      cfg.Agents.Docker.Host,
+		CertPath:  cfg.Agents.Docker.CertPath,
+		TLSVerify: cfg.Agents.Docker.TLSVerify,
+	}
+}
+
+// agentHealthcheck returns the configured agent container healthcheck, or
+// nil to keep the image's own.
+func agentHealthcheck(cfg *config.Config) *docker.Healthcheck {
+	hc := cfg.Agents.Healthcheck
+	if hc.Cmd == "" {
+		return nil
+	}
+	return &docker.Healthcheck{
+		Cmd:         hc.Cmd,
+		Interval:    time.Duration(hc.IntervalSeconds) * time.Second,
+		Timeout:     time.Duration(hc.TimeoutSeconds) * time.Second,
+		StartPeriod: time.Duration(hc.StartPeriodSeconds) * time.Second,
+		Retries:     hc.Retries,
+	}
+}
+
+// newRedactor creates the redactor for captured agent output. Provider
+// tokens are passed to agents on purpose, so they are redacted from it
+// rather than withheld.
+func newRedactor(cfg *config.Config) (*logging.Redactor, error) {
+	redactor, err := logging.NewRedactor(
+		append(serverSecrets(cfg), cfg.Providers.GitHub.Token, cfg.Providers.GitLab.Token,
+			cfg.Providers.GitHub.ReadOnlyToken, cfg.Providers.GitLab.ReadOnlyToken),
+		cfg.Logging.RedactPatterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid logging.redact_patterns: %w", err)
+	}
+	return redactor, nil
+}
+
+// serverSecrets returns secret values that must never reach agent containers.
+func serverSecrets(cfg *config.Config) []string {
+	values := []string{
+		cfg.LLM.API.APIKey,
+		cfg.Server.AdminToken,
+		cfg.Server.OpsToken,
+		cfg.Agents.Registry.Password,
+		cfg.Providers.GitHub.WebhookSecret,
+		cfg.Providers.GitLab.WebhookSecret,
+	}
+	for _, sink := range cfg.Logging.Ship.Sinks {
+		values = append(values, sink.Password)
+	}
+	// Header values carry collector credentials
+	for _, v := range cfg.Metrics.Push.Headers {
+		values = append(values, v)
+	}
+	for _, v := range cfg.Tracing.Headers {
+		values = append(values, v)
+	}
+	return append(values, secrets.Credentials()...)
+}
+
+// concurrencyLimits applies runtime limit changes to both the spawn queue and
+// the spawner so they stay in agreement.
+type concurrencyLimits struct {
+	manager *agent.Manager
+	spawner *agent.Spawner
+}
+
+func (c *concurrencyLimits) Limits() (maxAgents, queueSize int) {
+	l := c.manager.Limits()
+	return l.MaxConcurrent, l.QueueSize
+}
+
+func (c *concurrencyLimits) SetLimits(maxAgents, queueSize int) error {
+	if err := c.manager.SetLimits(agent.ManagerConfig{MaxConcurrent: maxAgents, QueueSize: queueSize}); err != nil {
+		return err
+	}
+	return c.spawner.SetMaxAgents(maxAgents)
+}
 
-	log.Printf("Starting Familiar server on %s", addr)
-	if err := http.ListenAndServe(addr, srv.Handler()); err != nil {
-		log.Fatalf("Server error: %v", err)
+func (c *concurrencyLimits) ActiveCount() int { return c.manager.ActiveCount() }
+func (c *concurrencyLimits) QueueLength() int { return c.manager.QueueLength() }
+
+// adminCleanup runs log cleanup and worktree pruning when triggered via the
+// admin API.
+type adminCleanup struct {
+	logs   *logging.Cleaner
+	cache  *repocache.Cache
+	maxAge time.Duration // Worktrees are only pruned when set
+	active func(worktreeID string) bool
+}
+
+func (c *adminCleanup) Cleanup(ctx context.Context) (server.CleanupResult, error) {
+	var result server.CleanupResult
+	deleted, err := c.logs.Cleanup()
+	result.LogsDeleted = deleted
+	if err != nil {
+		return result, fmt.Errorf("cleaning up logs: %w", err)
+	}
+	if c.maxAge > 0 {
+		pruned, err := c.cache.PruneWorktrees(ctx, c.maxAge, c.active)
+		result.WorktreesPruned = pruned
+		if err != nil {
+			return result, fmt.Errorf("pruning worktrees: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// pinger is implemented by providers and intent parsers that can check
+// their API and credentials.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// dependencyChecks reports each configured provider's API, and the intent
+// parser's API key, in /health.
+func dependencyChecks(cfg *config.Config, reg *registry.Registry) []server.Option {
+	var opts []server.Option
+	for _, name := range reg.List() {
+		opts = append(opts, server.WithHealthCheck("provider_"+name, func(ctx context.Context) error {
+			// Look the provider up each time so reloaded tokens are checked
+			p, ok := reg.Get(name).(pinger)
+			if !ok {
+				return fmt.Errorf("provider %s not configured", name)
+			}
+			return p.Ping(ctx)
+		}))
+	}
+	if cfg.LLM.Strategy == string(intent.StrategyAPI) && cfg.LLM.API.APIKey != "" {
+		parser := api.New(cfg.LLM.API.APIKey, cfg.LLM.API.Model)
+		opts = append(opts, server.WithHealthCheck("intent_parser", parser.Ping))
+	}
+	return opts
+}
+
+// configReloader re-reads the config file and applies what can change
+// without a restart: provider tokens (and their redaction), the secrets
+// withheld from agents, bot username, event toggles, prompts, concurrency
+// limits, and the agent image. Running agents and the listener are left
+// alone.
+type configReloader struct {
+	path     string
+	limits   *concurrencyLimits
+	spawner  *agent.Spawner
+	images   *docker.ImageWarmer
+	router   *event.Router
+	registry *registry.Registry
+
+	started *config.Config // config at startup, whose restart-only secrets stay in use
+
+	mu      sync.Mutex     // serializes reloads
+	current *config.Config // last config loaded, to log what changed
+	image   string         // agent image in use
+}
+
+// Current returns the config last loaded.
+func (r *configReloader) Current() *config.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// Reload applies the config file. Everything that can be checked up front
+// is, before any setting changes; after that settings are applied in order,
+// so an error leaves those before it in effect. The config is only recorded
+// as current once all of it is.
+func (r *configReloader) Reload(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := config.Load(r.path)
+	if err != nil {
+		return err
+	}
+	// New tokens must not reach agents before a redactor that scrubs them
+	redactor, err := newRedactor(cfg)
+	if err != nil {
+		return err
+	}
+	digest, err := docker.ParseDigest(cfg.Agents.ImageDigest)
+	if err != nil {
+		return fmt.Errorf("keeping agent image %s: %w", r.image, err)
+	}
+	logChanges(r.current, cfg)
+
+	if err := r.limits.SetLimits(cfg.Concurrency.MaxAgents, cfg.Concurrency.QueueSize); err != nil {
+		return fmt.Errorf("applying concurrency limits: %w", err)
+	}
+	r.spawner.SetRedactor(redactor)
+	// Secrets that need a restart to change are still the startup values
+	filter := envFilter(cfg)
+	filter.Secrets = append(filter.Secrets, serverSecrets(r.started)...)
+	r.spawner.SetEnvFilter(filter)
+	r.registry.Reload(cfg)
+	r.router.SetConfig(cfg)
+	slog.Info("reloaded config",
+		"max_agents", cfg.Concurrency.MaxAgents, "queue_size", cfg.Concurrency.QueueSize,
+		"providers", r.registry.List())
+
+	// Only switch images once the new one is present locally; with
+	// pull_policy always this also refreshes the current one. Webhooks keep
+	// being accepted on the current image while it pulls.
+	if err := r.images.Switch(ctx, cfg.Agents.Image); err != nil {
+		return fmt.Errorf("agent image %s unavailable; keeping %s: %w", cfg.Agents.Image, r.image, err)
+	}
+	if cfg.Agents.Image != r.image {
+		slog.Info("switched agent image", "from", r.image, "image", cfg.Agents.Image)
+	}
+	r.image = cfg.Agents.Image
+	r.spawner.SetImage(r.image, digest)
+	r.current = cfg
+	return nil
+}
+
+// logChanges logs each setting that differs between old and new, warning
+// about those that only take effect after a restart.
+func logChanges(old, new *config.Config) {
+	var restart []string
+	for _, change := range config.Diff(old, new) {
+		slog.Info("config setting changed", "key", change.Key, "from", change.Old, "to", change.New)
+		if change.RestartRequired() {
+			restart = append(restart, change.Key)
+		}
+	}
+	if len(restart) > 0 {
+		slog.Warn("some config changes need a restart to take effect", "keys", restart)
+	}
+}
+
+// reloadOnSIGHUP reloads the config file on each SIGHUP.
+func reloadOnSIGHUP(reloader *configReloader) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		if err := reloader.Reload(context.Background()); err != nil {
+			slog.Error("config reload failed", "error", err)
+		}
 	}
 }
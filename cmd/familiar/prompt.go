@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/event"
+	"github.com/drewdunne/familiar/internal/intent"
+	"github.com/drewdunne/familiar/internal/prompt"
+	"github.com/drewdunne/familiar/internal/registry"
+)
+
+func printPromptUsage() {
+	fmt.Println("Usage: familiar prompt <command> [options]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  preview   Print the prompt an event's agents would get")
+}
+
+func runPrompt(args []string) {
+	if len(args) < 1 {
+		printPromptUsage()
+		os.Exit(1)
+	}
+
+	var err error
+	switch args[0] {
+	case "preview":
+		err = runPromptPreview(args[1:])
+	default:
+		fmt.Printf("Unknown prompt command: %s\n", args[0])
+		printPromptUsage()
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "familiar prompt %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+}
+
+// eventTypes are the event types agents run for.
+var eventTypes = []string{
+	string(event.TypeMROpened),
+	string(event.TypeMRComment),
+	string(event.TypeMRUpdated),
+	string(event.TypeMention),
+}
+
+// runPromptPreview prints the prompt the builder would produce for an
+// event, from a saved webhook payload or from an MR fetched from its
+// provider. Nothing is spawned.
+func runPromptPreview(args []string) error {
+	fs := flag.NewFlagSet("prompt preview", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "Path to config file")
+	envFile := fs.String("env-file", "", "Path to .env file (optional)")
+	payloadPath := fs.String("payload", "", "Saved webhook payload to build the event from")
+	providerName := fs.String("provider", "", "Provider of the payload or MR (default: guessed from the payload)")
+	repoPath := fs.String("repo", "", "Repository as owner/name, to fetch the MR instead of reading a payload")
+	mrNumber := fs.Int("mr", 0, "Merge request or pull request number, with --repo")
+	eventType := fs.String("event", "", "Event type to build the prompt for (default: the payload's, or mr_opened)")
+	comment := fs.String("comment", "", "Comment text, for mr_comment and mention events")
+	actions := fs.String("actions", "", "Comma-separated actions the comment's intent requests (merge, approve, dismiss_reviews, push)")
+	persona := fs.String("persona", "", "Print only this persona's prompt")
+	repoDir := fs.String("repo-dir", "", "Path to a repo checkout whose .familiar/config.yaml is merged in")
+	fs.Parse(args)
+
+	if *eventType != "" && !slices.Contains(eventTypes, *eventType) {
+		return fmt.Errorf("unknown event type %q (want %s)", *eventType, strings.Join(eventTypes, ", "))
+	}
+	parsedIntent, err := previewIntent(*actions)
+	if err != nil {
+		return err
+	}
+
+	loadEnv(*envFile)
+
+	cfg, err := config.Load(configFile(fs, *configPath))
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	var evt *event.Event
+	switch {
+	case *payloadPath != "":
+		payload, err := os.ReadFile(*payloadPath)
+		if err != nil {
+			return err
+		}
+		if *providerName == "" {
+			*providerName = guessProvider(payload)
+		}
+		if evt, err = normalizePayload(*providerName, "", payload); err != nil {
+			return fmt.Errorf("normalizing payload: %w", err)
+		}
+	case *repoPath != "" && *mrNumber > 0:
+		i := strings.LastIndex(*repoPath, "/")
+		if i <= 0 || i == len(*repoPath)-1 {
+			return errors.New("--repo must be owner/name")
+		}
+		prov := registry.New(cfg).Get(*providerName)
+		if prov == nil {
+			return fmt.Errorf("--provider must name a configured provider, not %q", *providerName)
+		}
+		if evt, err = mrEvent(context.Background(), prov, (*repoPath)[:i], (*repoPath)[i+1:], *mrNumber, ""); err != nil {
+			return err
+		}
+	default:
+		return errors.New("--payload, or --provider, --repo, and --mr, are required")
+	}
+
+	if *eventType != "" {
+		evt.Type = event.Type(*eventType)
+	}
+	if *comment != "" {
+		evt.CommentBody = *comment
+		if evt.CommentAuthor == "" {
+			evt.CommentAuthor = os.Getenv("USER")
+		}
+	}
+
+	repoCfg := &config.RepoConfig{}
+	if *repoDir != "" {
+		repoCfg, err = config.LoadRepoConfig(context.Background(), dirReader(*repoDir), "", "", "")
+		if err != nil {
+			return err
+		}
+	}
+	merged, err := config.MergeConfigs(cfg, repoCfg)
+	if err != nil {
+		return fmt.Errorf("merging config: %w", err)
+	}
+	merged = agentConfig(evt, merged)
+
+	var personas []config.PersonaConfig
+	if *persona != "" {
+		p, err := findPersona(merged.Personas, *persona)
+		if err != nil {
+			return err
+		}
+		personas = []config.PersonaConfig{p}
+	} else {
+		personas = merged.PersonasFor(string(evt.Type))
+	}
+
+	builder := prompt.NewBuilder()
+	if len(personas) == 0 {
+		fmt.Println(builder.Build(evt, merged, parsedIntent))
+		return nil
+	}
+	for i := range personas {
+		if len(personas) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("==> persona %s <==\n", personas[i].Name)
+		}
+		fmt.Println(builder.BuildForPersona(evt, merged, parsedIntent, &personas[i]))
+	}
+	return nil
+}
+
+// previewIntent returns an intent requesting actions, a comma-separated
+// list, or nil if there are none.
+func previewIntent(actions string) (*intent.ParsedIntent, error) {
+	if actions == "" {
+		return nil, nil
+	}
+	known := []intent.Action{intent.ActionMerge, intent.ActionApprove, intent.ActionDismissReviews, intent.ActionPush}
+	parsed := &intent.ParsedIntent{Confidence: 1}
+	for _, a := range strings.Split(actions, ",") {
+		action := intent.Action(strings.TrimSpace(a))
+		if !slices.Contains(known, action) {
+			return nil, fmt.Errorf("unknown action %q (want merge, approve, dismiss_reviews, push)", action)
+		}
+		parsed.RequestedActions = append(parsed.RequestedActions, action)
+	}
+	return parsed, nil
+}
+
+// guessProvider tells a GitLab payload from a GitHub one.
+func guessProvider(payload []byte) string {
+	var keys map[string]json.RawMessage
+	if json.Unmarshal(payload, &keys) == nil {
+		if _, ok := keys["object_kind"]; ok {
+			return "gitlab"
+		}
+	}
+	return "github"
+}
+
+// agentConfig returns the config an event's agents run with. A fork's
+// branch can't be pushed to, so its agents always run in patch mode.
+func agentConfig(evt *event.Event, merged *config.MergedConfig) *config.MergedConfig {
+	if !evt.FromFork {
+		return merged
+	}
+	forked := *merged
+	forked.AgentMode = config.AgentModePatch
+	return &forked
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/server"
+)
+
+func runQueue(args []string) {
+	if err := showQueue(args); err != nil {
+		fmt.Fprintf(os.Stderr, "familiar queue: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// queueState is what familiar queue shows: the spawns waiting for a slot
+// and the events whose repeats are being dropped.
+type queueState struct {
+	Queued    []server.QueuedInfo    `json:"queued"`
+	Debounced []server.DebouncedInfo `json:"debounced"`
+}
+
+// showQueue prints what a running server is holding back, for when agents
+// seem slow to respond.
+func showQueue(args []string) error {
+	fs := flag.NewFlagSet("queue", flag.ExitOnError)
+	common := newAgentsFlags(fs)
+	asJSON := fs.Bool("json", false, "Print the admin API's JSON")
+	fs.Parse(args)
+
+	client, err := common.client()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	var state queueState
+	if err := getJSON(ctx, client, "/admin/queue", &state.Queued); err != nil {
+		return fmt.Errorf("listing queue: %w", err)
+	}
+	if err := getJSON(ctx, client, "/admin/debounced", &state.Debounced); err != nil {
+		return fmt.Errorf("listing debounced events: %w", err)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(state)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Queued spawns (%d), started in this order as slots free up:\n", len(state.Queued))
+	if len(state.Queued) > 0 {
+		fmt.Fprintln(w, "POS\tAGENT\tREPO\tMR\tEVENT\tPERSONA\tWAITING")
+		for _, q := range state.Queued {
+			persona := q.Persona
+			if persona == "" {
+				persona = "-"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\t%s\n",
+				q.Position, q.ID, q.Repo, q.MRNumber, q.EventType, persona, seconds(q.WaitSeconds))
+		}
+	}
+	fmt.Fprintf(w, "\nDebounced events (%d), repeats dropped until the window ends:\n", len(state.Debounced))
+	if len(state.Debounced) > 0 {
+		fmt.Fprintln(w, "PROVIDER\tREPO\tMR\tEVENT\tSUPPRESSED\tENDS IN")
+		for _, d := range state.Debounced {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%d\t%s\n",
+				d.Provider, d.Repo, d.MRNumber, d.EventType, d.Suppressed, seconds(d.RemainingSeconds))
+		}
+	}
+	return w.Flush()
+}
+
+// getJSON decodes the admin API's response to a GET of path into v.
+func getJSON(ctx context.Context, client *adminClient, path string, v any) error {
+	resp, err := client.do(ctx, http.MethodGet, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// seconds renders n seconds as a duration, e.g. "1m30s".
+func seconds(n int64) string {
+	return (time.Duration(n) * time.Second).String()
+}
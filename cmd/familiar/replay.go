@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/event"
+	"github.com/drewdunne/familiar/internal/handler"
+	"github.com/drewdunne/familiar/internal/intent"
+	"github.com/drewdunne/familiar/internal/logging"
+	"github.com/drewdunne/familiar/internal/registry"
+	"github.com/drewdunne/familiar/internal/webhook"
+)
+
+func runReplay(args []string) {
+	if err := replay(args); err != nil {
+		fmt.Fprintf(os.Stderr, "familiar replay: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// replay runs a saved webhook payload through normalization and routing, as
+// if it had just been delivered.
+func replay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "Path to config file")
+	envFile := fs.String("env-file", "", "Path to .env file (optional)")
+	providerName := fs.String("provider", "", "Provider that sent the payload (github, gitlab)")
+	eventType := fs.String("event", "", "GitHub event type, from X-GitHub-Event (default: guessed from the payload)")
+	dryRun := fs.Bool("dry-run", false, "Show how the event would be handled without starting agents")
+	fs.Parse(args)
+
+	if *providerName == "" || fs.NArg() != 1 {
+		return errors.New("usage: familiar replay --provider github|gitlab [options] <payload.json>")
+	}
+	payload, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	loadEnv(*envFile)
+
+	cfg, err := config.Load(configFile(fs, *configPath))
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	// The router logs why it drops an event, so show at least that much
+	level := cfg.Logging.Level
+	if level == "warn" || level == "error" {
+		level = "info"
+	}
+	logger, err := logging.NewServerLogger(os.Stderr, level, cfg.Logging.Format)
+	if err != nil {
+		return fmt.Errorf("invalid logging config: %w", err)
+	}
+	slog.SetDefault(logger)
+
+	evt, err := normalizePayload(*providerName, *eventType, payload)
+	if err != nil {
+		return fmt.Errorf("normalizing payload: %w", err)
+	}
+	printEvent(os.Stdout, evt)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	routed := false
+	if *dryRun {
+		router := event.NewRouter(cfg, func(_ context.Context, evt *event.Event, merged *config.MergedConfig, _ *intent.ParsedIntent) error {
+			routed = true
+			printPlan(os.Stdout, evt, merged)
+			return nil
+		}, nil)
+		if err := router.Route(ctx, evt); err != nil {
+			return err
+		}
+	} else {
+		if err := translateHostPaths(cfg); err != nil {
+			return fmt.Errorf("invalid host path: %w", err)
+		}
+		err := handleAndWait(ctx, cfg, registry.New(cfg), func(h *handler.AgentHandler) error {
+			router := event.NewRouter(cfg, func(ctx context.Context, evt *event.Event, merged *config.MergedConfig, parsed *intent.ParsedIntent) error {
+				routed = true
+				return h.Handle(ctx, evt, merged, parsed)
+			}, nil)
+			return router.Route(ctx, evt)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if !routed {
+		fmt.Println("\nThe event was not routed to an agent; the log above says why.")
+	}
+	return nil
+}
+
+// normalizePayload turns a raw webhook payload into an event, as the server
+// does for a delivery. GitHub sends its event type in a header, so
+// eventType stands in for it, or is guessed from the payload if empty.
+func normalizePayload(providerName, eventType string, payload []byte) (*event.Event, error) {
+	switch providerName {
+	case "github":
+		if eventType == "" {
+			var err error
+			if eventType, err = guessGitHubEvent(payload); err != nil {
+				return nil, err
+			}
+		}
+		ghEvent := &webhook.GitHubEvent{EventType: eventType, RawPayload: payload, CorrelationID: newRunID()}
+		if err := json.Unmarshal(payload, ghEvent); err != nil {
+			return nil, fmt.Errorf("parsing payload: %w", err)
+		}
+		return event.NormalizeGitHubEvent(ghEvent)
+	case "gitlab":
+		glEvent := &webhook.GitLabEvent{RawPayload: payload, CorrelationID: newRunID()}
+		if err := json.Unmarshal(payload, glEvent); err != nil {
+			return nil, fmt.Errorf("parsing payload: %w", err)
+		}
+		return event.NormalizeGitLabEvent(glEvent)
+	default:
+		return nil, fmt.Errorf("unknown provider %q (want github, gitlab)", providerName)
+	}
+}
+
+// guessGitHubEvent infers the X-GitHub-Event header from the payload's
+// top-level keys.
+func guessGitHubEvent(payload []byte) (string, error) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &keys); err != nil {
+		return "", fmt.Errorf("parsing payload: %w", err)
+	}
+	_, hasComment := keys["comment"]
+	_, hasIssue := keys["issue"]
+	_, hasPR := keys["pull_request"]
+	switch {
+	case hasComment && hasIssue:
+		return "issue_comment", nil
+	case hasComment && hasPR:
+		return "pull_request_review_comment", nil
+	case hasPR:
+		return "pull_request", nil
+	default:
+		return "", errors.New("can't tell the GitHub event type from the payload; pass --event")
+	}
+}
+
+// printEvent shows the normalized event.
+func printEvent(w io.Writer, evt *event.Event) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Event:\t%s\n", evt.Type)
+	fmt.Fprintf(tw, "Repo:\t%s/%s (%s)\n", evt.RepoOwner, evt.RepoName, evt.RepoURL)
+	fmt.Fprintf(tw, "MR:\t#%d %s\n", evt.MRNumber, evt.MRTitle)
+	if evt.SourceBranch != "" {
+		branches := evt.SourceBranch + " -> " + evt.TargetBranch
+		if evt.FromFork {
+			branches += " (from a fork)"
+		}
+		fmt.Fprintf(tw, "Branches:\t%s\n", branches)
+	}
+	fmt.Fprintf(tw, "Actor:\t%s\n", evt.Actor)
+	if evt.CommentBody != "" {
+		fmt.Fprintf(tw, "Comment:\t%s\n", strings.ReplaceAll(evt.CommentBody, "\n", " "))
+	}
+	if evt.CommentFilePath != "" {
+		fmt.Fprintf(tw, "Line:\t%s:%d\n", evt.CommentFilePath, evt.CommentLine)
+	}
+	tw.Flush()
+}
+
+// printPlan shows the agents a routed event would start.
+func printPlan(w io.Writer, evt *event.Event, merged *config.MergedConfig) {
+	mode := agentConfig(evt, merged).AgentMode
+
+	fmt.Fprintln(w)
+	personas := merged.PersonasFor(string(evt.Type))
+	if len(personas) == 0 {
+		fmt.Fprintf(w, "Would start one agent in %s mode.\n", mode)
+		return
+	}
+	fmt.Fprintf(w, "Would start %d persona agent(s) in %s mode:\n", len(personas), mode)
+	for _, p := range personas {
+		fmt.Fprintf(w, "  %s\n", p.Name)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drewdunne/familiar/internal/event"
+)
+
+const (
+	githubPROpened = `{
+		"action": "opened",
+		"number": 42,
+		"pull_request": {
+			"title": "Test PR",
+			"head": {"ref": "feature"},
+			"base": {"ref": "main"},
+			"user": {"login": "author"}
+		},
+		"repository": {"full_name": "owner/repo", "clone_url": "https://github.com/owner/repo.git"},
+		"sender": {"login": "actor"}
+	}`
+	githubPRComment = `{
+		"action": "created",
+		"issue": {"number": 42, "pull_request": {"url": "https://api.github.com/repos/owner/repo/pulls/42"}},
+		"comment": {"id": 123, "body": "Please fix this", "user": {"login": "commenter"}},
+		"repository": {"full_name": "owner/repo", "clone_url": "https://github.com/owner/repo.git"},
+		"sender": {"login": "commenter"}
+	}`
+	gitlabMROpened = `{
+		"object_kind": "merge_request",
+		"object_attributes": {
+			"iid": 42,
+			"title": "Test MR",
+			"source_branch": "feature",
+			"target_branch": "main",
+			"action": "open"
+		},
+		"project": {"path_with_namespace": "owner/repo", "git_http_url": "https://gitlab.com/owner/repo.git"},
+		"user": {"username": "actor"}
+	}`
+)
+
+func TestGuessGitHubEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    string
+		wantErr bool
+	}{
+		{name: "pull request", payload: `{"action": "opened", "pull_request": {}}`, want: "pull_request"},
+		{name: "issue comment", payload: `{"issue": {}, "comment": {}}`, want: "issue_comment"},
+		{name: "review comment", payload: `{"pull_request": {}, "comment": {}}`, want: "pull_request_review_comment"},
+		{name: "push", payload: `{"ref": "refs/heads/main", "commits": []}`, wantErr: true},
+		{name: "not json", payload: `nope`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := guessGitHubEvent([]byte(tt.payload))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("guessGitHubEvent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("guessGitHubEvent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizePayload(t *testing.T) {
+	tests := []struct {
+		name      string
+		provider  string
+		eventType string
+		payload   string
+		wantType  event.Type
+		wantActor string
+		wantErr   string
+	}{
+		{name: "github guessed", provider: "github", payload: githubPROpened, wantType: event.TypeMROpened, wantActor: "actor"},
+		{name: "github given", provider: "github", eventType: "pull_request", payload: githubPROpened, wantType: event.TypeMROpened, wantActor: "actor"},
+		{name: "github comment", provider: "github", payload: githubPRComment, wantType: event.TypeMRComment, wantActor: "commenter"},
+		{name: "github unguessable", provider: "github", payload: `{"zen": "hi"}`, wantErr: "pass --event"},
+		{name: "gitlab", provider: "gitlab", payload: gitlabMROpened, wantType: event.TypeMROpened, wantActor: "actor"},
+		{name: "gitlab not json", provider: "gitlab", payload: `nope`, wantErr: "parsing payload"},
+		{name: "unknown provider", provider: "bitbucket", payload: `{}`, wantErr: "unknown provider"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evt, err := normalizePayload(tt.provider, tt.eventType, []byte(tt.payload))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("normalizePayload() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizePayload() error = %v", err)
+			}
+
+			if evt.Type != tt.wantType {
+				t.Errorf("Type = %q, want %q", evt.Type, tt.wantType)
+			}
+			if evt.Provider != tt.provider {
+				t.Errorf("Provider = %q, want %q", evt.Provider, tt.provider)
+			}
+			if evt.RepoOwner != "owner" || evt.RepoName != "repo" || evt.MRNumber != 42 {
+				t.Errorf("MR = %s/%s#%d, want owner/repo#42", evt.RepoOwner, evt.RepoName, evt.MRNumber)
+			}
+			if evt.Actor != tt.wantActor {
+				t.Errorf("Actor = %q, want %q", evt.Actor, tt.wantActor)
+			}
+			if evt.CorrelationID == "" {
+				t.Error("replayed event should get a correlation ID")
+			}
+		})
+	}
+}
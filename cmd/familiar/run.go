@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/agent"
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/event"
+	"github.com/drewdunne/familiar/internal/handler"
+	"github.com/drewdunne/familiar/internal/logging"
+	"github.com/drewdunne/familiar/internal/provider"
+	"github.com/drewdunne/familiar/internal/registry"
+)
+
+func runRun(args []string) {
+	if err := runAgents(args); err != nil {
+		fmt.Fprintf(os.Stderr, "familiar run: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runAgents triggers agents for an MR by hand, as a webhook for it would,
+// and waits for them to finish.
+func runAgents(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "Path to config file")
+	envFile := fs.String("env-file", "", "Path to .env file (optional)")
+	providerName := fs.String("provider", "", "Provider hosting the repo (github, gitlab)")
+	repoPath := fs.String("repo", "", "Repository as owner/name")
+	mrNumber := fs.Int("mr", 0, "Merge request or pull request number")
+	profile := fs.String("prompt-profile", "", "Persona to run instead of the event's prompt")
+	instructions := fs.String("instructions", "", "Instructions for the agent, as if given in a mention")
+	fs.Parse(args)
+
+	// GitLab owners may be nested groups, so the name is the last segment
+	i := strings.LastIndex(*repoPath, "/")
+	if *providerName == "" || i <= 0 || i == len(*repoPath)-1 || *mrNumber <= 0 {
+		return errors.New("--provider, --repo owner/name, and --mr are required")
+	}
+	owner, name := (*repoPath)[:i], (*repoPath)[i+1:]
+
+	loadEnv(*envFile)
+
+	cfg, err := config.Load(configFile(fs, *configPath))
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	logger, err := logging.NewServerLogger(os.Stderr, cfg.Logging.Level, cfg.Logging.Format)
+	if err != nil {
+		return fmt.Errorf("invalid logging config: %w", err)
+	}
+	slog.SetDefault(logger)
+	if err := translateHostPaths(cfg); err != nil {
+		return fmt.Errorf("invalid host path: %w", err)
+	}
+
+	reg := registry.New(cfg)
+	prov := reg.Get(*providerName)
+	if prov == nil {
+		return fmt.Errorf("no %s provider configured", *providerName)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	evt, err := mrEvent(ctx, prov, owner, name, *mrNumber, *instructions)
+	if err != nil {
+		return err
+	}
+
+	// Repo configs aren't fetched for webhooks yet either
+	merged, err := config.MergeConfigs(cfg, &config.RepoConfig{})
+	if err != nil {
+		return fmt.Errorf("merging config: %w", err)
+	}
+	if *profile != "" {
+		persona, err := findPersona(merged.Personas, *profile)
+		if err != nil {
+			return err
+		}
+		merged.Personas = []config.PersonaConfig{persona}
+	}
+
+	return handleAndWait(ctx, cfg, reg, func(h *handler.AgentHandler) error {
+		return h.Handle(ctx, evt, merged, nil)
+	})
+}
+
+// handleAndWait gives handle an agent handler that spawns agents directly,
+// then waits for every agent it started to finish, stopping them if ctx is
+// cancelled.
+func handleAndWait(ctx context.Context, cfg *config.Config, reg *registry.Registry, handle func(*handler.AgentHandler) error) error {
+	spawner, err := newSpawner(cfg)
+	if err != nil {
+		return fmt.Errorf("creating agent spawner: %w", err)
+	}
+	defer spawner.Close()
+
+	tracker := &agentTracker{Spawner: spawner, pending: make(map[string]bool)}
+	opts := []handler.Option{
+		handler.WithHooks(hookRunner(cfg)),
+		handler.WithLogIndex(logging.NewIndex(cfg.Logging.Dir)),
+		handler.WithCredentials(cfg.Agents.Credentials),
+	}
+	if cfg.Logging.AuditFile != "" {
+		opts = append(opts, handler.WithAuditLog(logging.NewAuditLog(cfg.Logging.AuditFile)))
+	}
+	agentHandler := handler.NewAgentHandler(tracker, newRepoCache(cfg), reg, cfg.Logging.Dir, cfg.Logging.HostDir, opts...)
+	spawner.OnTimeout = tracker.after(agentHandler.HandleTimeout)
+	spawner.OnExit = tracker.after(agentHandler.HandleExit)
+	spawner.OnFailure = tracker.after(agentHandler.HandleFailure)
+
+	// Agents that started before a failure still run to completion
+	handleErr := handle(agentHandler)
+
+	finished := make(chan struct{})
+	go func() {
+		tracker.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		for _, session := range spawner.ListSessions() {
+			spawner.Terminate(context.Background(), session.ID, "interrupted")
+		}
+		<-finished
+	}
+	return handleErr
+}
+
+// mrEvent builds the event a webhook would deliver for the MR: an opened MR,
+// or a mention carrying instructions.
+func mrEvent(ctx context.Context, prov provider.Provider, owner, name string, number int, instructions string) (*event.Event, error) {
+	repo, err := prov.GetRepository(ctx, owner, name)
+	if err != nil {
+		return nil, err
+	}
+	mr, err := prov.GetMergeRequest(ctx, owner, name, number)
+	if err != nil {
+		return nil, err
+	}
+
+	evt := &event.Event{
+		Type:          event.TypeMROpened,
+		Provider:      prov.Name(),
+		RepoOwner:     owner,
+		RepoName:      name,
+		RepoURL:       repo.CloneURL,
+		MRNumber:      mr.Number,
+		MRTitle:       mr.Title,
+		MRDescription: mr.Description,
+		SourceBranch:  mr.SourceBranch,
+		TargetBranch:  mr.TargetBranch,
+		FromFork:      mr.FromFork,
+		Actor:         mr.Author,
+		Timestamp:     time.Now(),
+		CorrelationID: newRunID(),
+	}
+	if instructions != "" {
+		evt.Type = event.TypeMention
+		evt.CommentBody = instructions
+		evt.CommentAuthor = os.Getenv("USER")
+		evt.Actor = evt.CommentAuthor
+	}
+	return evt, nil
+}
+
+// findPersona returns the named persona, set to run for any event.
+func findPersona(personas []config.PersonaConfig, name string) (config.PersonaConfig, error) {
+	var names []string
+	for _, p := range personas {
+		if p.Name == name {
+			p.Events = nil
+			return p, nil
+		}
+		names = append(names, p.Name)
+	}
+	if len(names) == 0 {
+		return config.PersonaConfig{}, fmt.Errorf("unknown prompt profile %q (no personas configured)", name)
+	}
+	return config.PersonaConfig{}, fmt.Errorf("unknown prompt profile %q (want %s)", name, strings.Join(names, ", "))
+}
+
+// newRunID returns a correlation ID for a manual run.
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// agentTracker counts the agents a run spawns until the handler has cleaned
+// up after each of them.
+type agentTracker struct {
+	*agent.Spawner
+
+	mu      sync.Mutex
+	pending map[string]bool
+	wg      sync.WaitGroup
+}
+
+func (t *agentTracker) Spawn(ctx context.Context, req agent.SpawnRequest) (*agent.Session, error) {
+	// Counted first, since a fast agent can exit before Spawn returns
+	t.mu.Lock()
+	t.pending[req.ID] = true
+	t.wg.Add(1)
+	t.mu.Unlock()
+
+	session, err := t.Spawner.Spawn(ctx, req)
+	if err != nil {
+		t.done(req.ID)
+	}
+	return session, err
+}
+
+// after wraps a spawner callback to mark the session done once it returns.
+func (t *agentTracker) after(handle func(*agent.Session)) func(*agent.Session) {
+	return func(session *agent.Session) {
+		handle(session)
+		t.done(session.ID)
+	}
+}
+
+// done marks the agent finished. A session can be handed to more than one
+// callback, e.g. if it exits while being terminated, so repeats are ignored.
+func (t *agentTracker) done(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pending[id] {
+		delete(t.pending, id)
+		t.wg.Done()
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/drewdunne/familiar/internal/server"
+	"github.com/drewdunne/familiar/internal/systemd"
+)
+
+// notifySystemd tells systemd when srv is ready and pings the watchdog
+// while alive passes, so a Type=notify unit is ordered correctly and
+// restarted if the server wedges. Outside systemd it does nothing. The
+// returned func stops the watchdog.
+func notifySystemd(srv *server.Server, alive func(ctx context.Context) error) (stop func()) {
+	if os.Getenv("NOTIFY_SOCKET") == "" {
+		return func() {}
+	}
+
+	go func() {
+		<-srv.Ready()
+		if _, err := systemd.Notify(systemd.StateReady + "\n" + systemd.Status("serving")); err != nil {
+			slog.Warn("failed to notify systemd of readiness", "error", err)
+		}
+	}()
+
+	interval, err := systemd.WatchdogInterval()
+	if err != nil {
+		slog.Warn("ignoring systemd watchdog", "error", err)
+	}
+	if interval <= 0 {
+		return func() {}
+	}
+	watchdog := systemd.NewWatchdog(interval, alive)
+	watchdog.Start()
+	slog.Info("pinging systemd watchdog", "interval", interval)
+	return watchdog.Stop
+}
+
+// notifyStopping tells systemd the server is shutting down, so it reports
+// the unit as deactivating while agents are stopped.
+func notifyStopping(context.Context) {
+	if _, err := systemd.Notify(systemd.StateStopping + "\n" + systemd.Status("stopping agents")); err != nil {
+		slog.Warn("failed to notify systemd of shutdown", "error", err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/webhook"
+)
+
+func runTestWebhook(args []string) {
+	if err := sendTestWebhook(args); err != nil {
+		fmt.Fprintf(os.Stderr, "familiar test-webhook: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// sendTestWebhook signs a sample event with the configured webhook secret
+// and delivers it to a running server.
+func sendTestWebhook(args []string) error {
+	fs := flag.NewFlagSet("test-webhook", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "Path to config file")
+	envFile := fs.String("env-file", "", "Path to .env file (optional)")
+	serverURL := fs.String("server", "", "Familiar server URL (default: from the config's server host and port)")
+	providerName := fs.String("provider", "", "Provider to impersonate (github, gitlab)")
+	kind := fs.String("event", webhook.SampleMention, "Event to send: "+strings.Join(webhook.SampleKinds, ", "))
+	var opts webhook.SampleOptions
+	fs.StringVar(&opts.Repo, "repo", "", "Repository as owner/name (default: familiar-test/sample)")
+	fs.IntVar(&opts.MR, "mr", 0, "Merge request number (default: 1)")
+	fs.StringVar(&opts.Comment, "comment", "", "Comment text for note, mention, and line events")
+	fs.StringVar(&opts.File, "file", "", "File a line comment is on (default: README.md)")
+	fs.IntVar(&opts.Line, "line", 0, "Line a line comment is on (default: 1)")
+	fs.StringVar(&opts.Author, "author", "", "Username the event comes from (default: familiar-tester)")
+	fs.StringVar(&opts.SourceBranch, "source-branch", "", "MR source branch (default: feature)")
+	fs.StringVar(&opts.TargetBranch, "target-branch", "", "MR target branch (default: main)")
+	fs.Parse(args)
+
+	if *providerName == "" {
+		return fmt.Errorf("--provider is required")
+	}
+
+	loadEnv(*envFile)
+	cfg, err := config.Load(configFile(fs, *configPath))
+	if err != nil {
+		return err
+	}
+
+	var secret string
+	switch *providerName {
+	case "github":
+		secret = cfg.Providers.GitHub.WebhookSecret
+	case "gitlab":
+		secret = cfg.Providers.GitLab.WebhookSecret
+		opts.BaseURL = cfg.Providers.GitLab.BaseURL
+	default:
+		return fmt.Errorf("unknown provider %q (want github, gitlab)", *providerName)
+	}
+	if secret == "" {
+		return fmt.Errorf("providers.%s.webhook_secret is not set, so the server doesn't accept %s webhooks", *providerName, *providerName)
+	}
+
+	if *serverURL == "" {
+		*serverURL = localServerURL(cfg)
+	}
+	endpoint := strings.TrimSuffix(*serverURL, "/") + "/webhook/" + *providerName
+	req, err := webhook.SampleRequest(*providerName, *kind, secret, endpoint, opts)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	fmt.Printf("POST %s: %s\n", endpoint, resp.Status)
+	if id := resp.Header.Get(webhook.CorrelationHeader); id != "" {
+		fmt.Printf("Correlation ID: %s (search the server logs for correlation_id=%s)\n", id, id)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server rejected the delivery: %s", strings.TrimSpace(string(body)))
+	}
+	return nil
+}
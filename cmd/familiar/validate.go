@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/docker"
+	"github.com/drewdunne/familiar/internal/hostpath"
+	"github.com/drewdunne/familiar/internal/logging"
+	"github.com/drewdunne/familiar/internal/repocache"
+)
+
+// runValidate checks a config file without starting the server: unknown
+// keys, invalid values, missing secrets, and missing paths are all
+// reported, one per line.
+func runValidate(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "Path to config file")
+	envFile := fs.String("env-file", "", "Path to .env file (optional)")
+	fs.Parse(args)
+
+	loadEnv(*envFile)
+
+	path := configFile(fs, *configPath)
+	source := path
+	if source == "" {
+		source = "environment"
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", source, line)
+		}
+		os.Exit(1)
+	}
+
+	problems := validationProblems(cfg)
+	if len(problems) == 0 {
+		fmt.Printf("%s: OK\n", source)
+		return
+	}
+	for _, p := range problems {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", source, p)
+	}
+	os.Exit(1)
+}
+
+// validationProblems returns every problem with cfg, including those only
+// the packages that consume a setting know how to parse.
+func validationProblems(cfg *config.Config) []string {
+	errs := []error{cfg.Validate(), cfg.CheckPaths()}
+	if _, err := docker.ParsePullPolicy(cfg.Agents.PullPolicy); err != nil {
+		errs = append(errs, fmt.Errorf("agents.pull_policy: %w", err))
+	}
+	if _, err := docker.ParseDigest(cfg.Agents.ImageDigest); err != nil {
+		errs = append(errs, fmt.Errorf("agents.image_digest: %w", err))
+	}
+	if _, err := hostpath.ParseStyle(cfg.Agents.HostPathStyle); err != nil {
+		errs = append(errs, fmt.Errorf("agents.host_path_style: %w", err))
+	}
+	if _, err := repocache.ParseWindow(cfg.RepoCache.Maintenance.Window); err != nil {
+		errs = append(errs, fmt.Errorf("repo_cache.maintenance.window: %w", err))
+	}
+	if _, err := logging.NewServerLogger(io.Discard, cfg.Logging.Level, cfg.Logging.Format); err != nil {
+		errs = append(errs, fmt.Errorf("logging: %w", err))
+	}
+	if _, err := logging.NewRedactor(nil, cfg.Logging.RedactPatterns); err != nil {
+		errs = append(errs, fmt.Errorf("logging.redact_patterns: %w", err))
+	}
+
+	var problems []string
+	if err := errors.Join(errs...); err != nil {
+		problems = strings.Split(err.Error(), "\n")
+	}
+	return problems
+}
@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"path"
+	"sort"
+)
+
+// defaultEnvDeny names server-side secrets that never belong in an agent
+// container, whatever the configured filter says.
+var defaultEnvDeny = []string{
+	"ANTHROPIC_API_KEY",
+	"*WEBHOOK_SECRET",
+	"*ADMIN_TOKEN",
+	"*OPS_TOKEN",
+	"FAMILIAR_*", // Server config, including FAMILIAR_ADMIN_TOKEN
+	// Secret store credentials
+	"VAULT_TOKEN*",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+	"GOOGLE_OAUTH_ACCESS_TOKEN",
+	"SOPS_AGE_KEY*",
+}
+
+// credentialEnv names variables whose values are credentials, which are
+// redacted from the agent's captured output.
+var credentialEnv = []string{"*TOKEN*", "*SECRET*", "*PASSWORD*", "*_KEY"}
+
+// EnvFilter decides which environment variables may be passed to agents.
+// Patterns use path.Match syntax (e.g. "GITLAB_*").
+type EnvFilter struct {
+	Allow   []string // If set, only matching names pass
+	Deny    []string // Matching names are dropped (in addition to the defaults)
+	Secrets []string // Values that must never be passed, under any name
+}
+
+// Apply returns the variables that pass the filter and the sorted names of
+// those that were dropped.
+func (f EnvFilter) Apply(env map[string]string) (map[string]string, []string) {
+	if env == nil {
+		return nil, nil
+	}
+
+	secrets := make(map[string]bool, len(f.Secrets))
+	for _, v := range f.Secrets {
+		if v != "" {
+			secrets[v] = true
+		}
+	}
+
+	allowed := make(map[string]string, len(env))
+	var blocked []string
+	for k, v := range env {
+		switch {
+		case matchAny(defaultEnvDeny, k), matchAny(f.Deny, k):
+			blocked = append(blocked, k)
+		case len(f.Allow) > 0 && !matchAny(f.Allow, k):
+			blocked = append(blocked, k)
+		case secrets[v]:
+			blocked = append(blocked, k)
+		default:
+			allowed[k] = v
+		}
+	}
+	sort.Strings(blocked)
+	return allowed, blocked
+}
+
+// matchAny reports whether name matches any of the patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// credentialValues returns the values of env's credential variables.
+func credentialValues(env map[string]string) []string {
+	var values []string
+	for k, v := range env {
+		if matchAny(credentialEnv, k) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
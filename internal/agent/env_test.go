@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestEnvFilter_Apply(t *testing.T) {
+	env := map[string]string{
+		"GITLAB_TOKEN":          "glpat-abc",
+		"GITLAB_HOST":           "gitlab.example.com",
+		"ANTHROPIC_API_KEY":     "sk-ant-123",
+		"GITLAB_WEBHOOK_SECRET": "hook",
+		"OPS_TOKEN":             "ops",
+		"VAULT_TOKEN":           "s.vault",
+		"CUSTOM":                "value",
+	}
+
+	tests := []struct {
+		name        string
+		filter      EnvFilter
+		wantKeys    []string
+		wantBlocked []string
+	}{
+		{
+			name:        "defaults block server secrets",
+			filter:      EnvFilter{},
+			wantKeys:    []string{"CUSTOM", "GITLAB_HOST", "GITLAB_TOKEN"},
+			wantBlocked: []string{"ANTHROPIC_API_KEY", "GITLAB_WEBHOOK_SECRET", "OPS_TOKEN", "VAULT_TOKEN"},
+		},
+		{
+			name:        "allowlist",
+			filter:      EnvFilter{Allow: []string{"GITLAB_*"}},
+			wantKeys:    []string{"GITLAB_HOST", "GITLAB_TOKEN"},
+			wantBlocked: []string{"ANTHROPIC_API_KEY", "CUSTOM", "GITLAB_WEBHOOK_SECRET", "OPS_TOKEN", "VAULT_TOKEN"},
+		},
+		{
+			name:        "allowlist cannot override defaults",
+			filter:      EnvFilter{Allow: []string{"*"}},
+			wantKeys:    []string{"CUSTOM", "GITLAB_HOST", "GITLAB_TOKEN"},
+			wantBlocked: []string{"ANTHROPIC_API_KEY", "GITLAB_WEBHOOK_SECRET", "OPS_TOKEN", "VAULT_TOKEN"},
+		},
+		{
+			name:        "denylist",
+			filter:      EnvFilter{Deny: []string{"CUSTOM"}},
+			wantKeys:    []string{"GITLAB_HOST", "GITLAB_TOKEN"},
+			wantBlocked: []string{"ANTHROPIC_API_KEY", "CUSTOM", "GITLAB_WEBHOOK_SECRET", "OPS_TOKEN", "VAULT_TOKEN"},
+		},
+		{
+			name:        "secret values blocked under any name",
+			filter:      EnvFilter{Secrets: []string{"value", ""}},
+			wantKeys:    []string{"GITLAB_HOST", "GITLAB_TOKEN"},
+			wantBlocked: []string{"ANTHROPIC_API_KEY", "CUSTOM", "GITLAB_WEBHOOK_SECRET", "OPS_TOKEN", "VAULT_TOKEN"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, blocked := tt.filter.Apply(env)
+
+			var keys []string
+			for k := range got {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("allowed = %v, want %v", keys, tt.wantKeys)
+			}
+			if !reflect.DeepEqual(blocked, tt.wantBlocked) {
+				t.Errorf("blocked = %v, want %v", blocked, tt.wantBlocked)
+			}
+		})
+	}
+}
+
+func TestEnvFilter_ApplyNil(t *testing.T) {
+	got, blocked := EnvFilter{}.Apply(nil)
+	if got != nil || blocked != nil {
+		t.Errorf("Apply(nil) = %v, %v; want nil, nil", got, blocked)
+	}
+}
+
+func TestCredentialValues(t *testing.T) {
+	got := credentialValues(map[string]string{
+		"GITLAB_TOKEN":   "glpat-abc",
+		"DB_PASSWORD":    "pw",
+		"CLIENT_SECRET":  "s3cret",
+		"SIGNING_KEY":    "key",
+		"GITLAB_HOST":    "gitlab.example.com",
+		"KEYBOARD_SETUP": "us",
+	})
+	sort.Strings(got)
+
+	want := []string{"glpat-abc", "key", "pw", "s3cret"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("credentialValues() = %v, want %v", got, want)
+	}
+}
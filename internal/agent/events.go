@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/docker"
+)
+
+// eventRetryDelay is how long to wait before resubscribing after the
+// container event stream fails.
+const eventRetryDelay = 5 * time.Second
+
+// startEventWatcher subscribes to agent container events so deaths (crash,
+// OOM, manual removal) are handled as they happen. Returns a function to
+// stop the watcher.
+func (s *Spawner) startEventWatcher() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go s.watchEvents(ctx)
+	return cancel
+}
+
+// watchEvents consumes agent container events until ctx is done,
+// resubscribing whenever the stream fails. Exits missed while disconnected
+// are still caught by checkExited.
+func (s *Spawner) watchEvents(ctx context.Context) {
+	for {
+		eventC, errC := s.events.ContainerEvents(ctx, LabelAgent+"=true")
+		err := s.consumeEvents(ctx, eventC, errC)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("container event subscription ended; resubscribing", "retry_in", eventRetryDelay, "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(eventRetryDelay):
+		}
+	}
+}
+
+// consumeEvents handles events until the stream reports an error.
+func (s *Spawner) consumeEvents(ctx context.Context, eventC <-chan docker.ContainerEvent, errC <-chan error) error {
+	for {
+		select {
+		case event := <-eventC:
+			s.handleContainerEvent(ctx, event)
+		case err := <-errC:
+			return err
+		}
+	}
+}
+
+// handleContainerEvent marks the session owning a dead or removed container
+// as exited. Events for containers Familiar has already stopped are ignored.
+func (s *Spawner) handleContainerEvent(ctx context.Context, event docker.ContainerEvent) {
+	session := s.sessionForContainer(event.ContainerID)
+	if session == nil {
+		return
+	}
+
+	switch event.Action {
+	case docker.EventOOM:
+		slog.Warn("agent ran out of memory", "agent_id", session.ID)
+	case docker.EventDie:
+		s.markExited(ctx, session, event.ExitCode)
+	case docker.EventDestroy:
+		// Removed outside Familiar before its die event was seen
+		s.markExited(ctx, session, -1)
+	}
+}
+
+// sessionForContainer returns the active session running the container.
+func (s *Spawner) sessionForContainer(containerID string) *Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, session := range s.sessions {
+		if session.ContainerID == containerID {
+			return session
+		}
+	}
+	return nil
+}
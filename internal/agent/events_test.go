@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/docker"
+	"github.com/drewdunne/familiar/internal/metrics"
+)
+
+func TestSpawner_HandleContainerEvent(t *testing.T) {
+	tests := []struct {
+		name         string
+		event        docker.ContainerEvent
+		wantExited   bool
+		wantExitCode int
+	}{
+		{"died", docker.ContainerEvent{ContainerID: "c1", Action: docker.EventDie, ExitCode: 137}, true, 137},
+		{"removed", docker.ContainerEvent{ContainerID: "c1", Action: docker.EventDestroy}, true, -1},
+		{"oom before die", docker.ContainerEvent{ContainerID: "c1", Action: docker.EventOOM}, false, 0},
+		{"unknown container", docker.ContainerEvent{ContainerID: "other", Action: docker.EventDie}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spawner := &Spawner{sessions: make(map[string]*Session)}
+			session := &Session{ID: "agent", ContainerID: "c1", Status: "running"}
+			spawner.sessions["agent"] = session
+
+			exited := make(chan *Session, 1)
+			spawner.OnExit = func(s *Session) { exited <- s }
+
+			spawner.handleContainerEvent(context.Background(), tt.event)
+
+			if !tt.wantExited {
+				if session.Status != "running" {
+					t.Errorf("Status = %q, want running", session.Status)
+				}
+				return
+			}
+			select {
+			case s := <-exited:
+				if s.ExitCode != tt.wantExitCode {
+					t.Errorf("ExitCode = %d, want %d", s.ExitCode, tt.wantExitCode)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("OnExit was not called")
+			}
+		})
+	}
+}
+
+type mockEventSource struct {
+	subscriptions chan struct{}
+	eventC        chan docker.ContainerEvent
+	errC          chan error
+}
+
+func (m *mockEventSource) ContainerEvents(_ context.Context, label string) (<-chan docker.ContainerEvent, <-chan error) {
+	if label != LabelAgent+"=true" {
+		panic("unexpected label filter " + label)
+	}
+	m.subscriptions <- struct{}{}
+	return m.eventC, m.errC
+}
+
+func TestSpawner_WatchEvents(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+
+	source := &mockEventSource{
+		subscriptions: make(chan struct{}, 1),
+		eventC:        make(chan docker.ContainerEvent),
+		errC:          make(chan error, 1),
+	}
+	spawner := &Spawner{sessions: make(map[string]*Session), events: source}
+	session := &Session{ID: "agent", ContainerID: "c1", Status: "running"}
+	spawner.sessions["agent"] = session
+
+	exited := make(chan *Session, 1)
+	spawner.OnExit = func(s *Session) { exited <- s }
+
+	stop := spawner.startEventWatcher()
+	defer stop()
+
+	<-source.subscriptions
+	source.eventC <- docker.ContainerEvent{ContainerID: "c1", Action: docker.EventDie, ExitCode: 1}
+
+	select {
+	case s := <-exited:
+		if s.ExitCode != 1 {
+			t.Errorf("ExitCode = %d, want 1", s.ExitCode)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnExit was not called for a die event")
+	}
+}
+
+func TestSpawner_MarkExitedLeavesCountingToOnExit(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+
+	spawner := &Spawner{sessions: make(map[string]*Session)}
+	session := &Session{ID: "agent", Status: "running"}
+	spawner.sessions["agent"] = session
+	exited := make(chan *Session, 1)
+	spawner.OnExit = func(s *Session) { exited <- s }
+
+	spawner.markExited(context.Background(), session, 0)
+	<-exited
+
+	// A clean exit may still fail later, e.g. for changing a protected
+	// path, so the outcome is counted once it is known
+	m := metrics.Get()
+	if m.AgentsCompleted != 0 || m.AgentsFailed != 0 {
+		t.Errorf("completed, failed = %d, %d, want 0, 0", m.AgentsCompleted, m.AgentsFailed)
+	}
+}
+
+func TestSpawner_ConsumeEventsReturnsStreamError(t *testing.T) {
+	spawner := &Spawner{sessions: make(map[string]*Session)}
+	errC := make(chan error, 1)
+	errC <- errors.New("connection reset")
+
+	err := spawner.consumeEvents(context.Background(), make(chan docker.ContainerEvent), errC)
+	if err == nil || err.Error() != "connection reset" {
+		t.Errorf("consumeEvents() = %v, want stream error", err)
+	}
+}
@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"regexp"
+)
+
+// FailureCategory classifies why an agent run failed, separating
+// infrastructure problems from the agent simply not finishing its task.
+type FailureCategory string
+
+const (
+	FailureNone         FailureCategory = ""
+	FailureOOMKilled    FailureCategory = "oom_killed"
+	FailureAuth         FailureCategory = "auth"
+	FailureQuota        FailureCategory = "quota"
+	FailurePushRejected FailureCategory = "push_rejected"
+	FailureTimeout      FailureCategory = "timeout"
+	FailureStuck        FailureCategory = "stuck"
+	FailureTask         FailureCategory = "task"
+)
+
+// Infrastructure reports whether the failure points at the environment
+// (credentials, limits, resources) rather than the task itself.
+func (c FailureCategory) Infrastructure() bool {
+	switch c {
+	case FailureOOMKilled, FailureAuth, FailureQuota:
+		return true
+	}
+	return false
+}
+
+// Description returns a short human-readable explanation.
+func (c FailureCategory) Description() string {
+	switch c {
+	case FailureOOMKilled:
+		return "the agent container ran out of memory"
+	case FailureAuth:
+		return "Claude or the git provider rejected the agent's credentials"
+	case FailureQuota:
+		return "Claude usage limits or quota were exhausted"
+	case FailurePushRejected:
+		return "the git provider rejected the agent's push"
+	case FailureTimeout:
+		return "the agent ran out of time"
+	case FailureStuck:
+		return "the agent stopped making progress"
+	case FailureTask:
+		return "the agent exited with an error"
+	}
+	return ""
+}
+
+// ExitState is how an agent container finished.
+type ExitState struct {
+	ExitCode  int
+	OOMKilled bool
+}
+
+// failurePatterns map known log lines to categories, checked in order.
+var failurePatterns = []struct {
+	category FailureCategory
+	pattern  *regexp.Regexp
+}{
+	{FailureAuth, regexp.MustCompile(`(?i)invalid api key|authentication_error|oauth token has expired|please run /login|` +
+		`authentication failed for 'http|could not read username for 'http|http basic: access denied`)},
+	{FailureQuota, regexp.MustCompile(`(?i)usage limit reached|rate_limit_error|credit balance is too low|exceeded your current quota`)},
+	{FailurePushRejected, regexp.MustCompile(`(?i)failed to push some refs|! \[remote rejected\]|! \[rejected\]|pre-receive hook declined|protected branch hook declined`)},
+}
+
+// ClassifyFailure categorizes a finished run from its exit state and output.
+// A run that exited cleanly has not failed, whatever its output mentions.
+// Otherwise known log patterns refine the failure, so a run that could not
+// authenticate or push is reported as such.
+func ClassifyFailure(exit ExitState, output []byte) FailureCategory {
+	if exit.OOMKilled {
+		return FailureOOMKilled
+	}
+	if exit.ExitCode == 0 {
+		return FailureNone
+	}
+	if category := matchFailure(output); category != FailureNone {
+		return category
+	}
+	return FailureTask
+}
+
+// transcriptTypes are the stream-json records that carry the conversation:
+// the agent's messages and the tool results it read, which may quote anything.
+var transcriptTypes = map[string]bool{"system": true, "assistant": true, "user": true}
+
+// matchFailure returns the first failure pattern found in output, ignoring
+// the transcript so only Claude CLI's own errors and result records count.
+func matchFailure(output []byte) FailureCategory {
+	var records [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var record struct {
+			Type string `json:"type"`
+		}
+		if line[0] == '{' && json.Unmarshal(line, &record) == nil && transcriptTypes[record.Type] {
+			continue
+		}
+		records = append(records, line)
+	}
+	if len(records) == 0 {
+		return FailureNone
+	}
+
+	text := bytes.Join(records, []byte("\n"))
+	for _, fp := range failurePatterns {
+		if fp.pattern.Match(text) {
+			return fp.category
+		}
+	}
+	return FailureNone
+}
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/drewdunne/familiar/internal/metrics"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		exit   ExitState
+		output string
+		want   FailureCategory
+	}{
+		{"success", ExitState{}, `{"type":"result","subtype":"success"}`, FailureNone},
+		{"empty output", ExitState{}, "", FailureNone},
+		{"task failure", ExitState{ExitCode: 1}, "something went wrong", FailureTask},
+		{"oom killed", ExitState{ExitCode: 137, OOMKilled: true}, "Invalid API key", FailureOOMKilled},
+		{"killed without oom", ExitState{ExitCode: 137}, "", FailureTask},
+		{"claude auth", ExitState{ExitCode: 1}, "Invalid API key · Please run /login", FailureAuth},
+		{"expired oauth", ExitState{ExitCode: 1}, `{"error":"OAuth token has expired"}`, FailureAuth},
+		{"git auth", ExitState{ExitCode: 1}, "fatal: Authentication failed for 'https://gitlab.com/o/r.git/'", FailureAuth},
+		{"quota", ExitState{ExitCode: 1}, "Claude AI usage limit reached|1700000000", FailureQuota},
+		{"rate limit", ExitState{ExitCode: 1}, `{"type":"error","error":{"type":"rate_limit_error"}}`, FailureQuota},
+		{"push rejected", ExitState{ExitCode: 1}, " ! [rejected]        feature -> feature (non-fast-forward)\nerror: failed to push some refs", FailurePushRejected},
+		{"protected branch", ExitState{ExitCode: 1}, "remote: GitLab: You are not allowed to push code to protected branches on this project.\n ! [remote rejected] main -> main (pre-receive hook declined)", FailurePushRejected},
+		{"clean exit mentioning failures", ExitState{},
+			`{"type":"user","message":{"content":[{"type":"tool_result","content":"401 Unauthorized: Invalid API key\n ! [rejected] main -> main"}]}}` + "\n" +
+				`{"type":"result","subtype":"success","is_error":false,"result":"Fixed the retry on 401 responses"}`,
+			FailureNone},
+		{"failure quoted only in transcript", ExitState{ExitCode: 1},
+			`{"type":"user","message":{"content":[{"type":"tool_result","content":"usage limit reached"}]}}` + "\n" +
+				`{"type":"result","subtype":"error_during_execution","is_error":true}`,
+			FailureTask},
+		{"failure in result record", ExitState{ExitCode: 1},
+			`{"type":"assistant","message":{"content":[{"type":"text","text":"Pushing now"}]}}` + "\n" +
+				`{"type":"result","subtype":"success","is_error":true,"result":"Credit balance is too low"}`,
+			FailureQuota},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyFailure(tt.exit, []byte(tt.output)); got != tt.want {
+				t.Errorf("ClassifyFailure() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailureCategory_Infrastructure(t *testing.T) {
+	infra := []FailureCategory{FailureOOMKilled, FailureAuth, FailureQuota}
+	task := []FailureCategory{FailureNone, FailureTask, FailurePushRejected, FailureTimeout, FailureStuck}
+
+	for _, c := range infra {
+		if !c.Infrastructure() {
+			t.Errorf("%q.Infrastructure() = false, want true", c)
+		}
+		if c.Description() == "" {
+			t.Errorf("%q has no description", c)
+		}
+	}
+	for _, c := range task {
+		if c.Infrastructure() {
+			t.Errorf("%q.Infrastructure() = true, want false", c)
+		}
+	}
+}
+
+func TestSpawner_RecordFailure(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+
+	spawner := &Spawner{sessions: make(map[string]*Session)}
+	session := &Session{ID: "agent"}
+
+	spawner.recordFailure(session, FailureNone)
+	spawner.recordFailure(session, FailureAuth)
+	spawner.recordFailure(session, FailureTimeout) // first category wins
+
+	if session.FailureCategory != FailureAuth {
+		t.Errorf("FailureCategory = %q, want %q", session.FailureCategory, FailureAuth)
+	}
+	got := metrics.Get().FailureCategories
+	if len(got) != 1 || got["auth"] != 1 {
+		t.Errorf("FailureCategories = %v, want map[auth:1]", got)
+	}
+}
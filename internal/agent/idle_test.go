@@ -0,0 +1,491 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/docker"
+	"github.com/drewdunne/familiar/internal/metrics"
+)
+
+type mockProbe struct {
+	procs    []string
+	procsErr error
+	size     int64
+	statErr  error
+	exited   bool
+	health   string
+	stats    *docker.ContainerStats
+	statsErr error
+
+	bootstrapping bool
+	onProcs       func() // called as processes are listed
+}
+
+func (m *mockProbe) Stats(_ context.Context, _ string) (*docker.ContainerStats, error) {
+	return m.stats, m.statsErr
+}
+
+func (m *mockProbe) ContainerProcesses(_ context.Context, _ string) ([]string, error) {
+	if m.onProcs != nil {
+		m.onProcs()
+	}
+	return m.procs, m.procsErr
+}
+
+func (m *mockProbe) InspectContainer(_ context.Context, _ string) (*docker.ContainerInspect, error) {
+	return &docker.ContainerInspect{Running: !m.exited, Health: m.health}, nil
+}
+
+func (m *mockProbe) StatPath(_ context.Context, _, path string) (docker.PathStat, error) {
+	if path == bootstrapMarker {
+		if m.bootstrapping {
+			return docker.PathStat{}, nil
+		}
+		return docker.PathStat{}, errors.New("no such file")
+	}
+	return docker.PathStat{Size: m.size}, m.statErr
+}
+
+var healthyProcs = []string{
+	"/bin/sh -c mkdir -p /home/agent/.claude",
+	"tmux new-session -d -s claude",
+	"node /usr/local/bin/claude --dangerously-skip-permissions -p",
+}
+
+func TestSpawner_IdleReason(t *testing.T) {
+	idle := 10 * time.Minute
+	now := time.Now()
+
+	tests := []struct {
+		name         string
+		probe        *mockProbe
+		startedAgo   time.Duration
+		lastActivity time.Duration
+		outputSize   int64
+		procsMissing time.Duration // 0 means not previously missing
+		wantStuck    bool
+	}{
+		{
+			name:         "recent output",
+			probe:        &mockProbe{procs: healthyProcs, size: 100},
+			startedAgo:   20 * time.Minute,
+			lastActivity: time.Minute,
+			outputSize:   100,
+		},
+		{
+			name:         "output grew since last check",
+			probe:        &mockProbe{procs: healthyProcs, size: 200},
+			startedAgo:   20 * time.Minute,
+			lastActivity: 15 * time.Minute,
+			outputSize:   100,
+		},
+		{
+			name:         "no output for idle window",
+			probe:        &mockProbe{procs: healthyProcs, size: 100},
+			startedAgo:   20 * time.Minute,
+			lastActivity: 11 * time.Minute,
+			outputSize:   100,
+			wantStuck:    true,
+		},
+		{
+			name:         "claude missing for the first time",
+			probe:        &mockProbe{procs: healthyProcs[:2], size: 100},
+			startedAgo:   5 * time.Minute,
+			lastActivity: time.Minute,
+			outputSize:   100,
+		},
+		{
+			name:         "claude missing beyond grace",
+			probe:        &mockProbe{procs: healthyProcs[:2], size: 100},
+			startedAgo:   5 * time.Minute,
+			lastActivity: time.Minute,
+			outputSize:   100,
+			procsMissing: 2 * time.Minute,
+			wantStuck:    true,
+		},
+		{
+			name:         "tmux missing beyond grace",
+			probe:        &mockProbe{procs: healthyProcs[:1], size: 100},
+			startedAgo:   5 * time.Minute,
+			lastActivity: time.Minute,
+			outputSize:   100,
+			procsMissing: 2 * time.Minute,
+			wantStuck:    true,
+		},
+		{
+			name:         "bootstrap still running",
+			probe:        &mockProbe{procs: healthyProcs[:1], size: 0, bootstrapping: true},
+			startedAgo:   20 * time.Minute,
+			lastActivity: 15 * time.Minute,
+			procsMissing: 5 * time.Minute,
+		},
+		{
+			name:         "probe errors are not treated as stuck",
+			probe:        &mockProbe{procsErr: errors.New("daemon busy"), statErr: errors.New("daemon busy")},
+			startedAgo:   5 * time.Minute,
+			lastActivity: time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spawner := &Spawner{sessions: make(map[string]*Session), probe: tt.probe}
+			session := &Session{
+				ID:           "agent",
+				Status:       "running",
+				StartedAt:    now.Add(-tt.startedAgo),
+				lastActivity: now.Add(-tt.lastActivity),
+				outputSize:   tt.outputSize,
+			}
+			if tt.procsMissing > 0 {
+				session.procsMissing = now.Add(-tt.procsMissing)
+			}
+
+			reason := spawner.idleReason(context.Background(), session, idle, now)
+			if stuck := reason != ""; stuck != tt.wantStuck {
+				t.Errorf("idleReason() = %q, want stuck=%v", reason, tt.wantStuck)
+			}
+		})
+	}
+}
+
+func TestSpawner_IdleReason_RecoveredProcessesResetGrace(t *testing.T) {
+	now := time.Now()
+	spawner := &Spawner{sessions: make(map[string]*Session), probe: &mockProbe{procs: healthyProcs}}
+	session := &Session{
+		ID:           "agent",
+		StartedAt:    now.Add(-5 * time.Minute),
+		lastActivity: now,
+		procsMissing: now.Add(-30 * time.Second),
+	}
+
+	if reason := spawner.idleReason(context.Background(), session, time.Hour, now); reason != "" {
+		t.Errorf("idleReason() = %q, want empty", reason)
+	}
+	if !session.procsMissing.IsZero() {
+		t.Error("procsMissing should reset once tmux and claude are seen again")
+	}
+}
+
+func TestSpawner_IdleReason_ProbesWithoutLock(t *testing.T) {
+	probe := &mockProbe{procs: healthyProcs}
+	spawner := &Spawner{sessions: make(map[string]*Session), probe: probe}
+	session := &Session{ID: "agent", Status: "running", StartedAt: time.Now(), lastActivity: time.Now()}
+
+	// Other callers must be able to take the lock while Docker is probed
+	probe.onProcs = func() {
+		locked := make(chan struct{})
+		go func() {
+			spawner.mu.Lock()
+			spawner.mu.Unlock()
+			close(locked)
+		}()
+		select {
+		case <-locked:
+		case <-time.After(time.Second):
+			t.Error("spawner lock held while probing the container")
+		}
+	}
+
+	spawner.idleReason(context.Background(), session, time.Hour, time.Now())
+}
+
+func TestSpawner_CheckIdle_Disabled(t *testing.T) {
+	probe := &mockProbe{procs: nil}
+	spawner := &Spawner{sessions: make(map[string]*Session), probe: probe}
+	spawner.sessions["agent"] = &Session{ID: "agent", Status: "running", StartedAt: time.Now().Add(-time.Hour)}
+
+	// IdleMinutes == 0 must never terminate (Stop would panic without a docker client)
+	spawner.checkIdle(context.Background())
+
+	if spawner.sessions["agent"].Status != "running" {
+		t.Errorf("Status = %q, want running", spawner.sessions["agent"].Status)
+	}
+}
+
+func TestSpawner_CheckIdle_SkipsInteractive(t *testing.T) {
+	probe := &mockProbe{procs: nil}
+	spawner := &Spawner{cfg: SpawnerConfig{IdleMinutes: 1}, sessions: make(map[string]*Session), probe: probe}
+	spawner.sessions["agent"] = &Session{
+		ID:           "agent",
+		Status:       "running",
+		Interactive:  true,
+		StartedAt:    time.Now().Add(-time.Hour),
+		lastActivity: time.Now().Add(-time.Hour),
+	}
+
+	// An attached developer may be idle for a while; never terminate
+	spawner.checkIdle(context.Background())
+
+	if spawner.sessions["agent"].Status != "running" {
+		t.Errorf("Status = %q, want running", spawner.sessions["agent"].Status)
+	}
+}
+
+func TestSpawner_CheckIdle_HandsStuckSessionToOnFailure(t *testing.T) {
+	probe := &mockProbe{procs: healthyProcs, size: 100}
+	spawner := &Spawner{cfg: SpawnerConfig{IdleMinutes: 1}, sessions: make(map[string]*Session), probe: probe}
+	spawner.sessions["agent"] = &Session{
+		ID:           "agent",
+		Status:       "running",
+		StartedAt:    time.Now().Add(-time.Hour),
+		lastActivity: time.Now().Add(-time.Hour),
+		outputSize:   100,
+	}
+
+	failed := make(chan *Session, 1)
+	spawner.OnFailure = func(s *Session) { failed <- s }
+
+	spawner.checkIdle(context.Background())
+
+	select {
+	case s := <-failed:
+		if s.Status != "failed" || s.FailureCategory != FailureStuck {
+			t.Errorf("OnFailure session status = %q, category = %q; want failed, %q", s.Status, s.FailureCategory, FailureStuck)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnFailure was not called for the stuck session")
+	}
+}
+
+func TestSpawner_CheckIdle_SkipsSessionThatExitedDuringProbe(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+
+	probe := &mockProbe{size: 100}
+	spawner := &Spawner{cfg: SpawnerConfig{IdleMinutes: 1}, sessions: make(map[string]*Session), probe: probe}
+	session := &Session{
+		ID:           "agent",
+		Status:       "running",
+		StartedAt:    time.Now().Add(-time.Hour),
+		lastActivity: time.Now().Add(-time.Hour),
+		outputSize:   100,
+	}
+	spawner.sessions["agent"] = session
+
+	// The container exits, and markExited runs, while it is being probed
+	probe.onProcs = func() {
+		spawner.mu.Lock()
+		session.Status = "completed"
+		spawner.mu.Unlock()
+	}
+	failed := make(chan *Session, 1)
+	spawner.OnFailure = func(s *Session) { failed <- s }
+
+	spawner.checkIdle(context.Background())
+
+	if session.Status != "completed" || session.FailureCategory != FailureNone {
+		t.Errorf("Status = %q, category = %q; want completed and no failure", session.Status, session.FailureCategory)
+	}
+	if got := metrics.Get().AgentsFailed; got != 0 {
+		t.Errorf("AgentsFailed = %d, want 0", got)
+	}
+	select {
+	case <-failed:
+		t.Error("OnFailure called for a session that already exited")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSpawner_Terminate(t *testing.T) {
+	spawner := &Spawner{sessions: make(map[string]*Session)}
+	spawner.sessions["agent"] = &Session{ID: "agent", Status: "running"}
+
+	failed := make(chan *Session, 1)
+	spawner.OnFailure = func(s *Session) { failed <- s }
+
+	if err := spawner.Terminate(context.Background(), "missing", "stopped by admin"); err == nil {
+		t.Error("Terminate() should error for an unknown session")
+	}
+	spawner.sessions["done"] = &Session{ID: "done", Status: "completed"}
+	if err := spawner.Terminate(context.Background(), "done", "stopped by admin"); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Terminate() error = %v for a finished session, want ErrNotRunning", err)
+	}
+	if status := spawner.sessions["done"].Status; status != "completed" {
+		t.Errorf("finished session status = %q, want completed", status)
+	}
+	if err := spawner.Terminate(context.Background(), "agent", "stopped by admin"); err != nil {
+		t.Fatalf("Terminate() error = %v", err)
+	}
+
+	select {
+	case s := <-failed:
+		if s.Status != "failed" || s.FailureReason != "stopped by admin" {
+			t.Errorf("OnFailure session status = %q, reason = %q; want failed, stopped by admin", s.Status, s.FailureReason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnFailure was not called for the terminated session")
+	}
+}
+
+func TestIsProcess(t *testing.T) {
+	tests := []struct {
+		cmdline string
+		name    string
+		want    bool
+	}{
+		{"tmux new-session -d -s claude", "tmux", true},
+		{"tmux new-session -d -s claude", "claude", false},
+		{"claude --dangerously-skip-permissions", "claude", true},
+		{"node /usr/local/bin/claude -p", "claude", true},
+		{"/bin/sh -c claude --dangerously-skip-permissions", "claude", false},
+		{"", "claude", false},
+	}
+
+	for _, tt := range tests {
+		if got := isProcess(tt.cmdline, tt.name); got != tt.want {
+			t.Errorf("isProcess(%q, %q) = %v, want %v", tt.cmdline, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSpawner_CheckExited(t *testing.T) {
+	probe := &mockProbe{exited: true}
+	spawner := &Spawner{sessions: make(map[string]*Session), probe: probe}
+	spawner.sessions["done"] = &Session{ID: "done", Status: "running"}
+	spawner.sessions["timing-out"] = &Session{ID: "timing-out", Status: "timed_out"}
+
+	exited := make(chan *Session, 2)
+	spawner.OnExit = func(s *Session) { exited <- s }
+
+	spawner.checkExited(context.Background())
+
+	select {
+	case s := <-exited:
+		if s.ID != "done" {
+			t.Errorf("OnExit session = %q, want %q", s.ID, "done")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnExit was not called for the exited session")
+	}
+	if got := spawner.sessions["done"].Status; got != "exited" {
+		t.Errorf("Status = %q, want exited", got)
+	}
+
+	// Sessions already being handled are left alone
+	select {
+	case s := <-exited:
+		t.Errorf("OnExit called for %q, want only running sessions", s.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSpawner_CheckExited_StillRunning(t *testing.T) {
+	spawner := &Spawner{sessions: make(map[string]*Session), probe: &mockProbe{}}
+	spawner.sessions["agent"] = &Session{ID: "agent", Status: "running"}
+	spawner.OnExit = func(s *Session) { t.Errorf("OnExit called for running session %q", s.ID) }
+
+	spawner.checkExited(context.Background())
+
+	if got := spawner.sessions["agent"].Status; got != "running" {
+		t.Errorf("Status = %q, want running", got)
+	}
+}
+
+type mockWaiter struct {
+	exitC chan docker.ContainerExit
+	errC  chan error
+}
+
+func newMockWaiter() *mockWaiter {
+	return &mockWaiter{exitC: make(chan docker.ContainerExit, 1), errC: make(chan error, 1)}
+}
+
+func (m *mockWaiter) WaitContainer(_ context.Context, _ string) (<-chan docker.ContainerExit, <-chan error) {
+	return m.exitC, m.errC
+}
+
+func TestSpawner_WaitExit(t *testing.T) {
+	waiter := newMockWaiter()
+	spawner := &Spawner{sessions: make(map[string]*Session), waiter: waiter}
+	session := &Session{ID: "agent", Status: "running", done: make(chan struct{})}
+	spawner.sessions["agent"] = session
+
+	exited := make(chan *Session, 1)
+	spawner.OnExit = func(s *Session) { exited <- s }
+
+	go spawner.waitExit(session)
+	waiter.exitC <- docker.ContainerExit{ExitCode: 3}
+
+	select {
+	case s := <-exited:
+		if s.ExitCode != 3 {
+			t.Errorf("ExitCode = %d, want 3", s.ExitCode)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnExit was not called when the container exited")
+	}
+	if session.Status != "exited" {
+		t.Errorf("Status = %q, want exited", session.Status)
+	}
+}
+
+func TestSpawner_WaitExit_StoppedSessionIgnored(t *testing.T) {
+	waiter := newMockWaiter()
+	spawner := &Spawner{sessions: make(map[string]*Session), waiter: waiter}
+	session := &Session{ID: "agent", Status: "running", done: make(chan struct{})}
+	spawner.OnExit = func(s *Session) { t.Errorf("OnExit called for stopped session %q", s.ID) }
+
+	// Stopped sessions are no longer tracked; a late exit must not be handled
+	waiter.exitC <- docker.ContainerExit{ExitCode: 137}
+	spawner.waitExit(session)
+
+	if session.Status != "running" {
+		t.Errorf("Status = %q, want unchanged", session.Status)
+	}
+}
+
+func TestSpawner_WaitExit_ReturnsWhenStopped(t *testing.T) {
+	spawner := &Spawner{sessions: make(map[string]*Session), waiter: newMockWaiter()}
+	session := &Session{ID: "agent", Status: "running", done: make(chan struct{})}
+	spawner.sessions["agent"] = session
+
+	returned := make(chan struct{})
+	go func() {
+		spawner.waitExit(session)
+		close(returned)
+	}()
+	close(session.done)
+
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("waitExit did not return after the session stopped")
+	}
+}
+
+func TestSpawner_UpdateHealth(t *testing.T) {
+	tests := []struct {
+		name       string
+		health     string
+		status     string
+		wantFailed bool
+		wantHealth string
+	}{
+		{"no healthcheck", "", "running", false, ""},
+		{"starting", docker.HealthStarting, "running", false, docker.HealthStarting},
+		{"healthy", docker.HealthHealthy, "running", false, docker.HealthHealthy},
+		{"unhealthy", docker.HealthUnhealthy, "running", true, docker.HealthUnhealthy},
+		{"already timed out", docker.HealthUnhealthy, "timed_out", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spawner := &Spawner{sessions: make(map[string]*Session), probe: &mockProbe{health: tt.health}}
+			session := &Session{ID: "agent", Status: tt.status}
+			spawner.sessions["agent"] = session
+
+			if got := spawner.updateHealth(context.Background(), session); got != tt.wantFailed {
+				t.Errorf("updateHealth() = %v, want %v", got, tt.wantFailed)
+			}
+			if session.Health != tt.wantHealth {
+				t.Errorf("Health = %q, want %q", session.Health, tt.wantHealth)
+			}
+			if tt.wantFailed && (session.Status != "failed" || session.FailureCategory != FailureStuck) {
+				t.Errorf("Status = %q, FailureCategory = %q; want failed/stuck", session.Status, session.FailureCategory)
+			}
+		})
+	}
+}
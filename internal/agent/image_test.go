@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/drewdunne/familiar/internal/docker"
+)
+
+type mockImages struct {
+	identity *docker.ImageIdentity
+	err      error
+}
+
+func (m *mockImages) InspectImage(_ context.Context, _ string) (*docker.ImageIdentity, error) {
+	return m.identity, m.err
+}
+
+func TestSpawner_ResolveImage(t *testing.T) {
+	pinned := "sha256:" + strings.Repeat("a", 64)
+	identity := &docker.ImageIdentity{
+		ID:          "sha256:" + strings.Repeat("f", 64),
+		RepoDigests: []string{"ghcr.io/org/agent@" + pinned},
+	}
+
+	tests := []struct {
+		name    string
+		cfg     SpawnerConfig
+		images  *mockImages
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "no pin uses the tag",
+			cfg:    SpawnerConfig{Image: "ghcr.io/org/agent:latest"},
+			images: &mockImages{err: errors.New("should not inspect")},
+			want:   "ghcr.io/org/agent:latest",
+		},
+		{
+			name:   "matching pin uses the image ID",
+			cfg:    SpawnerConfig{Image: "ghcr.io/org/agent:latest", ImageDigest: pinned},
+			images: &mockImages{identity: identity},
+			want:   identity.ID,
+		},
+		{
+			name:   "digest reference is verified",
+			cfg:    SpawnerConfig{Image: "ghcr.io/org/agent@" + pinned},
+			images: &mockImages{identity: identity},
+			want:   identity.ID,
+		},
+		{
+			name:    "mismatch refuses",
+			cfg:     SpawnerConfig{Image: "ghcr.io/org/agent:latest", ImageDigest: "sha256:" + strings.Repeat("b", 64)},
+			images:  &mockImages{identity: identity},
+			wantErr: true,
+		},
+		{
+			name:    "missing image refuses",
+			cfg:     SpawnerConfig{Image: "ghcr.io/org/agent:latest", ImageDigest: pinned},
+			images:  &mockImages{err: errors.New("no such image")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Spawner{cfg: tt.cfg, images: tt.images}
+			got, err := s.resolveImage(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveImage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveImage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package agent
+
+import "strconv"
+
+// Labels set on agent containers, so operators can filter `docker ps` and
+// sessions can be reconstructed from running containers.
+const (
+	LabelAgent       = "familiar.agent"
+	LabelAgentID     = "familiar.agent.id"
+	LabelRepo        = "familiar.repo"    // owner/name
+	LabelMR          = "familiar.mr"      // MR number
+	LabelEvent       = "familiar.event"   // event type that triggered the agent
+	LabelPersona     = "familiar.persona" // prompt profile, if any
+	LabelInteractive = "familiar.interactive"
+	LabelCorrelation = "familiar.correlation_id" // webhook delivery that triggered the agent
+)
+
+// containerLabels returns the labels for a request's agent container.
+// Unset fields are omitted.
+func containerLabels(req SpawnRequest) map[string]string {
+	labels := map[string]string{
+		LabelAgent:   "true",
+		LabelAgentID: req.ID,
+	}
+	if req.Repo != "" {
+		labels[LabelRepo] = req.Repo
+	}
+	if req.MRNumber > 0 {
+		labels[LabelMR] = strconv.Itoa(req.MRNumber)
+	}
+	if req.EventType != "" {
+		labels[LabelEvent] = req.EventType
+	}
+	if req.Persona != "" {
+		labels[LabelPersona] = req.Persona
+	}
+	if req.Interactive {
+		labels[LabelInteractive] = "true"
+	}
+	if req.CorrelationID != "" {
+		labels[LabelCorrelation] = req.CorrelationID
+	}
+	return labels
+}
+
+// SessionFromLabels reconstructs session metadata from an agent container's
+// labels. Returns false if the labels don't belong to a Familiar agent.
+func SessionFromLabels(labels map[string]string) (*Session, bool) {
+	if labels[LabelAgent] != "true" || labels[LabelAgentID] == "" {
+		return nil, false
+	}
+	mr, _ := strconv.Atoi(labels[LabelMR])
+	return &Session{
+		ID:            labels[LabelAgentID],
+		Repo:          labels[LabelRepo],
+		MRNumber:      mr,
+		EventType:     labels[LabelEvent],
+		Persona:       labels[LabelPersona],
+		Interactive:   labels[LabelInteractive] == "true",
+		CorrelationID: labels[LabelCorrelation],
+	}, true
+}
@@ -0,0 +1,66 @@
+package agent
+
+import "testing"
+
+func TestContainerLabels(t *testing.T) {
+	labels := containerLabels(SpawnRequest{
+		ID:            "gitlab-repo-7-1700000000-security",
+		Repo:          "owner/repo",
+		MRNumber:      7,
+		EventType:     "mr_opened",
+		Persona:       "security",
+		CorrelationID: "abc123",
+	})
+
+	want := map[string]string{
+		LabelAgent:       "true",
+		LabelAgentID:     "gitlab-repo-7-1700000000-security",
+		LabelRepo:        "owner/repo",
+		LabelMR:          "7",
+		LabelEvent:       "mr_opened",
+		LabelPersona:     "security",
+		LabelCorrelation: "abc123",
+	}
+	if len(labels) != len(want) {
+		t.Errorf("labels = %v, want %v", labels, want)
+	}
+	for k, v := range want {
+		if labels[k] != v {
+			t.Errorf("labels[%q] = %q, want %q", k, labels[k], v)
+		}
+	}
+}
+
+func TestContainerLabels_OmitsUnsetFields(t *testing.T) {
+	labels := containerLabels(SpawnRequest{ID: "agent-1"})
+
+	for _, key := range []string{LabelRepo, LabelMR, LabelEvent, LabelPersona, LabelInteractive, LabelCorrelation} {
+		if _, ok := labels[key]; ok {
+			t.Errorf("labels[%q] should be unset, got %q", key, labels[key])
+		}
+	}
+}
+
+func TestSessionFromLabels(t *testing.T) {
+	req := SpawnRequest{
+		ID:            "agent-1",
+		Repo:          "owner/repo",
+		MRNumber:      42,
+		EventType:     "mention",
+		Interactive:   true,
+		CorrelationID: "abc123",
+	}
+
+	session, ok := SessionFromLabels(containerLabels(req))
+	if !ok {
+		t.Fatal("SessionFromLabels() should recognize agent labels")
+	}
+	if session.ID != req.ID || session.Repo != req.Repo || session.MRNumber != 42 ||
+		session.EventType != "mention" || !session.Interactive || session.CorrelationID != "abc123" {
+		t.Errorf("SessionFromLabels() = %+v, want fields from %+v", session, req)
+	}
+
+	if _, ok := SessionFromLabels(map[string]string{"com.example": "x"}); ok {
+		t.Error("SessionFromLabels() should reject non-agent containers")
+	}
+}
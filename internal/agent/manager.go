@@ -3,35 +3,53 @@ package agent
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
+	"time"
 )
 
 // ErrQueueFull is returned when the queue is at capacity.
 var ErrQueueFull = errors.New("agent queue is full")
 
+// ErrDrained is the cause of the context a drained request's SpawnFunc is
+// called with.
+var ErrDrained = errors.New("removed from the agent queue")
+
 // ManagerConfig configures the session manager.
 type ManagerConfig struct {
 	MaxConcurrent int
 	QueueSize     int
 }
 
-// SpawnFunc is a function that spawns an agent.
+// SpawnFunc is a function that spawns an agent. Requests removed by Drain
+// are passed to it with an already cancelled context so the caller can clean
+// up after them.
 type SpawnFunc func(ctx context.Context, req SpawnRequest) error
 
 // queuedRequest represents a queued spawn request.
 type queuedRequest struct {
-	req     SpawnRequest
-	spawnFn SpawnFunc
+	req      SpawnRequest
+	spawnFn  SpawnFunc
+	queuedAt time.Time
+}
+
+// QueuedRequest describes a spawn request waiting for a slot.
+type QueuedRequest struct {
+	Request  SpawnRequest
+	QueuedAt time.Time
 }
 
 // Manager manages agent concurrency and queueing.
+// Limits can be changed at runtime with SetLimits.
 type Manager struct {
-	cfg       ManagerConfig
-	queue     chan queuedRequest
-	semaphore chan struct{}
-	wg        sync.WaitGroup
-	ctx       context.Context
-	cancel    context.CancelFunc
+	cfg    ManagerConfig
+	queue  []queuedRequest
+	active int
+	mu     sync.Mutex
+	cond   *sync.Cond // signalled when the queue, active count, or limits change
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // NewManager creates a new session manager.
@@ -46,12 +64,11 @@ func NewManager(cfg ManagerConfig) *Manager {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	m := &Manager{
-		cfg:       cfg,
-		queue:     make(chan queuedRequest, cfg.QueueSize),
-		semaphore: make(chan struct{}, cfg.MaxConcurrent),
-		ctx:       ctx,
-		cancel:    cancel,
+		cfg:    cfg,
+		ctx:    ctx,
+		cancel: cancel,
 	}
+	m.cond = sync.NewCond(&m.mu)
 
 	// Start worker
 	go m.worker()
@@ -61,47 +78,132 @@ func NewManager(cfg ManagerConfig) *Manager {
 
 // Enqueue adds a spawn request to the queue.
 func (m *Manager) Enqueue(req SpawnRequest, spawnFn SpawnFunc) error {
-	select {
-	case m.queue <- queuedRequest{req: req, spawnFn: spawnFn}:
-		return nil
-	default:
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.queue) >= m.cfg.QueueSize {
 		return ErrQueueFull
 	}
+	m.queue = append(m.queue, queuedRequest{req: req, spawnFn: spawnFn, queuedAt: time.Now()})
+	m.cond.Broadcast()
+	return nil
 }
 
 // worker processes the queue.
 func (m *Manager) worker() {
 	for {
-		select {
-		case <-m.ctx.Done():
+		m.mu.Lock()
+		for m.ctx.Err() == nil && (len(m.queue) == 0 || m.active >= m.cfg.MaxConcurrent) {
+			m.cond.Wait()
+		}
+		if m.ctx.Err() != nil {
+			m.mu.Unlock()
 			return
-		case queued := <-m.queue:
-			// Wait for semaphore
-			m.semaphore <- struct{}{}
+		}
 
-			m.wg.Add(1)
-			go func(q queuedRequest) {
-				defer m.wg.Done()
-				defer func() { <-m.semaphore }()
+		queued := m.queue[0]
+		m.queue = m.queue[1:]
+		m.active++
+		m.wg.Add(1)
+		m.mu.Unlock()
 
-				q.spawnFn(m.ctx, q.req)
-			}(queued)
-		}
+		go func(q queuedRequest) {
+			defer m.wg.Done()
+			defer m.release()
+
+			q.spawnFn(m.ctx, q.req)
+		}(queued)
+	}
+}
+
+// release frees a concurrency slot.
+func (m *Manager) release() {
+	m.mu.Lock()
+	m.active--
+	m.cond.Broadcast()
+	m.mu.Unlock()
+}
+
+// Limits returns the current concurrency limits.
+func (m *Manager) Limits() ManagerConfig {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cfg
+}
+
+// SetLimits changes the concurrency limits at runtime.
+// Lowering MaxConcurrent doesn't stop running agents; new spawns wait until
+// the active count drops below the new limit. Lowering QueueSize doesn't drop
+// already-queued requests.
+func (m *Manager) SetLimits(cfg ManagerConfig) error {
+	if cfg.MaxConcurrent < 1 {
+		return fmt.Errorf("max concurrent must be at least 1, got %d", cfg.MaxConcurrent)
 	}
+	if cfg.QueueSize < 1 {
+		return fmt.Errorf("queue size must be at least 1, got %d", cfg.QueueSize)
+	}
+
+	m.mu.Lock()
+	m.cfg = cfg
+	m.cond.Broadcast()
+	m.mu.Unlock()
+	return nil
 }
 
 // QueueLength returns current queue length.
 func (m *Manager) QueueLength() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	return len(m.queue)
 }
 
+// Queued returns the requests waiting for a slot, oldest first.
+func (m *Manager) Queued() []QueuedRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	queued := make([]QueuedRequest, len(m.queue))
+	for i, q := range m.queue {
+		queued[i] = QueuedRequest{Request: q.req, QueuedAt: q.queuedAt}
+	}
+	return queued
+}
+
+// Drain removes every waiting request without spawning it. Each request's
+// SpawnFunc is called with a context cancelled with ErrDrained so it can
+// release what it holds. Returns the removed requests.
+func (m *Manager) Drain() []SpawnRequest {
+	m.mu.Lock()
+	queue := m.queue
+	m.queue = nil
+	m.cond.Broadcast()
+	m.mu.Unlock()
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(ErrDrained)
+
+	drained := make([]SpawnRequest, len(queue))
+	for i, q := range queue {
+		drained[i] = q.req
+		q.spawnFn(ctx, q.req)
+	}
+	return drained
+}
+
 // ActiveCount returns number of currently running agents.
 func (m *Manager) ActiveCount() int {
-	return len(m.semaphore)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.active
 }
 
 // Shutdown stops the manager and waits for agents to complete.
 func (m *Manager) Shutdown() {
 	m.cancel()
+
+	m.mu.Lock()
+	m.cond.Broadcast()
+	m.mu.Unlock()
+
 	m.wg.Wait()
 }
@@ -146,3 +146,140 @@ func TestManager_DefaultConfig(t *testing.T) {
 	// Wait for processing
 	time.Sleep(50 * time.Millisecond)
 }
+
+func TestManager_SetLimits(t *testing.T) {
+	manager := NewManager(ManagerConfig{
+		MaxConcurrent: 1,
+		QueueSize:     5,
+	})
+	defer manager.Shutdown()
+
+	blocking := make(chan struct{})
+	var started int32
+	spawnFn := func(ctx context.Context, req SpawnRequest) error {
+		atomic.AddInt32(&started, 1)
+		<-blocking
+		return nil
+	}
+	defer close(blocking)
+
+	for i := 0; i < 3; i++ {
+		if err := manager.Enqueue(SpawnRequest{ID: fmt.Sprintf("agent-%d", i)}, spawnFn); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&started); n != 1 {
+		t.Fatalf("started = %d before raising limit, want 1", n)
+	}
+
+	// Raising the limit should start queued requests without a restart
+	if err := manager.SetLimits(ManagerConfig{MaxConcurrent: 3, QueueSize: 5}); err != nil {
+		t.Fatalf("SetLimits() error = %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&started); n != 3 {
+		t.Errorf("started = %d after raising limit, want 3", n)
+	}
+	if got := manager.Limits(); got.MaxConcurrent != 3 || got.QueueSize != 5 {
+		t.Errorf("Limits() = %+v, want {3 5}", got)
+	}
+}
+
+func TestManager_SetLimits_ShrinkQueue(t *testing.T) {
+	manager := NewManager(ManagerConfig{
+		MaxConcurrent: 1,
+		QueueSize:     5,
+	})
+	defer manager.Shutdown()
+
+	if err := manager.SetLimits(ManagerConfig{MaxConcurrent: 1, QueueSize: 1}); err != nil {
+		t.Fatalf("SetLimits() error = %v", err)
+	}
+
+	blocking := make(chan struct{})
+	defer close(blocking)
+	started := make(chan struct{})
+	manager.Enqueue(SpawnRequest{ID: "active"}, func(ctx context.Context, req SpawnRequest) error {
+		close(started)
+		<-blocking
+		return nil
+	})
+	<-started
+
+	noop := func(ctx context.Context, req SpawnRequest) error { return nil }
+	if err := manager.Enqueue(SpawnRequest{ID: "queued"}, noop); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if err := manager.Enqueue(SpawnRequest{ID: "overflow"}, noop); err != ErrQueueFull {
+		t.Errorf("Enqueue() error = %v, want ErrQueueFull", err)
+	}
+}
+
+func TestManager_SetLimits_Invalid(t *testing.T) {
+	manager := NewManager(ManagerConfig{})
+	defer manager.Shutdown()
+
+	tests := []ManagerConfig{
+		{MaxConcurrent: 0, QueueSize: 5},
+		{MaxConcurrent: 5, QueueSize: 0},
+		{MaxConcurrent: -1, QueueSize: -1},
+	}
+	for _, cfg := range tests {
+		if err := manager.SetLimits(cfg); err == nil {
+			t.Errorf("SetLimits(%+v) should fail", cfg)
+		}
+	}
+
+	if got := manager.Limits(); got.MaxConcurrent != 5 || got.QueueSize != 20 {
+		t.Errorf("Limits() = %+v, want defaults unchanged", got)
+	}
+}
+
+func TestManager_QueuedAndDrain(t *testing.T) {
+	manager := NewManager(ManagerConfig{
+		MaxConcurrent: 1,
+		QueueSize:     5,
+	})
+	defer manager.Shutdown()
+
+	// Block the single slot
+	blocking := make(chan struct{})
+	defer close(blocking)
+	started := make(chan struct{})
+	manager.Enqueue(SpawnRequest{ID: "blocking"}, func(ctx context.Context, req SpawnRequest) error {
+		close(started)
+		<-blocking
+		return nil
+	})
+	<-started
+
+	var causes []error
+	spawnFn := func(ctx context.Context, req SpawnRequest) error {
+		causes = append(causes, context.Cause(ctx))
+		return nil
+	}
+	manager.Enqueue(SpawnRequest{ID: "first", Repo: "owner/repo"}, spawnFn)
+	manager.Enqueue(SpawnRequest{ID: "second"}, spawnFn)
+
+	queued := manager.Queued()
+	if len(queued) != 2 || queued[0].Request.ID != "first" || queued[1].Request.ID != "second" {
+		t.Fatalf("Queued() = %+v, want first then second", queued)
+	}
+	if queued[0].QueuedAt.IsZero() {
+		t.Error("QueuedAt should be set")
+	}
+
+	drained := manager.Drain()
+	if len(drained) != 2 {
+		t.Fatalf("Drain() removed %d requests, want 2", len(drained))
+	}
+	if n := manager.QueueLength(); n != 0 {
+		t.Errorf("QueueLength() after Drain = %d, want 0", n)
+	}
+	if len(causes) != 2 || causes[0] != ErrDrained || causes[1] != ErrDrained {
+		t.Errorf("spawn funcs called with causes %v, want ErrDrained for both", causes)
+	}
+}
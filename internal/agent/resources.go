@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/drewdunne/familiar/internal/docker"
+	"github.com/drewdunne/familiar/internal/metrics"
+)
+
+// checkStats samples CPU and memory for every running agent in parallel,
+// recording the latest sample and peak memory on each session and the
+// totals in metrics.
+func (s *Spawner) checkStats(ctx context.Context) {
+	if s.probe == nil {
+		return
+	}
+
+	var running []*Session
+	for _, session := range s.ListSessions() {
+		s.mu.RLock()
+		if session.Status == "running" {
+			running = append(running, session)
+		}
+		s.mu.RUnlock()
+	}
+
+	var wg sync.WaitGroup
+	for _, session := range running {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stats, err := s.probe.Stats(ctx, session.ContainerID)
+			if err != nil {
+				slog.Warn("failed to sample agent resources", "agent_id", session.ID, "error", err)
+				return
+			}
+			s.mu.Lock()
+			session.Stats = stats
+			session.PeakMemoryBytes = max(session.PeakMemoryBytes, stats.MemoryBytes)
+			s.mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	var (
+		agents    int
+		cpu       float64
+		memory    uint64
+		maxMemory uint64
+	)
+	s.mu.RLock()
+	for _, session := range running {
+		if session.Stats == nil {
+			continue
+		}
+		agents++
+		cpu += session.Stats.CPUPercent
+		memory += session.Stats.MemoryBytes
+		maxMemory = max(maxMemory, session.PeakMemoryBytes)
+	}
+	s.mu.RUnlock()
+	metrics.AgentResourcesSampled(agents, cpu, memory, maxMemory)
+}
+
+// ResourceStats describes one running agent's latest resource sample.
+type ResourceStats struct {
+	ID              string                `json:"id"`
+	Repo            string                `json:"repo"`
+	Stats           docker.ContainerStats `json:"stats"`
+	PeakMemoryBytes uint64                `json:"peak_memory_bytes"`
+}
+
+// ResourceStats returns the latest resource sample of each running agent
+// that has been sampled.
+func (s *Spawner) ResourceStats() []ResourceStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []ResourceStats
+	for _, session := range s.sessions {
+		if session.Stats == nil {
+			continue
+		}
+		out = append(out, ResourceStats{
+			ID:              session.ID,
+			Repo:            session.Repo,
+			Stats:           *session.Stats,
+			PeakMemoryBytes: session.PeakMemoryBytes,
+		})
+	}
+	return out
+}
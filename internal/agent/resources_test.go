@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/drewdunne/familiar/internal/docker"
+	"github.com/drewdunne/familiar/internal/metrics"
+)
+
+func TestSpawner_CheckStats(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+
+	probe := &mockProbe{stats: &docker.ContainerStats{CPUPercent: 75, MemoryBytes: 300 << 20}}
+	spawner := &Spawner{sessions: make(map[string]*Session), probe: probe}
+	spawner.sessions["a"] = &Session{ID: "a", Repo: "owner/repo", Status: "running", PeakMemoryBytes: 500 << 20}
+	spawner.sessions["b"] = &Session{ID: "b", Repo: "owner/repo", Status: "running"}
+	spawner.sessions["c"] = &Session{ID: "c", Status: "timed_out"}
+
+	spawner.checkStats(context.Background())
+
+	if got := spawner.sessions["a"].PeakMemoryBytes; got != 500<<20 {
+		t.Errorf("PeakMemoryBytes = %d, want earlier peak kept", got)
+	}
+	if got := spawner.sessions["b"].PeakMemoryBytes; got != 300<<20 {
+		t.Errorf("PeakMemoryBytes = %d, want sampled value", got)
+	}
+	if spawner.sessions["c"].Stats != nil {
+		t.Error("sessions that are no longer running should not be sampled")
+	}
+
+	want := metrics.Resources{Agents: 2, CPUPercent: 150, MemoryBytes: 600 << 20, PeakMemoryBytes: 500 << 20}
+	if got := metrics.Get().AgentResources; got != want {
+		t.Errorf("AgentResources = %+v, want %+v", got, want)
+	}
+
+	stats := spawner.ResourceStats()
+	if len(stats) != 2 {
+		t.Fatalf("ResourceStats() = %v, want 2 sampled agents", stats)
+	}
+}
+
+func TestSpawner_CheckStats_Error(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+
+	spawner := &Spawner{sessions: make(map[string]*Session), probe: &mockProbe{statsErr: errors.New("gone")}}
+	spawner.sessions["a"] = &Session{ID: "a", Status: "running"}
+
+	spawner.checkStats(context.Background())
+
+	if spawner.sessions["a"].Stats != nil {
+		t.Error("failed samples should not be recorded")
+	}
+	if got := metrics.Get().AgentResources.Agents; got != 0 {
+		t.Errorf("AgentResources.Agents = %d, want 0", got)
+	}
+}
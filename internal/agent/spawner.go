@@ -1,80 +1,193 @@
 package agent
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
+	"path"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/drewdunne/familiar/internal/agent/instructions"
 	"github.com/drewdunne/familiar/internal/docker"
+	"github.com/drewdunne/familiar/internal/logging"
+	"github.com/drewdunne/familiar/internal/metrics"
 )
 
+// ErrNotRunning is returned when terminating a session that has already
+// finished.
+var ErrNotRunning = errors.New("agent is not running")
+
 // SpawnerConfig configures the agent spawner.
 type SpawnerConfig struct {
-	Image            string
-	ClaudeAuthDir    string // Host path — used for Docker bind mounts to agent containers
-	MaxAgents        int
-	TimeoutMinutes   int    // 0 means no timeout
-	NetworkMode      string // Docker network mode (e.g. "host")
-	RepoCacheHostDir string // Host path to repo cache — mounted at /cache in agent containers
+	Image              string
+	ImageDigest        string // Pinned digest Image must match; also taken from an image@digest reference
+	ClaudeAuthDir      string // Host path — used for Docker bind mounts to agent containers
+	MaxAgents          int
+	TimeoutMinutes     int       // 0 means no timeout
+	InteractiveMinutes int       // Timeout for interactive sessions; 0 falls back to TimeoutMinutes
+	IdleMinutes        int       // Terminate agents with no output for this long; 0 disables
+	NetworkMode        string    // Docker network mode (e.g. "host")
+	RepoCacheHostDir   string    // Host path to repo cache — mounted at /cache in agent containers
+	Env                EnvFilter // Which request env vars may reach agent containers
+	Docker             docker.Connection
+	Healthcheck        *docker.Healthcheck // Unhealthy agents are terminated; nil uses the image's own
+	Redactor           *logging.Redactor   // Scrubs secrets from captured output; nil writes it as is
 }
 
 // SpawnRequest contains parameters for spawning an agent.
 type SpawnRequest struct {
 	ID           string
+	Repo         string // owner/name, used to attribute usage
+	MRNumber     int
+	EventType    string
+	Persona      string // prompt profile, when one of several personas
 	WorktreePath string
 	WorkDir      string // Working directory inside container
 	Prompt       string
 	Env          map[string]string
+	Interactive  bool     // Keep Claude open in tmux so a developer can attach and take over
+	Bootstrap    []string // Commands run in the worktree before Claude starts
+	ReadOnlyGit  bool     // Mount the repo cache read-only, so the agent cannot change git config or hooks
+
+	// CorrelationID identifies the webhook delivery that triggered the agent.
+	CorrelationID string
 }
 
 // Session represents a running agent session.
 type Session struct {
-	ID            string
-	ContainerID   string
-	ContainerUser string
-	WorktreePath  string
-	StartedAt     time.Time
-	Status        string
+	ID              string
+	Repo            string
+	MRNumber        int
+	EventType       string
+	Persona         string
+	CorrelationID   string
+	ContainerID     string
+	ContainerUser   string
+	WorktreePath    string
+	StartedAt       time.Time
+	EndedAt         time.Time
+	Status          string
+	Interactive     bool
+	ExitCode        int                    // set when the container exits on its own
+	Health          string                 // latest healthcheck status, if the container has one
+	Stats           *docker.ContainerStats // latest resource sample while running
+	PeakMemoryBytes uint64                 // highest sampled memory use
+	Usage           *Usage                 // set when the run's output has been captured
+	FailureReason   string                 // set when the spawner terminates the session early
+
+	// FailureCategory classifies a failed run; empty if it succeeded or
+	// its outcome is not yet known.
+	FailureCategory FailureCategory
+
+	done chan struct{} // closed when the session is stopped
+
+	// Credential values passed to the container, redacted from its output
+	secrets []string
+
+	// Idle detection state
+	lastActivity time.Time
+	outputSize   int64
+	procsMissing time.Time // when tmux/claude were first seen missing
+}
+
+// agentOutputPath is where the agent's Claude output is tee'd inside the container.
+const agentOutputPath = "/tmp/claude-output.log"
+
+// bootstrapMarker exists inside the container while bootstrap commands run.
+const bootstrapMarker = "/tmp/familiar-bootstrapping"
+
+// idleGrace is how long tmux/claude may be missing before the agent is
+// considered stuck. Covers container setup and a normal exit in progress.
+const idleGrace = time.Minute
+
+// containerProbe reports liveness signals from a running agent container.
+type containerProbe interface {
+	ContainerProcesses(ctx context.Context, containerID string) ([]string, error)
+	StatPath(ctx context.Context, containerID, path string) (docker.PathStat, error)
+	InspectContainer(ctx context.Context, containerID string) (*docker.ContainerInspect, error)
+	Stats(ctx context.Context, containerID string) (*docker.ContainerStats, error)
+}
+
+// containerWaiter reports when an agent container stops running.
+type containerWaiter interface {
+	WaitContainer(ctx context.Context, id string) (<-chan docker.ContainerExit, <-chan error)
+}
+
+// containerEventSource streams lifecycle events for containers with a label.
+type containerEventSource interface {
+	ContainerEvents(ctx context.Context, label string) (<-chan docker.ContainerEvent, <-chan error)
 }
 
+// imageInspector resolves the agent image's identity for digest pinning.
+type imageInspector interface {
+	InspectImage(ctx context.Context, image string) (*docker.ImageIdentity, error)
+}
+
+// maxHistory bounds the number of finished sessions kept for reporting.
+const maxHistory = 100
+
 // Spawner manages agent container lifecycle.
 type Spawner struct {
-	cfg       SpawnerConfig
-	client    *docker.Client
-	sessions  map[string]*Session
-	mu        sync.RWMutex
-	OnTimeout func(*Session) // Called when a session times out
+	cfg        SpawnerConfig
+	client     *docker.Client
+	sessions   map[string]*Session
+	probe      containerProbe
+	images     imageInspector
+	waiter     containerWaiter
+	events     containerEventSource
+	history    []Session // finished sessions, oldest first
+	mu         sync.RWMutex
+	stopWatch  func()
+	stopEvents func()
+	OnTimeout  func(*Session) // Called when a session times out
+	OnExit     func(*Session) // Called when a session's container exits on its own
+	OnFailure  func(*Session) // Called when the spawner terminates a stuck or unhealthy session
 }
 
 // NewSpawner creates a new agent spawner.
 func NewSpawner(cfg SpawnerConfig) (*Spawner, error) {
-	client, err := docker.NewClient()
+	client, err := docker.NewClient(docker.WithConnection(cfg.Docker))
 	if err != nil {
 		return nil, fmt.Errorf("creating docker client: %w", err)
 	}
 
 	if cfg.ClaudeAuthDir == "" {
-		log.Println("WARNING: claude_auth_dir not configured; agents will hit first-run prompts")
+		slog.Warn("claude_auth_dir not configured; agents will hit first-run prompts")
 	}
 
 	if cfg.MaxAgents == 0 {
 		cfg.MaxAgents = 5
 	}
 
-	return &Spawner{
+	s := &Spawner{
 		cfg:      cfg,
 		client:   client,
+		probe:    client,
+		images:   client,
+		waiter:   client,
+		events:   client,
 		sessions: make(map[string]*Session),
-	}, nil
+	}
+	s.stopWatch = s.startTimeoutWatcher()
+	s.stopEvents = s.startEventWatcher()
+	return s, nil
 }
 
-// Close closes the spawner.
+// Close stops the session watchers and closes the spawner.
 func (s *Spawner) Close() error {
+	if s.stopWatch != nil {
+		s.stopWatch()
+	}
+	if s.stopEvents != nil {
+		s.stopEvents()
+	}
 	return s.client.Close()
 }
 
@@ -105,7 +218,7 @@ func (s *Spawner) Spawn(ctx context.Context, req SpawnRequest) (*Session, error)
 		mounts = append(mounts, docker.Mount{
 			Source:   s.cfg.RepoCacheHostDir,
 			Target:   "/cache",
-			ReadOnly: false,
+			ReadOnly: req.ReadOnlyGit,
 		})
 	}
 
@@ -128,9 +241,13 @@ func (s *Spawner) Spawn(ctx context.Context, req SpawnRequest) (*Session, error)
 		})
 	}
 
-	// Prepare environment
+	// Prepare environment, withholding anything the filter blocks
+	reqEnv, blocked := s.cfg.Env.Apply(req.Env)
+	if len(blocked) > 0 {
+		slog.Warn("withheld env vars from agent", "agent_id", req.ID, "vars", strings.Join(blocked, ", "))
+	}
 	env := []string{}
-	for k, v := range req.Env {
+	for k, v := range reqEnv {
 		env = append(env, fmt.Sprintf("%s=%s", k, v))
 	}
 
@@ -139,26 +256,30 @@ func (s *Spawner) Spawn(ctx context.Context, req SpawnRequest) (*Session, error)
 		env = append(env, "HOME=/home/agent")
 	}
 
+	// Verify the image against its pinned digest, if any
+	image, err := s.resolveImage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Build container command (claude CLI inside tmux, prompt via env var)
-	cmd, cmdEnv := containerCmd(req.Prompt, instructions.Content())
+	cmd, cmdEnv := containerCmd(req.Prompt, instructions.Content(), req.Interactive, req.Bootstrap)
 	env = append(env, cmdEnv...)
 
 	// Create container
 	containerID, err := s.client.CreateContainer(ctx, docker.ContainerConfig{
 		Name:        "familiar-agent-" + req.ID,
-		Image:       s.cfg.Image,
+		Image:       image,
 		User:        containerUser,
 		WorkDir:     req.WorkDir,
 		Mounts:      mounts,
 		TmpfsMounts: tmpfsMounts,
 		Env:         env,
-		Labels: map[string]string{
-			"familiar.agent":    "true",
-			"familiar.agent.id": req.ID,
-		},
+		Labels:      containerLabels(req),
 		Cmd:         cmd,
 		Entrypoint:  []string{"/bin/sh"},
 		NetworkMode: s.cfg.NetworkMode,
+		Healthcheck: s.cfg.Healthcheck,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("creating container: %w", err)
@@ -173,14 +294,27 @@ func (s *Spawner) Spawn(ctx context.Context, req SpawnRequest) (*Session, error)
 
 	session := &Session{
 		ID:            req.ID,
+		Repo:          req.Repo,
+		MRNumber:      req.MRNumber,
+		EventType:     req.EventType,
+		Persona:       req.Persona,
+		CorrelationID: req.CorrelationID,
 		ContainerID:   containerID,
 		ContainerUser: containerUser,
 		WorktreePath:  req.WorktreePath,
 		StartedAt:     time.Now(),
 		Status:        "running",
+		Interactive:   req.Interactive,
+		done:          make(chan struct{}),
+		lastActivity:  time.Now(),
+		secrets:       credentialValues(reqEnv),
 	}
 
 	s.sessions[req.ID] = session
+	metrics.AgentSpawned()
+	if s.waiter != nil {
+		go s.waitExit(session)
+	}
 	return session, nil
 }
 
@@ -197,7 +331,7 @@ func (s *Spawner) Stop(ctx context.Context, sessionID string) error {
 	// Stop container (10 second timeout)
 	if err := s.client.StopContainer(ctx, session.ContainerID, 10); err != nil {
 		// Log but continue to cleanup
-		log.Printf("warning: failed to stop container %s: %v", session.ContainerID, err)
+		slog.Warn("failed to stop container", "agent_id", session.ID, "container", session.ContainerID, "error", err)
 	}
 
 	// Remove container
@@ -206,9 +340,31 @@ func (s *Spawner) Stop(ctx context.Context, sessionID string) error {
 	}
 
 	delete(s.sessions, sessionID)
+	s.recordHistory(session)
+	if session.done != nil {
+		close(session.done)
+	}
 	return nil
 }
 
+// Wait blocks until the session has been stopped or ctx is done.
+// Returns nil immediately if the session is not active.
+func (s *Spawner) Wait(ctx context.Context, sessionID string) error {
+	s.mu.RLock()
+	session, ok := s.sessions[sessionID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	select {
+	case <-session.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // GetSession returns a session by ID.
 func (s *Spawner) GetSession(sessionID string) (*Session, bool) {
 	s.mu.RLock()
@@ -229,6 +385,144 @@ func (s *Spawner) ListSessions() []*Session {
 	return sessions
 }
 
+// ActiveSessions returns a copy of each active session, oldest first.
+func (s *Spawner) ActiveSessions() []Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessions := make([]Session, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, *session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].StartedAt.Before(sessions[j].StartedAt)
+	})
+	return sessions
+}
+
+// History returns finished sessions, most recent first.
+func (s *Spawner) History() []Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	history := make([]Session, len(s.history))
+	for i, session := range s.history {
+		history[len(s.history)-1-i] = session
+	}
+	return history
+}
+
+// recordHistory appends a finished session to the bounded history.
+// Caller must hold s.mu.
+func (s *Spawner) recordHistory(session *Session) {
+	finished := *session
+	finished.EndedAt = time.Now()
+	finished.done = nil
+	metrics.AgentRunFinished(finished.EndedAt.Sub(finished.StartedAt))
+
+	s.history = append(s.history, finished)
+	if len(s.history) > maxHistory {
+		s.history = s.history[len(s.history)-maxHistory:]
+	}
+}
+
+// MaxAgents returns the current concurrent agent limit.
+func (s *Spawner) MaxAgents() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg.MaxAgents
+}
+
+// SetMaxAgents changes the concurrent agent limit at runtime.
+// Running agents are unaffected; the limit applies to new spawns.
+func (s *Spawner) SetMaxAgents(n int) error {
+	if n < 1 {
+		return fmt.Errorf("max agents must be at least 1, got %d", n)
+	}
+	s.mu.Lock()
+	s.cfg.MaxAgents = n
+	s.mu.Unlock()
+	return nil
+}
+
+// SetImage changes the agent image, and its pinned digest, used for new spawns.
+func (s *Spawner) SetImage(image, digest string) {
+	s.mu.Lock()
+	s.cfg.Image = image
+	s.cfg.ImageDigest = digest
+	s.mu.Unlock()
+}
+
+// SetRedactor changes the redactor applied to captured output, such as after
+// a reload rotates provider tokens.
+func (s *Spawner) SetRedactor(r *logging.Redactor) {
+	s.mu.Lock()
+	s.cfg.Redactor = r
+	s.mu.Unlock()
+}
+
+// SetEnvFilter changes which env vars new spawns may pass to agents, such as
+// after a reload rotates server secrets.
+func (s *Spawner) SetEnvFilter(f EnvFilter) {
+	s.mu.Lock()
+	s.cfg.Env = f
+	s.mu.Unlock()
+}
+
+// Image returns the agent image reference used for new spawns, including its
+// pinned digest if there is one.
+func (s *Spawner) Image() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cfg.ImageDigest != "" && docker.PinnedDigest(s.cfg.Image) == "" {
+		return s.cfg.Image + "@" + s.cfg.ImageDigest
+	}
+	return s.cfg.Image
+}
+
+// Ping checks that the Docker daemon agents run on is reachable.
+func (s *Spawner) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx)
+}
+
+// CheckImage reports an error unless the agent image is present locally.
+func (s *Spawner) CheckImage(ctx context.Context) error {
+	image := s.Image()
+	ok, err := s.client.ImageExists(ctx, image)
+	if err != nil {
+		return fmt.Errorf("checking agent image: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("agent image %s not present", image)
+	}
+	return nil
+}
+
+// resolveImage returns the image reference to create agent containers from.
+// When a digest is pinned, the local image must match it, and containers are
+// created from its image ID so a tag moved after the check can't be used.
+// Caller must hold s.mu.
+func (s *Spawner) resolveImage(ctx context.Context) (string, error) {
+	want := s.cfg.ImageDigest
+	if want == "" {
+		want = docker.PinnedDigest(s.cfg.Image)
+	}
+	if want == "" {
+		return s.cfg.Image, nil
+	}
+
+	id, err := s.images.InspectImage(ctx, s.cfg.Image)
+	if err != nil {
+		return "", fmt.Errorf("verifying agent image %s: %w", s.cfg.Image, err)
+	}
+	if !id.Matches(want) {
+		slog.Warn("agent image does not match pinned digest; refusing to spawn",
+			"image", s.cfg.Image, "digest", want, "image_id", id.ID, "repo_digests", id.RepoDigests)
+		return "", fmt.Errorf("agent image %s does not match pinned digest %s", s.cfg.Image, want)
+	}
+	return id.ID, nil
+}
+
 // ActiveCount returns the number of active agents.
 func (s *Spawner) ActiveCount() int {
 	s.mu.RLock()
@@ -243,29 +537,103 @@ func (s *Spawner) CaptureAndStop(ctx context.Context, sessionID string, logPath
 		return fmt.Errorf("session not found: %s", sessionID)
 	}
 
-	// Get container logs
-	logs, err := s.client.GetContainerLogs(ctx, session.ContainerID)
-	if err != nil {
-		return fmt.Errorf("getting container logs: %w", err)
+	var output bytes.Buffer
+	if err := s.client.StreamContainerLogs(ctx, session.ContainerID, docker.LogOptions{}, &output, &output); err != nil {
+		return err
 	}
-	defer logs.Close()
 
-	// Write to log file
+	// Agents often echo tokens from their environment or git remotes, so
+	// secrets are scrubbed before anything reaches disk
+	logged := output.Bytes()
+	s.mu.Lock()
+	redactor := s.cfg.Redactor
+	s.mu.Unlock()
+	if redactor != nil {
+		logged = redactor.WithSecrets(session.secrets...).Redact(logged)
+	}
 	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
 	if err != nil {
 		return fmt.Errorf("opening log file: %w", err)
 	}
 	defer f.Close()
-
-	if _, err := io.Copy(f, logs); err != nil {
-		return fmt.Errorf("writing logs: %w", err)
+	if _, err := f.Write(logged); err != nil {
+		return fmt.Errorf("writing log file: %w", err)
 	}
 
+	s.recordUsage(session, output.Bytes())
+	s.classify(ctx, session, output.Bytes())
+
 	return s.Stop(ctx, sessionID)
 }
 
-// startTimeoutWatcher starts a goroutine that periodically checks for timed-out sessions.
-// Returns a function to stop the watcher.
+// StreamLogs writes a running agent's container output to w. With follow it
+// blocks until the container stops or ctx is done.
+func (s *Spawner) StreamLogs(ctx context.Context, sessionID string, follow bool, w io.Writer) error {
+	session, ok := s.GetSession(sessionID)
+	if !ok {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	return s.client.StreamContainerLogs(ctx, session.ContainerID, docker.LogOptions{Follow: follow}, w, w)
+}
+
+// classify records why the session failed, if it did, on the session and in
+// metrics. A container still running at capture time was cut short.
+func (s *Spawner) classify(ctx context.Context, session *Session, output []byte) {
+	var exit ExitState
+	running := true
+	if inspect, err := s.probe.InspectContainer(ctx, session.ContainerID); err != nil {
+		slog.Warn("failed to inspect agent", "agent_id", session.ID, "error", err)
+	} else {
+		running = inspect.Running
+		exit = ExitState{ExitCode: inspect.ExitCode, OOMKilled: inspect.OOMKilled}
+	}
+
+	category := ClassifyFailure(exit, output)
+	s.mu.Lock()
+	if category == FailureNone && running && session.Status == "timed_out" {
+		category = FailureTimeout
+	}
+	s.recordFailure(session, category)
+	s.mu.Unlock()
+}
+
+// recordFailure sets the session's failure category and counts it.
+// Caller must hold s.mu.
+func (s *Spawner) recordFailure(session *Session, category FailureCategory) {
+	if category == FailureNone || session.FailureCategory != FailureNone {
+		return
+	}
+	session.FailureCategory = category
+	metrics.AgentFailureCategorized(string(category))
+	slog.Info("agent failed", "agent_id", session.ID, "category", category, "reason", category.Description())
+}
+
+// recordUsage parses Claude's usage report from the run output and records
+// it on the session and in metrics.
+func (s *Spawner) recordUsage(session *Session, output []byte) {
+	usage, err := ParseUsage(output)
+	if err != nil {
+		slog.Warn("no usage reported by agent", "agent_id", session.ID, "error", err)
+		return
+	}
+
+	s.mu.Lock()
+	session.Usage = usage
+	s.mu.Unlock()
+
+	metrics.AgentUsageRecorded(session.Repo, metrics.Usage{
+		InputTokens:      uint64(usage.InputTokens),
+		OutputTokens:     uint64(usage.OutputTokens),
+		CacheReadTokens:  uint64(usage.CacheReadTokens),
+		CacheWriteTokens: uint64(usage.CacheWriteTokens),
+		CostUSD:          usage.CostUSD,
+	})
+	slog.Info("agent usage", "agent_id", session.ID,
+		"input_tokens", usage.InputTokens, "output_tokens", usage.OutputTokens, "cost_usd", usage.CostUSD)
+}
+
+// startTimeoutWatcher starts a goroutine that periodically checks for timed-out
+// and idle sessions. Returns a function to stop the watcher.
 func (s *Spawner) startTimeoutWatcher() func() {
 	ticker := time.NewTicker(30 * time.Second)
 	done := make(chan struct{})
@@ -274,7 +642,11 @@ func (s *Spawner) startTimeoutWatcher() func() {
 		for {
 			select {
 			case <-ticker.C:
+				s.checkExited(context.Background())
 				s.checkTimeouts()
+				s.checkIdle(context.Background())
+				s.checkHealth(context.Background())
+				s.checkStats(context.Background())
 			case <-done:
 				ticker.Stop()
 				return
@@ -287,14 +659,74 @@ func (s *Spawner) startTimeoutWatcher() func() {
 	}
 }
 
-// checkTimeouts checks all sessions and marks/handles those that have exceeded the timeout.
-func (s *Spawner) checkTimeouts() {
-	// Skip if no timeout configured
-	if s.cfg.TimeoutMinutes == 0 {
+// checkExited marks running sessions whose container has exited as "exited"
+// and hands them to OnExit for cleanup. Without OnExit they are stopped.
+func (s *Spawner) checkExited(ctx context.Context) {
+	if s.probe == nil {
+		return
+	}
+
+	for _, session := range s.ListSessions() {
+		s.mu.RLock()
+		running := session.Status == "running"
+		s.mu.RUnlock()
+		if !running {
+			continue
+		}
+
+		inspect, err := s.probe.InspectContainer(ctx, session.ContainerID)
+		if err != nil || inspect.Running {
+			continue
+		}
+		s.markExited(ctx, session, inspect.ExitCode)
+	}
+}
+
+// waitExit blocks until the session's container exits on its own, then marks
+// it exited. Returns early once the session is stopped; if waiting fails,
+// the watcher's checkExited polling still catches the exit.
+func (s *Spawner) waitExit(session *Session) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	exitC, errC := s.waiter.WaitContainer(ctx, session.ContainerID)
+	select {
+	case exit := <-exitC:
+		if exit.Error != "" {
+			slog.Warn("agent container reported an error", "agent_id", session.ID, "error", exit.Error)
+		}
+		s.markExited(ctx, session, exit.ExitCode)
+	case err := <-errC:
+		slog.Warn("container wait failed; exit will be detected by polling", "agent_id", session.ID, "error", err)
+	case <-session.done:
+	}
+}
+
+// markExited records that a running session's container exited and hands it
+// to OnExit for cleanup; without OnExit it is stopped. Sessions that were
+// already stopped or handled are ignored, so it is safe to call from both
+// the container wait and the watcher.
+func (s *Spawner) markExited(ctx context.Context, session *Session, exitCode int) {
+	s.mu.Lock()
+	if s.sessions[session.ID] != session || session.Status != "running" {
+		s.mu.Unlock()
 		return
 	}
+	session.Status = "exited"
+	session.ExitCode = exitCode
+	sessionCopy := *session
+	s.mu.Unlock()
+
+	slog.Info("agent exited", "agent_id", session.ID, "exit_code", exitCode)
+	if s.OnExit != nil {
+		go s.OnExit(&sessionCopy)
+	} else if err := s.Stop(ctx, session.ID); err != nil {
+		slog.Warn("failed to stop exited agent", "agent_id", session.ID, "error", err)
+	}
+}
 
-	timeout := time.Duration(s.cfg.TimeoutMinutes) * time.Minute
+// checkTimeouts checks all sessions and marks/handles those that have exceeded the timeout.
+func (s *Spawner) checkTimeouts() {
 	now := time.Now()
 
 	s.mu.Lock()
@@ -305,7 +737,8 @@ func (s *Spawner) checkTimeouts() {
 			continue
 		}
 
-		if now.Sub(session.StartedAt) > timeout {
+		timeout := s.timeoutFor(session)
+		if timeout > 0 && now.Sub(session.StartedAt) > timeout {
 			// Mark session as timed out
 			session.Status = "timed_out"
 
@@ -320,13 +753,213 @@ func (s *Spawner) checkTimeouts() {
 	}
 }
 
+// timeoutFor returns the session's run time limit, or 0 for no limit.
+// Interactive sessions get their own, typically longer, limit.
+func (s *Spawner) timeoutFor(session *Session) time.Duration {
+	minutes := s.cfg.TimeoutMinutes
+	if session.Interactive && s.cfg.InteractiveMinutes > 0 {
+		minutes = s.cfg.InteractiveMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// checkIdle terminates running sessions that have stopped making progress:
+// no output for IdleMinutes, or tmux/claude gone while the container lingers.
+// Terminated sessions are marked failed. Interactive sessions are skipped since
+// a developer may be attached and thinking.
+func (s *Spawner) checkIdle(ctx context.Context) {
+	if s.cfg.IdleMinutes == 0 || s.probe == nil {
+		return
+	}
+
+	idle := time.Duration(s.cfg.IdleMinutes) * time.Minute
+	for _, session := range s.ListSessions() {
+		s.mu.RLock()
+		running := session.Status == "running" && !session.Interactive
+		s.mu.RUnlock()
+		if !running {
+			continue
+		}
+
+		reason := s.idleReason(ctx, session, idle, time.Now())
+		if reason == "" {
+			continue
+		}
+
+		// The container may have exited while it was probed, in which case
+		// markExited has already handed it off
+		s.mu.Lock()
+		if session.Status != "running" {
+			s.mu.Unlock()
+			continue
+		}
+		slog.Warn("terminating stuck agent", "agent_id", session.ID, "reason", reason)
+		session.Status = "failed"
+		session.FailureReason = reason
+		s.recordFailure(session, FailureStuck)
+		s.mu.Unlock()
+		s.terminate(ctx, session, "stuck")
+	}
+}
+
+// checkHealth records each running session's healthcheck status and
+// terminates sessions whose container Docker reports as unhealthy.
+func (s *Spawner) checkHealth(ctx context.Context) {
+	if s.probe == nil {
+		return
+	}
+
+	for _, session := range s.ListSessions() {
+		if !s.updateHealth(ctx, session) {
+			continue
+		}
+		slog.Warn("terminating unhealthy agent", "agent_id", session.ID)
+		s.terminate(ctx, session, "unhealthy")
+	}
+}
+
+// Terminate stops a running session on request, marking it failed with
+// reason. Like stuck or unhealthy sessions it is handed to OnFailure for
+// cleanup. A session that has already finished returns ErrNotRunning.
+func (s *Spawner) Terminate(ctx context.Context, sessionID, reason string) error {
+	s.mu.Lock()
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	if session.Status != "running" {
+		status := session.Status
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s is %s", ErrNotRunning, sessionID, status)
+	}
+	session.Status = "failed"
+	session.FailureReason = reason
+	s.mu.Unlock()
+
+	slog.Info("terminating agent", "agent_id", sessionID, "reason", reason)
+	s.terminate(ctx, session, "terminated")
+	return nil
+}
+
+// terminate hands a session the spawner marked failed to OnFailure for
+// cleanup; without OnFailure it is stopped.
+func (s *Spawner) terminate(ctx context.Context, session *Session, what string) {
+	if s.OnFailure != nil {
+		s.mu.RLock()
+		sessionCopy := *session
+		s.mu.RUnlock()
+		go s.OnFailure(&sessionCopy)
+		return
+	}
+	if err := s.Stop(ctx, session.ID); err != nil {
+		slog.Warn("failed to stop "+what+" agent", "agent_id", session.ID, "error", err)
+	}
+}
+
+// updateHealth probes a running session's healthcheck status and records
+// it. Returns true if the session was just marked failed for being unhealthy.
+func (s *Spawner) updateHealth(ctx context.Context, session *Session) bool {
+	s.mu.RLock()
+	running := session.Status == "running"
+	s.mu.RUnlock()
+	if !running {
+		return false
+	}
+
+	inspect, err := s.probe.InspectContainer(ctx, session.ContainerID)
+	if err != nil || inspect.Health == "" {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session.Health = inspect.Health
+	if inspect.Health != docker.HealthUnhealthy || session.Status != "running" {
+		return false
+	}
+	session.Status = "failed"
+	session.FailureReason = "container healthcheck is failing"
+	s.recordFailure(session, FailureStuck)
+	return true
+}
+
+// idleReason probes a session's container and returns why it is considered
+// stuck, or "" if it is still making progress.
+func (s *Spawner) idleReason(ctx context.Context, session *Session, idle time.Duration, now time.Time) string {
+	// Probe without the lock so a slow Docker daemon doesn't stall every
+	// other session. tmux and claude only start once bootstrap commands
+	// finish, so processes are only checked after that.
+	output, outputErr := s.probe.StatPath(ctx, session.ContainerID, agentOutputPath)
+	_, bootstrapErr := s.probe.StatPath(ctx, session.ContainerID, bootstrapMarker)
+	var procs []string
+	procsErr := bootstrapErr
+	if bootstrapErr != nil {
+		procs, procsErr = s.probe.ContainerProcesses(ctx, session.ContainerID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Output growth is the progress heartbeat
+	if outputErr == nil && output.Size != session.outputSize {
+		session.outputSize = output.Size
+		session.lastActivity = now
+	}
+
+	// The run timeout still bounds a bootstrap that hangs
+	if bootstrapErr == nil {
+		session.procsMissing = time.Time{}
+		session.lastActivity = now
+		return ""
+	}
+
+	if procsErr == nil {
+		tmuxAlive, claudeAlive := false, false
+		for _, p := range procs {
+			tmuxAlive = tmuxAlive || isProcess(p, "tmux")
+			claudeAlive = claudeAlive || isProcess(p, "claude")
+		}
+
+		switch {
+		case tmuxAlive && claudeAlive:
+			session.procsMissing = time.Time{}
+		case session.procsMissing.IsZero():
+			session.procsMissing = now
+		case now.Sub(session.procsMissing) >= idleGrace && now.Sub(session.StartedAt) >= idleGrace:
+			if !tmuxAlive {
+				return "tmux session exited but container is still running"
+			}
+			return "claude process exited but container is still running"
+		}
+	}
+
+	if now.Sub(session.lastActivity) >= idle {
+		return fmt.Sprintf("no output for %s", idle)
+	}
+	return ""
+}
+
+// isProcess reports whether a container command line runs the named program,
+// either directly or via node (as the claude CLI does).
+func isProcess(cmdline, name string) bool {
+	fields := strings.Fields(cmdline)
+	if len(fields) == 0 {
+		return false
+	}
+	if path.Base(fields[0]) == name {
+		return true
+	}
+	return path.Base(fields[0]) == "node" && len(fields) > 1 && path.Base(fields[1]) == name
+}
+
 // resolveContainerUser returns the UID of the current process as a string.
 // Since Familiar runs as the host user (via docker-compose user:), agent
 // containers should run as the same UID for consistent file ownership.
 func resolveContainerUser() string {
 	uid := os.Getuid()
 	if uid == 0 {
-		log.Println("WARNING: running as root (UID 0); agent containers will also run as root")
+		slog.Warn("running as root (UID 0); agent containers will also run as root")
 	}
 	return fmt.Sprintf("%d", uid)
 }
@@ -338,8 +971,18 @@ func resolveContainerUser() string {
 // The command first copies credentials from /claude-auth-src (read-only bind mount)
 // to /home/agent/.claude (tmpfs), sets up glab config if GITLAB_HOST is set,
 // then runs Claude in a tmux session.
-// Uses -p (print mode) for non-interactive operation.
-func containerCmd(prompt string, claudeMD string) (cmd []string, extraEnv []string) {
+// Uses -p (print mode) for non-interactive operation. Output is streamed as
+// JSON lines so the output file grows as the agent works (the idle heartbeat)
+// and the final result record carries token usage and cost.
+//
+// In interactive mode Claude starts with the prompt but without -p, so the
+// session stays open for a developer to attach; the tmux pane is mirrored to
+// the output file instead.
+//
+// Before Claude starts, the bootstrap commands (or, without any, the repo's
+// .familiar/bootstrap.sh) run from the worktree root, with their output in
+// the container log. A failing bootstrap exits without starting Claude.
+func containerCmd(prompt string, claudeMD string, interactive bool, bootstrap []string) (cmd []string, extraEnv []string) {
 	// Setup claude credentials
 	setupCmd := `mkdir -p /home/agent/.claude && ` +
 		`cp /claude-auth-src/.credentials.json /home/agent/.claude/ && ` +
@@ -380,15 +1023,34 @@ chmod +x /home/agent/git-credential-helper && ` +
 		`chmod 600 /home/agent/.config/glab-cli/config.yml; ` +
 		`fi; `
 
-	// Run claude in tmux; tee output to a log file so Docker can capture it afterward.
-	// Without tee, tmux swallows all stdout/stderr and `docker logs` is empty.
-	setupCmd += `tmux new-session -d -s claude 'claude --dangerously-skip-permissions -p "$FAMILIAR_PROMPT" 2>&1 | tee /tmp/claude-output.log; tmux wait-for -S claude' && ` +
-		`tmux wait-for claude && cat /tmp/claude-output.log`
+	// Bootstrap the worktree (install dependencies, generate files)
+	setupCmd += `if [ -n "$FAMILIAR_BOOTSTRAP" ] || [ -f /workspace/.familiar/bootstrap.sh ]; then ` +
+		`touch ` + bootstrapMarker + `; echo "==> [bootstrap]"; ` +
+		`(cd /workspace && if [ -n "$FAMILIAR_BOOTSTRAP" ]; then sh -exc "$FAMILIAR_BOOTSTRAP"; else sh -ex .familiar/bootstrap.sh; fi) 2>&1; ` +
+		`status=$?; rm -f ` + bootstrapMarker + `; ` +
+		`if [ $status -ne 0 ]; then echo "==> [bootstrap] failed with exit code $status; not starting Claude"; exit $status; fi; ` +
+		`fi; `
 
-	return []string{"-c", setupCmd}, []string{
+	if interactive {
+		// Claude's TUI needs the terminal, so capture the pane rather than tee.
+		setupCmd += `tmux new-session -d -s claude 'claude --dangerously-skip-permissions "$FAMILIAR_PROMPT"; tmux wait-for -S claude' && ` +
+			`tmux pipe-pane -t claude 'cat >> /tmp/claude-output.log' && ` +
+			`tmux wait-for claude && cat /tmp/claude-output.log`
+	} else {
+		// Run claude in tmux; tee output to a log file so Docker can capture it afterward.
+		// Without tee, tmux swallows all stdout/stderr and `docker logs` is empty.
+		setupCmd += `tmux new-session -d -s claude 'claude --dangerously-skip-permissions -p "$FAMILIAR_PROMPT" --output-format stream-json --verbose 2>&1 | tee /tmp/claude-output.log; tmux wait-for -S claude' && ` +
+			`tmux wait-for claude && cat /tmp/claude-output.log`
+	}
+
+	extraEnv = []string{
 		"FAMILIAR_PROMPT=" + prompt,
 		"FAMILIAR_CLAUDE_MD=" + claudeMD,
 	}
+	if len(bootstrap) > 0 {
+		extraEnv = append(extraEnv, "FAMILIAR_BOOTSTRAP="+strings.Join(bootstrap, "\n"))
+	}
+	return []string{"-c", setupCmd}, extraEnv
 }
 
 // StopAll stops all active sessions.
@@ -402,7 +1064,7 @@ func (s *Spawner) StopAll(ctx context.Context) {
 
 	for _, id := range sessionIDs {
 		if err := s.Stop(ctx, id); err != nil {
-			log.Printf("warning: failed to stop session %s: %v", id, err)
+			slog.Warn("failed to stop session", "agent_id", id, "error", err)
 		}
 	}
 }
@@ -9,6 +9,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/metrics"
 )
 
 func TestSpawner_Spawn(t *testing.T) {
@@ -264,7 +267,7 @@ func TestContainerCmd(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cmd, env := containerCmd(tt.prompt, tt.claudeMD)
+			cmd, env := containerCmd(tt.prompt, tt.claudeMD, false, nil)
 
 			// Should produce shell command via /bin/sh -c
 			if len(cmd) != 2 || cmd[0] != "-c" {
@@ -311,6 +314,11 @@ func TestContainerCmd(t *testing.T) {
 				t.Error("command should invoke claude with --dangerously-skip-permissions -p")
 			}
 
+			// Command should stream JSON output so progress and usage can be tracked
+			if !strings.Contains(cmd[1], "--output-format stream-json --verbose") {
+				t.Error("command should invoke claude with --output-format stream-json --verbose")
+			}
+
 			// Command should use tmux
 			if !strings.Contains(cmd[1], "tmux new-session") {
 				t.Error("command should use tmux")
@@ -342,6 +350,64 @@ func TestContainerCmd(t *testing.T) {
 	}
 }
 
+func TestContainerCmd_Interactive(t *testing.T) {
+	cmd, env := containerCmd("Fix the build", "# Agent", true, nil)
+
+	if len(cmd) != 2 || cmd[0] != "-c" {
+		t.Fatalf("cmd = %v, want [-c <command>]", cmd)
+	}
+
+	// Interactive sessions must not use print mode, which exits after one response
+	if strings.Contains(cmd[1], "claude --dangerously-skip-permissions -p") {
+		t.Error("interactive command should not use -p")
+	}
+	if !strings.Contains(cmd[1], `claude --dangerously-skip-permissions "$FAMILIAR_PROMPT"`) {
+		t.Error("interactive command should start claude with the prompt")
+	}
+
+	// The pane is mirrored to the output file so logs are still captured
+	if !strings.Contains(cmd[1], "tmux pipe-pane -t claude") {
+		t.Error("interactive command should mirror the tmux pane to the output file")
+	}
+	if !strings.Contains(cmd[1], agentOutputPath) {
+		t.Errorf("interactive command should write output to %s", agentOutputPath)
+	}
+
+	if len(env) == 0 || env[0] != "FAMILIAR_PROMPT=Fix the build" {
+		t.Errorf("env = %v, want FAMILIAR_PROMPT first", env)
+	}
+}
+
+func TestContainerCmd_Bootstrap(t *testing.T) {
+	cmd, env := containerCmd("Fix the build", "# Agent", false, []string{"npm ci", "cp .env.example .env"})
+
+	// Bootstrap runs before Claude starts and stops the run on failure
+	bootstrap := strings.Index(cmd[1], `sh -exc "$FAMILIAR_BOOTSTRAP"`)
+	tmux := strings.Index(cmd[1], "tmux new-session")
+	if bootstrap < 0 || tmux < 0 || bootstrap > tmux {
+		t.Errorf("command should run bootstrap commands before starting tmux:\n%s", cmd[1])
+	}
+	if !strings.Contains(cmd[1], "sh -ex .familiar/bootstrap.sh") {
+		t.Error("command should fall back to .familiar/bootstrap.sh")
+	}
+	if !strings.Contains(cmd[1], "exit $status") {
+		t.Error("command should exit when bootstrap fails")
+	}
+
+	want := "FAMILIAR_BOOTSTRAP=npm ci\ncp .env.example .env"
+	if env[len(env)-1] != want {
+		t.Errorf("env = %v, want %q last", env, want)
+	}
+
+	// Without configured commands the variable is unset so the script is used
+	_, env = containerCmd("Fix the build", "# Agent", false, nil)
+	for _, e := range env {
+		if strings.HasPrefix(e, "FAMILIAR_BOOTSTRAP=") {
+			t.Errorf("env should not set FAMILIAR_BOOTSTRAP without commands: %v", env)
+		}
+	}
+}
+
 func TestNewSpawner_WarnsOnEmptyClaudeAuthDir(t *testing.T) {
 	// Skip if Docker not available
 	if os.Getenv("DOCKER_HOST") == "" && os.Getenv("CI") == "" {
@@ -603,3 +669,132 @@ func TestSpawner_CaptureAndStop_NonExistent(t *testing.T) {
 		t.Error("CaptureAndStop() expected error for non-existent session")
 	}
 }
+
+func TestSpawner_Wait(t *testing.T) {
+	spawner := &Spawner{sessions: make(map[string]*Session)}
+	session := &Session{ID: "waiting", Status: "running", done: make(chan struct{})}
+	spawner.sessions["waiting"] = session
+
+	result := make(chan error, 1)
+	go func() {
+		result <- spawner.Wait(context.Background(), "waiting")
+	}()
+
+	select {
+	case <-result:
+		t.Fatal("Wait() returned before the session ended")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(session.done)
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("Wait() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait() did not return after the session ended")
+	}
+}
+
+func TestSpawner_Wait_UnknownSession(t *testing.T) {
+	spawner := &Spawner{sessions: make(map[string]*Session)}
+
+	if err := spawner.Wait(context.Background(), "missing"); err != nil {
+		t.Errorf("Wait() error = %v, want nil", err)
+	}
+}
+
+func TestSpawner_Wait_ContextCancelled(t *testing.T) {
+	spawner := &Spawner{sessions: make(map[string]*Session)}
+	spawner.sessions["stuck"] = &Session{ID: "stuck", done: make(chan struct{})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := spawner.Wait(ctx, "stuck"); err != context.Canceled {
+		t.Errorf("Wait() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestSpawner_RecordUsage(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+
+	spawner := &Spawner{sessions: make(map[string]*Session)}
+	session := &Session{ID: "usage-agent", Repo: "org/repo", Status: "running"}
+	spawner.sessions[session.ID] = session
+
+	output := []byte(`{"type":"result","total_cost_usd":0.12,"usage":{"input_tokens":300,"output_tokens":40}}`)
+	spawner.recordUsage(session, output)
+
+	if session.Usage == nil {
+		t.Fatal("session.Usage should be set")
+	}
+	if session.Usage.InputTokens != 300 {
+		t.Errorf("session.Usage.InputTokens = %d, want 300", session.Usage.InputTokens)
+	}
+
+	m := metrics.Get()
+	if m.RepoUsage["org/repo"].OutputTokens != 40 {
+		t.Errorf("RepoUsage[org/repo].OutputTokens = %d, want 40", m.RepoUsage["org/repo"].OutputTokens)
+	}
+}
+
+func TestSpawner_RecordUsage_NoResult(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+
+	spawner := &Spawner{sessions: make(map[string]*Session)}
+	session := &Session{ID: "quiet-agent", Repo: "org/repo"}
+
+	spawner.recordUsage(session, []byte("plain text only\n"))
+
+	if session.Usage != nil {
+		t.Errorf("session.Usage = %+v, want nil", session.Usage)
+	}
+	if m := metrics.Get(); m.AgentUsage.Runs != 0 {
+		t.Errorf("AgentUsage.Runs = %d, want 0", m.AgentUsage.Runs)
+	}
+}
+
+func TestSpawner_History(t *testing.T) {
+	spawner := &Spawner{sessions: make(map[string]*Session)}
+
+	for i := 0; i < maxHistory+5; i++ {
+		spawner.recordHistory(&Session{ID: fmt.Sprintf("agent-%d", i), Status: "running"})
+	}
+
+	history := spawner.History()
+	if len(history) != maxHistory {
+		t.Fatalf("len(History()) = %d, want %d", len(history), maxHistory)
+	}
+	if history[0].ID != fmt.Sprintf("agent-%d", maxHistory+4) {
+		t.Errorf("History()[0].ID = %q, want most recent session", history[0].ID)
+	}
+	if history[0].EndedAt.IsZero() {
+		t.Error("finished session should have EndedAt set")
+	}
+}
+
+func TestSpawner_SetMaxAgents(t *testing.T) {
+	spawner := &Spawner{
+		cfg:      SpawnerConfig{MaxAgents: 5},
+		sessions: make(map[string]*Session),
+	}
+
+	if err := spawner.SetMaxAgents(8); err != nil {
+		t.Fatalf("SetMaxAgents() error = %v", err)
+	}
+	if got := spawner.MaxAgents(); got != 8 {
+		t.Errorf("MaxAgents() = %d, want 8", got)
+	}
+
+	if err := spawner.SetMaxAgents(0); err == nil {
+		t.Error("SetMaxAgents(0) should fail")
+	}
+	if got := spawner.MaxAgents(); got != 8 {
+		t.Errorf("MaxAgents() = %d after invalid update, want 8", got)
+	}
+}
@@ -153,6 +153,62 @@ func TestSpawner_CheckTimeouts_NoCallback(t *testing.T) {
 	}
 }
 
+func TestSpawner_CheckTimeouts_Interactive(t *testing.T) {
+	spawner := &Spawner{
+		cfg: SpawnerConfig{
+			TimeoutMinutes:     1,
+			InteractiveMinutes: 60,
+		},
+		sessions: make(map[string]*Session),
+	}
+
+	// Past the normal timeout but within the interactive one
+	spawner.sessions["interactive"] = &Session{
+		ID:          "interactive",
+		StartedAt:   time.Now().Add(-10 * time.Minute),
+		Status:      "running",
+		Interactive: true,
+	}
+	spawner.sessions["batch"] = &Session{
+		ID:        "batch",
+		StartedAt: time.Now().Add(-10 * time.Minute),
+		Status:    "running",
+	}
+
+	spawner.checkTimeouts()
+
+	if got := spawner.sessions["interactive"].Status; got != "running" {
+		t.Errorf("interactive session status = %q, want %q", got, "running")
+	}
+	if got := spawner.sessions["batch"].Status; got != "timed_out" {
+		t.Errorf("batch session status = %q, want %q", got, "timed_out")
+	}
+}
+
+func TestSpawner_TimeoutFor(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         SpawnerConfig
+		interactive bool
+		want        time.Duration
+	}{
+		{"batch", SpawnerConfig{TimeoutMinutes: 30, InteractiveMinutes: 120}, false, 30 * time.Minute},
+		{"interactive", SpawnerConfig{TimeoutMinutes: 30, InteractiveMinutes: 120}, true, 120 * time.Minute},
+		{"interactive falls back", SpawnerConfig{TimeoutMinutes: 30}, true, 30 * time.Minute},
+		{"no timeout", SpawnerConfig{}, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spawner := &Spawner{cfg: tt.cfg}
+			got := spawner.timeoutFor(&Session{Interactive: tt.interactive})
+			if got != tt.want {
+				t.Errorf("timeoutFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestSpawner_StopAll(t *testing.T) {
 	// Skip if Docker not available - we need real containers for StopAll
 	if !dockerAvailable() {
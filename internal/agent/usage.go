@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// ErrNoUsage is returned when agent output contains no Claude result record.
+var ErrNoUsage = errors.New("no usage record in agent output")
+
+// ErrNoResult is returned when agent output contains no final response.
+var ErrNoResult = errors.New("no result in agent output")
+
+// Usage reports token consumption and estimated cost for an agent run.
+type Usage struct {
+	InputTokens      int     `json:"input_tokens"`
+	OutputTokens     int     `json:"output_tokens"`
+	CacheReadTokens  int     `json:"cache_read_tokens"`
+	CacheWriteTokens int     `json:"cache_write_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	NumTurns         int     `json:"num_turns"`
+	DurationMS       int     `json:"duration_ms"`
+}
+
+// claudeResult is the final record Claude CLI prints with --output-format json.
+type claudeResult struct {
+	Type         string   `json:"type"`
+	Result       string   `json:"result"` // The agent's final response
+	TotalCostUSD *float64 `json:"total_cost_usd"`
+	CostUSD      *float64 `json:"cost_usd"` // older CLI versions
+	NumTurns     int      `json:"num_turns"`
+	DurationMS   int      `json:"duration_ms"`
+	Usage        struct {
+		InputTokens              int `json:"input_tokens"`
+		OutputTokens             int `json:"output_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	} `json:"usage"`
+}
+
+// ParseUsage extracts token usage and cost from captured Claude CLI output.
+// The last result record wins; non-JSON lines are ignored.
+func ParseUsage(output []byte) (*Usage, error) {
+	result, err := lastResult(output)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, ErrNoUsage
+	}
+
+	u := &Usage{
+		InputTokens:      result.Usage.InputTokens,
+		OutputTokens:     result.Usage.OutputTokens,
+		CacheReadTokens:  result.Usage.CacheReadInputTokens,
+		CacheWriteTokens: result.Usage.CacheCreationInputTokens,
+		NumTurns:         result.NumTurns,
+		DurationMS:       result.DurationMS,
+	}
+	switch {
+	case result.TotalCostUSD != nil:
+		u.CostUSD = *result.TotalCostUSD
+	case result.CostUSD != nil:
+		u.CostUSD = *result.CostUSD
+	}
+	return u, nil
+}
+
+// ParseResult extracts the agent's final response from captured Claude CLI
+// output.
+func ParseResult(output []byte) (string, error) {
+	result, err := lastResult(output)
+	if err != nil {
+		return "", err
+	}
+	if result == nil || result.Result == "" {
+		return "", ErrNoResult
+	}
+	return result.Result, nil
+}
+
+// lastResult returns the last result record in output, or nil if there is
+// none. Non-JSON lines are ignored.
+func lastResult(output []byte) (*claudeResult, error) {
+	var last *claudeResult
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+
+		var result claudeResult
+		if err := json.Unmarshal(line, &result); err != nil || result.Type != "result" {
+			continue
+		}
+		last = &result
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return last, nil
+}
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseUsage(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    Usage
+		wantErr error
+	}{
+		{
+			name: "result record",
+			output: `some setup noise
+{"type":"result","subtype":"success","total_cost_usd":0.42,"num_turns":7,"duration_ms":91000,"usage":{"input_tokens":1200,"output_tokens":800,"cache_read_input_tokens":5000,"cache_creation_input_tokens":300},"result":"Done"}
+`,
+			want: Usage{
+				InputTokens:      1200,
+				OutputTokens:     800,
+				CacheReadTokens:  5000,
+				CacheWriteTokens: 300,
+				CostUSD:          0.42,
+				NumTurns:         7,
+				DurationMS:       91000,
+			},
+		},
+		{
+			name:   "legacy cost field",
+			output: `{"type":"result","cost_usd":0.05,"usage":{"input_tokens":10,"output_tokens":20}}`,
+			want:   Usage{InputTokens: 10, OutputTokens: 20, CostUSD: 0.05},
+		},
+		{
+			name: "last result wins",
+			output: `{"type":"result","total_cost_usd":0.01,"usage":{"input_tokens":1}}
+{"type":"assistant","message":{}}
+{"type":"result","total_cost_usd":0.02,"usage":{"input_tokens":2}}`,
+			want: Usage{InputTokens: 2, CostUSD: 0.02},
+		},
+		{
+			name:    "plain text output",
+			output:  "Review complete.\n",
+			wantErr: ErrNoUsage,
+		},
+		{
+			name:    "empty output",
+			output:  "",
+			wantErr: ErrNoUsage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseUsage([]byte(tt.output))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseUsage() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseUsage() error = %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("ParseUsage() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "result record",
+			output: `{"type":"assistant","message":{}}
+{"type":"result","subtype":"success","result":"No security issues found."}`,
+			want: "No security issues found.",
+		},
+		{
+			name:    "result without text",
+			output:  `{"type":"result","total_cost_usd":0.01}`,
+			wantErr: ErrNoResult,
+		},
+		{
+			name:    "plain text output",
+			output:  "Review complete.\n",
+			wantErr: ErrNoResult,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseResult([]byte(tt.output))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseResult() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseResult() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseResult() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"regexp"
+	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -13,6 +17,8 @@ type Config struct {
 	BotUsername string                  `yaml:"bot_username"`
 	Server      ServerConfig            `yaml:"server"`
 	Logging     LoggingConfig           `yaml:"logging"`
+	Tracing     TracingConfig           `yaml:"tracing"`
+	Metrics     MetricsConfig           `yaml:"metrics"`
 	Providers   ProvidersConfig         `yaml:"providers"`
 	Events      ServerEventsConfig      `yaml:"events"`
 	Permissions ServerPermissionsConfig `yaml:"permissions"`
@@ -21,6 +27,25 @@ type Config struct {
 	LLM         LLMConfig               `yaml:"llm"`
 	Concurrency ConcurrencyConfig       `yaml:"concurrency"`
 	RepoCache   RepoCacheConfig         `yaml:"repo_cache"`
+	Hooks       HooksConfig             `yaml:"hooks"`
+	Personas    []PersonaConfig         `yaml:"personas"`
+	Reload      ReloadConfig            `yaml:"reload"`
+	Include     []string                `yaml:"include"` // Files merged over this one, e.g. "conf.d/*.yaml"
+}
+
+// ReloadConfig controls reloading the config file when it changes, in
+// addition to on SIGHUP and via the admin API.
+type ReloadConfig struct {
+	Watch           bool `yaml:"watch"`
+	IntervalSeconds int  `yaml:"interval_seconds"` // How often the file is checked
+}
+
+// HooksConfig holds shell commands run in an agent's worktree on the
+// Familiar host before the agent starts and after it finishes.
+type HooksConfig struct {
+	PreAgent       []string `yaml:"pre_agent"`
+	PostAgent      []string `yaml:"post_agent"`
+	TimeoutSeconds int      `yaml:"timeout_seconds"` // Per command
 }
 
 // ServerEventsConfig controls which events are enabled at server level.
@@ -37,6 +62,10 @@ type ServerPermissionsConfig struct {
 	Approve        string `yaml:"approve"`
 	PushCommits    string `yaml:"push_commits"`
 	DismissReviews string `yaml:"dismiss_reviews"`
+
+	// Events overrides the permissions above for individual event types,
+	// keyed by type (e.g. "mr_comment").
+	Events map[string]EventPermissionsConfig `yaml:"events"`
 }
 
 // ServerPromptsConfig holds default prompts per event type.
@@ -49,11 +78,99 @@ type ServerPromptsConfig struct {
 
 // AgentsConfig holds agent settings.
 type AgentsConfig struct {
-	TimeoutMinutes  int    `yaml:"timeout_minutes"`
-	DebounceSeconds int    `yaml:"debounce_seconds"`
-	Image           string `yaml:"image"`
-	ClaudeAuthDir   string `yaml:"claude_auth_dir"` // Host path for Docker bind mounts
-	NetworkMode     string `yaml:"network_mode"`    // Docker network mode (e.g. "host")
+	TimeoutMinutes            int               `yaml:"timeout_minutes"`
+	IdleTimeoutMinutes        int               `yaml:"idle_timeout_minutes"`        // No-output limit before an agent is terminated; 0 disables
+	InteractiveTimeoutMinutes int               `yaml:"interactive_timeout_minutes"` // Run limit for interactive sessions
+	InteractiveEvents         []string          `yaml:"interactive_events"`          // Event types that spawn interactive sessions
+	DebounceSeconds           int               `yaml:"debounce_seconds"`
+	Image                     string            `yaml:"image"`
+	ImageDigest               string            `yaml:"image_digest"`    // sha256:... the image must match before agents spawn
+	ClaudeAuthDir             string            `yaml:"claude_auth_dir"` // Host path for Docker bind mounts
+	NetworkMode               string            `yaml:"network_mode"`    // Docker network mode (e.g. "host")
+	Env                       EnvConfig         `yaml:"env"`
+	Mode                      string            `yaml:"mode"`            // "direct" (default) or "patch"
+	DryRun                    bool              `yaml:"dry_run"`         // Log the agents events would start instead of starting them
+	Acknowledge               string            `yaml:"acknowledge"`     // How trigger comments are acknowledged: reaction (default), comment, or none
+	Summary                   string            `yaml:"summary"`         // When a finished agent's summary is posted: always (default), failures, or never
+	BusyPolicy                string            `yaml:"busy_policy"`     // What an event for an MR with agents still running does: queue (default), skip, supersede, or allow
+	Credentials               string            `yaml:"credentials"`     // Which provider token agents get: full (default) or scoped
+	ProtectedPaths            []string          `yaml:"protected_paths"` // Paths agents must never change, e.g. ".github/workflows/**"
+	Docker                    DockerConfig      `yaml:"docker"`
+	PullPolicy                string            `yaml:"pull_policy"` // always, if-not-present (default), or never
+	Registry                  RegistryConfig    `yaml:"registry"`
+	Healthcheck               HealthcheckConfig `yaml:"healthcheck"`
+
+	// Docker Desktop support: how host paths (repo_cache.host_dir,
+	// claude_auth_dir) are translated for bind mounts.
+	HostPathStyle   string `yaml:"host_path_style"`   // linux (default), windows, or macos
+	HostMountPrefix string `yaml:"host_mount_prefix"` // Windows drive prefix (default /host_mnt)
+}
+
+// How agents.acknowledge lets a commenter know their comment started agents.
+const (
+	AcknowledgeReaction = "reaction" // React to the comment, or reply where reactions aren't supported
+	AcknowledgeComment  = "comment"  // Reply with the agents' IDs
+	AcknowledgeNone     = "none"
+)
+
+// When agents.summary posts a finished agent's outcome, duration, changed
+// files, and log location on the MR.
+const (
+	SummaryAlways   = "always"
+	SummaryFailures = "failures" // Only for agents that failed or timed out
+	SummaryNever    = "never"
+)
+
+// Which provider credentials agents.credentials gives an agent.
+const (
+	CredentialsFull   = "full"   // The provider token, whatever the agent is allowed
+	CredentialsScoped = "scoped" // The read-only token unless pushing or merging is allowed, or patch mode without one
+)
+
+// What agents.busy_policy does with an event for an MR whose agents are
+// still running.
+const (
+	BusyQueue     = "queue"     // Handle it once they finish
+	BusySkip      = "skip"      // Drop it
+	BusySupersede = "supersede" // Stop them and start the event's agents
+	BusyAllow     = "allow"     // Start its agents alongside them
+)
+
+// DockerConfig selects the Docker daemon agents run on. Empty fields fall
+// back to DOCKER_HOST and DOCKER_CERT_PATH in the process environment.
+type DockerConfig struct {
+	Host      string `yaml:"host"`       // e.g. tcp://docker.internal:2376
+	CertPath  string `yaml:"cert_path"`  // Directory holding ca.pem, cert.pem, and key.pem
+	TLSVerify bool   `yaml:"tls_verify"` // Verify the daemon's certificate (with cert_path)
+}
+
+// HealthcheckConfig defines a Docker healthcheck for agent containers.
+// Agents whose healthcheck keeps failing are terminated before their
+// timeout. An empty Cmd keeps the image's own HEALTHCHECK, if any; zero
+// values use Docker's defaults.
+type HealthcheckConfig struct {
+	Cmd                string `yaml:"cmd"` // Shell command run in the container; exit 0 means healthy
+	IntervalSeconds    int    `yaml:"interval_seconds"`
+	TimeoutSeconds     int    `yaml:"timeout_seconds"`
+	StartPeriodSeconds int    `yaml:"start_period_seconds"`
+	Retries            int    `yaml:"retries"` // Consecutive failures before unhealthy
+}
+
+// RegistryConfig holds credentials for pulling agent images from a private
+// registry: a username and password, or a Docker credential helper name
+// (e.g. "ecr-login" for docker-credential-ecr-login).
+type RegistryConfig struct {
+	Username         string `yaml:"username"`
+	Password         string `yaml:"password"`
+	CredentialHelper string `yaml:"credential_helper"`
+	ServerAddress    string `yaml:"server_address"` // Defaults to the image's registry
+}
+
+// EnvConfig filters environment variables passed to agent containers.
+// Patterns use glob syntax (e.g. "GITLAB_*").
+type EnvConfig struct {
+	Allow []string `yaml:"allow"` // If set, only matching names are passed
+	Deny  []string `yaml:"deny"`  // Matching names are never passed
 }
 
 // ConcurrencyConfig holds concurrency limits.
@@ -66,6 +183,32 @@ type ConcurrencyConfig struct {
 type RepoCacheConfig struct {
 	Dir     string `yaml:"dir"`      // Container path for git operations
 	HostDir string `yaml:"host_dir"` // Host path for Docker bind mounts
+
+	// Least recently used repos without active worktrees are evicted when
+	// the cache grows past this size. 0 means no limit.
+	MaxSizeMB int `yaml:"max_size_mb"`
+
+	// Worktrees older than this with no running agent are pruned hourly,
+	// cleaning up after crashes and restarts. 0 disables pruning.
+	WorktreeMaxAgeHours int `yaml:"worktree_max_age_hours"`
+
+	// Repos used in the last day are fetched this often in the background,
+	// so events find them up to date. 0 disables background fetches.
+	FetchIntervalMinutes int `yaml:"fetch_interval_minutes"`
+
+	Maintenance MaintenanceConfig `yaml:"maintenance"`
+
+	// Limit what new clones download: CloneDepth keeps only recent history
+	// and CloneFilter (e.g. "blob:none") fetches file contents on demand.
+	// Both default to full clones.
+	CloneDepth  int    `yaml:"clone_depth"`
+	CloneFilter string `yaml:"clone_filter"`
+}
+
+// MaintenanceConfig schedules git gc for cached repos.
+type MaintenanceConfig struct {
+	IntervalHours int    `yaml:"interval_hours"` // 0 disables maintenance
+	Window        string `yaml:"window"`         // Local time "HH:MM-HH:MM" to run in; empty means any time
 }
 
 // LLMConfig holds LLM/intent parsing configuration.
@@ -79,12 +222,68 @@ type LLMAPIConfig struct {
 	Provider string `yaml:"provider"`
 	Model    string `yaml:"model"`
 	APIKey   string `yaml:"api_key"`
+
+	// Token prices used to estimate intent parsing cost; zero disables
+	// the estimate.
+	InputUSDPerMTok  float64 `yaml:"input_usd_per_mtok"`
+	OutputUSDPerMTok float64 `yaml:"output_usd_per_mtok"`
 }
 
 // ServerConfig holds HTTP server settings.
 type ServerConfig struct {
-	Host string `yaml:"host"`
-	Port int    `yaml:"port"`
+	Host       string `yaml:"host"`
+	Port       int    `yaml:"port"`
+	AdminToken string `yaml:"admin_token"` // Bearer token for /admin endpoints; empty disables them
+
+	// AdminSocket is a Unix socket path serving the admin API without the
+	// token to local users who can open it. Empty disables it.
+	AdminSocket string `yaml:"admin_socket"`
+
+	// OpsAddr (host:port) serves /health and /metrics on their own listener
+	// instead of alongside the webhooks; empty keeps them on the main one.
+	OpsAddr string `yaml:"ops_addr"`
+	// OpsToken, if set, is required as a bearer token for /health and /metrics.
+	OpsToken string `yaml:"ops_token"`
+
+	TLS TLSConfig `yaml:"tls"`
+
+	// Timeouts bound slow clients on the HTTP listeners.
+	Timeouts HTTPTimeoutsConfig `yaml:"timeouts"`
+
+	// CORS lets browser apps hosted elsewhere call the admin and status
+	// endpoints.
+	CORS CORSConfig `yaml:"cors"`
+}
+
+// CORSConfig controls cross-origin access to the admin and status
+// endpoints. No origins disables CORS.
+type CORSConfig struct {
+	// AllowedOrigins are exact origins such as "https://dash.example.com".
+	// "*" allows any origin, without credentials.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	AllowedMethods []string `yaml:"allowed_methods"`
+}
+
+// HTTPTimeoutsConfig holds HTTP server timeouts, in seconds. Zero disables
+// a timeout.
+type HTTPTimeoutsConfig struct {
+	ReadHeaderSeconds int `yaml:"read_header_seconds"` // Time to send request headers
+	ReadSeconds       int `yaml:"read_seconds"`        // Time to send the whole request
+	WriteSeconds      int `yaml:"write_seconds"`       // Time to write the response; followed log streams are exempt
+	IdleSeconds       int `yaml:"idle_seconds"`        // Keep-alive connections idle longer are closed
+}
+
+// TLSConfig holds the certificate Familiar serves HTTPS with. Empty files
+// serve plain HTTP.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	Reload   bool   `yaml:"reload"` // Pick up renewed files without a restart
+
+	// ClientCAFile verifies client certificates; a verified certificate
+	// authorizes admin, metrics, and log-streaming routes in place of a
+	// token. Webhook deliveries don't need one.
+	ClientCAFile string `yaml:"client_ca_file"`
 }
 
 // LoggingConfig holds logging settings.
@@ -92,6 +291,108 @@ type LoggingConfig struct {
 	Dir           string `yaml:"dir"`
 	HostDir       string `yaml:"host_dir"` // Absolute host path for log display
 	RetentionDays int    `yaml:"retention_days"`
+
+	// CompressAfterDays gzips agent logs older than this; 0 disables.
+	CompressAfterDays int `yaml:"compress_after_days"`
+
+	// MaxSizeMB caps the log directory, deleting the oldest logs first when
+	// exceeded; 0 means no limit.
+	MaxSizeMB int `yaml:"max_size_mb"`
+
+	// RedactPatterns are regular expressions scrubbed from captured agent
+	// output, in addition to known secrets and common token formats.
+	RedactPatterns []string `yaml:"redact_patterns"`
+
+	// Level and Format control Familiar's own logs: debug, info, warn, or
+	// error; text or json.
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+
+	// File writes Familiar's own logs to a rotated file instead of stderr.
+	File LogFileConfig `yaml:"file"`
+
+	// Ship forwards logs to external sinks.
+	Ship ShipConfig `yaml:"ship"`
+
+	// Access logs each HTTP request Familiar serves.
+	Access AccessLogConfig `yaml:"access"`
+
+	// AuditFile records, one JSON object per line, every privileged
+	// permission granted to an agent and whether it was used where that can
+	// be told. Empty disables it.
+	AuditFile string `yaml:"audit_file"`
+}
+
+// AccessLogConfig controls logging each HTTP request.
+type AccessLogConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Exclude []string `yaml:"exclude"` // Paths not logged, e.g. polled health checks
+}
+
+// LogFileConfig controls writing Familiar's own logs to a file. Empty Path
+// logs to stderr.
+type LogFileConfig struct {
+	Path       string `yaml:"path"`
+	MaxSizeMB  int    `yaml:"max_size_mb"` // Rotate once the file reaches this size; 0 never rotates
+	MaxBackups int    `yaml:"max_backups"` // Rotated files to keep
+}
+
+// MetricsConfig controls persisting metric counters across restarts. Empty
+// StateFile keeps them in memory only.
+type MetricsConfig struct {
+	StateFile               string `yaml:"state_file"`
+	SnapshotIntervalSeconds int    `yaml:"snapshot_interval_seconds"`
+
+	// Push sends metrics to a collector for environments that can't scrape.
+	Push MetricsPushConfig `yaml:"push"`
+}
+
+// MetricsPushConfig controls pushing metrics to StatsD or an OTLP collector.
+// Empty Type disables pushing.
+type MetricsPushConfig struct {
+	Type            string            `yaml:"type"`     // statsd or otlp
+	Endpoint        string            `yaml:"endpoint"` // statsd: host:port; otlp: collector URL
+	IntervalSeconds int               `yaml:"interval_seconds"`
+	Prefix          string            `yaml:"prefix"`  // statsd only
+	Headers         map[string]string `yaml:"headers"` // otlp only
+}
+
+// TracingConfig controls exporting OpenTelemetry traces of the webhook
+// pipeline. Tracing is off without an Endpoint.
+type TracingConfig struct {
+	Endpoint    string            `yaml:"endpoint"`     // OTLP/HTTP collector URL, e.g. http://localhost:4318
+	Headers     map[string]string `yaml:"headers"`      // Sent with each export, e.g. for auth
+	SampleRatio float64           `yaml:"sample_ratio"` // Fraction of webhooks traced, 0 to 1
+	ServiceName string            `yaml:"service_name"`
+}
+
+// ShipConfig controls forwarding of server and agent logs to external sinks.
+// Nothing is shipped without sinks.
+type ShipConfig struct {
+	ServerLogs           bool         `yaml:"server_logs"` // Familiar's own logs
+	AgentLogs            bool         `yaml:"agent_logs"`  // Each agent's log once it finishes
+	BatchSize            int          `yaml:"batch_size"`
+	FlushIntervalSeconds int          `yaml:"flush_interval_seconds"`
+	MaxRetries           int          `yaml:"max_retries"`
+	Sinks                []SinkConfig `yaml:"sinks"`
+}
+
+// SinkConfig configures one log sink. Which fields apply depends on Type:
+// syslog uses Address and Tag; loki uses URL, TenantID, Username, Password,
+// and Labels; cloudwatch uses Region, LogGroup, and LogStream.
+type SinkConfig struct {
+	Type     string            `yaml:"type"` // syslog, loki, or cloudwatch
+	Address  string            `yaml:"address"`
+	Tag      string            `yaml:"tag"`
+	URL      string            `yaml:"url"`
+	TenantID string            `yaml:"tenant_id"`
+	Username string            `yaml:"username"`
+	Password string            `yaml:"password"`
+	Labels   map[string]string `yaml:"labels"`
+
+	Region    string `yaml:"region"`
+	LogGroup  string `yaml:"log_group"`
+	LogStream string `yaml:"log_stream"`
 }
 
 // ProvidersConfig holds git provider configurations.
@@ -104,6 +405,7 @@ type ProvidersConfig struct {
 type GitHubConfig struct {
 	AuthMethod    string `yaml:"auth_method"`
 	Token         string `yaml:"token"`
+	ReadOnlyToken string `yaml:"read_only_token"` // Can read and comment but not push or merge; see agents.credentials
 	WebhookSecret string `yaml:"webhook_secret"`
 }
 
@@ -111,13 +413,11 @@ type GitHubConfig struct {
 type GitLabConfig struct {
 	AuthMethod    string `yaml:"auth_method"`
 	Token         string `yaml:"token"`
+	ReadOnlyToken string `yaml:"read_only_token"` // Can read and comment but not push or merge; see agents.credentials
 	WebhookSecret string `yaml:"webhook_secret"`
 	BaseURL       string `yaml:"base_url"`
 }
 
-// envVarPattern matches ${VAR_NAME} patterns.
-var envVarPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
-
 // DefaultConfig returns a Config with default values.
 func DefaultConfig() *Config {
 	return &Config{
@@ -125,45 +425,168 @@ func DefaultConfig() *Config {
 		Server: ServerConfig{
 			Host: "0.0.0.0",
 			Port: 7000,
+			Timeouts: HTTPTimeoutsConfig{
+				ReadHeaderSeconds: 10,
+				ReadSeconds:       30,
+				WriteSeconds:      60,
+				IdleSeconds:       120,
+			},
+			CORS: CORSConfig{
+				AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
+			},
 		},
 		Logging: LoggingConfig{
-			Dir:           "/var/log/familiar",
-			RetentionDays: 30,
+			Dir:               "/var/log/familiar",
+			RetentionDays:     30,
+			CompressAfterDays: 7,
+			Level:             "info",
+			Format:            "text",
+			File: LogFileConfig{
+				MaxSizeMB:  100,
+				MaxBackups: 5,
+			},
+			Access: AccessLogConfig{
+				Enabled: true,
+				Exclude: []string{"/health"},
+			},
+			Ship: ShipConfig{
+				ServerLogs:           true,
+				AgentLogs:            true,
+				BatchSize:            500,
+				FlushIntervalSeconds: 5,
+				MaxRetries:           3,
+			},
+		},
+		Metrics: MetricsConfig{
+			SnapshotIntervalSeconds: 60,
+			Push: MetricsPushConfig{
+				IntervalSeconds: 15,
+			},
+		},
+		Tracing: TracingConfig{
+			SampleRatio: 1,
+			ServiceName: "familiar",
 		},
 		Concurrency: ConcurrencyConfig{
 			MaxAgents: 5,
 			QueueSize: 20,
 		},
 		RepoCache: RepoCacheConfig{
-			Dir: "./cache/repos",
+			Dir:                  "./cache/repos",
+			WorktreeMaxAgeHours:  24,
+			FetchIntervalMinutes: 10,
+			Maintenance: MaintenanceConfig{
+				IntervalHours: 24,
+			},
+		},
+		Hooks: HooksConfig{
+			TimeoutSeconds: 300,
+		},
+		Reload: ReloadConfig{
+			IntervalSeconds: 5,
 		},
 		Agents: AgentsConfig{
-			TimeoutMinutes:  30,
-			DebounceSeconds: 10,
-			Image:           "familiar-agent:latest",
+			TimeoutMinutes:            30,
+			IdleTimeoutMinutes:        15,
+			InteractiveTimeoutMinutes: 120,
+			DebounceSeconds:           10,
+			Image:                     "familiar-agent:latest",
+			Acknowledge:               AcknowledgeReaction,
+			Summary:                   SummaryAlways,
+			BusyPolicy:                BusyQueue,
+			Credentials:               CredentialsFull,
+			Docker: DockerConfig{
+				TLSVerify: true,
+			},
 		},
 	}
 }
 
-// Load reads and parses the config file at the given path.
+// Load reads and parses the config file at the given path, then applies
+// the files it includes and the FAMILIAR_* environment variables on top.
+// With an empty path, the config comes from defaults and the environment
+// alone. Keys the config doesn't define are rejected, so typos fail
+// instead of leaving a setting at its default.
 func Load(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("reading config file: %w", err)
-	}
-
-	// Substitute environment variables
-	data = envVarPattern.ReplaceAllFunc(data, func(match []byte) []byte {
-		varName := envVarPattern.FindSubmatch(match)[1]
-		return []byte(os.Getenv(string(varName)))
-	})
-
 	// Start with defaults
 	cfg := DefaultConfig()
 
-	if err := yaml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("parsing config file: %w", err)
+	if path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			return nil, err
+		}
+		if err := loadIncludes(cfg, filepath.Dir(path)); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyEnv(cfg); err != nil {
+		return nil, fmt.Errorf("applying environment: %w", err)
+	}
+	// An unknown permission would grant nothing without saying so
+	if err := PermissionsConfig(cfg.Permissions).validate("permissions"); err != nil {
+		return nil, err
+	}
+	// A reload must not swap in new secrets it then cannot redact
+	if err := validateRegexps("logging.redact_patterns", cfg.Logging.RedactPatterns); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
 }
+
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	if sopsEncrypted(data) {
+		if data, err = decryptSops(path); err != nil {
+			return err
+		}
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return fmt.Errorf("parsing config file: %w", err)
+	}
+	if root.Kind == 0 {
+		return nil // Empty file
+	}
+	if err := checkKnownFields(data); err != nil {
+		return fmt.Errorf("parsing config file: %w", err)
+	}
+	if err := substituteNode(&root); err != nil {
+		return err
+	}
+	if err := root.Decode(cfg); err != nil {
+		return fmt.Errorf("parsing config file: %w", err)
+	}
+	return nil
+}
+
+// checkKnownFields rejects settings Config doesn't have. References are
+// only resolved after parsing, which decoding a node can't check, so this
+// decodes the file as written and ignores the type errors unresolved
+// references cause.
+func checkKnownFields(data []byte) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var scratch Config
+	var typeErr *yaml.TypeError
+	if err := dec.Decode(&scratch); !errors.As(err, &typeErr) {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return err
+	}
+	var unknown []string
+	for _, msg := range typeErr.Errors {
+		if strings.Contains(msg, " not found in type ") {
+			unknown = append(unknown, msg)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	return &yaml.TypeError{Errors: unknown}
+}
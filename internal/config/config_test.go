@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -20,6 +21,8 @@ logging:
   dir: "/var/log/familiar"
   host_dir: "/home/user/project/logs"
   retention_days: 30
+  redact_patterns:
+    - "internal-[0-9]+"
 `
 	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
 		t.Fatalf("Failed to write test config: %v", err)
@@ -36,6 +39,12 @@ logging:
 	if cfg.Server.Port != 8080 {
 		t.Errorf("Server.Port = %d, want %d", cfg.Server.Port, 8080)
 	}
+	if to := cfg.Server.Timeouts; to.ReadHeaderSeconds != 10 || to.ReadSeconds != 30 || to.WriteSeconds != 60 || to.IdleSeconds != 120 {
+		t.Errorf("Server.Timeouts = %+v, want 10s headers, 30s reads, 60s writes, 120s idle", to)
+	}
+	if cors := cfg.Server.CORS; len(cors.AllowedOrigins) != 0 || !reflect.DeepEqual(cors.AllowedMethods, []string{"GET", "POST", "PUT", "DELETE"}) {
+		t.Errorf("Server.CORS = %+v, want no origins and the admin API's methods", cors)
+	}
 	if cfg.Logging.Dir != "/var/log/familiar" {
 		t.Errorf("Logging.Dir = %q, want %q", cfg.Logging.Dir, "/var/log/familiar")
 	}
@@ -45,6 +54,31 @@ logging:
 	if cfg.Logging.RetentionDays != 30 {
 		t.Errorf("Logging.RetentionDays = %d, want %d", cfg.Logging.RetentionDays, 30)
 	}
+	if cfg.Logging.CompressAfterDays != 7 {
+		t.Errorf("Logging.CompressAfterDays = %d, want %d", cfg.Logging.CompressAfterDays, 7)
+	}
+	if ship := cfg.Logging.Ship; !ship.ServerLogs || !ship.AgentLogs || ship.BatchSize != 500 ||
+		ship.FlushIntervalSeconds != 5 || ship.MaxRetries != 3 || len(ship.Sinks) != 0 {
+		t.Errorf("Logging.Ship = %+v, want both log kinds, batches of 500 every 5s, 3 retries, no sinks", ship)
+	}
+	if file := cfg.Logging.File; file.Path != "" || file.MaxSizeMB != 100 || file.MaxBackups != 5 {
+		t.Errorf("Logging.File = %+v, want stderr logging with 100MB files and 5 backups", file)
+	}
+	if access := cfg.Logging.Access; !access.Enabled || !reflect.DeepEqual(access.Exclude, []string{"/health"}) {
+		t.Errorf("Logging.Access = %+v, want enabled, excluding /health", access)
+	}
+	if want := []string{"internal-[0-9]+"}; !reflect.DeepEqual(cfg.Logging.RedactPatterns, want) {
+		t.Errorf("Logging.RedactPatterns = %v, want %v", cfg.Logging.RedactPatterns, want)
+	}
+	if m := cfg.Metrics; m.StateFile != "" || m.SnapshotIntervalSeconds != 60 || m.Push.Type != "" || m.Push.IntervalSeconds != 15 {
+		t.Errorf("Metrics = %+v, want in-memory metrics snapshotted every 60s and pushed every 15s when enabled", m)
+	}
+	if r := cfg.Reload; r.Watch || r.IntervalSeconds != 5 {
+		t.Errorf("Reload = %+v, want watching off, checking every 5s when on", r)
+	}
+	if tr := cfg.Tracing; tr.Endpoint != "" || tr.SampleRatio != 1 || tr.ServiceName != "familiar" {
+		t.Errorf("Tracing = %+v, want disabled, sampling everything, as familiar", tr)
+	}
 }
 
 func TestLoadConfig_FileNotFound(t *testing.T) {
@@ -173,6 +207,15 @@ server:
 	if cfg.BotUsername != "Familiar" {
 		t.Errorf("BotUsername = %q, want default %q", cfg.BotUsername, "Familiar")
 	}
+	if cfg.Agents.IdleTimeoutMinutes != 15 {
+		t.Errorf("Agents.IdleTimeoutMinutes = %d, want default %d", cfg.Agents.IdleTimeoutMinutes, 15)
+	}
+	if cfg.Agents.InteractiveTimeoutMinutes != 120 {
+		t.Errorf("Agents.InteractiveTimeoutMinutes = %d, want default %d", cfg.Agents.InteractiveTimeoutMinutes, 120)
+	}
+	if !cfg.Agents.Docker.TLSVerify {
+		t.Error("Agents.Docker.TLSVerify should default to true")
+	}
 }
 
 func TestLoadConfig_BotUsernameOverride(t *testing.T) {
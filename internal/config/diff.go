@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Change is one setting that differs between two configs. Secret values
+// are masked.
+type Change struct {
+	Key string // dotted YAML path, e.g. "agents.debounce_seconds"
+	Old string
+	New string
+}
+
+// reloadable lists the settings, by key or key prefix, that a running
+// server applies on reload. Everything else needs a restart.
+var reloadable = []string{
+	"bot_username",
+	"events",
+	"permissions",
+	"prompts",
+	"personas",
+	"providers.github.token",
+	"providers.gitlab.token",
+	"providers.gitlab.base_url",
+	"concurrency",
+	"agents.image",
+	"agents.image_digest",
+	"agents.debounce_seconds",
+	"agents.interactive_events",
+	"agents.mode",
+	"include", // The included settings are compared individually
+}
+
+// RestartRequired reports whether the change only takes effect after a
+// restart.
+func (c Change) RestartRequired() bool {
+	for _, key := range reloadable {
+		if c.Key == key || strings.HasPrefix(c.Key, key+".") {
+			return false
+		}
+	}
+	return true
+}
+
+// Diff returns the settings that differ between old and new, in config
+// file order.
+func Diff(old, new *Config) []Change {
+	var changes []Change
+	diffValues("", reflect.ValueOf(*old), reflect.ValueOf(*new), &changes)
+	return changes
+}
+
+func diffValues(key string, old, new reflect.Value, changes *[]Change) {
+	if old.Kind() == reflect.Struct {
+		for i := range old.NumField() {
+			name, _, _ := strings.Cut(old.Type().Field(i).Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			if key != "" {
+				name = key + "." + name
+			}
+			diffValues(name, old.Field(i), new.Field(i), changes)
+		}
+		return
+	}
+	if reflect.DeepEqual(old.Interface(), new.Interface()) {
+		return
+	}
+
+	change := Change{Key: key, Old: fmt.Sprint(old.Interface()), New: fmt.Sprint(new.Interface())}
+	if slices.Contains(secretKeys, key[strings.LastIndex(key, ".")+1:]) {
+		change.Old, change.New = maskSecret(change.Old), maskSecret(change.New)
+	}
+	*changes = append(*changes, change)
+}
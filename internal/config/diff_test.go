@@ -0,0 +1,56 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := validConfig()
+	new := validConfig()
+	new.Server.Port = 8443
+	new.Agents.DebounceSeconds = 30
+	new.Permissions.Merge = PermissionOnRequest
+	new.Providers.GitLab.Token = "glpat-rotated"
+	new.Tracing.Headers = map[string]string{"Authorization": "Bearer x"}
+	new.Agents.InteractiveEvents = []string{"mention"}
+
+	want := []Change{
+		{Key: "server.port", Old: "7000", New: "8443"},
+		{Key: "tracing.headers", Old: "map[]", New: "********"},
+		{Key: "providers.gitlab.token", Old: "********", New: "********"},
+		{Key: "permissions.merge", Old: "", New: "on_request"},
+		{Key: "agents.interactive_events", Old: "[]", New: "[mention]"},
+		{Key: "agents.debounce_seconds", Old: "10", New: "30"},
+	}
+	if got := Diff(old, new); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() =\n%+v\nwant\n%+v", got, want)
+	}
+	if got := Diff(old, validConfig()); len(got) != 0 {
+		t.Errorf("Diff() of equal configs = %+v, want none", got)
+	}
+}
+
+func TestChange_RestartRequired(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: "server.port", want: true},
+		{key: "server.host", want: true},
+		{key: "agents.timeout_minutes", want: true},
+		{key: "agents.image", want: false},
+		{key: "agents.image_pull", want: true},
+		{key: "agents.debounce_seconds", want: false},
+		{key: "permissions.merge", want: false},
+		{key: "events.mr_opened", want: false},
+		{key: "concurrency.max_agents", want: false},
+		{key: "providers.gitlab.token", want: false},
+		{key: "providers.gitlab.webhook_secret", want: true},
+	}
+	for _, tt := range tests {
+		if got := (Change{Key: tt.key}).RestartRequired(); got != tt.want {
+			t.Errorf("Change{Key: %q}.RestartRequired() = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
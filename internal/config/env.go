@@ -0,0 +1,93 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix starts the environment variable for every config key: the
+// key's path, upper-cased, with "_" for ".", so server.port is set by
+// FAMILIAR_SERVER_PORT.
+const envPrefix = "FAMILIAR"
+
+// applyEnv overrides cfg with the FAMILIAR_* environment variables that
+// name config keys. Others are ignored.
+func applyEnv(cfg *Config) error {
+	var errs []error
+	applyEnvValue(envPrefix, "", reflect.ValueOf(cfg).Elem(), &errs)
+	return errors.Join(errs...)
+}
+
+func applyEnvValue(name, key string, v reflect.Value, errs *[]error) {
+	if v.Kind() == reflect.Struct {
+		for i := range v.NumField() {
+			field, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+			if field == "" || field == "-" {
+				continue
+			}
+			applyEnvValue(name+"_"+strings.ToUpper(field), strings.TrimPrefix(key+"."+field, "."), v.Field(i), errs)
+		}
+		return
+	}
+
+	value, ok, err := lookupEnv(name)
+	if err == nil && ok {
+		err = setFromEnv(v, value)
+	}
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("%s: %s: %w", name, key, err))
+	}
+}
+
+// lookupEnv returns the value of the variable name, with ${...} references
+// resolved, or the contents of the file named by name_FILE.
+func lookupEnv(name string) (string, bool, error) {
+	if value, ok := os.LookupEnv(name); ok {
+		data, err := substitute([]byte(value))
+		return string(data), true, err
+	}
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		value, err := readSecretFile(path, name+"_FILE")
+		return value, true, err
+	}
+	return "", false, nil
+}
+
+// setFromEnv sets v from an environment variable's value. Strings are
+// taken as is and string lists may be comma-separated; anything else is
+// parsed as YAML, e.g. "[{name: reviewer, events: [mr_opened]}]".
+func setFromEnv(v reflect.Value, value string) error {
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(value)
+		return nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(value), "["):
+		list := []string{}
+		for item := range strings.SplitSeq(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+		return nil
+	}
+
+	parsed := reflect.New(v.Type())
+	dec := yaml.NewDecoder(strings.NewReader(value))
+	dec.KnownFields(true)
+	if err := dec.Decode(parsed.Interface()); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			return fmt.Errorf("invalid value %q (want %s)", value, v.Type())
+		}
+		return fmt.Errorf("invalid value %q: %w", value, err)
+	}
+	v.Set(parsed.Elem())
+	return nil
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad_Environment(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "webhook_secret")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITLAB_PAT", "glpat-env")
+	t.Setenv("FAMILIAR_SERVER_PORT", "8080")
+	t.Setenv("FAMILIAR_PROVIDERS_GITLAB_TOKEN", "${GITLAB_PAT}")
+	t.Setenv("FAMILIAR_PROVIDERS_GITLAB_WEBHOOK_SECRET_FILE", secretFile)
+	t.Setenv("FAMILIAR_AGENTS_INTERACTIVE_EVENTS", "mention, mr_comment")
+	t.Setenv("FAMILIAR_RELOAD_WATCH", "true")
+	t.Setenv("FAMILIAR_PERSONAS", "[{name: reviewer, events: [mr_opened]}]")
+	t.Setenv("FAMILIAR_ADMIN_TOKEN", "not a config key")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Server.Port != 8080 {
+		t.Errorf("server.port = %d, want 8080", cfg.Server.Port)
+	}
+	if cfg.Providers.GitLab.Token != "glpat-env" {
+		t.Errorf("providers.gitlab.token = %q, want the ${GITLAB_PAT} reference resolved", cfg.Providers.GitLab.Token)
+	}
+	if cfg.Providers.GitLab.WebhookSecret != "from-file" {
+		t.Errorf("providers.gitlab.webhook_secret = %q, want it read from the _FILE variable", cfg.Providers.GitLab.WebhookSecret)
+	}
+	if want := []string{"mention", "mr_comment"}; !reflect.DeepEqual(cfg.Agents.InteractiveEvents, want) {
+		t.Errorf("agents.interactive_events = %v, want %v", cfg.Agents.InteractiveEvents, want)
+	}
+	if !cfg.Reload.Watch {
+		t.Error("reload.watch = false, want true")
+	}
+	if len(cfg.Personas) != 1 || cfg.Personas[0].Name != "reviewer" {
+		t.Errorf("personas = %+v, want the reviewer persona", cfg.Personas)
+	}
+	if cfg.Agents.DebounceSeconds != DefaultConfig().Agents.DebounceSeconds {
+		t.Errorf("agents.debounce_seconds = %d, want the default", cfg.Agents.DebounceSeconds)
+	}
+}
+
+func TestLoad_EnvironmentOverridesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("server:\n  port: 9000\nagents:\n  debounce_seconds: 30\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FAMILIAR_SERVER_PORT", "8080")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Server.Port != 8080 || cfg.Agents.DebounceSeconds != 30 {
+		t.Errorf("port = %d, debounce = %d; want 8080 from the environment and 30 from the file",
+			cfg.Server.Port, cfg.Agents.DebounceSeconds)
+	}
+}
+
+func TestLoad_InvalidEnvironment(t *testing.T) {
+	t.Setenv("FAMILIAR_SERVER_PORT", "eighty")
+	t.Setenv("FAMILIAR_RELOAD_WATCH", "sometimes")
+	t.Setenv("FAMILIAR_PERSONAS", "[{name: reviewer, evnets: [mr_opened]}]")
+
+	_, err := Load("")
+	if err == nil {
+		t.Fatal("Load() should fail on values that don't parse")
+	}
+	for _, want := range []string{`FAMILIAR_SERVER_PORT: server.port: invalid value "eighty"`, "FAMILIAR_RELOAD_WATCH", "evnets"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Load() error = %v, want it to contain %q", err, want)
+		}
+	}
+}
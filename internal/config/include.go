@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadIncludes decodes the files matched by cfg.Include over cfg, in
+// order, so each one's settings override those before it: nested settings
+// merge, while lists and plain values are replaced. Relative patterns are
+// resolved against dir, the main config file's directory.
+func loadIncludes(cfg *Config, dir string) error {
+	patterns := cfg.Include
+	files, err := includeFiles(dir, patterns)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		cfg.Include = nil
+		if err := loadFile(file, cfg); err != nil {
+			return fmt.Errorf("include %s: %w", file, err)
+		}
+		if len(cfg.Include) > 0 {
+			return fmt.Errorf("include %s: included files can't include others", file)
+		}
+	}
+	cfg.Include = patterns
+	return nil
+}
+
+// includeFiles returns the files matched by patterns, each pattern's
+// matches in lexical order. A glob may match nothing, as for an empty
+// conf.d directory, but a plain path must exist.
+func includeFiles(dir string, patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("include %s: %w", pattern, err)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(pattern, `*?[\`) {
+			return nil, fmt.Errorf("include %s: %w", pattern, os.ErrNotExist)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// watchedFiles returns the config file at path and the files it includes.
+// ${...} references in the include patterns aren't expanded.
+func watchedFiles(path string) []string {
+	files := []string{path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return files
+	}
+	var top struct {
+		Include []string `yaml:"include"`
+	}
+	if yaml.Unmarshal(data, &top) != nil {
+		return files
+	}
+	included, _ := includeFiles(filepath.Dir(path), top.Include)
+	return append(files, included...)
+}
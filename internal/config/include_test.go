@@ -0,0 +1,148 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFiles writes each file, by path relative to dir, and returns dir.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoad_Include(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"config.yaml": `
+include:
+  - providers.yaml
+  - conf.d/*.yaml
+  - empty.d/*.yaml
+agents:
+  debounce_seconds: 30
+  interactive_events: [mention]
+tracing:
+  headers:
+    X-Team: platform
+`,
+		"providers.yaml": `
+providers:
+  gitlab:
+    token: glpat-included
+    webhook_secret: included-secret
+`,
+		"conf.d/10-prompts.yaml": `
+prompts:
+  mr_opened: Review this merge request.
+agents:
+  interactive_events: [mr_comment]
+tracing:
+  headers:
+    Authorization: Bearer x
+`,
+		"conf.d/20-override.yaml": `
+prompts:
+  mr_opened: Review this merge request carefully.
+`,
+	})
+	os.Mkdir(filepath.Join(dir, "empty.d"), 0755)
+
+	cfg, err := Load(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Providers.GitLab.Token != "glpat-included" || cfg.Providers.GitLab.WebhookSecret != "included-secret" {
+		t.Errorf("providers.gitlab = %+v, want the included credentials", cfg.Providers.GitLab)
+	}
+	if cfg.Prompts.MROpened != "Review this merge request carefully." {
+		t.Errorf("prompts.mr_opened = %q, want the later file to win", cfg.Prompts.MROpened)
+	}
+	if cfg.Agents.DebounceSeconds != 30 {
+		t.Errorf("agents.debounce_seconds = %d, want 30 kept from the main file", cfg.Agents.DebounceSeconds)
+	}
+	if want := []string{"mr_comment"}; !reflect.DeepEqual(cfg.Agents.InteractiveEvents, want) {
+		t.Errorf("agents.interactive_events = %v, want lists replaced with %v", cfg.Agents.InteractiveEvents, want)
+	}
+	if want := map[string]string{"X-Team": "platform", "Authorization": "Bearer x"}; !reflect.DeepEqual(cfg.Tracing.Headers, want) {
+		t.Errorf("tracing.headers = %v, want maps merged into %v", cfg.Tracing.Headers, want)
+	}
+}
+
+func TestLoad_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name:    "missing file",
+			files:   map[string]string{"config.yaml": "include: [providers.yaml]\n"},
+			wantErr: "providers.yaml: file does not exist",
+		},
+		{
+			name: "unknown key",
+			files: map[string]string{
+				"config.yaml":       "include: [conf.d/*.yaml]\n",
+				"conf.d/agent.yaml": "agents:\n  debouce_seconds: 5\n",
+			},
+			wantErr: "agent.yaml: parsing config file: yaml: unmarshal errors:\n  line 2: field debouce_seconds not found",
+		},
+		{
+			name: "nested include",
+			files: map[string]string{
+				"config.yaml": "include: [a.yaml]\n",
+				"a.yaml":      "include: [b.yaml]\n",
+				"b.yaml":      "bot_username: nested\n",
+			},
+			wantErr: "included files can't include others",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, tt.files)
+			_, err := Load(filepath.Join(dir, "config.yaml"))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWatcher_CheckIncludes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"config.yaml":        "include: [conf.d/*.yaml]\n",
+		"conf.d/agents.yaml": "agents:\n  debounce_seconds: 5\n",
+	})
+
+	calls := 0
+	w := NewWatcher(filepath.Join(dir, "config.yaml"), time.Hour, func() { calls++ })
+	defer w.Stop()
+
+	included := filepath.Join(dir, "conf.d", "agents.yaml")
+	os.WriteFile(included, []byte("agents:\n  debounce_seconds: 15\n"), 0644)
+	os.Chtimes(included, time.Now(), time.Now().Add(time.Second))
+	w.check()
+	if calls != 1 {
+		t.Fatalf("onChange calls = %d after an included file changed, want 1", calls)
+	}
+
+	os.WriteFile(filepath.Join(dir, "conf.d", "prompts.yaml"), []byte("prompts: {}\n"), 0644)
+	w.check()
+	if calls != 2 {
+		t.Errorf("onChange calls = %d after a file was added to conf.d, want 2", calls)
+	}
+}
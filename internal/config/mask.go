@@ -0,0 +1,82 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// secretKeys are the YAML keys whose values are masked in diffs and in
+// printed configs.
+var secretKeys = []string{"token", "webhook_secret", "admin_token", "ops_token", "api_key", "password", "headers"}
+
+// maskSecret hides a secret value, keeping whether it was set.
+func maskSecret(s string) string {
+	if s == "" || s == "map[]" {
+		return s
+	}
+	return "********"
+}
+
+// Masked returns a copy of c with its secrets masked, for display.
+func (c *Config) Masked() *Config {
+	masked := *c
+	maskSecrets(reflect.ValueOf(&masked).Elem())
+	return &masked
+}
+
+// maskSecrets masks the secrets in v, copying any lists it changes so the
+// original config is left alone.
+func maskSecrets(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := range v.NumField() {
+			name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+			field := v.Field(i)
+			if !slices.Contains(secretKeys, name) {
+				maskSecrets(field)
+				continue
+			}
+			switch field.Kind() {
+			case reflect.String:
+				field.SetString(maskSecret(field.String()))
+			case reflect.Map:
+				if field.Len() > 0 {
+					masked := reflect.MakeMap(field.Type())
+					for _, k := range field.MapKeys() {
+						masked.SetMapIndex(k, reflect.ValueOf(maskSecret(field.MapIndex(k).String())))
+					}
+					field.Set(masked)
+				}
+			}
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Struct || v.Len() == 0 {
+			return
+		}
+		clone := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(clone, v)
+		for i := range clone.Len() {
+			maskSecrets(clone.Index(i))
+		}
+		v.Set(clone)
+	}
+}
+
+// Effective is the configuration in effect: the server config, with its
+// secrets masked, and the settings events run with once a repo's
+// overrides are merged over it.
+type Effective struct {
+	Server *Config       `yaml:"server"`
+	Merged *MergedConfig `yaml:"merged"`
+}
+
+// NewEffective returns the configuration in effect for a repo with the
+// given config.
+func NewEffective(server *Config, repo *RepoConfig) (Effective, error) {
+	merged, err := MergeConfigs(server, repo)
+	if err != nil {
+		return Effective{}, err
+	}
+	return Effective{Server: server.Masked(), Merged: merged}, nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMasked(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.AdminToken = "admin-secret"
+	cfg.Providers.GitLab.Token = "glpat-secret"
+	cfg.LLM.API.APIKey = ""
+	cfg.Tracing.Headers = map[string]string{"Authorization": "Bearer x"}
+	cfg.Logging.Ship.Sinks = []SinkConfig{{Type: "loki", Address: "http://loki:3100", Password: "loki-secret"}}
+	original := *cfg
+	original.Logging.Ship.Sinks = append([]SinkConfig(nil), cfg.Logging.Ship.Sinks...)
+
+	masked := cfg.Masked()
+
+	if masked.Server.AdminToken != "********" || masked.Providers.GitLab.Token != "********" {
+		t.Errorf("tokens = %q, %q; want them masked", masked.Server.AdminToken, masked.Providers.GitLab.Token)
+	}
+	if masked.LLM.API.APIKey != "" {
+		t.Errorf("llm.api.api_key = %q, want an unset secret left empty", masked.LLM.API.APIKey)
+	}
+	if got := masked.Tracing.Headers["Authorization"]; got != "********" {
+		t.Errorf("tracing.headers = %v, want values masked", masked.Tracing.Headers)
+	}
+	if got := masked.Logging.Ship.Sinks[0]; got.Password != "********" || got.Address != "http://loki:3100" {
+		t.Errorf("sink = %+v, want only the password masked", got)
+	}
+	if masked.BotUsername != cfg.BotUsername {
+		t.Errorf("bot_username = %q, want it unchanged", masked.BotUsername)
+	}
+	if !reflect.DeepEqual(*cfg, original) {
+		t.Error("Masked() changed the original config")
+	}
+}
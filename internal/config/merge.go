@@ -1,17 +1,76 @@
 package config
 
+import (
+	"fmt"
+	"slices"
+)
+
+// Agent modes. In patch mode the agent gets no provider credentials and the
+// server commits and pushes its changes.
+const (
+	AgentModeDirect = "direct"
+	AgentModePatch  = "patch"
+)
+
 // MergedConfig represents the final merged configuration.
 type MergedConfig struct {
-	Prompts     PromptsConfig
-	Permissions PermissionsConfig
-	Events      EventsConfig
-	AgentImage  string
+	Prompts     PromptsConfig     `yaml:"prompts"`
+	Permissions PermissionsConfig `yaml:"permissions"`
+	Events      EventsConfig      `yaml:"events"`
+	AgentImage  string            `yaml:"agent_image"`
+
+	// InteractiveEvents lists event types that spawn interactive sessions.
+	InteractiveEvents []string `yaml:"interactive_events"`
+
+	// AgentMode is AgentModeDirect or AgentModePatch.
+	AgentMode string `yaml:"agent_mode"`
+
+	// Personas are the prompt profiles to run side by side.
+	Personas []PersonaConfig `yaml:"personas"`
+
+	// Bootstrap lists commands run in the worktree before Claude starts.
+	Bootstrap []string `yaml:"bootstrap"`
+
+	// ProtectedPaths are the path patterns agents must never change, from
+	// the server and the repo.
+	ProtectedPaths []string `yaml:"protected_paths"`
+
+	// PermissionSources names the setting each permission came from, such
+	// as "repo config permissions.merge", by its path under permissions.
+	PermissionSources map[string]string `yaml:"-"`
 }
 
 // MergeConfigs merges server config with repo config.
-// Repo config values take precedence over server defaults.
-func MergeConfigs(server *Config, repo *RepoConfig) *MergedConfig {
-	merged := &MergedConfig{}
+// Repo config values take precedence over server defaults. Permissions
+// that aren't one of the allowed values are an error.
+func MergeConfigs(server *Config, repo *RepoConfig) (*MergedConfig, error) {
+	if err := PermissionsConfig(server.Permissions).validate("permissions"); err != nil {
+		return nil, err
+	}
+	if err := repo.Permissions.validate("permissions"); err != nil {
+		return nil, fmt.Errorf("repo config: %w", err)
+	}
+	if err := validatePathPatterns("protected_paths", repo.ProtectedPaths); err != nil {
+		return nil, fmt.Errorf("repo config: %w", err)
+	}
+
+	merged := &MergedConfig{PermissionSources: make(map[string]string)}
+	// resolve returns the first set permission among settings, most
+	// specific first, recording where it came from under key.
+	resolve := func(key string, settings ...setting) string {
+		for _, s := range settings {
+			if s.value != "" {
+				merged.PermissionSources[key] = s.source
+				return s.value
+			}
+		}
+		return ""
+	}
+	base := func(name, repoValue, serverValue string) string {
+		return resolve(name,
+			setting{repoValue, "repo config permissions." + name},
+			setting{serverValue, "server config permissions." + name})
+	}
 
 	// Merge prompts (repo overrides if non-empty)
 	merged.Prompts.MROpened = coalesce(repo.Prompts.MROpened, server.Prompts.MROpened)
@@ -20,10 +79,38 @@ func MergeConfigs(server *Config, repo *RepoConfig) *MergedConfig {
 	merged.Prompts.Mention = coalesce(repo.Prompts.Mention, server.Prompts.Mention)
 
 	// Merge permissions (repo overrides if non-empty)
-	merged.Permissions.Merge = coalesce(repo.Permissions.Merge, server.Permissions.Merge)
-	merged.Permissions.Approve = coalesce(repo.Permissions.Approve, server.Permissions.Approve)
-	merged.Permissions.PushCommits = coalesce(repo.Permissions.PushCommits, server.Permissions.PushCommits)
-	merged.Permissions.DismissReviews = coalesce(repo.Permissions.DismissReviews, server.Permissions.DismissReviews)
+	merged.Permissions.Merge = base("merge", repo.Permissions.Merge, server.Permissions.Merge)
+	merged.Permissions.Approve = base("approve", repo.Permissions.Approve, server.Permissions.Approve)
+	merged.Permissions.PushCommits = base("push_commits", repo.Permissions.PushCommits, server.Permissions.PushCommits)
+	merged.Permissions.DismissReviews = base("dismiss_reviews", repo.Permissions.DismissReviews, server.Permissions.DismissReviews)
+
+	// Per-event permissions. The most specific setting wins, and a repo's
+	// default beats the server's per-event override.
+	for _, events := range []map[string]EventPermissionsConfig{server.Permissions.Events, repo.Permissions.Events} {
+		for t := range events {
+			if _, ok := merged.Permissions.Events[t]; ok {
+				continue
+			}
+			s, r := server.Permissions.Events[t], repo.Permissions.Events[t]
+			if merged.Permissions.Events == nil {
+				merged.Permissions.Events = make(map[string]EventPermissionsConfig)
+			}
+			event := func(name, repoEvent, repoDefault, serverEvent, serverDefault string) string {
+				key := "events." + t + "." + name
+				return resolve(key,
+					setting{repoEvent, "repo config permissions." + key},
+					setting{repoDefault, "repo config permissions." + name},
+					setting{serverEvent, "server config permissions." + key},
+					setting{serverDefault, "server config permissions." + name})
+			}
+			merged.Permissions.Events[t] = EventPermissionsConfig{
+				Merge:          event("merge", r.Merge, repo.Permissions.Merge, s.Merge, server.Permissions.Merge),
+				Approve:        event("approve", r.Approve, repo.Permissions.Approve, s.Approve, server.Permissions.Approve),
+				PushCommits:    event("push_commits", r.PushCommits, repo.Permissions.PushCommits, s.PushCommits, server.Permissions.PushCommits),
+				DismissReviews: event("dismiss_reviews", r.DismissReviews, repo.Permissions.DismissReviews, s.DismissReviews, server.Permissions.DismissReviews),
+			}
+		}
+	}
 
 	// Merge events - use repo value if explicitly set, otherwise use server
 	merged.Events.MROpened = repo.Events.MROpened || server.Events.MROpened
@@ -34,12 +121,91 @@ func MergeConfigs(server *Config, repo *RepoConfig) *MergedConfig {
 	// Agent image
 	merged.AgentImage = coalesce(repo.AgentImage, "")
 
-	return merged
+	// Interactive events (repo list replaces server list if set)
+	merged.InteractiveEvents = server.Agents.InteractiveEvents
+	if len(repo.InteractiveEvents) > 0 {
+		merged.InteractiveEvents = repo.InteractiveEvents
+	}
+
+	// Agent mode (patch wins, so repos can't escape server-wide patch mode)
+	merged.AgentMode = AgentModeDirect
+	if server.Agents.Mode == AgentModePatch || repo.AgentMode == AgentModePatch {
+		merged.AgentMode = AgentModePatch
+	}
+
+	// Personas (repo list replaces server list if non-empty)
+	merged.Personas = server.Personas
+	if len(repo.Personas) > 0 {
+		merged.Personas = repo.Personas
+	}
+
+	// Bootstrap commands are repo-specific
+	merged.Bootstrap = repo.Bootstrap
+
+	// Protected paths (repo patterns add to the server's)
+	for _, p := range append(slices.Clone(server.Agents.ProtectedPaths), repo.ProtectedPaths...) {
+		if !slices.Contains(merged.ProtectedPaths, p) {
+			merged.ProtectedPaths = append(merged.ProtectedPaths, p)
+		}
+	}
+
+	return merged, nil
+}
+
+// PersonasFor returns the personas that run for the event type.
+func (m *MergedConfig) PersonasFor(eventType string) []PersonaConfig {
+	var personas []PersonaConfig
+	for _, p := range m.Personas {
+		if len(p.Events) == 0 || slices.Contains(p.Events, eventType) {
+			personas = append(personas, p)
+		}
+	}
+	return personas
+}
+
+// IsInteractive reports whether the event type spawns interactive sessions.
+func (m *MergedConfig) IsInteractive(eventType string) bool {
+	for _, t := range m.InteractiveEvents {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionsFor returns the permissions for the event type, with any
+// per-event overrides applied.
+func (m *MergedConfig) PermissionsFor(eventType string) PermissionsConfig {
+	p := m.Permissions.Events[eventType]
+	return PermissionsConfig{
+		Merge:          coalesce(p.Merge, m.Permissions.Merge),
+		Approve:        coalesce(p.Approve, m.Permissions.Approve),
+		PushCommits:    coalesce(p.PushCommits, m.Permissions.PushCommits),
+		DismissReviews: coalesce(p.DismissReviews, m.Permissions.DismissReviews),
+	}
+}
+
+// PermissionSource names the setting the event type's permission came
+// from, such as "server config permissions.events.mention.merge", or ""
+// if unknown. permission is its YAML name, e.g. push_commits.
+func (m *MergedConfig) PermissionSource(eventType, permission string) string {
+	if source, ok := m.PermissionSources["events."+eventType+"."+permission]; ok {
+		return source
+	}
+	return m.PermissionSources[permission]
+}
+
+// setting is a config value and the setting it came from.
+type setting struct {
+	value, source string
 }
 
-func coalesce(a, b string) string {
-	if a != "" {
-		return a
+// coalesce returns the first non-empty value.
+func coalesce(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
 	}
-	return b
+	return ""
 }
@@ -1,6 +1,20 @@
 package config
 
-import "testing"
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func mustMerge(t *testing.T, server *Config, repo *RepoConfig) *MergedConfig {
+	t.Helper()
+	merged, err := MergeConfigs(server, repo)
+	if err != nil {
+		t.Fatalf("MergeConfigs() error = %v", err)
+	}
+	return merged
+}
 
 func TestMergeConfigs(t *testing.T) {
 	server := &Config{
@@ -31,7 +45,7 @@ func TestMergeConfigs(t *testing.T) {
 		},
 	}
 
-	merged := MergeConfigs(server, repo)
+	merged := mustMerge(t, server, repo)
 
 	// Repo prompt should override
 	if merged.Prompts.MROpened != "Repo custom prompt" {
@@ -64,7 +78,7 @@ func TestMergeConfigs_EmptyRepo(t *testing.T) {
 
 	repo := &RepoConfig{} // Empty repo config
 
-	merged := MergeConfigs(server, repo)
+	merged := mustMerge(t, server, repo)
 
 	// Should use server defaults
 	if merged.Prompts.MROpened != "Server prompt" {
@@ -74,3 +88,211 @@ func TestMergeConfigs_EmptyRepo(t *testing.T) {
 		t.Errorf("Permissions.Merge = %q, want server default", merged.Permissions.Merge)
 	}
 }
+
+func TestMergeConfigs_InteractiveEvents(t *testing.T) {
+	server := &Config{Agents: AgentsConfig{InteractiveEvents: []string{"mention"}}}
+
+	tests := []struct {
+		name      string
+		repo      *RepoConfig
+		eventType string
+		want      bool
+	}{
+		{"server list applies", &RepoConfig{}, "mention", true},
+		{"unlisted event", &RepoConfig{}, "mr_opened", false},
+		{"repo list replaces server list", &RepoConfig{InteractiveEvents: []string{"mr_comment"}}, "mention", false},
+		{"repo list applies", &RepoConfig{InteractiveEvents: []string{"mr_comment"}}, "mr_comment", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := mustMerge(t, server, tt.repo)
+			if got := merged.IsInteractive(tt.eventType); got != tt.want {
+				t.Errorf("IsInteractive(%q) = %v, want %v", tt.eventType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeConfigs_AgentMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		server string
+		repo   string
+		want   string
+	}{
+		{"default", "", "", AgentModeDirect},
+		{"server patch", AgentModePatch, "", AgentModePatch},
+		{"repo patch", "", AgentModePatch, AgentModePatch},
+		{"repo cannot override server patch", AgentModePatch, AgentModeDirect, AgentModePatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &Config{Agents: AgentsConfig{Mode: tt.server}}
+			merged := mustMerge(t, server, &RepoConfig{AgentMode: tt.repo})
+			if merged.AgentMode != tt.want {
+				t.Errorf("AgentMode = %q, want %q", merged.AgentMode, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeConfigs_Personas(t *testing.T) {
+	server := &Config{Personas: []PersonaConfig{
+		{Name: "security", Prompt: "Find vulnerabilities."},
+		{Name: "docs", Prompt: "Check the docs.", Events: []string{"mr_opened"}},
+	}}
+
+	merged := mustMerge(t, server, &RepoConfig{})
+	if got := merged.PersonasFor("mr_opened"); len(got) != 2 {
+		t.Errorf("PersonasFor(mr_opened) = %v, want both personas", got)
+	}
+	if got := merged.PersonasFor("mr_comment"); len(got) != 1 || got[0].Name != "security" {
+		t.Errorf("PersonasFor(mr_comment) = %v, want [security]", got)
+	}
+
+	repo := &RepoConfig{Personas: []PersonaConfig{{Name: "tests", Prompt: "Write tests."}}}
+	merged = mustMerge(t, server, repo)
+	if got := merged.PersonasFor("mr_opened"); len(got) != 1 || got[0].Name != "tests" {
+		t.Errorf("PersonasFor(mr_opened) = %v, want repo list to replace server list", got)
+	}
+}
+
+func TestMergeConfigs_Bootstrap(t *testing.T) {
+	repo := &RepoConfig{Bootstrap: []string{"npm ci"}}
+	merged := mustMerge(t, &Config{}, repo)
+	if len(merged.Bootstrap) != 1 || merged.Bootstrap[0] != "npm ci" {
+		t.Errorf("Bootstrap = %v, want [npm ci]", merged.Bootstrap)
+	}
+}
+
+func TestMergeConfigs_ProtectedPaths(t *testing.T) {
+	server := &Config{Agents: AgentsConfig{ProtectedPaths: []string{".github/workflows/**", "deploy/**"}}}
+	repo := &RepoConfig{ProtectedPaths: []string{"deploy/**", "migrations/*.sql"}}
+
+	merged := mustMerge(t, server, repo)
+	want := []string{".github/workflows/**", "deploy/**", "migrations/*.sql"}
+	if !slices.Equal(merged.ProtectedPaths, want) {
+		t.Errorf("ProtectedPaths = %v, want %v", merged.ProtectedPaths, want)
+	}
+
+	if _, err := MergeConfigs(server, &RepoConfig{ProtectedPaths: []string{""}}); err == nil ||
+		!strings.Contains(err.Error(), "repo config: protected_paths[0]: empty pattern") {
+		t.Errorf("MergeConfigs() error = %v, want the repo's empty pattern rejected", err)
+	}
+}
+
+func TestMergeConfigs_EventPermissions(t *testing.T) {
+	server := &Config{
+		Permissions: ServerPermissionsConfig{
+			Merge:       "never",
+			PushCommits: "on_request",
+			Approve:     "never",
+			Events: map[string]EventPermissionsConfig{
+				"mr_comment": {PushCommits: "always", Approve: "on_request"},
+				"mention":    {Merge: "on_request"},
+			},
+		},
+	}
+	repo := &RepoConfig{
+		Permissions: PermissionsConfig{
+			Approve: "always",
+			Events: map[string]EventPermissionsConfig{
+				"mr_opened": {PushCommits: "never"},
+			},
+		},
+	}
+
+	merged := mustMerge(t, server, repo)
+
+	tests := []struct {
+		eventType string
+		want      PermissionsConfig
+	}{
+		// Server per-event override, except approve, which the repo's default beats
+		{"mr_comment", PermissionsConfig{Merge: "never", PushCommits: "always", Approve: "always"}},
+		{"mention", PermissionsConfig{Merge: "on_request", PushCommits: "on_request", Approve: "always"}},
+		// Repo per-event override
+		{"mr_opened", PermissionsConfig{Merge: "never", PushCommits: "never", Approve: "always"}},
+		// Defaults
+		{"mr_updated", PermissionsConfig{Merge: "never", PushCommits: "on_request", Approve: "always"}},
+	}
+	for _, tt := range tests {
+		if got := merged.PermissionsFor(tt.eventType); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("PermissionsFor(%q) = %+v, want %+v", tt.eventType, got, tt.want)
+		}
+	}
+}
+
+func TestMergedConfig_PermissionSource(t *testing.T) {
+	server := &Config{
+		Permissions: ServerPermissionsConfig{
+			Merge:       "never",
+			PushCommits: "on_request",
+			Events: map[string]EventPermissionsConfig{
+				"mr_comment": {PushCommits: "always"},
+			},
+		},
+	}
+	repo := &RepoConfig{
+		Permissions: PermissionsConfig{
+			Approve: "always",
+			Events: map[string]EventPermissionsConfig{
+				"mention": {Merge: "on_request"},
+			},
+		},
+	}
+
+	merged := mustMerge(t, server, repo)
+
+	tests := []struct {
+		eventType, permission string
+		want                  string
+	}{
+		{"mr_comment", "push_commits", "server config permissions.events.mr_comment.push_commits"},
+		{"mr_comment", "approve", "repo config permissions.approve"},
+		{"mention", "merge", "repo config permissions.events.mention.merge"},
+		{"mention", "push_commits", "server config permissions.push_commits"},
+		{"mr_opened", "merge", "server config permissions.merge"},
+		{"mr_opened", "dismiss_reviews", ""},
+	}
+	for _, tt := range tests {
+		if got := merged.PermissionSource(tt.eventType, tt.permission); got != tt.want {
+			t.Errorf("PermissionSource(%q, %q) = %q, want %q", tt.eventType, tt.permission, got, tt.want)
+		}
+	}
+}
+
+func TestMergeConfigs_InvalidPermissions(t *testing.T) {
+	tests := []struct {
+		name    string
+		server  ServerPermissionsConfig
+		repo    PermissionsConfig
+		wantErr string
+	}{
+		{
+			name:    "server",
+			server:  ServerPermissionsConfig{Merge: "sometimes"},
+			wantErr: `permissions.merge: invalid value "sometimes" (want always, on_request, never)`,
+		},
+		{
+			name:    "repo",
+			repo:    PermissionsConfig{PushCommits: "yes"},
+			wantErr: `repo config: permissions.push_commits: invalid value "yes" (want always, on_request, never)`,
+		},
+		{
+			name:    "repo per-event",
+			repo:    PermissionsConfig{Events: map[string]EventPermissionsConfig{"mr_comment": {Approve: "maybe"}}},
+			wantErr: `repo config: permissions.events.mr_comment.approve: invalid value "maybe"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := MergeConfigs(&Config{Permissions: tt.server}, &RepoConfig{Permissions: tt.repo})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("MergeConfigs() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// MatchPath reports whether name, a slash-separated path relative to the
+// repo root, matches pattern. Each segment of pattern uses path.Match
+// syntax, and a "**" segment matches any number of segments, so
+// ".github/workflows/**" matches every file under .github/workflows.
+func MatchPath(pattern, name string) bool {
+	pattern = strings.TrimPrefix(pattern, "/")
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// ProtectedFiles returns the files that match one of c's protected paths.
+func (c *MergedConfig) ProtectedFiles(files []string) []string {
+	var protected []string
+	for _, f := range files {
+		for _, p := range c.ProtectedPaths {
+			if MatchPath(p, f) {
+				protected = append(protected, f)
+				break
+			}
+		}
+	}
+	return protected
+}
+
+// validatePathPatterns reports the first pattern under field that is empty
+// or not valid path.Match syntax.
+func validatePathPatterns(field string, patterns []string) error {
+	for i, p := range patterns {
+		if strings.Trim(p, "/") == "" {
+			return fmt.Errorf("%s[%d]: empty pattern", field, i)
+		}
+		for _, segment := range strings.Split(p, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("%s[%d]: invalid pattern %q: %w", field, i, p, err)
+			}
+		}
+	}
+	return nil
+}
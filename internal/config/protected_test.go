@@ -0,0 +1,42 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{".github/workflows/**", ".github/workflows/ci.yml", true},
+		{".github/workflows/**", ".github/workflows/nested/deploy.yml", true},
+		{".github/workflows/**", ".github/CODEOWNERS", false},
+		{"deploy/**", "deploy", true},
+		{"deploy/**", "deployment/app.yaml", false},
+		{"/deploy/*.yaml", "deploy/app.yaml", true},
+		{"deploy/*.yaml", "deploy/prod/app.yaml", false},
+		{"**/*.pem", "certs/server.pem", true},
+		{"**/*.pem", "server.pem", true},
+		{"Makefile", "Makefile", true},
+		{"Makefile", "src/Makefile", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchPath(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("MatchPath(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMergedConfig_ProtectedFiles(t *testing.T) {
+	cfg := &MergedConfig{ProtectedPaths: []string{".github/workflows/**", "**/*.pem"}}
+	files := []string{"main.go", ".github/workflows/ci.yml", "certs/server.pem"}
+
+	want := []string{".github/workflows/ci.yml", "certs/server.pem"}
+	if got := cfg.ProtectedFiles(files); !slices.Equal(got, want) {
+		t.Errorf("ProtectedFiles() = %v, want %v", got, want)
+	}
+}
@@ -17,6 +17,33 @@ type RepoConfig struct {
 	Permissions PermissionsConfig `yaml:"permissions"`
 	Prompts     PromptsConfig     `yaml:"prompts"`
 	AgentImage  string            `yaml:"agent_image"`
+
+	// InteractiveEvents lists event types that spawn interactive sessions.
+	InteractiveEvents []string `yaml:"interactive_events"`
+
+	// AgentMode may opt a repo into patch mode; it cannot opt out of a
+	// server-wide patch mode.
+	AgentMode string `yaml:"agent_mode"`
+
+	// Personas replaces the server's persona list when set.
+	Personas []PersonaConfig `yaml:"personas"`
+
+	// Bootstrap lists shell commands run in the worktree, inside the agent
+	// container, before Claude starts. When empty, .familiar/bootstrap.sh
+	// runs if the repo has one.
+	Bootstrap []string `yaml:"bootstrap"`
+
+	// ProtectedPaths adds to the server's agents.protected_paths; a repo
+	// cannot unprotect a path.
+	ProtectedPaths []string `yaml:"protected_paths"`
+}
+
+// PersonaConfig is a named prompt profile. When personas apply to an event,
+// one agent runs per persona and their results are combined in one comment.
+type PersonaConfig struct {
+	Name   string   `yaml:"name"`
+	Prompt string   `yaml:"prompt"` // Replaces the event's base prompt
+	Events []string `yaml:"events"` // Event types the persona runs for; empty means all
 }
 
 // EventsConfig controls which events are enabled.
@@ -33,6 +60,19 @@ type PermissionsConfig struct {
 	Approve        string `yaml:"approve"`
 	PushCommits    string `yaml:"push_commits"`
 	DismissReviews string `yaml:"dismiss_reviews"`
+
+	// Events overrides the permissions above for individual event types,
+	// keyed by type (e.g. "mr_comment").
+	Events map[string]EventPermissionsConfig `yaml:"events,omitempty"`
+}
+
+// EventPermissionsConfig holds the permissions for one event type. Unset
+// permissions fall back to the defaults.
+type EventPermissionsConfig struct {
+	Merge          string `yaml:"merge"`
+	Approve        string `yaml:"approve"`
+	PushCommits    string `yaml:"push_commits"`
+	DismissReviews string `yaml:"dismiss_reviews"`
 }
 
 // PromptsConfig holds custom prompts per event type.
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// placeholder matches a ${...} reference, which substitute replaces before
+// the file is parsed, so it's allowed wherever a non-string value is.
+var placeholder = map[string]any{"type": "string", "pattern": `^\$\{[^}]+\}$`}
+
+// Schema returns a JSON Schema for the config file, for editors that
+// validate YAML against one. Unknown keys are rejected, as Load does, and
+// defaults come from DefaultConfig.
+func Schema() map[string]any {
+	s := schemaFor("", reflect.TypeOf(Config{}), reflect.ValueOf(*DefaultConfig()))
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["title"] = "Familiar configuration"
+	return s
+}
+
+// schemaFor describes a value of type t at the dotted YAML path key. def
+// holds its default, or is the zero Value when there is none.
+func schemaFor(key string, t reflect.Type, def reflect.Value) map[string]any {
+	var s map[string]any
+	switch t.Kind() {
+	case reflect.Struct:
+		props := map[string]any{}
+		for i := range t.NumField() {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			var fieldDef reflect.Value
+			if def.IsValid() {
+				fieldDef = def.Field(i)
+			}
+			props[name] = schemaFor(strings.TrimPrefix(key+"."+name, "."), t.Field(i).Type, fieldDef)
+		}
+		return map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	case reflect.Slice:
+		s = map[string]any{"type": "array", "items": schemaFor(key, t.Elem(), reflect.Value{})}
+	case reflect.Map:
+		s = map[string]any{"type": "object", "additionalProperties": schemaFor(key, t.Elem(), reflect.Value{})}
+		if key == "permissions.events" {
+			s["propertyNames"] = map[string]any{"enum": eventTypes}
+		}
+	case reflect.String:
+		s = map[string]any{"type": "string"}
+	case reflect.Int:
+		s = map[string]any{"type": "integer"}
+	case reflect.Float64:
+		s = map[string]any{"type": "number"}
+	case reflect.Bool:
+		s = map[string]any{"type": "boolean"}
+	}
+
+	if values, ok := allowedValues[key]; ok {
+		s["enum"] = values
+	}
+	if _, ok := s["enum"]; ok || t.Kind() == reflect.Int || t.Kind() == reflect.Float64 || t.Kind() == reflect.Bool {
+		s = map[string]any{"anyOf": []any{s, placeholder}}
+	}
+	if def.IsValid() && !def.IsZero() {
+		s["default"] = def.Interface()
+	}
+	return s
+}
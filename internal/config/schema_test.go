@@ -0,0 +1,61 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	data, err := json.Marshal(Schema())
+	if err != nil {
+		t.Fatalf("marshaling schema: %v", err)
+	}
+	// Decode generically, as an editor would
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	property := func(path ...string) map[string]any {
+		t.Helper()
+		s := schema
+		for _, name := range path {
+			props, _ := s["properties"].(map[string]any)
+			s, _ = props[name].(map[string]any)
+			if s == nil {
+				t.Fatalf("schema has no property %v", path)
+			}
+		}
+		return s
+	}
+
+	if schema["additionalProperties"] != false || property("agents")["additionalProperties"] != false {
+		t.Error("objects should reject unknown keys")
+	}
+	if got := property("agents", "debounce_seconds")["default"]; got != float64(10) {
+		t.Errorf("agents.debounce_seconds default = %v, want 10", got)
+	}
+	if got := property("server", "cors", "allowed_methods")["default"]; len(got.([]any)) != 4 {
+		t.Errorf("server.cors.allowed_methods default = %v, want the four default methods", got)
+	}
+
+	// Enums and non-string settings also accept a ${VAR} reference
+	anyOf, _ := property("permissions", "merge")["anyOf"].([]any)
+	if len(anyOf) != 2 {
+		t.Fatalf("permissions.merge = %v, want an enum or a placeholder", property("permissions", "merge"))
+	}
+	if enum := anyOf[0].(map[string]any)["enum"]; len(enum.([]any)) != len(permissionValues) {
+		t.Errorf("permissions.merge enum = %v, want %v", enum, permissionValues)
+	}
+	if _, ok := property("server", "port")["anyOf"]; !ok {
+		t.Error("server.port should accept a ${VAR} reference")
+	}
+	if got := property("bot_username")["type"]; got != "string" {
+		t.Errorf("bot_username type = %v, want string", got)
+	}
+
+	sinks := property("logging", "ship", "sinks")
+	items, _ := sinks["items"].(map[string]any)
+	if items == nil || items["properties"].(map[string]any)["type"] == nil {
+		t.Errorf("logging.ship.sinks = %v, want items describing a sink", sinks)
+	}
+}
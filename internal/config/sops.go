@@ -0,0 +1,42 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sopsCommand is the sops binary that decrypts encrypted config files.
+const sopsCommand = "sops"
+
+// sopsEncrypted reports whether data is a sops-encrypted YAML document,
+// which carries its metadata under a top-level "sops" key.
+func sopsEncrypted(data []byte) bool {
+	var doc struct {
+		Sops map[string]any `yaml:"sops"`
+	}
+	return yaml.Unmarshal(data, &doc) == nil && doc.Sops != nil
+}
+
+// decryptSops decrypts the config file at path with the sops CLI, which
+// finds its key as it usually would, e.g. from SOPS_AGE_KEY_FILE.
+func decryptSops(path string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, sopsCommand, "--decrypt", "--input-type", "yaml", "--output-type", "yaml", path)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("decrypting config file with sops: %w", err)
+	}
+	return data, nil
+}
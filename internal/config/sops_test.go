@@ -0,0 +1,61 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeCommand installs an executable shell script as name on PATH.
+func fakeCommand(t *testing.T, name, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestLoad_SopsEncrypted(t *testing.T) {
+	// Stands in for sops, checking it's asked to decrypt the file
+	fakeCommand(t, "sops", `
+[ "$1" = "--decrypt" ] || exit 2
+eval last=\${$#}
+grep -q 'ENC\[AES256_GCM' "$last" || { echo "not encrypted" >&2; exit 1; }
+printf 'providers:\n  gitlab:\n    token: glpat-decrypted\n    webhook_secret: ${SOPS_TEST_SECRET}\n'
+`)
+	t.Setenv("SOPS_TEST_SECRET", "from-env")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	encrypted := `providers:
+  gitlab:
+    token: ENC[AES256_GCM,data:abc=,iv:def=,tag:ghi=,type:str]
+sops:
+  age:
+    - recipient: age1example
+  version: 3.9.0
+`
+	if err := os.WriteFile(path, []byte(encrypted), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Providers.GitLab.Token != "glpat-decrypted" {
+		t.Errorf("providers.gitlab.token = %q, want the decrypted value", cfg.Providers.GitLab.Token)
+	}
+	if cfg.Providers.GitLab.WebhookSecret != "from-env" {
+		t.Errorf("providers.gitlab.webhook_secret = %q, want ${...} substituted after decryption", cfg.Providers.GitLab.WebhookSecret)
+	}
+
+	// Decryption failures are reported with sops' own message
+	fakeCommand(t, "sops", `echo "Failed to get the data key" >&2; exit 128`)
+	_, err = Load(path)
+	if err == nil || !strings.Contains(err.Error(), "decrypting config file with sops: exit status 128: Failed to get the data key") {
+		t.Errorf("Load() error = %v, want the sops failure", err)
+	}
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envVarPattern matches ${VAR_NAME} patterns.
+var envVarPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// filePrefix marks a ${file:/path} reference, replaced by the file's
+// contents, as for Docker and Kubernetes secret mounts.
+const filePrefix = "file:"
+
+// Resolver looks up secret references of one scheme, such as
+// ${vault:secret/familiar#gitlab_token}, given the text after the colon.
+type Resolver interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// resolveTimeout bounds each secret lookup.
+const resolveTimeout = 10 * time.Second
+
+var (
+	resolversMu sync.RWMutex
+	resolvers   = make(map[string]Resolver)
+)
+
+// RegisterResolver makes ${scheme:ref} references resolve through r.
+func RegisterResolver(scheme string, r Resolver) {
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	resolvers[scheme] = r
+}
+
+// lookupResolver returns the resolver registered for ref's scheme, if any.
+func lookupResolver(ref string) (Resolver, string, bool) {
+	scheme, rest, ok := strings.Cut(ref, ":")
+	if !ok {
+		return nil, "", false
+	}
+	resolversMu.RLock()
+	defer resolversMu.RUnlock()
+	r, ok := resolvers[scheme]
+	return r, rest, ok
+}
+
+// substitute replaces ${VAR} with the environment variable's value and
+// ${file:/path} with the file's contents. An unset VAR falls back to the
+// contents of the file named by VAR_FILE, the convention for passing
+// secrets to containers. Referenced files must be readable; a trailing
+// newline is dropped. As in the shell, ${VAR:-default} uses default and
+// ${VAR:?message} fails with message when VAR is unset or empty. Other
+// ${scheme:ref} references go to the Resolver registered for the scheme.
+func substitute(data []byte) ([]byte, error) {
+	var firstErr error
+	data = envVarPattern.ReplaceAllFunc(data, func(match []byte) []byte {
+		ref := string(envVarPattern.FindSubmatch(match)[1])
+		value, err := resolve(ref)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return []byte(value)
+	})
+	return data, firstErr
+}
+
+// substituteNode resolves the references in each scalar under n, as
+// substitute does. Working on parsed values rather than the file's text
+// keeps values with quotes, backslashes, or newlines, such as PEM keys,
+// intact. An unquoted scalar's type follows its new value, so
+// "port: ${PORT}" is still a number.
+func substituteNode(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode && strings.Contains(n.Value, "${") {
+		value, err := substitute([]byte(n.Value))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n.Line, err)
+		}
+		n.Value = string(value)
+		if n.Style == 0 {
+			n.Tag = ""
+		}
+	}
+	for _, c := range n.Content {
+		if err := substituteNode(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve returns the value of one ${...} reference.
+func resolve(ref string) (string, error) {
+	if path, ok := strings.CutPrefix(ref, filePrefix); ok {
+		return readSecretFile(path, "${"+ref+"}")
+	}
+	if r, rest, ok := lookupResolver(ref); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+		defer cancel()
+		value, err := r.Resolve(ctx, rest)
+		if err != nil {
+			return "", fmt.Errorf("resolving ${%s}: %w", ref, err)
+		}
+		return value, nil
+	}
+
+	// ${VAR}, ${VAR:-default}, or ${VAR:?message}
+	name, op, arg := ref, "", ""
+	if i := strings.Index(ref, ":"); i > 0 && i+1 < len(ref) && (ref[i+1] == '-' || ref[i+1] == '?') {
+		name, op, arg = ref[:i], ref[i:i+2], ref[i+2:]
+	}
+	value, err := envValue(name)
+	if err != nil || value != "" {
+		return value, err
+	}
+	switch op {
+	case ":-":
+		return arg, nil
+	case ":?":
+		if arg == "" {
+			arg = "not set"
+		}
+		return "", fmt.Errorf("${%s}: %s", name, arg)
+	}
+	return "", nil
+}
+
+// envValue returns the environment variable name, or the contents of the
+// file named by name_FILE if name is unset.
+func envValue(name string) (string, error) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, nil
+	}
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		return readSecretFile(path, name+"_FILE")
+	}
+	return "", nil
+}
+
+// readSecretFile returns the contents of a secret file without its
+// trailing newline.
+func readSecretFile(path, source string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", source, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSubstitute(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "gitlab_token")
+	if err := os.WriteFile(secretFile, []byte("glpat-from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SUBST_PLAIN", "plain-value")
+	t.Setenv("SUBST_INDIRECT_FILE", secretFile)
+	t.Setenv("SUBST_BOTH", "env-wins")
+	t.Setenv("SUBST_BOTH_FILE", secretFile)
+	t.Setenv("SUBST_BROKEN_FILE", filepath.Join(dir, "missing"))
+	t.Setenv("SUBST_EMPTY", "")
+
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr string
+	}{
+		{name: "env var", in: "token: ${SUBST_PLAIN}", want: "token: plain-value"},
+		{name: "unset", in: "token: ${SUBST_UNSET}", want: "token: "},
+		{name: "file reference", in: "token: ${file:" + secretFile + "}", want: "token: glpat-from-file"},
+		{name: "_FILE fallback", in: "token: ${SUBST_INDIRECT}", want: "token: glpat-from-file"},
+		{name: "env var over _FILE", in: "token: ${SUBST_BOTH}", want: "token: env-wins"},
+		{name: "missing file", in: "token: ${file:" + filepath.Join(dir, "missing") + "}", wantErr: "reading ${file:"},
+		{name: "missing _FILE", in: "token: ${SUBST_BROKEN}", wantErr: "reading SUBST_BROKEN_FILE"},
+		{name: "default unused", in: "port: ${SUBST_PLAIN:-7000}", want: "port: plain-value"},
+		{name: "default for unset", in: "url: ${SUBST_UNSET:-http://localhost:3100}", want: "url: http://localhost:3100"},
+		{name: "default for empty", in: "port: ${SUBST_EMPTY:-7000}", want: "port: 7000"},
+		{name: "empty default", in: "token: ${SUBST_UNSET:-}", want: "token: "},
+		{name: "default with _FILE", in: "token: ${SUBST_INDIRECT:-none}", want: "token: glpat-from-file"},
+		{name: "required and set", in: "secret: ${SUBST_PLAIN:?set the secret}", want: "secret: plain-value"},
+		{name: "required and unset", in: "secret: ${SUBST_UNSET:?set the webhook secret}", wantErr: "${SUBST_UNSET}: set the webhook secret"},
+		{name: "required and empty", in: "secret: ${SUBST_EMPTY:?}", wantErr: "${SUBST_EMPTY}: not set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := substitute([]byte(tt.in))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("substitute() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("substitute() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("substitute() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_SecretFile(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "webhook_secret")
+	if err := os.WriteFile(secretFile, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(dir, "config.yaml")
+	configContent := `
+providers:
+  gitlab:
+    webhook_secret: "${file:` + secretFile + `}"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Providers.GitLab.WebhookSecret != "s3cret" {
+		t.Errorf("Providers.GitLab.WebhookSecret = %q, want %q", cfg.Providers.GitLab.WebhookSecret, "s3cret")
+	}
+}
+
+func TestLoadConfig_SubstitutesParsedValues(t *testing.T) {
+	dir := t.TempDir()
+	pem := "-----BEGIN KEY-----\nabc\\def\n-----END KEY-----"
+	secretFile := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secretFile, []byte(pem+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SUBST_QUOTED", `say "hi"\n`)
+	t.Setenv("SUBST_INJECT", "x\nadmin_token: injected")
+	t.Setenv("SUBST_PORT", "8123")
+
+	configPath := filepath.Join(dir, "config.yaml")
+	configContent := `
+# ${SUBST_UNSET:?comments are not substituted}
+server:
+  port: ${SUBST_PORT}
+  ops_token: ${SUBST_INJECT}
+providers:
+  gitlab:
+    token: "${SUBST_QUOTED}"
+    webhook_secret: '${file:` + secretFile + `}'
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Server.Port != 8123 {
+		t.Errorf("Server.Port = %d, want 8123", cfg.Server.Port)
+	}
+	if cfg.Server.OpsToken != "x\nadmin_token: injected" || cfg.Server.AdminToken != "" {
+		t.Errorf("OpsToken = %q, AdminToken = %q; want the value kept whole", cfg.Server.OpsToken, cfg.Server.AdminToken)
+	}
+	if cfg.Providers.GitLab.Token != `say "hi"\n` {
+		t.Errorf("Providers.GitLab.Token = %q, want %q", cfg.Providers.GitLab.Token, `say "hi"\n`)
+	}
+	if cfg.Providers.GitLab.WebhookSecret != pem {
+		t.Errorf("Providers.GitLab.WebhookSecret = %q, want %q", cfg.Providers.GitLab.WebhookSecret, pem)
+	}
+}
+
+func TestLoadConfig_SubstituteErrorLine(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("server:\n  admin_token: ${SUBST_UNSET:?set it}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(configPath); err == nil || !strings.Contains(err.Error(), "line 2: ${SUBST_UNSET}: set it") {
+		t.Errorf("Load() error = %v, want the reference's line", err)
+	}
+}
@@ -0,0 +1,249 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Permission values. An unset permission grants nothing.
+const (
+	PermissionAlways    = "always"
+	PermissionOnRequest = "on_request"
+	PermissionNever     = "never"
+)
+
+// permissionValues lists the valid permission values.
+var permissionValues = []string{PermissionAlways, PermissionOnRequest, PermissionNever}
+
+// eventTypes lists the event types, as used to key per-event settings.
+var eventTypes = []string{"mr_opened", "mr_comment", "mr_updated", "mention"}
+
+// allowedValues lists the values each enumerated setting accepts, by
+// dotted YAML path. Validate checks them and Schema publishes them; the
+// permissions are checked by PermissionsConfig.validate.
+var allowedValues = map[string][]string{
+	"permissions.merge":           permissionValues,
+	"permissions.approve":         permissionValues,
+	"permissions.push_commits":    permissionValues,
+	"permissions.dismiss_reviews": permissionValues,
+	"agents.mode":                 {AgentModeDirect, AgentModePatch},
+	"agents.acknowledge":          {AcknowledgeReaction, AcknowledgeComment, AcknowledgeNone},
+	"agents.summary":              {SummaryAlways, SummaryFailures, SummaryNever},
+	"agents.busy_policy":          {BusyQueue, BusySkip, BusySupersede, BusyAllow},
+	"agents.credentials":          {CredentialsFull, CredentialsScoped},
+	"llm.strategy":                {"api"},
+	"metrics.push.type":           {"statsd", "otlp"},
+	"logging.ship.sinks.type":     {"syslog", "loki", "cloudwatch"},
+
+	// Per-event overrides
+	"permissions.events.merge":           permissionValues,
+	"permissions.events.approve":         permissionValues,
+	"permissions.events.push_commits":    permissionValues,
+	"permissions.events.dismiss_reviews": permissionValues,
+}
+
+// Validate reports every setting that is out of range, not one of its
+// allowed values, or missing a secret it needs, joined into one error. It
+// doesn't touch the filesystem; see CheckPaths.
+func (c *Config) Validate() error {
+	var errs []error
+	oneOf := func(field, value string) {
+		valid := allowedValues[stripIndexes(field)]
+		if value != "" && !slices.Contains(valid, value) {
+			errs = append(errs, fmt.Errorf("%s: invalid value %q (want %s)", field, value, strings.Join(valid, ", ")))
+		}
+	}
+	required := func(field, value, reason string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s: required %s", field, reason))
+		}
+	}
+
+	errs = append(errs, PermissionsConfig(c.Permissions).validate("permissions"))
+	oneOf("agents.mode", c.Agents.Mode)
+	oneOf("agents.acknowledge", c.Agents.Acknowledge)
+	oneOf("agents.summary", c.Agents.Summary)
+	oneOf("agents.busy_policy", c.Agents.BusyPolicy)
+	oneOf("agents.credentials", c.Agents.Credentials)
+	errs = append(errs, validatePathPatterns("agents.protected_paths", c.Agents.ProtectedPaths))
+	errs = append(errs, validateRegexps("logging.redact_patterns", c.Logging.RedactPatterns))
+	oneOf("llm.strategy", c.LLM.Strategy)
+	oneOf("metrics.push.type", c.Metrics.Push.Type)
+	for i, sink := range c.Logging.Ship.Sinks {
+		field := fmt.Sprintf("logging.ship.sinks[%d].type", i)
+		required(field, sink.Type, "for every sink")
+		oneOf(field, sink.Type)
+	}
+
+	// Docker accepts user-defined network names, so only the forms it
+	// would reject outright are caught here.
+	if mode := c.Agents.NetworkMode; mode == "container:" || strings.ContainsAny(mode, " \t") {
+		errs = append(errs, fmt.Errorf("agents.network_mode: invalid value %q (want bridge, host, none, container:<name>, or a network name)", mode))
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port: %d is out of range (want 1-65535)", c.Server.Port))
+	}
+	if c.Concurrency.MaxAgents < 1 {
+		errs = append(errs, fmt.Errorf("concurrency.max_agents: %d is out of range (want at least 1)", c.Concurrency.MaxAgents))
+	}
+	if c.Concurrency.QueueSize < 0 {
+		errs = append(errs, fmt.Errorf("concurrency.queue_size: %d is out of range (want 0 or more)", c.Concurrency.QueueSize))
+	}
+
+	tls := c.Server.TLS
+	if (tls.CertFile == "") != (tls.KeyFile == "") {
+		errs = append(errs, errors.New("server.tls: cert_file and key_file must be set together"))
+	}
+	if tls.ClientCAFile != "" {
+		required("server.tls.cert_file", tls.CertFile, "when client_ca_file is set")
+	}
+
+	gh, gl := c.Providers.GitHub, c.Providers.GitLab
+	if gh.Token == "" && gl.Token == "" {
+		errs = append(errs, errors.New("providers: configure a token for github or gitlab"))
+	}
+	if gh.Token != "" {
+		required("providers.github.webhook_secret", gh.WebhookSecret, "when providers.github.token is set")
+	} else if gh.WebhookSecret != "" {
+		required("providers.github.token", gh.Token, "when providers.github.webhook_secret is set")
+	}
+	if gl.Token != "" {
+		required("providers.gitlab.webhook_secret", gl.WebhookSecret, "when providers.gitlab.token is set")
+	} else if gl.WebhookSecret != "" {
+		required("providers.gitlab.token", gl.Token, "when providers.gitlab.webhook_secret is set")
+	}
+	if c.LLM.Strategy == "api" {
+		required("llm.api.api_key", c.LLM.API.APIKey, "for the api strategy")
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateRegexps reports the first pattern under field that is not a valid
+// regular expression.
+func validateRegexps(field string, patterns []string) error {
+	for i, p := range patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("%s[%d]: invalid pattern %q: %w", field, i, p, err)
+		}
+	}
+	return nil
+}
+
+// CheckPaths reports files Familiar reads that don't exist, and
+// directories it writes to that neither exist nor can be created. Host paths used only for Docker bind mounts
+// (host_dir, claude_auth_dir) are resolved by the daemon and not checked.
+func (c *Config) CheckPaths() error {
+	var errs []error
+	file := func(field, path string) {
+		if path == "" {
+			return
+		}
+		if info, err := os.Stat(path); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		} else if info.IsDir() {
+			errs = append(errs, fmt.Errorf("%s: %s is a directory", field, path))
+		}
+	}
+	dir := func(field, path string, creatable bool) {
+		if path == "" {
+			return
+		}
+		info, err := os.Stat(path)
+		switch {
+		case err == nil && !info.IsDir():
+			errs = append(errs, fmt.Errorf("%s: %s is not a directory", field, path))
+		case errors.Is(err, os.ErrNotExist) && creatable:
+			if err := creatableDir(path); err != nil {
+				errs = append(errs, fmt.Errorf("%s: cannot create %s: %w", field, path, err))
+			}
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		}
+	}
+
+	file("server.tls.cert_file", c.Server.TLS.CertFile)
+	file("server.tls.key_file", c.Server.TLS.KeyFile)
+	file("server.tls.client_ca_file", c.Server.TLS.ClientCAFile)
+	dir("agents.docker.cert_path", c.Agents.Docker.CertPath, false)
+	dir("logging.dir", c.Logging.Dir, true)
+	dir("repo_cache.dir", c.RepoCache.Dir, true)
+	if c.Logging.File.Path != "" {
+		dir("logging.file.path", filepath.Dir(c.Logging.File.Path), true)
+	}
+	if c.Logging.AuditFile != "" {
+		dir("logging.audit_file", filepath.Dir(c.Logging.AuditFile), true)
+	}
+	if c.Metrics.StateFile != "" {
+		dir("metrics.state_file", filepath.Dir(c.Metrics.StateFile), true)
+	}
+
+	return errors.Join(errs...)
+}
+
+// creatableDir reports why the missing directory path couldn't be created
+// with os.MkdirAll: its nearest existing ancestor isn't a directory.
+func creatableDir(path string) error {
+	for parent := filepath.Dir(path); ; parent = filepath.Dir(parent) {
+		info, err := os.Stat(parent)
+		switch {
+		case err == nil && info.IsDir():
+			return nil
+		case err == nil:
+			return fmt.Errorf("%s is not a directory", parent)
+		case !errors.Is(err, os.ErrNotExist):
+			return err
+		case parent == filepath.Dir(parent):
+			return err
+		}
+	}
+}
+
+// stripIndexes turns a field like "logging.ship.sinks[0].type" into its
+// key, "logging.ship.sinks.type".
+func stripIndexes(field string) string {
+	var b strings.Builder
+	for {
+		before, rest, ok := strings.Cut(field, "[")
+		b.WriteString(before)
+		if !ok {
+			return b.String()
+		}
+		_, field, _ = strings.Cut(rest, "]")
+	}
+}
+
+// validate reports permissions that aren't one of the allowed values, and
+// per-event overrides for unknown event types. prefix is the YAML path of
+// the permissions, for the errors.
+func (p PermissionsConfig) validate(prefix string) error {
+	var errs []error
+	check := func(field, value string) {
+		if value != "" && !slices.Contains(permissionValues, value) {
+			errs = append(errs, fmt.Errorf("%s: invalid value %q (want %s)", field, value, strings.Join(permissionValues, ", ")))
+		}
+	}
+
+	check(prefix+".merge", p.Merge)
+	check(prefix+".approve", p.Approve)
+	check(prefix+".push_commits", p.PushCommits)
+	check(prefix+".dismiss_reviews", p.DismissReviews)
+	for _, t := range slices.Sorted(maps.Keys(p.Events)) {
+		e, field := p.Events[t], prefix+".events."+t
+		if !slices.Contains(eventTypes, t) {
+			errs = append(errs, fmt.Errorf("%s: unknown event type (want %s)", field, strings.Join(eventTypes, ", ")))
+		}
+		check(field+".merge", e.Merge)
+		check(field+".approve", e.Approve)
+		check(field+".push_commits", e.PushCommits)
+		check(field+".dismiss_reviews", e.DismissReviews)
+	}
+	return errors.Join(errs...)
+}
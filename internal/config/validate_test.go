@@ -0,0 +1,193 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// validConfig returns the defaults with a GitLab provider configured.
+func validConfig() *Config {
+	cfg := DefaultConfig()
+	cfg.Providers.GitLab.Token = "glpat-test"
+	cfg.Providers.GitLab.WebhookSecret = "secret"
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *Config)
+		want   []string // substrings of the error; none means valid
+	}{
+		{name: "valid", modify: func(cfg *Config) {}},
+		{
+			name: "valid permissions and modes",
+			modify: func(cfg *Config) {
+				cfg.Permissions = ServerPermissionsConfig{Merge: "on_request", PushCommits: "always", Approve: "never"}
+				cfg.Agents.Mode = AgentModePatch
+				cfg.Agents.NetworkMode = "container:proxy"
+			},
+		},
+		{
+			name:   "unknown permission",
+			modify: func(cfg *Config) { cfg.Permissions.Merge = "sometimes" },
+			want:   []string{`permissions.merge: invalid value "sometimes" (want always, on_request, never)`},
+		},
+		{
+			name: "per-event permissions",
+			modify: func(cfg *Config) {
+				cfg.Permissions.Events = map[string]EventPermissionsConfig{
+					"mr_comment": {PushCommits: "always"},
+					"mr_closed":  {Merge: "never"},
+					"mention":    {Approve: "sometimes"},
+				}
+			},
+			want: []string{
+				"permissions.events.mr_closed: unknown event type (want mr_opened, mr_comment, mr_updated, mention)",
+				`permissions.events.mention.approve: invalid value "sometimes"`,
+			},
+		},
+		{
+			name: "unknown enumerations",
+			modify: func(cfg *Config) {
+				cfg.Agents.Mode = "yolo"
+				cfg.LLM.Strategy = "psychic"
+				cfg.Metrics.Push.Type = "carrier-pigeon"
+				cfg.Logging.Ship.Sinks = []SinkConfig{{Type: "syslog"}, {}}
+				cfg.Agents.NetworkMode = "container:"
+			},
+			want: []string{"agents.mode", "llm.strategy", "metrics.push.type", "logging.ship.sinks[1].type: required", "agents.network_mode"},
+		},
+		{
+			name:   "invalid protected path",
+			modify: func(cfg *Config) { cfg.Agents.ProtectedPaths = []string{"deploy/**", "[a-"} },
+			want:   []string{`agents.protected_paths[1]: invalid pattern "[a-"`},
+		},
+		{
+			name: "out of range",
+			modify: func(cfg *Config) {
+				cfg.Server.Port = 70000
+				cfg.Concurrency.MaxAgents = 0
+			},
+			want: []string{"server.port", "concurrency.max_agents"},
+		},
+		{
+			name:   "no provider",
+			modify: func(cfg *Config) { cfg.Providers.GitLab = GitLabConfig{} },
+			want:   []string{"providers: configure a token"},
+		},
+		{
+			name: "missing secrets",
+			modify: func(cfg *Config) {
+				cfg.Providers.GitLab.WebhookSecret = ""
+				cfg.Providers.GitHub.WebhookSecret = "secret"
+				cfg.LLM.Strategy = "api"
+			},
+			want: []string{"providers.gitlab.webhook_secret: required", "providers.github.token: required", "llm.api.api_key: required"},
+		},
+		{
+			name: "incomplete tls",
+			modify: func(cfg *Config) {
+				cfg.Server.TLS.KeyFile = "key.pem"
+				cfg.Server.TLS.ClientCAFile = "ca.pem"
+			},
+			want: []string{"cert_file and key_file must be set together", "server.tls.cert_file: required"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() error = nil, want %q", tt.want)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckPaths(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	if err := os.WriteFile(certFile, []byte("cert"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := validConfig()
+	cfg.Server.TLS.CertFile = certFile
+	cfg.Logging.Dir = filepath.Join(dir, "logs", "agents") // created on demand
+	cfg.RepoCache.Dir = dir
+	if err := cfg.CheckPaths(); err != nil {
+		t.Fatalf("CheckPaths() error = %v, want nil", err)
+	}
+
+	cfg.Server.TLS.KeyFile = filepath.Join(dir, "missing.pem")
+	cfg.Server.TLS.ClientCAFile = dir
+	cfg.RepoCache.Dir = filepath.Join(certFile, "repos")
+	err := cfg.CheckPaths()
+	if err == nil {
+		t.Fatal("CheckPaths() error = nil, want missing and misplaced paths")
+	}
+	for _, want := range []string{"server.tls.key_file", "server.tls.client_ca_file: " + dir + " is a directory", "repo_cache.dir: "} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("CheckPaths() error = %v, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestLoad_UnknownKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("agents:\n  debouce_seconds: 5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Load(path)
+	if err == nil || !strings.Contains(err.Error(), "debouce_seconds") {
+		t.Errorf("Load() error = %v, want the unknown key reported", err)
+	}
+}
+
+func TestLoad_InvalidPermission(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "permissions:\n  merge: sometimes\n  events:\n    mr_comment:\n      push_commits: eventually\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Load(path)
+	for _, want := range []string{
+		`permissions.merge: invalid value "sometimes" (want always, on_request, never)`,
+		`permissions.events.mr_comment.push_commits: invalid value "eventually"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Load() error = %v, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestLoad_InvalidRedactPattern(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "logging:\n  redact_patterns:\n    - \"internal-[0-9]+\"\n    - \"(unclosed\"\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Load(path)
+	if want := `logging.redact_patterns[1]: invalid pattern "(unclosed"`; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Load() error = %v, want it to contain %q", err, want)
+	}
+}
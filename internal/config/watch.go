@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Watcher calls a function when the config file, or a file it includes,
+// changes. It polls the files' modification times and sizes, which also
+// catches the symlink swaps Kubernetes uses to update mounted ConfigMaps.
+type Watcher struct {
+	path     string
+	onChange func()
+	ticker   *time.Ticker
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	state string // the files' names, modification times, and sizes
+}
+
+// NewWatcher returns a Watcher that checks path every interval.
+func NewWatcher(path string, interval time.Duration, onChange func()) *Watcher {
+	w := &Watcher{
+		path:     path,
+		onChange: onChange,
+		ticker:   time.NewTicker(interval),
+		stop:     make(chan struct{}),
+	}
+	w.state, _ = fileState(path)
+	return w
+}
+
+func (w *Watcher) Start() {
+	go func() {
+		for {
+			select {
+			case <-w.ticker.C:
+				w.check()
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
+
+// check calls onChange if the files changed since the last check. A
+// missing config file, as in the middle of an editor's save, is not a
+// change.
+func (w *Watcher) check() {
+	state, err := fileState(w.path)
+	if err != nil {
+		slog.Debug("config file not readable", "path", w.path, "error", err)
+		return
+	}
+	if state == w.state {
+		return
+	}
+	w.state = state
+	slog.Info("config file changed", "path", w.path)
+	w.onChange()
+}
+
+// fileState describes the config file at path and the files it includes,
+// so that any change to them changes the result.
+func fileState(path string) (string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, file := range watchedFiles(path) {
+		if info, err := os.Stat(file); err == nil {
+			fmt.Fprintf(&b, "%s %d %d\n", file, info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return b.String(), nil
+}
+
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() {
+		w.ticker.Stop()
+		close(w.stop)
+	})
+}
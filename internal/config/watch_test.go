@@ -0,0 +1,41 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher_Check(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("bot_username: familiar\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	w := NewWatcher(path, time.Hour, func() { calls++ })
+	defer w.Stop()
+
+	w.check()
+	if calls != 0 {
+		t.Fatalf("onChange calls = %d for an unchanged file, want 0", calls)
+	}
+
+	if err := os.WriteFile(path, []byte("bot_username: helper\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, time.Now(), time.Now().Add(time.Second))
+	w.check()
+	w.check()
+	if calls != 1 {
+		t.Fatalf("onChange calls = %d after one edit, want 1", calls)
+	}
+
+	// A file briefly missing mid-save is not a change
+	os.Remove(path)
+	w.check()
+	if calls != 1 {
+		t.Errorf("onChange calls = %d while the file is missing, want 1", calls)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
@@ -15,16 +17,28 @@ import (
 
 // Client wraps the Docker client with convenience methods.
 type Client struct {
-	cli *client.Client
+	cli  *client.Client
+	auth RegistryAuth
 }
 
-// NewClient creates a new Docker client.
-func NewClient() (*Client, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+// NewClient creates a new Docker client. Without options it connects to the
+// daemon named by the process environment.
+func NewClient(opts ...ClientOption) (*Client, error) {
+	var o clientOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	connOpts, err := o.conn.opts()
+	if err != nil {
+		return nil, fmt.Errorf("configuring docker connection: %w", err)
+	}
+
+	cliOpts := append([]client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}, connOpts...)
+	cli, err := client.NewClientWithOpts(cliOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("creating docker client: %w", err)
 	}
-	return &Client{cli: cli}, nil
+	return &Client{cli: cli, auth: o.auth}, nil
 }
 
 // Close closes the Docker client.
@@ -49,17 +63,14 @@ func (c *Client) ImageExists(ctx context.Context, imageName string) (bool, error
 	return len(images) > 0, nil
 }
 
-// PullImage pulls an image if it doesn't exist.
+// PullImage pulls an image, using the client's registry credentials if any.
 func (c *Client) PullImage(ctx context.Context, imageName string) error {
-	exists, err := c.ImageExists(ctx, imageName)
+	auth, err := c.auth.encode(ctx, imageName)
 	if err != nil {
-		return err
-	}
-	if exists {
-		return nil
+		return fmt.Errorf("resolving registry credentials: %w", err)
 	}
 
-	reader, err := c.cli.ImagePull(ctx, imageName, image.PullOptions{})
+	reader, err := c.cli.ImagePull(ctx, imageName, image.PullOptions{RegistryAuth: auth})
 	if err != nil {
 		return fmt.Errorf("pulling image: %w", err)
 	}
@@ -82,9 +93,28 @@ type ContainerConfig struct {
 	Labels      map[string]string
 	Cmd         []string
 	Entrypoint  []string
-	NetworkMode string // e.g. "host", "bridge", or empty for default
+	NetworkMode string       // e.g. "host", "bridge", or empty for default
+	Healthcheck *Healthcheck // Overrides the image's HEALTHCHECK when set
 }
 
+// Healthcheck is a command Docker runs periodically inside a container to
+// decide whether it is healthy. Zero durations and retries use Docker's
+// defaults.
+type Healthcheck struct {
+	Cmd         string // Run with the container's shell; exit 0 means healthy
+	Interval    time.Duration
+	Timeout     time.Duration
+	StartPeriod time.Duration // Failures during this initial period don't count
+	Retries     int           // Consecutive failures before the container is unhealthy
+}
+
+// Health statuses reported by InspectContainer for containers with a healthcheck.
+const (
+	HealthStarting  = container.Starting
+	HealthHealthy   = container.Healthy
+	HealthUnhealthy = container.Unhealthy
+)
+
 // Mount represents a bind mount.
 type Mount struct {
 	Source   string
@@ -133,18 +163,21 @@ func (c *Client) CreateContainer(ctx context.Context, cfg ContainerConfig) (stri
 		hostConfig.NetworkMode = container.NetworkMode(cfg.NetworkMode)
 	}
 
+	containerConfig := &container.Config{
+		Image:       cfg.Image,
+		User:        cfg.User,
+		WorkingDir:  cfg.WorkDir,
+		Env:         cfg.Env,
+		Labels:      cfg.Labels,
+		Cmd:         cfg.Cmd,
+		Entrypoint:  cfg.Entrypoint,
+		Tty:         true,
+		OpenStdin:   true,
+		Healthcheck: healthConfig(cfg.Healthcheck),
+	}
+
 	resp, err := c.cli.ContainerCreate(ctx,
-		&container.Config{
-			Image:      cfg.Image,
-			User:       cfg.User,
-			WorkingDir: cfg.WorkDir,
-			Env:        cfg.Env,
-			Labels:     cfg.Labels,
-			Cmd:        cfg.Cmd,
-			Entrypoint: cfg.Entrypoint,
-			Tty:        true,
-			OpenStdin:  true,
-		},
+		containerConfig,
 		hostConfig,
 		nil, nil, cfg.Name,
 	)
@@ -155,6 +188,21 @@ func (c *Client) CreateContainer(ctx context.Context, cfg ContainerConfig) (stri
 	return resp.ID, nil
 }
 
+// healthConfig converts a healthcheck to Docker's form. Nil or empty leaves
+// the image's own HEALTHCHECK in effect.
+func healthConfig(hc *Healthcheck) *container.HealthConfig {
+	if hc == nil || hc.Cmd == "" {
+		return nil
+	}
+	return &container.HealthConfig{
+		Test:        []string{"CMD-SHELL", hc.Cmd},
+		Interval:    hc.Interval,
+		Timeout:     hc.Timeout,
+		StartPeriod: hc.StartPeriod,
+		Retries:     hc.Retries,
+	}
+}
+
 // StartContainer starts a container.
 func (c *Client) StartContainer(ctx context.Context, id string) error {
 	return c.cli.ContainerStart(ctx, id, container.StartOptions{})
@@ -171,36 +219,84 @@ func (c *Client) RemoveContainer(ctx context.Context, id string, force bool) err
 	return c.cli.ContainerRemove(ctx, id, container.RemoveOptions{Force: force})
 }
 
-// ExecInContainer runs a command in a container and waits for it to complete.
-// It returns an error if the command exits with a non-zero exit code.
-func (c *Client) ExecInContainer(ctx context.Context, containerID string, cmd []string) error {
-	execConfig, err := c.cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{
-		Cmd: cmd,
+// ContainerSummary describes a container returned by ListContainers.
+type ContainerSummary struct {
+	ID      string
+	Name    string
+	State   string // e.g. "running", "exited", "created"
+	Labels  map[string]string
+	Created time.Time
+	SizeRw  int64 // Bytes in the container's writable layer
+}
+
+// ListContainers returns every container, running or not, carrying label
+// (a "key" or "key=value" filter).
+func (c *Client) ListContainers(ctx context.Context, label string) ([]ContainerSummary, error) {
+	list, err := c.cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Size:    true,
+		Filters: filters.NewArgs(filters.Arg("label", label)),
 	})
 	if err != nil {
-		return fmt.Errorf("creating exec: %w", err)
+		return nil, fmt.Errorf("listing containers: %w", err)
 	}
 
-	if err := c.cli.ContainerExecStart(ctx, execConfig.ID, container.ExecStartOptions{}); err != nil {
-		return fmt.Errorf("starting exec: %w", err)
+	containers := make([]ContainerSummary, 0, len(list))
+	for _, s := range list {
+		var name string
+		if len(s.Names) > 0 {
+			name = strings.TrimPrefix(s.Names[0], "/")
+		}
+		containers = append(containers, ContainerSummary{
+			ID:      s.ID,
+			Name:    name,
+			State:   string(s.State),
+			Labels:  s.Labels,
+			Created: time.Unix(s.Created, 0),
+			SizeRw:  s.SizeRw,
+		})
 	}
+	return containers, nil
+}
 
-	// Wait for the exec to complete and check exit code
-	inspect, err := c.cli.ContainerExecInspect(ctx, execConfig.ID)
+// CheckMount verifies that hostPath, bind-mounted into a throwaway container
+// of the given image, contains the named entry. It catches host paths the
+// daemon cannot resolve, such as untranslated Docker Desktop paths.
+func (c *Client) CheckMount(ctx context.Context, image, hostPath, name string) error {
+	id, err := c.CreateContainer(ctx, ContainerConfig{
+		Image:      image,
+		Mounts:     []Mount{{Source: hostPath, Target: "/familiar-mount-check", ReadOnly: true}},
+		Entrypoint: []string{"/bin/sh"},
+		Cmd:        []string{"-c", `test -e "/familiar-mount-check/$1"`, "sh", name},
+	})
 	if err != nil {
-		return fmt.Errorf("inspecting exec: %w", err)
+		return err
 	}
+	defer c.RemoveContainer(context.Background(), id, true)
 
-	if inspect.ExitCode != 0 {
-		return fmt.Errorf("command exited with code %d", inspect.ExitCode)
+	if err := c.StartContainer(ctx, id); err != nil {
+		return fmt.Errorf("starting check container: %w", err)
 	}
 
+	statusCh, errCh := c.cli.ContainerWait(ctx, id, container.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("waiting for check container: %w", err)
+	case status := <-statusCh:
+		if status.StatusCode != 0 {
+			return fmt.Errorf("%s does not contain %s as seen by the Docker daemon", hostPath, name)
+		}
+	}
 	return nil
 }
 
 // ContainerInspect holds selected fields from a container inspection.
 type ContainerInspect struct {
-	Mounts []MountPoint
+	Mounts    []MountPoint
+	Running   bool
+	ExitCode  int
+	OOMKilled bool
+	Health    string // HealthStarting, HealthHealthy, or HealthUnhealthy; empty without a healthcheck
 }
 
 // MountPoint describes a mount in a running container.
@@ -226,14 +322,85 @@ func (c *Client) InspectContainer(ctx context.Context, containerID string) (*Con
 		}
 	}
 
-	return &ContainerInspect{Mounts: mounts}, nil
+	inspect := &ContainerInspect{Mounts: mounts}
+	if resp.State != nil {
+		inspect.Running = resp.State.Running
+		inspect.ExitCode = resp.State.ExitCode
+		inspect.OOMKilled = resp.State.OOMKilled
+		if resp.State.Health != nil {
+			inspect.Health = resp.State.Health.Status
+		}
+	}
+	return inspect, nil
 }
 
-// GetContainerLogs returns container logs.
-func (c *Client) GetContainerLogs(ctx context.Context, containerID string) (io.ReadCloser, error) {
-	return c.cli.ContainerLogs(ctx, containerID, container.LogsOptions{
-		ShowStdout: true,
-		ShowStderr: true,
-		Follow:     false,
-	})
+// ContainerExit is how a container finished running.
+type ContainerExit struct {
+	ExitCode int
+	Error    string // Error the daemon reported for the container, if any
+}
+
+// WaitContainer waits for a container to stop running. The exit channel
+// receives its exit status once; the error channel receives an error if
+// waiting fails, including when ctx is cancelled.
+func (c *Client) WaitContainer(ctx context.Context, id string) (<-chan ContainerExit, <-chan error) {
+	exitC := make(chan ContainerExit, 1)
+	errC := make(chan error, 1)
+
+	respC, waitErrC := c.cli.ContainerWait(ctx, id, container.WaitConditionNotRunning)
+	go func() {
+		select {
+		case resp := <-respC:
+			exit := ContainerExit{ExitCode: int(resp.StatusCode)}
+			if resp.Error != nil {
+				exit.Error = resp.Error.Message
+			}
+			exitC <- exit
+		case err := <-waitErrC:
+			errC <- fmt.Errorf("waiting for container: %w", err)
+		}
+	}()
+	return exitC, errC
+}
+
+// ContainerProcesses returns the command lines of processes running in a container.
+func (c *Client) ContainerProcesses(ctx context.Context, containerID string) ([]string, error) {
+	top, err := c.cli.ContainerTop(ctx, containerID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("listing container processes: %w", err)
+	}
+
+	cmdCol := -1
+	for i, title := range top.Titles {
+		if title == "CMD" || title == "COMMAND" {
+			cmdCol = i
+			break
+		}
+	}
+	if cmdCol < 0 {
+		return nil, fmt.Errorf("listing container processes: no command column in %v", top.Titles)
+	}
+
+	procs := make([]string, 0, len(top.Processes))
+	for _, p := range top.Processes {
+		if cmdCol < len(p) {
+			procs = append(procs, strings.Join(p[cmdCol:], " "))
+		}
+	}
+	return procs, nil
+}
+
+// PathStat describes a file inside a container.
+type PathStat struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// StatPath returns size and modification time of a path inside a container.
+func (c *Client) StatPath(ctx context.Context, containerID, path string) (PathStat, error) {
+	stat, err := c.cli.ContainerStatPath(ctx, containerID, path)
+	if err != nil {
+		return PathStat{}, fmt.Errorf("stat %s: %w", path, err)
+	}
+	return PathStat{Size: stat.Size, ModTime: stat.Mtime}, nil
 }
@@ -2,7 +2,9 @@ package docker
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestClient_Ping(t *testing.T) {
@@ -84,3 +86,71 @@ func TestClient_ImageExists(t *testing.T) {
 		t.Error("ImageExists() returned false for pulled image, expected true")
 	}
 }
+
+func TestClient_ListContainers(t *testing.T) {
+	client, err := NewClient()
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if err := client.Ping(ctx); err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	if err := client.PullImage(ctx, "alpine:latest"); err != nil {
+		t.Skipf("Could not pull alpine image: %v", err)
+	}
+
+	label := "familiar.test.list=" + time.Now().Format("150405.000000000")
+	key, value, _ := strings.Cut(label, "=")
+	containerID, err := client.CreateContainer(ctx, ContainerConfig{
+		Name:   "test-list-containers",
+		Image:  "alpine:latest",
+		Labels: map[string]string{key: value},
+		Cmd:    []string{"true"},
+	})
+	if err != nil {
+		t.Fatalf("CreateContainer() error = %v", err)
+	}
+	defer client.RemoveContainer(ctx, containerID, true)
+
+	// Created but never started, so only listed because All is set
+	containers, err := client.ListContainers(ctx, label)
+	if err != nil {
+		t.Fatalf("ListContainers() error = %v", err)
+	}
+	if len(containers) != 1 {
+		t.Fatalf("ListContainers() returned %d containers, want 1", len(containers))
+	}
+	got := containers[0]
+	if got.ID != containerID || got.Name != "test-list-containers" || got.State != "created" || got.Labels[key] != value {
+		t.Errorf("ListContainers() = %+v, want the created container", got)
+	}
+}
+
+func TestHealthConfig(t *testing.T) {
+	if got := healthConfig(nil); got != nil {
+		t.Errorf("healthConfig(nil) = %+v, want nil", got)
+	}
+	if got := healthConfig(&Healthcheck{Interval: time.Second}); got != nil {
+		t.Errorf("healthConfig(no cmd) = %+v, want nil", got)
+	}
+
+	got := healthConfig(&Healthcheck{
+		Cmd:         "pgrep -f claude",
+		Interval:    30 * time.Second,
+		Timeout:     5 * time.Second,
+		StartPeriod: time.Minute,
+		Retries:     3,
+	})
+	if got == nil {
+		t.Fatal("healthConfig() = nil")
+	}
+	if len(got.Test) != 2 || got.Test[0] != "CMD-SHELL" || got.Test[1] != "pgrep -f claude" {
+		t.Errorf("Test = %v, want CMD-SHELL form", got.Test)
+	}
+	if got.Interval != 30*time.Second || got.Timeout != 5*time.Second || got.StartPeriod != time.Minute || got.Retries != 3 {
+		t.Errorf("healthConfig() = %+v", got)
+	}
+}
@@ -0,0 +1,91 @@
+package docker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/client"
+)
+
+// Connection selects the Docker daemon agents run on. Empty fields fall back
+// to the standard DOCKER_HOST, DOCKER_CERT_PATH, and DOCKER_TLS_VERIFY
+// variables.
+type Connection struct {
+	Host      string // e.g. tcp://docker.internal:2376
+	CertPath  string // Directory holding ca.pem, cert.pem, and key.pem
+	TLSVerify bool   // Verify the daemon's certificate against ca.pem
+}
+
+// ClientOption configures a Docker client.
+type ClientOption func(*clientOptions)
+
+type clientOptions struct {
+	conn Connection
+	auth RegistryAuth
+}
+
+// WithConnection connects to the given daemon instead of the one named by
+// the process environment.
+func WithConnection(conn Connection) ClientOption {
+	return func(o *clientOptions) {
+		o.conn = conn
+	}
+}
+
+// WithRegistryAuth authenticates image pulls with the given credentials.
+func WithRegistryAuth(auth RegistryAuth) ClientOption {
+	return func(o *clientOptions) {
+		o.auth = auth
+	}
+}
+
+// opts returns the Docker client options for the connection.
+func (conn Connection) opts() ([]client.Opt, error) {
+	var opts []client.Opt
+	if conn.Host != "" {
+		opts = append(opts, client.WithHost(conn.Host))
+	}
+	if conn.CertPath != "" {
+		tlsConfig, err := conn.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, client.WithHTTPClient(&http.Client{
+			Transport:     &http.Transport{TLSClientConfig: tlsConfig},
+			CheckRedirect: client.CheckRedirect,
+		}))
+	}
+	return opts, nil
+}
+
+// tlsConfig loads the client certificate and CA from CertPath.
+func (conn Connection) tlsConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(
+		filepath.Join(conn.CertPath, "cert.pem"),
+		filepath.Join(conn.CertPath, "key.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("loading docker client certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: !conn.TLSVerify,
+	}
+	if conn.TLSVerify {
+		ca, err := os.ReadFile(filepath.Join(conn.CertPath, "ca.pem"))
+		if err != nil {
+			return nil, fmt.Errorf("reading docker CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", filepath.Join(conn.CertPath, "ca.pem"))
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
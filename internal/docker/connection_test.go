@@ -0,0 +1,101 @@
+package docker
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCerts writes a self-signed ca.pem, cert.pem, and key.pem to dir.
+func writeCerts(t *testing.T, dir string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "familiar-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	for name, data := range map[string][]byte{"ca.pem": certPEM, "cert.pem": certPEM, "key.pem": keyPEM} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestConnection_TLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeCerts(t, dir)
+
+	tlsConfig, err := Connection{CertPath: dir, TLSVerify: true}.tlsConfig()
+	if err != nil {
+		t.Fatalf("tlsConfig() error = %v", err)
+	}
+	if tlsConfig.InsecureSkipVerify {
+		t.Error("InsecureSkipVerify should be false when TLSVerify is set")
+	}
+	if tlsConfig.RootCAs == nil || len(tlsConfig.Certificates) != 1 {
+		t.Error("tlsConfig() should load the CA and client certificate")
+	}
+
+	tlsConfig, err = Connection{CertPath: dir}.tlsConfig()
+	if err != nil {
+		t.Fatalf("tlsConfig() error = %v", err)
+	}
+	if !tlsConfig.InsecureSkipVerify {
+		t.Error("InsecureSkipVerify should be true when TLSVerify is unset")
+	}
+}
+
+func TestConnection_TLSConfigMissingCerts(t *testing.T) {
+	if _, err := (Connection{CertPath: t.TempDir(), TLSVerify: true}).tlsConfig(); err == nil {
+		t.Error("tlsConfig() should fail without certificates")
+	}
+}
+
+func TestNewClient_WithConnection(t *testing.T) {
+	dir := t.TempDir()
+	writeCerts(t, dir)
+
+	client, err := NewClient(WithConnection(Connection{
+		Host:      "tcp://docker.example.com:2376",
+		CertPath:  dir,
+		TLSVerify: true,
+	}))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer client.Close()
+
+	if got := client.cli.DaemonHost(); got != "tcp://docker.example.com:2376" {
+		t.Errorf("DaemonHost() = %q, want configured host", got)
+	}
+
+	if _, err := NewClient(WithConnection(Connection{CertPath: t.TempDir()})); err == nil {
+		t.Error("NewClient() should fail when the configured certificates are missing")
+	}
+}
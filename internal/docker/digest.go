@@ -0,0 +1,62 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var digestPattern = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// ImageIdentity identifies a local image's content.
+type ImageIdentity struct {
+	ID          string   // Image ID (digest of its config)
+	RepoDigests []string // Registry manifest references, e.g. "ghcr.io/org/agent@sha256:..."
+}
+
+// InspectImage returns the identity of a local image.
+func (c *Client) InspectImage(ctx context.Context, imageName string) (*ImageIdentity, error) {
+	resp, err := c.cli.ImageInspect(ctx, imageName)
+	if err != nil {
+		return nil, fmt.Errorf("inspecting image: %w", err)
+	}
+	return &ImageIdentity{ID: resp.ID, RepoDigests: resp.RepoDigests}, nil
+}
+
+// Matches reports whether digest identifies the image, either as a registry
+// manifest digest or as the local image ID (for images built locally).
+func (id ImageIdentity) Matches(digest string) bool {
+	if digest == "" {
+		return false
+	}
+	if id.ID == digest {
+		return true
+	}
+	for _, ref := range id.RepoDigests {
+		if _, d, ok := strings.Cut(ref, "@"); ok && d == digest {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseDigest validates a configured image digest (sha256:<64 hex>).
+// Empty is allowed and means no pin.
+func ParseDigest(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s != "" && !digestPattern.MatchString(s) {
+		return "", fmt.Errorf("invalid image digest %q (want sha256:<64 hex characters>)", s)
+	}
+	return s, nil
+}
+
+// PinnedDigest returns the digest from an image reference like
+// name@sha256:..., or "" if the reference isn't pinned.
+func PinnedDigest(image string) string {
+	_, digest, ok := strings.Cut(image, "@")
+	if !ok {
+		return ""
+	}
+	return digest
+}
@@ -0,0 +1,54 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testDigest  = "sha256:" + "ab12ab12ab12ab12ab12ab12ab12ab12ab12ab12ab12ab12ab12ab12ab12ab12"
+	otherDigest = "sha256:" + "cd34cd34cd34cd34cd34cd34cd34cd34cd34cd34cd34cd34cd34cd34cd34cd34"
+)
+
+func TestImageIdentity_Matches(t *testing.T) {
+	id := ImageIdentity{
+		ID:          otherDigest,
+		RepoDigests: []string{"ghcr.io/org/agent@" + testDigest},
+	}
+
+	if !id.Matches(testDigest) {
+		t.Error("Matches() should accept a registry manifest digest")
+	}
+	if !id.Matches(otherDigest) {
+		t.Error("Matches() should accept the local image ID")
+	}
+	if id.Matches("sha256:" + strings.Repeat("0", 64)) {
+		t.Error("Matches() accepted an unrelated digest")
+	}
+	if id.Matches("") {
+		t.Error("Matches() accepted an empty digest")
+	}
+}
+
+func TestParseDigest(t *testing.T) {
+	if got, err := ParseDigest(""); err != nil || got != "" {
+		t.Errorf("ParseDigest(\"\") = %q, %v; want no pin", got, err)
+	}
+	if got, err := ParseDigest(" " + strings.ToUpper(testDigest) + " "); err != nil || got != testDigest {
+		t.Errorf("ParseDigest() = %q, %v; want normalized %q", got, err, testDigest)
+	}
+	for _, bad := range []string{"latest", "sha256:abc", "md5:" + strings.Repeat("a", 64)} {
+		if _, err := ParseDigest(bad); err == nil {
+			t.Errorf("ParseDigest(%q) should fail", bad)
+		}
+	}
+}
+
+func TestPinnedDigest(t *testing.T) {
+	if got := PinnedDigest("ghcr.io/org/agent@" + testDigest); got != testDigest {
+		t.Errorf("PinnedDigest() = %q, want %q", got, testDigest)
+	}
+	if got := PinnedDigest("ghcr.io/org/agent:latest"); got != "" {
+		t.Errorf("PinnedDigest() = %q, want empty for a tag", got)
+	}
+}
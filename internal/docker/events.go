@@ -0,0 +1,76 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// Container lifecycle actions reported by ContainerEvents.
+const (
+	EventDie     = string(events.ActionDie)     // The container's process exited
+	EventOOM     = string(events.ActionOOM)     // The kernel OOM-killed a process in the container
+	EventDestroy = string(events.ActionDestroy) // The container was removed
+)
+
+// ContainerEvent is a lifecycle event for a container.
+type ContainerEvent struct {
+	ContainerID string
+	Action      string
+	ExitCode    int // Set for EventDie
+	Labels      map[string]string
+	Time        time.Time
+}
+
+// ContainerEvents subscribes to die, oom, and destroy events for containers
+// carrying label (a "key" or "key=value" filter). The error channel receives
+// an error when the subscription ends, including when ctx is cancelled.
+func (c *Client) ContainerEvents(ctx context.Context, label string) (<-chan ContainerEvent, <-chan error) {
+	eventC := make(chan ContainerEvent)
+	errC := make(chan error, 1)
+
+	msgC, msgErrC := c.cli.Events(ctx, events.ListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("type", string(events.ContainerEventType)),
+			filters.Arg("label", label),
+			filters.Arg("event", EventDie),
+			filters.Arg("event", EventOOM),
+			filters.Arg("event", EventDestroy),
+		),
+	})
+	go func() {
+		for {
+			select {
+			case msg := <-msgC:
+				select {
+				case eventC <- containerEvent(msg):
+				case <-ctx.Done():
+					errC <- ctx.Err()
+					return
+				}
+			case err := <-msgErrC:
+				errC <- fmt.Errorf("watching container events: %w", err)
+				return
+			}
+		}
+	}()
+	return eventC, errC
+}
+
+// containerEvent converts a daemon event message.
+func containerEvent(msg events.Message) ContainerEvent {
+	event := ContainerEvent{
+		ContainerID: msg.Actor.ID,
+		Action:      string(msg.Action),
+		Labels:      msg.Actor.Attributes,
+		Time:        time.Unix(0, msg.TimeNano),
+	}
+	if code, err := strconv.Atoi(msg.Actor.Attributes["exitCode"]); err == nil {
+		event.ExitCode = code
+	}
+	return event
+}
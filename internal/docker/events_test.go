@@ -0,0 +1,36 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/events"
+)
+
+func TestContainerEvent(t *testing.T) {
+	msg := events.Message{
+		Type:   events.ContainerEventType,
+		Action: events.ActionDie,
+		Actor: events.Actor{
+			ID:         "abc123",
+			Attributes: map[string]string{"exitCode": "137", "familiar.agent": "true"},
+		},
+		TimeNano: 1_700_000_000_000_000_000,
+	}
+
+	got := containerEvent(msg)
+	if got.ContainerID != "abc123" || got.Action != EventDie || got.ExitCode != 137 {
+		t.Errorf("containerEvent() = %+v", got)
+	}
+	if got.Labels["familiar.agent"] != "true" {
+		t.Errorf("Labels = %v, want familiar.agent label", got.Labels)
+	}
+	if got.Time.Unix() != 1_700_000_000 {
+		t.Errorf("Time = %v", got.Time)
+	}
+
+	msg.Action = events.ActionDestroy
+	delete(msg.Actor.Attributes, "exitCode")
+	if got := containerEvent(msg); got.ExitCode != 0 || got.Action != EventDestroy {
+		t.Errorf("containerEvent(destroy) = %+v", got)
+	}
+}
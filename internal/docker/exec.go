@@ -0,0 +1,110 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// DefaultExecTimeout bounds ExecInContainer when no timeout is given.
+const DefaultExecTimeout = 5 * time.Minute
+
+// execPollInterval is how often to re-check an exec whose output has closed
+// but which the daemon still reports as running.
+const execPollInterval = 50 * time.Millisecond
+
+// ExecResult is the outcome of a command run in a container.
+type ExecResult struct {
+	ExitCode int
+	Stdout   string
+	Stderr   string
+}
+
+// ExecInContainer runs a command in a running container, capturing its
+// stdout and stderr, and waits up to timeout (0 uses DefaultExecTimeout) for
+// it to finish. A non-zero exit is reported in the result, not as an error.
+// The daemon cannot kill an exec, so a timed-out command may keep running
+// until the container stops.
+func (c *Client) ExecInContainer(ctx context.Context, containerID string, cmd []string, timeout time.Duration) (*ExecResult, error) {
+	if timeout <= 0 {
+		timeout = DefaultExecTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	created, err := c.cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating exec: %w", err)
+	}
+
+	conn, err := c.cli.ContainerExecAttach(ctx, created.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("attaching to exec: %w", err)
+	}
+	defer conn.Close()
+
+	var stdout, stderr bytes.Buffer
+	if err := collectExec(ctx, conn.Reader, conn.Close, false, &stdout, &stderr); err != nil {
+		return nil, execError(err, timeout)
+	}
+
+	// The output stream closes as the process exits, but the daemon may
+	// not have recorded the exit code yet
+	for {
+		inspect, err := c.cli.ContainerExecInspect(ctx, created.ID)
+		if err != nil {
+			return nil, execError(fmt.Errorf("inspecting exec: %w", err), timeout)
+		}
+		if !inspect.Running {
+			return &ExecResult{
+				ExitCode: inspect.ExitCode,
+				Stdout:   stdout.String(),
+				Stderr:   stderr.String(),
+			}, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, execError(ctx.Err(), timeout)
+		case <-time.After(execPollInterval):
+		}
+	}
+}
+
+// collectExec copies an exec's output until the stream ends or ctx is done,
+// in which case it calls closeStream to unblock the copy and returns
+// ctx.Err().
+func collectExec(ctx context.Context, stream io.Reader, closeStream func(), tty bool, stdout, stderr io.Writer) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- copyLogs(stream, tty, stdout, stderr)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("reading exec output: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		closeStream()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// execError reports a deadline as a timeout.
+func execError(err error, timeout time.Duration) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("exec timed out after %s", timeout)
+	}
+	return err
+}
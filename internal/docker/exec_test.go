@@ -0,0 +1,53 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+func TestCollectExec_CapturesOutput(t *testing.T) {
+	var stream bytes.Buffer
+	stdcopy.NewStdWriter(&stream, stdcopy.Stdout).Write([]byte("ok\n"))
+	stdcopy.NewStdWriter(&stream, stdcopy.Stderr).Write([]byte("warn\n"))
+
+	var stdout, stderr bytes.Buffer
+	if err := collectExec(context.Background(), &stream, func() {}, false, &stdout, &stderr); err != nil {
+		t.Fatalf("collectExec() error = %v", err)
+	}
+	if stdout.String() != "ok\n" || stderr.String() != "warn\n" {
+		t.Errorf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestCollectExec_Timeout(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	err := collectExec(ctx, r, func() { r.Close() }, false, &stdout, &stderr)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("collectExec() error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestExecError(t *testing.T) {
+	err := execError(context.DeadlineExceeded, time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "timed out after 1m0s") {
+		t.Errorf("execError(deadline) = %v, want timeout message", err)
+	}
+
+	other := errors.New("boom")
+	if got := execError(other, time.Minute); got != other {
+		t.Errorf("execError(other) = %v, want unchanged", got)
+	}
+}
@@ -0,0 +1,64 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// LogOptions selects which container output to stream.
+type LogOptions struct {
+	Follow bool      // Keep streaming until the container stops or ctx is done
+	Tail   int       // Start with this many lines from the end; 0 means all
+	Since  time.Time // Only output after this time; zero means from the start
+}
+
+// StreamContainerLogs writes a container's output to stdout and stderr.
+// Non-TTY output is demultiplexed so neither writer sees Docker's stream
+// headers; TTY output has no separate stderr and all goes to stdout. With
+// Follow it blocks until the container stops or ctx is done.
+func (c *Client) StreamContainerLogs(ctx context.Context, containerID string, opts LogOptions, stdout, stderr io.Writer) error {
+	info, err := c.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("inspecting container: %w", err)
+	}
+	tty := info.Config != nil && info.Config.Tty
+
+	logOpts := container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     opts.Follow,
+	}
+	if opts.Tail > 0 {
+		logOpts.Tail = fmt.Sprint(opts.Tail)
+	}
+	if !opts.Since.IsZero() {
+		logOpts.Since = opts.Since.Format(time.RFC3339Nano)
+	}
+
+	logs, err := c.cli.ContainerLogs(ctx, containerID, logOpts)
+	if err != nil {
+		return fmt.Errorf("getting container logs: %w", err)
+	}
+	defer logs.Close()
+
+	if err := copyLogs(logs, tty, stdout, stderr); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("streaming container logs: %w", err)
+	}
+	return nil
+}
+
+// copyLogs copies a container log stream, demultiplexing it unless the
+// container has a TTY.
+func copyLogs(logs io.Reader, tty bool, stdout, stderr io.Writer) error {
+	if tty {
+		_, err := io.Copy(stdout, logs)
+		return err
+	}
+	_, err := stdcopy.StdCopy(stdout, stderr, logs)
+	return err
+}
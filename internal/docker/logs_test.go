@@ -0,0 +1,35 @@
+package docker
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+func TestCopyLogs_Demultiplexes(t *testing.T) {
+	var stream bytes.Buffer
+	stdcopy.NewStdWriter(&stream, stdcopy.Stdout).Write([]byte("{\"type\":\"result\"}\n"))
+	stdcopy.NewStdWriter(&stream, stdcopy.Stderr).Write([]byte("warning: something\n"))
+
+	var stdout, stderr bytes.Buffer
+	if err := copyLogs(&stream, false, &stdout, &stderr); err != nil {
+		t.Fatalf("copyLogs() error = %v", err)
+	}
+	if got := stdout.String(); got != "{\"type\":\"result\"}\n" {
+		t.Errorf("stdout = %q, want clean JSON line", got)
+	}
+	if got := stderr.String(); got != "warning: something\n" {
+		t.Errorf("stderr = %q, want stderr frame", got)
+	}
+}
+
+func TestCopyLogs_TTYPassesThrough(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if err := copyLogs(bytes.NewBufferString("raw tty output\r\n"), true, &stdout, &stderr); err != nil {
+		t.Fatalf("copyLogs() error = %v", err)
+	}
+	if stdout.String() != "raw tty output\r\n" || stderr.Len() != 0 {
+		t.Errorf("stdout = %q, stderr = %q; want TTY output on stdout", stdout.String(), stderr.String())
+	}
+}
@@ -0,0 +1,113 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/docker/docker/api/types/registry"
+)
+
+// PullPolicy controls when agent images are pulled.
+type PullPolicy string
+
+const (
+	PullAlways       PullPolicy = "always"         // Pull on every warm-up, picking up moved tags
+	PullIfNotPresent PullPolicy = "if-not-present" // Pull only missing images (default)
+	PullNever        PullPolicy = "never"          // Never pull; missing images fail
+)
+
+// ParsePullPolicy validates a configured pull policy. Empty means
+// if-not-present.
+func ParsePullPolicy(s string) (PullPolicy, error) {
+	switch PullPolicy(strings.ToLower(s)) {
+	case "", PullIfNotPresent:
+		return PullIfNotPresent, nil
+	case PullAlways:
+		return PullAlways, nil
+	case PullNever:
+		return PullNever, nil
+	default:
+		return "", fmt.Errorf("unknown pull policy %q (want always, if-not-present, or never)", s)
+	}
+}
+
+// dockerHubServer is the server address Docker uses for Docker Hub credentials.
+const dockerHubServer = "https://index.docker.io/v1/"
+
+// RegistryAuth holds credentials for pulling agent images from a private
+// registry: either a username and password, or the name of a Docker
+// credential helper (e.g. "ecr-login" runs docker-credential-ecr-login).
+type RegistryAuth struct {
+	Username         string
+	Password         string
+	CredentialHelper string
+	ServerAddress    string // Defaults to the registry in the image reference
+}
+
+// encode returns the X-Registry-Auth value for pulling image, or "" when no
+// credentials are configured.
+func (a RegistryAuth) encode(ctx context.Context, image string) (string, error) {
+	if a.Username == "" && a.CredentialHelper == "" {
+		return "", nil
+	}
+
+	server := a.ServerAddress
+	if server == "" {
+		server = registryHost(image)
+	}
+	cfg := registry.AuthConfig{
+		Username:      a.Username,
+		Password:      a.Password,
+		ServerAddress: server,
+	}
+	if a.CredentialHelper != "" {
+		creds, err := helperCredentials(ctx, a.CredentialHelper, server)
+		if err != nil {
+			return "", err
+		}
+		cfg.Username, cfg.Password = creds.Username, creds.Secret
+		// Helpers return "<token>" as the username for identity tokens
+		if creds.Username == "<token>" {
+			cfg.Username, cfg.Password, cfg.IdentityToken = "", "", creds.Secret
+		}
+	}
+	return registry.EncodeAuthConfig(cfg)
+}
+
+// helperCreds is the response of a docker-credential-* helper's get command.
+type helperCreds struct {
+	Username string
+	Secret   string
+}
+
+// helperCredentials asks a Docker credential helper for server's credentials.
+func helperCredentials(ctx context.Context, helper, server string) (*helperCreds, error) {
+	cmd := exec.CommandContext(ctx, "docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(server)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("credential helper %s: %w: %s", helper, err, strings.TrimSpace(stderr.String()+string(out)))
+	}
+	var creds helperCreds
+	if err := json.Unmarshal(out, &creds); err != nil {
+		return nil, fmt.Errorf("parsing credential helper %s output: %w", helper, err)
+	}
+	return &creds, nil
+}
+
+// registryHost returns the registry an image reference points at, following
+// Docker's rule that the first path component is a registry only if it looks
+// like a host name.
+func registryHost(image string) string {
+	first, _, found := strings.Cut(image, "/")
+	if !found || (!strings.ContainsAny(first, ".:") && first != "localhost") {
+		return dockerHubServer
+	}
+	return first
+}
@@ -0,0 +1,108 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types/registry"
+)
+
+func TestParsePullPolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    PullPolicy
+		wantErr bool
+	}{
+		{"", PullIfNotPresent, false},
+		{"if-not-present", PullIfNotPresent, false},
+		{"Always", PullAlways, false},
+		{"never", PullNever, false},
+		{"sometimes", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePullPolicy(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParsePullPolicy(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRegistryHost(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"familiar-agent:latest", dockerHubServer},
+		{"myorg/familiar-agent:latest", dockerHubServer},
+		{"ghcr.io/myorg/familiar-agent:latest", "ghcr.io"},
+		{"registry.internal:5000/agent@sha256:abc", "registry.internal:5000"},
+		{"localhost/agent", "localhost"},
+	}
+
+	for _, tt := range tests {
+		if got := registryHost(tt.image); got != tt.want {
+			t.Errorf("registryHost(%q) = %q, want %q", tt.image, got, tt.want)
+		}
+	}
+}
+
+// decodeAuth decodes an X-Registry-Auth value.
+func decodeAuth(t *testing.T, encoded string) registry.AuthConfig {
+	t.Helper()
+	data, err := base64.URLEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("decoding auth: %v", err)
+	}
+	var cfg registry.AuthConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("parsing auth: %v", err)
+	}
+	return cfg
+}
+
+func TestRegistryAuth_Encode(t *testing.T) {
+	ctx := context.Background()
+
+	if got, err := (RegistryAuth{}).encode(ctx, "ghcr.io/org/agent"); err != nil || got != "" {
+		t.Errorf("encode() without credentials = %q, %v; want empty", got, err)
+	}
+
+	encoded, err := RegistryAuth{Username: "bot", Password: "s3cret"}.encode(ctx, "ghcr.io/org/agent")
+	if err != nil {
+		t.Fatalf("encode() error = %v", err)
+	}
+	cfg := decodeAuth(t, encoded)
+	if cfg.Username != "bot" || cfg.Password != "s3cret" || cfg.ServerAddress != "ghcr.io" {
+		t.Errorf("auth = %+v, want bot/s3cret for ghcr.io", cfg)
+	}
+}
+
+func TestRegistryAuth_CredentialHelper(t *testing.T) {
+	dir := t.TempDir()
+	script := `#!/bin/sh
+read server
+echo "{\"Username\":\"helper-user\",\"Secret\":\"token-for-$server\"}"
+`
+	if err := os.WriteFile(filepath.Join(dir, "docker-credential-fake"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	encoded, err := RegistryAuth{CredentialHelper: "fake"}.encode(context.Background(), "registry.internal:5000/agent")
+	if err != nil {
+		t.Fatalf("encode() error = %v", err)
+	}
+	cfg := decodeAuth(t, encoded)
+	if cfg.Username != "helper-user" || cfg.Password != "token-for-registry.internal:5000" {
+		t.Errorf("auth = %+v, want helper credentials", cfg)
+	}
+
+	if _, err := (RegistryAuth{CredentialHelper: "missing"}).encode(context.Background(), "agent"); err == nil {
+		t.Error("encode() should fail when the helper is not installed")
+	}
+}
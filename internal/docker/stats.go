@@ -0,0 +1,65 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// ContainerStats is a snapshot of a container's resource usage.
+type ContainerStats struct {
+	CPUPercent       float64   `json:"cpu_percent"` // Of one CPU; 200 means two cores busy
+	MemoryBytes      uint64    `json:"memory_bytes"`
+	MemoryLimitBytes uint64    `json:"memory_limit_bytes"`
+	PIDs             uint64    `json:"pids"`
+	SampledAt        time.Time `json:"sampled_at"`
+}
+
+// Stats samples a running container's CPU and memory usage. The daemon
+// measures CPU over about a second, so this blocks for that long.
+func (c *Client) Stats(ctx context.Context, containerID string) (*ContainerStats, error) {
+	resp, err := c.cli.ContainerStats(ctx, containerID, false)
+	if err != nil {
+		return nil, fmt.Errorf("getting container stats: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var raw container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("decoding container stats: %w", err)
+	}
+	return computeStats(&raw), nil
+}
+
+// computeStats derives usage the way `docker stats` does: CPU from the
+// change since the previous sample, memory excluding reclaimable page cache.
+func computeStats(raw *container.StatsResponse) *ContainerStats {
+	stats := &ContainerStats{
+		MemoryLimitBytes: raw.MemoryStats.Limit,
+		PIDs:             raw.PidsStats.Current,
+		SampledAt:        raw.Read,
+	}
+
+	cpuDelta := float64(raw.CPUStats.CPUUsage.TotalUsage) - float64(raw.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(raw.CPUStats.SystemUsage) - float64(raw.PreCPUStats.SystemUsage)
+	cpus := float64(raw.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(raw.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if raw.PreCPUStats.SystemUsage > 0 && cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUPercent = cpuDelta / systemDelta * cpus * 100
+	}
+
+	// cgroup v2 reports inactive_file; v1 reports total_inactive_file
+	cache := raw.MemoryStats.Stats["inactive_file"]
+	if v, ok := raw.MemoryStats.Stats["total_inactive_file"]; ok {
+		cache = v
+	}
+	if raw.MemoryStats.Usage > cache {
+		stats.MemoryBytes = raw.MemoryStats.Usage - cache
+	}
+	return stats
+}
@@ -0,0 +1,51 @@
+package docker
+
+import (
+	"math"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestComputeStats(t *testing.T) {
+	raw := &container.StatsResponse{}
+	raw.PreCPUStats.CPUUsage.TotalUsage = 1_000_000_000
+	raw.PreCPUStats.SystemUsage = 100_000_000_000
+	raw.CPUStats.CPUUsage.TotalUsage = 1_500_000_000 // 0.5s of CPU
+	raw.CPUStats.SystemUsage = 104_000_000_000       // 4s across all CPUs
+	raw.CPUStats.OnlineCPUs = 4
+	raw.MemoryStats.Usage = 600 << 20
+	raw.MemoryStats.Limit = 2 << 30
+	raw.MemoryStats.Stats = map[string]uint64{"inactive_file": 100 << 20}
+	raw.PidsStats.Current = 12
+
+	stats := computeStats(raw)
+
+	if math.Abs(stats.CPUPercent-50) > 0.001 {
+		t.Errorf("CPUPercent = %v, want 50", stats.CPUPercent)
+	}
+	if stats.MemoryBytes != 500<<20 {
+		t.Errorf("MemoryBytes = %d, want usage minus page cache (%d)", stats.MemoryBytes, 500<<20)
+	}
+	if stats.MemoryLimitBytes != 2<<30 || stats.PIDs != 12 {
+		t.Errorf("limit = %d, pids = %d", stats.MemoryLimitBytes, stats.PIDs)
+	}
+}
+
+func TestComputeStats_FirstSample(t *testing.T) {
+	raw := &container.StatsResponse{}
+	raw.CPUStats.CPUUsage.TotalUsage = 1_000_000
+	raw.CPUStats.SystemUsage = 1_000_000
+	raw.CPUStats.OnlineCPUs = 2
+	raw.MemoryStats.Usage = 10 << 20
+	raw.MemoryStats.Stats = map[string]uint64{"total_inactive_file": 20 << 20}
+
+	stats := computeStats(raw)
+
+	if stats.CPUPercent != 0 {
+		t.Errorf("CPUPercent = %v, want 0 without a previous sample", stats.CPUPercent)
+	}
+	if stats.MemoryBytes != 0 {
+		t.Errorf("MemoryBytes = %d, want 0 when cache exceeds usage", stats.MemoryBytes)
+	}
+}
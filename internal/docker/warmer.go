@@ -0,0 +1,190 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Image pull states reported by ImageWarmer.
+const (
+	ImagePending = "pending"
+	ImagePulling = "pulling"
+	ImageReady   = "ready"
+	ImageFailed  = "failed"
+)
+
+// ImageStore checks for and pulls images. Implemented by Client.
+type ImageStore interface {
+	ImageExists(ctx context.Context, imageName string) (bool, error)
+	PullImage(ctx context.Context, imageName string) error
+}
+
+// ImageStatus describes the pull state of one image.
+type ImageStatus struct {
+	Image     string    `json:"image"`
+	State     string    `json:"state"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ImageWarmer pre-pulls agent images so spawns don't pay for on-demand pulls.
+type ImageWarmer struct {
+	store  ImageStore
+	policy PullPolicy
+	mu     sync.RWMutex
+	images []string
+	status map[string]ImageStatus
+}
+
+// WarmerOption configures an ImageWarmer.
+type WarmerOption func(*ImageWarmer)
+
+// WithPullPolicy sets when images are pulled. The default is PullIfNotPresent.
+func WithPullPolicy(p PullPolicy) WarmerOption {
+	return func(w *ImageWarmer) {
+		w.policy = p
+	}
+}
+
+// NewImageWarmer creates a warmer backed by the given image store.
+func NewImageWarmer(store ImageStore, opts ...WarmerOption) *ImageWarmer {
+	w := &ImageWarmer{
+		store:  store,
+		policy: PullIfNotPresent,
+		status: make(map[string]ImageStatus),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Warm makes sure each image is present locally, pulling missing ones.
+// The given images replace any previously tracked set. Returns once every
+// image is ready or has failed.
+func (w *ImageWarmer) Warm(ctx context.Context, images ...string) {
+	w.mu.Lock()
+	w.images = dedupe(images)
+	status := make(map[string]ImageStatus, len(w.images))
+	for _, img := range w.images {
+		if prev, ok := w.status[img]; ok && prev.State == ImageReady {
+			status[img] = prev
+			continue
+		}
+		status[img] = ImageStatus{Image: img, State: ImagePending, UpdatedAt: time.Now()}
+	}
+	w.status = status
+	pending := append([]string(nil), w.images...)
+	w.mu.Unlock()
+
+	for _, img := range pending {
+		w.warmOne(ctx, img)
+	}
+}
+
+// warmOne verifies or pulls a single image and records the outcome.
+func (w *ImageWarmer) warmOne(ctx context.Context, img string) {
+	if err := w.pull(ctx, img, func() { w.set(img, ImagePulling, nil) }); err != nil {
+		slog.Warn("agent image unavailable", "image", img, "error", err)
+		w.set(img, ImageFailed, err)
+		return
+	}
+	w.set(img, ImageReady, nil)
+}
+
+// Switch makes img the only tracked image once it is present locally,
+// pulling it as the policy requires. The tracked set, and so Ready, is left
+// alone while it pulls and if it fails.
+func (w *ImageWarmer) Switch(ctx context.Context, img string) error {
+	if err := w.pull(ctx, img, func() {}); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.images = []string{img}
+	w.status = map[string]ImageStatus{img: {Image: img, State: ImageReady, UpdatedAt: time.Now()}}
+	return nil
+}
+
+// pull makes sure img is present locally under the pull policy, calling
+// pulling before it pulls from the registry.
+func (w *ImageWarmer) pull(ctx context.Context, img string, pulling func()) error {
+	exists, err := w.store.ImageExists(ctx, img)
+	if err == nil && exists && w.policy != PullAlways {
+		return nil
+	}
+	if w.policy == PullNever {
+		if err == nil {
+			err = errors.New("image not present locally and pull policy is never")
+		}
+		return err
+	}
+
+	pulling()
+	start := time.Now()
+	if err := w.store.PullImage(ctx, img); err != nil {
+		if exists {
+			// Policy is always: keep using the local copy rather than
+			// blocking agents on a registry outage
+			slog.Warn("failed to refresh agent image, using local copy", "image", img, "error", err)
+			return nil
+		}
+		return err
+	}
+	slog.Info("pulled agent image", "image", img, "duration", time.Since(start).Round(time.Second))
+	return nil
+}
+
+// set records an image's state if it is still tracked.
+func (w *ImageWarmer) set(img, state string, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.status[img]; !ok {
+		return
+	}
+	st := ImageStatus{Image: img, State: state, UpdatedAt: time.Now()}
+	if err != nil {
+		st.Error = err.Error()
+	}
+	w.status[img] = st
+}
+
+// Ready reports whether every tracked image is present locally.
+func (w *ImageWarmer) Ready() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	for _, img := range w.images {
+		if w.status[img].State != ImageReady {
+			return false
+		}
+	}
+	return true
+}
+
+// Status returns the pull state of each tracked image, in configured order.
+func (w *ImageWarmer) Status() []ImageStatus {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	status := make([]ImageStatus, 0, len(w.images))
+	for _, img := range w.images {
+		status = append(status, w.status[img])
+	}
+	return status
+}
+
+// dedupe drops empty and repeated image names, keeping order.
+func dedupe(images []string) []string {
+	seen := make(map[string]bool, len(images))
+	var out []string
+	for _, img := range images {
+		if img == "" || seen[img] {
+			continue
+		}
+		seen[img] = true
+		out = append(out, img)
+	}
+	return out
+}
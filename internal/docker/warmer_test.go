@@ -0,0 +1,159 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type mockImageStore struct {
+	present map[string]bool
+	pullErr map[string]error
+	pulled  []string
+	onPull  func(img string) // called as a pull starts
+}
+
+func (m *mockImageStore) ImageExists(_ context.Context, img string) (bool, error) {
+	return m.present[img], nil
+}
+
+func (m *mockImageStore) PullImage(_ context.Context, img string) error {
+	if m.onPull != nil {
+		m.onPull(img)
+	}
+	if err := m.pullErr[img]; err != nil {
+		return err
+	}
+	m.pulled = append(m.pulled, img)
+	m.present[img] = true
+	return nil
+}
+
+func TestImageWarmer_Warm(t *testing.T) {
+	store := &mockImageStore{
+		present: map[string]bool{"local:latest": true},
+		pullErr: map[string]error{"missing:latest": errors.New("not found")},
+	}
+	w := NewImageWarmer(store)
+
+	w.Warm(context.Background(), "local:latest", "remote:latest", "missing:latest", "remote:latest", "")
+
+	if len(store.pulled) != 1 || store.pulled[0] != "remote:latest" {
+		t.Errorf("pulled = %v, want [remote:latest]", store.pulled)
+	}
+
+	want := map[string]string{
+		"local:latest":   ImageReady,
+		"remote:latest":  ImageReady,
+		"missing:latest": ImageFailed,
+	}
+	status := w.Status()
+	if len(status) != len(want) {
+		t.Fatalf("Status() = %v, want %d images", status, len(want))
+	}
+	for _, st := range status {
+		if st.State != want[st.Image] {
+			t.Errorf("%s state = %q, want %q", st.Image, st.State, want[st.Image])
+		}
+	}
+	if status[2].Error == "" {
+		t.Error("failed image should report its error")
+	}
+	if w.Ready() {
+		t.Error("Ready() = true with a failed image")
+	}
+}
+
+func TestImageWarmer_Ready(t *testing.T) {
+	store := &mockImageStore{present: map[string]bool{}}
+	w := NewImageWarmer(store)
+
+	// Nothing configured yet counts as ready
+	if !w.Ready() {
+		t.Error("Ready() = false before any images are configured")
+	}
+
+	w.Warm(context.Background(), "agent:latest")
+	if !w.Ready() {
+		t.Errorf("Ready() = false after pull, status = %v", w.Status())
+	}
+}
+
+func TestImageWarmer_RewarmReplacesImages(t *testing.T) {
+	store := &mockImageStore{present: map[string]bool{}}
+	w := NewImageWarmer(store)
+
+	w.Warm(context.Background(), "old:latest")
+	w.Warm(context.Background(), "new:latest")
+
+	status := w.Status()
+	if len(status) != 1 || status[0].Image != "new:latest" {
+		t.Errorf("Status() = %v, want only new:latest", status)
+	}
+}
+
+func TestImageWarmer_Switch(t *testing.T) {
+	store := &mockImageStore{
+		present: map[string]bool{"old:latest": true},
+		pullErr: map[string]error{"missing:latest": errors.New("not found")},
+	}
+	w := NewImageWarmer(store, WithPullPolicy(PullAlways))
+	w.Warm(context.Background(), "old:latest")
+
+	readyDuringPull := true
+	store.onPull = func(string) { readyDuringPull = readyDuringPull && w.Ready() }
+
+	if err := w.Switch(context.Background(), "missing:latest"); err == nil {
+		t.Error("Switch() error = nil for an image that cannot be pulled")
+	}
+	if status := w.Status(); len(status) != 1 || status[0].Image != "old:latest" || status[0].State != ImageReady {
+		t.Errorf("Status() = %v after failed switch, want old:latest ready", status)
+	}
+
+	if err := w.Switch(context.Background(), "new:latest"); err != nil {
+		t.Fatalf("Switch() error = %v", err)
+	}
+	if status := w.Status(); len(status) != 1 || status[0].Image != "new:latest" || status[0].State != ImageReady {
+		t.Errorf("Status() = %v after switch, want new:latest ready", status)
+	}
+	if !readyDuringPull {
+		t.Error("Ready() = false while the candidate image was pulling")
+	}
+}
+
+func TestImageWarmer_PullPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     PullPolicy
+		present    bool
+		pullErr    error
+		wantPulled bool
+		wantState  string
+	}{
+		{"if-not-present skips local image", PullIfNotPresent, true, nil, false, ImageReady},
+		{"always refreshes local image", PullAlways, true, nil, true, ImageReady},
+		{"always keeps local copy when pull fails", PullAlways, true, errors.New("registry down"), false, ImageReady},
+		{"always fails without local copy", PullAlways, false, errors.New("registry down"), false, ImageFailed},
+		{"never uses local image", PullNever, true, nil, false, ImageReady},
+		{"never fails for missing image", PullNever, false, nil, false, ImageFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &mockImageStore{
+				present: map[string]bool{"agent:latest": tt.present},
+				pullErr: map[string]error{"agent:latest": tt.pullErr},
+			}
+			w := NewImageWarmer(store, WithPullPolicy(tt.policy))
+
+			w.Warm(context.Background(), "agent:latest")
+
+			if pulled := len(store.pulled) > 0; pulled != tt.wantPulled {
+				t.Errorf("pulled = %v, want %v", pulled, tt.wantPulled)
+			}
+			if got := w.Status()[0].State; got != tt.wantState {
+				t.Errorf("state = %q, want %q", got, tt.wantState)
+			}
+		})
+	}
+}
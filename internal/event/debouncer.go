@@ -1,6 +1,7 @@
 package event
 
 import (
+	"sort"
 	"sync"
 	"time"
 )
@@ -8,18 +9,37 @@ import (
 // Debouncer prevents duplicate events within a time window.
 type Debouncer struct {
 	window time.Duration
-	seen   map[string]time.Time
+	seen   map[string]*DebouncedEvent
 	mu     sync.Mutex
 }
 
+// DebouncedEvent describes an event whose repeats are being dropped until
+// its window ends.
+type DebouncedEvent struct {
+	Provider   string
+	Repo       string // owner/name
+	MRNumber   int
+	Type       Type
+	SeenAt     time.Time // when the event was last processed
+	Until      time.Time // when a repeat will be processed again
+	Suppressed int       // repeats dropped since SeenAt
+}
+
 // NewDebouncer creates a new debouncer with the given window.
 func NewDebouncer(window time.Duration) *Debouncer {
 	return &Debouncer{
 		window: window,
-		seen:   make(map[string]time.Time),
+		seen:   make(map[string]*DebouncedEvent),
 	}
 }
 
+// SetWindow changes the window for events seen from now on.
+func (d *Debouncer) SetWindow(window time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.window = window
+}
+
 // ShouldProcess returns true if the event should be processed.
 // Returns false if a similar event was processed recently.
 func (d *Debouncer) ShouldProcess(e *Event) bool {
@@ -29,24 +49,49 @@ func (d *Debouncer) ShouldProcess(e *Event) bool {
 	key := e.Key()
 	now := time.Now()
 
-	if lastSeen, ok := d.seen[key]; ok {
-		if now.Sub(lastSeen) < d.window {
+	if last, ok := d.seen[key]; ok {
+		if now.Sub(last.SeenAt) < d.window {
+			last.Suppressed++
 			return false
 		}
 	}
 
-	d.seen[key] = now
+	d.seen[key] = &DebouncedEvent{
+		Provider: e.Provider,
+		Repo:     e.RepoOwner + "/" + e.RepoName,
+		MRNumber: e.MRNumber,
+		Type:     e.Type,
+		SeenAt:   now,
+	}
 	return true
 }
 
+// Pending returns the events still within their window as of now, soonest
+// to expire first. Their repeats are dropped until Until.
+func (d *Debouncer) Pending(now time.Time) []DebouncedEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var pending []DebouncedEvent
+	for _, e := range d.seen {
+		if until := e.SeenAt.Add(d.window); now.Before(until) {
+			p := *e
+			p.Until = until
+			pending = append(pending, p)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Until.Before(pending[j].Until) })
+	return pending
+}
+
 // Cleanup removes old entries from the seen map.
 func (d *Debouncer) Cleanup() {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
 	threshold := time.Now().Add(-d.window * 2)
-	for key, t := range d.seen {
-		if t.Before(threshold) {
+	for key, e := range d.seen {
+		if e.SeenAt.Before(threshold) {
 			delete(d.seen, key)
 		}
 	}
@@ -86,3 +86,43 @@ func TestDebouncer_Cleanup(t *testing.T) {
 		t.Error("Event should be accepted after cleanup")
 	}
 }
+
+func TestDebouncer_SetWindow(t *testing.T) {
+	d := NewDebouncer(time.Hour)
+	event := &Event{Provider: "github", RepoOwner: "owner", RepoName: "repo", Type: TypeMRUpdated, MRNumber: 42}
+
+	d.ShouldProcess(event)
+	if d.ShouldProcess(event) {
+		t.Fatal("Duplicate event should be debounced within the original window")
+	}
+
+	d.SetWindow(0)
+	if !d.ShouldProcess(event) {
+		t.Error("Event should be accepted once the window is shortened")
+	}
+}
+
+func TestDebouncer_Pending(t *testing.T) {
+	d := NewDebouncer(time.Minute)
+	event := &Event{Provider: "github", RepoOwner: "owner", RepoName: "repo", Type: TypeMRUpdated, MRNumber: 42}
+
+	d.ShouldProcess(event)
+	d.ShouldProcess(event)
+	d.ShouldProcess(event)
+
+	pending := d.Pending(time.Now())
+	if len(pending) != 1 {
+		t.Fatalf("Pending() = %+v, want one event", pending)
+	}
+	p := pending[0]
+	if p.Repo != "owner/repo" || p.MRNumber != 42 || p.Type != TypeMRUpdated || p.Suppressed != 2 {
+		t.Errorf("Pending()[0] = %+v, want owner/repo !42 mr_updated with 2 suppressed", p)
+	}
+	if want := p.SeenAt.Add(time.Minute); !p.Until.Equal(want) {
+		t.Errorf("Until = %v, want %v", p.Until, want)
+	}
+
+	if pending := d.Pending(time.Now().Add(time.Minute)); len(pending) != 0 {
+		t.Errorf("Pending() after the window = %+v, want none", pending)
+	}
+}
@@ -2,6 +2,7 @@ package event
 
 import (
 	"fmt"
+	"log/slog"
 	"time"
 )
 
@@ -34,6 +35,12 @@ type Event struct {
 	MRDescription string
 	SourceBranch  string
 	TargetBranch  string
+	FromFork      bool // The source branch lives in a fork, not in this repo
+	IsIssue       bool // MRNumber is an issue rather than a merge request
+
+	// DefaultBranch is set when no source branch applies, such as for a
+	// mention in an issue; agents then work from the repo's default branch.
+	DefaultBranch string
 
 	// Comment information (for TypeMRComment and TypeMention).
 	CommentID           int
@@ -51,9 +58,27 @@ type Event struct {
 
 	// RawPayload is the original webhook payload.
 	RawPayload []byte
+
+	// CorrelationID identifies the webhook delivery this event came from.
+	CorrelationID string
+}
+
+// Logger returns the default logger with the event's provider, repo, MR
+// number, and correlation ID attached.
+func (e *Event) Logger() *slog.Logger {
+	logger := slog.With("provider", e.Provider, "repo", e.RepoOwner+"/"+e.RepoName, "mr", e.MRNumber)
+	if e.CorrelationID != "" {
+		logger = logger.With("correlation_id", e.CorrelationID)
+	}
+	return logger
 }
 
 // Key returns a unique key for this event (used for debouncing).
 func (e *Event) Key() string {
 	return e.Provider + "/" + e.RepoOwner + "/" + e.RepoName + "/" + string(e.Type) + "/" + fmt.Sprint(e.MRNumber)
 }
+
+// MRKey identifies the merge request this event is for.
+func (e *Event) MRKey() string {
+	return e.Provider + "/" + e.RepoOwner + "/" + e.RepoName + "/" + fmt.Sprint(e.MRNumber)
+}
@@ -17,7 +17,10 @@ type gitHubPayload struct {
 		Title string `json:"title"`
 		Body  string `json:"body"`
 		Head  struct {
-			Ref string `json:"ref"`
+			Ref  string `json:"ref"`
+			Repo *struct {
+				FullName string `json:"full_name"`
+			} `json:"repo"`
 		} `json:"head"`
 		Base struct {
 			Ref string `json:"ref"`
@@ -27,7 +30,8 @@ type gitHubPayload struct {
 		} `json:"user"`
 	} `json:"pull_request"`
 	Issue struct {
-		Number int `json:"number"`
+		Number      int       `json:"number"`
+		PullRequest *struct{} `json:"pull_request"` // present when the issue is a pull request
 	} `json:"issue"`
 	Comment struct {
 		ID   int    `json:"id"`
@@ -59,13 +63,14 @@ func NormalizeGitHubEvent(ghEvent *webhook.GitHubEvent) (*Event, error) {
 	}
 
 	event := &Event{
-		Provider:   "github",
-		RepoOwner:  parts[0],
-		RepoName:   parts[1],
-		RepoURL:    payload.Repository.CloneURL,
-		Actor:      payload.Sender.Login,
-		Timestamp:  time.Now(),
-		RawPayload: ghEvent.RawPayload,
+		Provider:      "github",
+		RepoOwner:     parts[0],
+		RepoName:      parts[1],
+		RepoURL:       payload.Repository.CloneURL,
+		Actor:         payload.Sender.Login,
+		Timestamp:     time.Now(),
+		RawPayload:    ghEvent.RawPayload,
+		CorrelationID: ghEvent.CorrelationID,
 	}
 
 	switch ghEvent.EventType {
@@ -75,6 +80,9 @@ func NormalizeGitHubEvent(ghEvent *webhook.GitHubEvent) (*Event, error) {
 		event.MRDescription = payload.PullRequest.Body
 		event.SourceBranch = payload.PullRequest.Head.Ref
 		event.TargetBranch = payload.PullRequest.Base.Ref
+		if head := payload.PullRequest.Head.Repo; head != nil && head.FullName != payload.Repository.FullName {
+			event.FromFork = true
+		}
 
 		switch payload.Action {
 		case "opened":
@@ -87,6 +95,7 @@ func NormalizeGitHubEvent(ghEvent *webhook.GitHubEvent) (*Event, error) {
 
 	case "issue_comment":
 		event.MRNumber = payload.Issue.Number
+		event.IsIssue = payload.Issue.PullRequest == nil
 		event.CommentID = payload.Comment.ID
 		event.CommentBody = payload.Comment.Body
 		event.CommentAuthor = payload.Comment.User.Login
@@ -25,9 +25,10 @@ func TestNormalizeGitHubEvent_PROpened(t *testing.T) {
 	}`)
 
 	ghEvent := &webhook.GitHubEvent{
-		EventType:  "pull_request",
-		Action:     "opened",
-		RawPayload: raw,
+		EventType:     "pull_request",
+		Action:        "opened",
+		RawPayload:    raw,
+		CorrelationID: "abc123",
 	}
 
 	event, err := NormalizeGitHubEvent(ghEvent)
@@ -47,12 +48,49 @@ func TestNormalizeGitHubEvent_PROpened(t *testing.T) {
 	if event.SourceBranch != "feature" {
 		t.Errorf("SourceBranch = %q, want %q", event.SourceBranch, "feature")
 	}
+	if event.CorrelationID != "abc123" {
+		t.Errorf("CorrelationID = %q, want %q", event.CorrelationID, "abc123")
+	}
+}
+
+func TestNormalizeGitHubEvent_FromFork(t *testing.T) {
+	tests := []struct {
+		name     string
+		headRepo string
+		want     bool
+	}{
+		{"same repo", `"repo": {"full_name": "owner/repo"}`, false},
+		{"fork", `"repo": {"full_name": "contributor/repo"}`, true},
+		{"head repo omitted", `"sha": "abc123"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := []byte(`{
+				"action": "opened",
+				"number": 42,
+				"pull_request": {
+					"head": {"ref": "feature", ` + tt.headRepo + `},
+					"base": {"ref": "main"}
+				},
+				"repository": {"full_name": "owner/repo"}
+			}`)
+
+			event, err := NormalizeGitHubEvent(&webhook.GitHubEvent{EventType: "pull_request", RawPayload: raw})
+			if err != nil {
+				t.Fatalf("NormalizeGitHubEvent() error = %v", err)
+			}
+			if event.FromFork != tt.want {
+				t.Errorf("FromFork = %v, want %v", event.FromFork, tt.want)
+			}
+		})
+	}
 }
 
 func TestNormalizeGitHubEvent_PRComment(t *testing.T) {
 	raw := []byte(`{
 		"action": "created",
-		"issue": {"number": 42},
+		"issue": {"number": 42, "pull_request": {"url": "https://api.github.com/repos/owner/repo/pulls/42"}},
 		"comment": {
 			"id": 123,
 			"body": "Please fix this",
@@ -82,6 +120,9 @@ func TestNormalizeGitHubEvent_PRComment(t *testing.T) {
 	if event.CommentBody != "Please fix this" {
 		t.Errorf("CommentBody = %q, want %q", event.CommentBody, "Please fix this")
 	}
+	if event.IsIssue {
+		t.Error("IsIssue = true for a pull request comment")
+	}
 }
 
 func TestNormalizeGitHubEvent_Mention(t *testing.T) {
@@ -115,6 +156,10 @@ func TestNormalizeGitHubEvent_Mention(t *testing.T) {
 	if event.Type != TypeMention {
 		t.Errorf("Type = %q, want %q", event.Type, TypeMention)
 	}
+	// The issue has no pull_request, so it is a plain issue
+	if !event.IsIssue {
+		t.Error("IsIssue = false for a comment on an issue")
+	}
 }
 
 func TestNormalizeGitHubEvent_PRSynchronize(t *testing.T) {
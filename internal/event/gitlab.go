@@ -12,25 +12,29 @@ import (
 type gitLabPayload struct {
 	ObjectKind       string `json:"object_kind"`
 	ObjectAttributes struct {
-		IID          int    `json:"iid"`
-		ID           int    `json:"id"`
-		Title        string `json:"title"`
-		Description  string `json:"description"`
-		Note         string `json:"note"`
-		SourceBranch string `json:"source_branch"`
-		TargetBranch string `json:"target_branch"`
-		Action       string `json:"action"`
-		NoteableType string `json:"noteable_type"`
-		DiscussionID string `json:"discussion_id"`
-		Position     struct {
+		IID             int    `json:"iid"`
+		ID              int    `json:"id"`
+		Title           string `json:"title"`
+		Description     string `json:"description"`
+		Note            string `json:"note"`
+		SourceBranch    string `json:"source_branch"`
+		TargetBranch    string `json:"target_branch"`
+		SourceProjectID int    `json:"source_project_id"`
+		TargetProjectID int    `json:"target_project_id"`
+		Action          string `json:"action"`
+		NoteableType    string `json:"noteable_type"`
+		DiscussionID    string `json:"discussion_id"`
+		Position        struct {
 			NewPath string `json:"new_path"`
 			NewLine int    `json:"new_line"`
 		} `json:"position"`
 	} `json:"object_attributes"`
 	MergeRequest struct {
-		IID          int    `json:"iid"`
-		SourceBranch string `json:"source_branch"`
-		TargetBranch string `json:"target_branch"`
+		IID             int    `json:"iid"`
+		SourceBranch    string `json:"source_branch"`
+		TargetBranch    string `json:"target_branch"`
+		SourceProjectID int    `json:"source_project_id"`
+		TargetProjectID int    `json:"target_project_id"`
 	} `json:"merge_request"`
 	Project struct {
 		PathWithNamespace string `json:"path_with_namespace"`
@@ -54,13 +58,14 @@ func NormalizeGitLabEvent(glEvent *webhook.GitLabEvent) (*Event, error) {
 	}
 
 	event := &Event{
-		Provider:   "gitlab",
-		RepoOwner:  parts[0],
-		RepoName:   parts[1],
-		RepoURL:    payload.Project.GitHTTPURL,
-		Actor:      payload.User.Username,
-		Timestamp:  time.Now(),
-		RawPayload: glEvent.RawPayload,
+		Provider:      "gitlab",
+		RepoOwner:     parts[0],
+		RepoName:      parts[1],
+		RepoURL:       payload.Project.GitHTTPURL,
+		Actor:         payload.User.Username,
+		Timestamp:     time.Now(),
+		RawPayload:    glEvent.RawPayload,
+		CorrelationID: glEvent.CorrelationID,
 	}
 
 	switch payload.ObjectKind {
@@ -70,6 +75,7 @@ func NormalizeGitLabEvent(glEvent *webhook.GitLabEvent) (*Event, error) {
 		event.MRDescription = payload.ObjectAttributes.Description
 		event.SourceBranch = payload.ObjectAttributes.SourceBranch
 		event.TargetBranch = payload.ObjectAttributes.TargetBranch
+		event.FromFork = isFork(payload.ObjectAttributes.SourceProjectID, payload.ObjectAttributes.TargetProjectID)
 
 		switch payload.ObjectAttributes.Action {
 		case "open":
@@ -87,6 +93,7 @@ func NormalizeGitLabEvent(glEvent *webhook.GitLabEvent) (*Event, error) {
 		event.MRNumber = payload.MergeRequest.IID
 		event.SourceBranch = payload.MergeRequest.SourceBranch
 		event.TargetBranch = payload.MergeRequest.TargetBranch
+		event.FromFork = isFork(payload.MergeRequest.SourceProjectID, payload.MergeRequest.TargetProjectID)
 		event.CommentID = payload.ObjectAttributes.ID
 		event.CommentBody = payload.ObjectAttributes.Note
 		event.CommentAuthor = payload.User.Username
@@ -106,3 +113,8 @@ func NormalizeGitLabEvent(glEvent *webhook.GitLabEvent) (*Event, error) {
 
 	return event, nil
 }
+
+// isFork reports whether an MR's source project differs from its target.
+func isFork(sourceProjectID, targetProjectID int) bool {
+	return sourceProjectID != 0 && targetProjectID != 0 && sourceProjectID != targetProjectID
+}
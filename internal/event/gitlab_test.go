@@ -25,9 +25,10 @@ func TestNormalizeGitLabEvent_MROpened(t *testing.T) {
 	}`)
 
 	glEvent := &webhook.GitLabEvent{
-		EventType:  "Merge Request Hook",
-		ObjectKind: "merge_request",
-		RawPayload: raw,
+		EventType:     "Merge Request Hook",
+		ObjectKind:    "merge_request",
+		RawPayload:    raw,
+		CorrelationID: "abc123",
 	}
 
 	event, err := NormalizeGitLabEvent(glEvent)
@@ -47,6 +48,9 @@ func TestNormalizeGitLabEvent_MROpened(t *testing.T) {
 	if event.SourceBranch != "feature" {
 		t.Errorf("SourceBranch = %q, want %q", event.SourceBranch, "feature")
 	}
+	if event.CorrelationID != "abc123" {
+		t.Errorf("CorrelationID = %q, want %q", event.CorrelationID, "abc123")
+	}
 }
 
 func TestNormalizeGitLabEvent_MRUpdated(t *testing.T) {
@@ -128,6 +132,45 @@ func TestNormalizeGitLabEvent_Note(t *testing.T) {
 	}
 }
 
+func TestNormalizeGitLabEvent_FromFork(t *testing.T) {
+	raw := []byte(`{
+		"object_kind": "note",
+		"object_attributes": {"id": 123, "note": "Looks good", "noteable_type": "MergeRequest"},
+		"merge_request": {
+			"iid": 42,
+			"source_branch": "feature",
+			"target_branch": "main",
+			"source_project_id": 200,
+			"target_project_id": 100
+		},
+		"project": {"path_with_namespace": "owner/repo"}
+	}`)
+
+	event, err := NormalizeGitLabEvent(&webhook.GitLabEvent{ObjectKind: "note", RawPayload: raw})
+	if err != nil {
+		t.Fatalf("NormalizeGitLabEvent() error = %v", err)
+	}
+	if !event.FromFork {
+		t.Error("FromFork = false, want true when source and target projects differ")
+	}
+}
+
+func TestIsFork(t *testing.T) {
+	tests := []struct {
+		source, target int
+		want           bool
+	}{
+		{100, 100, false},
+		{200, 100, true},
+		{0, 100, false}, // Older payloads without project IDs
+	}
+	for _, tt := range tests {
+		if got := isFork(tt.source, tt.target); got != tt.want {
+			t.Errorf("isFork(%d, %d) = %v, want %v", tt.source, tt.target, got, tt.want)
+		}
+	}
+}
+
 func TestNormalizeGitLabEvent_NoteWithPosition(t *testing.T) {
 	raw := []byte(`{
 		"object_kind": "note",
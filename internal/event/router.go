@@ -2,13 +2,17 @@ package event
 
 import (
 	"context"
-	"log"
+	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/drewdunne/familiar/internal/config"
 	"github.com/drewdunne/familiar/internal/intent"
+	"github.com/drewdunne/familiar/internal/metrics"
+	"github.com/drewdunne/familiar/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // gitlabBotPattern matches GitLab project access token bot usernames.
@@ -41,7 +45,8 @@ type Handler func(ctx context.Context, event *Event, cfg *config.MergedConfig, i
 
 // Router routes events to handlers after config merging and validation.
 type Router struct {
-	serverCfg *config.Config
+	cfgMu     sync.RWMutex
+	serverCfg *config.Config // replaced by SetConfig
 	handler   Handler
 	debouncer *Debouncer
 	parser    intent.Parser
@@ -50,67 +55,125 @@ type Router struct {
 // NewRouter creates a new event router.
 // The parser parameter is optional and can be nil if intent parsing is not needed.
 func NewRouter(serverCfg *config.Config, handler Handler, parser intent.Parser) *Router {
-	debounceWindow := time.Duration(serverCfg.Agents.DebounceSeconds) * time.Second
-	if debounceWindow == 0 {
-		debounceWindow = 10 * time.Second // Default
-	}
 	return &Router{
 		serverCfg: serverCfg,
 		handler:   handler,
-		debouncer: NewDebouncer(debounceWindow),
+		debouncer: NewDebouncer(debounceWindow(serverCfg)),
 		parser:    parser,
 	}
 }
 
+// debounceWindow returns the configured debounce window.
+func debounceWindow(cfg *config.Config) time.Duration {
+	window := time.Duration(cfg.Agents.DebounceSeconds) * time.Second
+	if window == 0 {
+		window = 10 * time.Second // Default
+	}
+	return window
+}
+
 // Route processes an event through the routing pipeline.
 func (r *Router) Route(ctx context.Context, event *Event) error {
+	ctx, span := tracing.Start(ctx, "event.route",
+		attribute.String("familiar.provider", event.Provider),
+		attribute.String("familiar.repo", event.RepoOwner+"/"+event.RepoName),
+		attribute.Int("familiar.mr", event.MRNumber),
+		attribute.String("familiar.event_type", string(event.Type)),
+		attribute.String("familiar.correlation_id", event.CorrelationID),
+	)
+	err := r.route(ctx, event)
+	tracing.End(span, err)
+	return err
+}
+
+// SetConfig replaces the server config used for events routed from now on,
+// so bot detection, event toggles, prompts, and the debounce window can be
+// reloaded. Events already being handled keep the config they started with.
+func (r *Router) SetConfig(cfg *config.Config) {
+	r.cfgMu.Lock()
+	defer r.cfgMu.Unlock()
+	r.serverCfg = cfg
+	r.debouncer.SetWindow(debounceWindow(cfg))
+}
+
+// Debounced returns the events whose repeats are being dropped, as of now.
+func (r *Router) Debounced() []DebouncedEvent {
+	return r.debouncer.Pending(time.Now())
+}
+
+// config returns the current server config.
+func (r *Router) config() *config.Config {
+	r.cfgMu.RLock()
+	defer r.cfgMu.RUnlock()
+	return r.serverCfg
+}
+
+func (r *Router) route(ctx context.Context, event *Event) error {
+	serverCfg := r.config()
+
 	// Skip events from bot actors to prevent recursive loops
-	if isBotActor(event.Actor, r.serverCfg.BotUsername) {
-		log.Printf("Skipping event from bot actor: %s", event.Actor)
+	if isBotActor(event.Actor, serverCfg.BotUsername) {
+		event.Logger().Info("skipping event from bot actor", "actor", event.Actor)
 		return nil
 	}
 
 	// Check if event type is enabled at server level first
-	if !r.isEventEnabled(event.Type) {
-		log.Printf("Event type disabled: %s", event.Type)
+	if !isEventEnabled(serverCfg, event.Type) {
+		event.Logger().Info("event type disabled", "type", event.Type)
 		return nil
 	}
 
 	// Check debounce
 	if !r.debouncer.ShouldProcess(event) {
-		log.Printf("Event debounced: %s", event.Key())
+		event.Logger().Info("event debounced", "type", event.Type)
 		return nil
 	}
 
 	// TODO: Fetch repo config and merge
 	// For now, use server config only
-	merged := config.MergeConfigs(r.serverCfg, &config.RepoConfig{})
+	merged, err := config.MergeConfigs(serverCfg, &config.RepoConfig{})
+	if err != nil {
+		return fmt.Errorf("merging config: %w", err)
+	}
 
 	// Parse intent for comment-based events
 	var parsedIntent *intent.ParsedIntent
 	if r.parser != nil && (event.Type == TypeMRComment || event.Type == TypeMention) {
 		var err error
-		parsedIntent, err = r.parser.Parse(ctx, event.CommentBody)
+		start := time.Now()
+		parseCtx, span := tracing.Start(ctx, "intent.parse")
+		parsedIntent, err = r.parser.Parse(parseCtx, event.CommentBody)
+		tracing.End(span, err)
+		metrics.IntentParsed(time.Since(start))
 		if err != nil {
-			log.Printf("Failed to parse intent for event %s: %v", event.Key(), err)
+			event.Logger().Warn("failed to parse intent", "type", event.Type, "error", err)
 			// Continue without intent - we don't want to fail the event just because parsing failed
+		} else if parsedIntent != nil {
+			event.Logger().Info("parsed intent", "type", event.Type, "actions", parsedIntent.RequestedActions, "confidence", parsedIntent.Confidence)
 		}
 	}
 
+	event.Logger().Info("routing event", "type", event.Type)
+	metrics.EventRouted(metrics.Labels{
+		Provider:  event.Provider,
+		Repo:      event.RepoOwner + "/" + event.RepoName,
+		EventType: string(event.Type),
+	})
+
 	// Call handler
 	return r.handler(ctx, event, merged, parsedIntent)
 }
 
-func (r *Router) isEventEnabled(t Type) bool {
+func isEventEnabled(serverCfg *config.Config, t Type) bool {
 	switch t {
 	case TypeMROpened:
-		return r.serverCfg.Events.MROpened
+		return serverCfg.Events.MROpened
 	case TypeMRComment:
-		return r.serverCfg.Events.MRComment
+		return serverCfg.Events.MRComment
 	case TypeMRUpdated:
-		return r.serverCfg.Events.MRUpdated
+		return serverCfg.Events.MRUpdated
 	case TypeMention:
-		return r.serverCfg.Events.Mention
+		return serverCfg.Events.Mention
 	default:
 		return false
 	}
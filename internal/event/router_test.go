@@ -3,12 +3,17 @@ package event
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/drewdunne/familiar/internal/config"
 	"github.com/drewdunne/familiar/internal/intent"
+	"github.com/drewdunne/familiar/internal/metrics"
 )
 
 func TestRouter_Route(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+
 	var handledEvent *Event
 	handler := func(ctx context.Context, e *Event, cfg *config.MergedConfig, parsedIntent *intent.ParsedIntent) error {
 		handledEvent = e
@@ -42,6 +47,12 @@ func TestRouter_Route(t *testing.T) {
 	if handledEvent == nil {
 		t.Error("Handler was not called")
 	}
+
+	routed := metrics.Get().EventsRouted
+	want := metrics.Labels{Provider: "github", Repo: "owner/repo", EventType: "mr_opened"}
+	if len(routed) != 1 || routed[0].Labels != want || routed[0].Count != 1 {
+		t.Errorf("EventsRouted = %+v, want one %+v", routed, want)
+	}
 }
 
 func TestRouter_EventDisabled(t *testing.T) {
@@ -78,6 +89,31 @@ func TestRouter_EventDisabled(t *testing.T) {
 	}
 }
 
+func TestRouter_SetConfig(t *testing.T) {
+	calls := 0
+	handler := func(ctx context.Context, e *Event, cfg *config.MergedConfig, parsedIntent *intent.ParsedIntent) error {
+		calls++
+		return nil
+	}
+
+	router := NewRouter(&config.Config{Agents: config.AgentsConfig{DebounceSeconds: 1}}, handler, nil)
+	event := &Event{Type: TypeMROpened, Provider: "github", RepoOwner: "owner", RepoName: "repo", MRNumber: 42}
+
+	router.Route(context.Background(), event)
+	if calls != 0 {
+		t.Fatal("Handler should not be called while mr_opened is disabled")
+	}
+
+	router.SetConfig(&config.Config{Events: config.ServerEventsConfig{MROpened: true}, Agents: config.AgentsConfig{DebounceSeconds: 30}})
+	router.Route(context.Background(), event)
+	if calls != 1 {
+		t.Errorf("handler calls = %d after enabling mr_opened, want 1", calls)
+	}
+	if router.debouncer.window != 30*time.Second {
+		t.Errorf("debounce window = %v after reload, want 30s", router.debouncer.window)
+	}
+}
+
 func TestRouter_Debounce(t *testing.T) {
 	callCount := 0
 	handler := func(ctx context.Context, e *Event, cfg *config.MergedConfig, parsedIntent *intent.ParsedIntent) error {
@@ -0,0 +1,56 @@
+package event
+
+import (
+	"io"
+	"testing"
+
+	"github.com/drewdunne/familiar/internal/webhook"
+)
+
+func TestSampleRequests_Normalize(t *testing.T) {
+	opts := webhook.SampleOptions{Repo: "owner/repo", MR: 7, Comment: "fix the build", File: "main.go", Line: 12}
+	tests := []struct {
+		provider string
+		kind     string
+		want     Type
+	}{
+		{"github", webhook.SampleMROpened, TypeMROpened},
+		{"github", webhook.SampleNote, TypeMRComment},
+		{"github", webhook.SampleMention, TypeMention},
+		{"gitlab", webhook.SampleMROpened, TypeMROpened},
+		{"gitlab", webhook.SampleNote, TypeMRComment},
+		{"gitlab", webhook.SampleMention, TypeMention},
+		{"gitlab", webhook.SampleLineComment, TypeMention},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider+"/"+tt.kind, func(t *testing.T) {
+			req, err := webhook.SampleRequest(tt.provider, tt.kind, "secret", "http://familiar", opts)
+			if err != nil {
+				t.Fatalf("SampleRequest() error = %v", err)
+			}
+			raw, err := io.ReadAll(req.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var event *Event
+			if tt.provider == "github" {
+				event, err = NormalizeGitHubEvent(&webhook.GitHubEvent{EventType: req.Header.Get("X-GitHub-Event"), RawPayload: raw})
+			} else {
+				event, err = NormalizeGitLabEvent(&webhook.GitLabEvent{EventType: req.Header.Get("X-Gitlab-Event"), RawPayload: raw})
+			}
+			if err != nil {
+				t.Fatalf("normalizing error = %v", err)
+			}
+			if event.Type != tt.want {
+				t.Errorf("Type = %q, want %q", event.Type, tt.want)
+			}
+			if event.RepoOwner != "owner" || event.RepoName != "repo" || event.MRNumber != 7 {
+				t.Errorf("event = %s/%s!%d, want owner/repo!7", event.RepoOwner, event.RepoName, event.MRNumber)
+			}
+			if tt.kind == webhook.SampleLineComment && (event.CommentFilePath != "main.go" || event.CommentLine != 12) {
+				t.Errorf("comment position = %s:%d, want main.go:12", event.CommentFilePath, event.CommentLine)
+			}
+		})
+	}
+}
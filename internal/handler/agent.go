@@ -1,65 +1,246 @@
 package handler
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/drewdunne/familiar/internal/agent"
 	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/docker"
 	"github.com/drewdunne/familiar/internal/event"
+	"github.com/drewdunne/familiar/internal/hooks"
 	"github.com/drewdunne/familiar/internal/intent"
 	"github.com/drewdunne/familiar/internal/lca"
 	"github.com/drewdunne/familiar/internal/logging"
+	"github.com/drewdunne/familiar/internal/logship"
+	"github.com/drewdunne/familiar/internal/metrics"
 	"github.com/drewdunne/familiar/internal/prompt"
 	"github.com/drewdunne/familiar/internal/provider"
+	"github.com/drewdunne/familiar/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // AgentSpawner spawns agent containers.
 type AgentSpawner interface {
 	Spawn(ctx context.Context, req agent.SpawnRequest) (*agent.Session, error)
+	Wait(ctx context.Context, sessionID string) error
+	Stop(ctx context.Context, sessionID string) error
+	CaptureAndStop(ctx context.Context, sessionID string, logPath string) error
+	Terminate(ctx context.Context, sessionID, reason string) error
+	Image() string
+}
+
+// AgentQueue schedules spawns under the configured concurrency limits.
+type AgentQueue interface {
+	Enqueue(req agent.SpawnRequest, spawnFn agent.SpawnFunc) error
 }
 
 // RepoCache manages repository clones and worktrees.
 type RepoCache interface {
-	EnsureRepo(ctx context.Context, cloneURL, owner, repo string) (string, error)
+	SetCredentials(owner, repo, username, password string)
+	EnsureRepo(ctx context.Context, cloneURL, owner, repo string, refs ...string) (string, error)
 	CreateWorktree(ctx context.Context, owner, repo, ref, worktreeID string) (string, error)
 	RemoveWorktree(ctx context.Context, owner, repo, worktreeID string) error
 	HostPath(containerPath string) string
 }
 
+// PatchRepo commits and pushes an agent's changes on the server side.
+// Needed for patch mode; implemented by the repo cache.
+type PatchRepo interface {
+	CommitChanges(ctx context.Context, owner, repo, worktreeID, base, message string) (int, error)
+	PushChanges(ctx context.Context, owner, repo, worktreeID, branch string) error
+	FormatPatch(ctx context.Context, owner, repo, worktreeID, base string) (string, error)
+}
+
+// ChangeLister lists the files an agent changed in its worktree since base,
+// for its summary comment. Optional; implemented by the repo cache.
+type ChangeLister interface {
+	ChangedFiles(ctx context.Context, owner, repo, worktreeID, base string) ([]string, error)
+}
+
+// RemoteChangeLister lists the files changed since base on a branch as the
+// remote has it, for the protected paths check. Optional; implemented by the
+// repo cache.
+type RemoteChangeLister interface {
+	RemoteChangedFiles(ctx context.Context, owner, repo, worktreeID, base, branch string) ([]string, error)
+}
+
 // ProviderRegistry looks up configured providers by name.
 type ProviderRegistry interface {
 	Get(name string) provider.Provider
 }
 
+// CommentReactor reacts to a comment on a merge request. Implemented by
+// providers that support reactions; review marks a comment on the diff.
+type CommentReactor interface {
+	ReactToComment(ctx context.Context, owner, repo string, number, commentID int, review bool) error
+}
+
+// LogShipper forwards log lines to external sinks.
+type LogShipper interface {
+	ShipReader(ctx context.Context, source string, labels map[string]string, r io.Reader) error
+}
+
 // AgentHandler handles events by spawning agents.
 type AgentHandler struct {
 	spawner       AgentSpawner
 	repoCache     RepoCache
 	registry      ProviderRegistry
+	queue         AgentQueue    // optional; spawns run inline when nil
+	hooks         *hooks.Runner // optional pre/post-agent hook commands
 	promptBuilder *prompt.Builder
 	logWriter     *logging.Writer
-	logDir        string // container path for creating log files
-	logHostDir    string // host path for display in log messages
+	logDir        string            // container path for creating log files
+	logHostDir    string            // host path for display in log messages
+	logShipper    LogShipper        // optional; forwards finished agent logs
+	logIndex      *logging.Index    // optional; records each agent log and its outcome
+	audit         *logging.AuditLog // optional; records privileged grants and actions
+	dryRun        bool              // log agents instead of starting them
+	acknowledge   string            // how trigger comments are acknowledged; empty for none
+	summary       string            // when finished agents' summaries are posted; empty for always
+	busyPolicy    string            // what an event for an MR with running agents does; empty for allow
+	credentials   string            // which provider credentials agents get; empty for full
+
+	runs   map[string]*agentRun // in-flight agents by ID
+	runsMu sync.Mutex
+	mrs    *mrRegistry
 }
 
-// NewAgentHandler creates a new agent handler.
-func NewAgentHandler(spawner AgentSpawner, repoCache RepoCache, reg ProviderRegistry, logDir, logHostDir string) *AgentHandler {
-	var logWriter *logging.Writer
-	if logDir != "" {
-		logWriter = logging.NewWriter(logDir)
+// agentRun tracks what the handler needs to clean up after an agent.
+type agentRun struct {
+	evt          *event.Event
+	logPath      string               // container path; empty if no log file was created
+	worktreePath string               // container path, where hooks run
+	base         string               // ref the worktree was created from
+	patch        bool                 // server commits the agent's changes (patch mode)
+	pushAllowed  bool                 // in patch mode, whether the server may push them
+	group        *personaGroup        // set when the agent runs as one of several personas
+	grants       []logging.AuditEntry // audit entries for its privileged permissions
+	protected    []string             // path patterns it must not change
+}
+
+// maxPatchComment bounds how much of a proposed patch is posted on the MR.
+const maxPatchComment = 60000
+
+// interactiveCommand in a comment asks for an interactive session.
+const interactiveCommand = "/interactive"
+
+// Option configures the agent handler.
+type Option func(*AgentHandler)
+
+// WithQueue routes spawns through the given queue instead of spawning inline.
+// Queued spawns hold their slot until the agent session ends.
+func WithQueue(q AgentQueue) Option {
+	return func(h *AgentHandler) {
+		h.queue = q
+	}
+}
+
+// WithHooks runs the given hook commands in the worktree before each agent
+// starts and after it finishes.
+func WithHooks(r *hooks.Runner) Option {
+	return func(h *AgentHandler) {
+		h.hooks = r
+	}
+}
+
+// WithLogShipper forwards each agent's log to external sinks once the agent
+// finishes.
+func WithLogShipper(s LogShipper) Option {
+	return func(h *AgentHandler) {
+		h.logShipper = s
+	}
+}
+
+// WithLogIndex records each agent log, and how its run ended, in idx.
+func WithLogIndex(idx *logging.Index) Option {
+	return func(h *AgentHandler) {
+		h.logIndex = idx
+	}
+}
+
+// WithAuditLog records the privileged permissions each agent is granted in
+// log, and whether it used them where that can be told.
+func WithAuditLog(log *logging.AuditLog) Option {
+	return func(h *AgentHandler) {
+		h.audit = log
+	}
+}
+
+// WithDryRun logs the agent each event would start, with its prompt,
+// instead of creating its worktree and container.
+func WithDryRun() Option {
+	return func(h *AgentHandler) {
+		h.dryRun = true
+	}
+}
+
+// WithAcknowledge acknowledges comments and mentions that start agents, as
+// agents.acknowledge describes, so the commenter gets feedback before the
+// agents reply.
+func WithAcknowledge(mode string) Option {
+	return func(h *AgentHandler) {
+		h.acknowledge = mode
 	}
-	return &AgentHandler{
+}
+
+// WithSummary limits which finished agents get a summary comment, as
+// agents.summary describes. Without it every agent gets one.
+func WithSummary(mode string) Option {
+	return func(h *AgentHandler) {
+		h.summary = mode
+	}
+}
+
+// WithCredentials limits which provider credentials agents get, as
+// agents.credentials describes. Without it every agent gets the full token.
+func WithCredentials(mode string) Option {
+	return func(h *AgentHandler) {
+		h.credentials = mode
+	}
+}
+
+// WithBusyPolicy has events for an MR whose agents are still running wait,
+// be dropped, or stop those agents, as agents.busy_policy describes.
+// Without it every event starts agents right away.
+func WithBusyPolicy(policy string) Option {
+	return func(h *AgentHandler) {
+		h.busyPolicy = policy
+	}
+}
+
+// NewAgentHandler creates a new agent handler.
+func NewAgentHandler(spawner AgentSpawner, repoCache RepoCache, reg ProviderRegistry, logDir, logHostDir string, opts ...Option) *AgentHandler {
+	h := &AgentHandler{
 		spawner:       spawner,
 		repoCache:     repoCache,
 		registry:      reg,
 		promptBuilder: prompt.NewBuilder(),
-		logWriter:     logWriter,
 		logDir:        logDir,
 		logHostDir:    logHostDir,
+		runs:          make(map[string]*agentRun),
+		mrs:           newMRRegistry(),
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if logDir != "" {
+		var writerOpts []logging.WriterOption
+		if h.logIndex != nil {
+			writerOpts = append(writerOpts, logging.WithIndex(h.logIndex))
+		}
+		h.logWriter = logging.NewWriter(logDir, writerOpts...)
+	}
+	return h
 }
 
 // hostLogPath converts a container log path to a host display path.
@@ -72,40 +253,89 @@ func (h *AgentHandler) hostLogPath(containerPath string) string {
 	return containerPath
 }
 
-// Handle processes an event by spawning an agent.
+// Handle processes an event by spawning an agent, or one agent per persona
+// when personas apply to the event. If the MR already has agents running,
+// the busy policy decides whether the event waits, is dropped, or stops
+// them.
 func (h *AgentHandler) Handle(ctx context.Context, evt *event.Event, cfg *config.MergedConfig, parsedIntent *intent.ParsedIntent) error {
+	if h.busyPolicy == "" || h.busyPolicy == config.BusyAllow || h.dryRun {
+		return h.handle(ctx, evt, cfg, parsedIntent)
+	}
+
+	key := evt.MRKey()
+	ok, running := h.mrs.begin(key, h.busyPolicy, pendingEvent{evt: evt, cfg: cfg, parsedIntent: parsedIntent})
+	if !ok {
+		action := "skipped"
+		if h.busyPolicy == config.BusyQueue {
+			action = "queued"
+		}
+		evt.Logger().Info("agents already running for MR; event "+action, "event_type", evt.Type, "agents", running)
+		return nil
+	}
+	h.supersede(ctx, evt, running)
+
+	err := h.handle(ctx, evt, cfg, parsedIntent)
+	h.dispatch(h.mrs.end(key))
+	return err
+}
+
+// handle starts the event's agents.
+func (h *AgentHandler) handle(ctx context.Context, evt *event.Event, cfg *config.MergedConfig, parsedIntent *intent.ParsedIntent) error {
 	// Generate unique agent ID
 	agentID := fmt.Sprintf("%s-%s-%d-%d", evt.Provider, evt.RepoName, evt.MRNumber, evt.Timestamp.Unix())
 
-	// Get authenticated clone URL from provider
-	cloneURL := evt.RepoURL
+	// Authenticate the cache's git operations with the provider's token
 	prov := h.registry.Get(evt.Provider)
 	if prov != nil {
-		authURL, err := prov.AuthenticatedCloneURL(evt.RepoURL)
-		if err != nil {
-			log.Printf("warning: failed to get authenticated URL, using raw URL: %v", err)
-		} else {
-			cloneURL = authURL
-		}
+		username, password := prov.GitCredentials()
+		h.repoCache.SetCredentials(evt.RepoOwner, evt.RepoName, username, password)
 	}
 
-	// Ensure repo is cached and create worktree
-	_, err := h.repoCache.EnsureRepo(ctx, cloneURL, evt.RepoOwner, evt.RepoName)
+	evt, err := resolveBranch(ctx, evt, prov)
 	if err != nil {
-		return fmt.Errorf("ensuring repo: %w", err)
+		return err
+	}
+
+	// A fork's branch is not in this repo and can't be pushed to, so work
+	// from the MR head ref and have the server propose the changes
+	ref := evt.SourceBranch
+	if evt.DefaultBranch != "" {
+		ref = evt.DefaultBranch
+	}
+	if evt.FromFork {
+		if prov == nil {
+			return fmt.Errorf("no %s provider to find the head of fork MR #%d", evt.Provider, evt.MRNumber)
+		}
+		ref = prov.MRHeadRef(evt.MRNumber)
+		cfg = withPatchMode(cfg)
 	}
 
-	worktreePath, err := h.repoCache.CreateWorktree(ctx, evt.RepoOwner, evt.RepoName, evt.SourceBranch, agentID)
+	// Agents that may neither push nor merge get credentials that can't push
+	// or merge, or none at all
+	readOnlyEnv, cfg := h.scopeCredentials(evt, prov, cfg, parsedIntent)
+
+	patchMode := cfg != nil && cfg.AgentMode == config.AgentModePatch
+	if _, ok := h.repoCache.(PatchRepo); patchMode && !ok {
+		return fmt.Errorf("patch mode is not supported by the repo cache")
+	}
+
+	// Ensure repo is cached, fetching only the refs the event needs
+	start := time.Now()
+	ensureCtx, span := tracing.Start(ctx, "repo.ensure")
+	_, err = h.repoCache.EnsureRepo(ensureCtx, evt.RepoURL, evt.RepoOwner, evt.RepoName, eventRefs(evt, prov)...)
+	tracing.End(span, err)
+	metrics.RepoEnsured(time.Since(start))
 	if err != nil {
-		return fmt.Errorf("creating worktree: %w", err)
+		metrics.AgentFailed(metrics.FailureCloneError)
+		return fmt.Errorf("ensuring repo: %w", err)
 	}
 
 	// Get changed files and calculate LCA for working directory
 	workDir := "/workspace"
-	if prov != nil {
+	if prov != nil && evt.DefaultBranch == "" {
 		changedFiles, err := prov.GetChangedFiles(ctx, evt.RepoOwner, evt.RepoName, evt.MRNumber)
 		if err != nil {
-			log.Printf("warning: failed to get changed files: %v", err)
+			evt.Logger().Warn("failed to get changed files", "error", err)
 		} else if len(changedFiles) > 0 {
 			// Extract file paths
 			filePaths := make([]string, len(changedFiles))
@@ -120,55 +350,692 @@ func (h *AgentHandler) Handle(ctx context.Context, evt *event.Event, cfg *config
 		}
 	}
 
-	// Collect provider environment variables for the agent container
+	// Collect provider environment variables for the agent container.
+	// Patch-mode agents get no provider credentials at all.
 	var spawnEnv map[string]string
-	if prov != nil {
+	switch {
+	case readOnlyEnv != nil:
+		spawnEnv = readOnlyEnv
+	case prov != nil && !patchMode:
 		spawnEnv = prov.AgentEnv()
 	}
 
+	l := &launch{
+		evt:          evt,
+		cfg:          cfg,
+		parsedIntent: parsedIntent,
+		workDir:      workDir,
+		env:          spawnEnv,
+		ref:          ref,
+		patch:        patchMode,
+	}
+
+	var personas []config.PersonaConfig
+	if cfg != nil {
+		personas = cfg.PersonasFor(string(evt.Type))
+	}
+	if len(personas) == 0 {
+		if err := h.start(ctx, l, agentID, nil, nil); err != nil {
+			return err
+		}
+		h.acknowledgeTrigger(ctx, evt, []string{agentID})
+		return nil
+	}
+
+	// Register every persona before starting any, so an early finisher
+	// can't report the group as complete.
+	group := newPersonaGroup(evt)
+	ids := make([]string, len(personas))
+	for i, p := range personas {
+		ids[i] = fmt.Sprintf("%s-%s", agentID, personaSlug(p.Name, i))
+		group.add(ids[i], p.Name)
+	}
+
+	var errs []error
+	var started []string
+	for i := range personas {
+		if err := h.start(ctx, l, ids[i], &personas[i], group); err != nil {
+			errs = append(errs, fmt.Errorf("persona %s: %w", personas[i].Name, err))
+			continue
+		}
+		started = append(started, ids[i])
+	}
+	h.acknowledgeTrigger(ctx, evt, started)
+	return errors.Join(errs...)
+}
+
+// acknowledgeTrigger lets whoever started agents with a comment know it was
+// heard: with a reaction to the comment, or a reply naming the agents.
+func (h *AgentHandler) acknowledgeTrigger(ctx context.Context, evt *event.Event, agentIDs []string) {
+	if h.acknowledge == "" || h.acknowledge == config.AcknowledgeNone || h.dryRun || len(agentIDs) == 0 {
+		return
+	}
+	if evt.Type != event.TypeMRComment && evt.Type != event.TypeMention {
+		return
+	}
+	prov := h.registry.Get(evt.Provider)
+	if prov == nil {
+		return
+	}
+
+	if reactor, ok := prov.(CommentReactor); ok && h.acknowledge == config.AcknowledgeReaction && evt.CommentID != 0 {
+		err := reactor.ReactToComment(ctx, evt.RepoOwner, evt.RepoName, evt.MRNumber, evt.CommentID, evt.CommentFilePath != "")
+		if err == nil {
+			return
+		}
+		evt.Logger().Warn("failed to react to comment, replying instead", "comment_id", evt.CommentID, "error", err)
+	}
+
+	body := fmt.Sprintf("🪄 On it — agent `%s` is starting.", agentIDs[0])
+	if len(agentIDs) > 1 {
+		body = fmt.Sprintf("🪄 On it — %d agents are starting: `%s`.", len(agentIDs), strings.Join(agentIDs, "`, `"))
+	}
+	if err := prov.PostComment(ctx, evt.RepoOwner, evt.RepoName, evt.MRNumber, body); err != nil {
+		evt.Logger().Warn("failed to acknowledge comment", "error", err)
+	}
+}
+
+// launch holds what every agent started for one event shares.
+type launch struct {
+	evt          *event.Event
+	cfg          *config.MergedConfig
+	parsedIntent *intent.ParsedIntent
+	workDir      string
+	env          map[string]string
+	ref          string // ref to create worktrees from
+	patch        bool
+}
+
+// start creates the agent's worktree and spawns it, inline or through the
+// queue. Agents in a persona group report failures to start to the group.
+func (h *AgentHandler) start(ctx context.Context, l *launch, agentID string, persona *config.PersonaConfig, group *personaGroup) error {
+	evt := l.evt
+	fail := func(err error) error {
+		if group != nil {
+			h.personaDone(ctx, group, agentID, "", err)
+		}
+		h.dispatch(h.mrs.done(evt.MRKey(), agentID))
+		return err
+	}
+
+	if h.dryRun {
+		h.logDryRun(l, agentID, persona)
+		return nil
+	}
+
+	start := time.Now()
+	worktreeCtx, span := tracing.Start(ctx, "worktree.create", attribute.String("familiar.agent_id", agentID))
+	worktreePath, err := h.repoCache.CreateWorktree(worktreeCtx, evt.RepoOwner, evt.RepoName, l.ref, agentID)
+	tracing.End(span, err)
+	metrics.WorktreeCreated(time.Since(start))
+	if err != nil {
+		metrics.AgentFailed(metrics.FailureCloneError)
+		return fail(fmt.Errorf("creating worktree: %w", err))
+	}
+
 	// Build prompt using the prompt builder
-	agentPrompt := h.promptBuilder.Build(evt, cfg, parsedIntent)
-
-	// Spawn agent - use host path for Docker bind mount
-	hostWorktreePath := h.repoCache.HostPath(worktreePath)
-	_, err = h.spawner.Spawn(ctx, agent.SpawnRequest{
-		ID:           agentID,
-		WorktreePath: hostWorktreePath,
-		WorkDir:      workDir,
-		Prompt:       agentPrompt,
-		Env:          spawnEnv,
+	agentPrompt := h.promptBuilder.BuildForPersona(evt, l.cfg, l.parsedIntent, persona)
+
+	// Spawn agent - use host path for Docker bind mount. Persona agents
+	// report through their final response, so they never run interactively.
+	req := agent.SpawnRequest{
+		ID:            agentID,
+		Repo:          evt.RepoOwner + "/" + evt.RepoName,
+		MRNumber:      evt.MRNumber,
+		EventType:     string(evt.Type),
+		WorktreePath:  h.repoCache.HostPath(worktreePath),
+		WorkDir:       l.workDir,
+		Prompt:        agentPrompt,
+		Env:           l.env,
+		Interactive:   persona == nil && wantsInteractive(evt, l.cfg),
+		ReadOnlyGit:   l.patch,
+		CorrelationID: evt.CorrelationID,
+	}
+	if persona != nil {
+		req.Persona = persona.Name
+	}
+	if l.cfg != nil {
+		req.Bootstrap = l.cfg.Bootstrap
+	}
+
+	run := &agentRun{
+		evt:          evt,
+		worktreePath: worktreePath,
+		base:         l.ref,
+		patch:        l.patch,
+		pushAllowed:  l.patch && !evt.FromFork && evt.SourceBranch != "" && prompt.PushAllowed(evt, l.cfg, l.parsedIntent),
+		group:        group,
+	}
+	if l.cfg != nil {
+		run.protected = l.cfg.ProtectedPaths
+	}
+	run.grants = h.auditGrants(l, agentID, run)
+	h.mrs.add(evt.MRKey(), agentID)
+
+	if h.queue == nil {
+		if err := h.spawn(ctx, req, run); err != nil {
+			return fail(err)
+		}
+		return nil
+	}
+
+	// The queued spawn runs on the queue's context; carry the trace over
+	parent := ctx
+	_, waitSpan := tracing.Start(ctx, "agent.queue", attribute.String("familiar.agent_id", agentID))
+	err = h.queue.Enqueue(req, func(ctx context.Context, req agent.SpawnRequest) error {
+		if err := context.Cause(ctx); errors.Is(err, agent.ErrDrained) {
+			tracing.End(waitSpan, err)
+			evt.Logger().Info("dropped queued agent", "agent_id", req.ID, "reason", err)
+			h.removeWorktree(parent, evt, req.ID)
+			return fail(err)
+		}
+		if !h.mrs.has(evt.MRKey(), req.ID) {
+			tracing.End(waitSpan, errSuperseded)
+			evt.Logger().Info("dropped queued agent", "agent_id", req.ID, "reason", errSuperseded)
+			h.removeWorktree(parent, evt, req.ID)
+			return fail(errSuperseded)
+		}
+		waitSpan.End()
+		ctx = tracing.WithParent(ctx, parent)
+		if err := h.spawn(ctx, req, run); err != nil {
+			evt.Logger().Error("failed to spawn queued agent", "agent_id", req.ID, "error", err)
+			return fail(err)
+		}
+		// Hold the queue slot until the agent session ends
+		return h.spawner.Wait(ctx, req.ID)
 	})
 	if err != nil {
-		// Cleanup worktree on failure
-		if cleanupErr := h.repoCache.RemoveWorktree(ctx, evt.RepoOwner, evt.RepoName, agentID); cleanupErr != nil {
-			log.Printf("warning: failed to cleanup worktree %s: %v", agentID, cleanupErr)
+		if errors.Is(err, agent.ErrQueueFull) {
+			metrics.AgentFailed(metrics.FailureQueueFull)
 		}
-		return fmt.Errorf("spawning agent: %w", err)
+		tracing.End(waitSpan, err)
+		h.removeWorktree(ctx, evt, agentID)
+		return fail(fmt.Errorf("queueing agent: %w", err))
 	}
 
-	// Create log file so the path exists when printed
-	var displayPath string
+	evt.Logger().Info("queued agent", "agent_id", agentID)
+	return nil
+}
+
+// logDryRun logs the agent start would have spawned.
+func (h *AgentHandler) logDryRun(l *launch, agentID string, persona *config.PersonaConfig) {
+	agentPrompt := h.promptBuilder.BuildForPersona(l.evt, l.cfg, l.parsedIntent, persona)
+	logger := l.evt.Logger().With("agent_id", agentID)
+	if persona != nil {
+		logger = logger.With("persona", persona.Name)
+	}
+	mode := config.AgentModeDirect
+	if l.patch {
+		mode = config.AgentModePatch
+	}
+	logger.Info("dry run: would start agent",
+		"ref", l.ref,
+		"work_dir", l.workDir,
+		"mode", mode,
+		"interactive", persona == nil && wantsInteractive(l.evt, l.cfg),
+		"prompt_bytes", len(agentPrompt))
+	logger.Debug("dry run: agent prompt", "prompt", agentPrompt)
+}
+
+// spawn creates the agent's log file, runs pre-agent hooks, and starts the
+// agent container. The worktree is removed if a hook or the spawn fails.
+func (h *AgentHandler) spawn(ctx context.Context, req agent.SpawnRequest, run *agentRun) (err error) {
+	ctx, span := tracing.Start(ctx, "agent.spawn", attribute.String("familiar.agent_id", req.ID))
+	defer func() { tracing.End(span, err) }()
+
+	agentID := req.ID
+	evt := run.evt
+
+	// Create log file first so hook output has somewhere to go
+	var logPath, displayPath string
 	if h.logWriter != nil {
-		logPath, err := h.logWriter.Create(logging.LogEntry{
-			AgentID:   agentID,
-			RepoOwner: evt.RepoOwner,
-			RepoName:  evt.RepoName,
-			MRNumber:  evt.MRNumber,
-			EventType: string(evt.Type),
-			Timestamp: evt.Timestamp,
+		path, err := h.logWriter.Create(logging.LogEntry{
+			AgentID:       agentID,
+			RepoOwner:     evt.RepoOwner,
+			RepoName:      evt.RepoName,
+			MRNumber:      evt.MRNumber,
+			EventType:     string(evt.Type),
+			Timestamp:     evt.Timestamp,
+			CorrelationID: evt.CorrelationID,
+			Prompt:        req.Prompt,
+			Image:         h.spawner.Image(),
 		})
 		if err != nil {
-			log.Printf("warning: failed to create log file: %v", err)
+			evt.Logger().Warn("failed to create log file", "agent_id", agentID, "error", err)
 		} else {
-			displayPath = h.hostLogPath(logPath)
+			logPath = path
+			displayPath = h.hostLogPath(path)
 		}
 	}
 
+	run.logPath = logPath
+	if err := h.runHooks(ctx, hooks.PhasePreAgent, agentID, run); err != nil {
+		h.removeWorktree(ctx, evt, agentID)
+		return err
+	}
+
+	if _, err := h.spawner.Spawn(ctx, req); err != nil {
+		metrics.AgentFailed(metrics.FailureSpawnError)
+		h.removeWorktree(ctx, evt, agentID)
+		return fmt.Errorf("spawning agent: %w", err)
+	}
+
+	h.runsMu.Lock()
+	h.runs[agentID] = run
+	h.runsMu.Unlock()
+	for _, e := range run.grants {
+		h.recordAudit(run, e)
+	}
+
 	containerName := "familiar-agent-" + agentID
-	log.Printf("Spawned agent %s for %s/%s MR #%d (workDir: %s)", agentID, evt.RepoOwner, evt.RepoName, evt.MRNumber, workDir)
-	if displayPath != "" {
-		log.Printf("  Container logs: %s", displayPath)
+	evt.Logger().Info("spawned agent", "agent_id", agentID, "work_dir", req.WorkDir, "log", displayPath,
+		"attach", "docker exec -it "+containerName+" tmux attach-session -t claude")
+
+	if req.Interactive {
+		h.postAttachInstructions(ctx, evt, agentID, containerName)
 	}
-	log.Printf("  Live LLM session: docker exec -it %s tmux attach-session -t claude", containerName)
 	return nil
 }
+
+// wantsInteractive reports whether the event should spawn an interactive
+// session, either by config for its event type or by request in the comment.
+func wantsInteractive(evt *event.Event, cfg *config.MergedConfig) bool {
+	if cfg != nil && cfg.IsInteractive(string(evt.Type)) {
+		return true
+	}
+	for _, field := range strings.Fields(evt.CommentBody) {
+		if field == interactiveCommand {
+			return true
+		}
+	}
+	return false
+}
+
+// postAttachInstructions tells the MR how to take over an interactive session.
+func (h *AgentHandler) postAttachInstructions(ctx context.Context, evt *event.Event, agentID, containerName string) {
+	prov := h.registry.Get(evt.Provider)
+	if prov == nil {
+		return
+	}
+	body := fmt.Sprintf("🧑‍💻 Familiar agent `%s` is running interactively. To take over, run this on the Familiar host:\n\n"+
+		"```\ndocker exec -it %s tmux attach-session -t claude\n```\n\n"+
+		"Detach with `Ctrl-b d`. The session ends when Claude exits.", agentID, containerName)
+	if err := prov.PostComment(ctx, evt.RepoOwner, evt.RepoName, evt.MRNumber, body); err != nil {
+		evt.Logger().Warn("failed to post attach instructions", "agent_id", agentID, "error", err)
+	}
+}
+
+// runHooks runs the phase's hook commands in the agent's worktree, appending
+// their output to the agent log. Failures are reported on the MR.
+func (h *AgentHandler) runHooks(ctx context.Context, phase hooks.Phase, agentID string, run *agentRun) error {
+	if len(h.hooks.Commands(phase)) == 0 {
+		return nil
+	}
+
+	env := map[string]string{
+		"FAMILIAR_AGENT_ID": agentID,
+		"FAMILIAR_REPO":     run.evt.RepoOwner + "/" + run.evt.RepoName,
+		"FAMILIAR_MR":       fmt.Sprint(run.evt.MRNumber),
+		"FAMILIAR_BRANCH":   run.evt.SourceBranch,
+	}
+	var output bytes.Buffer
+	hookErr := h.hooks.Run(ctx, phase, run.worktreePath, env, &output)
+
+	if run.logPath != "" {
+		if err := h.logWriter.Append(run.logPath, output.Bytes()); err != nil {
+			run.evt.Logger().Warn("failed to write hook output", "agent_id", agentID, "phase", phase, "error", err)
+		}
+	}
+	if hookErr == nil {
+		return nil
+	}
+
+	run.evt.Logger().Warn("hook failed", "agent_id", agentID, "phase", phase, "error", hookErr)
+	body := fmt.Sprintf("⚠️ Familiar %s hook failed for agent `%s`: %v", phase, agentID, hookErr)
+	if phase == hooks.PhasePreAgent {
+		body += "\n\nThe agent was not started."
+	}
+	h.postComment(ctx, run.evt, agentID, body)
+	return hookErr
+}
+
+// postComment posts a comment on the event's MR, logging any failure.
+func (h *AgentHandler) postComment(ctx context.Context, evt *event.Event, agentID, body string) {
+	prov := h.registry.Get(evt.Provider)
+	if prov == nil {
+		return
+	}
+	if err := prov.PostComment(ctx, evt.RepoOwner, evt.RepoName, evt.MRNumber, body); err != nil {
+		evt.Logger().Warn("failed to post comment", "agent_id", agentID, "error", err)
+	}
+}
+
+// removeWorktree removes the agent's worktree, logging any failure.
+func (h *AgentHandler) removeWorktree(ctx context.Context, evt *event.Event, agentID string) {
+	if err := h.repoCache.RemoveWorktree(ctx, evt.RepoOwner, evt.RepoName, agentID); err != nil {
+		evt.Logger().Warn("failed to cleanup worktree", "agent_id", agentID, "error", err)
+	}
+}
+
+// HandleTimeout cleans up after an agent that exceeded its timeout: it
+// captures the container logs, stops the container, removes the worktree,
+// and explains what happened on the MR. Intended as the spawner's OnTimeout.
+func (h *AgentHandler) HandleTimeout(session *agent.Session) {
+	ctx := context.Background()
+
+	metrics.AgentTimedOut()
+	elapsed := time.Since(session.StartedAt).Round(time.Minute)
+	slog.Warn("agent timed out", "agent_id", session.ID, "elapsed", elapsed)
+
+	run, ok := h.finish(ctx, session)
+	if !ok {
+		return
+	}
+	h.recordOutcome(run, session.ID, logging.OutcomeTimedOut, "", metrics.FailureTimeout)
+	h.auditMerge(ctx, session.ID, run)
+	if !run.patch {
+		h.checkProtected(ctx, session.ID, run)
+	}
+	if run.group == nil {
+		h.postSummary(ctx, session, run, completion{
+			emoji:     "⏱️",
+			outcome:   fmt.Sprintf("timed out after %s and was stopped before finishing", elapsed),
+			failed:    true,
+			discarded: true,
+		})
+	}
+	h.releaseWorktree(ctx, session.ID, run)
+
+	if run.group != nil {
+		h.personaDone(ctx, run.group, session.ID, "", fmt.Errorf("timed out after %s", elapsed))
+	}
+	h.dispatch(h.mrs.done(run.evt.MRKey(), session.ID))
+}
+
+// HandleExit cleans up after an agent whose container exited on its own: it
+// captures the logs, runs post-agent hooks, fails the run if it changed a
+// protected path, commits the agent's changes in patch mode, summarizes the
+// run on the MR, and removes the worktree.
+// Intended as the spawner's OnExit.
+func (h *AgentHandler) HandleExit(session *agent.Session) {
+	ctx := context.Background()
+
+	run, ok := h.finish(ctx, session)
+	if !ok {
+		return
+	}
+
+	// A run that changed a protected path fails, and in patch mode nothing
+	// is committed unless its changes could be checked
+	var violation error
+	switch changed, checked := h.checkProtected(ctx, session.ID, run); {
+	case len(changed) > 0:
+		violation = fmt.Errorf("changed protected paths: %s", strings.Join(changed, ", "))
+	case !checked && run.patch:
+		violation = errors.New("could not check its changes against protected paths")
+		run.evt.Logger().Error("not committing agent changes", "agent_id", session.ID, "error", violation)
+	}
+
+	switch {
+	case violation != nil:
+		h.recordOutcome(run, session.ID, logging.OutcomeFailed, "protected_path", metrics.FailureProtectedPath)
+	case session.FailureCategory != agent.FailureNone:
+		h.recordOutcome(run, session.ID, logging.OutcomeFailed, string(session.FailureCategory), metrics.FailureNonzeroExit)
+	default:
+		h.recordOutcome(run, session.ID, logging.OutcomeSucceeded, "", "")
+	}
+	h.auditMerge(ctx, session.ID, run)
+	if run.patch && violation == nil {
+		h.applyPatch(ctx, session.ID, run)
+	}
+	if run.group == nil {
+		c := exitCompletion(session, run)
+		if violation != nil {
+			c = completion{emoji: "⛔", outcome: "failed: " + violation.Error(), failed: true, discarded: true}
+		}
+		h.postSummary(ctx, session, run, c)
+	}
+	h.releaseWorktree(ctx, session.ID, run)
+
+	switch {
+	case run.group != nil && violation != nil:
+		h.personaDone(ctx, run.group, session.ID, "", violation)
+	case run.group != nil:
+		h.reportPersona(ctx, session.ID, run)
+	}
+	h.dispatch(h.mrs.done(run.evt.MRKey(), session.ID))
+}
+
+// HandleFailure cleans up after an agent the spawner terminated for being
+// stuck or unhealthy: it captures the logs, stops the container, removes the
+// worktree, and explains what happened on the MR. Intended as the spawner's
+// OnFailure.
+func (h *AgentHandler) HandleFailure(session *agent.Session) {
+	ctx := context.Background()
+
+	run, ok := h.finish(ctx, session)
+	if !ok {
+		return
+	}
+	h.recordOutcome(run, session.ID, logging.OutcomeFailed, session.FailureReason, stopFailure(session))
+	h.auditMerge(ctx, session.ID, run)
+	if !run.patch {
+		h.checkProtected(ctx, session.ID, run)
+	}
+	if run.group == nil {
+		h.postSummary(ctx, session, run, completion{
+			emoji:     "⚠️",
+			outcome:   "was stopped before finishing: " + session.FailureReason,
+			failed:    true,
+			discarded: true,
+		})
+	}
+	h.releaseWorktree(ctx, session.ID, run)
+
+	if run.group != nil {
+		h.personaDone(ctx, run.group, session.ID, "", errors.New(session.FailureReason))
+	}
+	h.dispatch(h.mrs.done(run.evt.MRKey(), session.ID))
+}
+
+// stopFailure returns the metrics reason for a session the spawner stopped:
+// stuck, unhealthy, or terminated on request.
+func stopFailure(session *agent.Session) string {
+	switch {
+	case session.FailureCategory != agent.FailureStuck:
+		return metrics.FailureTerminated
+	case session.Health == docker.HealthUnhealthy:
+		return metrics.FailureUnhealthy
+	}
+	return metrics.FailureStuck
+}
+
+// recordOutcome counts how the agent's run ended in metrics and records it
+// in the log index, if any. Completed and failed agents are counted here,
+// from the final outcome, with failure as the metrics reason for a run that
+// did not succeed.
+func (h *AgentHandler) recordOutcome(run *agentRun, agentID, outcome, reason, failure string) {
+	if outcome == logging.OutcomeSucceeded {
+		metrics.AgentCompleted()
+	} else {
+		metrics.AgentFailed(failure)
+	}
+	metrics.AgentRunOutcome(metrics.Labels{
+		Provider:  run.evt.Provider,
+		Repo:      run.evt.RepoOwner + "/" + run.evt.RepoName,
+		EventType: string(run.evt.Type),
+		Outcome:   outcome,
+	})
+	if h.logIndex == nil || run.logPath == "" {
+		return
+	}
+	if err := h.logIndex.Finish(agentID, outcome, reason, time.Now()); err != nil {
+		run.evt.Logger().Warn("failed to index agent outcome", "agent_id", agentID, "error", err)
+	}
+}
+
+// releaseWorktree removes a finished agent's worktree and records the
+// cleanup in its log.
+func (h *AgentHandler) releaseWorktree(ctx context.Context, agentID string, run *agentRun) {
+	note := "\n==> worktree removed\n"
+	if err := h.repoCache.RemoveWorktree(ctx, run.evt.RepoOwner, run.evt.RepoName, agentID); err != nil {
+		run.evt.Logger().Warn("failed to cleanup worktree", "agent_id", agentID, "error", err)
+		note = fmt.Sprintf("\n==> failed to remove worktree: %v\n", err)
+	}
+	if run.logPath != "" {
+		if err := h.logWriter.Append(run.logPath, []byte(note)); err != nil {
+			run.evt.Logger().Warn("failed to record worktree cleanup", "agent_id", agentID, "error", err)
+		}
+		h.shipLog(ctx, agentID, run)
+	}
+}
+
+// shipLog forwards the agent's finished log to the log shipper, if any.
+func (h *AgentHandler) shipLog(ctx context.Context, agentID string, run *agentRun) {
+	if h.logShipper == nil {
+		return
+	}
+	f, err := os.Open(run.logPath)
+	if err != nil {
+		run.evt.Logger().Warn("failed to ship agent log", "agent_id", agentID, "error", err)
+		return
+	}
+	defer f.Close()
+
+	labels := map[string]string{
+		"repo":     run.evt.RepoOwner + "/" + run.evt.RepoName,
+		"mr":       fmt.Sprint(run.evt.MRNumber),
+		"agent_id": agentID,
+	}
+	if err := h.logShipper.ShipReader(ctx, logship.SourceAgent, labels, f); err != nil {
+		run.evt.Logger().Warn("failed to ship agent log", "agent_id", agentID, "error", err)
+	}
+}
+
+// finish forgets the agent's run, captures its logs, stops its container,
+// and runs post-agent hooks. Returns false if no run was recorded, in which
+// case the worktree is removed based on the session alone.
+func (h *AgentHandler) finish(ctx context.Context, session *agent.Session) (*agentRun, bool) {
+	agentID := session.ID
+	h.runsMu.Lock()
+	run, ok := h.runs[agentID]
+	delete(h.runs, agentID)
+	h.runsMu.Unlock()
+
+	// Capture whatever the agent produced before stopping it
+	stopped := false
+	if ok && run.logPath != "" {
+		if err := h.spawner.CaptureAndStop(ctx, agentID, run.logPath); err != nil {
+			run.evt.Logger().Warn("failed to capture agent logs", "agent_id", agentID, "error", err)
+		} else {
+			stopped = true
+		}
+	}
+	if !stopped {
+		if err := h.spawner.Stop(ctx, agentID); err != nil {
+			slog.Warn("failed to stop agent", "agent_id", agentID, "error", err)
+		}
+	}
+
+	if !ok {
+		slog.Warn("no run recorded for agent", "agent_id", agentID)
+		if owner, repo, found := strings.Cut(session.Repo, "/"); found {
+			if err := h.repoCache.RemoveWorktree(ctx, owner, repo, agentID); err != nil {
+				slog.Warn("failed to cleanup worktree", "agent_id", agentID, "error", err)
+			}
+		}
+		return nil, false
+	}
+
+	h.runHooks(ctx, hooks.PhasePostAgent, agentID, run)
+	return run, true
+}
+
+// applyPatch commits a patch-mode agent's changes with the server's identity
+// and pushes them if permitted; otherwise it posts them on the MR as a patch.
+func (h *AgentHandler) applyPatch(ctx context.Context, agentID string, run *agentRun) {
+	patcher := h.repoCache.(PatchRepo)
+	evt := run.evt
+
+	message := fmt.Sprintf("Apply Familiar agent changes for MR #%d\n\nAgent: %s", evt.MRNumber, agentID)
+	ahead, err := patcher.CommitChanges(ctx, evt.RepoOwner, evt.RepoName, agentID, run.base, message)
+	if err != nil {
+		evt.Logger().Warn("failed to commit agent changes", "agent_id", agentID, "error", err)
+		h.postComment(ctx, evt, agentID, fmt.Sprintf("⚠️ Familiar could not commit the changes from agent `%s`: %v", agentID, err))
+		return
+	}
+	if ahead == 0 {
+		evt.Logger().Info("agent made no changes", "agent_id", agentID)
+		return
+	}
+
+	reason := "pushing is not permitted for this request"
+	switch {
+	case evt.FromFork:
+		reason = "the source branch is in a fork Familiar cannot push to"
+	case evt.DefaultBranch != "":
+		reason = fmt.Sprintf("there is no merge request branch, and Familiar does not push to %s", evt.DefaultBranch)
+	}
+	if run.pushAllowed {
+		err := patcher.PushChanges(ctx, evt.RepoOwner, evt.RepoName, agentID, evt.SourceBranch)
+		if err == nil {
+			evt.Logger().Info("pushed agent changes", "agent_id", agentID, "commits", ahead, "branch", evt.SourceBranch)
+			h.auditAction(agentID, run, "push_commits", true, fmt.Sprintf("pushed %d commit(s) to %s", ahead, evt.SourceBranch))
+			h.postComment(ctx, evt, agentID, fmt.Sprintf("✅ Familiar pushed %d commit(s) from agent `%s` to `%s`.",
+				ahead, agentID, evt.SourceBranch))
+			return
+		}
+		evt.Logger().Warn("failed to push agent changes", "agent_id", agentID, "error", err)
+		h.auditAction(agentID, run, "push_commits", false, fmt.Sprintf("push failed: %v", err))
+		reason = fmt.Sprintf("the push failed: %v", err)
+	}
+
+	patch, err := patcher.FormatPatch(ctx, evt.RepoOwner, evt.RepoName, agentID, run.base)
+	if err != nil {
+		evt.Logger().Warn("failed to format agent patch", "agent_id", agentID, "error", err)
+		return
+	}
+	if run.logPath != "" {
+		if err := h.logWriter.Append(run.logPath, []byte("\n==> proposed patch\n"+patch+"\n")); err != nil {
+			evt.Logger().Warn("failed to write agent patch", "agent_id", agentID, "error", err)
+		}
+	}
+
+	shown := patch
+	if len(shown) > maxPatchComment {
+		shown = shown[:maxPatchComment] + "\n... (truncated; full patch in the server logs)"
+	}
+	h.postComment(ctx, evt, agentID, fmt.Sprintf("📝 Familiar agent `%s` proposes these changes (%s). "+
+		"Apply them with `git am`:\n\n<details><summary>Patch</summary>\n\n```diff\n%s\n```\n</details>",
+		agentID, reason, shown))
+}
+
+// withPatchMode returns cfg with patch mode forced on, for agents that must
+// not hold provider credentials.
+func withPatchMode(cfg *config.MergedConfig) *config.MergedConfig {
+	forked := config.MergedConfig{}
+	if cfg != nil {
+		forked = *cfg
+	}
+	forked.AgentMode = config.AgentModePatch
+	return &forked
+}
+
+// eventRefs returns the branches and merge request head ref an event's
+// agents work from. A fork's source branch is not in the repo, and an
+// event on the default branch has no merge request head.
+func eventRefs(evt *event.Event, prov provider.Provider) []string {
+	var refs []string
+	for _, branch := range []string{evt.SourceBranch, evt.TargetBranch, evt.DefaultBranch} {
+		if branch == evt.SourceBranch && evt.FromFork {
+			continue
+		}
+		if branch != "" && !slices.Contains(refs, branch) {
+			refs = append(refs, branch)
+		}
+	}
+	if prov != nil && evt.MRNumber > 0 && evt.DefaultBranch == "" {
+		refs = append(refs, prov.MRHeadRef(evt.MRNumber))
+	}
+	return refs
+}
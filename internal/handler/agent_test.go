@@ -2,17 +2,26 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/drewdunne/familiar/internal/agent"
 	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/docker"
 	"github.com/drewdunne/familiar/internal/event"
+	"github.com/drewdunne/familiar/internal/hooks"
 	"github.com/drewdunne/familiar/internal/intent"
 	"github.com/drewdunne/familiar/internal/lca"
 	"github.com/drewdunne/familiar/internal/logging"
+	"github.com/drewdunne/familiar/internal/metrics"
 	"github.com/drewdunne/familiar/internal/provider"
 )
 
@@ -198,37 +207,104 @@ func TestLCAToWorkDir(t *testing.T) {
 
 type mockSpawner struct {
 	lastRequest agent.SpawnRequest
+	requests    []agent.SpawnRequest
 	spawnErr    error
+	waited      []string
+	stopped     []string
+	captured    map[string]string // session ID -> log path
+	captureErr  error
+	output      map[string]string // session ID -> output appended on capture
+	terminated  []string
+	onFailure   func(*agent.Session) // as the spawner's OnFailure
 }
 
 func (m *mockSpawner) Spawn(_ context.Context, req agent.SpawnRequest) (*agent.Session, error) {
 	m.lastRequest = req
+	m.requests = append(m.requests, req)
 	if m.spawnErr != nil {
 		return nil, m.spawnErr
 	}
 	return &agent.Session{ID: req.ID, Status: "running"}, nil
 }
 
+func (m *mockSpawner) Image() string { return "familiar-agent:test" }
+
+func (m *mockSpawner) Wait(_ context.Context, sessionID string) error {
+	m.waited = append(m.waited, sessionID)
+	return nil
+}
+
+func (m *mockSpawner) Terminate(_ context.Context, sessionID, reason string) error {
+	m.terminated = append(m.terminated, sessionID)
+	if m.onFailure != nil {
+		m.onFailure(&agent.Session{ID: sessionID, Status: "failed", FailureReason: reason})
+	}
+	return nil
+}
+
+func (m *mockSpawner) Stop(_ context.Context, sessionID string) error {
+	m.stopped = append(m.stopped, sessionID)
+	return nil
+}
+
+func (m *mockSpawner) CaptureAndStop(_ context.Context, sessionID string, logPath string) error {
+	if m.captureErr != nil {
+		return m.captureErr
+	}
+	if m.captured == nil {
+		m.captured = make(map[string]string)
+	}
+	m.captured[sessionID] = logPath
+	if out, ok := m.output[sessionID]; ok {
+		f, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = f.WriteString(out)
+		return err
+	}
+	return nil
+}
+
 type mockRepoCache struct {
-	ensureErr   error
-	worktreeErr error
+	ensureErr    error
+	worktreeErr  error
+	worktreePath string // defaults to a fixed fake path
+	removed      []string
+	credentials  map[string]string // repo -> username:password
+	fetchedRefs  []string
+	worktreeRef  string
 }
 
-func (m *mockRepoCache) EnsureRepo(_ context.Context, _, _, _ string) (string, error) {
+func (m *mockRepoCache) SetCredentials(owner, repo, username, password string) {
+	if m.credentials == nil {
+		m.credentials = make(map[string]string)
+	}
+	m.credentials[owner+"/"+repo] = username + ":" + password
+}
+
+func (m *mockRepoCache) EnsureRepo(_ context.Context, _, _, _ string, refs ...string) (string, error) {
+	m.fetchedRefs = refs
 	if m.ensureErr != nil {
 		return "", m.ensureErr
 	}
 	return "/cache/owner/repo.git", nil
 }
 
-func (m *mockRepoCache) CreateWorktree(_ context.Context, _, _, _, _ string) (string, error) {
+func (m *mockRepoCache) CreateWorktree(_ context.Context, _, _, ref, _ string) (string, error) {
+	m.worktreeRef = ref
 	if m.worktreeErr != nil {
 		return "", m.worktreeErr
 	}
+	if m.worktreePath != "" {
+		return m.worktreePath, nil
+	}
 	return "/cache/owner/repo.git/worktrees-data/wt-1", nil
 }
 
-func (m *mockRepoCache) RemoveWorktree(_ context.Context, _, _, _ string) error {
+func (m *mockRepoCache) RemoveWorktree(_ context.Context, _, _, worktreeID string) error {
+	m.removed = append(m.removed, worktreeID)
 	return nil
 }
 
@@ -239,26 +315,31 @@ func (m *mockRepoCache) HostPath(containerPath string) string {
 type mockProvider struct {
 	name     string
 	agentEnv map[string]string
-	authURL  string
+	token    string
 	files    []provider.ChangedFile
 	filesErr error
+	comments []string
+	mr       *provider.MergeRequest
+	mrErr    error
+	repo     *provider.Repository
 }
 
 func (m *mockProvider) Name() string { return m.name }
 
 func (m *mockProvider) GetRepository(_ context.Context, _, _ string) (*provider.Repository, error) {
-	return nil, nil
+	return m.repo, nil
 }
 
 func (m *mockProvider) GetMergeRequest(_ context.Context, _, _ string, _ int) (*provider.MergeRequest, error) {
-	return nil, nil
+	return m.mr, m.mrErr
 }
 
 func (m *mockProvider) GetChangedFiles(_ context.Context, _, _ string, _ int) ([]provider.ChangedFile, error) {
 	return m.files, m.filesErr
 }
 
-func (m *mockProvider) PostComment(_ context.Context, _, _ string, _ int, _ string) error {
+func (m *mockProvider) PostComment(_ context.Context, _, _ string, _ int, body string) error {
+	m.comments = append(m.comments, body)
 	return nil
 }
 
@@ -270,11 +351,29 @@ func (m *mockProvider) AgentEnv() map[string]string {
 	return m.agentEnv
 }
 
-func (m *mockProvider) AuthenticatedCloneURL(rawURL string) (string, error) {
-	if m.authURL != "" {
-		return m.authURL, nil
+func (m *mockProvider) GitCredentials() (string, string) {
+	return "x-access-token", m.token
+}
+
+func (m *mockProvider) MRHeadRef(number int) string {
+	return fmt.Sprintf("refs/pull/%d/head", number)
+}
+
+// mockReactor is a provider that supports comment reactions.
+type mockReactor struct {
+	mockProvider
+	reactions []int // comment IDs reacted to
+	review    bool
+	reactErr  error
+}
+
+func (m *mockReactor) ReactToComment(_ context.Context, _, _ string, _, commentID int, review bool) error {
+	if m.reactErr != nil {
+		return m.reactErr
 	}
-	return rawURL, nil
+	m.reactions = append(m.reactions, commentID)
+	m.review = review
+	return nil
 }
 
 type mockRegistry struct {
@@ -338,6 +437,83 @@ func TestHandle_PassesProviderEnvToSpawner(t *testing.T) {
 	}
 }
 
+func TestHandle_SetsRepoCredentials(t *testing.T) {
+	cache := &mockRepoCache{}
+	prov := &mockProvider{name: "github", token: "ghp-test-token"}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"github": prov}}
+	h := NewAgentHandler(&mockSpawner{}, cache, reg, "", "")
+
+	evt := &event.Event{
+		Type:         event.TypeMRComment,
+		Provider:     "github",
+		RepoOwner:    "owner",
+		RepoName:     "repo",
+		RepoURL:      "https://github.com/owner/repo.git",
+		MRNumber:     1,
+		SourceBranch: "feature",
+		Timestamp:    time.Now(),
+	}
+	if err := h.Handle(context.Background(), evt, &config.MergedConfig{}, &intent.ParsedIntent{}); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	if got := cache.credentials["owner/repo"]; got != "x-access-token:ghp-test-token" {
+		t.Errorf("repo credentials = %q, want provider credentials", got)
+	}
+}
+
+func TestHandle_FetchesEventRefs(t *testing.T) {
+	cache := &mockRepoCache{}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"github": &mockProvider{name: "github"}}}
+	h := NewAgentHandler(&mockSpawner{}, cache, reg, "", "")
+
+	evt := &event.Event{
+		Type:         event.TypeMROpened,
+		Provider:     "github",
+		RepoOwner:    "owner",
+		RepoName:     "repo",
+		MRNumber:     7,
+		SourceBranch: "feature",
+		TargetBranch: "main",
+		Timestamp:    time.Now(),
+	}
+	if err := h.Handle(context.Background(), evt, &config.MergedConfig{}, &intent.ParsedIntent{}); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	want := []string{"feature", "main", "refs/pull/7/head"}
+	if strings.Join(cache.fetchedRefs, ",") != strings.Join(want, ",") {
+		t.Errorf("fetched refs = %v, want %v", cache.fetchedRefs, want)
+	}
+}
+
+func TestHandle_CarriesCorrelationID(t *testing.T) {
+	logDir := t.TempDir()
+	spawner := &mockSpawner{}
+	reg := &mockRegistry{providers: map[string]provider.Provider{}}
+	h := NewAgentHandler(spawner, &mockRepoCache{}, reg, logDir, "")
+
+	evt := &event.Event{
+		Type:          event.TypeMROpened,
+		Provider:      "github",
+		RepoOwner:     "owner",
+		RepoName:      "repo",
+		MRNumber:      1,
+		Timestamp:     time.Now(),
+		CorrelationID: "abc123",
+	}
+	if err := h.Handle(context.Background(), evt, &config.MergedConfig{}, &intent.ParsedIntent{}); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	if spawner.lastRequest.CorrelationID != "abc123" {
+		t.Errorf("SpawnRequest.CorrelationID = %q, want %q", spawner.lastRequest.CorrelationID, "abc123")
+	}
+	if log := agentLog(t, logDir); !strings.Contains(log, `"correlation_id":"abc123"`) {
+		t.Errorf("agent log = %q, want correlation ID in header", log)
+	}
+}
+
 func TestHandle_NilProviderSkipsEnv(t *testing.T) {
 	spawner := &mockSpawner{}
 	cache := &mockRepoCache{}
@@ -371,3 +547,912 @@ func TestHandle_NilProviderSkipsEnv(t *testing.T) {
 		t.Errorf("expected SpawnRequest.Env to be nil, got %v", spawner.lastRequest.Env)
 	}
 }
+
+// --- Tests for queued spawning ---
+
+type mockQueue struct {
+	enqueued []agent.SpawnRequest
+	spawnFns []agent.SpawnFunc
+	err      error
+}
+
+func (m *mockQueue) Enqueue(req agent.SpawnRequest, spawnFn agent.SpawnFunc) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.enqueued = append(m.enqueued, req)
+	m.spawnFns = append(m.spawnFns, spawnFn)
+	return nil
+}
+
+func testEvent() *event.Event {
+	return &event.Event{
+		Type:         event.TypeMRComment,
+		Provider:     "gitlab",
+		RepoOwner:    "owner",
+		RepoName:     "repo",
+		RepoURL:      "https://gitlab.example.com/owner/repo.git",
+		MRNumber:     1,
+		SourceBranch: "feature",
+		TargetBranch: "main",
+		Timestamp:    time.Now(),
+	}
+}
+
+func TestHandle_WithQueue_DefersSpawn(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{}
+	queue := &mockQueue{}
+	reg := &mockRegistry{providers: map[string]provider.Provider{}}
+
+	h := NewAgentHandler(spawner, cache, reg, "", "", WithQueue(queue))
+
+	if err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	if len(queue.enqueued) != 1 {
+		t.Fatalf("enqueued = %d, want 1", len(queue.enqueued))
+	}
+	if spawner.lastRequest.ID != "" {
+		t.Error("spawner should not be called until the queued request runs")
+	}
+
+	// Run the queued spawn
+	if err := queue.spawnFns[0](context.Background(), queue.enqueued[0]); err != nil {
+		t.Fatalf("spawnFn error: %v", err)
+	}
+
+	if spawner.lastRequest.ID != queue.enqueued[0].ID {
+		t.Errorf("spawned ID = %q, want %q", spawner.lastRequest.ID, queue.enqueued[0].ID)
+	}
+	if len(spawner.waited) != 1 || spawner.waited[0] != queue.enqueued[0].ID {
+		t.Errorf("waited = %v, want [%s]", spawner.waited, queue.enqueued[0].ID)
+	}
+}
+
+func TestHandle_DryRunSpawnsNothing(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{}
+	queue := &mockQueue{}
+	logDir := t.TempDir()
+	reg := &mockRegistry{providers: map[string]provider.Provider{}}
+
+	h := NewAgentHandler(spawner, cache, reg, logDir, "", WithQueue(queue), WithDryRun())
+
+	if err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	if cache.fetchedRefs == nil {
+		t.Error("dry run should still fetch the repo")
+	}
+	if cache.worktreeRef != "" {
+		t.Errorf("dry run created a worktree from %q", cache.worktreeRef)
+	}
+	if len(queue.enqueued) != 0 || len(spawner.requests) != 0 {
+		t.Errorf("dry run enqueued %d and spawned %d agents, want none", len(queue.enqueued), len(spawner.requests))
+	}
+	if entries, _ := os.ReadDir(logDir); len(entries) != 0 {
+		t.Errorf("dry run wrote %d log entries, want none", len(entries))
+	}
+}
+
+func TestHandle_WithQueue_QueueFullRemovesWorktree(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{}
+	queue := &mockQueue{err: agent.ErrQueueFull}
+	reg := &mockRegistry{providers: map[string]provider.Provider{}}
+
+	h := NewAgentHandler(spawner, cache, reg, "", "", WithQueue(queue))
+
+	err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil)
+	if !errors.Is(err, agent.ErrQueueFull) {
+		t.Fatalf("Handle() error = %v, want ErrQueueFull", err)
+	}
+	if len(cache.removed) != 1 {
+		t.Errorf("removed worktrees = %v, want 1 entry", cache.removed)
+	}
+}
+
+func TestHandle_WithQueue_DrainedRemovesWorktree(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{}
+	queue := &mockQueue{}
+	reg := &mockRegistry{providers: map[string]provider.Provider{}}
+
+	h := NewAgentHandler(spawner, cache, reg, "", "", WithQueue(queue))
+
+	if err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(agent.ErrDrained)
+	if err := queue.spawnFns[0](ctx, queue.enqueued[0]); !errors.Is(err, agent.ErrDrained) {
+		t.Fatalf("spawnFn error = %v, want ErrDrained", err)
+	}
+	if spawner.lastRequest.ID != "" {
+		t.Error("drained request should not be spawned")
+	}
+	if len(cache.removed) != 1 {
+		t.Errorf("removed worktrees = %v, want 1 entry", cache.removed)
+	}
+}
+
+func TestHandle_WithQueue_SpawnFailureSkipsWait(t *testing.T) {
+	spawner := &mockSpawner{spawnErr: errors.New("boom")}
+	cache := &mockRepoCache{}
+	queue := &mockQueue{}
+	reg := &mockRegistry{providers: map[string]provider.Provider{}}
+
+	h := NewAgentHandler(spawner, cache, reg, "", "", WithQueue(queue))
+
+	if err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	if err := queue.spawnFns[0](context.Background(), queue.enqueued[0]); err == nil {
+		t.Fatal("spawnFn should return the spawn error")
+	}
+	if len(spawner.waited) != 0 {
+		t.Errorf("waited = %v, want none", spawner.waited)
+	}
+	if len(cache.removed) != 1 {
+		t.Errorf("removed worktrees = %v, want 1 entry", cache.removed)
+	}
+}
+
+// --- Tests for interactive sessions ---
+
+func TestWantsInteractive(t *testing.T) {
+	tests := []struct {
+		name    string
+		evtType event.Type
+		comment string
+		cfg     *config.MergedConfig
+		want    bool
+	}{
+		{"default batch", event.TypeMRComment, "please fix", &config.MergedConfig{}, false},
+		{"comment command", event.TypeMRComment, "@familiar /interactive fix the tests", &config.MergedConfig{}, true},
+		{"command must be its own word", event.TypeMRComment, "see /interactive-docs", &config.MergedConfig{}, false},
+		{"configured event type", event.TypeMROpened, "", &config.MergedConfig{InteractiveEvents: []string{"mr_opened"}}, true},
+		{"nil config", event.TypeMRComment, "/interactive", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evt := &event.Event{Type: tt.evtType, CommentBody: tt.comment}
+			if got := wantsInteractive(evt, tt.cfg); got != tt.want {
+				t.Errorf("wantsInteractive() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandle_InteractivePostsAttachInstructions(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{}
+	prov := &mockProvider{name: "gitlab"}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, "", "")
+
+	evt := testEvent()
+	evt.CommentBody = "@familiar /interactive"
+	if err := h.Handle(context.Background(), evt, &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	if !spawner.lastRequest.Interactive {
+		t.Error("SpawnRequest.Interactive = false, want true")
+	}
+	if len(prov.comments) != 1 {
+		t.Fatalf("comments = %d, want 1", len(prov.comments))
+	}
+	want := "docker exec -it familiar-agent-" + spawner.lastRequest.ID + " tmux attach-session -t claude"
+	if !strings.Contains(prov.comments[0], want) {
+		t.Errorf("comment = %q, want attach command %q", prov.comments[0], want)
+	}
+}
+
+func TestHandle_AcknowledgesTrigger(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          string
+		evtType       event.Type
+		commentID     int
+		reactErr      error
+		wantReactions int
+		wantComment   bool
+	}{
+		{"reaction", config.AcknowledgeReaction, event.TypeMention, 7, nil, 1, false},
+		{"reply", config.AcknowledgeComment, event.TypeMRComment, 7, nil, 0, true},
+		{"none", config.AcknowledgeNone, event.TypeMention, 7, nil, 0, false},
+		{"unset", "", event.TypeMention, 7, nil, 0, false},
+		{"not a comment", config.AcknowledgeReaction, event.TypeMROpened, 0, nil, 0, false},
+		{"reply without a comment ID", config.AcknowledgeReaction, event.TypeMention, 0, nil, 0, true},
+		{"reply when reacting fails", config.AcknowledgeReaction, event.TypeMention, 7, errors.New("forbidden"), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spawner := &mockSpawner{}
+			prov := &mockReactor{mockProvider: mockProvider{name: "gitlab"}, reactErr: tt.reactErr}
+			reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+			h := NewAgentHandler(spawner, &mockRepoCache{}, reg, "", "", WithAcknowledge(tt.mode))
+
+			evt := testEvent()
+			evt.Type = tt.evtType
+			evt.CommentID = tt.commentID
+			if err := h.Handle(context.Background(), evt, &config.MergedConfig{}, nil); err != nil {
+				t.Fatalf("Handle() error: %v", err)
+			}
+
+			if len(prov.reactions) != tt.wantReactions {
+				t.Errorf("reactions = %v, want %d", prov.reactions, tt.wantReactions)
+			}
+			if gotComment := len(prov.comments) == 1; gotComment != tt.wantComment {
+				t.Fatalf("comments = %q, want a reply: %v", prov.comments, tt.wantComment)
+			}
+			if tt.wantComment && !strings.Contains(prov.comments[0], spawner.lastRequest.ID) {
+				t.Errorf("reply = %q, want it to name agent %s", prov.comments[0], spawner.lastRequest.ID)
+			}
+		})
+	}
+}
+
+func TestHandle_AcknowledgeReviewComment(t *testing.T) {
+	prov := &mockReactor{mockProvider: mockProvider{name: "gitlab"}}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+	h := NewAgentHandler(&mockSpawner{}, &mockRepoCache{}, reg, "", "", WithAcknowledge(config.AcknowledgeReaction))
+
+	evt := testEvent()
+	evt.CommentID = 7
+	evt.CommentFilePath = "main.go"
+	if err := h.Handle(context.Background(), evt, &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	if !prov.review {
+		t.Error("reaction to a comment on the diff should be marked as a review comment")
+	}
+}
+
+func TestHandle_BatchPostsNoAttachInstructions(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{}
+	prov := &mockProvider{name: "gitlab"}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, "", "")
+
+	if err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	if spawner.lastRequest.Interactive {
+		t.Error("SpawnRequest.Interactive = true, want false")
+	}
+	if len(prov.comments) != 0 {
+		t.Errorf("comments = %v, want none", prov.comments)
+	}
+}
+
+// --- Tests for hooks ---
+
+// agentLog returns the contents of the single agent log under logDir.
+func agentLog(t *testing.T, logDir string) string {
+	t.Helper()
+	matches, _ := filepath.Glob(filepath.Join(logDir, "owner", "repo", "1", "*.log"))
+	if len(matches) != 1 {
+		t.Fatalf("agent logs = %v, want 1", matches)
+	}
+	data, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatalf("reading agent log: %v", err)
+	}
+	return string(data)
+}
+
+func TestHandle_PreAgentHooksRunInWorktree(t *testing.T) {
+	worktree := t.TempDir()
+	logDir := t.TempDir()
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{worktreePath: worktree}
+	reg := &mockRegistry{providers: map[string]provider.Provider{}}
+
+	h := NewAgentHandler(spawner, cache, reg, logDir, "",
+		WithHooks(&hooks.Runner{Pre: []string{"touch installed && echo deps for $FAMILIAR_REPO"}}))
+
+	if err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(worktree, "installed")); err != nil {
+		t.Errorf("pre-agent hook should run in the worktree: %v", err)
+	}
+	if got := agentLog(t, logDir); !strings.Contains(got, "deps for owner/repo") {
+		t.Errorf("agent log = %q, want hook output", got)
+	}
+	if spawner.lastRequest.ID == "" {
+		t.Error("agent should spawn after hooks succeed")
+	}
+}
+
+func TestHandle_PreAgentHookFailureSkipsSpawn(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{worktreePath: t.TempDir()}
+	prov := &mockProvider{name: "gitlab"}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, t.TempDir(), "",
+		WithHooks(&hooks.Runner{Pre: []string{"exit 1"}}))
+
+	if err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil); err == nil {
+		t.Fatal("Handle() should fail when a pre-agent hook fails")
+	}
+
+	if spawner.lastRequest.ID != "" {
+		t.Error("agent should not spawn when a pre-agent hook fails")
+	}
+	if len(cache.removed) != 1 {
+		t.Errorf("removed worktrees = %v, want 1 entry", cache.removed)
+	}
+	if len(prov.comments) != 1 || !strings.Contains(prov.comments[0], "pre_agent hook failed") {
+		t.Errorf("comments = %v, want hook failure report", prov.comments)
+	}
+}
+
+func TestHandleTimeout_RunsPostAgentHooks(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+
+	logDir := t.TempDir()
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{worktreePath: t.TempDir()}
+	reg := &mockRegistry{providers: map[string]provider.Provider{}}
+
+	h := NewAgentHandler(spawner, cache, reg, logDir, "",
+		WithHooks(&hooks.Runner{Post: []string{"echo cleanup $FAMILIAR_AGENT_ID"}}))
+
+	if err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	agentID := spawner.lastRequest.ID
+
+	h.HandleTimeout(&agent.Session{ID: agentID, StartedAt: time.Now()})
+
+	if got := agentLog(t, logDir); !strings.Contains(got, "cleanup "+agentID) {
+		t.Errorf("agent log = %q, want post-agent hook output", got)
+	}
+}
+
+func TestHandle_CountsFailureReasons(t *testing.T) {
+	tests := []struct {
+		name    string
+		cache   *mockRepoCache
+		spawner *mockSpawner
+		queue   *mockQueue
+		reason  string
+	}{
+		{"clone", &mockRepoCache{ensureErr: errors.New("auth failed")}, &mockSpawner{}, nil, metrics.FailureCloneError},
+		{"worktree", &mockRepoCache{worktreeErr: errors.New("bad ref")}, &mockSpawner{}, nil, metrics.FailureCloneError},
+		{"spawn", &mockRepoCache{}, &mockSpawner{spawnErr: errors.New("no docker")}, nil, metrics.FailureSpawnError},
+		{"queue full", &mockRepoCache{}, &mockSpawner{}, &mockQueue{err: agent.ErrQueueFull}, metrics.FailureQueueFull},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics.Reset()
+			defer metrics.Reset()
+
+			reg := &mockRegistry{providers: map[string]provider.Provider{}}
+			var opts []Option
+			if tt.queue != nil {
+				opts = append(opts, WithQueue(tt.queue))
+			}
+			h := NewAgentHandler(tt.spawner, tt.cache, reg, t.TempDir(), "", opts...)
+
+			if err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil); err == nil {
+				t.Fatal("Handle() expected error")
+			}
+			m := metrics.Get()
+			if m.FailureReasons[tt.reason] != 1 || m.AgentsFailed != 1 {
+				t.Errorf("FailureReasons = %v, AgentsFailed = %d; want one %s", m.FailureReasons, m.AgentsFailed, tt.reason)
+			}
+		})
+	}
+}
+
+func TestHandler_CountsFinalOutcome(t *testing.T) {
+	exit := func(h *AgentHandler, s *agent.Session) { h.HandleExit(s) }
+	stop := func(h *AgentHandler, s *agent.Session) { h.HandleFailure(s) }
+	tests := []struct {
+		name    string
+		files   []string
+		session agent.Session
+		finish  func(*AgentHandler, *agent.Session)
+		reason  string // "" means counted as completed
+	}{
+		{"succeeded", []string{"main.go"}, agent.Session{}, exit, ""},
+		{"task failure", nil, agent.Session{ExitCode: 1, FailureCategory: agent.FailureTask}, exit, metrics.FailureNonzeroExit},
+		{"protected path", []string{".github/workflows/ci.yml"}, agent.Session{}, exit, metrics.FailureProtectedPath},
+		{"timed out", nil, agent.Session{StartedAt: time.Now()}, func(h *AgentHandler, s *agent.Session) { h.HandleTimeout(s) }, metrics.FailureTimeout},
+		{"stuck", nil, agent.Session{FailureCategory: agent.FailureStuck, FailureReason: "no output"}, stop, metrics.FailureStuck},
+		{"unhealthy", nil, agent.Session{FailureCategory: agent.FailureStuck, Health: docker.HealthUnhealthy}, stop, metrics.FailureUnhealthy},
+		{"terminated", nil, agent.Session{FailureReason: "stopped by admin"}, stop, metrics.FailureTerminated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics.Reset()
+			defer metrics.Reset()
+
+			spawner := &mockSpawner{}
+			cache := &mockChangeLister{files: tt.files}
+			reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": &mockProvider{name: "gitlab"}}}
+			h := NewAgentHandler(spawner, cache, reg, "", "")
+
+			cfg := &config.MergedConfig{ProtectedPaths: []string{".github/workflows/**"}}
+			if err := h.Handle(context.Background(), testEvent(), cfg, nil); err != nil {
+				t.Fatalf("Handle() error: %v", err)
+			}
+			session := tt.session
+			session.ID = spawner.lastRequest.ID
+			tt.finish(h, &session)
+
+			m := metrics.Get()
+			switch {
+			case tt.reason == "" && (m.AgentsCompleted != 1 || m.AgentsFailed != 0):
+				t.Errorf("completed, failed = %d, %d; want 1, 0", m.AgentsCompleted, m.AgentsFailed)
+			case tt.reason != "" && (m.AgentsCompleted != 0 || m.AgentsFailed != 1 || m.FailureReasons[tt.reason] != 1):
+				t.Errorf("completed = %d, failure reasons = %v; want one %s failure", m.AgentsCompleted, m.FailureReasons, tt.reason)
+			}
+		})
+	}
+}
+
+// --- Tests for timeout handling ---
+
+func TestHandleTimeout_CapturesLogsAndCleansUp(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{}
+	prov := &mockProvider{name: "gitlab"}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, t.TempDir(), "")
+
+	if err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	agentID := spawner.lastRequest.ID
+
+	h.HandleTimeout(&agent.Session{ID: agentID, StartedAt: time.Now().Add(-30 * time.Minute)})
+
+	if spawner.captured[agentID] == "" {
+		t.Error("timed out agent logs should be captured to its log file")
+	}
+	if len(cache.removed) != 1 || cache.removed[0] != agentID {
+		t.Errorf("removed worktrees = %v, want [%s]", cache.removed, agentID)
+	}
+	if got := metrics.Get().AgentsTimedOut; got != 1 {
+		t.Errorf("AgentsTimedOut = %d, want 1", got)
+	}
+	if len(prov.comments) != 1 || !strings.Contains(prov.comments[0], "timed out after 30m") {
+		t.Errorf("comments = %v, want one timeout explanation", prov.comments)
+	}
+}
+
+func TestHandleTimeout_CaptureFailureStillStops(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+
+	spawner := &mockSpawner{captureErr: errors.New("no logs")}
+	cache := &mockRepoCache{}
+	reg := &mockRegistry{providers: map[string]provider.Provider{}}
+
+	h := NewAgentHandler(spawner, cache, reg, t.TempDir(), "")
+
+	if err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	agentID := spawner.lastRequest.ID
+
+	h.HandleTimeout(&agent.Session{ID: agentID, StartedAt: time.Now()})
+
+	if len(spawner.stopped) != 1 || spawner.stopped[0] != agentID {
+		t.Errorf("stopped = %v, want [%s]", spawner.stopped, agentID)
+	}
+	if len(cache.removed) != 1 {
+		t.Errorf("removed worktrees = %v, want 1 entry", cache.removed)
+	}
+}
+
+func TestHandleTimeout_UnknownAgent(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{}
+	h := NewAgentHandler(spawner, cache, &mockRegistry{}, "", "")
+
+	h.HandleTimeout(&agent.Session{ID: "unknown", StartedAt: time.Now()})
+
+	if len(spawner.stopped) != 1 {
+		t.Errorf("stopped = %v, want the session stopped", spawner.stopped)
+	}
+	if len(cache.removed) != 0 {
+		t.Errorf("removed worktrees = %v, want none", cache.removed)
+	}
+	if got := metrics.Get().AgentsTimedOut; got != 1 {
+		t.Errorf("AgentsTimedOut = %d, want 1", got)
+	}
+}
+
+// --- Tests for patch mode ---
+
+type mockPatchRepo struct {
+	mockRepoCache
+	ahead     int
+	pushErr   error
+	committed []string
+	bases     []string
+	pushed    []string
+}
+
+func (m *mockPatchRepo) CommitChanges(_ context.Context, _, _, worktreeID, base, _ string) (int, error) {
+	m.committed = append(m.committed, worktreeID)
+	m.bases = append(m.bases, base)
+	return m.ahead, nil
+}
+
+func (m *mockPatchRepo) PushChanges(_ context.Context, _, _, _, branch string) error {
+	if m.pushErr != nil {
+		return m.pushErr
+	}
+	m.pushed = append(m.pushed, branch)
+	return nil
+}
+
+func (m *mockPatchRepo) FormatPatch(_ context.Context, _, _, _, _ string) (string, error) {
+	return "From abc123\nSubject: [PATCH] Apply Familiar agent changes\n", nil
+}
+
+func patchConfig(push string) *config.MergedConfig {
+	cfg := &config.MergedConfig{AgentMode: config.AgentModePatch}
+	cfg.Permissions.PushCommits = push
+	return cfg
+}
+
+func TestHandle_PatchModeWithholdsProviderEnv(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockPatchRepo{}
+	prov := &mockProvider{name: "gitlab", agentEnv: map[string]string{"GITLAB_TOKEN": "glpat-test-token"}}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, "", "")
+
+	if err := h.Handle(context.Background(), testEvent(), patchConfig("always"), nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	if _, ok := spawner.lastRequest.Env["GITLAB_TOKEN"]; ok {
+		t.Error("patch-mode agents should not receive provider credentials")
+	}
+	if !spawner.lastRequest.ReadOnlyGit {
+		t.Error("patch-mode agents should get a read-only repo cache")
+	}
+}
+
+func TestHandle_PatchModeUnsupportedCache(t *testing.T) {
+	spawner := &mockSpawner{}
+	h := NewAgentHandler(spawner, &mockRepoCache{}, &mockRegistry{}, "", "")
+
+	if err := h.Handle(context.Background(), testEvent(), patchConfig("always"), nil); err == nil {
+		t.Fatal("Handle() should fail when the repo cache cannot apply patches")
+	}
+	if spawner.lastRequest.ID != "" {
+		t.Error("agent should not spawn")
+	}
+}
+
+func TestHandleExit_PatchModePushes(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockPatchRepo{ahead: 2}
+	prov := &mockProvider{name: "gitlab"}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, t.TempDir(), "")
+
+	if err := h.Handle(context.Background(), testEvent(), patchConfig("always"), nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	agentID := spawner.lastRequest.ID
+
+	h.HandleExit(&agent.Session{ID: agentID, StartedAt: time.Now()})
+
+	if len(cache.pushed) != 1 || cache.pushed[0] != "feature" {
+		t.Errorf("pushed = %v, want [feature]", cache.pushed)
+	}
+	if len(prov.comments) != 2 || !strings.Contains(prov.comments[0], "pushed 2 commit(s)") {
+		t.Errorf("comments = %v, want push report", prov.comments)
+	}
+	if len(cache.removed) != 1 || cache.removed[0] != agentID {
+		t.Errorf("removed worktrees = %v, want [%s]", cache.removed, agentID)
+	}
+}
+
+func TestHandle_ForkMRProposesPatchFromHeadRef(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockPatchRepo{ahead: 1}
+	prov := &mockProvider{name: "gitlab", agentEnv: map[string]string{"GITLAB_TOKEN": "glpat-test-token"}}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, t.TempDir(), "")
+
+	evt := testEvent()
+	evt.FromFork = true
+	cfg := &config.MergedConfig{}
+	cfg.Permissions.PushCommits = "always"
+	if err := h.Handle(context.Background(), evt, cfg, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	headRef := fmt.Sprintf("refs/pull/%d/head", evt.MRNumber)
+	if cache.worktreeRef != headRef {
+		t.Errorf("worktree ref = %q, want %q", cache.worktreeRef, headRef)
+	}
+	if slices.Contains(cache.fetchedRefs, evt.SourceBranch) {
+		t.Errorf("fetched refs = %v, should not include the fork's branch", cache.fetchedRefs)
+	}
+	if _, ok := spawner.lastRequest.Env["GITLAB_TOKEN"]; ok {
+		t.Error("fork MR agents should run in patch mode without provider credentials")
+	}
+	if cfg.AgentMode == config.AgentModePatch {
+		t.Error("the caller's config should not be modified")
+	}
+
+	h.HandleExit(&agent.Session{ID: spawner.lastRequest.ID, StartedAt: time.Now()})
+
+	if len(cache.bases) != 1 || cache.bases[0] != headRef {
+		t.Errorf("commit bases = %v, want [%s]", cache.bases, headRef)
+	}
+	if len(cache.pushed) != 0 {
+		t.Errorf("pushed = %v, want none for a fork", cache.pushed)
+	}
+	if len(prov.comments) != 2 || !strings.Contains(prov.comments[0], "in a fork") {
+		t.Errorf("comments = %v, want a proposed patch explaining the fork", prov.comments)
+	}
+}
+
+func TestHandleExit_PatchModeProposesWhenPushNotAllowed(t *testing.T) {
+	logDir := t.TempDir()
+	spawner := &mockSpawner{}
+	cache := &mockPatchRepo{ahead: 1}
+	prov := &mockProvider{name: "gitlab"}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, logDir, "")
+
+	if err := h.Handle(context.Background(), testEvent(), patchConfig("never"), nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	h.HandleExit(&agent.Session{ID: spawner.lastRequest.ID, StartedAt: time.Now()})
+
+	if len(cache.pushed) != 0 {
+		t.Errorf("pushed = %v, want none", cache.pushed)
+	}
+	if len(prov.comments) != 2 || !strings.Contains(prov.comments[0], "Subject: [PATCH]") {
+		t.Errorf("comments = %v, want proposed patch", prov.comments)
+	}
+	if got := agentLog(t, logDir); !strings.Contains(got, "==> proposed patch") {
+		t.Errorf("agent log = %q, want proposed patch", got)
+	}
+}
+
+func TestHandleExit_PatchModePushFailureProposes(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockPatchRepo{ahead: 1, pushErr: errors.New("rejected")}
+	prov := &mockProvider{name: "gitlab"}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, t.TempDir(), "")
+
+	if err := h.Handle(context.Background(), testEvent(), patchConfig("always"), nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	h.HandleExit(&agent.Session{ID: spawner.lastRequest.ID, StartedAt: time.Now()})
+
+	if len(prov.comments) != 2 || !strings.Contains(prov.comments[0], "the push failed: rejected") {
+		t.Errorf("comments = %v, want proposal after failed push", prov.comments)
+	}
+}
+
+func TestHandleTimeout_UnknownAgentRemovesSessionWorktree(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+
+	cache := &mockRepoCache{}
+	h := NewAgentHandler(&mockSpawner{}, cache, &mockRegistry{}, "", "")
+
+	// e.g. a session whose run was lost to a server restart
+	h.HandleTimeout(&agent.Session{ID: "orphan", Repo: "owner/repo", StartedAt: time.Now()})
+
+	if len(cache.removed) != 1 || cache.removed[0] != "orphan" {
+		t.Errorf("removed worktrees = %v, want [orphan]", cache.removed)
+	}
+}
+
+func TestHandleFailure_CleansUpAndExplains(t *testing.T) {
+	logDir := t.TempDir()
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{}
+	prov := &mockProvider{name: "gitlab"}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, logDir, "")
+
+	if err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	agentID := spawner.lastRequest.ID
+
+	h.HandleFailure(&agent.Session{ID: agentID, Status: "failed", FailureReason: "no output for 10m0s"})
+
+	if spawner.captured[agentID] == "" {
+		t.Error("failed agent logs should be captured")
+	}
+	if len(cache.removed) != 1 || cache.removed[0] != agentID {
+		t.Errorf("removed worktrees = %v, want [%s]", cache.removed, agentID)
+	}
+	if got := agentLog(t, logDir); !strings.Contains(got, "==> worktree removed") {
+		t.Errorf("agent log = %q, want the worktree cleanup recorded", got)
+	}
+	if len(prov.comments) != 1 || !strings.Contains(prov.comments[0], "no output for 10m0s") {
+		t.Errorf("comments = %v, want one failure explanation", prov.comments)
+	}
+}
+
+type mockLogShipper struct {
+	source string
+	labels map[string]string
+	data   string
+}
+
+func (m *mockLogShipper) ShipReader(_ context.Context, source string, labels map[string]string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	m.source, m.labels, m.data = source, labels, string(data)
+	return err
+}
+
+func TestHandleExit_ShipsAgentLog(t *testing.T) {
+	spawner := &mockSpawner{}
+	reg := &mockRegistry{providers: map[string]provider.Provider{}}
+	shipper := &mockLogShipper{}
+
+	h := NewAgentHandler(spawner, &mockRepoCache{}, reg, t.TempDir(), "", WithLogShipper(shipper))
+
+	if err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	agentID := spawner.lastRequest.ID
+	spawner.output = map[string]string{agentID: "agent output\n"}
+
+	h.HandleExit(&agent.Session{ID: agentID, StartedAt: time.Now()})
+
+	if shipper.source != "agent" {
+		t.Errorf("source = %q, want %q", shipper.source, "agent")
+	}
+	if shipper.labels["agent_id"] != agentID || shipper.labels["repo"] != "owner/repo" || shipper.labels["mr"] != "1" {
+		t.Errorf("labels = %v", shipper.labels)
+	}
+	if !strings.Contains(shipper.data, "agent output") || !strings.Contains(shipper.data, "==> worktree removed") {
+		t.Errorf("shipped log = %q, want the finished log", shipper.data)
+	}
+}
+
+func TestHandleExit_DirectModeCleansUp(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockPatchRepo{ahead: 1}
+	prov := &mockProvider{name: "gitlab"}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, t.TempDir(), "")
+
+	if err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	agentID := spawner.lastRequest.ID
+
+	h.HandleExit(&agent.Session{ID: agentID, StartedAt: time.Now()})
+
+	if spawner.captured[agentID] == "" {
+		t.Error("exited agent logs should be captured")
+	}
+	if len(cache.committed) != 0 {
+		t.Errorf("committed = %v, want none in direct mode", cache.committed)
+	}
+	if len(cache.removed) != 1 {
+		t.Errorf("removed worktrees = %v, want 1 entry", cache.removed)
+	}
+	if len(prov.comments) != 1 || !strings.Contains(prov.comments[0], "finished") {
+		t.Errorf("comments = %v, want only the summary", prov.comments)
+	}
+}
+
+func TestHandle_IndexesOutcome(t *testing.T) {
+	tests := []struct {
+		name        string
+		end         func(h *AgentHandler, session *agent.Session)
+		session     agent.Session
+		wantOutcome string
+		wantReason  string
+	}{
+		{
+			name:        "exit",
+			end:         (*AgentHandler).HandleExit,
+			wantOutcome: logging.OutcomeSucceeded,
+		},
+		{
+			name:        "exit with failure",
+			end:         (*AgentHandler).HandleExit,
+			session:     agent.Session{FailureCategory: agent.FailureAuth},
+			wantOutcome: logging.OutcomeFailed,
+			wantReason:  "auth",
+		},
+		{
+			name:        "timeout",
+			end:         (*AgentHandler).HandleTimeout,
+			wantOutcome: logging.OutcomeTimedOut,
+		},
+		{
+			name:        "failure",
+			end:         (*AgentHandler).HandleFailure,
+			session:     agent.Session{FailureReason: "no output for 10m0s"},
+			wantOutcome: logging.OutcomeFailed,
+			wantReason:  "no output for 10m0s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics.Reset()
+			defer metrics.Reset()
+			logDir := t.TempDir()
+			idx := logging.NewIndex(logDir)
+			spawner := &mockSpawner{}
+			reg := &mockRegistry{providers: map[string]provider.Provider{}}
+			h := NewAgentHandler(spawner, &mockRepoCache{}, reg, logDir, "", WithLogIndex(idx))
+
+			if err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil); err != nil {
+				t.Fatalf("Handle() error: %v", err)
+			}
+			session := tt.session
+			session.ID = spawner.lastRequest.ID
+			session.StartedAt = time.Now()
+			tt.end(h, &session)
+
+			entries, err := idx.Query(logging.IndexQuery{AgentID: session.ID})
+			if err != nil || len(entries) != 1 {
+				t.Fatalf("Query() = %v, %v; want one entry", entries, err)
+			}
+			e := entries[0]
+			if e.Repo != "owner/repo" || e.MR != 1 {
+				t.Errorf("entry = %+v, want owner/repo MR 1", e)
+			}
+			if e.Outcome != tt.wantOutcome || e.Reason != tt.wantReason {
+				t.Errorf("outcome = %q, %q; want %q, %q", e.Outcome, e.Reason, tt.wantOutcome, tt.wantReason)
+			}
+
+			wantRuns := []metrics.LabeledCount{{
+				Labels: metrics.Labels{Provider: "gitlab", Repo: "owner/repo", EventType: "mr_comment", Outcome: tt.wantOutcome},
+				Count:  1,
+			}}
+			if got := metrics.Get().AgentRuns; !reflect.DeepEqual(got, wantRuns) {
+				t.Errorf("AgentRuns = %+v, want %+v", got, wantRuns)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/drewdunne/familiar/internal/logging"
+	"github.com/drewdunne/familiar/internal/prompt"
+)
+
+// auditEntry starts an audit entry about the agent's run.
+func auditEntry(kind, agentID string, run *agentRun, permission string) logging.AuditEntry {
+	evt := run.evt
+	return logging.AuditEntry{
+		Kind:          kind,
+		AgentID:       agentID,
+		Provider:      evt.Provider,
+		Repo:          evt.RepoOwner + "/" + evt.RepoName,
+		MR:            evt.MRNumber,
+		EventType:     string(evt.Type),
+		CorrelationID: evt.CorrelationID,
+		Permission:    permission,
+	}
+}
+
+// auditGrants returns the audit entries for the privileged permissions the
+// agent is granted, or nil without an audit log. A patch-mode agent has
+// no credentials, so only the server's push on its behalf is recorded.
+func (h *AgentHandler) auditGrants(l *launch, agentID string, run *agentRun) []logging.AuditEntry {
+	if h.audit == nil {
+		return nil
+	}
+	evt := l.evt
+	requestedBy := evt.CommentAuthor
+	if requestedBy == "" {
+		requestedBy = evt.Actor
+	}
+
+	var requested []string
+	var confidence *float64
+	if l.parsedIntent != nil {
+		for _, a := range l.parsedIntent.RequestedActions {
+			requested = append(requested, string(a))
+		}
+		confidence = &l.parsedIntent.Confidence
+	}
+
+	var entries []logging.AuditEntry
+	for _, g := range prompt.Grants(evt, l.cfg, l.parsedIntent) {
+		e := auditEntry(logging.AuditGrant, agentID, run, g.Permission)
+		e.Setting = g.Setting
+		e.Source = l.cfg.PermissionSource(string(evt.Type), g.Permission)
+		e.RequestedBy = requestedBy
+		e.RequestedActions = requested
+		e.IntentConfidence = confidence
+		if run.patch {
+			if g.Permission != "push_commits" || !run.pushAllowed {
+				continue
+			}
+			e.Detail = "pushed by Familiar in patch mode"
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// recordAudit appends e to the audit log, if any.
+func (h *AgentHandler) recordAudit(run *agentRun, e logging.AuditEntry) {
+	if h.audit == nil {
+		return
+	}
+	if err := h.audit.Record(e); err != nil {
+		run.evt.Logger().Warn("failed to record audit entry", "agent_id", e.AgentID, "permission", e.Permission, "error", err)
+	}
+}
+
+// auditAction records whether a granted privileged action was taken.
+func (h *AgentHandler) auditAction(agentID string, run *agentRun, permission string, occurred bool, detail string) {
+	e := auditEntry(logging.AuditAction, agentID, run, permission)
+	e.Occurred = &occurred
+	e.Detail = detail
+	h.recordAudit(run, e)
+}
+
+// auditMerge records whether an agent granted merge merged its MR, by
+// checking the MR's state once the agent finishes.
+func (h *AgentHandler) auditMerge(ctx context.Context, agentID string, run *agentRun) {
+	granted := slices.ContainsFunc(run.grants, func(e logging.AuditEntry) bool { return e.Permission == "merge" })
+	if h.audit == nil || !granted {
+		return
+	}
+	evt := run.evt
+	prov := h.registry.Get(evt.Provider)
+	if prov == nil {
+		return
+	}
+	mr, err := prov.GetMergeRequest(ctx, evt.RepoOwner, evt.RepoName, evt.MRNumber)
+	if err != nil || mr == nil {
+		evt.Logger().Warn("failed to check whether agent merged", "agent_id", agentID, "error", err)
+		return
+	}
+	h.auditAction(agentID, run, "merge", mr.State == "merged", fmt.Sprintf("MR state after the agent finished: %s", mr.State))
+}
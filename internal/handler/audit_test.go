@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drewdunne/familiar/internal/agent"
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/intent"
+	"github.com/drewdunne/familiar/internal/logging"
+	"github.com/drewdunne/familiar/internal/provider"
+)
+
+// readAudit returns the entries recorded in the audit log at path.
+func readAudit(t *testing.T, path string) []logging.AuditEntry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("opening audit log: %v", err)
+	}
+	defer f.Close()
+
+	var entries []logging.AuditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e logging.AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("audit line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestHandle_AuditsGrantsAndMerge(t *testing.T) {
+	spawner := &mockSpawner{}
+	prov := &mockProvider{name: "gitlab", mr: &provider.MergeRequest{State: "merged"}}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	h := NewAgentHandler(spawner, &mockRepoCache{}, reg, "", "", WithAuditLog(logging.NewAuditLog(path)))
+
+	cfg, err := config.MergeConfigs(
+		&config.Config{Permissions: config.ServerPermissionsConfig{Merge: "on_request", PushCommits: "never", Approve: "never"}},
+		&config.RepoConfig{Permissions: config.PermissionsConfig{PushCommits: "always"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	evt := testEvent()
+	evt.CommentAuthor = "alice"
+	parsed := &intent.ParsedIntent{RequestedActions: []intent.Action{intent.ActionMerge}, Confidence: 0.8}
+
+	if err := h.Handle(context.Background(), evt, cfg, parsed); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	agentID := spawner.lastRequest.ID
+	h.HandleExit(&agent.Session{ID: agentID})
+
+	entries := readAudit(t, path)
+	if len(entries) != 3 {
+		t.Fatalf("audit entries = %+v, want push and merge grants and the merge", entries)
+	}
+	push, merge, merged := entries[0], entries[1], entries[2]
+	if push.Kind != logging.AuditGrant || push.Permission != "push_commits" || push.Source != "repo config permissions.push_commits" {
+		t.Errorf("push grant = %+v", push)
+	}
+	if merge.Kind != logging.AuditGrant || merge.Permission != "merge" || merge.Setting != "on_request" ||
+		merge.Source != "server config permissions.merge" {
+		t.Errorf("merge grant = %+v", merge)
+	}
+	if merge.AgentID != agentID || merge.RequestedBy != "alice" || merge.Repo != "owner/repo" || merge.MR != 1 {
+		t.Errorf("merge grant = %+v, want it to name the agent, requester, and MR", merge)
+	}
+	if merge.IntentConfidence == nil || *merge.IntentConfidence != 0.8 || len(merge.RequestedActions) != 1 {
+		t.Errorf("merge grant intent = %v, %v, want the parsed intent", merge.IntentConfidence, merge.RequestedActions)
+	}
+	if merged.Kind != logging.AuditAction || merged.Permission != "merge" || merged.Occurred == nil || !*merged.Occurred {
+		t.Errorf("merge action = %+v, want it recorded as occurred", merged)
+	}
+}
+
+func TestHandleExit_AuditsPatchModePush(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockPatchRepo{ahead: 2}
+	prov := &mockProvider{name: "gitlab"}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	h := NewAgentHandler(spawner, cache, reg, "", "", WithAuditLog(logging.NewAuditLog(path)))
+
+	cfg := patchConfig("always")
+	cfg.Permissions.Merge = "always"
+	if err := h.Handle(context.Background(), testEvent(), cfg, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	h.HandleExit(&agent.Session{ID: spawner.lastRequest.ID})
+
+	// The agent has no credentials to merge with; only the server's push counts
+	entries := readAudit(t, path)
+	if len(entries) != 2 {
+		t.Fatalf("audit entries = %+v, want the push grant and the push", entries)
+	}
+	if grant := entries[0]; grant.Kind != logging.AuditGrant || grant.Permission != "push_commits" {
+		t.Errorf("grant = %+v, want push_commits", grant)
+	}
+	if push := entries[1]; push.Kind != logging.AuditAction || push.Occurred == nil || !*push.Occurred {
+		t.Errorf("push = %+v, want it recorded as occurred", push)
+	}
+}
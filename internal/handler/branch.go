@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drewdunne/familiar/internal/event"
+	"github.com/drewdunne/familiar/internal/provider"
+)
+
+// resolveBranch fills in an event that arrived without a source branch.
+// GitHub's issue_comment webhook omits a pull request's branches, so they
+// are looked up from the MR. When no MR branch applies, as for a mention
+// in an issue, DefaultBranch is set to the repo's default branch instead.
+// A failed MR lookup is returned rather than falling back, so an MR event
+// never runs on the default branch by accident. Returns evt itself when it
+// already has a source branch or there is no provider to ask.
+func resolveBranch(ctx context.Context, evt *event.Event, prov provider.Provider) (*event.Event, error) {
+	if evt.SourceBranch != "" || prov == nil {
+		return evt, nil
+	}
+	resolved := *evt
+
+	if evt.MRNumber > 0 && !evt.IsIssue {
+		mr, err := prov.GetMergeRequest(ctx, evt.RepoOwner, evt.RepoName, evt.MRNumber)
+		if err != nil {
+			return nil, fmt.Errorf("looking up merge request branch: %w", err)
+		}
+		if mr != nil && mr.SourceBranch != "" {
+			resolved.SourceBranch = mr.SourceBranch
+			resolved.TargetBranch = mr.TargetBranch
+			resolved.FromFork = mr.FromFork
+			if resolved.MRTitle == "" {
+				resolved.MRTitle = mr.Title
+				resolved.MRDescription = mr.Description
+			}
+			return &resolved, nil
+		}
+		evt.Logger().Info("merge request has no source branch, using the default branch")
+	}
+
+	repo, err := prov.GetRepository(ctx, evt.RepoOwner, evt.RepoName)
+	if err != nil {
+		return nil, fmt.Errorf("finding default branch: %w", err)
+	}
+	if repo == nil || repo.DefaultBranch == "" {
+		return nil, fmt.Errorf("finding default branch: %s/%s has none", evt.RepoOwner, evt.RepoName)
+	}
+	resolved.DefaultBranch = repo.DefaultBranch
+	return &resolved, nil
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/drewdunne/familiar/internal/agent"
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/provider"
+)
+
+func TestHandle_LooksUpMissingMRBranch(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{}
+	prov := &mockProvider{name: "gitlab", mr: &provider.MergeRequest{SourceBranch: "fix", TargetBranch: "main", Title: "Fix it"}}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, "", "")
+
+	evt := testEvent()
+	evt.SourceBranch, evt.TargetBranch = "", ""
+	if err := h.Handle(context.Background(), evt, &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	if cache.worktreeRef != "fix" {
+		t.Errorf("worktree ref = %q, want the MR's source branch", cache.worktreeRef)
+	}
+	if !strings.Contains(spawner.lastRequest.Prompt, "MR #1: fix → main") {
+		t.Errorf("prompt = %q, want the MR's branches", spawner.lastRequest.Prompt)
+	}
+	if evt.SourceBranch != "" {
+		t.Error("Handle() should not modify the caller's event")
+	}
+}
+
+func TestHandle_DefaultBranchWithoutMRBranch(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{}
+	prov := &mockProvider{name: "gitlab", repo: &provider.Repository{DefaultBranch: "trunk"}}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, "", "")
+
+	evt := testEvent()
+	evt.SourceBranch, evt.TargetBranch = "", ""
+	if err := h.Handle(context.Background(), evt, &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	if cache.worktreeRef != "trunk" {
+		t.Errorf("worktree ref = %q, want the default branch", cache.worktreeRef)
+	}
+	if !slices.Equal(cache.fetchedRefs, []string{"trunk"}) {
+		t.Errorf("fetched refs = %v, want only the default branch", cache.fetchedRefs)
+	}
+	if !strings.Contains(spawner.lastRequest.Prompt, "on the default branch, trunk") {
+		t.Errorf("prompt = %q, want it to explain the agent is on the default branch", spawner.lastRequest.Prompt)
+	}
+}
+
+func TestHandle_MRLookupFailureDoesNotUseDefaultBranch(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{}
+	prov := &mockProvider{
+		name:  "gitlab",
+		mrErr: errors.New("API rate limit exceeded"),
+		repo:  &provider.Repository{DefaultBranch: "trunk"},
+	}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, "", "")
+
+	evt := testEvent()
+	evt.SourceBranch, evt.TargetBranch = "", ""
+	if err := h.Handle(context.Background(), evt, &config.MergedConfig{}, nil); err == nil {
+		t.Fatal("Handle() should fail when the merge request lookup fails")
+	}
+	if len(spawner.requests) != 0 {
+		t.Error("agent should not spawn")
+	}
+	if cache.worktreeRef != "" {
+		t.Errorf("worktree created on %q, want none", cache.worktreeRef)
+	}
+}
+
+func TestHandle_IssueUsesDefaultBranch(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{}
+	// Issues are not merge requests; looking one up would fail
+	prov := &mockProvider{
+		name:  "github",
+		mrErr: errors.New("404 Not Found"),
+		repo:  &provider.Repository{DefaultBranch: "main"},
+	}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"github": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, "", "")
+
+	evt := testEvent()
+	evt.Provider = "github"
+	evt.SourceBranch, evt.TargetBranch = "", ""
+	evt.IsIssue = true
+	if err := h.Handle(context.Background(), evt, &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	if cache.worktreeRef != "main" {
+		t.Errorf("worktree ref = %q, want the default branch", cache.worktreeRef)
+	}
+}
+
+func TestHandle_DefaultBranchRequiresOne(t *testing.T) {
+	spawner := &mockSpawner{}
+	prov := &mockProvider{name: "gitlab", repo: &provider.Repository{}}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, &mockRepoCache{}, reg, "", "")
+
+	evt := testEvent()
+	evt.SourceBranch = ""
+	if err := h.Handle(context.Background(), evt, &config.MergedConfig{}, nil); err == nil {
+		t.Fatal("Handle() should fail when the repo has no default branch")
+	}
+	if len(spawner.requests) != 0 {
+		t.Error("agent should not spawn")
+	}
+}
+
+func TestHandleExit_PatchModeDoesNotPushDefaultBranch(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockPatchRepo{ahead: 1}
+	prov := &mockProvider{name: "gitlab", repo: &provider.Repository{DefaultBranch: "main"}}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, "", "")
+
+	evt := testEvent()
+	evt.SourceBranch = ""
+	if err := h.Handle(context.Background(), evt, patchConfig("always"), nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	h.HandleExit(&agent.Session{ID: spawner.lastRequest.ID})
+
+	if len(cache.pushed) != 0 {
+		t.Errorf("pushed %v, want the changes proposed instead", cache.pushed)
+	}
+	if len(prov.comments) == 0 || !strings.Contains(prov.comments[0], "no merge request branch") {
+		t.Errorf("comments = %v, want a proposed patch explaining why it wasn't pushed", prov.comments)
+	}
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/event"
+	"github.com/drewdunne/familiar/internal/intent"
+)
+
+// errSuperseded is why a queued agent is dropped after a newer event for
+// its MR superseded it.
+var errSuperseded = errors.New("superseded by a newer event")
+
+// pendingEvent is an event waiting for its MR's agents to finish.
+type pendingEvent struct {
+	evt          *event.Event
+	cfg          *config.MergedConfig
+	parsedIntent *intent.ParsedIntent
+}
+
+// mrRegistry tracks the agents working on each MR, by event.MRKey, and
+// the events queued behind them.
+type mrRegistry struct {
+	mu       sync.Mutex
+	agents   map[string]map[string]bool // IDs of agents started and not yet finished
+	handling map[string]int             // events being handled, which may start more
+	waiting  map[string][]pendingEvent
+}
+
+func newMRRegistry() *mrRegistry {
+	return &mrRegistry{
+		agents:   make(map[string]map[string]bool),
+		handling: make(map[string]int),
+		waiting:  make(map[string][]pendingEvent),
+	}
+}
+
+// begin decides by policy whether p may be handled now. If its MR is busy
+// it is queued or dropped and begin returns false. Otherwise the event
+// holds the MR until end; under supersede, the agents it displaces are
+// returned and forgotten.
+func (r *mrRegistry) begin(key, policy string, p pendingEvent) (bool, []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var running []string
+	for id := range r.agents[key] {
+		running = append(running, id)
+	}
+	sort.Strings(running)
+	if len(running) > 0 || r.handling[key] > 0 {
+		switch policy {
+		case config.BusySkip:
+			return false, running
+		case config.BusyQueue:
+			r.waiting[key] = append(r.waiting[key], p)
+			return false, running
+		case config.BusySupersede:
+			delete(r.agents, key)
+		default:
+			running = nil
+		}
+	}
+	r.handling[key]++
+	return true, running
+}
+
+// end releases an event's hold on its MR. Returns the next queued event,
+// now holding the MR, if the MR is no longer busy.
+func (r *mrRegistry) end(key string) *pendingEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.handling[key]--; r.handling[key] <= 0 {
+		delete(r.handling, key)
+	}
+	return r.next(key)
+}
+
+// add records an agent working on the MR.
+func (r *mrRegistry) add(key, agentID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.agents[key] == nil {
+		r.agents[key] = make(map[string]bool)
+	}
+	r.agents[key][agentID] = true
+}
+
+// has reports whether the agent is still recorded for the MR; superseded
+// agents are not.
+func (r *mrRegistry) has(key, agentID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.agents[key][agentID]
+}
+
+// done forgets a finished agent. Returns the next queued event, now
+// holding the MR, if the MR is no longer busy.
+func (r *mrRegistry) done(key, agentID string) *pendingEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.agents[key], agentID)
+	if len(r.agents[key]) == 0 {
+		delete(r.agents, key)
+	}
+	return r.next(key)
+}
+
+// next pops the MR's next queued event if nothing else holds the MR.
+// Requires r.mu.
+func (r *mrRegistry) next(key string) *pendingEvent {
+	queue := r.waiting[key]
+	if len(queue) == 0 || len(r.agents[key]) > 0 || r.handling[key] > 0 {
+		return nil
+	}
+	p := queue[0]
+	if len(queue) == 1 {
+		delete(r.waiting, key)
+	} else {
+		r.waiting[key] = queue[1:]
+	}
+	r.handling[key]++
+	return &p
+}
+
+// supersede has the spawner terminate the running agents a newer event
+// displaced, so they finish as failures through HandleFailure. Displaced
+// agents still queued are dropped when their turn comes.
+func (h *AgentHandler) supersede(ctx context.Context, evt *event.Event, agentIDs []string) {
+	for _, id := range agentIDs {
+		h.runsMu.Lock()
+		_, running := h.runs[id]
+		h.runsMu.Unlock()
+		if !running {
+			continue
+		}
+		evt.Logger().Info("stopping superseded agent", "agent_id", id)
+		if err := h.spawner.Terminate(ctx, id, errSuperseded.Error()); err != nil {
+			evt.Logger().Warn("failed to stop superseded agent", "agent_id", id, "error", err)
+		}
+	}
+}
+
+// dispatch handles events that were queued behind a busy MR, as long as
+// each leaves the MR free for the next.
+func (h *AgentHandler) dispatch(p *pendingEvent) {
+	for p != nil {
+		p.evt.Logger().Info("handling queued event", "event_type", p.evt.Type)
+		if err := h.handle(context.Background(), p.evt, p.cfg, p.parsedIntent); err != nil {
+			p.evt.Logger().Error("failed to handle queued event", "event_type", p.evt.Type, "error", err)
+		}
+		p = h.mrs.end(p.evt.MRKey())
+	}
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/agent"
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/event"
+	"github.com/drewdunne/familiar/internal/provider"
+)
+
+// laterEvent returns evt a second later, so its agents get new IDs.
+func laterEvent(evt *event.Event) *event.Event {
+	later := *evt
+	later.Timestamp = evt.Timestamp.Add(time.Second)
+	return &later
+}
+
+func TestHandle_BusyPolicy(t *testing.T) {
+	tests := []struct {
+		policy      string
+		wantSpawned int
+		wantStopped bool // the first agent is stopped by the second event
+	}{
+		{"", 2, false},
+		{config.BusyAllow, 2, false},
+		{config.BusySkip, 1, false},
+		{config.BusyQueue, 1, false},
+		{config.BusySupersede, 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			spawner := &mockSpawner{}
+			prov := &mockProvider{name: "gitlab"}
+			reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+			h := NewAgentHandler(spawner, &mockRepoCache{}, reg, "", "", WithBusyPolicy(tt.policy))
+			spawner.onFailure = h.HandleFailure
+
+			first := testEvent()
+			if err := h.Handle(context.Background(), first, &config.MergedConfig{}, nil); err != nil {
+				t.Fatalf("Handle() error: %v", err)
+			}
+			firstID := spawner.lastRequest.ID
+			if err := h.Handle(context.Background(), laterEvent(first), &config.MergedConfig{}, nil); err != nil {
+				t.Fatalf("Handle() error: %v", err)
+			}
+
+			if len(spawner.requests) != tt.wantSpawned {
+				t.Errorf("spawned %d agents, want %d", len(spawner.requests), tt.wantSpawned)
+			}
+			terminated := len(spawner.terminated) == 1 && spawner.terminated[0] == firstID
+			if terminated != tt.wantStopped {
+				t.Errorf("terminated = %v, want first agent terminated = %v", spawner.terminated, tt.wantStopped)
+			}
+			stopped := len(spawner.stopped) == 1 && spawner.stopped[0] == firstID
+			if stopped != tt.wantStopped {
+				t.Errorf("stopped = %v, want first agent stopped = %v", spawner.stopped, tt.wantStopped)
+			}
+			if tt.wantStopped && (len(prov.comments) != 1 || !strings.Contains(prov.comments[0], "superseded by a newer event")) {
+				t.Errorf("comments = %v, want the superseded agent's summary", prov.comments)
+			}
+		})
+	}
+}
+
+func TestHandle_BusyQueueRunsAfterAgentsFinish(t *testing.T) {
+	spawner := &mockSpawner{}
+	reg := &mockRegistry{providers: map[string]provider.Provider{}}
+
+	h := NewAgentHandler(spawner, &mockRepoCache{}, reg, "", "", WithBusyPolicy(config.BusyQueue))
+
+	first := testEvent()
+	second := laterEvent(first)
+	third := laterEvent(second)
+	for _, evt := range []*event.Event{first, second, third} {
+		if err := h.Handle(context.Background(), evt, &config.MergedConfig{}, nil); err != nil {
+			t.Fatalf("Handle() error: %v", err)
+		}
+	}
+
+	// Another MR isn't held up
+	other := testEvent()
+	other.MRNumber = 2
+	if err := h.Handle(context.Background(), other, &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	if len(spawner.requests) != 2 {
+		t.Fatalf("spawned %d agents before the first finished, want 2", len(spawner.requests))
+	}
+
+	// Queued events start one at a time, in order
+	running := spawner.requests[0].ID
+	for i, want := range []*event.Event{second, third} {
+		h.HandleExit(&agent.Session{ID: running})
+		running = spawner.lastRequest.ID
+		wantSuffix := fmt.Sprintf("-1-%d", want.Timestamp.Unix())
+		if !strings.HasSuffix(running, wantSuffix) {
+			t.Errorf("queued event %d started agent %s, want one ending in %s", i, running, wantSuffix)
+		}
+		if len(spawner.requests) != 3+i {
+			t.Errorf("spawned %d agents after queued event %d, want %d", len(spawner.requests), i, 3+i)
+		}
+	}
+}
+
+func TestHandle_BusySupersedeDropsQueuedAgent(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{}
+	queue := &mockQueue{}
+	reg := &mockRegistry{providers: map[string]provider.Provider{}}
+
+	h := NewAgentHandler(spawner, cache, reg, "", "", WithQueue(queue), WithBusyPolicy(config.BusySupersede))
+
+	first := testEvent()
+	for _, evt := range []*event.Event{first, laterEvent(first)} {
+		if err := h.Handle(context.Background(), evt, &config.MergedConfig{}, nil); err != nil {
+			t.Fatalf("Handle() error: %v", err)
+		}
+	}
+	if len(queue.enqueued) != 2 {
+		t.Fatalf("enqueued = %d, want 2", len(queue.enqueued))
+	}
+
+	if err := queue.spawnFns[0](context.Background(), queue.enqueued[0]); err == nil {
+		t.Error("superseded queued agent should not spawn")
+	}
+	if err := queue.spawnFns[1](context.Background(), queue.enqueued[1]); err != nil {
+		t.Fatalf("spawnFn error: %v", err)
+	}
+	if len(spawner.requests) != 1 || spawner.requests[0].ID != queue.enqueued[1].ID {
+		t.Errorf("spawned %v, want only the newer agent", spawner.requests)
+	}
+	if len(cache.removed) != 1 || cache.removed[0] != queue.enqueued[0].ID {
+		t.Errorf("removed worktrees = %v, want the superseded agent's", cache.removed)
+	}
+}
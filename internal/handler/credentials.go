@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/event"
+	"github.com/drewdunne/familiar/internal/intent"
+	"github.com/drewdunne/familiar/internal/prompt"
+	"github.com/drewdunne/familiar/internal/provider"
+)
+
+// ReadOnlyEnv gives agents credentials that can read and comment but not
+// push or merge. Optional; implemented by providers configured with a
+// read-only token.
+type ReadOnlyEnv interface {
+	// ReadOnlyAgentEnv returns nil when there is no read-only token.
+	ReadOnlyAgentEnv() map[string]string
+}
+
+// scopeCredentials keeps the provider's full token from agents granted no
+// permission (push, merge, approve, or dismiss reviews) that needs it, under
+// scoped credentials. It returns the read-only environment for the agent,
+// or, when the provider has none, cfg with patch mode forced on so the agent
+// runs without credentials.
+func (h *AgentHandler) scopeCredentials(evt *event.Event, prov provider.Provider, cfg *config.MergedConfig, parsedIntent *intent.ParsedIntent) (map[string]string, *config.MergedConfig) {
+	if h.credentials != config.CredentialsScoped || prov == nil || cfg != nil && cfg.AgentMode == config.AgentModePatch {
+		return nil, cfg
+	}
+	// Every grant is a write the read-only token cannot make
+	if len(prompt.Grants(evt, cfg, parsedIntent)) > 0 {
+		return nil, cfg
+	}
+
+	if ro, ok := prov.(ReadOnlyEnv); ok {
+		if env := ro.ReadOnlyAgentEnv(); env != nil {
+			evt.Logger().Info("agent has no write permissions, passing read-only credentials")
+			return env, cfg
+		}
+	}
+	evt.Logger().Info("agent has no write permissions and there is no read-only token, using patch mode")
+	return nil, withPatchMode(cfg)
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/provider"
+)
+
+// readOnlyProvider is a mockProvider with a read-only token.
+type readOnlyProvider struct {
+	*mockProvider
+	readOnlyEnv map[string]string
+}
+
+func (p *readOnlyProvider) ReadOnlyAgentEnv() map[string]string { return p.readOnlyEnv }
+
+func TestHandle_ScopedCredentials(t *testing.T) {
+	full := map[string]string{"GITLAB_TOKEN": "glpat-full"}
+	readOnly := map[string]string{"GITLAB_TOKEN": "glpat-read"}
+
+	tests := []struct {
+		name        string
+		mode        string
+		push, merge string
+		approve     string
+		readOnlyEnv map[string]string
+		wantToken   string // empty for no credentials
+		wantPatch   bool
+	}{
+		{"full mode", config.CredentialsFull, "never", "never", "never", readOnly, "glpat-full", false},
+		{"push allowed", config.CredentialsScoped, "always", "never", "never", readOnly, "glpat-full", false},
+		{"merge allowed", config.CredentialsScoped, "never", "always", "never", readOnly, "glpat-full", false},
+		{"approve allowed", config.CredentialsScoped, "never", "never", "always", readOnly, "glpat-full", false},
+		{"read-only token", config.CredentialsScoped, "never", "never", "never", readOnly, "glpat-read", false},
+		{"no read-only token", config.CredentialsScoped, "never", "never", "never", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spawner := &mockSpawner{}
+			prov := &readOnlyProvider{mockProvider: &mockProvider{name: "gitlab", agentEnv: full}, readOnlyEnv: tt.readOnlyEnv}
+			reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+			h := NewAgentHandler(spawner, &mockPatchRepo{}, reg, "", "", WithCredentials(tt.mode))
+
+			cfg := &config.MergedConfig{}
+			cfg.Permissions.PushCommits = tt.push
+			cfg.Permissions.Merge = tt.merge
+			cfg.Permissions.Approve = tt.approve
+			if err := h.Handle(context.Background(), testEvent(), cfg, nil); err != nil {
+				t.Fatalf("Handle() error: %v", err)
+			}
+
+			req := spawner.lastRequest
+			if got := req.Env["GITLAB_TOKEN"]; got != tt.wantToken {
+				t.Errorf("GITLAB_TOKEN = %q, want %q", got, tt.wantToken)
+			}
+			h.runsMu.Lock()
+			run := h.runs[req.ID]
+			h.runsMu.Unlock()
+			if run == nil || run.patch != tt.wantPatch {
+				t.Errorf("run = %+v, want patch = %v", run, tt.wantPatch)
+			}
+			if tt.wantPatch && !strings.Contains(req.Prompt, "You must NOT commit or push") {
+				t.Error("patch-mode agent should be told to leave its changes uncommitted")
+			}
+		})
+	}
+}
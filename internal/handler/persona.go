@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/drewdunne/familiar/internal/agent"
+	"github.com/drewdunne/familiar/internal/event"
+)
+
+// maxPersonaOutput bounds each persona's section of the combined comment.
+const maxPersonaOutput = 20000
+
+// personaGroup collects the results of agents started for one event with
+// different personas, so they can be reported in a single comment.
+type personaGroup struct {
+	evt *event.Event
+
+	mu      sync.Mutex
+	results []personaResult // in persona order
+	pending int
+}
+
+// personaResult is one persona agent's outcome.
+type personaResult struct {
+	persona string
+	agentID string
+	output  string // the agent's final response
+	err     error  // why there is no output
+	done    bool
+}
+
+func newPersonaGroup(evt *event.Event) *personaGroup {
+	return &personaGroup{evt: evt}
+}
+
+// add registers an agent that the group waits for.
+func (g *personaGroup) add(agentID, persona string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.results = append(g.results, personaResult{persona: persona, agentID: agentID})
+	g.pending++
+}
+
+// complete records an agent's outcome and reports whether it was the last
+// one outstanding. Repeated reports for the same agent are ignored.
+func (g *personaGroup) complete(agentID, output string, err error) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i := range g.results {
+		r := &g.results[i]
+		if r.agentID != agentID || r.done {
+			continue
+		}
+		r.output, r.err, r.done = output, err, true
+		g.pending--
+		return g.pending == 0
+	}
+	return false
+}
+
+// summary renders the combined comment.
+func (g *personaGroup) summary() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🧩 Familiar ran %d personas on this MR:\n", len(g.results))
+	for _, r := range g.results {
+		fmt.Fprintf(&b, "\n### %s\n\n", r.persona)
+		switch {
+		case r.err != nil:
+			fmt.Fprintf(&b, "⚠️ Agent `%s` produced no result: %v\n", r.agentID, r.err)
+		default:
+			output := strings.TrimSpace(r.output)
+			if len(output) > maxPersonaOutput {
+				output = output[:maxPersonaOutput] + "\n\n... (truncated; full output in the server logs)"
+			}
+			b.WriteString(output + "\n")
+		}
+	}
+	return b.String()
+}
+
+// personaDone records a persona agent's outcome and posts the combined
+// comment once every persona in the group has finished.
+func (h *AgentHandler) personaDone(ctx context.Context, group *personaGroup, agentID, output string, err error) {
+	if !group.complete(agentID, output, err) {
+		return
+	}
+	h.postComment(ctx, group.evt, agentID, group.summary())
+}
+
+// agentResult reads the agent's final response from its captured log.
+func (h *AgentHandler) agentResult(run *agentRun) (string, error) {
+	if run.logPath == "" {
+		return "", errors.New("no log file to read the result from")
+	}
+	data, err := os.ReadFile(run.logPath)
+	if err != nil {
+		return "", fmt.Errorf("reading agent log: %w", err)
+	}
+	return agent.ParseResult(data)
+}
+
+// personaSlug turns a persona name into an agent ID suffix, falling back to
+// its position when the name has no usable characters.
+func personaSlug(name string, index int) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			dash = false
+		case b.Len() > 0 && !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		return fmt.Sprintf("persona%d", index+1)
+	}
+	return slug
+}
+
+// reportPersona hands a finished persona agent's result to its group.
+func (h *AgentHandler) reportPersona(ctx context.Context, agentID string, run *agentRun) {
+	output, err := h.agentResult(run)
+	if err != nil {
+		run.evt.Logger().Warn("no result from persona agent", "agent_id", agentID, "error", err)
+	}
+	h.personaDone(ctx, run.group, agentID, output, err)
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/agent"
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/provider"
+)
+
+func personaConfig() *config.MergedConfig {
+	return &config.MergedConfig{Personas: []config.PersonaConfig{
+		{Name: "Security Reviewer", Prompt: "Look for vulnerabilities in MR {MR_NUMBER}."},
+		{Name: "Test Writer", Prompt: "Add missing tests."},
+		{Name: "Docs", Prompt: "Check the docs.", Events: []string{"mr_opened"}},
+	}}
+}
+
+func TestPersonaSlug(t *testing.T) {
+	tests := []struct {
+		name  string
+		index int
+		want  string
+	}{
+		{"security", 0, "security"},
+		{"Security Reviewer", 0, "security-reviewer"},
+		{"  Tests & Coverage!! ", 1, "tests-coverage"},
+		{"🔒", 2, "persona3"},
+	}
+
+	for _, tt := range tests {
+		if got := personaSlug(tt.name, tt.index); got != tt.want {
+			t.Errorf("personaSlug(%q, %d) = %q, want %q", tt.name, tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestHandle_PersonasSpawnSeparateAgents(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{}
+	reg := &mockRegistry{providers: map[string]provider.Provider{}}
+
+	h := NewAgentHandler(spawner, cache, reg, "", "")
+
+	if err := h.Handle(context.Background(), testEvent(), personaConfig(), nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	// The docs persona only runs for mr_opened
+	if len(spawner.requests) != 2 {
+		t.Fatalf("spawned %d agents, want 2", len(spawner.requests))
+	}
+	first, second := spawner.requests[0], spawner.requests[1]
+	if !strings.HasSuffix(first.ID, "-security-reviewer") || !strings.HasSuffix(second.ID, "-test-writer") {
+		t.Errorf("agent IDs = %q, %q, want persona suffixes", first.ID, second.ID)
+	}
+	if !strings.Contains(first.Prompt, "## Persona: Security Reviewer") ||
+		!strings.Contains(first.Prompt, "Look for vulnerabilities in MR 1.") {
+		t.Errorf("first prompt missing persona profile:\n%s", first.Prompt)
+	}
+	if first.Persona != "Security Reviewer" || first.MRNumber != 1 || first.EventType != "mr_comment" {
+		t.Errorf("first request labels: persona %q, MR %d, event %q", first.Persona, first.MRNumber, first.EventType)
+	}
+	if first.Interactive || second.Interactive {
+		t.Error("persona agents should not run interactively")
+	}
+}
+
+func TestHandleExit_PersonaResultsAggregated(t *testing.T) {
+	spawner := &mockSpawner{output: map[string]string{}}
+	cache := &mockRepoCache{}
+	prov := &mockProvider{name: "gitlab"}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, t.TempDir(), "")
+
+	if err := h.Handle(context.Background(), testEvent(), personaConfig(), nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	security, tests := spawner.requests[0].ID, spawner.requests[1].ID
+	spawner.output[security] = `{"type":"result","result":"No vulnerabilities found."}` + "\n"
+
+	h.HandleExit(&agent.Session{ID: security, StartedAt: time.Now()})
+	if len(prov.comments) != 0 {
+		t.Fatalf("comments = %v, want none until every persona finishes", prov.comments)
+	}
+
+	h.HandleTimeout(&agent.Session{ID: tests, StartedAt: time.Now().Add(-30 * time.Minute)})
+
+	if len(prov.comments) != 1 {
+		t.Fatalf("comments = %v, want one combined comment", prov.comments)
+	}
+	comment := prov.comments[0]
+	for _, want := range []string{
+		"ran 2 personas",
+		"### Security Reviewer\n\nNo vulnerabilities found.",
+		"### Test Writer",
+		"timed out after 30m",
+	} {
+		if !strings.Contains(comment, want) {
+			t.Errorf("comment missing %q:\n%s", want, comment)
+		}
+	}
+	if len(cache.removed) != 2 {
+		t.Errorf("removed worktrees = %v, want 2", cache.removed)
+	}
+}
+
+func TestHandle_PersonaStartFailureReported(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockRepoCache{}
+	prov := &mockProvider{name: "gitlab"}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, "", "", WithQueue(&mockQueue{err: agent.ErrQueueFull}))
+
+	if err := h.Handle(context.Background(), testEvent(), personaConfig(), nil); err == nil {
+		t.Fatal("Handle() should fail when no persona can start")
+	}
+	if len(prov.comments) != 1 || strings.Count(prov.comments[0], "produced no result") != 2 {
+		t.Errorf("comments = %v, want one comment reporting both failures", prov.comments)
+	}
+}
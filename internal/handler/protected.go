@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/logging"
+	"github.com/drewdunne/familiar/internal/metrics"
+)
+
+// checkProtected returns the protected paths the agent changed, alerting on
+// them: it logs an error, counts the change, records it in the audit log,
+// and comments on the MR. Outside patch mode the agent could push and then
+// tidy its worktree, so the MR branch is also fetched from the remote and
+// checked. Returns false if some of the agent's changes couldn't be listed;
+// those that could are still checked. The worktree must still exist.
+func (h *AgentHandler) checkProtected(ctx context.Context, agentID string, run *agentRun) ([]string, bool) {
+	if len(run.protected) == 0 {
+		return nil, true
+	}
+	files, ok := h.changedFiles(ctx, agentID, run)
+	if !run.patch {
+		pushed, pushedOK := h.pushedFiles(ctx, agentID, run)
+		files = append(files, pushed...)
+		ok = ok && pushedOK
+	}
+	slices.Sort(files)
+	changed := (&config.MergedConfig{ProtectedPaths: run.protected}).ProtectedFiles(slices.Compact(files))
+	if len(changed) == 0 {
+		return nil, ok
+	}
+
+	evt := run.evt
+	evt.Logger().Error("agent changed protected paths", "agent_id", agentID, "paths", strings.Join(changed, ", "))
+	metrics.ProtectedPathChanged()
+	e := auditEntry(logging.AuditViolation, agentID, run, "")
+	e.Detail = "changed protected paths: " + strings.Join(changed, ", ")
+	h.recordAudit(run, e)
+
+	body := fmt.Sprintf("🚨 Familiar agent `%s` changed protected paths, so its run failed: %s.", agentID, formatFiles(changed))
+	if run.patch {
+		body += " Its changes were not committed."
+	} else {
+		body += " Review the branch: any of these changes it pushed are still there."
+	}
+	h.postComment(ctx, evt, agentID, body)
+	return changed, ok
+}
+
+// pushedFiles lists the files changed on the MR's source branch, as fetched
+// from the remote, since the agent started. Commits others pushed to the
+// branch meanwhile are included. Returns true with nothing when there is no
+// branch in this repo the agent could have pushed to.
+func (h *AgentHandler) pushedFiles(ctx context.Context, agentID string, run *agentRun) ([]string, bool) {
+	evt := run.evt
+	if evt.SourceBranch == "" || evt.FromFork || run.base == "" {
+		return nil, true
+	}
+	lister, ok := h.repoCache.(RemoteChangeLister)
+	if !ok {
+		return nil, true
+	}
+	files, err := lister.RemoteChangedFiles(ctx, evt.RepoOwner, evt.RepoName, agentID, run.base, evt.SourceBranch)
+	if err != nil {
+		evt.Logger().Warn("failed to list changes pushed by agent", "agent_id", agentID, "error", err)
+		return nil, false
+	}
+	return files, true
+}
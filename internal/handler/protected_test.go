@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/drewdunne/familiar/internal/agent"
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/logging"
+	"github.com/drewdunne/familiar/internal/metrics"
+	"github.com/drewdunne/familiar/internal/provider"
+)
+
+// mockListingPatchRepo is a patch-mode repo cache that reports the agent's
+// changed files.
+type mockListingPatchRepo struct {
+	mockPatchRepo
+	files   []string
+	listErr error
+}
+
+func (m *mockListingPatchRepo) ChangedFiles(_ context.Context, _, _, _, _ string) ([]string, error) {
+	return m.files, m.listErr
+}
+
+func TestHandleExit_ProtectedPathFailsRun(t *testing.T) {
+	metrics.Reset()
+	spawner := &mockSpawner{}
+	cache := &mockChangeLister{files: []string{".github/workflows/ci.yml", "main.go"}}
+	prov := &mockProvider{name: "gitlab"}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	h := NewAgentHandler(spawner, cache, reg, "", "", WithAuditLog(logging.NewAuditLog(path)))
+
+	cfg := &config.MergedConfig{ProtectedPaths: []string{".github/workflows/**"}}
+	if err := h.Handle(context.Background(), testEvent(), cfg, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	agentID := spawner.lastRequest.ID
+	h.HandleExit(&agent.Session{ID: agentID})
+
+	if len(prov.comments) != 2 {
+		t.Fatalf("comments = %v, want the alert and the summary", prov.comments)
+	}
+	if alert := prov.comments[0]; !strings.Contains(alert, "🚨") || !strings.Contains(alert, "`.github/workflows/ci.yml`") ||
+		!strings.Contains(alert, "any of these changes it pushed are still there") {
+		t.Errorf("alert = %q, want it to name the protected file and warn about pushed changes", alert)
+	}
+	if summary := prov.comments[1]; !strings.Contains(summary, "failed: changed protected paths: .github/workflows/ci.yml") {
+		t.Errorf("summary = %q, want the run reported as failed", summary)
+	}
+	if got := metrics.Get().ProtectedPathChanges; got != 1 {
+		t.Errorf("ProtectedPathChanges = %d, want 1", got)
+	}
+	entries := readAudit(t, path)
+	if len(entries) != 1 || entries[0].Kind != logging.AuditViolation || entries[0].AgentID != agentID {
+		t.Errorf("audit entries = %+v, want the violation", entries)
+	}
+}
+
+// mockRemoteLister is a repo cache that also lists what the agent pushed.
+type mockRemoteLister struct {
+	mockChangeLister
+	pushed    []string
+	pushedErr error
+	branches  []string
+}
+
+func (m *mockRemoteLister) RemoteChangedFiles(_ context.Context, _, _, _, _, branch string) ([]string, error) {
+	m.branches = append(m.branches, branch)
+	return m.pushed, m.pushedErr
+}
+
+func TestHandleExit_ProtectedPathPushedThenReverted(t *testing.T) {
+	metrics.Reset()
+	spawner := &mockSpawner{}
+	// The worktree is clean again, but the branch on the remote has the change
+	cache := &mockRemoteLister{pushed: []string{".github/workflows/ci.yml"}}
+	prov := &mockProvider{name: "gitlab"}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, "", "")
+
+	cfg := &config.MergedConfig{ProtectedPaths: []string{".github/workflows/**"}}
+	if err := h.Handle(context.Background(), testEvent(), cfg, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	h.HandleExit(&agent.Session{ID: spawner.lastRequest.ID})
+
+	if !slices.Equal(cache.branches, []string{testEvent().SourceBranch}) {
+		t.Errorf("fetched branches = %v, want the MR's source branch", cache.branches)
+	}
+	if len(prov.comments) != 2 || !strings.Contains(prov.comments[1], "failed: changed protected paths: .github/workflows/ci.yml") {
+		t.Errorf("comments = %v, want the alert and a failed summary", prov.comments)
+	}
+	if got := metrics.Get().ProtectedPathChanges; got != 1 {
+		t.Errorf("ProtectedPathChanges = %d, want 1", got)
+	}
+}
+
+func TestHandleExit_ProtectedPathSkipsRemoteInPatchMode(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockRemotePatchRepo{}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": &mockProvider{name: "gitlab"}}}
+	h := NewAgentHandler(spawner, cache, reg, "", "")
+
+	cfg := patchConfig("never")
+	cfg.ProtectedPaths = []string{"deploy/**"}
+	if err := h.Handle(context.Background(), testEvent(), cfg, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	h.HandleExit(&agent.Session{ID: spawner.lastRequest.ID})
+
+	// Patch-mode agents have no credentials to push with
+	if len(cache.branches) != 0 {
+		t.Errorf("fetched branches = %v, want none", cache.branches)
+	}
+}
+
+// mockRemotePatchRepo is a patch-mode repo cache that could also list
+// pushed changes.
+type mockRemotePatchRepo struct {
+	mockListingPatchRepo
+	branches []string
+}
+
+func (m *mockRemotePatchRepo) RemoteChangedFiles(_ context.Context, _, _, _, _, branch string) ([]string, error) {
+	m.branches = append(m.branches, branch)
+	return nil, nil
+}
+
+func TestHandleExit_ProtectedPathBlocksPatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		files         []string
+		listErr       error
+		wantCommitted bool
+	}{
+		{"unprotected changes", []string{"main.go"}, nil, true},
+		{"protected change", []string{"deploy/prod.yaml", "main.go"}, nil, false},
+		{"changes not listed", nil, errors.New("worktree gone"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spawner := &mockSpawner{}
+			cache := &mockListingPatchRepo{mockPatchRepo: mockPatchRepo{ahead: 1}, files: tt.files, listErr: tt.listErr}
+			prov := &mockProvider{name: "gitlab"}
+			reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+			h := NewAgentHandler(spawner, cache, reg, "", "")
+
+			cfg := patchConfig("always")
+			cfg.ProtectedPaths = []string{"deploy/**"}
+			if err := h.Handle(context.Background(), testEvent(), cfg, nil); err != nil {
+				t.Fatalf("Handle() error: %v", err)
+			}
+			h.HandleExit(&agent.Session{ID: spawner.lastRequest.ID})
+
+			if committed := len(cache.committed) > 0; committed != tt.wantCommitted {
+				t.Errorf("committed = %v, want %v", committed, tt.wantCommitted)
+			}
+			if !tt.wantCommitted && len(cache.pushed) > 0 {
+				t.Errorf("pushed %v, want nothing pushed", cache.pushed)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/agent"
+	"github.com/drewdunne/familiar/internal/config"
+)
+
+// maxSummaryFiles bounds how many changed files a summary comment lists.
+const maxSummaryFiles = 20
+
+// completion describes how an agent's run ended, for its summary comment.
+type completion struct {
+	emoji     string
+	outcome   string // completes "Familiar agent `<id>` ..."
+	failed    bool
+	discarded bool // the worktree's unpushed changes were thrown away
+}
+
+// exitCompletion describes an agent whose container exited on its own.
+func exitCompletion(session *agent.Session, run *agentRun) completion {
+	if session.FailureCategory == agent.FailureNone {
+		return completion{emoji: "✅", outcome: "finished"}
+	}
+	reason := session.FailureCategory.Description()
+	if reason == "" {
+		reason = session.FailureReason
+	}
+	// Patch mode commits what a failed agent left behind, so nothing is lost
+	return completion{emoji: "⚠️", outcome: "failed: " + reason, failed: true, discarded: !run.patch}
+}
+
+// postSummary comments on the MR with how the agent's run ended, how long
+// it took, the files it changed, and where its full log is, as
+// agents.summary allows. The worktree must still exist.
+func (h *AgentHandler) postSummary(ctx context.Context, session *agent.Session, run *agentRun, c completion) {
+	switch h.summary {
+	case config.SummaryNever:
+		return
+	case config.SummaryFailures:
+		if !c.failed {
+			return
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s Familiar agent `%s` %s.", c.emoji, session.ID, c.outcome)
+	if c.discarded {
+		b.WriteString(" Any changes it did not push were discarded.")
+	}
+	b.WriteString("\n")
+
+	if !session.StartedAt.IsZero() {
+		fmt.Fprintf(&b, "\n- **Duration:** %s", time.Since(session.StartedAt).Round(time.Second))
+	}
+	if files, ok := h.changedFiles(ctx, session.ID, run); ok {
+		fmt.Fprintf(&b, "\n- **Files changed:** %s", formatFiles(files))
+	}
+	if run.logPath != "" {
+		fmt.Fprintf(&b, "\n- **Log:** `%s` on the server; `familiar logs tail %s` shows the end of it",
+			h.hostLogPath(run.logPath), session.ID)
+	}
+	h.postComment(ctx, run.evt, session.ID, b.String())
+}
+
+// changedFiles lists the files the agent changed in its worktree. Returns
+// false if the repo cache can't list them.
+func (h *AgentHandler) changedFiles(ctx context.Context, agentID string, run *agentRun) ([]string, bool) {
+	lister, ok := h.repoCache.(ChangeLister)
+	if !ok || run.base == "" {
+		return nil, false
+	}
+	files, err := lister.ChangedFiles(ctx, run.evt.RepoOwner, run.evt.RepoName, agentID, run.base)
+	if err != nil {
+		run.evt.Logger().Warn("failed to list agent changes", "agent_id", agentID, "error", err)
+		return nil, false
+	}
+	return files, true
+}
+
+// formatFiles renders files as an inline list, eliding past maxSummaryFiles.
+func formatFiles(files []string) string {
+	if len(files) == 0 {
+		return "none"
+	}
+	shown := files
+	if len(shown) > maxSummaryFiles {
+		shown = shown[:maxSummaryFiles]
+	}
+	quoted := make([]string, len(shown))
+	for i, f := range shown {
+		quoted[i] = "`" + f + "`"
+	}
+	list := strings.Join(quoted, ", ")
+	if more := len(files) - len(shown); more > 0 {
+		list += fmt.Sprintf(" and %d more", more)
+	}
+	return list
+}
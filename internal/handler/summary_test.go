@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/agent"
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/provider"
+)
+
+// mockChangeLister is a repo cache that reports the agent's changed files.
+type mockChangeLister struct {
+	mockRepoCache
+	files []string
+	err   error
+	bases []string
+}
+
+func (m *mockChangeLister) ChangedFiles(_ context.Context, _, _, _, base string) ([]string, error) {
+	m.bases = append(m.bases, base)
+	return m.files, m.err
+}
+
+func TestHandleExit_PostsSummary(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockChangeLister{files: []string{"main.go", "README.md"}}
+	prov := &mockProvider{name: "gitlab"}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	logDir := t.TempDir()
+	h := NewAgentHandler(spawner, cache, reg, logDir, "/srv/familiar/logs")
+
+	if err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	agentID := spawner.lastRequest.ID
+
+	h.HandleExit(&agent.Session{ID: agentID, StartedAt: time.Now().Add(-90 * time.Second)})
+
+	if len(prov.comments) != 1 {
+		t.Fatalf("comments = %v, want the summary", prov.comments)
+	}
+	comment := prov.comments[0]
+	for _, want := range []string{
+		"✅ Familiar agent `" + agentID + "` finished.",
+		"**Duration:** 1m30s",
+		"**Files changed:** `main.go`, `README.md`",
+		"`/srv/familiar/logs/owner/repo/1/",
+		"`familiar logs tail " + agentID + "`",
+	} {
+		if !strings.Contains(comment, want) {
+			t.Errorf("summary = %q, want it to contain %q", comment, want)
+		}
+	}
+	if strings.Contains(comment, "discarded") {
+		t.Errorf("summary = %q, should not mention discarded changes after success", comment)
+	}
+	if len(cache.bases) != 1 || cache.bases[0] != "feature" {
+		t.Errorf("changes listed since %v, want [feature]", cache.bases)
+	}
+}
+
+func TestHandleExit_SummaryExplainsFailure(t *testing.T) {
+	spawner := &mockSpawner{}
+	cache := &mockChangeLister{err: fmt.Errorf("worktree gone")}
+	prov := &mockProvider{name: "gitlab"}
+	reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+	h := NewAgentHandler(spawner, cache, reg, "", "")
+
+	if err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	agentID := spawner.lastRequest.ID
+
+	h.HandleExit(&agent.Session{ID: agentID, FailureCategory: agent.FailureOOMKilled})
+
+	if len(prov.comments) != 1 {
+		t.Fatalf("comments = %v, want the summary", prov.comments)
+	}
+	comment := prov.comments[0]
+	if !strings.Contains(comment, "failed: the agent container ran out of memory. Any changes it did not push were discarded.") {
+		t.Errorf("summary = %q, want the failure explained", comment)
+	}
+	// Unknown start time, unlistable changes, and no log are left out
+	for _, unwanted := range []string{"Duration", "Files changed", "Log"} {
+		if strings.Contains(comment, unwanted) {
+			t.Errorf("summary = %q, should not contain %q", comment, unwanted)
+		}
+	}
+}
+
+func TestPostSummary_Modes(t *testing.T) {
+	tests := []struct {
+		mode        string
+		failed      bool
+		wantComment bool
+	}{
+		{"", false, true},
+		{config.SummaryAlways, false, true},
+		{config.SummaryAlways, true, true},
+		{config.SummaryFailures, false, false},
+		{config.SummaryFailures, true, true},
+		{config.SummaryNever, false, false},
+		{config.SummaryNever, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/failed=%v", tt.mode, tt.failed), func(t *testing.T) {
+			spawner := &mockSpawner{}
+			prov := &mockProvider{name: "gitlab"}
+			reg := &mockRegistry{providers: map[string]provider.Provider{"gitlab": prov}}
+
+			h := NewAgentHandler(spawner, &mockRepoCache{}, reg, "", "", WithSummary(tt.mode))
+
+			if err := h.Handle(context.Background(), testEvent(), &config.MergedConfig{}, nil); err != nil {
+				t.Fatalf("Handle() error: %v", err)
+			}
+			session := &agent.Session{ID: spawner.lastRequest.ID}
+			if tt.failed {
+				session.FailureCategory = agent.FailureTask
+			}
+			h.HandleExit(session)
+
+			if gotComment := len(prov.comments) == 1; gotComment != tt.wantComment {
+				t.Errorf("comments = %v, want summary = %v", prov.comments, tt.wantComment)
+			}
+		})
+	}
+}
+
+func TestFormatFiles(t *testing.T) {
+	many := make([]string, maxSummaryFiles+3)
+	for i := range many {
+		many[i] = fmt.Sprintf("f%d", i)
+	}
+
+	tests := []struct {
+		name  string
+		files []string
+		want  string
+	}{
+		{"none", nil, "none"},
+		{"one", []string{"a.go"}, "`a.go`"},
+		{"several", []string{"a.go", "b/c.go"}, "`a.go`, `b/c.go`"},
+		{"elided", many, "`f19` and 3 more"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatFiles(tt.files)
+			if !strings.HasSuffix(got, tt.want) {
+				t.Errorf("formatFiles() = %q, want it to end with %q", got, tt.want)
+			}
+		})
+	}
+}
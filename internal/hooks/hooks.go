@@ -0,0 +1,94 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// Phase identifies when a hook runs relative to the agent.
+type Phase string
+
+const (
+	PhasePreAgent  Phase = "pre_agent"
+	PhasePostAgent Phase = "post_agent"
+)
+
+// DefaultTimeout bounds each hook command when no timeout is configured.
+const DefaultTimeout = 5 * time.Minute
+
+// waitDelay is how long to wait for output after a timed-out command is killed.
+const waitDelay = time.Second
+
+// Runner runs configured hook commands in an agent's worktree.
+type Runner struct {
+	Pre     []string
+	Post    []string
+	Timeout time.Duration // Per command; 0 uses DefaultTimeout
+}
+
+// Commands returns the hook commands for the phase.
+func (r *Runner) Commands(phase Phase) []string {
+	if r == nil {
+		return nil
+	}
+	if phase == PhasePreAgent {
+		return r.Pre
+	}
+	return r.Post
+}
+
+// inheritedEnv names the only server environment variables hooks see.
+// Hook commands run code from the MR, so the server's secrets stay out.
+var inheritedEnv = []string{"PATH", "HOME"}
+
+// Run executes the phase's commands in order with sh -c in dir, writing a
+// header and the combined output of each to out. Commands see only PATH,
+// HOME, FAMILIAR_HOOK_PHASE, and env. It stops at the first failing
+// command and returns its error.
+func (r *Runner) Run(ctx context.Context, phase Phase, dir string, env map[string]string, out io.Writer) error {
+	timeout := DefaultTimeout
+	if r != nil && r.Timeout > 0 {
+		timeout = r.Timeout
+	}
+
+	var environ []string
+	for _, name := range inheritedEnv {
+		if v, ok := os.LookupEnv(name); ok {
+			environ = append(environ, name+"="+v)
+		}
+	}
+	environ = append(environ, "FAMILIAR_HOOK_PHASE="+string(phase))
+	for k, v := range env {
+		environ = append(environ, k+"="+v)
+	}
+
+	for _, command := range r.Commands(phase) {
+		fmt.Fprintf(out, "==> [%s] %s\n", phase, command)
+
+		cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+		cmd := exec.CommandContext(cmdCtx, "sh", "-c", command)
+		cmd.Dir = dir
+		cmd.Env = environ
+		var output bytes.Buffer
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		cmd.WaitDelay = waitDelay // don't hang on children still holding the output pipe
+		err := cmd.Run()
+		cancel()
+
+		out.Write(output.Bytes())
+		if err != nil {
+			if cmdCtx.Err() == context.DeadlineExceeded {
+				err = fmt.Errorf("timed out after %s", timeout)
+			}
+			fmt.Fprintf(out, "==> [%s] failed: %v\n", phase, err)
+			return fmt.Errorf("%s hook %q: %w", phase, command, err)
+		}
+	}
+	return nil
+}
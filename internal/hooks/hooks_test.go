@@ -0,0 +1,98 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunner_Run(t *testing.T) {
+	dir := t.TempDir()
+	r := &Runner{
+		Pre: []string{
+			"echo installing > pre.txt",
+			`echo "agent=$FAMILIAR_AGENT_ID phase=$FAMILIAR_HOOK_PHASE"`,
+		},
+	}
+
+	var out bytes.Buffer
+	err := r.Run(context.Background(), PhasePreAgent, dir, map[string]string{"FAMILIAR_AGENT_ID": "agent-1"}, &out)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// Commands run in the worktree
+	if _, err := os.Stat(filepath.Join(dir, "pre.txt")); err != nil {
+		t.Errorf("hook should run in dir: %v", err)
+	}
+
+	for _, want := range []string{
+		"==> [pre_agent] echo installing > pre.txt",
+		"agent=agent-1 phase=pre_agent",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output = %q, want %q", out.String(), want)
+		}
+	}
+}
+
+func TestRunner_Run_WithholdsServerEnv(t *testing.T) {
+	t.Setenv("GITLAB_TOKEN", "glpat-server-token")
+	t.Setenv("ANTHROPIC_API_KEY", "sk-server-key")
+	r := &Runner{Pre: []string{`echo "token=$GITLAB_TOKEN key=$ANTHROPIC_API_KEY"`, "command -v sh"}}
+
+	var out bytes.Buffer
+	if err := r.Run(context.Background(), PhasePreAgent, t.TempDir(), nil, &out); err != nil {
+		t.Fatalf("Run() error = %v; hooks should still find commands on PATH", err)
+	}
+	if strings.Contains(out.String(), "glpat-server-token") || strings.Contains(out.String(), "sk-server-key") {
+		t.Errorf("output = %q, hooks should not see the server's secrets", out.String())
+	}
+	if !strings.Contains(out.String(), "token= key=") {
+		t.Errorf("output = %q, want the secrets unset", out.String())
+	}
+}
+
+func TestRunner_Run_StopsAtFailure(t *testing.T) {
+	r := &Runner{Post: []string{"echo lint; exit 3", "echo never"}}
+
+	var out bytes.Buffer
+	err := r.Run(context.Background(), PhasePostAgent, t.TempDir(), nil, &out)
+	if err == nil {
+		t.Fatal("Run() should return the failing hook's error")
+	}
+	if !strings.Contains(err.Error(), `post_agent hook "echo lint; exit 3"`) {
+		t.Errorf("error = %v, want hook named", err)
+	}
+	if strings.Contains(out.String(), "never") {
+		t.Error("commands after a failure should not run")
+	}
+	if !strings.Contains(out.String(), "==> [post_agent] failed") {
+		t.Errorf("output = %q, want failure recorded", out.String())
+	}
+}
+
+func TestRunner_Run_Timeout(t *testing.T) {
+	r := &Runner{Pre: []string{"sleep 5"}, Timeout: 50 * time.Millisecond}
+
+	var out bytes.Buffer
+	err := r.Run(context.Background(), PhasePreAgent, t.TempDir(), nil, &out)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Run() error = %v, want timeout", err)
+	}
+}
+
+func TestRunner_NilHasNoCommands(t *testing.T) {
+	var r *Runner
+	if cmds := r.Commands(PhasePreAgent); cmds != nil {
+		t.Errorf("Commands() = %v, want nil", cmds)
+	}
+	var out bytes.Buffer
+	if err := r.Run(context.Background(), PhasePostAgent, t.TempDir(), nil, &out); err != nil {
+		t.Errorf("Run() error = %v, want nil", err)
+	}
+}
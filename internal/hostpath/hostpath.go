@@ -0,0 +1,110 @@
+// Package hostpath translates host paths into the form the Docker daemon
+// expects for bind mounts, covering Docker Desktop on Windows and macOS.
+package hostpath
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Style names the host platform behind the Docker daemon.
+type Style string
+
+const (
+	StyleLinux   Style = "linux"   // Native daemon; paths used as-is
+	StyleWindows Style = "windows" // Docker Desktop on Windows
+	StyleMacOS   Style = "macos"   // Docker Desktop on macOS
+)
+
+// DefaultWindowsPrefix is where Docker Desktop exposes Windows drives to the
+// daemon's VM. WSL2 backends may need "/run/desktop/mnt/host" instead.
+const DefaultWindowsPrefix = "/host_mnt"
+
+// macSharedRoots are the directories Docker Desktop shares by default.
+var macSharedRoots = []string{"/Users", "/Volumes", "/private", "/tmp", "/var/folders"}
+
+var (
+	drivePath  = regexp.MustCompile(`^([A-Za-z]):[\\/]?(.*)$`)
+	posixDrive = regexp.MustCompile(`^/([A-Za-z])(/.*)?$`) // Git Bash / MSYS style: /c/Users/...
+)
+
+// Translator converts configured host paths to daemon bind-mount sources.
+type Translator struct {
+	Style  Style
+	Prefix string // Windows drive mount prefix; empty uses DefaultWindowsPrefix
+}
+
+// ParseStyle validates a configured style. Empty means linux.
+func ParseStyle(s string) (Style, error) {
+	switch Style(strings.ToLower(s)) {
+	case "", StyleLinux:
+		return StyleLinux, nil
+	case StyleWindows:
+		return StyleWindows, nil
+	case StyleMacOS, "darwin":
+		return StyleMacOS, nil
+	default:
+		return "", fmt.Errorf("unknown host path style %q (want linux, windows, or macos)", s)
+	}
+}
+
+// Translate returns p in the form the daemon expects. Empty paths pass
+// through unchanged.
+func (t Translator) Translate(p string) (string, error) {
+	if p == "" || t.Style != StyleWindows {
+		if p != "" && !strings.HasPrefix(p, "/") {
+			return "", fmt.Errorf("host path %q must be absolute", p)
+		}
+		return p, nil
+	}
+
+	prefix := t.Prefix
+	if prefix == "" {
+		prefix = DefaultWindowsPrefix
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	// Already translated
+	if strings.HasPrefix(p, prefix+"/") {
+		return p, nil
+	}
+
+	var drive, rest string
+	if m := drivePath.FindStringSubmatch(p); m != nil {
+		drive, rest = m[1], m[2]
+	} else if m := posixDrive.FindStringSubmatch(p); m != nil {
+		drive, rest = m[1], m[2]
+	} else {
+		return "", fmt.Errorf("host path %q is not an absolute Windows path (e.g. C:\\Users\\me\\cache)", p)
+	}
+
+	rest = strings.ReplaceAll(rest, `\`, "/")
+	return path.Clean(prefix + "/" + strings.ToLower(drive) + "/" + strings.TrimPrefix(rest, "/")), nil
+}
+
+// Warnings returns platform caveats for a translated host path.
+func (t Translator) Warnings(p string) []string {
+	if p == "" || t.Style != StyleMacOS {
+		return nil
+	}
+
+	var warnings []string
+	shared := false
+	for _, root := range macSharedRoots {
+		if p == root || strings.HasPrefix(p, root+"/") {
+			shared = true
+			break
+		}
+	}
+	if !shared {
+		warnings = append(warnings, fmt.Sprintf(
+			"%s is outside Docker Desktop's default shared directories (%s); add it under Settings > Resources > File sharing",
+			p, strings.Join(macSharedRoots, ", ")))
+	}
+	warnings = append(warnings, fmt.Sprintf(
+		"%s: with VirtioFS, files created in containers are owned by your macOS user regardless of container UID, and file events may lag",
+		p))
+	return warnings
+}
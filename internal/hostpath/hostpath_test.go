@@ -0,0 +1,85 @@
+package hostpath
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseStyle(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Style
+		wantErr bool
+	}{
+		{"", StyleLinux, false},
+		{"linux", StyleLinux, false},
+		{"Windows", StyleWindows, false},
+		{"macos", StyleMacOS, false},
+		{"darwin", StyleMacOS, false},
+		{"solaris", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseStyle(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStyle(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseStyle(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslator_Translate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tr      Translator
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"linux unchanged", Translator{Style: StyleLinux}, "/home/me/cache", "/home/me/cache", false},
+		{"linux relative", Translator{Style: StyleLinux}, "cache", "", true},
+		{"empty", Translator{Style: StyleWindows}, "", "", false},
+		{"macos unchanged", Translator{Style: StyleMacOS}, "/Users/me/cache", "/Users/me/cache", false},
+		{"windows backslashes", Translator{Style: StyleWindows}, `C:\Users\me\cache`, "/host_mnt/c/Users/me/cache", false},
+		{"windows forward slashes", Translator{Style: StyleWindows}, "D:/work//cache/", "/host_mnt/d/work/cache", false},
+		{"windows drive root", Translator{Style: StyleWindows}, `E:\`, "/host_mnt/e", false},
+		{"git bash style", Translator{Style: StyleWindows}, "/c/Users/me", "/host_mnt/c/Users/me", false},
+		{"already translated", Translator{Style: StyleWindows}, "/host_mnt/c/Users/me", "/host_mnt/c/Users/me", false},
+		{"custom prefix", Translator{Style: StyleWindows, Prefix: "/run/desktop/mnt/host/"}, `C:\cache`, "/run/desktop/mnt/host/c/cache", false},
+		{"windows relative", Translator{Style: StyleWindows}, `cache\repos`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.tr.Translate(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Translate(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Translate(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslator_Warnings(t *testing.T) {
+	mac := Translator{Style: StyleMacOS}
+
+	if w := (Translator{Style: StyleLinux}).Warnings("/opt/cache"); len(w) != 0 {
+		t.Errorf("linux warnings = %v, want none", w)
+	}
+
+	shared := mac.Warnings("/Users/me/cache")
+	if len(shared) != 1 || !strings.Contains(shared[0], "VirtioFS") {
+		t.Errorf("shared path warnings = %v, want only the VirtioFS caveat", shared)
+	}
+
+	unshared := mac.Warnings("/opt/cache")
+	if len(unshared) != 2 || !strings.Contains(unshared[0], "File sharing") {
+		t.Errorf("unshared path warnings = %v, want file sharing warning", unshared)
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/drewdunne/familiar/internal/config"
 	"github.com/drewdunne/familiar/internal/intent"
+	"github.com/drewdunne/familiar/internal/metrics"
 )
 
 const defaultBaseURL = "https://api.anthropic.com/v1"
@@ -18,8 +20,8 @@ const defaultBaseURL = "https://api.anthropic.com/v1"
 var _ intent.Parser = (*Parser)(nil)
 
 func init() {
-	intent.Register(intent.StrategyAPI, func(apiKey, model string) intent.Parser {
-		return New(apiKey, model)
+	intent.Register(intent.StrategyAPI, func(cfg config.LLMAPIConfig) intent.Parser {
+		return New(cfg.APIKey, cfg.Model, WithPricing(cfg.InputUSDPerMTok, cfg.OutputUSDPerMTok))
 	})
 }
 
@@ -30,6 +32,10 @@ type Parser struct {
 	baseURL    string
 	client     *http.Client
 	maxRetries int
+
+	// Prices in USD per million tokens, for cost metrics.
+	inputPrice  float64
+	outputPrice float64
 }
 
 // Option configures the API parser.
@@ -49,6 +55,15 @@ func WithRetries(n int) Option {
 	}
 }
 
+// WithPricing sets input and output token prices in USD per million tokens,
+// used to estimate the cost reported in metrics.
+func WithPricing(inputUSDPerMTok, outputUSDPerMTok float64) Option {
+	return func(p *Parser) {
+		p.inputPrice = inputUSDPerMTok
+		p.outputPrice = outputUSDPerMTok
+	}
+}
+
 // New creates a new API-based intent parser.
 func New(apiKey, model string, opts ...Option) *Parser {
 	p := &Parser{
@@ -66,6 +81,15 @@ func New(apiKey, model string, opts ...Option) *Parser {
 
 // Parse extracts intent from the given text using Claude API.
 func (p *Parser) Parse(ctx context.Context, text string) (*intent.ParsedIntent, error) {
+	start := time.Now()
+	var usage metrics.IntentUsage
+	parsed, err := p.parse(ctx, text, &usage)
+	metrics.IntentParseRecorded(string(intent.StrategyAPI), time.Since(start), err, usage)
+	return parsed, err
+}
+
+// parse does the API round trips for Parse, filling usage from the response.
+func (p *Parser) parse(ctx context.Context, text string, usage *metrics.IntentUsage) (*intent.ParsedIntent, error) {
 	prompt := buildPrompt(text)
 
 	reqBody := map[string]interface{}{
@@ -120,17 +144,50 @@ func (p *Parser) Parse(ctx context.Context, text string) (*intent.ParsedIntent,
 		}
 		resp.Body.Close()
 
+		usage.InputTokens = apiResp.Usage.InputTokens
+		usage.OutputTokens = apiResp.Usage.OutputTokens
+		usage.CostUSD = (float64(usage.InputTokens)*p.inputPrice + float64(usage.OutputTokens)*p.outputPrice) / 1e6
+
 		return parseResponse(apiResp, text)
 	}
 
 	return nil, lastErr
 }
 
+// Ping checks that the API accepts the key by listing one model, which
+// costs no tokens.
+func (p *Parser) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/models?limit=1", nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("API key rejected (status %d)", resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("API error (status %d)", resp.StatusCode)
+	}
+	return nil
+}
+
 type anthropicResponse struct {
 	Content []struct {
 		Type string `json:"type"`
 		Text string `json:"text"`
 	} `json:"content"`
+	Usage struct {
+		InputTokens  uint64 `json:"input_tokens"`
+		OutputTokens uint64 `json:"output_tokens"`
+	} `json:"usage"`
 }
 
 type parsedResponse struct {
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/drewdunne/familiar/internal/intent"
+	"github.com/drewdunne/familiar/internal/metrics"
 )
 
 func TestAPIParser_Parse_APIError(t *testing.T) {
@@ -134,3 +135,82 @@ func TestAPIParser_Parse_MultipleActions(t *testing.T) {
 		t.Error("Should have detected approve action")
 	}
 }
+
+func TestAPIParser_Parse_RecordsUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := map[string]interface{}{
+			"content": []map[string]interface{}{
+				{"type": "text", "text": `{"instructions": "fix it", "requested_actions": [], "confidence": 0.9}`},
+			},
+			"usage": map[string]interface{}{"input_tokens": 1000, "output_tokens": 200},
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	metrics.Reset()
+	defer metrics.Reset()
+
+	parser := New("test-key", "claude-sonnet-4-20250514", WithBaseURL(server.URL), WithPricing(3, 15))
+	if _, err := parser.Parse(context.Background(), "fix it"); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	u := metrics.Get().IntentUsage["api"]
+	if u.Calls != 1 || u.Failures != 0 {
+		t.Errorf("IntentUsage[api] = %+v, want 1 call, 0 failures", u)
+	}
+	if u.InputTokens != 1000 || u.OutputTokens != 200 {
+		t.Errorf("tokens = %d/%d, want 1000/200", u.InputTokens, u.OutputTokens)
+	}
+	if diff := u.CostUSD - 0.006; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("CostUSD = %f, want 0.006", u.CostUSD)
+	}
+}
+
+func TestAPIParser_Parse_RecordsFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	metrics.Reset()
+	defer metrics.Reset()
+
+	parser := New("test-key", "claude-sonnet-4-20250514", WithBaseURL(server.URL))
+	if _, err := parser.Parse(context.Background(), "test"); err == nil {
+		t.Fatal("Parse() should error on non-200 status")
+	}
+
+	if u := metrics.Get().IntentUsage["api"]; u.Calls != 1 || u.Failures != 1 {
+		t.Errorf("IntentUsage[api] = %+v, want 1 call, 1 failure", u)
+	}
+}
+
+func TestAPIParser_Ping(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"valid key", http.StatusOK, false},
+		{"invalid key", http.StatusUnauthorized, true},
+		{"server error", http.StatusInternalServerError, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/models" || r.Header.Get("x-api-key") != "test-key" {
+					t.Errorf("unexpected request: %s with key %q", r.URL.Path, r.Header.Get("x-api-key"))
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			parser := New("test-key", "claude-sonnet-4-20250514", WithBaseURL(server.URL))
+			if err := parser.Ping(context.Background()); (err != nil) != tt.wantErr {
+				t.Errorf("Ping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -7,7 +7,7 @@ import (
 )
 
 // ParserFactory is a function that creates a Parser.
-type ParserFactory func(apiKey, model string) Parser
+type ParserFactory func(cfg config.LLMAPIConfig) Parser
 
 // registry holds registered parser factories by strategy.
 var registry = make(map[Strategy]ParserFactory)
@@ -25,10 +25,7 @@ func NewParser(cfg *config.Config) (Parser, error) {
 		if !ok {
 			return nil, fmt.Errorf("API strategy not registered (import _ \"github.com/drewdunne/familiar/internal/intent/api\")")
 		}
-		return factory(
-			cfg.LLM.API.APIKey,
-			cfg.LLM.API.Model,
-		), nil
+		return factory(cfg.LLM.API), nil
 
 	case StrategyCLI:
 		return nil, fmt.Errorf("CLI strategy not yet implemented")
@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Audit record kinds.
+const (
+	AuditGrant     = "grant"     // an agent was allowed a privileged action
+	AuditAction    = "action"    // whether a granted action was taken
+	AuditViolation = "violation" // an agent changed a protected path
+)
+
+// AuditEntry records a privileged permission granted to an agent, whether
+// the agent used it, or a protected path it changed.
+type AuditEntry struct {
+	Time          time.Time `json:"time"`
+	Kind          string    `json:"kind"`
+	AgentID       string    `json:"agent_id"`
+	Provider      string    `json:"provider,omitempty"`
+	Repo          string    `json:"repo,omitempty"` // owner/name
+	MR            int       `json:"mr,omitempty"`
+	EventType     string    `json:"event_type,omitempty"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	Permission    string    `json:"permission,omitempty"` // push_commits, merge, approve, or dismiss_reviews
+	Setting       string    `json:"setting,omitempty"`    // always or on_request
+	Source        string    `json:"source,omitempty"`     // config setting that allowed it
+	RequestedBy   string    `json:"requested_by,omitempty"`
+
+	// What the parsed intent asked for, and how sure the parser was; absent
+	// when the event wasn't parsed.
+	RequestedActions []string `json:"requested_actions,omitempty"`
+	IntentConfidence *float64 `json:"intent_confidence,omitempty"`
+
+	Occurred *bool  `json:"occurred,omitempty"` // for actions
+	Detail   string `json:"detail,omitempty"`
+}
+
+// AuditLog is an append-only file of audit entries, one JSON object per
+// line. Entries are never rewritten or removed.
+type AuditLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewAuditLog returns the audit log at path.
+func NewAuditLog(path string) *AuditLog {
+	return &AuditLog{path: path}
+}
+
+// Record appends e, stamped with the current time if it has none.
+func (a *AuditLog) Record(e AuditEntry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding audit entry: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(a.path), 0755); err != nil {
+		return fmt.Errorf("creating audit log directory: %w", err)
+	}
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAuditLog_Record(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	audit := NewAuditLog(path)
+
+	confidence := 0.9
+	occurred := true
+	at := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	entries := []AuditEntry{
+		{Time: at, Kind: AuditGrant, AgentID: "agent-1", Permission: "merge", Setting: "on_request",
+			Source: "repo config permissions.merge", RequestedBy: "alice", IntentConfidence: &confidence},
+		{Kind: AuditAction, AgentID: "agent-1", Permission: "merge", Occurred: &occurred},
+	}
+	for _, e := range entries {
+		if err := audit.Record(e); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("audit log mode = %o, want 600", perm)
+	}
+
+	var got []AuditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		got = append(got, e)
+	}
+	if len(got) != 2 {
+		t.Fatalf("read %d entries, want 2", len(got))
+	}
+	if !got[0].Time.Equal(at) || got[0].Source != "repo config permissions.merge" || *got[0].IntentConfidence != 0.9 {
+		t.Errorf("grant = %+v, want it recorded as given", got[0])
+	}
+	if got[1].Time.IsZero() {
+		t.Error("entry without a time should be stamped")
+	}
+	if got[1].Occurred == nil || !*got[1].Occurred {
+		t.Errorf("action occurred = %v, want true", got[1].Occurred)
+	}
+}
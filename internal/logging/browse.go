@@ -0,0 +1,147 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampLayout is the timestamp at the start of every log filename.
+const timestampLayout = "2006-01-02T15-04-05"
+
+// LogFile describes one agent log in the log directory.
+type LogFile struct {
+	Path       string
+	Name       string
+	RepoOwner  string
+	RepoName   string
+	MRNumber   int
+	EventType  string
+	AgentID    string
+	Timestamp  time.Time
+	Size       int64
+	Compressed bool // gzipped by the Cleaner
+}
+
+// ParseLogName splits a log filename of the form
+// timestamp-eventType-agentID.log (or .log.gz) into its parts.
+func ParseLogName(name string) (timestamp time.Time, eventType, agentID string, ok bool) {
+	base := strings.TrimSuffix(name, ".gz")
+	base, found := strings.CutSuffix(base, ".log")
+	if !found || len(base) < len(timestampLayout)+1 || base[len(timestampLayout)] != '-' {
+		return time.Time{}, "", "", false
+	}
+	timestamp, err := time.ParseInLocation(timestampLayout, base[:len(timestampLayout)], time.Local)
+	if err != nil {
+		return time.Time{}, "", "", false
+	}
+	// Event types use underscores, so the first dash ends the event type
+	eventType, agentID, found = strings.Cut(base[len(timestampLayout)+1:], "-")
+	if !found || eventType == "" || agentID == "" {
+		return time.Time{}, "", "", false
+	}
+	return timestamp, eventType, agentID, true
+}
+
+// ListRepos returns the owner/name of every repo with logs under baseDir.
+func ListRepos(baseDir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(baseDir, "*", "*"))
+	if err != nil {
+		return nil, fmt.Errorf("listing repos: %w", err)
+	}
+	var repos []string
+	for _, m := range matches {
+		if info, err := os.Stat(m); err == nil && info.IsDir() {
+			repos = append(repos, filepath.Base(filepath.Dir(m))+"/"+filepath.Base(m))
+		}
+	}
+	sort.Strings(repos)
+	return repos, nil
+}
+
+// ListMRs returns the MR numbers with logs for a repo, newest first.
+func ListMRs(baseDir, owner, repo string) ([]int, error) {
+	entries, err := os.ReadDir(filepath.Join(baseDir, owner, repo))
+	if err != nil {
+		return nil, fmt.Errorf("listing MRs: %w", err)
+	}
+	var mrs []int
+	for _, e := range entries {
+		if n, err := strconv.Atoi(e.Name()); err == nil && e.IsDir() {
+			mrs = append(mrs, n)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(mrs)))
+	return mrs, nil
+}
+
+// ListLogs returns the agent logs for an MR, newest first. Files that don't
+// follow the naming convention are skipped.
+func ListLogs(baseDir, owner, repo string, mr int) ([]LogFile, error) {
+	dir := filepath.Join(baseDir, owner, repo, strconv.Itoa(mr))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("listing logs: %w", err)
+	}
+	var logs []LogFile
+	for _, e := range entries {
+		timestamp, eventType, agentID, ok := ParseLogName(e.Name())
+		if !ok || e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		logs = append(logs, LogFile{
+			Path:       filepath.Join(dir, e.Name()),
+			Name:       e.Name(),
+			RepoOwner:  owner,
+			RepoName:   repo,
+			MRNumber:   mr,
+			EventType:  eventType,
+			AgentID:    agentID,
+			Timestamp:  timestamp,
+			Size:       info.Size(),
+			Compressed: strings.HasSuffix(e.Name(), ".gz"),
+		})
+	}
+	sort.Slice(logs, func(i, j int) bool {
+		return logs[i].Timestamp.After(logs[j].Timestamp)
+	})
+	return logs, nil
+}
+
+// OpenLog opens a log file for reading, decompressing it if it was gzipped.
+func OpenLog(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, nil
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("decompressing %s: %w", path, err)
+	}
+	return &gzipReadCloser{Reader: zr, file: f}, nil
+}
+
+// gzipReadCloser closes both the gzip reader and its file.
+type gzipReadCloser struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g *gzipReadCloser) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLogName(t *testing.T) {
+	tests := []struct {
+		name          string
+		wantEventType string
+		wantAgentID   string
+		wantOK        bool
+	}{
+		{name: "2026-01-15T10-30-00-mr_opened-gitlab-repo-7-1700000000.log", wantEventType: "mr_opened", wantAgentID: "gitlab-repo-7-1700000000", wantOK: true},
+		{name: "2026-01-15T10-30-00-mention-agent-1.log.gz", wantEventType: "mention", wantAgentID: "agent-1", wantOK: true},
+		{name: "recent.log"},
+		{name: "2026-01-15T10-30-00-mr_opened.log"},
+		{name: "2026-01-15T10-30-00-mr_opened-agent-1.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timestamp, eventType, agentID, ok := ParseLogName(tt.name)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseLogName() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if eventType != tt.wantEventType || agentID != tt.wantAgentID {
+				t.Errorf("ParseLogName() = %q, %q, want %q, %q", eventType, agentID, tt.wantEventType, tt.wantAgentID)
+			}
+			if want := time.Date(2026, 1, 15, 10, 30, 0, 0, time.Local); !timestamp.Equal(want) {
+				t.Errorf("timestamp = %v, want %v", timestamp, want)
+			}
+		})
+	}
+}
+
+func TestListLogs_RoundTripsWriterNames(t *testing.T) {
+	baseDir := t.TempDir()
+	writer := NewWriter(baseDir)
+	for _, id := range []string{"agent-1", "agent-2"} {
+		_, err := writer.Create(LogEntry{
+			AgentID:   id,
+			RepoOwner: "owner",
+			RepoName:  "repo",
+			MRNumber:  7,
+			EventType: "mr_opened",
+			Timestamp: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	repos, err := ListRepos(baseDir)
+	if err != nil || len(repos) != 1 || repos[0] != "owner/repo" {
+		t.Errorf("ListRepos() = %v, %v, want [owner/repo]", repos, err)
+	}
+	mrs, err := ListMRs(baseDir, "owner", "repo")
+	if err != nil || len(mrs) != 1 || mrs[0] != 7 {
+		t.Errorf("ListMRs() = %v, %v, want [7]", mrs, err)
+	}
+	logs, err := ListLogs(baseDir, "owner", "repo", 7)
+	if err != nil {
+		t.Fatalf("ListLogs() error = %v", err)
+	}
+	if len(logs) != 2 {
+		t.Fatalf("ListLogs() = %+v, want 2 logs", logs)
+	}
+	for _, l := range logs {
+		if l.EventType != "mr_opened" || l.RepoOwner != "owner" || l.MRNumber != 7 || l.Compressed {
+			t.Errorf("log = %+v", l)
+		}
+	}
+}
@@ -3,23 +3,84 @@ package logging
 import (
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 )
 
 // Cleaner handles cleanup of old log files based on a retention policy.
 type Cleaner struct {
-	baseDir       string
-	retentionDays int
+	baseDir           string
+	retentionDays     int
+	compressAfterDays int
+	maxBytes          int64
+	index             *Index
 }
 
 // NewCleaner creates a new Cleaner with the specified base directory and retention period.
-func NewCleaner(baseDir string, retentionDays int) *Cleaner {
-	return &Cleaner{baseDir: baseDir, retentionDays: retentionDays}
+func NewCleaner(baseDir string, retentionDays int, opts ...CleanerOption) *Cleaner {
+	c := &Cleaner{baseDir: baseDir, retentionDays: retentionDays}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CleanerOption configures a Cleaner.
+type CleanerOption func(*Cleaner)
+
+// WithCompressAfter gzips log files once they are older than days, ahead of
+// the retention window deleting them. 0 disables compression.
+func WithCompressAfter(days int) CleanerOption {
+	return func(c *Cleaner) {
+		c.compressAfterDays = days
+	}
+}
+
+// WithMaxSize caps the total size of the log directory. Cleanup deletes the
+// oldest files, whatever their age, until it fits. 0 means no limit.
+func WithMaxSize(bytes int64) CleanerOption {
+	return func(c *Cleaner) {
+		c.maxBytes = bytes
+	}
+}
+
+// WithCompactIndex compacts idx after each cleanup, dropping the logs that
+// were deleted.
+func WithCompactIndex(idx *Index) CleanerOption {
+	return func(c *Cleaner) {
+		c.index = idx
+	}
 }
 
-// Cleanup removes log files older than the retention period and cleans up empty directories.
+// Cleanup removes log files older than the retention period, then the
+// oldest files until the logs fit within the size cap, and cleans up empty
+// directories. A retention period of 0 keeps logs forever unless the size
+// cap is exceeded.
 // Returns the number of files deleted and any error encountered.
 func (c *Cleaner) Cleanup() (int, error) {
+	var deleted int
+	var err error
+	if c.retentionDays > 0 {
+		deleted, err = c.expire()
+	}
+	if c.maxBytes > 0 {
+		deleted += c.trim()
+	}
+
+	// Clean up empty directories
+	c.cleanEmptyDirs()
+
+	if c.index != nil {
+		if indexErr := c.index.Compact(); err == nil {
+			err = indexErr
+		}
+	}
+
+	return deleted, err
+}
+
+// expire removes log files older than the retention period.
+func (c *Cleaner) expire() (int, error) {
 	threshold := time.Now().AddDate(0, 0, -c.retentionDays)
 	var deleted int
 
@@ -27,7 +88,7 @@ func (c *Cleaner) Cleanup() (int, error) {
 		if err != nil {
 			return nil // Skip errors
 		}
-		if info.IsDir() {
+		if info.IsDir() || c.isIndex(path) {
 			return nil
 		}
 		if info.ModTime().Before(threshold) {
@@ -38,12 +99,53 @@ func (c *Cleaner) Cleanup() (int, error) {
 		return nil
 	})
 
-	// Clean up empty directories
-	c.cleanEmptyDirs()
-
 	return deleted, err
 }
 
+// trim removes the oldest log files until the total size is within the cap.
+func (c *Cleaner) trim() int {
+	type logFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []logFile
+	var total int64
+
+	filepath.Walk(c.baseDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || c.isIndex(path) {
+			return nil // Skip errors
+		}
+		files = append(files, logFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if total <= c.maxBytes {
+		return 0
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	var deleted int
+	for _, f := range files {
+		if total <= c.maxBytes {
+			break
+		}
+		if err := os.Remove(f.path); err != nil {
+			continue
+		}
+		total -= f.size
+		deleted++
+	}
+	return deleted
+}
+
+// isIndex reports whether path is the log index, which Cleanup never deletes.
+func (c *Cleaner) isIndex(path string) bool {
+	return path == filepath.Join(c.baseDir, IndexFile)
+}
+
 // cleanEmptyDirs removes empty directories within the base directory.
 func (c *Cleaner) cleanEmptyDirs() {
 	// Walk in reverse depth order to clean nested empty dirs first
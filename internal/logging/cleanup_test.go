@@ -3,6 +3,7 @@ package logging
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -172,3 +173,112 @@ func TestCleaner_RetentionDays(t *testing.T) {
 		t.Errorf("deleted = %d, want 0 (30-day retention)", deleted)
 	}
 }
+
+func TestCleanup_ZeroRetentionKeepsLogs(t *testing.T) {
+	baseDir := t.TempDir()
+	dir := filepath.Join(baseDir, "owner", "repo", "1")
+	os.MkdirAll(dir, 0755)
+	oldFile := filepath.Join(dir, "old.log")
+	os.WriteFile(oldFile, []byte("old"), 0644)
+	os.Chtimes(oldFile, time.Now().AddDate(0, 0, -60), time.Now().AddDate(0, 0, -60))
+
+	deleted, err := NewCleaner(baseDir, 0).Cleanup()
+	if err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if deleted != 0 {
+		t.Errorf("deleted = %d, want 0", deleted)
+	}
+	if _, err := os.Stat(oldFile); err != nil {
+		t.Errorf("Old file should be kept: %v", err)
+	}
+}
+
+func TestCleanup_MaxSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxBytes    int64
+		wantDeleted int
+		wantKept    []string
+	}{
+		{name: "under cap", maxBytes: 100, wantDeleted: 0, wantKept: []string{"oldest.log", "middle.log", "newest.log"}},
+		{name: "over cap deletes oldest first", maxBytes: 25, wantDeleted: 1, wantKept: []string{"middle.log", "newest.log"}},
+		{name: "deletes until it fits", maxBytes: 10, wantDeleted: 2, wantKept: []string{"newest.log"}},
+		{name: "no cap", maxBytes: 0, wantDeleted: 0, wantKept: []string{"oldest.log", "middle.log", "newest.log"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseDir := t.TempDir()
+			// Ten bytes each and all within retention
+			writeAgedLog(t, baseDir, "oldest.log", "0123456789", 3)
+			writeAgedLog(t, baseDir, "middle.log", "0123456789", 2)
+			writeAgedLog(t, baseDir, "newest.log", "0123456789", 1)
+
+			deleted, err := NewCleaner(baseDir, 30, WithMaxSize(tt.maxBytes)).Cleanup()
+			if err != nil {
+				t.Fatalf("Cleanup() error = %v", err)
+			}
+			if deleted != tt.wantDeleted {
+				t.Errorf("deleted = %d, want %d", deleted, tt.wantDeleted)
+			}
+
+			matches, _ := filepath.Glob(filepath.Join(baseDir, "owner", "repo", "1", "*.log"))
+			var kept []string
+			for _, m := range matches {
+				kept = append(kept, filepath.Base(m))
+			}
+			if len(kept) != len(tt.wantKept) {
+				t.Fatalf("kept = %v, want %v", kept, tt.wantKept)
+			}
+			for _, name := range tt.wantKept {
+				if _, err := os.Stat(filepath.Join(baseDir, "owner", "repo", "1", name)); err != nil {
+					t.Errorf("%s should be kept: %v", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCleanup_MaxSizeWithoutRetention(t *testing.T) {
+	baseDir := t.TempDir()
+	old := writeAgedLog(t, baseDir, "old.log", "0123456789", 2)
+	writeAgedLog(t, baseDir, "new.log", "0123456789", 1)
+
+	deleted, err := NewCleaner(baseDir, 0, WithMaxSize(10)).Cleanup()
+	if err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted = %d, want 1", deleted)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("Oldest log should be deleted to fit the cap")
+	}
+}
+
+func TestCleanup_CompactsIndex(t *testing.T) {
+	baseDir := t.TempDir()
+	idx := NewIndex(baseDir)
+	old := writeAgedLog(t, baseDir, "2020-01-01T00-00-00-mr_opened-old.log", "old", 60)
+	recent := writeAgedLog(t, baseDir, "2026-01-01T00-00-00-mr_opened-recent.log", "recent", 1)
+	idx.Add(IndexEntry{AgentID: "old", Path: old})
+	idx.Add(IndexEntry{AgentID: "recent", Path: recent})
+
+	// An index untouched for longer than retention is still kept
+	indexPath := filepath.Join(baseDir, IndexFile)
+	aged := time.Now().AddDate(0, 0, -60)
+	os.Chtimes(indexPath, aged, aged)
+
+	if _, err := NewCleaner(baseDir, 30, WithCompactIndex(idx)).Cleanup(); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatalf("index should be kept: %v", err)
+	}
+	if strings.Contains(string(data), `"old"`) || !strings.Contains(string(data), `"recent"`) {
+		t.Errorf("index = %s, want only the recent log", data)
+	}
+}
@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Compress gzips .log files older than the compression threshold, replacing
+// each with a .log.gz that keeps its modification time so retention still
+// counts from when the log was written. Returns the number of files
+// compressed.
+func (c *Cleaner) Compress() (int, error) {
+	if c.compressAfterDays <= 0 {
+		return 0, nil
+	}
+	threshold := time.Now().AddDate(0, 0, -c.compressAfterDays)
+	var compressed int
+	var firstErr error
+
+	filepath.Walk(c.baseDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".log") {
+			return nil // Skip errors
+		}
+		if !info.ModTime().Before(threshold) {
+			return nil
+		}
+		if err := gzipFile(path, info.ModTime()); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return nil
+		}
+		compressed++
+		return nil
+	})
+
+	return compressed, firstErr
+}
+
+// gzipFile replaces path with path.gz, stamped with modTime.
+func gzipFile(path string, modTime time.Time) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer src.Close()
+
+	// Write beside the target and rename so a crash never leaves a
+	// truncated archive in place of the log
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".gz.tmp*")
+	if err != nil {
+		return fmt.Errorf("compressing %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	zw.Name = filepath.Base(path)
+	zw.ModTime = modTime
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Chtimes(tmp.Name(), modTime, modTime)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path+".gz")
+	}
+	if err != nil {
+		return fmt.Errorf("compressing %s: %w", path, err)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,122 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeAgedLog writes a log file under baseDir last modified daysAgo.
+func writeAgedLog(t *testing.T, baseDir, name, content string, daysAgo int) string {
+	t.Helper()
+	dir := filepath.Join(baseDir, "owner", "repo", "1")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().AddDate(0, 0, -daysAgo)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCompress_AgedLogs(t *testing.T) {
+	baseDir := t.TempDir()
+	oldFile := writeAgedLog(t, baseDir, "old.log", "old transcript\n", 10)
+	recentFile := writeAgedLog(t, baseDir, "recent.log", "recent", 1)
+	other := writeAgedLog(t, baseDir, "notes.txt", "not a log", 10)
+
+	oldInfo, _ := os.Stat(oldFile)
+
+	cleaner := NewCleaner(baseDir, 30, WithCompressAfter(7))
+	compressed, err := cleaner.Compress()
+	if err != nil {
+		t.Fatalf("Compress() error = %v", err)
+	}
+	if compressed != 1 {
+		t.Errorf("compressed = %d, want 1", compressed)
+	}
+
+	if _, err := os.Stat(oldFile); !os.IsNotExist(err) {
+		t.Error("Old log should be replaced by its archive")
+	}
+	for _, path := range []string{recentFile, other} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s should be left alone: %v", filepath.Base(path), err)
+		}
+	}
+
+	gzInfo, err := os.Stat(oldFile + ".gz")
+	if err != nil {
+		t.Fatalf("archive missing: %v", err)
+	}
+	if !gzInfo.ModTime().Equal(oldInfo.ModTime()) {
+		t.Errorf("archive mtime = %v, want %v", gzInfo.ModTime(), oldInfo.ModTime())
+	}
+
+	f, err := os.Open(oldFile + ".gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("reading archive: %v", err)
+	}
+	if string(content) != "old transcript\n" {
+		t.Errorf("archive content = %q, want %q", content, "old transcript\n")
+	}
+
+	// A second pass has nothing left to do
+	compressed, err = cleaner.Compress()
+	if err != nil || compressed != 0 {
+		t.Errorf("second Compress() = %d, %v, want 0, nil", compressed, err)
+	}
+}
+
+func TestCompress_Disabled(t *testing.T) {
+	baseDir := t.TempDir()
+	oldFile := writeAgedLog(t, baseDir, "old.log", "old", 10)
+
+	compressed, err := NewCleaner(baseDir, 30).Compress()
+	if err != nil {
+		t.Fatalf("Compress() error = %v", err)
+	}
+	if compressed != 0 {
+		t.Errorf("compressed = %d, want 0", compressed)
+	}
+	if _, err := os.Stat(oldFile); err != nil {
+		t.Errorf("Old log should be left alone: %v", err)
+	}
+}
+
+func TestCleanup_RemovesCompressedLogs(t *testing.T) {
+	baseDir := t.TempDir()
+	oldFile := writeAgedLog(t, baseDir, "old.log", "old", 60)
+
+	cleaner := NewCleaner(baseDir, 30, WithCompressAfter(7))
+	if _, err := cleaner.Compress(); err != nil {
+		t.Fatalf("Compress() error = %v", err)
+	}
+	deleted, err := cleaner.Cleanup()
+	if err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted = %d, want 1", deleted)
+	}
+	if _, err := os.Stat(oldFile + ".gz"); !os.IsNotExist(err) {
+		t.Error("Archive past retention should be deleted")
+	}
+}
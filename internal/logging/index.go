@@ -0,0 +1,338 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IndexFile is the name of the log index in the log directory. It is JSON
+// Lines rather than SQLite to keep the build free of cgo, so queries scan
+// the whole file.
+const IndexFile = "index.jsonl"
+
+// Agent run outcomes recorded in the index.
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+	OutcomeTimedOut  = "timed_out"
+)
+
+// IndexEntry describes one agent log. Entries without an outcome are for
+// agents that are still running, or that never finished recording one.
+type IndexEntry struct {
+	AgentID    string    `json:"agent_id"`
+	Repo       string    `json:"repo,omitempty"` // owner/name
+	MR         int       `json:"mr,omitempty"`
+	EventType  string    `json:"event_type,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitzero"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+	Outcome    string    `json:"outcome,omitempty"`
+	Reason     string    `json:"reason,omitempty"` // why a run failed
+	Path       string    `json:"path,omitempty"`   // absolute in query results, relative to the log directory on disk
+}
+
+// IndexQuery selects index entries. Zero fields match everything.
+type IndexQuery struct {
+	AgentID   string
+	Repo      string
+	MR        int
+	EventType string
+	Outcome   string
+	Since     time.Time // started at or after
+	Until     time.Time // started before
+	Limit     int
+}
+
+// matches reports whether e satisfies q.
+func (q IndexQuery) matches(e IndexEntry) bool {
+	switch {
+	case q.AgentID != "" && e.AgentID != q.AgentID,
+		q.Repo != "" && e.Repo != q.Repo,
+		q.MR != 0 && e.MR != q.MR,
+		q.EventType != "" && e.EventType != q.EventType,
+		q.Outcome != "" && e.Outcome != q.Outcome,
+		!q.Since.IsZero() && e.StartedAt.Before(q.Since),
+		!q.Until.IsZero() && !e.StartedAt.Before(q.Until):
+		return false
+	}
+	return true
+}
+
+// Index records every agent log and how its run ended, so logs can be
+// found without walking the log directory. It is an append-only file of
+// JSON records, one per line; later records for an agent update earlier
+// ones. Compact rewrites it without logs that have since been deleted.
+type Index struct {
+	baseDir string
+	path    string
+	mu      sync.Mutex
+}
+
+// NewIndex returns the index for the log directory baseDir.
+func NewIndex(baseDir string) *Index {
+	return &Index{baseDir: baseDir, path: filepath.Join(baseDir, IndexFile)}
+}
+
+// Add records a new agent log. e.Path may be absolute or relative to the
+// log directory.
+func (x *Index) Add(e IndexEntry) error {
+	if filepath.IsAbs(e.Path) {
+		rel, err := filepath.Rel(x.baseDir, e.Path)
+		if err != nil {
+			return fmt.Errorf("indexing %s: %w", e.Path, err)
+		}
+		e.Path = rel
+	}
+	return x.append(e)
+}
+
+// Finish records how an agent's run ended.
+func (x *Index) Finish(agentID, outcome, reason string, at time.Time) error {
+	return x.append(IndexEntry{AgentID: agentID, FinishedAt: at, Outcome: outcome, Reason: reason})
+}
+
+func (x *Index) append(e IndexEntry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding index entry: %w", err)
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if err := os.MkdirAll(x.baseDir, 0755); err != nil {
+		return fmt.Errorf("creating log directory: %w", err)
+	}
+	f, err := os.OpenFile(x.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("opening log index: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing log index: %w", err)
+	}
+	return nil
+}
+
+// Query returns the entries matching q whose logs still exist, newest
+// first.
+func (x *Index) Query(q IndexQuery) ([]IndexEntry, error) {
+	x.mu.Lock()
+	entries, err := x.load()
+	x.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []IndexEntry
+	for _, e := range entries {
+		if q.matches(e) {
+			e.Path = filepath.Join(x.baseDir, e.Path)
+			matched = append(matched, e)
+		}
+	}
+	return newestFirst(matched, q.Limit), nil
+}
+
+// Find is Query for tools: it also scans the log directory, so logs written
+// before the index existed, or missing from it, are found too. Their
+// outcomes are unknown.
+func (x *Index) Find(q IndexQuery) ([]IndexEntry, error) {
+	indexed, err := x.Query(IndexQuery{AgentID: q.AgentID, Repo: q.Repo, MR: q.MR})
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(indexed))
+	for _, e := range indexed {
+		seen[e.AgentID] = true
+	}
+
+	repos, err := ListRepos(x.baseDir)
+	if err != nil {
+		return nil, err
+	}
+	found := indexed
+	for _, repo := range repos {
+		if q.Repo != "" && repo != q.Repo {
+			continue
+		}
+		owner, name, _ := strings.Cut(repo, "/")
+		mrs, err := ListMRs(x.baseDir, owner, name)
+		if err != nil {
+			continue
+		}
+		for _, mr := range mrs {
+			if q.MR != 0 && mr != q.MR {
+				continue
+			}
+			logs, err := ListLogs(x.baseDir, owner, name, mr)
+			if err != nil {
+				continue
+			}
+			for _, l := range logs {
+				if seen[l.AgentID] {
+					continue
+				}
+				found = append(found, IndexEntry{
+					AgentID:   l.AgentID,
+					Repo:      repo,
+					MR:        mr,
+					EventType: l.EventType,
+					StartedAt: l.Timestamp,
+					Path:      l.Path,
+				})
+			}
+		}
+	}
+
+	var matched []IndexEntry
+	for _, e := range found {
+		if q.matches(e) {
+			matched = append(matched, e)
+		}
+	}
+	return newestFirst(matched, q.Limit), nil
+}
+
+// newestFirst sorts entries by start time, newest first, keeping at most
+// limit of them if limit is positive.
+func newestFirst(entries []IndexEntry, limit int) []IndexEntry {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedAt.After(entries[j].StartedAt)
+	})
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries
+}
+
+// Compact rewrites the index with one record per log, dropping logs that no
+// longer exist.
+func (x *Index) Compact() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if _, err := os.Stat(x.path); os.IsNotExist(err) {
+		return nil
+	}
+	entries, err := x.load()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(x.baseDir, IndexFile+".tmp*")
+	if err != nil {
+		return fmt.Errorf("compacting log index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err = enc.Encode(e); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), x.path)
+	}
+	if err != nil {
+		return fmt.Errorf("compacting log index: %w", err)
+	}
+	return nil
+}
+
+// load reads the index, merging each agent's records in the order the
+// agents were added. Logs that have been compressed are pointed at their
+// archive, and logs that have been deleted are dropped. The caller must
+// hold x.mu.
+func (x *Index) load() ([]IndexEntry, error) {
+	f, err := os.Open(x.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening log index: %w", err)
+	}
+	defer f.Close()
+
+	var entries []IndexEntry
+	byAgent := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec IndexEntry
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil || rec.AgentID == "" {
+			continue // Skip records torn by a crash
+		}
+		i, ok := byAgent[rec.AgentID]
+		if !ok {
+			byAgent[rec.AgentID] = len(entries)
+			entries = append(entries, rec)
+			continue
+		}
+		entries[i].merge(rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading log index: %w", err)
+	}
+
+	live := entries[:0]
+	for _, e := range entries {
+		if e.Path == "" {
+			continue
+		}
+		full := filepath.Join(x.baseDir, e.Path)
+		if _, err := os.Stat(full); err != nil {
+			if _, err := os.Stat(full + ".gz"); err != nil {
+				continue
+			}
+			e.Path += ".gz"
+		}
+		live = append(live, e)
+	}
+	return live, nil
+}
+
+// merge applies the set fields of a later record to e.
+func (e *IndexEntry) merge(rec IndexEntry) {
+	if rec.Repo != "" {
+		e.Repo = rec.Repo
+	}
+	if rec.MR != 0 {
+		e.MR = rec.MR
+	}
+	if rec.EventType != "" {
+		e.EventType = rec.EventType
+	}
+	if !rec.StartedAt.IsZero() {
+		e.StartedAt = rec.StartedAt
+	}
+	if !rec.FinishedAt.IsZero() {
+		e.FinishedAt = rec.FinishedAt
+	}
+	if rec.Outcome != "" {
+		e.Outcome = rec.Outcome
+	}
+	if rec.Reason != "" {
+		e.Reason = rec.Reason
+	}
+	if rec.Path != "" {
+		e.Path = rec.Path
+	}
+}
@@ -0,0 +1,230 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// indexedLogs creates an index over three logs: agent-1 succeeded on
+// owner/repo MR 1, agent-2 failed on owner/repo MR 2, and agent-3 is still
+// running on other/repo MR 1.
+func indexedLogs(t *testing.T) (*Index, string) {
+	t.Helper()
+	baseDir := t.TempDir()
+	writer := NewWriter(baseDir, WithIndex(NewIndex(baseDir)))
+	idx := writer.index
+	start := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	for i, e := range []LogEntry{
+		{AgentID: "agent-1", RepoOwner: "owner", RepoName: "repo", MRNumber: 1, EventType: "mr_opened"},
+		{AgentID: "agent-2", RepoOwner: "owner", RepoName: "repo", MRNumber: 2, EventType: "mention"},
+		{AgentID: "agent-3", RepoOwner: "other", RepoName: "repo", MRNumber: 1, EventType: "mention"},
+	} {
+		e.Timestamp = start.Add(time.Duration(i) * time.Hour)
+		if _, err := writer.Create(e); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if err := idx.Finish("agent-1", OutcomeSucceeded, "", start.Add(time.Minute)); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	if err := idx.Finish("agent-2", OutcomeFailed, "auth", start.Add(time.Hour+time.Minute)); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	return idx, baseDir
+}
+
+func agentIDs(entries []IndexEntry) string {
+	var ids []string
+	for _, e := range entries {
+		ids = append(ids, e.AgentID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestIndex_Query(t *testing.T) {
+	idx, _ := indexedLogs(t)
+	start := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		query IndexQuery
+		want  string
+	}{
+		{name: "all, newest first", query: IndexQuery{}, want: "agent-3,agent-2,agent-1"},
+		{name: "repo", query: IndexQuery{Repo: "owner/repo"}, want: "agent-2,agent-1"},
+		{name: "repo and MR", query: IndexQuery{Repo: "owner/repo", MR: 1}, want: "agent-1"},
+		{name: "event type", query: IndexQuery{EventType: "mention"}, want: "agent-3,agent-2"},
+		{name: "outcome", query: IndexQuery{Outcome: OutcomeFailed}, want: "agent-2"},
+		{name: "agent", query: IndexQuery{AgentID: "agent-3"}, want: "agent-3"},
+		{name: "time range", query: IndexQuery{Since: start.Add(time.Hour), Until: start.Add(2 * time.Hour)}, want: "agent-2"},
+		{name: "limit", query: IndexQuery{Limit: 1}, want: "agent-3"},
+		{name: "no match", query: IndexQuery{Repo: "missing/repo"}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := idx.Query(tt.query)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if ids := agentIDs(got); ids != tt.want {
+				t.Errorf("Query() = %s, want %s", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndex_QueryMergesOutcome(t *testing.T) {
+	idx, baseDir := indexedLogs(t)
+
+	got, err := idx.Query(IndexQuery{AgentID: "agent-2"})
+	if err != nil || len(got) != 1 {
+		t.Fatalf("Query() = %v, %v; want one entry", got, err)
+	}
+	e := got[0]
+	if e.Repo != "owner/repo" || e.MR != 2 || e.EventType != "mention" {
+		t.Errorf("entry = %+v, want owner/repo MR 2 mention", e)
+	}
+	if e.Outcome != OutcomeFailed || e.Reason != "auth" || e.FinishedAt.IsZero() {
+		t.Errorf("entry = %+v, want failed with reason auth", e)
+	}
+	if !strings.HasPrefix(e.Path, baseDir) || !strings.HasSuffix(e.Path, "-mention-agent-2.log") {
+		t.Errorf("Path = %q, want absolute path to the log", e.Path)
+	}
+	if _, err := os.Stat(e.Path); err != nil {
+		t.Errorf("Path does not exist: %v", err)
+	}
+}
+
+func TestIndex_FollowsCompressedAndDeletedLogs(t *testing.T) {
+	idx, _ := indexedLogs(t)
+
+	entries, err := idx.Query(IndexQuery{})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	byID := make(map[string]string)
+	for _, e := range entries {
+		byID[e.AgentID] = e.Path
+	}
+	if err := gzipFile(byID["agent-1"], time.Now()); err != nil {
+		t.Fatalf("gzipFile() error = %v", err)
+	}
+	if err := os.Remove(byID["agent-2"]); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := idx.Query(IndexQuery{})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if ids := agentIDs(got); ids != "agent-3,agent-1" {
+		t.Fatalf("Query() = %s, want agent-3,agent-1", ids)
+	}
+	if want := byID["agent-1"] + ".gz"; got[1].Path != want {
+		t.Errorf("Path = %q, want %q", got[1].Path, want)
+	}
+}
+
+func TestIndex_Compact(t *testing.T) {
+	idx, baseDir := indexedLogs(t)
+
+	entries, _ := idx.Query(IndexQuery{AgentID: "agent-3"})
+	if err := os.Remove(entries[0].Path); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := idx.Query(IndexQuery{})
+
+	if err := idx.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(baseDir, IndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Errorf("compacted index has %d records, want 2:\n%s", lines, data)
+	}
+	after, err := idx.Query(IndexQuery{})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(after) != len(before) {
+		t.Fatalf("Query() after Compact() = %d entries, want %d", len(after), len(before))
+	}
+	for i := range after {
+		if after[i] != before[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, after[i], before[i])
+		}
+	}
+}
+
+func TestIndex_SkipsTornRecords(t *testing.T) {
+	idx, baseDir := indexedLogs(t)
+
+	f, err := os.OpenFile(filepath.Join(baseDir, IndexFile), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"agent_id":"agent-4","repo":`)
+	f.Close()
+
+	got, err := idx.Query(IndexQuery{})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if ids := agentIDs(got); ids != "agent-3,agent-2,agent-1" {
+		t.Errorf("Query() = %s, want agent-3,agent-2,agent-1", ids)
+	}
+}
+
+func TestIndex_Missing(t *testing.T) {
+	idx := NewIndex(filepath.Join(t.TempDir(), "logs"))
+
+	got, err := idx.Query(IndexQuery{})
+	if err != nil || len(got) != 0 {
+		t.Errorf("Query() = %v, %v; want nothing", got, err)
+	}
+	if err := idx.Compact(); err != nil {
+		t.Errorf("Compact() error = %v", err)
+	}
+}
+
+func TestIndex_FindIncludesUnindexedLogs(t *testing.T) {
+	idx, baseDir := indexedLogs(t)
+
+	// A log written before the index existed
+	dir := filepath.Join(baseDir, "owner", "repo", "1")
+	if err := os.WriteFile(filepath.Join(dir, "2025-12-01T09-00-00-mention-agent-0.log"), []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query IndexQuery
+		want  string
+	}{
+		{name: "all", query: IndexQuery{}, want: "agent-3,agent-2,agent-1,agent-0"},
+		{name: "repo and MR", query: IndexQuery{Repo: "owner/repo", MR: 1}, want: "agent-1,agent-0"},
+		{name: "agent", query: IndexQuery{AgentID: "agent-0"}, want: "agent-0"},
+		{name: "outcome excludes unindexed", query: IndexQuery{Outcome: OutcomeSucceeded}, want: "agent-1"},
+		{name: "limit", query: IndexQuery{Repo: "owner/repo", Limit: 2}, want: "agent-2,agent-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := idx.Find(tt.query)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			if ids := agentIDs(got); ids != tt.want {
+				t.Errorf("Find() = %s, want %s", ids, tt.want)
+			}
+		})
+	}
+}
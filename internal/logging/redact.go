@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// Redacted replaces secrets in redacted output.
+const Redacted = "[REDACTED]"
+
+// minSecretLen is the shortest literal secret redacted; shorter values would
+// match ordinary text.
+const minSecretLen = 8
+
+// builtinRedactions match well-known credential formats.
+var builtinRedactions = []redaction{
+	{re: regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{36,}`)},                                 // GitHub tokens
+	{re: regexp.MustCompile(`github_pat_[A-Za-z0-9_]{22,}`)},                               // GitHub fine-grained tokens
+	{re: regexp.MustCompile(`glpat-[A-Za-z0-9_\-]{20,}`)},                                  // GitLab personal access tokens
+	{re: regexp.MustCompile(`sk-ant-[A-Za-z0-9_\-]{20,}`)},                                 // Anthropic API keys
+	{re: regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`)},                                       // AWS access key IDs
+	{re: regexp.MustCompile(`(\w+://)[^/\s:@]+:[^/\s@]+@`), repl: "${1}" + Redacted + "@"}, // URL userinfo
+}
+
+// redaction replaces matches of re with repl, or with Redacted if repl is
+// empty.
+type redaction struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// Redactor removes secrets from log output: known secret values, well-known
+// credential formats, and configured patterns.
+type Redactor struct {
+	secrets    [][]byte // longest first, so a secret containing another is fully replaced
+	redactions []redaction
+}
+
+// NewRedactor creates a Redactor for the given secret values and regular
+// expressions, in addition to the built-in credential formats.
+func NewRedactor(secrets, patterns []string) (*Redactor, error) {
+	r := &Redactor{redactions: append([]redaction(nil), builtinRedactions...)}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		r.redactions = append(r.redactions, redaction{re: re})
+	}
+	return r.WithSecrets(secrets...), nil
+}
+
+// WithSecrets returns a copy of r that also redacts the given values.
+func (r *Redactor) WithSecrets(secrets ...string) *Redactor {
+	c := &Redactor{
+		secrets:    append([][]byte(nil), r.secrets...),
+		redactions: r.redactions,
+	}
+	for _, s := range secrets {
+		if len(s) >= minSecretLen {
+			c.secrets = append(c.secrets, []byte(s))
+		}
+	}
+	sort.SliceStable(c.secrets, func(i, j int) bool {
+		return len(c.secrets[i]) > len(c.secrets[j])
+	})
+	return c
+}
+
+// Redact returns data with every secret replaced by Redacted.
+func (r *Redactor) Redact(data []byte) []byte {
+	for _, s := range r.secrets {
+		data = bytes.ReplaceAll(data, s, []byte(Redacted))
+	}
+	for _, red := range r.redactions {
+		repl := red.repl
+		if repl == "" {
+			repl = Redacted
+		}
+		data = red.re.ReplaceAll(data, []byte(repl))
+	}
+	return data
+}
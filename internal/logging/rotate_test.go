@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxBytes   int64
+		maxBackups int
+		writes     []string
+		want       map[string]string // file suffix -> content; "" is the live file
+	}{
+		{
+			name:       "under the limit",
+			maxBytes:   100,
+			maxBackups: 2,
+			writes:     []string{"one\n", "two\n"},
+			want:       map[string]string{"": "one\ntwo\n"},
+		},
+		{
+			name:       "rotates when full",
+			maxBytes:   8,
+			maxBackups: 2,
+			writes:     []string{"one\n", "two\n", "three\n"},
+			want:       map[string]string{"": "three\n", ".1": "one\ntwo\n"},
+		},
+		{
+			name:       "drops the oldest backup",
+			maxBytes:   4,
+			maxBackups: 2,
+			writes:     []string{"one\n", "two\n", "six\n", "ten\n"},
+			want:       map[string]string{"": "ten\n", ".1": "six\n", ".2": "two\n"},
+		},
+		{
+			name:       "no backups",
+			maxBytes:   4,
+			maxBackups: 0,
+			writes:     []string{"one\n", "two\n"},
+			want:       map[string]string{"": "two\n"},
+		},
+		{
+			name:       "oversized write gets its own file",
+			maxBytes:   4,
+			maxBackups: 1,
+			writes:     []string{"one\n", "a long line\n"},
+			want:       map[string]string{"": "a long line\n", ".1": "one\n"},
+		},
+		{
+			name:       "no limit",
+			maxBytes:   0,
+			maxBackups: 1,
+			writes:     []string{"one\n", "two\n"},
+			want:       map[string]string{"": "one\ntwo\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "logs", "familiar.log")
+			f, err := NewRotatingFile(path, tt.maxBytes, tt.maxBackups)
+			if err != nil {
+				t.Fatalf("NewRotatingFile() error = %v", err)
+			}
+			for _, w := range tt.writes {
+				if _, err := f.Write([]byte(w)); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			f.Close()
+
+			files, _ := filepath.Glob(path + "*")
+			if len(files) != len(tt.want) {
+				t.Errorf("files = %v, want %d", files, len(tt.want))
+			}
+			for suffix, want := range tt.want {
+				got, err := os.ReadFile(path + suffix)
+				if err != nil {
+					t.Errorf("reading %s: %v", path+suffix, err)
+					continue
+				}
+				if string(got) != want {
+					t.Errorf("%s = %q, want %q", filepath.Base(path+suffix), got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRotatingFile_AppendsToExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "familiar.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The existing content counts toward the limit
+	f, err := NewRotatingFile(path, 8, 1)
+	if err != nil {
+		t.Fatalf("NewRotatingFile() error = %v", err)
+	}
+	f.Write([]byte("new\n"))
+	f.Write([]byte("next\n"))
+	f.Close()
+
+	if got, _ := os.ReadFile(path + ".1"); string(got) != "old\nnew\n" {
+		t.Errorf("backup = %q, want %q", got, "old\nnew\n")
+	}
+	if got, _ := os.ReadFile(path); string(got) != "next\n" {
+		t.Errorf("log = %q, want %q", got, "next\n")
+	}
+}
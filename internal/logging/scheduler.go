@@ -1,7 +1,7 @@
 package logging
 
 import (
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 )
@@ -38,11 +38,20 @@ func (s *CleanupScheduler) Start() {
 }
 
 func (s *CleanupScheduler) runCleanup() {
+	// Compress first so the size cap counts archives, not the logs they replace
+	compressed, err := s.cleaner.Compress()
+	if err != nil {
+		slog.Error("log compression failed", "error", err)
+	}
+	if compressed > 0 {
+		slog.Info("compressed aged log files", "compressed", compressed)
+	}
+
 	deleted, err := s.cleaner.Cleanup()
 	if err != nil {
-		log.Printf("Cleanup error: %v", err)
+		slog.Error("log cleanup failed", "error", err)
 	} else if deleted > 0 {
-		log.Printf("Cleaned up %d old log files", deleted)
+		slog.Info("cleaned up old log files", "deleted", deleted)
 	}
 }
 
@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewServerLogger(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   string
+		format  string
+		wantErr bool
+	}{
+		{name: "defaults"},
+		{name: "json debug", level: "debug", format: "json"},
+		{name: "uppercase", level: "WARN", format: "TEXT"},
+		{name: "bad level", level: "verbose", wantErr: true},
+		{name: "bad format", format: "xml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewServerLogger(&bytes.Buffer{}, tt.level, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewServerLogger() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewServerLogger_JSONFieldsAndLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewServerLogger(&buf, "warn", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("dropped")
+	logger.Warn("kept", "agent_id", "agent-1", "mr", 42)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d log lines, want only the warning:\n%s", len(lines), buf.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	if record["msg"] != "kept" || record["agent_id"] != "agent-1" || record["mr"] != float64(42) {
+		t.Errorf("record = %v", record)
+	}
+}
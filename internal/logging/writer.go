@@ -1,9 +1,17 @@
 package logging
 
 import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -15,16 +23,99 @@ type LogEntry struct {
 	MRNumber  int
 	EventType string
 	Timestamp time.Time
+
+	// CorrelationID identifies the webhook delivery that triggered the
+	// agent.
+	CorrelationID string
+
+	Prompt string // Only its hash is written
+	Image  string // Agent image reference
+}
+
+// headerFence delimits the metadata header at the top of each log.
+const headerFence = "---"
+
+// ErrNoHeader is returned by ReadHeader for logs without a metadata header.
+var ErrNoHeader = errors.New("log has no metadata header")
+
+// Header is the metadata written as JSON at the top of each log, between
+// "---" lines, recording where the log came from.
+type Header struct {
+	AgentID       string    `json:"agent_id"`
+	Repo          string    `json:"repo"` // owner/name
+	MR            int       `json:"mr"`
+	EventType     string    `json:"event_type"`
+	PromptSHA256  string    `json:"prompt_sha256,omitempty"`
+	Image         string    `json:"image,omitempty"`
+	StartedAt     time.Time `json:"started_at"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+}
+
+// header returns the metadata header for entry.
+func (entry LogEntry) header() Header {
+	h := Header{
+		AgentID:       entry.AgentID,
+		Repo:          entry.RepoOwner + "/" + entry.RepoName,
+		MR:            entry.MRNumber,
+		EventType:     entry.EventType,
+		Image:         entry.Image,
+		StartedAt:     entry.Timestamp,
+		CorrelationID: entry.CorrelationID,
+	}
+	if entry.Prompt != "" {
+		sum := sha256.Sum256([]byte(entry.Prompt))
+		h.PromptSHA256 = hex.EncodeToString(sum[:])
+	}
+	return h
+}
+
+// ReadHeader parses the metadata header at the start of a log.
+func ReadHeader(r io.Reader) (Header, error) {
+	var h Header
+	br := bufio.NewReader(r)
+	lines := make([]string, 3)
+	for i := range lines {
+		line, err := br.ReadString('\n')
+		if err == io.EOF {
+			return h, ErrNoHeader
+		}
+		if err != nil {
+			return h, fmt.Errorf("reading log header: %w", err)
+		}
+		lines[i] = strings.TrimSuffix(line, "\n")
+	}
+	if lines[0] != headerFence || lines[2] != headerFence {
+		return h, ErrNoHeader
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &h); err != nil {
+		return h, fmt.Errorf("parsing log header: %w", err)
+	}
+	return h, nil
 }
 
 // Writer manages log files organized by repository and MR.
 type Writer struct {
 	baseDir string
+	index   *Index
+}
+
+// WriterOption configures a Writer.
+type WriterOption func(*Writer)
+
+// WithIndex records each log the Writer creates in idx.
+func WithIndex(idx *Index) WriterOption {
+	return func(w *Writer) {
+		w.index = idx
+	}
 }
 
 // NewWriter creates a new Writer with the specified base directory.
-func NewWriter(baseDir string) *Writer {
-	return &Writer{baseDir: baseDir}
+func NewWriter(baseDir string, opts ...WriterOption) *Writer {
+	w := &Writer{baseDir: baseDir}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
 }
 
 // Create creates a new log file for the given entry and returns the path.
@@ -53,7 +144,30 @@ func (w *Writer) Create(entry LogEntry) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("creating log file: %w", err)
 	}
-	f.Close()
+	defer f.Close()
+
+	header, err := json.Marshal(entry.header())
+	if err != nil {
+		return "", fmt.Errorf("encoding log header: %w", err)
+	}
+	if _, err := fmt.Fprintf(f, "%s\n%s\n%s\n", headerFence, header, headerFence); err != nil {
+		return "", fmt.Errorf("writing log header: %w", err)
+	}
+
+	// The log is usable without its index entry, so a failure is only logged
+	if w.index != nil {
+		err := w.index.Add(IndexEntry{
+			AgentID:   entry.AgentID,
+			Repo:      entry.RepoOwner + "/" + entry.RepoName,
+			MR:        entry.MRNumber,
+			EventType: entry.EventType,
+			StartedAt: entry.Timestamp,
+			Path:      path,
+		})
+		if err != nil {
+			slog.Warn("failed to index log file", "path", path, "error", err)
+		}
+	}
 
 	return path, nil
 }
@@ -107,7 +107,7 @@ func TestLogWriter_Append_MultipleWrites(t *testing.T) {
 	}
 
 	expected := "line 1\nline 2\nline 3\n"
-	if string(content) != expected {
+	if !strings.HasSuffix(string(content), "---\n"+expected) {
 		t.Errorf("Content = %q, want %q", string(content), expected)
 	}
 }
@@ -121,3 +121,72 @@ func TestLogWriter_Append_NonexistentFile(t *testing.T) {
 		t.Error("Append() should error for nonexistent file")
 	}
 }
+
+func TestLogWriter_Create_Header(t *testing.T) {
+	writer := NewWriter(t.TempDir())
+	started := time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	logPath, err := writer.Create(LogEntry{
+		AgentID:       "agent-1",
+		RepoOwner:     "owner",
+		RepoName:      "repo",
+		MRNumber:      7,
+		EventType:     "mention",
+		Timestamp:     started,
+		CorrelationID: "abc123",
+		Prompt:        "fix the tests",
+		Image:         "familiar-agent:latest",
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	f, err := os.Open(logPath)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer f.Close()
+
+	got, err := ReadHeader(f)
+	if err != nil {
+		t.Fatalf("ReadHeader() error = %v", err)
+	}
+	want := Header{
+		AgentID:       "agent-1",
+		Repo:          "owner/repo",
+		MR:            7,
+		EventType:     "mention",
+		PromptSHA256:  "fb63226511e785db45040892fc67000d7c42634932fc4271ac6d7b3f7d4e3561",
+		Image:         "familiar-agent:latest",
+		StartedAt:     started,
+		CorrelationID: "abc123",
+	}
+	if got != want {
+		t.Errorf("ReadHeader() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr error
+	}{
+		{name: "valid", content: "---\n{\"agent_id\":\"a\"}\n---\noutput\n"},
+		{name: "no header", content: "output\nmore output\nand more\n", wantErr: ErrNoHeader},
+		{name: "empty", content: "", wantErr: ErrNoHeader},
+		{name: "truncated", content: "---\n{\"agent_id\":\"a\"}\n", wantErr: ErrNoHeader},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := ReadHeader(strings.NewReader(tt.content))
+			if err != tt.wantErr {
+				t.Fatalf("ReadHeader() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && h.AgentID != "a" {
+				t.Errorf("AgentID = %q, want %q", h.AgentID, "a")
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/awsauth"
+)
+
+// PutLogEvents limits. Each event counts its message plus a fixed overhead
+// toward the batch size.
+const (
+	cloudWatchMaxEvents     = 10000
+	cloudWatchMaxBatchBytes = 1 << 20
+	cloudWatchMaxEventBytes = 256 << 10
+	cloudWatchEventOverhead = 26
+)
+
+// CloudWatchConfig configures a CloudWatchSink. Credentials come from the
+// standard AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN
+// environment variables.
+type CloudWatchConfig struct {
+	Region    string
+	LogGroup  string
+	LogStream string
+	Endpoint  string // Overrides https://logs.<region>.amazonaws.com
+}
+
+// CloudWatchSink sends entries to a CloudWatch Logs stream, creating the
+// stream on first use.
+type CloudWatchSink struct {
+	cfg    CloudWatchConfig
+	creds  awsauth.Credentials
+	client *http.Client
+	now    func() time.Time
+
+	mu            sync.Mutex
+	streamCreated bool
+}
+
+// NewCloudWatchSink creates a sink for cfg's log group and stream.
+func NewCloudWatchSink(cfg CloudWatchConfig) (*CloudWatchSink, error) {
+	if cfg.Region == "" {
+		cfg.Region = os.Getenv("AWS_REGION")
+	}
+	if cfg.Region == "" || cfg.LogGroup == "" || cfg.LogStream == "" {
+		return nil, fmt.Errorf("cloudwatch sink requires a region, log_group, and log_stream")
+	}
+	creds := awsauth.CredentialsFromEnv()
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("cloudwatch sink requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://logs." + cfg.Region + ".amazonaws.com"
+	}
+	return &CloudWatchSink{cfg: cfg, creds: creds, client: &http.Client{}, now: time.Now}, nil
+}
+
+// Name implements Sink.
+func (s *CloudWatchSink) Name() string { return "cloudwatch" }
+
+type cloudWatchEvent struct {
+	Timestamp int64  `json:"timestamp"` // Unix milliseconds
+	Message   string `json:"message"`
+}
+
+// Send implements Sink.
+func (s *CloudWatchSink) Send(ctx context.Context, entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.streamCreated {
+		err := s.call(ctx, "CreateLogStream", map[string]string{
+			"logGroupName":  s.cfg.LogGroup,
+			"logStreamName": s.cfg.LogStream,
+		})
+		if err != nil && !strings.Contains(err.Error(), "ResourceAlreadyExistsException") {
+			return fmt.Errorf("creating log stream: %w", err)
+		}
+		s.streamCreated = true
+	}
+
+	// PutLogEvents requires events in chronological order
+	events := make([]cloudWatchEvent, len(entries))
+	for i, e := range entries {
+		msg := formatLine(e)
+		if len(msg) > cloudWatchMaxEventBytes-cloudWatchEventOverhead {
+			msg = msg[:cloudWatchMaxEventBytes-cloudWatchEventOverhead]
+		}
+		events[i] = cloudWatchEvent{Timestamp: e.Time.UnixMilli(), Message: msg}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp < events[j].Timestamp
+	})
+
+	for len(events) > 0 {
+		n, size := 0, 0
+		for n < len(events) && n < cloudWatchMaxEvents {
+			size += len(events[n].Message) + cloudWatchEventOverhead
+			if size > cloudWatchMaxBatchBytes {
+				break
+			}
+			n++
+		}
+		err := s.call(ctx, "PutLogEvents", map[string]any{
+			"logGroupName":  s.cfg.LogGroup,
+			"logStreamName": s.cfg.LogStream,
+			"logEvents":     events[:n],
+		})
+		if err != nil {
+			return fmt.Errorf("putting log events: %w", err)
+		}
+		events = events[n:]
+	}
+	return nil
+}
+
+// call invokes a CloudWatch Logs API action.
+func (s *CloudWatchSink) call(ctx context.Context, action string, params any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", action, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.Endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating %s request: %w", action, err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "Logs_20140328."+action)
+	awsauth.SignV4(req, body, s.creds, s.cfg.Region, "logs", s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s failed: %s: %s", action, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
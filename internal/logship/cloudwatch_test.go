@@ -0,0 +1,86 @@
+package logship
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloudWatchSink_Send(t *testing.T) {
+	var actions []string
+	var events []cloudWatchEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			t.Errorf("Authorization = %q, want a SigV4 signature", r.Header.Get("Authorization"))
+		}
+		if r.Header.Get("X-Amz-Security-Token") != "session" {
+			t.Errorf("X-Amz-Security-Token = %q, want %q", r.Header.Get("X-Amz-Security-Token"), "session")
+		}
+		action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "Logs_20140328.")
+		actions = append(actions, action)
+		switch action {
+		case "CreateLogStream":
+			// Already created by another Familiar instance
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceAlreadyExistsException"}`))
+		case "PutLogEvents":
+			var body struct {
+				LogGroupName  string            `json:"logGroupName"`
+				LogStreamName string            `json:"logStreamName"`
+				LogEvents     []cloudWatchEvent `json:"logEvents"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body.LogGroupName != "familiar" || body.LogStreamName != "host-1" {
+				t.Errorf("log group, stream = %q, %q", body.LogGroupName, body.LogStreamName)
+			}
+			events = append(events, body.LogEvents...)
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "session")
+	sink, err := NewCloudWatchSink(CloudWatchConfig{
+		Region:    "us-east-1",
+		LogGroup:  "familiar",
+		LogStream: "host-1",
+		Endpoint:  srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("NewCloudWatchSink() error = %v", err)
+	}
+
+	later := time.UnixMilli(2000)
+	earlier := time.UnixMilli(1000)
+	entries := []Entry{
+		{Time: later, Source: SourceServer, Line: "second"},
+		{Time: earlier, Source: SourceServer, Line: "first"},
+	}
+	if err := sink.Send(context.Background(), entries); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := sink.Send(context.Background(), entries[:1]); err != nil {
+		t.Fatalf("second Send() error = %v", err)
+	}
+
+	if got := strings.Join(actions, ","); got != "CreateLogStream,PutLogEvents,PutLogEvents" {
+		t.Errorf("actions = %q, want the stream created once", got)
+	}
+	if len(events) != 3 || events[0].Message != "source=server: first" || events[0].Timestamp != 1000 {
+		t.Errorf("events = %+v, want chronological order", events)
+	}
+}
+
+func TestNewCloudWatchSink_RequiresCredentials(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	_, err := NewCloudWatchSink(CloudWatchConfig{Region: "us-east-1", LogGroup: "g", LogStream: "s"})
+	if err == nil {
+		t.Error("NewCloudWatchSink() should require AWS credentials")
+	}
+}
@@ -0,0 +1,122 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LokiConfig configures a LokiSink.
+type LokiConfig struct {
+	URL      string            // Push endpoint, e.g. http://loki:3100/loki/api/v1/push
+	TenantID string            // Sent as X-Scope-OrgID when set
+	Username string            // Basic auth, when set
+	Password string            //
+	Labels   map[string]string // Added to every stream
+}
+
+// LokiSink pushes entries to Grafana Loki. Each distinct set of source and
+// labels is its own stream.
+type LokiSink struct {
+	cfg    LokiConfig
+	client *http.Client
+}
+
+// NewLokiSink creates a sink that pushes to cfg.URL.
+func NewLokiSink(cfg LokiConfig) (*LokiSink, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("loki sink requires a url")
+	}
+	return &LokiSink{cfg: cfg, client: &http.Client{}}, nil
+}
+
+// Name implements Sink.
+func (s *LokiSink) Name() string { return "loki" }
+
+// lokiPush is the body of a Loki push request.
+type lokiPush struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"` // [unix nanoseconds, line]
+}
+
+// Send implements Sink.
+func (s *LokiSink) Send(ctx context.Context, entries []Entry) error {
+	body, err := json.Marshal(s.push(entries))
+	if err != nil {
+		return fmt.Errorf("encoding loki push: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating loki request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.cfg.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", s.cfg.TenantID)
+	}
+	if s.cfg.Username != "" {
+		req.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("pushing to loki: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("loki push failed: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// push groups entries into streams, keeping their order within each stream.
+func (s *LokiSink) push(entries []Entry) lokiPush {
+	var push lokiPush
+	index := make(map[string]int)
+	for _, e := range entries {
+		labels := map[string]string{"job": "familiar"}
+		for k, v := range s.cfg.Labels {
+			labels[k] = v
+		}
+		for k, v := range e.Labels {
+			labels[k] = v
+		}
+		labels["source"] = e.Source
+
+		key := streamKey(labels)
+		i, ok := index[key]
+		if !ok {
+			i = len(push.Streams)
+			index[key] = i
+			push.Streams = append(push.Streams, lokiStream{Stream: labels})
+		}
+		push.Streams[i].Values = append(push.Streams[i].Values,
+			[2]string{strconv.FormatInt(e.Time.UnixNano(), 10), e.Line})
+	}
+	return push
+}
+
+// streamKey identifies a label set.
+func streamKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%q,", k, labels[k])
+	}
+	return b.String()
+}
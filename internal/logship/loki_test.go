@@ -0,0 +1,82 @@
+package logship
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLokiSink_Send(t *testing.T) {
+	var got lokiPush
+	var tenant, user, pass string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = r.Header.Get("X-Scope-OrgID")
+		user, pass, _ = r.BasicAuth()
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding push: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sink, err := NewLokiSink(LokiConfig{
+		URL:      srv.URL,
+		TenantID: "team-a",
+		Username: "user",
+		Password: "secret",
+		Labels:   map[string]string{"env": "prod"},
+	})
+	if err != nil {
+		t.Fatalf("NewLokiSink() error = %v", err)
+	}
+
+	at := time.Unix(1700000000, 5)
+	agent := map[string]string{"agent_id": "agent-1"}
+	err = sink.Send(context.Background(), []Entry{
+		{Time: at, Source: SourceServer, Line: "server 1"},
+		{Time: at, Source: SourceAgent, Labels: agent, Line: "agent 1"},
+		{Time: at, Source: SourceServer, Line: "server 2"},
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if tenant != "team-a" || user != "user" || pass != "secret" {
+		t.Errorf("tenant, user, pass = %q, %q, %q", tenant, user, pass)
+	}
+	if len(got.Streams) != 2 {
+		t.Fatalf("streams = %+v, want 2", got.Streams)
+	}
+	server := got.Streams[0]
+	if server.Stream["source"] != SourceServer || server.Stream["env"] != "prod" || server.Stream["job"] != "familiar" {
+		t.Errorf("server stream labels = %v", server.Stream)
+	}
+	if len(server.Values) != 2 || server.Values[0] != [2]string{"1700000000000000005", "server 1"} || server.Values[1][1] != "server 2" {
+		t.Errorf("server stream values = %v", server.Values)
+	}
+	if got.Streams[1].Stream["agent_id"] != "agent-1" || got.Streams[1].Stream["source"] != SourceAgent {
+		t.Errorf("agent stream labels = %v", got.Streams[1].Stream)
+	}
+}
+
+func TestLokiSink_SendError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "too many streams", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	sink, _ := NewLokiSink(LokiConfig{URL: srv.URL})
+	err := sink.Send(context.Background(), []Entry{{Time: time.Now(), Source: SourceServer, Line: "x"}})
+	if err == nil {
+		t.Fatal("Send() should fail on a non-2xx response")
+	}
+}
+
+func TestNewLokiSink_RequiresURL(t *testing.T) {
+	if _, err := NewLokiSink(LokiConfig{}); err == nil {
+		t.Error("NewLokiSink() should require a url")
+	}
+}
@@ -0,0 +1,290 @@
+// Package logship forwards Familiar's server logs and agent logs to external
+// log stores such as syslog, Loki, or CloudWatch Logs.
+package logship
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Log sources.
+const (
+	SourceServer = "server" // Familiar's own logs
+	SourceAgent  = "agent"  // an agent's captured output
+)
+
+// Defaults for a Shipper's batching and retry behavior.
+const (
+	DefaultBatchSize     = 500
+	DefaultFlushInterval = 5 * time.Second
+	DefaultMaxRetries    = 3
+	DefaultBufferSize    = 10000
+)
+
+// retryBackoff is the delay before the first retry; it doubles each attempt.
+var retryBackoff = time.Second
+
+// Entry is one log line bound for a sink.
+type Entry struct {
+	Time   time.Time
+	Source string            // SourceServer or SourceAgent
+	Labels map[string]string // e.g. repo, mr, and agent_id for agent logs
+	Line   string
+}
+
+// Sink delivers batches of entries to an external log store.
+type Sink interface {
+	// Name identifies the sink in Familiar's own logs.
+	Name() string
+	// Send delivers entries, which are in the order they were shipped.
+	Send(ctx context.Context, entries []Entry) error
+}
+
+// Option configures a Shipper.
+type Option func(*Shipper)
+
+// WithBatchSize sets how many entries are sent to a sink at once.
+func WithBatchSize(n int) Option {
+	return func(s *Shipper) {
+		if n > 0 {
+			s.batchSize = n
+		}
+	}
+}
+
+// WithFlushInterval sets how long entries may wait for a batch to fill.
+func WithFlushInterval(d time.Duration) Option {
+	return func(s *Shipper) {
+		if d > 0 {
+			s.flushInterval = d
+		}
+	}
+}
+
+// WithMaxRetries sets how many times a failed batch is retried before it is
+// dropped.
+func WithMaxRetries(n int) Option {
+	return func(s *Shipper) {
+		if n >= 0 {
+			s.maxRetries = n
+		}
+	}
+}
+
+// WithBufferSize sets how many entries may wait for each sink.
+func WithBufferSize(n int) Option {
+	return func(s *Shipper) {
+		if n > 0 {
+			s.bufferSize = n
+		}
+	}
+}
+
+// Shipper batches entries and sends them to every sink, retrying failed
+// batches with backoff. Each sink has its own queue, so a slow sink does not
+// hold up the others.
+type Shipper struct {
+	batchSize     int
+	flushInterval time.Duration
+	maxRetries    int
+	bufferSize    int
+
+	queues  []*sinkQueue
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	started atomic.Bool
+	stopped sync.Once
+}
+
+// sinkQueue holds entries waiting for one sink.
+type sinkQueue struct {
+	sink    Sink
+	entries chan Entry
+	dropped atomic.Int64
+}
+
+// New creates a Shipper for the given sinks. Call Start to begin shipping.
+func New(sinks []Sink, opts ...Option) *Shipper {
+	s := &Shipper{
+		batchSize:     DefaultBatchSize,
+		flushInterval: DefaultFlushInterval,
+		maxRetries:    DefaultMaxRetries,
+		bufferSize:    DefaultBufferSize,
+		stop:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	for _, sink := range sinks {
+		s.queues = append(s.queues, &sinkQueue{sink: sink, entries: make(chan Entry, s.bufferSize)})
+	}
+	return s
+}
+
+// Start begins delivering entries to the sinks.
+func (s *Shipper) Start() {
+	if s.started.Swap(true) {
+		return
+	}
+	for _, q := range s.queues {
+		s.wg.Add(1)
+		go s.run(q)
+	}
+}
+
+// Stop flushes queued entries and stops shipping. Entries shipped after Stop
+// are dropped.
+func (s *Shipper) Stop() {
+	s.stopped.Do(func() {
+		close(s.stop)
+		s.wg.Wait()
+	})
+}
+
+// Ship queues an entry for every sink without blocking. Entries for a sink
+// whose queue is full are dropped and counted.
+func (s *Shipper) Ship(e Entry) {
+	for _, q := range s.queues {
+		select {
+		case q.entries <- e:
+		default:
+			q.dropped.Add(1)
+		}
+	}
+}
+
+// ShipReader queues each line of r for every sink, waiting for room in full
+// queues until ctx is done.
+func (s *Shipper) ShipReader(ctx context.Context, source string, labels map[string]string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		e := Entry{Time: time.Now(), Source: source, Labels: labels, Line: scanner.Text()}
+		for _, q := range s.queues {
+			select {
+			case q.entries <- e:
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-s.stop:
+				return fmt.Errorf("log shipper stopped")
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// Writer returns a writer that ships each line written to it, for teeing
+// Familiar's own log output. Writes never block or fail.
+func (s *Shipper) Writer(source string, labels map[string]string) io.Writer {
+	return &lineWriter{shipper: s, source: source, labels: labels}
+}
+
+// lineWriter ships each line of every write.
+type lineWriter struct {
+	shipper *Shipper
+	source  string
+	labels  map[string]string
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	now := time.Now()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		w.shipper.Ship(Entry{Time: now, Source: w.source, Labels: w.labels, Line: line})
+	}
+	return len(p), nil
+}
+
+// run batches a sink's entries and sends them until the shipper stops.
+func (s *Shipper) run(q *sinkQueue) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Entry, 0, s.batchSize)
+	flush := func() {
+		if dropped := q.dropped.Swap(0); dropped > 0 {
+			slog.Warn("dropped log entries; shipping queue is full", "sink", q.sink.Name(), "dropped", dropped)
+		}
+		if len(batch) == 0 {
+			return
+		}
+		s.send(q.sink, batch)
+		batch = make([]Entry, 0, s.batchSize)
+	}
+
+	for {
+		select {
+		case e := <-q.entries:
+			batch = append(batch, e)
+			if len(batch) >= s.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-s.stop:
+			// Drain what is already queued
+			for {
+				select {
+				case e := <-q.entries:
+					batch = append(batch, e)
+					if len(batch) >= s.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// send delivers a batch, retrying with exponential backoff. A batch that
+// still fails is dropped.
+func (s *Shipper) send(sink Sink, batch []Entry) {
+	backoff := retryBackoff
+	var err error
+	for attempt := 0; attempt <= s.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err = sink.Send(ctx, batch)
+		cancel()
+		if err == nil {
+			return
+		}
+	}
+	slog.Warn("dropped log batch after retries", "sink", sink.Name(), "entries", len(batch), "error", err)
+}
+
+// formatLine renders an entry as a single line for sinks without labels,
+// prefixing the source and labels as sorted key=value pairs.
+func formatLine(e Entry) string {
+	var b strings.Builder
+	b.WriteString("source=")
+	b.WriteString(e.Source)
+	keys := make([]string, 0, len(e.Labels))
+	for k := range e.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%s", k, e.Labels[k])
+	}
+	b.WriteString(": ")
+	b.WriteString(e.Line)
+	return b.String()
+}
@@ -0,0 +1,174 @@
+package logship
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink records every batch it receives, failing the first
+// failures calls.
+type recordingSink struct {
+	mu       sync.Mutex
+	batches  [][]Entry
+	failures int
+	calls    int
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Send(_ context.Context, entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.calls <= s.failures {
+		return errors.New("sink unavailable")
+	}
+	s.batches = append(s.batches, append([]Entry(nil), entries...))
+	return nil
+}
+
+func (s *recordingSink) lines() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var lines []string
+	for _, batch := range s.batches {
+		for _, e := range batch {
+			lines = append(lines, e.Line)
+		}
+	}
+	return lines
+}
+
+func init() {
+	retryBackoff = time.Millisecond
+}
+
+func TestShipper_BatchesAndFlushesOnStop(t *testing.T) {
+	sink := &recordingSink{}
+	s := New([]Sink{sink}, WithBatchSize(2), WithFlushInterval(time.Hour))
+	s.Start()
+
+	for _, line := range []string{"one", "two", "three"} {
+		s.Ship(Entry{Source: SourceServer, Line: line})
+	}
+	s.Stop()
+
+	if got := strings.Join(sink.lines(), ","); got != "one,two,three" {
+		t.Errorf("shipped lines = %q, want %q", got, "one,two,three")
+	}
+	if len(sink.batches) != 2 || len(sink.batches[0]) != 2 {
+		t.Errorf("batches = %v, want a full batch of 2 then the remainder", sink.batches)
+	}
+}
+
+func TestShipper_FlushesOnInterval(t *testing.T) {
+	sink := &recordingSink{}
+	s := New([]Sink{sink}, WithBatchSize(100), WithFlushInterval(10*time.Millisecond))
+	s.Start()
+	defer s.Stop()
+
+	s.Ship(Entry{Source: SourceServer, Line: "waiting"})
+
+	deadline := time.Now().Add(time.Second)
+	for len(sink.lines()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := sink.lines(); len(got) != 1 {
+		t.Errorf("shipped lines = %v, want the entry flushed on the interval", got)
+	}
+}
+
+func TestShipper_Retries(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		wantLines int
+		wantCalls int
+	}{
+		{name: "succeeds after retries", failures: 2, wantLines: 1, wantCalls: 3},
+		{name: "drops after max retries", failures: 10, wantLines: 0, wantCalls: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &recordingSink{failures: tt.failures}
+			s := New([]Sink{sink}, WithMaxRetries(2), WithFlushInterval(time.Hour))
+			s.Start()
+			s.Ship(Entry{Source: SourceServer, Line: "line"})
+			s.Stop()
+
+			if got := len(sink.lines()); got != tt.wantLines {
+				t.Errorf("shipped lines = %d, want %d", got, tt.wantLines)
+			}
+			if sink.calls != tt.wantCalls {
+				t.Errorf("Send calls = %d, want %d", sink.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestShipper_DropsWhenQueueFull(t *testing.T) {
+	sink := &recordingSink{}
+	s := New([]Sink{sink}, WithBufferSize(2))
+
+	// Not started, so nothing drains the queue
+	for i := 0; i < 5; i++ {
+		s.Ship(Entry{Source: SourceServer, Line: "line"})
+	}
+	if dropped := s.queues[0].dropped.Load(); dropped != 3 {
+		t.Errorf("dropped = %d, want 3", dropped)
+	}
+}
+
+func TestShipper_Writer(t *testing.T) {
+	sink := &recordingSink{}
+	s := New([]Sink{sink}, WithFlushInterval(time.Hour))
+	s.Start()
+
+	w := s.Writer(SourceServer, map[string]string{"host": "a"})
+	w.Write([]byte("first line\nsecond line\n"))
+	w.Write([]byte("\n"))
+	s.Stop()
+
+	if got := strings.Join(sink.lines(), ","); got != "first line,second line" {
+		t.Errorf("shipped lines = %q, want %q", got, "first line,second line")
+	}
+	if e := sink.batches[0][0]; e.Source != SourceServer || e.Labels["host"] != "a" {
+		t.Errorf("entry = %+v, want server source and labels", e)
+	}
+}
+
+func TestShipper_ShipReader(t *testing.T) {
+	sinks := []*recordingSink{{}, {}}
+	s := New([]Sink{sinks[0], sinks[1]}, WithBufferSize(1), WithFlushInterval(time.Hour))
+	s.Start()
+
+	// More lines than the queue holds: ShipReader waits instead of dropping
+	labels := map[string]string{"agent_id": "agent-1"}
+	if err := s.ShipReader(context.Background(), SourceAgent, labels, strings.NewReader("a\nb\nc\n")); err != nil {
+		t.Fatalf("ShipReader() error = %v", err)
+	}
+	s.Stop()
+
+	for i, sink := range sinks {
+		if got := strings.Join(sink.lines(), ","); got != "a,b,c" {
+			t.Errorf("sink %d lines = %q, want %q", i, got, "a,b,c")
+		}
+	}
+}
+
+func TestFormatLine(t *testing.T) {
+	e := Entry{
+		Source: SourceAgent,
+		Labels: map[string]string{"repo": "owner/repo", "agent_id": "agent-1"},
+		Line:   "hello",
+	}
+	want := "source=agent agent_id=agent-1 repo=owner/repo: hello"
+	if got := formatLine(e); got != want {
+		t.Errorf("formatLine() = %q, want %q", got, want)
+	}
+}
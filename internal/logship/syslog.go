@@ -0,0 +1,67 @@
+package logship
+
+import (
+	"context"
+	"fmt"
+	"log/syslog"
+	"net/url"
+	"sync"
+)
+
+// SyslogSink sends entries to a syslog daemon, one message per entry.
+type SyslogSink struct {
+	network string
+	addr    string
+	tag     string
+
+	mu     sync.Mutex
+	writer *syslog.Writer
+}
+
+// NewSyslogSink creates a sink for the syslog daemon at address, given as
+// "udp://host:port", "tcp://host:port", or "unix:///dev/log". An empty
+// address uses the local daemon. Messages are tagged with tag.
+func NewSyslogSink(address, tag string) (*SyslogSink, error) {
+	s := &SyslogSink{tag: tag}
+	if address != "" {
+		u, err := url.Parse(address)
+		if err != nil {
+			return nil, fmt.Errorf("parsing syslog address: %w", err)
+		}
+		switch u.Scheme {
+		case "udp", "tcp":
+			s.network, s.addr = u.Scheme, u.Host
+		case "unix", "unixgram":
+			s.network, s.addr = u.Scheme, u.Path
+		default:
+			return nil, fmt.Errorf("unsupported syslog address %q: use udp://, tcp://, or unix://", address)
+		}
+	}
+	return s, nil
+}
+
+// Name implements Sink.
+func (s *SyslogSink) Name() string { return "syslog" }
+
+// Send implements Sink. The connection is opened on first use and reopened
+// after a failure.
+func (s *SyslogSink) Send(_ context.Context, entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.writer == nil {
+		w, err := syslog.Dial(s.network, s.addr, syslog.LOG_INFO|syslog.LOG_DAEMON, s.tag)
+		if err != nil {
+			return fmt.Errorf("connecting to syslog: %w", err)
+		}
+		s.writer = w
+	}
+	for i, e := range entries {
+		if err := s.writer.Info(formatLine(e)); err != nil {
+			s.writer.Close()
+			s.writer = nil
+			return fmt.Errorf("writing to syslog after %d of %d entries: %w", i, len(entries), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package logship
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogSink_Send(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	defer conn.Close()
+
+	sink, err := NewSyslogSink("udp://"+conn.LocalAddr().String(), "familiar")
+	if err != nil {
+		t.Fatalf("NewSyslogSink() error = %v", err)
+	}
+	err = sink.Send(context.Background(), []Entry{{
+		Time:   time.Now(),
+		Source: SourceAgent,
+		Labels: map[string]string{"agent_id": "agent-1"},
+		Line:   "hello",
+	}})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	buf := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("reading syslog message: %v", err)
+	}
+	msg := string(buf[:n])
+	if !strings.Contains(msg, "familiar") || !strings.Contains(msg, "source=agent agent_id=agent-1: hello") {
+		t.Errorf("syslog message = %q", msg)
+	}
+}
+
+func TestNewSyslogSink_Address(t *testing.T) {
+	tests := []struct {
+		address     string
+		wantNetwork string
+		wantAddr    string
+		wantErr     bool
+	}{
+		{address: "udp://logs:514", wantNetwork: "udp", wantAddr: "logs:514"},
+		{address: "tcp://logs:601", wantNetwork: "tcp", wantAddr: "logs:601"},
+		{address: "unix:///dev/log", wantNetwork: "unix", wantAddr: "/dev/log"},
+		{address: ""},
+		{address: "http://logs", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			sink, err := NewSyslogSink(tt.address, "familiar")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSyslogSink() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if sink.network != tt.wantNetwork || sink.addr != tt.wantAddr {
+				t.Errorf("network, addr = %q, %q, want %q, %q", sink.network, sink.addr, tt.wantNetwork, tt.wantAddr)
+			}
+		})
+	}
+}
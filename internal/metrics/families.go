@@ -0,0 +1,193 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+// Metric types of a Family.
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// Family is one named metric and its current values, as exported to
+// Prometheus and push exporters. Histogram families have Histogram set and
+// no samples.
+type Family struct {
+	Name      string
+	Help      string
+	Type      string
+	Samples   []Sample
+	Histogram *Histogram
+}
+
+// Sample is one value of a metric. Labels alternate names and values.
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// processStart is when counting began in this process.
+var processStart = time.Now()
+
+// ProcessStart returns when this process started counting metrics.
+func ProcessStart() time.Time { return processStart }
+
+// Families lists m's metrics in a fixed order. Families without samples,
+// such as per-repo counters before any runs, are omitted.
+func Families(m Metrics) []Family {
+	var f families
+
+	f.counter("familiar_agents_spawned_total", "Agents spawned.", value(m.AgentsSpawned))
+	f.counter("familiar_agents_completed_total", "Agents that completed successfully.", value(m.AgentsCompleted))
+
+	var reasons []Sample
+	for _, reason := range sortedKeys(m.FailureReasons) {
+		reasons = append(reasons, Sample{Labels: []string{"reason", reason}, Value: float64(m.FailureReasons[reason])})
+	}
+	f.counter("familiar_agents_failed_total", "Agents that failed, by where they failed.", reasons...)
+
+	f.counter("familiar_agents_timed_out_total", "Agents stopped for exceeding their timeout.", value(m.AgentsTimedOut))
+	f.counter("familiar_webhooks_received_total", "Webhooks received.", value(m.WebhooksReceived))
+	f.counter("familiar_webhooks_processed_total", "Webhooks processed.", value(m.WebhooksProcessed))
+	f.counter("familiar_protected_path_changes_total", "Agents that changed a protected path.", value(m.ProtectedPathChanges))
+
+	var failures []Sample
+	for _, category := range sortedKeys(m.FailureCategories) {
+		failures = append(failures, Sample{Labels: []string{"category", category}, Value: float64(m.FailureCategories[category])})
+	}
+	f.counter("familiar_agent_failures_total", "Failed agent runs by cause.", failures...)
+
+	f.counter("familiar_agent_runs_total", "Agent runs with recorded usage.", value(m.AgentUsage.Runs))
+	f.counter("familiar_agent_tokens_total", "Tokens used by agents, by token type.", tokenSamples(nil, m.AgentUsage)...)
+	f.counter("familiar_agent_cost_usd_total", "Estimated cost of agent runs in US dollars.", Sample{Value: m.AgentUsage.CostUSD})
+
+	var repoRuns, repoTokens, repoCost []Sample
+	for _, repo := range sortedKeys(m.RepoUsage) {
+		u := m.RepoUsage[repo]
+		labels := []string{"repo", repo}
+		repoRuns = append(repoRuns, Sample{Labels: labels, Value: float64(u.Runs)})
+		repoTokens = append(repoTokens, tokenSamples(labels, u)...)
+		repoCost = append(repoCost, Sample{Labels: labels, Value: u.CostUSD})
+	}
+	f.counter("familiar_repo_agent_runs_total", "Agent runs with recorded usage, by repo.", repoRuns...)
+	f.counter("familiar_repo_agent_tokens_total", "Tokens used by agents, by repo and token type.", repoTokens...)
+	f.counter("familiar_repo_agent_cost_usd_total", "Estimated cost of agent runs in US dollars, by repo.", repoCost...)
+
+	var intentCalls, intentFailures, intentLatency, intentTokens, intentCost []Sample
+	for _, backend := range sortedKeys(m.IntentUsage) {
+		u := m.IntentUsage[backend]
+		labels := []string{"backend", backend}
+		intentCalls = append(intentCalls, Sample{Labels: labels, Value: float64(u.Calls)})
+		intentFailures = append(intentFailures, Sample{Labels: labels, Value: float64(u.Failures)})
+		intentLatency = append(intentLatency, Sample{Labels: labels, Value: u.LatencySeconds})
+		intentTokens = append(intentTokens,
+			Sample{Labels: []string{"backend", backend, "type", "input"}, Value: float64(u.InputTokens)},
+			Sample{Labels: []string{"backend", backend, "type", "output"}, Value: float64(u.OutputTokens)})
+		intentCost = append(intentCost, Sample{Labels: labels, Value: u.CostUSD})
+	}
+	f.counter("familiar_intent_parser_calls_total", "Intent parser calls, by backend.", intentCalls...)
+	f.counter("familiar_intent_parser_failures_total", "Intent parser calls that failed, by backend.", intentFailures...)
+	f.counter("familiar_intent_parser_seconds_total", "Time spent parsing intent, by backend.", intentLatency...)
+	f.counter("familiar_intent_parser_tokens_total", "Tokens used parsing intent, by backend and token type.", intentTokens...)
+	f.counter("familiar_intent_parser_cost_usd_total", "Estimated cost of parsing intent in US dollars, by backend.", intentCost...)
+
+	f.gauge("familiar_active_agents", "Agents currently running.", value(m.ActiveAgents))
+	f.gauge("familiar_queued_agents", "Agents waiting for a free slot.", value(m.QueuedAgents))
+
+	r := m.AgentResources
+	f.gauge("familiar_agent_resources_agents", "Running agents in the latest resource sample.", value(r.Agents))
+	f.gauge("familiar_agent_cpu_percent", "CPU use summed across running agents.", Sample{Value: r.CPUPercent})
+	f.gauge("familiar_agent_memory_bytes", "Memory use summed across running agents.", value(r.MemoryBytes))
+	f.gauge("familiar_agent_peak_memory_bytes", "Highest memory use of any one agent since startup.", value(r.PeakMemoryBytes))
+
+	if c := m.RepoCache; c != nil {
+		f.gauge("familiar_repo_cache_size_bytes", "Disk used by cached repos, including worktrees.", value(c.SizeBytes))
+		f.gauge("familiar_repo_cache_max_bytes", "Configured repo cache size limit; 0 means none.", value(c.MaxBytes))
+		f.gauge("familiar_repo_cache_worktrees", "Agent worktrees in the repo cache.", value(c.Worktrees))
+		f.gauge("familiar_repo_cache_volume_bytes", "Size of the filesystem holding the repo cache.", value(c.VolumeBytes))
+		f.gauge("familiar_repo_cache_volume_free_bytes", "Space available on the filesystem holding the repo cache.", value(c.VolumeFreeBytes))
+
+		var sizes, worktrees []Sample
+		for _, repo := range sortedKeys(c.Repos) {
+			labels := []string{"repo", repo}
+			sizes = append(sizes, Sample{Labels: labels, Value: float64(c.Repos[repo].SizeBytes)})
+			worktrees = append(worktrees, Sample{Labels: labels, Value: float64(c.Repos[repo].Worktrees)})
+		}
+		f.gauge("familiar_repo_cache_repo_size_bytes", "Disk used by a cached repo, including its worktrees.", sizes...)
+		f.gauge("familiar_repo_cache_repo_worktrees", "Agent worktrees of a cached repo.", worktrees...)
+	}
+
+	f.counter("familiar_agent_runs_finished_total", "Finished agent runs by provider, repo, event type, and outcome.", labeledSamples(m.AgentRuns)...)
+	f.counter("familiar_events_routed_total", "Events handed to the agent handler by provider, repo, and event type.", labeledSamples(m.EventsRouted)...)
+
+	for _, h := range latencies {
+		if snap, ok := m.Latencies[h.name]; ok {
+			f = append(f, Family{Name: h.promName, Help: h.help, Type: TypeHistogram, Histogram: &snap})
+		}
+	}
+
+	return f
+}
+
+// families collects metric families, dropping those without samples.
+type families []Family
+
+func (f *families) counter(name, help string, samples ...Sample) {
+	f.add(name, TypeCounter, help, samples)
+}
+
+func (f *families) gauge(name, help string, samples ...Sample) {
+	f.add(name, TypeGauge, help, samples)
+}
+
+func (f *families) add(name, typ, help string, samples []Sample) {
+	if len(samples) > 0 {
+		*f = append(*f, Family{Name: name, Help: help, Type: typ, Samples: samples})
+	}
+}
+
+// value is an unlabeled sample.
+func value[T uint64 | int64 | int | float64](v T) Sample {
+	return Sample{Value: float64(v)}
+}
+
+// tokenSamples splits u's token counts by a "type" label, after labels.
+func tokenSamples(labels []string, u Usage) []Sample {
+	var samples []Sample
+	for _, t := range []struct {
+		name  string
+		count uint64
+	}{
+		{"input", u.InputTokens},
+		{"output", u.OutputTokens},
+		{"cache_read", u.CacheReadTokens},
+		{"cache_write", u.CacheWriteTokens},
+	} {
+		samples = append(samples, Sample{
+			Labels: append(append([]string(nil), labels...), "type", t.name),
+			Value:  float64(t.count),
+		})
+	}
+	return samples
+}
+
+// labeledSamples converts labeled counts to samples.
+func labeledSamples(counts []LabeledCount) []Sample {
+	samples := make([]Sample, 0, len(counts))
+	for _, c := range counts {
+		samples = append(samples, Sample{Labels: c.pairs(), Value: float64(c.Count)})
+	}
+	return samples
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// Histogram is a snapshot of a latency distribution. Bucket counts are
+// cumulative, as in Prometheus; Count is the implicit +Inf bucket.
+type Histogram struct {
+	Count      uint64   `json:"count"`
+	SumSeconds float64  `json:"sum_seconds"`
+	Buckets    []Bucket `json:"buckets"`
+}
+
+// Bucket counts observations of at most UpperBound seconds.
+type Bucket struct {
+	UpperBound float64 `json:"le"`
+	Count      uint64  `json:"count"`
+}
+
+// Bucket bounds in seconds: pipeline steps take milliseconds to minutes,
+// agent runs minutes to hours.
+var (
+	stepBuckets     = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+	agentRunBuckets = []float64{30, 60, 120, 300, 600, 900, 1800, 3600, 7200}
+)
+
+// Latency histograms, keyed by operation in Metrics.Latencies.
+var latencies = []*histogram{
+	{name: "webhook", promName: "familiar_webhook_handling_seconds", help: "Time to handle a webhook request.", bounds: stepBuckets},
+	{name: "intent_parse", promName: "familiar_intent_parse_seconds", help: "Time to parse intent from a comment.", bounds: stepBuckets},
+	{name: "repo_ensure", promName: "familiar_repo_ensure_seconds", help: "Time to clone or fetch a repo into the cache.", bounds: stepBuckets},
+	{name: "worktree_create", promName: "familiar_worktree_create_seconds", help: "Time to create an agent worktree.", bounds: stepBuckets},
+	{name: "agent_run", promName: "familiar_agent_run_seconds", help: "How long agents ran, from spawn to stop.", bounds: agentRunBuckets},
+}
+
+var (
+	webhookLatency        = latencies[0]
+	intentParseLatency    = latencies[1]
+	repoEnsureLatency     = latencies[2]
+	worktreeCreateLatency = latencies[3]
+	agentRunLatency       = latencies[4]
+)
+
+// WebhookHandled records how long a webhook request took.
+func WebhookHandled(d time.Duration) { webhookLatency.observe(d) }
+
+// IntentParsed records how long parsing a comment's intent took.
+func IntentParsed(d time.Duration) { intentParseLatency.observe(d) }
+
+// RepoEnsured records how long cloning or fetching a repo took.
+func RepoEnsured(d time.Duration) { repoEnsureLatency.observe(d) }
+
+// WorktreeCreated records how long creating an agent worktree took.
+func WorktreeCreated(d time.Duration) { worktreeCreateLatency.observe(d) }
+
+// AgentRunFinished records how long an agent ran.
+func AgentRunFinished(d time.Duration) { agentRunLatency.observe(d) }
+
+// histogram accumulates observations into fixed buckets.
+type histogram struct {
+	name     string
+	promName string
+	help     string
+	bounds   []float64
+
+	mu     sync.Mutex
+	counts []uint64 // per bucket, not cumulative; the last is +Inf
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	v := d.Seconds()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.counts == nil {
+		h.counts = make([]uint64, len(h.bounds)+1)
+	}
+	i := 0
+	for i < len(h.bounds) && v > h.bounds[i] {
+		i++
+	}
+	h.counts[i]++
+	h.count++
+	h.sum += v
+}
+
+func (h *histogram) snapshot() Histogram {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	snap := Histogram{Count: h.count, SumSeconds: h.sum, Buckets: make([]Bucket, len(h.bounds))}
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		if h.counts != nil {
+			cumulative += h.counts[i]
+		}
+		snap.Buckets[i] = Bucket{UpperBound: bound, Count: cumulative}
+	}
+	return snap
+}
+
+func (h *histogram) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts, h.count, h.sum = nil, 0, 0
+}
+
+// latencySnapshots returns a snapshot of every latency histogram that has
+// observations.
+func latencySnapshots() map[string]Histogram {
+	var snaps map[string]Histogram
+	for _, h := range latencies {
+		snap := h.snapshot()
+		if snap.Count == 0 {
+			continue
+		}
+		if snaps == nil {
+			snaps = make(map[string]Histogram, len(latencies))
+		}
+		snaps[h.name] = snap
+	}
+	return snaps
+}
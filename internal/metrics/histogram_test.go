@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistograms(t *testing.T) {
+	Reset()
+
+	if Get().Latencies != nil {
+		t.Fatal("Latencies should be empty before any observations")
+	}
+
+	WebhookHandled(5 * time.Millisecond)
+	WebhookHandled(300 * time.Millisecond)
+	WebhookHandled(10 * time.Minute)
+
+	got, ok := Get().Latencies["webhook"]
+	if !ok {
+		t.Fatal("Latencies missing webhook")
+	}
+	if got.Count != 3 {
+		t.Errorf("Count = %d, want 3", got.Count)
+	}
+	if want := 600.305; got.SumSeconds < want-1e-9 || got.SumSeconds > want+1e-9 {
+		t.Errorf("SumSeconds = %v, want %v", got.SumSeconds, want)
+	}
+
+	// Counts are cumulative; the 10 minute observation only lands in +Inf
+	for _, tt := range []struct {
+		le   float64
+		want uint64
+	}{
+		{0.01, 1},
+		{0.25, 1},
+		{0.5, 2},
+		{300, 2},
+	} {
+		var found bool
+		for _, b := range got.Buckets {
+			if b.UpperBound == tt.le {
+				found = true
+				if b.Count != tt.want {
+					t.Errorf("bucket le=%v count = %d, want %d", tt.le, b.Count, tt.want)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("bucket le=%v missing", tt.le)
+		}
+	}
+
+	if _, ok := Get().Latencies["agent_run"]; ok {
+		t.Error("unobserved operations should be omitted")
+	}
+	if _, err := json.Marshal(Get()); err != nil {
+		t.Errorf("json.Marshal() error = %v", err)
+	}
+
+	Reset()
+	if Get().Latencies != nil {
+		t.Error("Latencies should be cleared after Reset")
+	}
+}
+
+func TestLatencyHistograms_BoundIsInclusive(t *testing.T) {
+	Reset()
+	AgentRunFinished(time.Minute)
+
+	for _, b := range Get().Latencies["agent_run"].Buckets {
+		want := uint64(0)
+		if b.UpperBound >= 60 {
+			want = 1
+		}
+		if b.Count != want {
+			t.Errorf("bucket le=%v count = %d, want %d", b.UpperBound, b.Count, want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// IntentUsage holds call counts, latency, and token usage of an intent
+// parser backend.
+type IntentUsage struct {
+	Calls          uint64  `json:"calls"`
+	Failures       uint64  `json:"failures"`
+	LatencySeconds float64 `json:"latency_seconds"` // Summed across calls
+	InputTokens    uint64  `json:"input_tokens"`
+	OutputTokens   uint64  `json:"output_tokens"`
+	CostUSD        float64 `json:"cost_usd"`
+}
+
+func (u *IntentUsage) add(o IntentUsage) {
+	u.Calls += o.Calls
+	u.Failures += o.Failures
+	u.LatencySeconds += o.LatencySeconds
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+	u.CostUSD += o.CostUSD
+}
+
+// intent usage is keyed by backend.
+var (
+	intentMu    sync.Mutex
+	intentUsage = make(map[string]IntentUsage)
+)
+
+// IntentParseRecorded adds one intent parse call by backend (e.g. "api") to
+// its totals: how long it took, whether it failed, and the tokens and
+// estimated cost in u.
+func IntentParseRecorded(backend string, d time.Duration, err error, u IntentUsage) {
+	u.Calls = 1
+	u.Failures = 0
+	if err != nil {
+		u.Failures = 1
+	}
+	u.LatencySeconds = d.Seconds()
+
+	intentMu.Lock()
+	defer intentMu.Unlock()
+	total := intentUsage[backend]
+	total.add(u)
+	intentUsage[backend] = total
+}
+
+// intentSnapshot returns a copy of the intent usage totals, or nil if no
+// parses have been recorded.
+func intentSnapshot() map[string]IntentUsage {
+	intentMu.Lock()
+	defer intentMu.Unlock()
+	if len(intentUsage) == 0 {
+		return nil
+	}
+	snap := make(map[string]IntentUsage, len(intentUsage))
+	for backend, u := range intentUsage {
+		snap[backend] = u
+	}
+	return snap
+}
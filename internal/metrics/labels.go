@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// Labels dimension a counter so it can be broken down by provider, repo
+// (owner/name), event type, and outcome. Empty labels are omitted.
+type Labels struct {
+	Provider  string `json:"provider,omitempty"`
+	Repo      string `json:"repo,omitempty"`
+	EventType string `json:"event_type,omitempty"`
+	Outcome   string `json:"outcome,omitempty"`
+}
+
+// LabeledCount is a counter's value for one combination of labels.
+type LabeledCount struct {
+	Labels
+	Count uint64 `json:"count"`
+}
+
+// labeledCounter counts occurrences per combination of labels.
+type labeledCounter struct {
+	mu     sync.Mutex
+	counts map[Labels]uint64
+}
+
+var (
+	agentRuns    = &labeledCounter{}
+	eventsRouted = &labeledCounter{}
+)
+
+// AgentRunOutcome counts a finished agent run under its provider, repo,
+// event type, and outcome (e.g. "succeeded", "failed", "timed_out").
+func AgentRunOutcome(l Labels) { agentRuns.inc(l) }
+
+// EventRouted counts an event handed to the agent handler, by provider,
+// repo, and event type.
+func EventRouted(l Labels) { eventsRouted.inc(l) }
+
+func (c *labeledCounter) inc(l Labels) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[Labels]uint64)
+	}
+	c.counts[l]++
+}
+
+// snapshot returns the counts sorted by label, or nil if there are none.
+func (c *labeledCounter) snapshot() []LabeledCount {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.counts) == 0 {
+		return nil
+	}
+	counts := make([]LabeledCount, 0, len(c.counts))
+	for l, n := range c.counts {
+		counts = append(counts, LabeledCount{Labels: l, Count: n})
+	}
+	slices.SortFunc(counts, func(a, b LabeledCount) int {
+		return cmp.Or(
+			cmp.Compare(a.Provider, b.Provider),
+			cmp.Compare(a.Repo, b.Repo),
+			cmp.Compare(a.EventType, b.EventType),
+			cmp.Compare(a.Outcome, b.Outcome),
+		)
+	})
+	return counts
+}
+
+func (c *labeledCounter) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts = nil
+}
+
+// pairs returns the non-empty labels as alternating names and values.
+func (l Labels) pairs() []string {
+	var pairs []string
+	for _, kv := range [][2]string{
+		{"provider", l.Provider},
+		{"repo", l.Repo},
+		{"event_type", l.EventType},
+		{"outcome", l.Outcome},
+	} {
+		if kv[1] != "" {
+			pairs = append(pairs, kv[0], kv[1])
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAgentRunOutcome(t *testing.T) {
+	Reset()
+
+	AgentRunOutcome(Labels{Provider: "gitlab", Repo: "owner/b", EventType: "mr_opened", Outcome: "failed"})
+	AgentRunOutcome(Labels{Provider: "gitlab", Repo: "owner/a", EventType: "mr_opened", Outcome: "succeeded"})
+	AgentRunOutcome(Labels{Provider: "gitlab", Repo: "owner/b", EventType: "mr_opened", Outcome: "failed"})
+	EventRouted(Labels{Provider: "github", Repo: "owner/a", EventType: "mention"})
+
+	want := []LabeledCount{
+		{Labels: Labels{Provider: "gitlab", Repo: "owner/a", EventType: "mr_opened", Outcome: "succeeded"}, Count: 1},
+		{Labels: Labels{Provider: "gitlab", Repo: "owner/b", EventType: "mr_opened", Outcome: "failed"}, Count: 2},
+	}
+	m := Get()
+	if !reflect.DeepEqual(m.AgentRuns, want) {
+		t.Errorf("AgentRuns = %+v, want %+v", m.AgentRuns, want)
+	}
+	if len(m.EventsRouted) != 1 || m.EventsRouted[0].Count != 1 {
+		t.Errorf("EventsRouted = %+v, want one event", m.EventsRouted)
+	}
+
+	Reset()
+	if m := Get(); m.AgentRuns != nil || m.EventsRouted != nil {
+		t.Errorf("labeled counts should be cleared after Reset, got %+v, %+v", m.AgentRuns, m.EventsRouted)
+	}
+}
+
+func TestLabels_Pairs(t *testing.T) {
+	got := Labels{Provider: "github", EventType: "mention"}.pairs()
+	want := []string{"provider", "github", "event_type", "mention"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pairs() = %v, want %v (empty labels omitted)", got, want)
+	}
+}
@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"sync"
 	"sync/atomic"
 )
 
@@ -12,37 +13,308 @@ type Metrics struct {
 	AgentsTimedOut    uint64 `json:"agents_timed_out"`
 	WebhooksReceived  uint64 `json:"webhooks_received"`
 	WebhooksProcessed uint64 `json:"webhooks_processed"`
+
+	// ProtectedPathChanges counts agents that changed a protected path.
+	ProtectedPathChanges uint64 `json:"protected_path_changes"`
+
+	AgentUsage Usage            `json:"agent_usage"`
+	RepoUsage  map[string]Usage `json:"repo_usage,omitempty"`
+
+	// IntentUsage totals intent parser calls by backend.
+	IntentUsage map[string]IntentUsage `json:"intent_usage,omitempty"`
+
+	// FailureReasons counts failures by where they happened (see the
+	// Failure* reasons); AgentsFailed is their total.
+	FailureReasons map[string]uint64 `json:"failure_reasons,omitempty"`
+
+	// FailureCategories counts failed runs by cause (e.g. "auth", "oom_killed").
+	FailureCategories map[string]uint64 `json:"failure_categories,omitempty"`
+
+	// ActiveAgents and QueuedAgents are the agents running and waiting for a
+	// slot when the snapshot was taken.
+	ActiveAgents int `json:"active_agents"`
+	QueuedAgents int `json:"queued_agents"`
+
+	// AgentResources is the latest resource sample of running agents.
+	AgentResources Resources `json:"agent_resources"`
+
+	// RepoCache is the latest disk usage sample of the repo cache; nil until
+	// the first sample.
+	RepoCache *RepoCache `json:"repo_cache,omitempty"`
+
+	// AgentRuns counts finished agent runs by provider, repo, event type,
+	// and outcome.
+	AgentRuns []LabeledCount `json:"agent_runs,omitempty"`
+
+	// EventsRouted counts events handed to the agent handler by provider,
+	// repo, and event type.
+	EventsRouted []LabeledCount `json:"events_routed,omitempty"`
+
+	// Latencies are timing distributions by operation (e.g. "webhook",
+	// "agent_run"); operations not yet observed are omitted.
+	Latencies map[string]Histogram `json:"latencies,omitempty"`
+}
+
+// RepoCache summarizes the repo cache's disk usage.
+type RepoCache struct {
+	SizeBytes       int64                 `json:"size_bytes"`          // All cached repos, including worktrees
+	MaxBytes        int64                 `json:"max_bytes,omitempty"` // Configured limit; 0 means none
+	Worktrees       int                   `json:"worktrees"`
+	VolumeBytes     uint64                `json:"volume_bytes"`      // Size of the filesystem holding the cache
+	VolumeFreeBytes uint64                `json:"volume_free_bytes"` // Space available on it
+	Repos           map[string]CachedRepo `json:"repos,omitempty"`   // Keyed by owner/name
+}
+
+// CachedRepo is the disk usage of one cached repo.
+type CachedRepo struct {
+	SizeBytes int64 `json:"size_bytes"`
+	Worktrees int   `json:"worktrees"`
+}
+
+// Resources summarizes sampled agent container resource usage.
+type Resources struct {
+	Agents          int     `json:"agents"`            // Running agents in the latest sample
+	CPUPercent      float64 `json:"cpu_percent"`       // Sum across running agents
+	MemoryBytes     uint64  `json:"memory_bytes"`      // Sum across running agents
+	PeakMemoryBytes uint64  `json:"peak_memory_bytes"` // Highest for any one agent since startup
+}
+
+// Usage holds token and estimated cost totals for agent runs.
+type Usage struct {
+	Runs             uint64  `json:"runs"`
+	InputTokens      uint64  `json:"input_tokens"`
+	OutputTokens     uint64  `json:"output_tokens"`
+	CacheReadTokens  uint64  `json:"cache_read_tokens"`
+	CacheWriteTokens uint64  `json:"cache_write_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+func (u *Usage) add(o Usage) {
+	u.Runs += o.Runs
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+	u.CacheReadTokens += o.CacheReadTokens
+	u.CacheWriteTokens += o.CacheWriteTokens
+	u.CostUSD += o.CostUSD
 }
 
 var global = &Metrics{}
 
+// usage totals are guarded by usageMu since they aren't simple counters.
+var (
+	usageMu    sync.Mutex
+	totalUsage Usage
+	repoUsage  = make(map[string]Usage)
+)
+
+// failure reasons and categories are keyed by name, so also need a lock.
+var (
+	failureMu         sync.Mutex
+	failureReasons    = make(map[string]uint64)
+	failureCategories = make(map[string]uint64)
+)
+
+// Reasons passed to AgentFailed. Infrastructure failures (spawn_error,
+// clone_error, queue_full, unhealthy) are Familiar's to fix; the rest
+// usually mean the agent couldn't finish its task.
+const (
+	FailureSpawnError  = "spawn_error"  // The agent container couldn't be started
+	FailureCloneError  = "clone_error"  // The repo couldn't be fetched or a worktree created
+	FailureQueueFull   = "queue_full"   // The spawn queue was full
+	FailureTimeout     = "timeout"      // The agent ran past its timeout
+	FailureNonzeroExit = "nonzero_exit" // The agent exited with a nonzero status
+	FailureStuck       = "stuck"        // The agent stopped making progress
+	FailureUnhealthy   = "unhealthy"    // The agent's container healthcheck failed
+
+	FailureProtectedPath = "protected_path" // The agent changed a protected path
+	FailureTerminated    = "terminated"     // The agent was stopped on request
+)
+
+// standardFailureReasons are always reported, even at zero, so alerts can rate them.
+var standardFailureReasons = []string{
+	FailureSpawnError, FailureCloneError, FailureQueueFull, FailureTimeout,
+	FailureNonzeroExit, FailureStuck, FailureUnhealthy, FailureProtectedPath, FailureTerminated,
+}
+
+// resources are replaced wholesale on each sample.
+var (
+	resourcesMu sync.Mutex
+	resources   Resources
+)
+
+// repo cache usage is also replaced wholesale on each sample.
+var (
+	repoCacheMu sync.Mutex
+	repoCache   *RepoCache
+)
+
+// agent counts are read live from the registered sources.
+var (
+	agentCountsMu sync.Mutex
+	activeAgents  func() int
+	queuedAgents  func() int
+)
+
+// TrackAgents sets the sources of the active and queued agent gauges, read
+// each time metrics are collected. Either may be nil.
+func TrackAgents(active, queued func() int) {
+	agentCountsMu.Lock()
+	defer agentCountsMu.Unlock()
+	activeAgents, queuedAgents = active, queued
+}
+
+// agentCounts reads the active and queued agent gauges.
+func agentCounts() (active, queued int) {
+	agentCountsMu.Lock()
+	activeFn, queuedFn := activeAgents, queuedAgents
+	agentCountsMu.Unlock()
+	if activeFn != nil {
+		active = activeFn()
+	}
+	if queuedFn != nil {
+		queued = queuedFn()
+	}
+	return active, queued
+}
+
 // AgentSpawned increments the count of agents spawned.
 func AgentSpawned() { atomic.AddUint64(&global.AgentsSpawned, 1) }
 
 // AgentCompleted increments the count of agents that completed successfully.
 func AgentCompleted() { atomic.AddUint64(&global.AgentsCompleted, 1) }
 
-// AgentFailed increments the count of agents that failed.
-func AgentFailed() { atomic.AddUint64(&global.AgentsFailed, 1) }
+// AgentFailed counts an agent that failed for reason, one of the Failure*
+// reasons.
+func AgentFailed(reason string) {
+	atomic.AddUint64(&global.AgentsFailed, 1)
+	failureMu.Lock()
+	failureReasons[reason]++
+	failureMu.Unlock()
+}
 
 // AgentTimedOut increments the count of agents that timed out.
 func AgentTimedOut() { atomic.AddUint64(&global.AgentsTimedOut, 1) }
 
+// ProtectedPathChanged increments the count of agents that changed a
+// protected path.
+func ProtectedPathChanged() { atomic.AddUint64(&global.ProtectedPathChanges, 1) }
+
 // WebhookReceived increments the count of webhooks received.
 func WebhookReceived() { atomic.AddUint64(&global.WebhooksReceived, 1) }
 
 // WebhookProcessed increments the count of webhooks processed.
 func WebhookProcessed() { atomic.AddUint64(&global.WebhooksProcessed, 1) }
 
-// Get returns a snapshot of the current metrics.
+// AgentFailureCategorized counts a failed run under its failure category.
+func AgentFailureCategorized(category string) {
+	failureMu.Lock()
+	failureCategories[category]++
+	failureMu.Unlock()
+}
+
+// AgentResourcesSampled records the latest resource sample across running
+// agents. maxAgentMemory is the highest memory use of any one of them.
+func AgentResourcesSampled(agents int, cpuPercent float64, memoryBytes, maxAgentMemory uint64) {
+	resourcesMu.Lock()
+	defer resourcesMu.Unlock()
+	resources.Agents = agents
+	resources.CPUPercent = cpuPercent
+	resources.MemoryBytes = memoryBytes
+	resources.PeakMemoryBytes = max(resources.PeakMemoryBytes, maxAgentMemory)
+}
+
+// RepoCacheSampled records the latest repo cache disk usage sample.
+func RepoCacheSampled(c RepoCache) {
+	repoCacheMu.Lock()
+	defer repoCacheMu.Unlock()
+	repoCache = &c
+}
+
+// AgentUsageRecorded adds one agent run's token usage and cost to the totals
+// for the given repository (owner/name).
+func AgentUsageRecorded(repo string, u Usage) {
+	u.Runs = 1
+
+	usageMu.Lock()
+	defer usageMu.Unlock()
+
+	totalUsage.add(u)
+	if repo != "" {
+		r := repoUsage[repo]
+		r.add(u)
+		repoUsage[repo] = r
+	}
+}
+
+// Get returns a snapshot of the metrics counted since process start; see
+// Totals for lifetime counts.
 func Get() Metrics {
+	usageMu.Lock()
+	agentUsage := totalUsage
+	var perRepo map[string]Usage
+	if len(repoUsage) > 0 {
+		perRepo = make(map[string]Usage, len(repoUsage))
+		for repo, u := range repoUsage {
+			perRepo[repo] = u
+		}
+	}
+	usageMu.Unlock()
+
+	failureMu.Lock()
+	reasons := make(map[string]uint64, len(standardFailureReasons)+len(failureReasons))
+	for _, reason := range standardFailureReasons {
+		reasons[reason] = 0
+	}
+	for reason, n := range failureReasons {
+		reasons[reason] = n
+	}
+	var failures map[string]uint64
+	if len(failureCategories) > 0 {
+		failures = make(map[string]uint64, len(failureCategories))
+		for category, n := range failureCategories {
+			failures[category] = n
+		}
+	}
+	failureMu.Unlock()
+
+	resourcesMu.Lock()
+	agentResources := resources
+	resourcesMu.Unlock()
+
+	repoCacheMu.Lock()
+	var cacheUsage *RepoCache
+	if repoCache != nil {
+		c := *repoCache
+		c.Repos = make(map[string]CachedRepo, len(repoCache.Repos))
+		for repo, r := range repoCache.Repos {
+			c.Repos[repo] = r
+		}
+		cacheUsage = &c
+	}
+	repoCacheMu.Unlock()
+
+	active, queued := agentCounts()
+
 	return Metrics{
-		AgentsSpawned:     atomic.LoadUint64(&global.AgentsSpawned),
-		AgentsCompleted:   atomic.LoadUint64(&global.AgentsCompleted),
-		AgentsFailed:      atomic.LoadUint64(&global.AgentsFailed),
-		AgentsTimedOut:    atomic.LoadUint64(&global.AgentsTimedOut),
-		WebhooksReceived:  atomic.LoadUint64(&global.WebhooksReceived),
-		WebhooksProcessed: atomic.LoadUint64(&global.WebhooksProcessed),
+		AgentsSpawned:        atomic.LoadUint64(&global.AgentsSpawned),
+		AgentsCompleted:      atomic.LoadUint64(&global.AgentsCompleted),
+		AgentsFailed:         atomic.LoadUint64(&global.AgentsFailed),
+		AgentsTimedOut:       atomic.LoadUint64(&global.AgentsTimedOut),
+		WebhooksReceived:     atomic.LoadUint64(&global.WebhooksReceived),
+		WebhooksProcessed:    atomic.LoadUint64(&global.WebhooksProcessed),
+		ProtectedPathChanges: atomic.LoadUint64(&global.ProtectedPathChanges),
+		ActiveAgents:         active,
+		QueuedAgents:         queued,
+		AgentUsage:           agentUsage,
+		RepoUsage:            perRepo,
+		IntentUsage:          intentSnapshot(),
+		FailureReasons:       reasons,
+		FailureCategories:    failures,
+		AgentResources:       agentResources,
+		RepoCache:            cacheUsage,
+		AgentRuns:            agentRuns.snapshot(),
+		EventsRouted:         eventsRouted.snapshot(),
+		Latencies:            latencySnapshots(),
 	}
 }
 
@@ -54,4 +326,40 @@ func Reset() {
 	atomic.StoreUint64(&global.AgentsTimedOut, 0)
 	atomic.StoreUint64(&global.WebhooksReceived, 0)
 	atomic.StoreUint64(&global.WebhooksProcessed, 0)
+	atomic.StoreUint64(&global.ProtectedPathChanges, 0)
+
+	usageMu.Lock()
+	totalUsage = Usage{}
+	repoUsage = make(map[string]Usage)
+	usageMu.Unlock()
+
+	intentMu.Lock()
+	intentUsage = make(map[string]IntentUsage)
+	intentMu.Unlock()
+
+	failureMu.Lock()
+	failureReasons = make(map[string]uint64)
+	failureCategories = make(map[string]uint64)
+	failureMu.Unlock()
+
+	resourcesMu.Lock()
+	resources = Resources{}
+	resourcesMu.Unlock()
+
+	repoCacheMu.Lock()
+	repoCache = nil
+	repoCacheMu.Unlock()
+
+	TrackAgents(nil, nil)
+
+	restoredMu.Lock()
+	restored = Metrics{}
+	restoredMu.Unlock()
+
+	agentRuns.reset()
+	eventsRouted.reset()
+
+	for _, h := range latencies {
+		h.reset()
+	}
 }
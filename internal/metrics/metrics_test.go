@@ -1,8 +1,11 @@
 package metrics
 
 import (
+	"errors"
+	"reflect"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestAgentSpawned(t *testing.T) {
@@ -30,11 +33,28 @@ func TestAgentCompleted(t *testing.T) {
 func TestAgentFailed(t *testing.T) {
 	Reset()
 
-	AgentFailed()
+	AgentFailed(FailureSpawnError)
+	AgentFailed(FailureTimeout)
+	AgentFailed(FailureTimeout)
 	m := Get()
 
-	if m.AgentsFailed != 1 {
-		t.Errorf("expected AgentsFailed=1, got %d", m.AgentsFailed)
+	if m.AgentsFailed != 3 {
+		t.Errorf("expected AgentsFailed=3, got %d", m.AgentsFailed)
+	}
+	want := map[string]uint64{
+		FailureSpawnError:  1,
+		FailureCloneError:  0,
+		FailureQueueFull:   0,
+		FailureTimeout:     2,
+		FailureNonzeroExit: 0,
+		FailureStuck:       0,
+		FailureUnhealthy:   0,
+
+		FailureProtectedPath: 0,
+		FailureTerminated:    0,
+	}
+	if !reflect.DeepEqual(m.FailureReasons, want) {
+		t.Errorf("FailureReasons = %v, want %v", m.FailureReasons, want)
 	}
 }
 
@@ -75,7 +95,7 @@ func TestReset(t *testing.T) {
 	// Set all counters
 	AgentSpawned()
 	AgentCompleted()
-	AgentFailed()
+	AgentFailed(FailureSpawnError)
 	AgentTimedOut()
 	WebhookReceived()
 	WebhookProcessed()
@@ -114,7 +134,7 @@ func TestMultipleIncrements(t *testing.T) {
 		AgentCompleted()
 	}
 	for i := 0; i < 2; i++ {
-		AgentFailed()
+		AgentFailed(FailureSpawnError)
 	}
 
 	m := Get()
@@ -148,7 +168,7 @@ func TestConcurrentIncrements(t *testing.T) {
 			wg.Done()
 		}()
 		go func() {
-			AgentFailed()
+			AgentFailed(FailureSpawnError)
 			wg.Done()
 		}()
 		go func() {
@@ -208,3 +228,139 @@ func TestGetReturnsSnapshot(t *testing.T) {
 		t.Errorf("current should be 2, got %d", current.AgentsSpawned)
 	}
 }
+
+func TestAgentUsageRecorded(t *testing.T) {
+	Reset()
+
+	AgentUsageRecorded("org/api", Usage{InputTokens: 100, OutputTokens: 50, CostUSD: 0.25})
+	AgentUsageRecorded("org/api", Usage{InputTokens: 10, OutputTokens: 5, CacheReadTokens: 7, CostUSD: 0.05})
+	AgentUsageRecorded("org/web", Usage{InputTokens: 1, CacheWriteTokens: 3, CostUSD: 0.01})
+
+	m := Get()
+
+	if m.AgentUsage.Runs != 3 {
+		t.Errorf("AgentUsage.Runs = %d, want 3", m.AgentUsage.Runs)
+	}
+	if m.AgentUsage.InputTokens != 111 {
+		t.Errorf("AgentUsage.InputTokens = %d, want 111", m.AgentUsage.InputTokens)
+	}
+	if m.AgentUsage.CacheWriteTokens != 3 {
+		t.Errorf("AgentUsage.CacheWriteTokens = %d, want 3", m.AgentUsage.CacheWriteTokens)
+	}
+
+	api := m.RepoUsage["org/api"]
+	if api.Runs != 2 || api.OutputTokens != 55 || api.CacheReadTokens != 7 {
+		t.Errorf("RepoUsage[org/api] = %+v, want 2 runs, 55 output, 7 cache read", api)
+	}
+	if diff := api.CostUSD - 0.30; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("RepoUsage[org/api].CostUSD = %f, want 0.30", api.CostUSD)
+	}
+
+	Reset()
+	if m := Get(); m.AgentUsage.Runs != 0 || len(m.RepoUsage) != 0 {
+		t.Errorf("usage should be cleared after Reset, got %+v", m.AgentUsage)
+	}
+}
+
+func TestIntentParseRecorded(t *testing.T) {
+	Reset()
+
+	IntentParseRecorded("api", 2*time.Second, nil, IntentUsage{InputTokens: 100, OutputTokens: 20, CostUSD: 0.0006})
+	IntentParseRecorded("api", 500*time.Millisecond, errors.New("boom"), IntentUsage{})
+
+	u := Get().IntentUsage["api"]
+	if u.Calls != 2 || u.Failures != 1 {
+		t.Errorf("IntentUsage[api] = %+v, want 2 calls, 1 failure", u)
+	}
+	if u.LatencySeconds != 2.5 {
+		t.Errorf("LatencySeconds = %v, want 2.5", u.LatencySeconds)
+	}
+	if u.InputTokens != 100 || u.OutputTokens != 20 || u.CostUSD != 0.0006 {
+		t.Errorf("IntentUsage[api] = %+v, want 100 input, 20 output, 0.0006 USD", u)
+	}
+
+	Reset()
+	if m := Get(); len(m.IntentUsage) != 0 {
+		t.Errorf("intent usage should be cleared after Reset, got %+v", m.IntentUsage)
+	}
+}
+
+func TestAgentFailureCategorized(t *testing.T) {
+	Reset()
+
+	AgentFailureCategorized("auth")
+	AgentFailureCategorized("auth")
+	AgentFailureCategorized("oom_killed")
+
+	m := Get()
+	if m.FailureCategories["auth"] != 2 || m.FailureCategories["oom_killed"] != 1 {
+		t.Errorf("FailureCategories = %v, want auth:2 oom_killed:1", m.FailureCategories)
+	}
+
+	Reset()
+	if m := Get(); len(m.FailureCategories) != 0 {
+		t.Errorf("failure categories should be cleared after Reset, got %v", m.FailureCategories)
+	}
+}
+
+func TestAgentResourcesSampled(t *testing.T) {
+	Reset()
+
+	AgentResourcesSampled(2, 150, 3<<30, 2<<30)
+	AgentResourcesSampled(1, 40, 1<<30, 1<<30)
+
+	got := Get().AgentResources
+	want := Resources{Agents: 1, CPUPercent: 40, MemoryBytes: 1 << 30, PeakMemoryBytes: 2 << 30}
+	if got != want {
+		t.Errorf("AgentResources = %+v, want %+v (peak kept across samples)", got, want)
+	}
+
+	Reset()
+	if got := Get().AgentResources; got != (Resources{}) {
+		t.Errorf("AgentResources should be cleared after Reset, got %+v", got)
+	}
+}
+
+func TestRepoCacheSampled(t *testing.T) {
+	Reset()
+
+	if Get().RepoCache != nil {
+		t.Fatal("RepoCache should be nil before the first sample")
+	}
+
+	RepoCacheSampled(RepoCache{SizeBytes: 300, Worktrees: 1, Repos: map[string]CachedRepo{"owner/repo": {SizeBytes: 300, Worktrees: 1}}})
+
+	got := Get().RepoCache
+	if got == nil || got.SizeBytes != 300 || got.Repos["owner/repo"].Worktrees != 1 {
+		t.Errorf("RepoCache = %+v, want the latest sample", got)
+	}
+
+	// Snapshots must not share the repo map
+	got.Repos["owner/other"] = CachedRepo{}
+	if _, ok := Get().RepoCache.Repos["owner/other"]; ok {
+		t.Error("modifying a snapshot changed the recorded sample")
+	}
+
+	Reset()
+	if Get().RepoCache != nil {
+		t.Error("RepoCache should be cleared after Reset")
+	}
+}
+
+func TestTrackAgents(t *testing.T) {
+	Reset()
+	defer Reset()
+
+	if m := Get(); m.ActiveAgents != 0 || m.QueuedAgents != 0 {
+		t.Errorf("untracked agent gauges = %d, %d; want 0, 0", m.ActiveAgents, m.QueuedAgents)
+	}
+
+	active := 2
+	TrackAgents(func() int { return active }, nil)
+	active = 4
+
+	// Gauges are read when collected, not when tracked
+	if m := Get(); m.ActiveAgents != 4 || m.QueuedAgents != 0 {
+		t.Errorf("agent gauges = %d, %d; want 4, 0", m.ActiveAgents, m.QueuedAgents)
+	}
+}
@@ -0,0 +1,229 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// restored holds the counters saved by a previous process, added to the
+// counters of this one to give lifetime totals.
+var (
+	restoredMu sync.Mutex
+	restored   Metrics
+)
+
+// Totals returns lifetime metrics: the counters restored from the last
+// snapshot plus everything counted since process start. Gauges are always
+// this process's latest values.
+func Totals() Metrics {
+	m := Get()
+	restoredMu.Lock()
+	defer restoredMu.Unlock()
+	return m.plus(restored)
+}
+
+// Restore loads counters saved by Save so Totals includes them. A missing
+// file is not an error.
+func Restore(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading metrics snapshot: %w", err)
+	}
+	var m Metrics
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("parsing metrics snapshot %s: %w", path, err)
+	}
+
+	restoredMu.Lock()
+	restored = m.counters()
+	restoredMu.Unlock()
+	return nil
+}
+
+// Save writes the lifetime counters to path, replacing it atomically.
+func Save(path string) error {
+	data, err := json.MarshalIndent(Totals().counters(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding metrics snapshot: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating metrics snapshot directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("writing metrics snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("writing metrics snapshot: %w", err)
+	}
+	return nil
+}
+
+// counters returns m without its gauges, which describe the moment the
+// snapshot was taken and mean nothing after a restart.
+func (m Metrics) counters() Metrics {
+	m.ActiveAgents, m.QueuedAgents = 0, 0
+	m.AgentResources = Resources{}
+	m.RepoCache = nil
+	return m
+}
+
+// plus adds o's counters to m's. Gauges come from m.
+func (m Metrics) plus(o Metrics) Metrics {
+	m.AgentsSpawned += o.AgentsSpawned
+	m.AgentsCompleted += o.AgentsCompleted
+	m.AgentsFailed += o.AgentsFailed
+	m.AgentsTimedOut += o.AgentsTimedOut
+	m.WebhooksReceived += o.WebhooksReceived
+	m.WebhooksProcessed += o.WebhooksProcessed
+	m.ProtectedPathChanges += o.ProtectedPathChanges
+	m.AgentUsage.add(o.AgentUsage)
+
+	if len(o.RepoUsage) > 0 {
+		repos := make(map[string]Usage, len(m.RepoUsage)+len(o.RepoUsage))
+		for repo, u := range m.RepoUsage {
+			repos[repo] = u
+		}
+		for repo, u := range o.RepoUsage {
+			r := repos[repo]
+			r.add(u)
+			repos[repo] = r
+		}
+		m.RepoUsage = repos
+	}
+
+	if len(o.IntentUsage) > 0 {
+		intents := make(map[string]IntentUsage, len(m.IntentUsage)+len(o.IntentUsage))
+		for backend, u := range m.IntentUsage {
+			intents[backend] = u
+		}
+		for backend, u := range o.IntentUsage {
+			i := intents[backend]
+			i.add(u)
+			intents[backend] = i
+		}
+		m.IntentUsage = intents
+	}
+
+	m.FailureReasons = addCounts(m.FailureReasons, o.FailureReasons)
+	m.FailureCategories = addCounts(m.FailureCategories, o.FailureCategories)
+
+	m.AgentRuns = addLabeled(m.AgentRuns, o.AgentRuns)
+	m.EventsRouted = addLabeled(m.EventsRouted, o.EventsRouted)
+
+	if len(o.Latencies) > 0 {
+		latencies := make(map[string]Histogram, len(m.Latencies)+len(o.Latencies))
+		for name, h := range m.Latencies {
+			latencies[name] = h
+		}
+		for name, h := range o.Latencies {
+			latencies[name] = latencies[name].plus(h)
+		}
+		m.Latencies = latencies
+	}
+	return m
+}
+
+// addCounts sums two sets of named counts.
+func addCounts(a, b map[string]uint64) map[string]uint64 {
+	if len(b) == 0 {
+		return a
+	}
+	sum := make(map[string]uint64, len(a)+len(b))
+	for name, n := range a {
+		sum[name] = n
+	}
+	for name, n := range b {
+		sum[name] += n
+	}
+	return sum
+}
+
+// addLabeled sums two sets of labeled counts.
+func addLabeled(a, b []LabeledCount) []LabeledCount {
+	if len(b) == 0 {
+		return a
+	}
+	c := &labeledCounter{counts: make(map[Labels]uint64, len(a)+len(b))}
+	for _, lc := range slices.Concat(a, b) {
+		c.counts[lc.Labels] += lc.Count
+	}
+	return c.snapshot()
+}
+
+// plus adds o's observations to h's. Buckets are matched by upper bound, so
+// a histogram whose bounds changed keeps only the newer buckets.
+func (h Histogram) plus(o Histogram) Histogram {
+	if h.Count == 0 {
+		return o
+	}
+	sum := Histogram{Count: h.Count + o.Count, SumSeconds: h.SumSeconds + o.SumSeconds}
+	sum.Buckets = make([]Bucket, len(h.Buckets))
+	for i, b := range h.Buckets {
+		sum.Buckets[i] = b
+		for _, ob := range o.Buckets {
+			if ob.UpperBound == b.UpperBound {
+				sum.Buckets[i].Count += ob.Count
+			}
+		}
+	}
+	return sum
+}
+
+// Snapshotter periodically saves metrics so their totals survive restarts.
+type Snapshotter struct {
+	path     string
+	ticker   *time.Ticker
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSnapshotter creates a snapshotter that saves metrics to path every
+// interval.
+func NewSnapshotter(path string, interval time.Duration) *Snapshotter {
+	return &Snapshotter{
+		path:   path,
+		ticker: time.NewTicker(interval),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start saves on every tick until Stop.
+func (s *Snapshotter) Start() {
+	go func() {
+		for {
+			select {
+			case <-s.ticker.C:
+				s.save()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the snapshotter and saves a final snapshot. It is safe to
+// call more than once.
+func (s *Snapshotter) Stop() {
+	s.stopOnce.Do(func() {
+		s.ticker.Stop()
+		close(s.stop)
+		s.save()
+	})
+}
+
+func (s *Snapshotter) save() {
+	if err := Save(s.path); err != nil {
+		slog.Warn("failed to save metrics snapshot", "path", s.path, "error", err)
+	}
+}
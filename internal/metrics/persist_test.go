@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveRestore(t *testing.T) {
+	Reset()
+	defer Reset()
+	path := filepath.Join(t.TempDir(), "state", "metrics.json")
+
+	// The previous process
+	AgentSpawned()
+	AgentSpawned()
+	AgentFailureCategorized("auth")
+	AgentUsageRecorded("owner/repo", Usage{InputTokens: 100, CostUSD: 0.5})
+	AgentRunOutcome(Labels{Repo: "owner/repo", Outcome: "failed"})
+	RepoEnsured(2 * time.Second)
+	IntentParseRecorded("api", time.Second, nil, IntentUsage{InputTokens: 40})
+	TrackAgents(func() int { return 3 }, nil)
+	if err := Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// A restart: counters start over, then the snapshot is restored
+	Reset()
+	if err := Restore(path); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	AgentSpawned()
+	AgentFailureCategorized("auth")
+	AgentRunOutcome(Labels{Repo: "owner/repo", Outcome: "failed"})
+	RepoEnsured(20 * time.Millisecond)
+	IntentParseRecorded("api", time.Second, nil, IntentUsage{InputTokens: 2})
+
+	if got := Get().AgentsSpawned; got != 1 {
+		t.Errorf("Get().AgentsSpawned = %d, want 1 since process start", got)
+	}
+
+	total := Totals()
+	if total.AgentsSpawned != 3 {
+		t.Errorf("AgentsSpawned = %d, want 3", total.AgentsSpawned)
+	}
+	if total.FailureCategories["auth"] != 2 {
+		t.Errorf("FailureCategories = %v, want auth: 2", total.FailureCategories)
+	}
+	if u := total.RepoUsage["owner/repo"]; u.Runs != 1 || u.InputTokens != 100 {
+		t.Errorf("RepoUsage = %+v, want the restored run", u)
+	}
+	if len(total.AgentRuns) != 1 || total.AgentRuns[0].Count != 2 {
+		t.Errorf("AgentRuns = %+v, want one label set counted twice", total.AgentRuns)
+	}
+	if h := total.Latencies["repo_ensure"]; h.Count != 2 || h.Buckets[0].Count != 0 || h.Buckets[2].Count != 1 {
+		t.Errorf("repo_ensure = %+v, want both observations in their buckets", h)
+	}
+	if u := total.IntentUsage["api"]; u.Calls != 2 || u.InputTokens != 42 {
+		t.Errorf("IntentUsage[api] = %+v, want 2 calls, 42 input tokens", u)
+	}
+	if total.ActiveAgents != 0 {
+		t.Errorf("ActiveAgents = %d, want gauges not restored", total.ActiveAgents)
+	}
+}
+
+func TestRestore_MissingFile(t *testing.T) {
+	Reset()
+	if err := Restore(filepath.Join(t.TempDir(), "metrics.json")); err != nil {
+		t.Errorf("Restore() error = %v, want nil for a first start", err)
+	}
+}
+
+func TestRestore_Corrupt(t *testing.T) {
+	Reset()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	os.WriteFile(path, []byte("{not json"), 0644)
+	if err := Restore(path); err == nil {
+		t.Error("Restore() expected error for a corrupt snapshot")
+	}
+}
+
+func TestSnapshotter_SavesOnStop(t *testing.T) {
+	Reset()
+	defer Reset()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	s := NewSnapshotter(path, time.Hour)
+	s.Start()
+	AgentCompleted()
+	s.Stop()
+	s.Stop()
+
+	Reset()
+	if err := Restore(path); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if got := Totals().AgentsCompleted; got != 1 {
+		t.Errorf("AgentsCompleted = %d, want 1 saved on Stop", got)
+	}
+}
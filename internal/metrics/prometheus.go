@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PrometheusContentType is the content type of WritePrometheus's output.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// WritePrometheus writes m in the Prometheus text exposition format.
+func WritePrometheus(w io.Writer, m Metrics) error {
+	p := &promWriter{w: bufio.NewWriter(w)}
+	for _, f := range Families(m) {
+		p.family(f)
+	}
+	if p.err != nil {
+		return p.err
+	}
+	return p.w.Flush()
+}
+
+// promWriter writes metric families, remembering the first error.
+type promWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// family writes a metric's HELP and TYPE lines and its samples, or for a
+// histogram its buckets, sum, and count.
+func (p *promWriter) family(f Family) {
+	if p.err != nil {
+		return
+	}
+	fmt.Fprintf(p.w, "# HELP %s %s\n# TYPE %s %s\n", f.Name, f.Help, f.Name, f.Type)
+	if h := f.Histogram; h != nil {
+		for _, b := range h.Buckets {
+			p.sample(f.Name+"_bucket", []string{"le", formatValue(b.UpperBound)}, float64(b.Count))
+		}
+		p.sample(f.Name+"_bucket", []string{"le", "+Inf"}, float64(h.Count))
+		p.sample(f.Name+"_sum", nil, h.SumSeconds)
+		p.sample(f.Name+"_count", nil, float64(h.Count))
+		return
+	}
+	for _, s := range f.Samples {
+		p.sample(f.Name, s.Labels, s.Value)
+	}
+}
+
+// sample writes one sample line.
+func (p *promWriter) sample(name string, labels []string, v float64) {
+	p.w.WriteString(name)
+	if len(labels) > 0 {
+		p.w.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				p.w.WriteByte(',')
+			}
+			fmt.Fprintf(p.w, "%s=\"%s\"", labels[i], escapeLabel(labels[i+1]))
+		}
+		p.w.WriteByte('}')
+	}
+	p.w.WriteByte(' ')
+	p.w.WriteString(formatValue(v))
+	if _, err := p.w.WriteString("\n"); err != nil {
+		p.err = err
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	m := Metrics{
+		AgentsSpawned:     3,
+		FailureReasons:    map[string]uint64{"timeout": 1, "queue_full": 0},
+		WebhooksReceived:  5,
+		FailureCategories: map[string]uint64{"oom_killed": 1, "auth": 2},
+		AgentUsage:        Usage{Runs: 2, InputTokens: 100, OutputTokens: 50, CostUSD: 0.25},
+		RepoUsage: map[string]Usage{
+			`owner/"quoted"`: {Runs: 2, InputTokens: 100, CostUSD: 0.25},
+		},
+		IntentUsage: map[string]IntentUsage{
+			"api": {Calls: 4, Failures: 1, LatencySeconds: 2.5, InputTokens: 800, OutputTokens: 120, CostUSD: 0.01},
+		},
+		ActiveAgents:   2,
+		AgentResources: Resources{Agents: 1, CPUPercent: 12.5},
+		RepoCache: &RepoCache{
+			SizeBytes: 2048,
+			Repos:     map[string]CachedRepo{"owner/repo": {SizeBytes: 2048, Worktrees: 1}},
+		},
+		AgentRuns: []LabeledCount{
+			{Labels: Labels{Provider: "gitlab", Repo: "owner/repo", EventType: "mr_opened", Outcome: "failed"}, Count: 2},
+		},
+	}
+
+	var b strings.Builder
+	if err := WritePrometheus(&b, m); err != nil {
+		t.Fatalf("WritePrometheus() error = %v", err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"# HELP familiar_agents_spawned_total Agents spawned.\n# TYPE familiar_agents_spawned_total counter\nfamiliar_agents_spawned_total 3\n",
+		"familiar_agents_completed_total 0\n",
+		"familiar_webhooks_received_total 5\n",
+		"familiar_agents_failed_total{reason=\"queue_full\"} 0\nfamiliar_agents_failed_total{reason=\"timeout\"} 1\n",
+		"familiar_agent_failures_total{category=\"auth\"} 2\nfamiliar_agent_failures_total{category=\"oom_killed\"} 1\n",
+		"familiar_agent_tokens_total{type=\"input\"} 100\n",
+		"familiar_agent_tokens_total{type=\"output\"} 50\n",
+		"familiar_agent_cost_usd_total 0.25\n",
+		"familiar_repo_agent_tokens_total{repo=\"owner/\\\"quoted\\\"\",type=\"input\"} 100\n",
+		"# TYPE familiar_agent_cpu_percent gauge\nfamiliar_agent_cpu_percent 12.5\n",
+		"# TYPE familiar_active_agents gauge\nfamiliar_active_agents 2\n",
+		"familiar_queued_agents 0\n",
+		"familiar_repo_cache_size_bytes 2048\n",
+		"familiar_repo_cache_repo_worktrees{repo=\"owner/repo\"} 1\n",
+		"familiar_intent_parser_calls_total{backend=\"api\"} 4\n",
+		"familiar_intent_parser_failures_total{backend=\"api\"} 1\n",
+		"familiar_intent_parser_seconds_total{backend=\"api\"} 2.5\n",
+		"familiar_intent_parser_tokens_total{backend=\"api\",type=\"input\"} 800\n",
+		"familiar_intent_parser_cost_usd_total{backend=\"api\"} 0.01\n",
+		"familiar_agent_runs_finished_total{provider=\"gitlab\",repo=\"owner/repo\",event_type=\"mr_opened\",outcome=\"failed\"} 2\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWritePrometheus_Histogram(t *testing.T) {
+	m := Metrics{Latencies: map[string]Histogram{
+		"repo_ensure": {Count: 3, SumSeconds: 4.5, Buckets: []Bucket{{UpperBound: 0.5, Count: 1}, {UpperBound: 2.5, Count: 2}}},
+	}}
+
+	var b strings.Builder
+	if err := WritePrometheus(&b, m); err != nil {
+		t.Fatalf("WritePrometheus() error = %v", err)
+	}
+
+	want := "# TYPE familiar_repo_ensure_seconds histogram\n" +
+		"familiar_repo_ensure_seconds_bucket{le=\"0.5\"} 1\n" +
+		"familiar_repo_ensure_seconds_bucket{le=\"2.5\"} 2\n" +
+		"familiar_repo_ensure_seconds_bucket{le=\"+Inf\"} 3\n" +
+		"familiar_repo_ensure_seconds_sum 4.5\n" +
+		"familiar_repo_ensure_seconds_count 3\n"
+	if out := b.String(); !strings.Contains(out, want) {
+		t.Errorf("output missing %q:\n%s", want, out)
+	}
+}
+
+func TestWritePrometheus_OmitsEmptyFamilies(t *testing.T) {
+	var b strings.Builder
+	if err := WritePrometheus(&b, Metrics{}); err != nil {
+		t.Fatalf("WritePrometheus() error = %v", err)
+	}
+	out := b.String()
+
+	if !strings.Contains(out, "familiar_agents_spawned_total 0\n") {
+		t.Errorf("counters should be written even at zero:\n%s", out)
+	}
+	for _, notWant := range []string{"familiar_agent_failures_total", "familiar_repo_agent_runs_total", "familiar_repo_cache_size_bytes", "familiar_webhook_handling_seconds", "familiar_agent_runs_finished_total"} {
+		if strings.Contains(out, notWant) {
+			t.Errorf("output should not contain %q without data:\n%s", notWant, out)
+		}
+	}
+}
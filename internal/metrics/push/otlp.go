@@ -0,0 +1,139 @@
+package push
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// OTLP pushes metrics to an OpenTelemetry collector over OTLP/HTTP.
+type OTLP struct {
+	provider *sdkmetric.MeterProvider
+}
+
+// NewOTLP creates a pusher exporting to endpoint (a collector URL such as
+// http://localhost:4318) every interval. The SDK runs the export loop, so
+// Start has nothing to do.
+func NewOTLP(ctx context.Context, endpoint string, headers map[string]string, interval time.Duration, snapshot func() metrics.Metrics) (*OTLP, error) {
+	exporter, err := otlpmetrichttp.New(ctx,
+		otlpmetrichttp.WithEndpointURL(endpoint),
+		otlpmetrichttp.WithHeaders(headers),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating metrics exporter: %w", err)
+	}
+	reader := sdkmetric.NewPeriodicReader(exporter,
+		sdkmetric.WithInterval(interval),
+		sdkmetric.WithProducer(producer{snapshot: snapshot}),
+	)
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithResource(resource.NewSchemaless(attribute.String("service.name", "familiar"))),
+	)
+	return &OTLP{provider: provider}, nil
+}
+
+// Start is a no-op; exports begin when the pusher is created.
+func (o *OTLP) Start() {}
+
+// Stop exports a final time and shuts the exporter down.
+func (o *OTLP) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := o.provider.Shutdown(ctx); err != nil {
+		slog.Warn("failed to push metrics over otlp", "error", err)
+	}
+}
+
+// producer converts Familiar's metrics to OTLP data on each export.
+type producer struct {
+	snapshot func() metrics.Metrics
+}
+
+func (p producer) Produce(context.Context) ([]metricdata.ScopeMetrics, error) {
+	return []metricdata.ScopeMetrics{{
+		Scope:   instrumentation.Scope{Name: "github.com/drewdunne/familiar"},
+		Metrics: otlpMetrics(p.snapshot(), metrics.ProcessStart(), time.Now()),
+	}}, nil
+}
+
+// otlpMetrics converts m's families to OTLP metrics. Counters become
+// cumulative monotonic sums, without Prometheus's _total suffix.
+func otlpMetrics(m metrics.Metrics, start, now time.Time) []metricdata.Metrics {
+	var out []metricdata.Metrics
+	for _, f := range metrics.Families(m) {
+		switch f.Type {
+		case metrics.TypeCounter:
+			out = append(out, metricdata.Metrics{
+				Name:        strings.TrimSuffix(f.Name, "_total"),
+				Description: f.Help,
+				Data: metricdata.Sum[float64]{
+					Temporality: metricdata.CumulativeTemporality,
+					IsMonotonic: true,
+					DataPoints:  dataPoints(f.Samples, start, now),
+				},
+			})
+		case metrics.TypeGauge:
+			out = append(out, metricdata.Metrics{
+				Name:        f.Name,
+				Description: f.Help,
+				Data:        metricdata.Gauge[float64]{DataPoints: dataPoints(f.Samples, start, now)},
+			})
+		case metrics.TypeHistogram:
+			h := f.Histogram
+			point := metricdata.HistogramDataPoint[float64]{
+				StartTime:    start,
+				Time:         now,
+				Count:        h.Count,
+				Sum:          h.SumSeconds,
+				Bounds:       make([]float64, len(h.Buckets)),
+				BucketCounts: make([]uint64, len(h.Buckets)+1),
+			}
+			// OTLP bucket counts are per bucket, not cumulative
+			var below uint64
+			for i, b := range h.Buckets {
+				point.Bounds[i] = b.UpperBound
+				point.BucketCounts[i] = b.Count - below
+				below = b.Count
+			}
+			point.BucketCounts[len(h.Buckets)] = h.Count - below
+			out = append(out, metricdata.Metrics{
+				Name:        f.Name,
+				Description: f.Help,
+				Unit:        "s",
+				Data: metricdata.Histogram[float64]{
+					Temporality: metricdata.CumulativeTemporality,
+					DataPoints:  []metricdata.HistogramDataPoint[float64]{point},
+				},
+			})
+		}
+	}
+	return out
+}
+
+func dataPoints(samples []metrics.Sample, start, now time.Time) []metricdata.DataPoint[float64] {
+	points := make([]metricdata.DataPoint[float64], 0, len(samples))
+	for _, s := range samples {
+		attrs := make([]attribute.KeyValue, 0, len(s.Labels)/2)
+		for i := 0; i+1 < len(s.Labels); i += 2 {
+			attrs = append(attrs, attribute.String(s.Labels[i], s.Labels[i+1]))
+		}
+		points = append(points, metricdata.DataPoint[float64]{
+			Attributes: attribute.NewSet(attrs...),
+			StartTime:  start,
+			Time:       now,
+			Value:      s.Value,
+		})
+	}
+	return points
+}
@@ -0,0 +1,56 @@
+package push
+
+import (
+	"testing"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestOTLPMetrics(t *testing.T) {
+	m := metrics.Metrics{
+		AgentsSpawned:  4,
+		ActiveAgents:   2,
+		FailureReasons: map[string]uint64{"queue_full": 3},
+		Latencies: map[string]metrics.Histogram{
+			"repo_ensure": {Count: 5, SumSeconds: 12, Buckets: []metrics.Bucket{{UpperBound: 1, Count: 2}, {UpperBound: 5, Count: 4}}},
+		},
+	}
+	start, now := time.Unix(100, 0), time.Unix(200, 0)
+
+	byName := make(map[string]metricdata.Metrics)
+	for _, om := range otlpMetrics(m, start, now) {
+		byName[om.Name] = om
+	}
+
+	spawned, ok := byName["familiar_agents_spawned"].Data.(metricdata.Sum[float64])
+	if !ok || !spawned.IsMonotonic || spawned.Temporality != metricdata.CumulativeTemporality {
+		t.Fatalf("familiar_agents_spawned = %+v, want a cumulative monotonic sum", byName["familiar_agents_spawned"])
+	}
+	if p := spawned.DataPoints[0]; p.Value != 4 || !p.StartTime.Equal(start) || !p.Time.Equal(now) {
+		t.Errorf("spawned point = %+v, want 4 from start to now", p)
+	}
+
+	failed := byName["familiar_agents_failed"].Data.(metricdata.Sum[float64])
+	if v, _ := failed.DataPoints[0].Attributes.Value(attribute.Key("reason")); v.AsString() != "queue_full" {
+		t.Errorf("failed attributes = %v, want reason=queue_full", failed.DataPoints[0].Attributes)
+	}
+
+	if _, ok := byName["familiar_active_agents"].Data.(metricdata.Gauge[float64]); !ok {
+		t.Errorf("familiar_active_agents = %+v, want a gauge", byName["familiar_active_agents"])
+	}
+
+	hist := byName["familiar_repo_ensure_seconds"].Data.(metricdata.Histogram[float64]).DataPoints[0]
+	if hist.Count != 5 || hist.Sum != 12 {
+		t.Errorf("histogram count, sum = %d, %v; want 5, 12", hist.Count, hist.Sum)
+	}
+	wantCounts := []uint64{2, 2, 1} // per bucket, including +Inf
+	for i, want := range wantCounts {
+		if hist.BucketCounts[i] != want {
+			t.Errorf("BucketCounts = %v, want %v", hist.BucketCounts, wantCounts)
+			break
+		}
+	}
+}
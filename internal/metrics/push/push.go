@@ -0,0 +1,42 @@
+// Package push sends Familiar's metrics to a collector on an interval, for
+// environments that can't scrape /metrics/prometheus.
+package push
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/metrics"
+)
+
+// Config describes where and how often to push metrics.
+type Config struct {
+	Type     string            // statsd or otlp
+	Endpoint string            // statsd: host:port (UDP); otlp: collector URL
+	Interval time.Duration     // Time between pushes
+	Prefix   string            // statsd: prepended to metric names
+	Headers  map[string]string // otlp: sent with each export
+}
+
+// Pusher pushes metrics until stopped.
+type Pusher interface {
+	Start()
+	Stop()
+}
+
+// New creates the pusher cfg describes. Pushers report lifetime totals, the
+// same values /metrics/prometheus serves.
+func New(ctx context.Context, cfg Config) (Pusher, error) {
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("push interval must be positive, got %s", cfg.Interval)
+	}
+	switch cfg.Type {
+	case "statsd":
+		return NewStatsD(cfg.Endpoint, cfg.Prefix, cfg.Interval, metrics.Totals)
+	case "otlp":
+		return NewOTLP(ctx, cfg.Endpoint, cfg.Headers, cfg.Interval, metrics.Totals)
+	default:
+		return nil, fmt.Errorf("unknown push type %q: use statsd or otlp", cfg.Type)
+	}
+}
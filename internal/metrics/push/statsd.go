@@ -0,0 +1,149 @@
+package push
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/metrics"
+)
+
+// maxPacket keeps StatsD datagrams under a typical MTU.
+const maxPacket = 1432
+
+// StatsD pushes metrics over UDP in the StatsD line format, with labels as
+// DogStatsD tags. Counters are sent as the change since the last push,
+// gauges as their current value, and histograms as their sum and count.
+type StatsD struct {
+	conn     net.Conn
+	prefix   string
+	snapshot func() metrics.Metrics
+	last     map[string]float64 // counter series -> value at the last push
+
+	ticker   *time.Ticker
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewStatsD creates a pusher sending to address (host:port) every interval.
+func NewStatsD(address, prefix string, interval time.Duration, snapshot func() metrics.Metrics) (*StatsD, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to statsd: %w", err)
+	}
+	return &StatsD{
+		conn:     conn,
+		prefix:   prefix,
+		snapshot: snapshot,
+		last:     make(map[string]float64),
+		ticker:   time.NewTicker(interval),
+		stop:     make(chan struct{}),
+	}, nil
+}
+
+// Start pushes on every tick until Stop.
+func (s *StatsD) Start() {
+	go func() {
+		for {
+			select {
+			case <-s.ticker.C:
+				if err := s.Push(); err != nil {
+					slog.Warn("failed to push metrics to statsd", "error", err)
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop pushes a final time and closes the connection. It is safe to call
+// more than once.
+func (s *StatsD) Stop() {
+	s.stopOnce.Do(func() {
+		s.ticker.Stop()
+		close(s.stop)
+		if err := s.Push(); err != nil {
+			slog.Warn("failed to push metrics to statsd", "error", err)
+		}
+		s.conn.Close()
+	})
+}
+
+// Push sends the current metrics.
+func (s *StatsD) Push() error {
+	var packet bytes.Buffer
+	flush := func() error {
+		if packet.Len() == 0 {
+			return nil
+		}
+		_, err := s.conn.Write(packet.Bytes())
+		packet.Reset()
+		return err
+	}
+
+	for _, line := range s.lines(s.snapshot()) {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > maxPacket {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	return flush()
+}
+
+// lines formats m as StatsD lines, advancing the counter baselines.
+func (s *StatsD) lines(m metrics.Metrics) []string {
+	var lines []string
+	counter := func(name string, labels []string, v float64) {
+		key := name + "|" + strings.Join(labels, "\x00")
+		delta := v - s.last[key]
+		if delta < 0 {
+			delta = v // the counter was reset
+		}
+		s.last[key] = v
+		if delta != 0 {
+			lines = append(lines, s.line(name, delta, "c", labels))
+		}
+	}
+
+	for _, f := range metrics.Families(m) {
+		switch f.Type {
+		case metrics.TypeCounter:
+			for _, sample := range f.Samples {
+				counter(f.Name, sample.Labels, sample.Value)
+			}
+		case metrics.TypeGauge:
+			for _, sample := range f.Samples {
+				lines = append(lines, s.line(f.Name, sample.Value, "g", sample.Labels))
+			}
+		case metrics.TypeHistogram:
+			counter(f.Name+"_sum", nil, f.Histogram.SumSeconds)
+			counter(f.Name+"_count", nil, float64(f.Histogram.Count))
+		}
+	}
+	return lines
+}
+
+var tagEscaper = strings.NewReplacer(",", "_", "|", "_", "\n", "_")
+
+func (s *StatsD) line(name string, v float64, typ string, labels []string) string {
+	line := s.prefix + name + ":" + strconv.FormatFloat(v, 'f', -1, 64) + "|" + typ
+	if len(labels) > 0 {
+		tags := make([]string, 0, len(labels)/2)
+		for i := 0; i+1 < len(labels); i += 2 {
+			tags = append(tags, labels[i]+":"+tagEscaper.Replace(labels[i+1]))
+		}
+		line += "|#" + strings.Join(tags, ",")
+	}
+	return line
+}
@@ -0,0 +1,124 @@
+package push
+
+import (
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/metrics"
+)
+
+func TestStatsD_Push(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	m := metrics.Metrics{
+		AgentsSpawned:  2,
+		ActiveAgents:   1,
+		FailureReasons: map[string]uint64{"timeout": 1},
+		Latencies: map[string]metrics.Histogram{
+			"agent_run": {Count: 1, SumSeconds: 90},
+		},
+	}
+	s, err := NewStatsD(conn.LocalAddr().String(), "ci.", time.Hour, func() metrics.Metrics { return m })
+	if err != nil {
+		t.Fatalf("NewStatsD() error = %v", err)
+	}
+	defer s.Stop()
+
+	receive := func() []string {
+		t.Helper()
+		buf := make([]byte, 64*1024)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("reading packet: %v", err)
+		}
+		return strings.Split(string(buf[:n]), "\n")
+	}
+
+	if err := s.Push(); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	first := receive()
+	for _, want := range []string{
+		"ci.familiar_agents_spawned_total:2|c",
+		"ci.familiar_agents_failed_total:1|c|#reason:timeout",
+		"ci.familiar_active_agents:1|g",
+		"ci.familiar_agent_run_seconds_sum:90|c",
+		"ci.familiar_agent_run_seconds_count:1|c",
+	} {
+		if !slices.Contains(first, want) {
+			t.Errorf("first push missing %q: %v", want, first)
+		}
+	}
+	if slices.Contains(first, "ci.familiar_agents_completed_total:0|c") {
+		t.Error("unchanged counters should not be sent")
+	}
+
+	// Counters are sent as the change since the last push
+	m.AgentsSpawned = 5
+	if err := s.Push(); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	second := receive()
+	if !slices.Contains(second, "ci.familiar_agents_spawned_total:3|c") {
+		t.Errorf("second push = %v, want spawned delta of 3", second)
+	}
+	if slices.ContainsFunc(second, func(l string) bool { return strings.HasPrefix(l, "ci.familiar_agents_failed_total") }) {
+		t.Errorf("second push = %v, want no unchanged failure counter", second)
+	}
+}
+
+func TestStatsD_SplitsPackets(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	m := metrics.Metrics{RepoUsage: map[string]metrics.Usage{}}
+	for i := range 100 {
+		m.RepoUsage[strings.Repeat("r", 20)+string(rune('a'+i%26))+string(rune('a'+i/26))] = metrics.Usage{Runs: 1}
+	}
+	s, err := NewStatsD(conn.LocalAddr().String(), "", time.Hour, func() metrics.Metrics { return m })
+	if err != nil {
+		t.Fatalf("NewStatsD() error = %v", err)
+	}
+	defer s.Stop()
+	if err := s.Push(); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	buf := make([]byte, 64*1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("reading packet: %v", err)
+	}
+	if n > maxPacket {
+		t.Errorf("packet of %d bytes, want at most %d", n, maxPacket)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"unknown type", Config{Type: "graphite", Endpoint: "localhost:2003", Interval: time.Minute}},
+		{"no interval", Config{Type: "statsd", Endpoint: "localhost:8125"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(t.Context(), tt.cfg); err == nil {
+				t.Error("New() expected error")
+			}
+		})
+	}
+}
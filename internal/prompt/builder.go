@@ -19,13 +19,25 @@ func NewBuilder() *Builder {
 
 // Build constructs a full prompt for the given event and configuration.
 func (b *Builder) Build(evt *event.Event, cfg *config.MergedConfig, parsedIntent *intent.ParsedIntent) string {
+	return b.BuildForPersona(evt, cfg, parsedIntent, nil)
+}
+
+// BuildForPersona constructs a prompt for one of several agents running side
+// by side on an event. The persona's prompt replaces the event's base prompt,
+// and the agent is told to report through its final response. A nil persona
+// builds the normal prompt.
+func (b *Builder) BuildForPersona(evt *event.Event, cfg *config.MergedConfig, parsedIntent *intent.ParsedIntent, persona *config.PersonaConfig) string {
 	var parts []string
 
 	// System context
 	parts = append(parts, b.buildContext(evt))
 
-	// Base prompt
-	parts = append(parts, b.getBasePrompt(evt.Type, cfg, evt))
+	// Base prompt, or the persona's profile
+	if persona != nil {
+		parts = append(parts, b.buildPersona(persona, evt))
+	} else {
+		parts = append(parts, b.getBasePrompt(evt.Type, cfg, evt))
+	}
 
 	// User instructions
 	if parsedIntent != nil && parsedIntent.Instructions != "" {
@@ -42,14 +54,20 @@ func (b *Builder) Build(evt *event.Event, cfg *config.MergedConfig, parsedIntent
 }
 
 func (b *Builder) buildContext(evt *event.Event) string {
+	mr := fmt.Sprintf("MR #%d: %s → %s", evt.MRNumber, evt.SourceBranch, evt.TargetBranch)
+	if evt.DefaultBranch != "" {
+		mr = fmt.Sprintf("#%d has no merge request branch; your working tree is on the default branch, %s",
+			evt.MRNumber, evt.DefaultBranch)
+	}
 	ctx := fmt.Sprintf(`## Context
 - Repository: %s/%s
-- MR #%d: %s → %s
+- %s
 - Provider: %s`,
-		evt.RepoOwner, evt.RepoName,
-		evt.MRNumber, evt.SourceBranch, evt.TargetBranch,
-		evt.Provider)
+		evt.RepoOwner, evt.RepoName, mr, evt.Provider)
 
+	if evt.FromFork {
+		ctx += "\n- The source branch is in a fork, so changes can't be pushed to it"
+	}
 	if evt.MRTitle != "" {
 		ctx += fmt.Sprintf("\n- Title: %s", evt.MRTitle)
 	}
@@ -87,21 +105,39 @@ func (b *Builder) getBasePrompt(t event.Type, cfg *config.MergedConfig, evt *eve
 	default:
 		prompt = ""
 	}
-	// Substitute placeholders
+	return substitute(prompt, evt)
+}
+
+func (b *Builder) buildPersona(persona *config.PersonaConfig, evt *event.Event) string {
+	return fmt.Sprintf(`## Persona: %s
+%s
+
+Other agents are working on this MR in parallel with different focuses. Do not post comments on the MR yourself. Finish with your findings as your final response; Familiar combines every persona's response into a single MR comment.`,
+		persona.Name, substitute(persona.Prompt, evt))
+}
+
+// substitute fills prompt placeholders from the event.
+func substitute(prompt string, evt *event.Event) string {
 	prompt = strings.ReplaceAll(prompt, "{MR_NUMBER}", fmt.Sprintf("%d", evt.MRNumber))
 	prompt = strings.ReplaceAll(prompt, "{REPO_OWNER}", evt.RepoOwner)
 	prompt = strings.ReplaceAll(prompt, "{REPO_NAME}", evt.RepoName)
 	return prompt
 }
 
-func (b *Builder) buildPermissions(evt *event.Event, cfg *config.MergedConfig, parsedIntent *intent.ParsedIntent) string {
-	var perms []string
-	perms = append(perms, "## Permissions")
+// permissionsFor returns the permissions that apply to the event.
+func permissionsFor(evt *event.Event, cfg *config.MergedConfig) config.PermissionsConfig {
+	if evt == nil {
+		return cfg.PermissionsFor("")
+	}
+	return cfg.PermissionsFor(string(evt.Type))
+}
 
-	// Push commits
-	switch cfg.Permissions.PushCommits {
+// PushAllowed reports whether commits may be pushed for this event under
+// the push_commits permission.
+func PushAllowed(evt *event.Event, cfg *config.MergedConfig, parsedIntent *intent.ParsedIntent) bool {
+	switch permissionsFor(evt, cfg).PushCommits {
 	case "always":
-		perms = append(perms, "- You SHOULD push commits when needed")
+		return true
 	case "on_request":
 		explicitPush := parsedIntent != nil && (parsedIntent.HasAction(intent.ActionMerge) || parsedIntent.HasAction(intent.ActionPush))
 		// Any MR-related event may require code changes — grant push.
@@ -110,17 +146,75 @@ func (b *Builder) buildPermissions(evt *event.Event, cfg *config.MergedConfig, p
 		// need to fix issues it finds.
 		mrEvent := evt != nil && (evt.Type == event.TypeMROpened || evt.Type == event.TypeMRUpdated ||
 			evt.Type == event.TypeMRComment || evt.Type == event.TypeMention)
-		if explicitPush || mrEvent {
+		return explicitPush || mrEvent
+	}
+	return false
+}
+
+// Grant is a privileged permission an agent is given for an event.
+type Grant struct {
+	Permission string // YAML name: push_commits, merge, approve, or dismiss_reviews
+	Setting    string // always or on_request
+}
+
+// Grants returns the privileged permissions the event's agent is given:
+// those set to always, and those set to on_request that the parsed intent
+// asks for. Pushing follows PushAllowed.
+func Grants(evt *event.Event, cfg *config.MergedConfig, parsedIntent *intent.ParsedIntent) []Grant {
+	if cfg == nil {
+		return nil
+	}
+	permissions := permissionsFor(evt, cfg)
+	var grants []Grant
+	if PushAllowed(evt, cfg, parsedIntent) {
+		grants = append(grants, Grant{Permission: "push_commits", Setting: permissions.PushCommits})
+	}
+	for _, p := range []struct {
+		name, setting string
+		action        intent.Action
+	}{
+		{"merge", permissions.Merge, intent.ActionMerge},
+		{"approve", permissions.Approve, intent.ActionApprove},
+		{"dismiss_reviews", permissions.DismissReviews, intent.ActionDismissReviews},
+	} {
+		requested := parsedIntent != nil && parsedIntent.HasAction(p.action)
+		if p.setting == "always" || p.setting == "on_request" && requested {
+			grants = append(grants, Grant{Permission: p.name, Setting: p.setting})
+		}
+	}
+	return grants
+}
+
+func (b *Builder) buildPermissions(evt *event.Event, cfg *config.MergedConfig, parsedIntent *intent.ParsedIntent) string {
+	permissions := permissionsFor(evt, cfg)
+	var perms []string
+	perms = append(perms, "## Permissions")
+
+	// Push commits
+	switch {
+	case cfg.AgentMode == config.AgentModePatch:
+		perms = append(perms, "- You must NOT commit or push. Leave your changes uncommitted in the working tree; "+
+			"Familiar will review the diff and commit it for you")
+		perms = append(perms, "- You have no git provider credentials; describe your results in your final response instead of posting comments")
+	case evt.DefaultBranch != "" && PushAllowed(evt, cfg, parsedIntent):
+		perms = append(perms, fmt.Sprintf("- You must NOT push to %s. If changes are needed, push them to a new branch "+
+			"and open a merge request for them", evt.DefaultBranch))
+	case evt.DefaultBranch != "":
+		perms = append(perms, "- You must NOT push commits")
+	case permissions.PushCommits == "always":
+		perms = append(perms, "- You SHOULD push commits when needed")
+	case permissions.PushCommits == "on_request":
+		if PushAllowed(evt, cfg, parsedIntent) {
 			perms = append(perms, "- You MAY push commits")
 		} else {
 			perms = append(perms, "- You must NOT push commits (not requested)")
 		}
-	case "never":
+	case permissions.PushCommits == "never":
 		perms = append(perms, "- You must NOT push commits")
 	}
 
 	// Merge
-	switch cfg.Permissions.Merge {
+	switch permissions.Merge {
 	case "always":
 		perms = append(perms, "- You SHOULD merge when appropriate")
 	case "on_request":
@@ -133,6 +227,12 @@ func (b *Builder) buildPermissions(evt *event.Event, cfg *config.MergedConfig, p
 		perms = append(perms, "- You must NOT merge")
 	}
 
+	// Protected paths
+	if len(cfg.ProtectedPaths) > 0 {
+		perms = append(perms, "- You must NOT change files matching these protected paths; a run that does fails: `"+
+			strings.Join(cfg.ProtectedPaths, "`, `")+"`")
+	}
+
 	return strings.Join(perms, "\n")
 }
 
@@ -1,6 +1,7 @@
 package prompt
 
 import (
+	"slices"
 	"strings"
 	"testing"
 
@@ -197,6 +198,16 @@ func TestBuilder_Build_IncludesMRTitleAndDescription(t *testing.T) {
 	}
 }
 
+func TestBuilder_Build_NotesForkSource(t *testing.T) {
+	evt := &event.Event{Type: event.TypeMROpened, MRNumber: 42, SourceBranch: "feature", TargetBranch: "main", FromFork: true}
+
+	prompt := NewBuilder().Build(evt, &config.MergedConfig{}, nil)
+
+	if !strings.Contains(prompt, "source branch is in a fork") {
+		t.Errorf("prompt should note the fork source:\n%s", prompt)
+	}
+}
+
 func TestBuilder_Build_IncludesCommentBody(t *testing.T) {
 	builder := NewBuilder()
 
@@ -609,3 +620,169 @@ func TestBuilder_Build_OnRequestWithPushRequested(t *testing.T) {
 		t.Error("Prompt should indicate merge is allowed")
 	}
 }
+
+func TestBuilder_Build_PatchModeForbidsCommits(t *testing.T) {
+	builder := NewBuilder()
+
+	evt := &event.Event{
+		Type:         event.TypeMRComment,
+		MRNumber:     1,
+		SourceBranch: "feature",
+		TargetBranch: "main",
+	}
+
+	cfg := &config.MergedConfig{
+		Permissions: config.PermissionsConfig{PushCommits: "always"},
+		AgentMode:   config.AgentModePatch,
+	}
+
+	prompt := builder.Build(evt, cfg, nil)
+
+	if !strings.Contains(prompt, "must NOT commit or push") {
+		t.Error("Prompt should tell patch-mode agents not to commit")
+	}
+	if strings.Contains(prompt, "SHOULD push") {
+		t.Error("Prompt should not grant push in patch mode")
+	}
+}
+
+func TestBuilder_Build_DefaultBranch(t *testing.T) {
+	builder := NewBuilder()
+
+	evt := &event.Event{Type: event.TypeMention, MRNumber: 12, DefaultBranch: "main"}
+	cfg := &config.MergedConfig{Permissions: config.PermissionsConfig{PushCommits: "on_request"}}
+
+	prompt := builder.Build(evt, cfg, nil)
+
+	for _, want := range []string{
+		"#12 has no merge request branch; your working tree is on the default branch, main",
+		"You must NOT push to main. If changes are needed, push them to a new branch",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Prompt should contain %q, got:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, " → ") {
+		t.Error("Prompt should not show empty MR branches")
+	}
+
+	cfg.Permissions.PushCommits = "never"
+	if prompt := builder.Build(evt, cfg, nil); !strings.Contains(prompt, "You must NOT push commits") {
+		t.Errorf("Prompt should forbid pushing when it isn't allowed, got:\n%s", prompt)
+	}
+}
+
+func TestBuilder_Build_ListsProtectedPaths(t *testing.T) {
+	builder := NewBuilder()
+
+	evt := &event.Event{Type: event.TypeMRComment, MRNumber: 1, SourceBranch: "feature", TargetBranch: "main"}
+	cfg := &config.MergedConfig{ProtectedPaths: []string{".github/workflows/**", "deploy/**"}}
+
+	prompt := builder.Build(evt, cfg, nil)
+
+	if !strings.Contains(prompt, "must NOT change files matching these protected paths") ||
+		!strings.Contains(prompt, "`.github/workflows/**`, `deploy/**`") {
+		t.Errorf("Prompt should list the protected paths, got:\n%s", prompt)
+	}
+	if strings.Contains(builder.Build(evt, &config.MergedConfig{}, nil), "protected paths") {
+		t.Error("Prompt should not mention protected paths when there are none")
+	}
+}
+
+func TestBuilder_BuildForPersona(t *testing.T) {
+	builder := NewBuilder()
+
+	evt := &event.Event{
+		Type:         event.TypeMROpened,
+		MRNumber:     7,
+		SourceBranch: "feature",
+		TargetBranch: "main",
+	}
+	cfg := &config.MergedConfig{Prompts: config.PromptsConfig{MROpened: "Review this MR"}}
+	persona := &config.PersonaConfig{Name: "security", Prompt: "Audit MR {MR_NUMBER} for injection bugs."}
+
+	prompt := builder.BuildForPersona(evt, cfg, nil, persona)
+
+	if !strings.Contains(prompt, "## Persona: security\nAudit MR 7 for injection bugs.") {
+		t.Errorf("prompt missing persona profile:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "Review this MR") {
+		t.Error("persona prompt should replace the event's base prompt")
+	}
+	if !strings.Contains(prompt, "Do not post comments on the MR yourself") {
+		t.Error("persona agents should report through their final response")
+	}
+}
+
+func TestBuilder_Build_EventPermissions(t *testing.T) {
+	builder := NewBuilder()
+	cfg := &config.MergedConfig{
+		Permissions: config.PermissionsConfig{
+			Merge:       "never",
+			PushCommits: "never",
+			Events: map[string]config.EventPermissionsConfig{
+				"mr_comment": {PushCommits: "always"},
+			},
+		},
+	}
+
+	tests := []struct {
+		eventType event.Type
+		want      string
+	}{
+		{event.TypeMRComment, "- You SHOULD push commits when needed"},
+		{event.TypeMROpened, "- You must NOT push commits"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.eventType), func(t *testing.T) {
+			evt := &event.Event{Type: tt.eventType, MRNumber: 1}
+			prompt := builder.Build(evt, cfg, nil)
+			if !strings.Contains(prompt, tt.want) {
+				t.Errorf("prompt should contain %q:\n%s", tt.want, prompt)
+			}
+			if !strings.Contains(prompt, "- You must NOT merge") {
+				t.Error("merge should fall back to the default permission")
+			}
+		})
+	}
+	if PushAllowed(&event.Event{Type: event.TypeMROpened}, cfg, nil) {
+		t.Error("PushAllowed() for mr_opened = true, want false")
+	}
+	if !PushAllowed(&event.Event{Type: event.TypeMRComment}, cfg, nil) {
+		t.Error("PushAllowed() for mr_comment = false, want true")
+	}
+}
+
+func TestGrants(t *testing.T) {
+	cfg := &config.MergedConfig{
+		Permissions: config.PermissionsConfig{
+			Merge:          "on_request",
+			Approve:        "always",
+			PushCommits:    "never",
+			DismissReviews: "on_request",
+			Events: map[string]config.EventPermissionsConfig{
+				"mr_comment": {PushCommits: "on_request"},
+			},
+		},
+	}
+	mergeRequested := &intent.ParsedIntent{RequestedActions: []intent.Action{intent.ActionMerge}}
+
+	tests := []struct {
+		name      string
+		eventType event.Type
+		intent    *intent.ParsedIntent
+		want      []Grant
+	}{
+		{"no intent", event.TypeMROpened, nil, []Grant{{"approve", "always"}}},
+		{"merge requested", event.TypeMROpened, mergeRequested, []Grant{{"merge", "on_request"}, {"approve", "always"}}},
+		{"comment implies push", event.TypeMRComment, nil, []Grant{{"push_commits", "on_request"}, {"approve", "always"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Grants(&event.Event{Type: tt.eventType}, cfg, tt.intent)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Grants() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -77,6 +77,38 @@ func TestGitHubProvider_GetMergeRequest(t *testing.T) {
 	}
 }
 
+func TestGitHubProvider_GetMergeRequest_FromFork(t *testing.T) {
+	tests := []struct {
+		name     string
+		headRepo string
+		want     bool
+	}{
+		{"same repo", "owner/repo", false},
+		{"fork", "contributor/repo", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"number": 42,
+					"head":   map[string]interface{}{"ref": "feature", "repo": map[string]string{"full_name": tt.headRepo}},
+					"base":   map[string]interface{}{"ref": "main", "repo": map[string]string{"full_name": "owner/repo"}},
+				})
+			}))
+			defer server.Close()
+
+			p := New("test-token", WithBaseURL(server.URL))
+			mr, err := p.GetMergeRequest(context.Background(), "owner", "repo", 42)
+			if err != nil {
+				t.Fatalf("GetMergeRequest() error = %v", err)
+			}
+			if mr.FromFork != tt.want {
+				t.Errorf("FromFork = %v, want %v", mr.FromFork, tt.want)
+			}
+		})
+	}
+}
+
 func TestGitHubProvider_Name(t *testing.T) {
 	p := New("test-token")
 	if p.Name() != "github" {
@@ -129,6 +161,38 @@ func TestGitHubProvider_PostComment(t *testing.T) {
 	}
 }
 
+func TestGitHubProvider_ReactToComment(t *testing.T) {
+	tests := []struct {
+		name   string
+		review bool
+		path   string
+	}{
+		{"conversation comment", false, "/repos/owner/repo/issues/comments/7/reactions"},
+		{"review comment", true, "/repos/owner/repo/pulls/comments/7/reactions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path || r.Method != http.MethodPost {
+					t.Errorf("request = %s %s, want POST %s", r.Method, r.URL.Path, tt.path)
+				}
+				var body map[string]string
+				json.NewDecoder(r.Body).Decode(&body)
+				if body["content"] != "eyes" {
+					t.Errorf("content = %q, want eyes", body["content"])
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{"id": 1, "content": "eyes"})
+			}))
+			defer server.Close()
+
+			p := New("test-token", WithBaseURL(server.URL))
+			if err := p.ReactToComment(context.Background(), "owner", "repo", 42, 7, tt.review); err != nil {
+				t.Fatalf("ReactToComment() error = %v", err)
+			}
+		})
+	}
+}
+
 func TestGitHubProvider_GetComments(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/repos/owner/repo/issues/42/comments" {
@@ -167,6 +231,17 @@ func TestGitHubProvider_AgentEnv(t *testing.T) {
 	}
 }
 
+func TestGitHubProvider_ReadOnlyAgentEnv(t *testing.T) {
+	if got := New("ghp-test-token-123").ReadOnlyAgentEnv(); got != nil {
+		t.Errorf("ReadOnlyAgentEnv() without a read-only token = %v, want nil", got)
+	}
+
+	got := New("ghp-test-token-123", WithReadOnlyToken("ghp-read")).ReadOnlyAgentEnv()
+	if len(got) != 1 || got["GITHUB_TOKEN"] != "ghp-read" {
+		t.Errorf("ReadOnlyAgentEnv() = %v, want GITHUB_TOKEN=ghp-read", got)
+	}
+}
+
 func TestGitHubProvider_GetRepository_Error(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
@@ -231,3 +306,63 @@ func TestGitHubProvider_GetComments_Error(t *testing.T) {
 		t.Error("GetComments() expected error for server unavailable")
 	}
 }
+
+func TestGitHubProvider_GitCredentials(t *testing.T) {
+	username, password := New("ghp-test-token-123").GitCredentials()
+	if username != "x-access-token" || password != "ghp-test-token-123" {
+		t.Errorf("GitCredentials() = %q, %q; want x-access-token and the token", username, password)
+	}
+}
+
+func TestGitHubProvider_MRHeadRef(t *testing.T) {
+	if got := New("token").MRHeadRef(42); got != "refs/pull/42/head" {
+		t.Errorf("MRHeadRef(42) = %q, want refs/pull/42/head", got)
+	}
+}
+
+func TestGitHubProvider_Whoami(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"login": "familiar-bot"})
+	}))
+	defer server.Close()
+
+	p := New("test-token", WithBaseURL(server.URL))
+	got, err := p.Whoami(context.Background())
+	if err != nil {
+		t.Fatalf("Whoami() error = %v", err)
+	}
+	if got != "familiar-bot" {
+		t.Errorf("Whoami() = %q, want %q", got, "familiar-bot")
+	}
+}
+
+func TestGitHubProvider_Ping(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"valid token", http.StatusOK, false},
+		{"bad token", http.StatusUnauthorized, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/user" {
+					t.Errorf("unexpected path: %s", r.URL.Path)
+				}
+				w.WriteHeader(tt.status)
+				json.NewEncoder(w).Encode(map[string]interface{}{"login": "familiar"})
+			}))
+			defer server.Close()
+
+			p := New("test-token", WithBaseURL(server.URL))
+			if err := p.Ping(context.Background()); (err != nil) != tt.wantErr {
+				t.Errorf("Ping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
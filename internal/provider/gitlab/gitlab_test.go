@@ -71,6 +71,40 @@ func TestGitLabProvider_GetMergeRequest(t *testing.T) {
 	}
 }
 
+func TestGitLabProvider_GetMergeRequest_FromFork(t *testing.T) {
+	tests := []struct {
+		name          string
+		sourceProject int
+		want          bool
+	}{
+		{"same project", 1, false},
+		{"fork", 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"iid":               42,
+					"source_branch":     "feature",
+					"target_branch":     "main",
+					"source_project_id": tt.sourceProject,
+					"target_project_id": 1,
+				})
+			}))
+			defer server.Close()
+
+			p := New("test-token", WithBaseURL(server.URL))
+			mr, err := p.GetMergeRequest(context.Background(), "owner", "repo", 42)
+			if err != nil {
+				t.Fatalf("GetMergeRequest() error = %v", err)
+			}
+			if mr.FromFork != tt.want {
+				t.Errorf("FromFork = %v, want %v", mr.FromFork, tt.want)
+			}
+		})
+	}
+}
+
 func TestGitLabProvider_Name(t *testing.T) {
 	p := New("test-token")
 	if p.Name() != "gitlab" {
@@ -125,6 +159,26 @@ func TestGitLabProvider_PostComment(t *testing.T) {
 	}
 }
 
+func TestGitLabProvider_ReactToComment(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/projects/owner/repo/merge_requests/42/notes/7/award_emoji" || r.Method != http.MethodPost {
+			t.Errorf("request = %s %s, want POST to the note's award_emoji", r.Method, r.URL.Path)
+		}
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["name"] != "eyes" {
+			t.Errorf("name = %q, want eyes", body["name"])
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 1, "name": "eyes"})
+	}))
+	defer server.Close()
+
+	p := New("test-token", WithBaseURL(server.URL))
+	if err := p.ReactToComment(context.Background(), "owner", "repo", 42, 7, false); err != nil {
+		t.Fatalf("ReactToComment() error = %v", err)
+	}
+}
+
 func TestGitLabProvider_GetComments(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/api/v4/projects/owner/repo/merge_requests/42/notes" {
@@ -197,6 +251,21 @@ func TestGitLabProvider_AgentEnv(t *testing.T) {
 	}
 }
 
+func TestGitLabProvider_ReadOnlyAgentEnv(t *testing.T) {
+	if got := New("glpat-abc123").ReadOnlyAgentEnv(); got != nil {
+		t.Errorf("ReadOnlyAgentEnv() without a read-only token = %v, want nil", got)
+	}
+
+	p := New("glpat-abc123", WithBaseURL("https://gitlab.example.com"), WithReadOnlyToken("glpat-read"))
+	got := p.ReadOnlyAgentEnv()
+	if got["GITLAB_TOKEN"] != "glpat-read" || got["GITLAB_HOST"] != "https://gitlab.example.com" {
+		t.Errorf("ReadOnlyAgentEnv() = %v, want the read-only token and host", got)
+	}
+	if full := p.AgentEnv(); full["GITLAB_TOKEN"] != "glpat-abc123" {
+		t.Errorf("AgentEnv()[GITLAB_TOKEN] = %q, want the full token", full["GITLAB_TOKEN"])
+	}
+}
+
 func TestGitLabProvider_GetRepository_Error(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
@@ -261,3 +330,63 @@ func TestGitLabProvider_GetComments_Error(t *testing.T) {
 		t.Error("GetComments() expected error for server unavailable")
 	}
 }
+
+func TestGitLabProvider_GitCredentials(t *testing.T) {
+	username, password := New("glpat-abc123").GitCredentials()
+	if username != "oauth2" || password != "glpat-abc123" {
+		t.Errorf("GitCredentials() = %q, %q; want oauth2 and the token", username, password)
+	}
+}
+
+func TestGitLabProvider_MRHeadRef(t *testing.T) {
+	if got := New("token").MRHeadRef(42); got != "refs/merge-requests/42/head" {
+		t.Errorf("MRHeadRef(42) = %q, want refs/merge-requests/42/head", got)
+	}
+}
+
+func TestGitLabProvider_Whoami(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/user" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"username": "familiar-bot"})
+	}))
+	defer server.Close()
+
+	p := New("test-token", WithBaseURL(server.URL))
+	got, err := p.Whoami(context.Background())
+	if err != nil {
+		t.Fatalf("Whoami() error = %v", err)
+	}
+	if got != "familiar-bot" {
+		t.Errorf("Whoami() = %q, want %q", got, "familiar-bot")
+	}
+}
+
+func TestGitLabProvider_Ping(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"valid token", http.StatusOK, false},
+		{"bad token", http.StatusUnauthorized, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v4/user" {
+					t.Errorf("unexpected path: %s", r.URL.Path)
+				}
+				w.WriteHeader(tt.status)
+				json.NewEncoder(w).Encode(map[string]interface{}{"username": "familiar"})
+			}))
+			defer server.Close()
+
+			p := New("test-token", WithBaseURL(server.URL))
+			if err := p.Ping(context.Background()); (err != nil) != tt.wantErr {
+				t.Errorf("Ping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
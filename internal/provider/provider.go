@@ -26,7 +26,11 @@ type Provider interface {
 	// with the provider's API via CLI tools (gh, glab).
 	AgentEnv() map[string]string
 
-	// AuthenticatedCloneURL returns a clone URL with embedded credentials.
-	// The rawURL is the original clone URL from the webhook payload.
-	AuthenticatedCloneURL(rawURL string) (string, error)
+	// GitCredentials returns the username and password git uses to clone,
+	// fetch, and push over HTTPS.
+	GitCredentials() (username, password string)
+
+	// MRHeadRef returns the ref the remote publishes a merge request's head
+	// commit under, which also covers branches in forks.
+	MRHeadRef(number int) string
 }
@@ -10,6 +10,7 @@ type MergeRequest struct {
 	Description  string
 	SourceBranch string
 	TargetBranch string
+	FromFork     bool   // The source branch lives in a fork, not in this repo
 	State        string // open, closed, merged
 	Author       string
 	URL          string
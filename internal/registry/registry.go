@@ -1,6 +1,8 @@
 package registry
 
 import (
+	"sync"
+
 	"github.com/drewdunne/familiar/internal/config"
 	"github.com/drewdunne/familiar/internal/provider"
 	"github.com/drewdunne/familiar/internal/provider/github"
@@ -9,17 +11,35 @@ import (
 
 // Registry manages provider instances.
 type Registry struct {
+	mu        sync.RWMutex
 	providers map[string]provider.Provider
 }
 
 // New creates a new provider registry from config.
 func New(cfg *config.Config) *Registry {
-	r := &Registry{
-		providers: make(map[string]provider.Provider),
-	}
+	return &Registry{providers: build(cfg)}
+}
+
+// Reload replaces the providers with ones built from cfg, e.g. after a
+// token rotation. Providers already handed out keep working with the old
+// settings.
+func (r *Registry) Reload(cfg *config.Config) {
+	providers := build(cfg)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers = providers
+}
+
+// build creates a provider for each one configured with a token.
+func build(cfg *config.Config) map[string]provider.Provider {
+	providers := make(map[string]provider.Provider)
 
 	if cfg.Providers.GitHub.Token != "" {
-		r.providers["github"] = github.New(cfg.Providers.GitHub.Token)
+		var opts []github.Option
+		if cfg.Providers.GitHub.ReadOnlyToken != "" {
+			opts = append(opts, github.WithReadOnlyToken(cfg.Providers.GitHub.ReadOnlyToken))
+		}
+		providers["github"] = github.New(cfg.Providers.GitHub.Token, opts...)
 	}
 
 	if cfg.Providers.GitLab.Token != "" {
@@ -27,19 +47,26 @@ func New(cfg *config.Config) *Registry {
 		if cfg.Providers.GitLab.BaseURL != "" {
 			opts = append(opts, gitlab.WithBaseURL(cfg.Providers.GitLab.BaseURL))
 		}
-		r.providers["gitlab"] = gitlab.New(cfg.Providers.GitLab.Token, opts...)
+		if cfg.Providers.GitLab.ReadOnlyToken != "" {
+			opts = append(opts, gitlab.WithReadOnlyToken(cfg.Providers.GitLab.ReadOnlyToken))
+		}
+		providers["gitlab"] = gitlab.New(cfg.Providers.GitLab.Token, opts...)
 	}
 
-	return r
+	return providers
 }
 
 // Get returns the provider for the given name, or nil if not configured.
 func (r *Registry) Get(name string) provider.Provider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	return r.providers[name]
 }
 
 // List returns all configured provider names.
 func (r *Registry) List() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	names := make([]string, 0, len(r.providers))
 	for name := range r.providers {
 		names = append(names, name)
@@ -76,3 +76,20 @@ func TestRegistry_List(t *testing.T) {
 		t.Errorf("List()[0] = %q, want %q", names[0], "github")
 	}
 }
+
+func TestRegistry_Reload(t *testing.T) {
+	reg := New(&config.Config{
+		Providers: config.ProvidersConfig{GitHub: config.GitHubConfig{Token: "gh-token"}},
+	})
+
+	reg.Reload(&config.Config{
+		Providers: config.ProvidersConfig{GitLab: config.GitLabConfig{Token: "gl-token"}},
+	})
+
+	if reg.Get("github") != nil {
+		t.Error("Get(github) should be nil after its token was removed")
+	}
+	if reg.Get("gitlab") == nil {
+		t.Error("Get(gitlab) should return the provider added on reload")
+	}
+}
@@ -3,6 +3,7 @@ package repocache
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -11,34 +12,52 @@ import (
 
 // Cache manages bare git repo clones.
 type Cache struct {
-	baseDir string // Container path for git operations
-	hostDir string // Host path for Docker bind mounts
-	mu      sync.Mutex
+	baseDir  string // Container path for git operations
+	hostDir  string // Host path for Docker bind mounts
+	maxBytes int64  // Size limit enforced by LRU eviction; 0 means none
+	depth    int    // Shallow clone depth; 0 clones full history
+	filter   string // Partial clone filter, e.g. "blob:none"
+	mu       sync.Mutex
+
+	creds   map[string]Credentials // by repo path
+	remotes map[string]string      // clone URLs by repo path, for pushes
+	credMu  sync.Mutex             // guards creds and remotes
 }
 
 // New creates a new repo cache at the given directory.
 // Converts relative paths to absolute to ensure Docker bind mounts work correctly.
-func New(baseDir string) *Cache {
+func New(baseDir string, opts ...Option) *Cache {
 	absPath, err := filepath.Abs(baseDir)
 	if err != nil {
 		absPath = baseDir // fallback to original if conversion fails
 	}
-	return &Cache{baseDir: absPath, hostDir: absPath}
+	c := &Cache{baseDir: absPath, hostDir: absPath}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // NewWithHostDir creates a new repo cache with separate container and host paths.
 // Use this when running inside a container where the paths differ.
-func NewWithHostDir(containerDir, hostDir string) *Cache {
-	return &Cache{baseDir: containerDir, hostDir: hostDir}
+func NewWithHostDir(containerDir, hostDir string, opts ...Option) *Cache {
+	c := &Cache{baseDir: containerDir, hostDir: hostDir}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // EnsureRepo ensures a bare clone of the repo exists and is up to date.
-// Returns the path to the bare repo.
-func (c *Cache) EnsureRepo(ctx context.Context, cloneURL, owner, repo string) (string, error) {
+// When refs are given, an existing clone fetches only those. Returns the
+// path to the bare repo. With a size limit, other repos may be evicted to
+// make room.
+func (c *Cache) EnsureRepo(ctx context.Context, cloneURL, owner, repo string, refs ...string) (string, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	repoPath := filepath.Join(c.baseDir, owner, repo+".git")
+	c.setRemote(repoPath, scrubURL(cloneURL))
 
 	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
 		// Clone bare repo
@@ -46,19 +65,20 @@ func (c *Cache) EnsureRepo(ctx context.Context, cloneURL, owner, repo string) (s
 			return "", fmt.Errorf("creating cache directory: %w", err)
 		}
 
-		cmd := exec.CommandContext(ctx, "git", "clone", "--bare", cloneURL, repoPath)
-		if output, err := cmd.CombinedOutput(); err != nil {
-			return "", fmt.Errorf("cloning repo: %w: %s", err, output)
+		args := append([]string{"clone", "--bare"}, c.cloneArgs()...)
+		if err := c.runRemote(ctx, repoPath, "", "cloning repo", append(args, scrubURL(cloneURL), repoPath)...); err != nil {
+			return "", err
 		}
 	} else {
-		// Fetch updates
-		cmd := exec.CommandContext(ctx, "git", "fetch", "--all")
-		cmd.Dir = repoPath
-		if output, err := cmd.CombinedOutput(); err != nil {
-			return "", fmt.Errorf("fetching repo: %w: %s", err, output)
+		scrubRemote(ctx, repoPath)
+
+		if err := c.update(ctx, repoPath, refs); err != nil {
+			return "", err
 		}
 	}
 
+	c.touch(repoPath)
+	c.evict(repoPath)
 	return repoPath, nil
 }
 
@@ -81,13 +101,27 @@ func (c *Cache) CreateWorktree(ctx context.Context, owner, repo, ref, worktreeID
 		return "", fmt.Errorf("creating worktree directory: %w", err)
 	}
 
-	// Create worktree
+	ref, err := c.ensureRef(ctx, repoPath, ref)
+	if err != nil {
+		return "", err
+	}
+
+	// Create worktree. LFS content is pulled afterwards in one batch rather
+	// than by the smudge filter file by file.
 	cmd := exec.CommandContext(ctx, "git", "worktree", "add", "--detach", worktreePath, ref)
 	cmd.Dir = repoPath
+	cmd.Env = append(os.Environ(), "GIT_LFS_SKIP_SMUDGE=1")
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return "", fmt.Errorf("creating worktree: %w: %s", err, output)
 	}
 
+	if usesLFS(worktreePath) {
+		if err := c.pullLFS(ctx, repoPath, worktreePath); err != nil {
+			slog.Warn("worktree keeps LFS pointer files", "repo", owner+"/"+repo, "agent_id", worktreeID, "error", err)
+		}
+	}
+
+	c.touch(repoPath)
 	return worktreePath, nil
 }
 
@@ -0,0 +1,62 @@
+package repocache
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+)
+
+// WithCloneDepth makes bare clones shallow, keeping only the most recent
+// depth commits of each branch. Fetches keep the same depth.
+func WithCloneDepth(depth int) Option {
+	return func(c *Cache) {
+		c.depth = depth
+	}
+}
+
+// WithCloneFilter makes bare clones partial (e.g. "blob:none" fetches file
+// contents only when a worktree checks them out). The remote must support
+// partial clone. Repos already cached stay complete.
+func WithCloneFilter(filter string) Option {
+	return func(c *Cache) {
+		c.filter = filter
+	}
+}
+
+// cloneArgs returns the clone flags limiting history and contents.
+func (c *Cache) cloneArgs() []string {
+	args := c.fetchArgs()
+	if c.depth > 0 {
+		args = append(args, "--no-single-branch") // --depth implies a single branch
+	}
+	if c.filter != "" {
+		args = append(args, "--filter="+c.filter)
+	}
+	return args
+}
+
+// fetchArgs returns the fetch flags limiting history. A partial clone's
+// filter is remembered by git, so it is not repeated.
+func (c *Cache) fetchArgs() []string {
+	if c.depth > 0 {
+		return []string{"--depth", strconv.Itoa(c.depth)}
+	}
+	return nil
+}
+
+// ensureRef makes sure ref resolves to a commit in the bare repo, fetching it
+// from origin on demand (e.g. a commit beyond a shallow clone's history).
+// Returns the ref to check out.
+func (c *Cache) ensureRef(ctx context.Context, repoPath, ref string) (string, error) {
+	verify := exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	verify.Dir = repoPath
+	if verify.Run() == nil {
+		return ref, nil
+	}
+
+	args := append([]string{"fetch"}, c.fetchArgs()...)
+	if err := c.runRemote(ctx, repoPath, repoPath, "fetching "+ref, append(args, "origin", ref)...); err != nil {
+		return "", err
+	}
+	return "FETCH_HEAD", nil
+}
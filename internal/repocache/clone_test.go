@@ -0,0 +1,98 @@
+package repocache
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCache_CloneArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantClone []string
+		wantFetch []string
+	}{
+		{"full", nil, nil, nil},
+		{"shallow", []Option{WithCloneDepth(50)}, []string{"--depth", "50", "--no-single-branch"}, []string{"--depth", "50"}},
+		{"partial", []Option{WithCloneFilter("blob:none")}, []string{"--filter=blob:none"}, nil},
+		{
+			name:      "shallow and partial",
+			opts:      []Option{WithCloneDepth(1), WithCloneFilter("blob:none")},
+			wantClone: []string{"--depth", "1", "--no-single-branch", "--filter=blob:none"},
+			wantFetch: []string{"--depth", "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := New(t.TempDir(), tt.opts...)
+			if got := cache.cloneArgs(); !reflect.DeepEqual(got, tt.wantClone) {
+				t.Errorf("cloneArgs() = %v, want %v", got, tt.wantClone)
+			}
+			if got := cache.fetchArgs(); !reflect.DeepEqual(got, tt.wantFetch) {
+				t.Errorf("fetchArgs() = %v, want %v", got, tt.wantFetch)
+			}
+		})
+	}
+}
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestCache_ShallowPartialClone(t *testing.T) {
+	sourceDir := t.TempDir()
+	setupTestRepo(t, sourceDir)
+	gitOutput(t, sourceDir, "config", "uploadpack.allowFilter", "true")
+	gitOutput(t, sourceDir, "config", "uploadpack.allowAnySHA1InWant", "true")
+	first := gitOutput(t, sourceDir, "rev-parse", "HEAD")
+	for _, msg := range []string{"second", "third"} {
+		if err := os.WriteFile(filepath.Join(sourceDir, msg+".txt"), []byte(msg), 0644); err != nil {
+			t.Fatal(err)
+		}
+		gitOutput(t, sourceDir, "add", ".")
+		gitOutput(t, sourceDir, "commit", "-m", msg)
+	}
+
+	ctx := context.Background()
+	cache := New(t.TempDir(), WithCloneDepth(1), WithCloneFilter("blob:none"))
+	repoPath, err := cache.EnsureRepo(ctx, "file://"+sourceDir, "owner", "repo")
+	if err != nil {
+		t.Fatalf("EnsureRepo() error = %v", err)
+	}
+	if got := gitOutput(t, repoPath, "rev-parse", "--is-shallow-repository"); got != "true" {
+		t.Errorf("is-shallow-repository = %s, want true", got)
+	}
+	if got := gitOutput(t, repoPath, "rev-list", "--count", "HEAD"); got != "1" {
+		t.Errorf("history length = %s, want 1", got)
+	}
+
+	// A worktree at the tip checks out file contents on demand
+	worktreePath, err := cache.CreateWorktree(ctx, "owner", "repo", "HEAD", "tip")
+	if err != nil {
+		t.Fatalf("CreateWorktree(HEAD) error = %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(worktreePath, "third.txt")); err != nil || string(data) != "third" {
+		t.Errorf("third.txt = %q, %v", data, err)
+	}
+
+	// A commit outside the shallow history is fetched on demand
+	worktreePath, err = cache.CreateWorktree(ctx, "owner", "repo", first, "old")
+	if err != nil {
+		t.Fatalf("CreateWorktree(%s) error = %v", first, err)
+	}
+	if got := gitOutput(t, worktreePath, "rev-parse", "HEAD"); got != first {
+		t.Errorf("worktree HEAD = %s, want %s", got, first)
+	}
+}
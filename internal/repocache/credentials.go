@@ -0,0 +1,119 @@
+package repocache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// credentialHelper answers git's credential requests from the environment
+// of the git process, so tokens never appear in remote URLs, repo config,
+// or command lines.
+const credentialHelper = `!f() { test "$1" = get || exit 0; ` +
+	`echo "username=$FAMILIAR_GIT_USERNAME"; echo "password=$FAMILIAR_GIT_PASSWORD"; }; f`
+
+// Credentials authenticate git with a repo's remote.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// SetCredentials sets the credentials used for a repo's clones, fetches, and
+// pushes. Without credentials git uses whatever the host has configured.
+func (c *Cache) SetCredentials(owner, repo, username, password string) {
+	c.credMu.Lock()
+	defer c.credMu.Unlock()
+	if c.creds == nil {
+		c.creds = make(map[string]Credentials)
+	}
+	c.creds[c.RepoPath(owner, repo)] = Credentials{Username: username, Password: password}
+}
+
+// setRemote records the URL a repo was cloned from. Pushes use it rather
+// than the origin URL in repo config, which agents can write to.
+func (c *Cache) setRemote(repoPath, cloneURL string) {
+	c.credMu.Lock()
+	defer c.credMu.Unlock()
+	if c.remotes == nil {
+		c.remotes = make(map[string]string)
+	}
+	c.remotes[repoPath] = cloneURL
+}
+
+// remote returns the URL a repo was cloned from, if it is known.
+func (c *Cache) remote(repoPath string) (string, bool) {
+	c.credMu.Lock()
+	defer c.credMu.Unlock()
+	cloneURL, ok := c.remotes[repoPath]
+	return cloneURL, ok
+}
+
+// remoteGit returns a git command that talks to the remote of the repo at
+// repoPath, authenticating with its credentials if any are set.
+func (c *Cache) remoteGit(ctx context.Context, repoPath string, args ...string) *exec.Cmd {
+	c.credMu.Lock()
+	creds, ok := c.creds[repoPath]
+	c.credMu.Unlock()
+
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if ok && creds.Password != "" {
+		// The empty helper clears any configured helpers so the token is
+		// not stored by them
+		args = append([]string{"-c", "credential.helper=", "-c", "credential.helper=" + credentialHelper}, args...)
+		env = append(env, "FAMILIAR_GIT_USERNAME="+creds.Username, "FAMILIAR_GIT_PASSWORD="+creds.Password)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = env
+	return cmd
+}
+
+// scrubURL removes any credentials embedded in a remote URL.
+func scrubURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.User == nil {
+		return rawURL
+	}
+	parsed.User = nil
+	return parsed.String()
+}
+
+// scrubRemote removes credentials that older versions embedded in the
+// bare repo's origin URL. Worktrees share the bare repo's config, so this
+// also cleans their remotes.
+func scrubRemote(ctx context.Context, repoPath string) {
+	get := exec.CommandContext(ctx, "git", "config", "--get", "remote.origin.url")
+	get.Dir = repoPath
+	output, err := get.Output()
+	if err != nil {
+		return
+	}
+	current := strings.TrimSpace(string(output))
+	scrubbed := scrubURL(current)
+	if scrubbed == current {
+		return
+	}
+
+	set := exec.CommandContext(ctx, "git", "remote", "set-url", "origin", scrubbed)
+	set.Dir = repoPath
+	if output, err := set.CombinedOutput(); err != nil {
+		slog.Warn("failed to remove credentials from remote URL", "path", repoPath, "error", err, "output", string(output))
+		return
+	}
+	slog.Info("removed embedded credentials from remote URL", "path", repoPath)
+}
+
+// runRemote runs a remote git command in dir, returning its combined output
+// on failure.
+func (c *Cache) runRemote(ctx context.Context, repoPath, dir, what string, args ...string) error {
+	cmd := c.remoteGit(ctx, repoPath, args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", what, err, output)
+	}
+	return nil
+}
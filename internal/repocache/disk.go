@@ -0,0 +1,80 @@
+package repocache
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/metrics"
+)
+
+// Volume describes the filesystem holding the cache.
+type Volume struct {
+	TotalBytes uint64
+	FreeBytes  uint64 // Available to unprivileged users
+}
+
+// Volume reports the size and free space of the filesystem holding the
+// cache.
+func (c *Cache) Volume() (Volume, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(c.baseDir, &st); err != nil {
+		return Volume{}, fmt.Errorf("checking cache volume: %w", err)
+	}
+	return Volume{
+		TotalBytes: uint64(st.Blocks) * uint64(st.Bsize),
+		FreeBytes:  uint64(st.Bavail) * uint64(st.Bsize),
+	}, nil
+}
+
+// CheckWritable reports an error unless a file can be created in the
+// cache directory, e.g. after a volume was remounted read-only.
+func (c *Cache) CheckWritable() error {
+	f, err := os.CreateTemp(c.baseDir, ".familiar-health-*")
+	if err != nil {
+		return fmt.Errorf("repo cache not writable: %w", err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// recordUsage samples the cache's disk usage into metrics.
+func (c *Cache) recordUsage() error {
+	usages, err := c.Usage()
+	if err != nil {
+		return err
+	}
+	volume, err := c.Volume()
+	if err != nil {
+		return err
+	}
+
+	sample := metrics.RepoCache{
+		MaxBytes:        c.maxBytes,
+		VolumeBytes:     volume.TotalBytes,
+		VolumeFreeBytes: volume.FreeBytes,
+		Repos:           make(map[string]metrics.CachedRepo, len(usages)),
+	}
+	for _, u := range usages {
+		sample.SizeBytes += u.SizeBytes
+		sample.Worktrees += u.ActiveWorktrees
+		sample.Repos[u.Owner+"/"+u.Repo] = metrics.CachedRepo{
+			SizeBytes: u.SizeBytes,
+			Worktrees: u.ActiveWorktrees,
+		}
+	}
+	metrics.RepoCacheSampled(sample)
+	return nil
+}
+
+// NewUsageScheduler creates a scheduler that records the cache's disk usage
+// in metrics every interval.
+func NewUsageScheduler(cache *Cache, interval time.Duration) *Scheduler {
+	return NewScheduler(interval, func() {
+		if err := cache.recordUsage(); err != nil {
+			slog.Warn("failed to measure repo cache", "error", err)
+		}
+	})
+}
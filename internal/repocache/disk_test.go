@@ -0,0 +1,54 @@
+package repocache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/metrics"
+)
+
+func TestCache_RecordUsage(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+
+	cache := New(t.TempDir(), WithMaxSize(1<<20))
+	fakeRepo(t, cache, "owner", "small", 100, time.Now())
+	bigPath := fakeRepo(t, cache, "owner", "big", 1000, time.Now())
+	if err := os.MkdirAll(filepath.Join(bigPath, "worktrees-data", "agent-1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cache.recordUsage(); err != nil {
+		t.Fatalf("recordUsage() error = %v", err)
+	}
+
+	got := metrics.Get().RepoCache
+	if got == nil {
+		t.Fatal("no repo cache sample recorded")
+	}
+	if got.SizeBytes != 1100 || got.Worktrees != 1 || got.MaxBytes != 1<<20 {
+		t.Errorf("sample = %+v, want 1100 bytes, 1 worktree, 1 MiB limit", got)
+	}
+	if got.Repos["owner/big"] != (metrics.CachedRepo{SizeBytes: 1000, Worktrees: 1}) {
+		t.Errorf("owner/big = %+v", got.Repos["owner/big"])
+	}
+	if got.VolumeBytes == 0 || got.VolumeFreeBytes > got.VolumeBytes {
+		t.Errorf("volume = %d bytes with %d free", got.VolumeBytes, got.VolumeFreeBytes)
+	}
+}
+
+func TestCache_CheckWritable(t *testing.T) {
+	dir := t.TempDir()
+	if err := New(dir).CheckWritable(); err != nil {
+		t.Errorf("CheckWritable() error = %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("CheckWritable() left %d files behind", len(entries))
+	}
+
+	if err := New(filepath.Join(dir, "missing")).CheckWritable(); err == nil {
+		t.Error("CheckWritable() should fail for a missing directory")
+	}
+}
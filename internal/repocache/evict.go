@@ -0,0 +1,153 @@
+package repocache
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// accessFile is touched inside a bare repo each time it is used, so last
+// access survives server restarts.
+const accessFile = "familiar-last-access"
+
+// RepoUsage describes the disk usage of one cached repo.
+type RepoUsage struct {
+	Owner           string
+	Repo            string
+	Path            string
+	SizeBytes       int64 // Including worktrees
+	LastAccess      time.Time
+	ActiveWorktrees int
+}
+
+// Option configures a Cache.
+type Option func(*Cache)
+
+// WithMaxSize caps the cache's disk usage. When it is exceeded, the least
+// recently used repos without active worktrees are removed. 0 means no limit.
+func WithMaxSize(bytes int64) Option {
+	return func(c *Cache) {
+		c.maxBytes = bytes
+	}
+}
+
+// touch records that a repo was just used. Caller must hold c.mu.
+func (c *Cache) touch(repoPath string) {
+	marker := filepath.Join(repoPath, accessFile)
+	now := time.Now()
+	if err := os.Chtimes(marker, now, now); err != nil {
+		if err := os.WriteFile(marker, nil, 0644); err != nil {
+			slog.Warn("failed to record repo access", "path", repoPath, "error", err)
+		}
+	}
+}
+
+// Usage reports the disk usage of every cached repo.
+func (c *Cache) Usage() ([]RepoUsage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.usage()
+}
+
+// usage reports per-repo disk usage. Caller must hold c.mu.
+func (c *Cache) usage() ([]RepoUsage, error) {
+	repoPaths, err := filepath.Glob(filepath.Join(c.baseDir, "*", "*.git"))
+	if err != nil {
+		return nil, fmt.Errorf("listing cached repos: %w", err)
+	}
+
+	usages := make([]RepoUsage, 0, len(repoPaths))
+	for _, repoPath := range repoPaths {
+		size, err := dirSize(repoPath)
+		if err != nil {
+			return nil, fmt.Errorf("measuring %s: %w", repoPath, err)
+		}
+		usage := RepoUsage{
+			Owner:           filepath.Base(filepath.Dir(repoPath)),
+			Repo:            strings.TrimSuffix(filepath.Base(repoPath), ".git"),
+			Path:            repoPath,
+			SizeBytes:       size,
+			LastAccess:      lastAccess(repoPath),
+			ActiveWorktrees: countEntries(filepath.Join(repoPath, "worktrees-data")),
+		}
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
+
+// evict removes least recently used repos without active worktrees until
+// the cache fits within its size limit. keep is never removed. Caller must
+// hold c.mu.
+func (c *Cache) evict(keep string) {
+	if c.maxBytes <= 0 {
+		return
+	}
+
+	usages, err := c.usage()
+	if err != nil {
+		slog.Warn("failed to measure repo cache", "error", err)
+		return
+	}
+
+	var total int64
+	for _, u := range usages {
+		total += u.SizeBytes
+	}
+	if total <= c.maxBytes {
+		return
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].LastAccess.Before(usages[j].LastAccess)
+	})
+	for _, u := range usages {
+		if total <= c.maxBytes {
+			break
+		}
+		if u.Path == keep || u.ActiveWorktrees > 0 {
+			continue
+		}
+		if err := os.RemoveAll(u.Path); err != nil {
+			slog.Warn("failed to evict cached repo", "repo", u.Owner+"/"+u.Repo, "error", err)
+			continue
+		}
+		total -= u.SizeBytes
+		slog.Info("evicted cached repo", "repo", u.Owner+"/"+u.Repo, "size_bytes", u.SizeBytes, "last_access", u.LastAccess)
+	}
+	if total > c.maxBytes {
+		slog.Warn("repo cache is over its size limit; remaining repos are in use", "size_bytes", total, "max_bytes", c.maxBytes)
+	}
+}
+
+// dirSize returns the total size of regular files under dir.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// countEntries returns the number of entries in dir, or 0 if it is missing.
+func countEntries(dir string) int {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	return len(entries)
+}
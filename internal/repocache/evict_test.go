@@ -0,0 +1,117 @@
+package repocache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeRepo creates a cached bare repo directory of the given size, last
+// used at the given time.
+func fakeRepo(t *testing.T, cache *Cache, owner, repo string, size int, lastAccess time.Time) string {
+	t.Helper()
+	repoPath := cache.RepoPath(owner, repo)
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, "pack"), make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	marker := filepath.Join(repoPath, accessFile)
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(marker, lastAccess, lastAccess); err != nil {
+		t.Fatal(err)
+	}
+	return repoPath
+}
+
+func TestCache_Usage(t *testing.T) {
+	cache := New(t.TempDir())
+	used := time.Now().Add(-time.Hour).Truncate(time.Second)
+	repoPath := fakeRepo(t, cache, "owner", "repo", 1000, used)
+	if err := os.MkdirAll(filepath.Join(repoPath, "worktrees-data", "agent-1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	usages, err := cache.Usage()
+	if err != nil {
+		t.Fatalf("Usage() error = %v", err)
+	}
+	if len(usages) != 1 {
+		t.Fatalf("Usage() returned %d repos, want 1", len(usages))
+	}
+	u := usages[0]
+	if u.Owner != "owner" || u.Repo != "repo" || u.SizeBytes != 1000 || u.ActiveWorktrees != 1 {
+		t.Errorf("Usage() = %+v", u)
+	}
+	if !u.LastAccess.Equal(used) {
+		t.Errorf("LastAccess = %v, want %v", u.LastAccess, used)
+	}
+}
+
+func TestCache_Evict(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name        string
+		maxBytes    int64
+		busy        string // repo with an active worktree
+		wantEvicted []string
+	}{
+		{"under limit", 10000, "", nil},
+		{"no limit", 0, "", nil},
+		{"evicts oldest first", 2500, "", []string{"oldest"}},
+		{"evicts until under limit", 1500, "", []string{"oldest", "older"}},
+		{"skips repos in use", 2500, "oldest", []string{"older"}},
+		{"never evicts the repo being used", 500, "", []string{"oldest", "older"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := New(t.TempDir(), WithMaxSize(tt.maxBytes))
+			paths := map[string]string{
+				"oldest": fakeRepo(t, cache, "owner", "oldest", 1000, now.Add(-3*time.Hour)),
+				"older":  fakeRepo(t, cache, "owner", "older", 1000, now.Add(-2*time.Hour)),
+				"recent": fakeRepo(t, cache, "owner", "recent", 1000, now.Add(-time.Hour)),
+			}
+			if tt.busy != "" {
+				if err := os.MkdirAll(filepath.Join(paths[tt.busy], "worktrees-data", "agent"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cache.evict(paths["recent"])
+
+			evicted := map[string]bool{}
+			for _, name := range tt.wantEvicted {
+				evicted[name] = true
+			}
+			for name, path := range paths {
+				_, err := os.Stat(path)
+				if exists := err == nil; exists == evicted[name] {
+					t.Errorf("%s exists = %v, want %v", name, exists, !evicted[name])
+				}
+			}
+		})
+	}
+}
+
+func TestCache_EnsureRepoRecordsAccess(t *testing.T) {
+	cache := New(t.TempDir())
+	sourceDir := t.TempDir()
+	setupTestRepo(t, sourceDir)
+
+	repoPath, err := cache.EnsureRepo(context.Background(), sourceDir, "owner", "repo")
+	if err != nil {
+		t.Fatalf("EnsureRepo() error = %v", err)
+	}
+	info, err := os.Stat(filepath.Join(repoPath, accessFile))
+	if err != nil {
+		t.Fatalf("access marker missing: %v", err)
+	}
+	if time.Since(info.ModTime()) > time.Minute {
+		t.Errorf("access marker mtime = %v, want recent", info.ModTime())
+	}
+}
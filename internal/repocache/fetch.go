@@ -0,0 +1,140 @@
+package repocache
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// branchRefspec mirrors the remote's branches into the bare repo. Bare
+// clones have no fetch refspec configured, so without it fetches would
+// not update any branch.
+const branchRefspec = "+refs/heads/*:refs/heads/*"
+
+// ActiveWindow is how recently a repo must have been used to be kept
+// fetched in the background.
+const ActiveWindow = 24 * time.Hour
+
+// fetch updates every branch of the bare repo from origin. Caller must hold
+// c.mu.
+func (c *Cache) fetch(ctx context.Context, repoPath string) error {
+	args := append([]string{"fetch", "--prune"}, c.fetchArgs()...)
+	return c.runRemote(ctx, repoPath, repoPath, "fetching repo", append(args, "origin", branchRefspec)...)
+}
+
+// fetchRefs updates only the given refs from origin. Plain names are
+// branches; full refs (e.g. a merge request's head) are mirrored as is.
+// Caller must hold c.mu.
+func (c *Cache) fetchRefs(ctx context.Context, repoPath string, refs []string) error {
+	args := append([]string{"fetch"}, c.fetchArgs()...)
+	args = append(args, "origin")
+	for _, ref := range refs {
+		if !strings.HasPrefix(ref, "refs/") {
+			ref = "refs/heads/" + ref
+		}
+		args = append(args, "+"+ref+":"+ref)
+	}
+	return c.runRemote(ctx, repoPath, repoPath, "fetching refs", args...)
+}
+
+// update brings a cached repo up to date. With refs, only those are
+// fetched, falling back to a full fetch if any is missing on origin.
+// Without, every branch is fetched unless a background fetch already has
+// them. Caller must hold c.mu.
+func (c *Cache) update(ctx context.Context, repoPath string, refs []string) error {
+	if len(refs) > 0 {
+		err := c.fetchRefs(ctx, repoPath, refs)
+		if err == nil {
+			return nil
+		}
+		slog.Warn("fetching refs failed, fetching all branches", "path", repoPath, "refs", strings.Join(refs, ", "), "error", err)
+	} else if c.refsCurrent(ctx, repoPath) {
+		return nil
+	}
+	return c.fetch(ctx, repoPath)
+}
+
+// refsCurrent reports whether the bare repo's branches already match
+// origin's, so a fetch would bring nothing new. Any error reports false.
+func (c *Cache) refsCurrent(ctx context.Context, repoPath string) bool {
+	remote := c.remoteGit(ctx, repoPath, "ls-remote", "--heads", "origin")
+	remote.Dir = repoPath
+	remoteRefs, err := remote.Output()
+	if err != nil {
+		return false
+	}
+
+	local := exec.CommandContext(ctx, "git", "for-each-ref", "--format=%(objectname)\t%(refname)", "refs/heads")
+	local.Dir = repoPath
+	localRefs, err := local.Output()
+	if err != nil {
+		return false
+	}
+	return slices.Equal(sortedLines(remoteRefs), sortedLines(localRefs))
+}
+
+// sortedLines splits output into sorted, non-empty lines.
+func sortedLines(output []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	slices.Sort(lines)
+	return lines
+}
+
+// FetchActive fetches every cached repo used within ActiveWindow, so
+// events for them find the cache already up to date. The cache is locked
+// for one repo at a time. Returns the number of repos fetched.
+func (c *Cache) FetchActive(ctx context.Context) int {
+	repoPaths, err := filepath.Glob(filepath.Join(c.baseDir, "*", "*.git"))
+	if err != nil {
+		slog.Warn("failed to list cached repos", "error", err)
+		return 0
+	}
+
+	fetched := 0
+	for _, repoPath := range repoPaths {
+		if time.Since(lastAccess(repoPath)) > ActiveWindow {
+			continue
+		}
+		c.mu.Lock()
+		if _, err := os.Stat(repoPath); err == nil {
+			if err := c.fetch(ctx, repoPath); err != nil {
+				slog.Warn("background fetch failed", "path", repoPath, "error", err)
+			} else {
+				fetched++
+			}
+		}
+		c.mu.Unlock()
+	}
+	return fetched
+}
+
+// lastAccess returns when a cached repo was last used.
+func lastAccess(repoPath string) time.Time {
+	if info, err := os.Stat(filepath.Join(repoPath, accessFile)); err == nil {
+		return info.ModTime()
+	}
+	if info, err := os.Stat(repoPath); err == nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
+// NewFetchScheduler creates a scheduler that fetches recently used repos
+// every interval.
+func NewFetchScheduler(cache *Cache, interval time.Duration) *Scheduler {
+	return NewScheduler(interval, func() {
+		if n := cache.FetchActive(context.Background()); n > 0 {
+			slog.Info("background fetched cached repos", "repos", n)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package repocache
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache_EnsureRepoFetchesBranches(t *testing.T) {
+	sourceDir := t.TempDir()
+	setupTestRepo(t, sourceDir)
+	ctx := context.Background()
+	cache := New(t.TempDir())
+
+	repoPath, err := cache.EnsureRepo(ctx, sourceDir, "owner", "repo")
+	if err != nil {
+		t.Fatalf("EnsureRepo() error = %v", err)
+	}
+	if !cache.refsCurrent(ctx, repoPath) {
+		t.Error("refsCurrent() = false right after cloning")
+	}
+
+	// A new branch and a new commit on the default branch
+	defaultBranch := gitOutput(t, sourceDir, "rev-parse", "--abbrev-ref", "HEAD")
+	gitOutput(t, sourceDir, "commit", "--allow-empty", "-m", "update")
+	gitOutput(t, sourceDir, "branch", "feature")
+	if cache.refsCurrent(ctx, repoPath) {
+		t.Error("refsCurrent() = true after the remote changed")
+	}
+
+	if _, err := cache.EnsureRepo(ctx, sourceDir, "owner", "repo"); err != nil {
+		t.Fatalf("EnsureRepo() second call error = %v", err)
+	}
+	want := gitOutput(t, sourceDir, "rev-parse", "HEAD")
+	for _, branch := range []string{defaultBranch, "feature"} {
+		if got := gitOutput(t, repoPath, "rev-parse", "refs/heads/"+branch); got != want {
+			t.Errorf("cached %s = %s, want %s", branch, got, want)
+		}
+	}
+}
+
+func TestCache_EnsureRepoFetchesOnlyRequestedRefs(t *testing.T) {
+	sourceDir := t.TempDir()
+	setupTestRepo(t, sourceDir)
+	ctx := context.Background()
+	cache := New(t.TempDir())
+
+	repoPath, err := cache.EnsureRepo(ctx, sourceDir, "owner", "repo")
+	if err != nil {
+		t.Fatalf("EnsureRepo() error = %v", err)
+	}
+	gitOutput(t, sourceDir, "branch", "feature")
+	gitOutput(t, sourceDir, "branch", "other")
+	gitOutput(t, sourceDir, "update-ref", "refs/merge-requests/3/head", "HEAD")
+
+	if _, err := cache.EnsureRepo(ctx, sourceDir, "owner", "repo", "feature", "refs/merge-requests/3/head"); err != nil {
+		t.Fatalf("EnsureRepo() with refs error = %v", err)
+	}
+	for _, ref := range []string{"refs/heads/feature", "refs/merge-requests/3/head"} {
+		if err := exec.Command("git", "-C", repoPath, "rev-parse", "--verify", "--quiet", ref).Run(); err != nil {
+			t.Errorf("%s was not fetched", ref)
+		}
+	}
+	if err := exec.Command("git", "-C", repoPath, "rev-parse", "--verify", "--quiet", "refs/heads/other").Run(); err == nil {
+		t.Error("unrequested branch was fetched")
+	}
+
+	// A ref missing on origin falls back to fetching every branch
+	if _, err := cache.EnsureRepo(ctx, sourceDir, "owner", "repo", "missing"); err != nil {
+		t.Fatalf("EnsureRepo() with a missing ref error = %v", err)
+	}
+	if !cache.refsCurrent(ctx, repoPath) {
+		t.Error("fallback did not fetch every branch")
+	}
+}
+
+func TestCache_FetchActive(t *testing.T) {
+	ctx := context.Background()
+	cache := New(t.TempDir())
+	sources := map[string]string{}
+	for _, name := range []string{"active", "idle"} {
+		sources[name] = t.TempDir()
+		setupTestRepo(t, sources[name])
+		if _, err := cache.EnsureRepo(ctx, sources[name], "owner", name); err != nil {
+			t.Fatalf("EnsureRepo(%s) error = %v", name, err)
+		}
+		gitOutput(t, sources[name], "commit", "--allow-empty", "-m", "update")
+	}
+	old := time.Now().Add(-2 * ActiveWindow)
+	if err := os.Chtimes(filepath.Join(cache.RepoPath("owner", "idle"), accessFile), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := cache.FetchActive(ctx); got != 1 {
+		t.Errorf("FetchActive() = %d, want 1", got)
+	}
+	if !cache.refsCurrent(ctx, cache.RepoPath("owner", "active")) {
+		t.Error("recently used repo was not fetched")
+	}
+	if cache.refsCurrent(ctx, cache.RepoPath("owner", "idle")) {
+		t.Error("idle repo was fetched")
+	}
+}
+
+func TestScheduler_RunsImmediatelyAndStops(t *testing.T) {
+	var runs atomic.Int32
+	scheduler := NewScheduler(time.Hour, func() { runs.Add(1) })
+
+	scheduler.Start()
+	deadline := time.Now().Add(time.Second)
+	for runs.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if runs.Load() != 1 {
+		t.Errorf("runs = %d, want 1 initial run", runs.Load())
+	}
+
+	// Stop is idempotent
+	scheduler.Stop()
+	scheduler.Stop()
+}
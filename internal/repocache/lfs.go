@@ -0,0 +1,47 @@
+package repocache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// errNoLFS is returned when a repo uses Git LFS but git-lfs is not installed.
+var errNoLFS = errors.New("git-lfs is not installed")
+
+// usesLFS reports whether the worktree's .gitattributes routes any paths
+// through the LFS filter.
+func usesLFS(worktreePath string) bool {
+	f, err := os.Open(filepath.Join(worktreePath, ".gitattributes"))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, attr := range strings.Fields(line) {
+			if attr == "filter=lfs" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pullLFS replaces LFS pointer files in the worktree with their content,
+// authenticating with the repo's credentials like fetch does.
+func (c *Cache) pullLFS(ctx context.Context, repoPath, worktreePath string) error {
+	if _, err := exec.LookPath("git-lfs"); err != nil {
+		return errNoLFS
+	}
+	return c.runRemote(ctx, repoPath, worktreePath, "git lfs pull", "lfs", "pull", "origin")
+}
@@ -0,0 +1,60 @@
+package repocache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUsesLFS(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes string // empty means no .gitattributes
+		want       bool
+	}{
+		{"no gitattributes", "", false},
+		{"lfs tracked", "*.psd filter=lfs diff=lfs merge=lfs -text\n", true},
+		{"other attributes", "*.sh text eol=lf\n", false},
+		{"commented out", "# *.psd filter=lfs diff=lfs merge=lfs -text\n", false},
+		{"similar filter name", "*.bin filter=lfs-custom\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.attributes != "" {
+				if err := os.WriteFile(filepath.Join(dir, ".gitattributes"), []byte(tt.attributes), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := usesLFS(dir); got != tt.want {
+				t.Errorf("usesLFS() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCache_CreateWorktreeWithLFSAttributes(t *testing.T) {
+	sourceDir := t.TempDir()
+	setupTestRepo(t, sourceDir)
+	if err := os.WriteFile(filepath.Join(sourceDir, ".gitattributes"), []byte("*.bin filter=lfs diff=lfs merge=lfs -text\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitOutput(t, sourceDir, "add", ".gitattributes")
+	gitOutput(t, sourceDir, "commit", "-m", "track binaries with lfs")
+
+	ctx := context.Background()
+	cache := New(t.TempDir())
+	if _, err := cache.EnsureRepo(ctx, sourceDir, "owner", "repo"); err != nil {
+		t.Fatalf("EnsureRepo() error = %v", err)
+	}
+
+	// Whether or not git-lfs is installed, the worktree is created
+	worktreePath, err := cache.CreateWorktree(ctx, "owner", "repo", "HEAD", "agent")
+	if err != nil {
+		t.Fatalf("CreateWorktree() error = %v", err)
+	}
+	if !usesLFS(worktreePath) {
+		t.Error("usesLFS() = false for a worktree tracking files with LFS")
+	}
+}
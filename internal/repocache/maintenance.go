@@ -0,0 +1,111 @@
+package repocache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maintenanceCheckInterval is how often the maintenance scheduler checks
+// whether a run is due.
+const maintenanceCheckInterval = 15 * time.Minute
+
+// Maintain runs `git gc --auto` in every cached repo, packing loose objects
+// and consolidating packs once git considers it worthwhile. The cache is
+// locked for one repo at a time. Returns the number of repos maintained.
+func (c *Cache) Maintain(ctx context.Context) int {
+	repoPaths, err := filepath.Glob(filepath.Join(c.baseDir, "*", "*.git"))
+	if err != nil {
+		slog.Warn("failed to list cached repos", "error", err)
+		return 0
+	}
+
+	maintained := 0
+	for _, repoPath := range repoPaths {
+		c.mu.Lock()
+		if _, err := os.Stat(repoPath); err == nil {
+			cmd := exec.CommandContext(ctx, "git", "gc", "--auto", "--quiet")
+			cmd.Dir = repoPath
+			if output, err := cmd.CombinedOutput(); err != nil {
+				slog.Warn("git gc failed", "path", repoPath, "error", err, "output", string(output))
+			} else {
+				maintained++
+			}
+		}
+		c.mu.Unlock()
+	}
+	return maintained
+}
+
+// Window is a daily time range, such as off-peak hours. It may wrap past
+// midnight. The zero Window allows any time.
+type Window struct {
+	Start, End time.Duration // Offsets from midnight
+}
+
+// ParseWindow parses "HH:MM-HH:MM" in local time. Empty means any time.
+func ParseWindow(s string) (Window, error) {
+	if s == "" {
+		return Window{}, nil
+	}
+	startStr, endStr, ok := strings.Cut(s, "-")
+	if !ok {
+		return Window{}, fmt.Errorf("invalid window %q (want HH:MM-HH:MM)", s)
+	}
+	start, err := parseClock(startStr)
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %w", s, err)
+	}
+	end, err := parseClock(endStr)
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %w", s, err)
+	}
+	return Window{Start: start, End: end}, nil
+}
+
+// parseClock parses HH:MM as an offset from midnight.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains reports whether t's time of day falls within the window.
+func (w Window) Contains(t time.Time) bool {
+	if w.Start == w.End {
+		return true
+	}
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End // Wraps past midnight
+}
+
+// NewMaintenanceScheduler creates a scheduler that maintains the cache's
+// repos once every interval, waiting for the window to open if needed.
+func NewMaintenanceScheduler(cache *Cache, interval time.Duration, window Window) *Scheduler {
+	var (
+		mu      sync.Mutex
+		lastRun time.Time
+	)
+	return NewScheduler(maintenanceCheckInterval, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		if !window.Contains(now) || now.Sub(lastRun) < interval {
+			return
+		}
+		lastRun = now
+		n := cache.Maintain(context.Background())
+		slog.Info("maintained cached repos", "repos", n, "duration", time.Since(now).Round(time.Second))
+	})
+}
@@ -0,0 +1,78 @@
+package repocache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Window
+		wantErr bool
+	}{
+		{"", Window{}, false},
+		{"02:00-05:30", Window{Start: 2 * time.Hour, End: 5*time.Hour + 30*time.Minute}, false},
+		{"22:00-04:00", Window{Start: 22 * time.Hour, End: 4 * time.Hour}, false},
+		{"02:00", Window{}, true},
+		{"2am-5am", Window{}, true},
+		{"25:00-04:00", Window{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseWindow(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWindow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseWindow() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindow_Contains(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 1, 1, hour, minute, 0, 0, time.Local)
+	}
+	night := Window{Start: 22 * time.Hour, End: 4 * time.Hour}
+	early := Window{Start: 2 * time.Hour, End: 5 * time.Hour}
+
+	tests := []struct {
+		name   string
+		window Window
+		t      time.Time
+		want   bool
+	}{
+		{"any time", Window{}, at(13, 0), true},
+		{"inside", early, at(3, 15), true},
+		{"at start", early, at(2, 0), true},
+		{"at end", early, at(5, 0), false},
+		{"before", early, at(1, 59), false},
+		{"wrapping, before midnight", night, at(23, 0), true},
+		{"wrapping, after midnight", night, at(1, 0), true},
+		{"wrapping, outside", night, at(12, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Contains(tt.t); got != tt.want {
+				t.Errorf("Contains(%s) = %v, want %v", tt.t.Format("15:04"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCache_Maintain(t *testing.T) {
+	sourceDir := t.TempDir()
+	setupTestRepo(t, sourceDir)
+	ctx := context.Background()
+	cache := New(t.TempDir())
+	if _, err := cache.EnsureRepo(ctx, sourceDir, "owner", "repo"); err != nil {
+		t.Fatalf("EnsureRepo() error = %v", err)
+	}
+
+	if got := cache.Maintain(ctx); got != 1 {
+		t.Errorf("Maintain() = %d, want 1", got)
+	}
+}
@@ -0,0 +1,204 @@
+package repocache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Identity used for commits the server makes on an agent's behalf.
+const (
+	commitName  = "Familiar"
+	commitEmail = "familiar@noreply.localhost"
+)
+
+// worktreeGit returns a git command for the worktree that ignores what an
+// agent could have planted there: the gitdir is taken from the cache rather
+// than the worktree's .git file, and hooks, fsmonitor, and external diff
+// drivers are turned off, so nothing from the worktree runs on the host.
+func (c *Cache) worktreeGit(ctx context.Context, owner, repo, worktreeID string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", append(c.worktreeArgs(owner, repo, worktreeID), args...)...)
+	cmd.Dir = c.WorktreePath(owner, repo, worktreeID)
+	return cmd
+}
+
+// worktreeArgs returns the global git options used by worktreeGit.
+func (c *Cache) worktreeArgs(owner, repo, worktreeID string) []string {
+	return []string{
+		"--git-dir=" + filepath.Join(c.RepoPath(owner, repo), "worktrees", worktreeID),
+		"--work-tree=" + c.WorktreePath(owner, repo, worktreeID),
+		"-c", "core.hooksPath=/dev/null",
+		"-c", "core.fsmonitor=false",
+	}
+}
+
+// git runs a git command in the worktree and returns its trimmed output.
+func (c *Cache) git(ctx context.Context, owner, repo, worktreeID string, args ...string) (string, error) {
+	output, err := c.worktreeGit(ctx, owner, repo, worktreeID, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// CommitChanges commits any uncommitted changes in the worktree and returns
+// how many commits the worktree is now ahead of base.
+func (c *Cache) CommitChanges(ctx context.Context, owner, repo, worktreeID, base, message string) (int, error) {
+	if _, err := c.git(ctx, owner, repo, worktreeID, "add", "-A"); err != nil {
+		return 0, err
+	}
+
+	// diff --cached --quiet exits 1 when there is something to commit
+	err := c.worktreeGit(ctx, owner, repo, worktreeID, "diff", "--cached", "--quiet", "--no-ext-diff").Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		// Nothing staged
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		if _, err := c.git(ctx, owner, repo, worktreeID,
+			"-c", "user.name="+commitName, "-c", "user.email="+commitEmail,
+			"commit", "--no-verify", "-m", message); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("checking for changes: %w", err)
+	}
+
+	count, err := c.git(ctx, owner, repo, worktreeID, "rev-list", "--count", base+"..HEAD")
+	if err != nil {
+		return 0, err
+	}
+	ahead, err := strconv.Atoi(count)
+	if err != nil {
+		return 0, fmt.Errorf("parsing commit count %q: %w", count, err)
+	}
+	return ahead, nil
+}
+
+// PushChanges pushes the worktree's HEAD to the given branch of the URL the
+// repo was cloned from, using the cache's own credentials. Only that URL's
+// protocol is allowed, so repo config cannot redirect the push or run a
+// remote helper.
+func (c *Cache) PushChanges(ctx context.Context, owner, repo, worktreeID, branch string) error {
+	repoPath := c.RepoPath(owner, repo)
+	pushURL, ok := c.remote(repoPath)
+	if !ok {
+		return fmt.Errorf("no remote URL known for %s/%s", owner, repo)
+	}
+	args := append(c.worktreeArgs(owner, repo, worktreeID), protocolArgs(pushURL)...)
+	args = append(args, "push", "--no-verify", pushURL, "HEAD:refs/heads/"+branch)
+	return c.runRemote(ctx, repoPath, c.WorktreePath(owner, repo, worktreeID), "git push", args...)
+}
+
+// protocolArgs returns git options that allow only remoteURL's protocol.
+func protocolArgs(remoteURL string) []string {
+	return []string{"-c", "protocol.allow=never", "-c", "protocol." + urlProtocol(remoteURL) + ".allow=always"}
+}
+
+// urlProtocol returns the git transport protocol a remote URL uses.
+func urlProtocol(remoteURL string) string {
+	if parsed, err := url.Parse(remoteURL); err == nil && parsed.Scheme != "" {
+		return parsed.Scheme
+	}
+	// scp-like syntax, user@host:path, is ssh; anything else is a local path
+	if colon := strings.Index(remoteURL, ":"); colon > 0 && !strings.Contains(remoteURL[:colon], "/") {
+		return "ssh"
+	}
+	return "file"
+}
+
+// FormatPatch returns the worktree's commits since base as an mbox patch
+// series suitable for `git am`.
+func (c *Cache) FormatPatch(ctx context.Context, owner, repo, worktreeID, base string) (string, error) {
+	return c.git(ctx, owner, repo, worktreeID, "format-patch", "--stdout", base+"..HEAD")
+}
+
+// ChangedFiles lists the files changed in the worktree since base, whether
+// committed or not, including untracked files. Paths are sorted and
+// relative to the repo root.
+func (c *Cache) ChangedFiles(ctx context.Context, owner, repo, worktreeID, base string) ([]string, error) {
+	committed, err := c.git(ctx, owner, repo, worktreeID, "diff", "--no-ext-diff", "--name-only", "-z", base, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	// Read status untrimmed: its first entry may start with a space
+	status, err := c.worktreeGit(ctx, owner, repo, worktreeID,
+		"status", "--porcelain", "-z", "--untracked-files=all").Output()
+	if err != nil {
+		return nil, fmt.Errorf("git status: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, path := range strings.Split(committed, "\x00") {
+		if path != "" {
+			seen[path] = true
+		}
+	}
+	// Entries are "XY path"; a rename or copy is followed by its source path
+	entries := strings.Split(string(status), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		seen[entry[3:]] = true
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++
+		}
+	}
+
+	files := make([]string, 0, len(seen))
+	for path := range seen {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// RemoteChangedFiles fetches branch from the URL the repo was cloned from
+// and lists the files that differ between base and its fetched head. Unlike
+// ChangedFiles it reads what the remote has, so it sees changes an agent
+// pushed and then removed from its worktree. Paths are sorted and relative
+// to the repo root.
+func (c *Cache) RemoteChangedFiles(ctx context.Context, owner, repo, worktreeID, base, branch string) ([]string, error) {
+	repoPath := c.RepoPath(owner, repo)
+	fetchURL, ok := c.remote(repoPath)
+	if !ok {
+		return nil, fmt.Errorf("no remote URL known for %s/%s", owner, repo)
+	}
+
+	// Fetch into a ref of the agent's own so concurrent checks don't collide
+	ref := "refs/familiar/checks/" + worktreeID
+	args := append([]string{"-c", "core.hooksPath=/dev/null"}, protocolArgs(fetchURL)...)
+	args = append(args, "fetch", "--no-tags", "--no-write-fetch-head", fetchURL, "+refs/heads/"+branch+":"+ref)
+	if err := c.runRemote(ctx, repoPath, repoPath, "fetching "+branch, args...); err != nil {
+		return nil, err
+	}
+	defer func() {
+		del := exec.Command("git", "-c", "core.hooksPath=/dev/null", "update-ref", "-d", ref)
+		del.Dir = repoPath
+		del.Run()
+	}()
+
+	diff := exec.CommandContext(ctx, "git", "-c", "core.hooksPath=/dev/null",
+		"diff", "--no-ext-diff", "--name-only", "-z", base, ref)
+	diff.Dir = repoPath
+	output, err := diff.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff: %w", err)
+	}
+	var files []string
+	for _, path := range strings.Split(string(output), "\x00") {
+		if path != "" {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,270 @@
+package repocache
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// setupPatchWorktree creates a cached repo with a worktree on the source
+// repo's default branch and returns the cache, source dir, and branch.
+func setupPatchWorktree(t *testing.T) (*Cache, string, string) {
+	t.Helper()
+	sourceDir := t.TempDir()
+	setupTestRepo(t, sourceDir)
+
+	out, err := exec.Command("git", "-C", sourceDir, "symbolic-ref", "--short", "HEAD").Output()
+	if err != nil {
+		t.Fatalf("reading default branch: %v", err)
+	}
+	branch := strings.TrimSpace(string(out))
+
+	cache := New(t.TempDir())
+	ctx := context.Background()
+	if _, err := cache.EnsureRepo(ctx, sourceDir, "owner", "repo"); err != nil {
+		t.Fatalf("EnsureRepo() error = %v", err)
+	}
+	if _, err := cache.CreateWorktree(ctx, "owner", "repo", branch, "agent-1"); err != nil {
+		t.Fatalf("CreateWorktree() error = %v", err)
+	}
+	return cache, sourceDir, branch
+}
+
+func TestCache_CommitChanges(t *testing.T) {
+	cache, _, branch := setupPatchWorktree(t)
+	ctx := context.Background()
+
+	// No changes yet
+	ahead, err := cache.CommitChanges(ctx, "owner", "repo", "agent-1", branch, "Apply agent changes")
+	if err != nil {
+		t.Fatalf("CommitChanges() error = %v", err)
+	}
+	if ahead != 0 {
+		t.Errorf("ahead = %d with no changes, want 0", ahead)
+	}
+
+	worktree := cache.WorktreePath("owner", "repo", "agent-1")
+	if err := os.WriteFile(filepath.Join(worktree, "fix.txt"), []byte("fixed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ahead, err = cache.CommitChanges(ctx, "owner", "repo", "agent-1", branch, "Apply agent changes")
+	if err != nil {
+		t.Fatalf("CommitChanges() error = %v", err)
+	}
+	if ahead != 1 {
+		t.Errorf("ahead = %d after commit, want 1", ahead)
+	}
+
+	patch, err := cache.FormatPatch(ctx, "owner", "repo", "agent-1", branch)
+	if err != nil {
+		t.Fatalf("FormatPatch() error = %v", err)
+	}
+	for _, want := range []string{"From: Familiar <familiar@noreply.localhost>", "Subject: [PATCH] Apply agent changes", "+fixed"} {
+		if !strings.Contains(patch, want) {
+			t.Errorf("patch missing %q:\n%s", want, patch)
+		}
+	}
+}
+
+func TestCache_PushChanges(t *testing.T) {
+	cache, sourceDir, branch := setupPatchWorktree(t)
+	ctx := context.Background()
+
+	worktree := cache.WorktreePath("owner", "repo", "agent-1")
+	if err := os.WriteFile(filepath.Join(worktree, "fix.txt"), []byte("fixed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.CommitChanges(ctx, "owner", "repo", "agent-1", branch, "Apply agent changes"); err != nil {
+		t.Fatalf("CommitChanges() error = %v", err)
+	}
+
+	// The source has the default branch checked out, so push to a new one
+	if err := cache.PushChanges(ctx, "owner", "repo", "agent-1", "familiar/fix"); err != nil {
+		t.Fatalf("PushChanges() error = %v", err)
+	}
+
+	out, err := exec.Command("git", "-C", sourceDir, "log", "-1", "--format=%s", "familiar/fix").Output()
+	if err != nil {
+		t.Fatalf("reading pushed branch: %v", err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "Apply agent changes" {
+		t.Errorf("pushed commit subject = %q, want %q", got, "Apply agent changes")
+	}
+}
+
+func TestCache_PatchIgnoresPlantedConfig(t *testing.T) {
+	cache, sourceDir, branch := setupPatchWorktree(t)
+	ctx := context.Background()
+	repoPath := cache.RepoPath("owner", "repo")
+	worktree := cache.WorktreePath("owner", "repo", "agent-1")
+
+	// Plant what an agent with write access to the cache could: hooks in
+	// the repo and in a configured hooks dir, an fsmonitor command, and an
+	// origin pointing somewhere else
+	marker := filepath.Join(t.TempDir(), "ran")
+	script := "#!/bin/sh\ntouch " + marker + "\n"
+	hooksDir := t.TempDir()
+	for _, dir := range []string{filepath.Join(repoPath, "hooks"), hooksDir} {
+		for _, hook := range []string{"pre-commit", "post-commit", "pre-push"} {
+			if err := os.WriteFile(filepath.Join(dir, hook), []byte(script), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	fsmonitor := filepath.Join(hooksDir, "fsmonitor")
+	if err := os.WriteFile(fsmonitor, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	decoy := t.TempDir()
+	if out, err := exec.Command("git", "init", "--bare", decoy).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	for key, value := range map[string]string{
+		"core.hooksPath":    hooksDir,
+		"core.fsmonitor":    fsmonitor,
+		"remote.origin.url": decoy,
+	} {
+		if out, err := exec.Command("git", "-C", repoPath, "config", key, value).CombinedOutput(); err != nil {
+			t.Fatalf("git config %s: %v: %s", key, err, out)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(worktree, "fix.txt"), []byte("fixed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.CommitChanges(ctx, "owner", "repo", "agent-1", branch, "Apply agent changes"); err != nil {
+		t.Fatalf("CommitChanges() error = %v", err)
+	}
+	if _, err := cache.ChangedFiles(ctx, "owner", "repo", "agent-1", branch); err != nil {
+		t.Fatalf("ChangedFiles() error = %v", err)
+	}
+	if err := cache.PushChanges(ctx, "owner", "repo", "agent-1", "familiar/fix"); err != nil {
+		t.Fatalf("PushChanges() error = %v", err)
+	}
+
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("a planted hook or fsmonitor command ran on the server")
+	}
+	if err := exec.Command("git", "-C", sourceDir, "rev-parse", "--verify", "familiar/fix").Run(); err != nil {
+		t.Errorf("branch not pushed to the clone URL: %v", err)
+	}
+	if err := exec.Command("git", "-C", decoy, "rev-parse", "--verify", "familiar/fix").Run(); err == nil {
+		t.Error("branch pushed to the rewritten origin URL")
+	}
+}
+
+func TestCache_PushChanges_UnknownRemote(t *testing.T) {
+	cache, _, _ := setupPatchWorktree(t)
+	// A cache that never cloned the repo does not know where to push it
+	fresh := New(filepath.Dir(filepath.Dir(cache.RepoPath("owner", "repo"))))
+	if err := fresh.PushChanges(context.Background(), "owner", "repo", "agent-1", "familiar/fix"); err == nil {
+		t.Error("PushChanges() error = nil, want error for an unknown remote")
+	}
+}
+
+func TestURLProtocol(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://github.com/owner/repo.git", "https"},
+		{"ssh://git@gitlab.com/owner/repo.git", "ssh"},
+		{"git@github.com:owner/repo.git", "ssh"},
+		{"/var/cache/repo", "file"},
+		{"file:///var/cache/repo", "file"},
+	}
+	for _, tt := range tests {
+		if got := urlProtocol(tt.url); got != tt.want {
+			t.Errorf("urlProtocol(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestCache_ChangedFiles(t *testing.T) {
+	cache, _, branch := setupPatchWorktree(t)
+	ctx := context.Background()
+	worktree := cache.WorktreePath("owner", "repo", "agent-1")
+
+	files, err := cache.ChangedFiles(ctx, "owner", "repo", "agent-1", branch)
+	if err != nil {
+		t.Fatalf("ChangedFiles() error = %v", err)
+	}
+	if len(files) != 0 {
+		t.Errorf("ChangedFiles() = %v with no changes, want none", files)
+	}
+
+	// One file committed, one modified, and one left untracked
+	if err := os.WriteFile(filepath.Join(worktree, "committed.txt"), []byte("done\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.CommitChanges(ctx, "owner", "repo", "agent-1", branch, "Commit"); err != nil {
+		t.Fatalf("CommitChanges() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(worktree, "README.md"), []byte("changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(worktree, "new dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(worktree, "new dir", "untracked.txt"), []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err = cache.ChangedFiles(ctx, "owner", "repo", "agent-1", branch)
+	if err != nil {
+		t.Fatalf("ChangedFiles() error = %v", err)
+	}
+	want := []string{"README.md", "committed.txt", "new dir/untracked.txt"}
+	if !slices.Equal(files, want) {
+		t.Errorf("ChangedFiles() = %q, want %q", files, want)
+	}
+}
+
+func TestCache_RemoteChangedFiles(t *testing.T) {
+	cache, _, branch := setupPatchWorktree(t)
+	ctx := context.Background()
+	worktree := cache.WorktreePath("owner", "repo", "agent-1")
+
+	base, err := exec.Command("git", "-C", worktree, "rev-parse", "HEAD").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The agent pushes a change, then resets its worktree to hide it
+	if err := os.MkdirAll(filepath.Join(worktree, ".github"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(worktree, ".github", "ci.yml"), []byte("on: push\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.CommitChanges(ctx, "owner", "repo", "agent-1", branch, "Change CI"); err != nil {
+		t.Fatalf("CommitChanges() error = %v", err)
+	}
+	if err := cache.PushChanges(ctx, "owner", "repo", "agent-1", "feature"); err != nil {
+		t.Fatalf("PushChanges() error = %v", err)
+	}
+	if out, err := exec.Command("git", "-C", worktree, "reset", "--hard", strings.TrimSpace(string(base))).CombinedOutput(); err != nil {
+		t.Fatalf("git reset: %v: %s", err, out)
+	}
+
+	files, err := cache.RemoteChangedFiles(ctx, "owner", "repo", "agent-1", strings.TrimSpace(string(base)), "feature")
+	if err != nil {
+		t.Fatalf("RemoteChangedFiles() error = %v", err)
+	}
+	if !slices.Equal(files, []string{".github/ci.yml"}) {
+		t.Errorf("RemoteChangedFiles() = %v, want [.github/ci.yml]", files)
+	}
+
+	// The check ref is removed afterwards
+	if err := exec.Command("git", "-C", cache.RepoPath("owner", "repo"), "rev-parse", "--verify", "refs/familiar/checks/agent-1").Run(); err == nil {
+		t.Error("check ref left behind")
+	}
+	if _, err := cache.RemoteChangedFiles(ctx, "owner", "repo", "agent-1", strings.TrimSpace(string(base)), "missing"); err == nil {
+		t.Error("RemoteChangedFiles() error = nil for a branch the remote doesn't have")
+	}
+}
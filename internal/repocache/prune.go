@@ -0,0 +1,65 @@
+package repocache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// PruneWorktrees removes agent worktrees older than maxAge whose ID active
+// does not report as in use, then runs `git worktree prune` so git forgets
+// them. Returns the number of worktrees removed.
+func (c *Cache) PruneWorktrees(ctx context.Context, maxAge time.Duration, active func(worktreeID string) bool) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	repoPaths, err := filepath.Glob(filepath.Join(c.baseDir, "*", "*.git"))
+	if err != nil {
+		return 0, fmt.Errorf("listing cached repos: %w", err)
+	}
+
+	threshold := time.Now().Add(-maxAge)
+	removed := 0
+	for _, repoPath := range repoPaths {
+		entries, err := os.ReadDir(filepath.Join(repoPath, "worktrees-data"))
+		if err != nil && !os.IsNotExist(err) {
+			slog.Warn("failed to list worktrees", "path", repoPath, "error", err)
+		}
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil || info.ModTime().After(threshold) || active(entry.Name()) {
+				continue
+			}
+			worktreePath := filepath.Join(repoPath, "worktrees-data", entry.Name())
+			if err := os.RemoveAll(worktreePath); err != nil {
+				slog.Warn("failed to remove stale worktree", "path", worktreePath, "error", err)
+				continue
+			}
+			removed++
+		}
+
+		cmd := exec.CommandContext(ctx, "git", "worktree", "prune")
+		cmd.Dir = repoPath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			slog.Warn("git worktree prune failed", "path", repoPath, "error", err, "output", string(output))
+		}
+	}
+	return removed, nil
+}
+
+// NewPruneScheduler creates a scheduler that prunes worktrees older than
+// maxAge every interval, keeping those active reports as in use.
+func NewPruneScheduler(cache *Cache, interval, maxAge time.Duration, active func(worktreeID string) bool) *Scheduler {
+	return NewScheduler(interval, func() {
+		removed, err := cache.PruneWorktrees(context.Background(), maxAge, active)
+		if err != nil {
+			slog.Error("worktree prune failed", "error", err)
+		} else if removed > 0 {
+			slog.Info("pruned stale worktrees", "removed", removed)
+		}
+	})
+}
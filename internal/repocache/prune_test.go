@@ -0,0 +1,73 @@
+package repocache
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache_PruneWorktrees(t *testing.T) {
+	cache := New(t.TempDir())
+	sourceDir := t.TempDir()
+	setupTestRepo(t, sourceDir)
+
+	ctx := context.Background()
+	if _, err := cache.EnsureRepo(ctx, sourceDir, "owner", "repo"); err != nil {
+		t.Fatalf("EnsureRepo() error = %v", err)
+	}
+
+	old := time.Now().Add(-48 * time.Hour)
+	for _, id := range []string{"stale", "running", "fresh"} {
+		path, err := cache.CreateWorktree(ctx, "owner", "repo", "HEAD", id)
+		if err != nil {
+			t.Fatalf("CreateWorktree(%s) error = %v", id, err)
+		}
+		if id != "fresh" {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	active := func(id string) bool { return id == "running" }
+	removed, err := cache.PruneWorktrees(ctx, 24*time.Hour, active)
+	if err != nil {
+		t.Fatalf("PruneWorktrees() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("PruneWorktrees() removed %d, want 1", removed)
+	}
+
+	for id, wantExists := range map[string]bool{"stale": false, "running": true, "fresh": true} {
+		_, err := os.Stat(cache.WorktreePath("owner", "repo", id))
+		if exists := err == nil; exists != wantExists {
+			t.Errorf("worktree %s exists = %v, want %v", id, exists, wantExists)
+		}
+	}
+
+	// git no longer tracks the pruned worktree
+	cmd := exec.Command("git", "worktree", "list")
+	cmd.Dir = cache.RepoPath("owner", "repo")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git worktree list: %v: %s", err, output)
+	}
+	if strings.Contains(string(output), "stale") {
+		t.Errorf("git still lists the pruned worktree:\n%s", output)
+	}
+}
+
+func TestPruneScheduler_StartStop(t *testing.T) {
+	cache := New(t.TempDir())
+	scheduler := NewPruneScheduler(cache, 100*time.Millisecond, time.Hour, func(string) bool { return false })
+
+	scheduler.Start()
+	time.Sleep(10 * time.Millisecond)
+
+	// Stop is idempotent
+	scheduler.Stop()
+	scheduler.Stop()
+}
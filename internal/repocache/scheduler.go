@@ -0,0 +1,47 @@
+package repocache
+
+import (
+	"sync"
+	"time"
+)
+
+// Scheduler runs a cache maintenance task periodically.
+type Scheduler struct {
+	run      func()
+	ticker   *time.Ticker
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewScheduler creates a scheduler that calls run every interval.
+func NewScheduler(interval time.Duration, run func()) *Scheduler {
+	return &Scheduler{
+		run:    run,
+		ticker: time.NewTicker(interval),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start runs the task immediately, then on every tick until Stop.
+func (s *Scheduler) Start() {
+	go s.run()
+
+	go func() {
+		for {
+			select {
+			case <-s.ticker.C:
+				s.run()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduler. It is safe to call more than once.
+func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() {
+		s.ticker.Stop()
+		close(s.stop)
+	})
+}
@@ -0,0 +1,61 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ageCommand is the age binary that decrypts ${enc:...} values.
+const ageCommand = "age"
+
+// Age decrypts config values encrypted with age, referenced as
+// ${enc:<base64 ciphertext>}, such as the output of
+// `printf %s "$TOKEN" | age -r <recipient> | base64 -w0`.
+type Age struct {
+	keyFile string
+}
+
+// AgeFromEnv returns an Age that decrypts with the identities in the key
+// file sops uses: SOPS_AGE_KEY_FILE, or sops/age/keys.txt in the user's
+// config directory.
+func AgeFromEnv() (*Age, error) {
+	keyFile := os.Getenv("SOPS_AGE_KEY_FILE")
+	if keyFile == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("age: SOPS_AGE_KEY_FILE is not set: %w", err)
+		}
+		keyFile = filepath.Join(dir, "sops", "age", "keys.txt")
+	}
+	if _, err := os.Stat(keyFile); err != nil {
+		return nil, fmt.Errorf("age key file: %w", err)
+	}
+	return &Age{keyFile: keyFile}, nil
+}
+
+// Fetch implements Fetcher. The plaintext's trailing newline is dropped.
+func (a *Age) Fetch(ctx context.Context, ref string) (Secret, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(ref)
+	if err != nil {
+		return Secret{}, fmt.Errorf("encrypted value is not base64: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, ageCommand, "--decrypt", "--identity", a.keyFile)
+	cmd.Stdin = bytes.NewReader(ciphertext)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	plaintext, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return Secret{}, fmt.Errorf("decrypting with age: %w", err)
+	}
+	return Secret{Value: strings.TrimRight(string(plaintext), "\r\n")}, nil
+}
@@ -0,0 +1,73 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAge_Fetch(t *testing.T) {
+	// Stands in for age: "decrypts" by stripping a marker, if given the key
+	bin := t.TempDir()
+	script := `#!/bin/sh
+[ "$1 $2 $3" = "--decrypt --identity $AGE_TEST_KEY" ] || { echo "wrong arguments: $*" >&2; exit 2; }
+input=$(cat)
+case "$input" in
+  age-encryption.org/v1:*) printf '%s\n' "${input#age-encryption.org/v1:}" ;;
+  *) echo "age: error: no identity matched any of the recipients" >&2; exit 1 ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(bin, "age"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	keyFile := filepath.Join(t.TempDir(), "keys.txt")
+	if err := os.WriteFile(keyFile, []byte("AGE-SECRET-KEY-1EXAMPLE\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AGE_TEST_KEY", keyFile)
+	t.Setenv("SOPS_AGE_KEY_FILE", keyFile)
+
+	a, err := AgeFromEnv()
+	if err != nil {
+		t.Fatalf("AgeFromEnv() error = %v", err)
+	}
+	encode := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{name: "decrypts", ref: encode("age-encryption.org/v1:glpat-secret"), want: "glpat-secret"},
+		{name: "wrong key", ref: encode("someone else's"), wantErr: "no identity matched"},
+		{name: "not base64", ref: "not base64!", wantErr: "not base64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.Fetch(context.Background(), tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Fetch() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			if got.Value != tt.want {
+				t.Errorf("Fetch() = %q, want %q", got.Value, tt.want)
+			}
+		})
+	}
+
+	t.Setenv("SOPS_AGE_KEY_FILE", filepath.Join(t.TempDir(), "missing.txt"))
+	if _, err := AgeFromEnv(); err == nil {
+		t.Error("AgeFromEnv() with a missing key file should fail")
+	}
+}
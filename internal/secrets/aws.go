@@ -0,0 +1,85 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/awsauth"
+)
+
+// AWSSecretsManager fetches secrets from AWS Secrets Manager. References
+// are "<secret name or ARN>[#key]", the key selecting a field of a JSON
+// secret.
+type AWSSecretsManager struct {
+	region   string
+	endpoint string
+	creds    awsauth.Credentials
+	client   *http.Client
+	now      func() time.Time
+}
+
+// AWSSecretsManagerFromEnv configures Secrets Manager from AWS_REGION and
+// the standard AWS credential environment variables.
+func AWSSecretsManagerFromEnv() (*AWSSecretsManager, error) {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		return nil, errors.New("aws-sm references need AWS_REGION")
+	}
+	creds := awsauth.CredentialsFromEnv()
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, errors.New("aws-sm references need AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	return &AWSSecretsManager{
+		region:   region,
+		endpoint: "https://secretsmanager." + region + ".amazonaws.com",
+		creds:    creds,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		now:      time.Now,
+	}, nil
+}
+
+// Fetch implements Fetcher.
+func (m *AWSSecretsManager) Fetch(ctx context.Context, ref string) (Secret, error) {
+	id, key := splitKey(ref)
+	body, err := json.Marshal(map[string]string{"SecretId": id})
+	if err != nil {
+		return Secret{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return Secret{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	awsauth.SignV4(req, body, m.creds, m.region, "secretsmanager", m.now())
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return Secret{}, fmt.Errorf("calling secrets manager: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return Secret{}, fmt.Errorf("GetSecretValue %s: %s: %s", id, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var out struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Secret{}, fmt.Errorf("decoding GetSecretValue response: %w", err)
+	}
+	value, err := jsonField(out.SecretString, key)
+	if err != nil {
+		return Secret{}, fmt.Errorf("secrets manager secret %s: %w", id, err)
+	}
+	return Secret{Value: value}, nil
+}
@@ -0,0 +1,58 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/awsauth"
+)
+
+func TestAWSSecretsManager_Fetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/20260115/us-east-1/secretsmanager/") {
+			t.Errorf("Authorization = %q, want a SigV4 signature for secretsmanager", r.Header.Get("Authorization"))
+		}
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" {
+			t.Errorf("X-Amz-Target = %q", r.Header.Get("X-Amz-Target"))
+		}
+		var body struct{ SecretId string }
+		json.NewDecoder(r.Body).Decode(&body)
+		switch body.SecretId {
+		case "familiar/gitlab":
+			w.Write([]byte(`{"SecretString":"{\"token\":\"glpat-aws\"}"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceNotFoundException"}`))
+		}
+	}))
+	defer srv.Close()
+
+	m := &AWSSecretsManager{
+		region:   "us-east-1",
+		endpoint: srv.URL,
+		creds:    awsauth.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+		client:   srv.Client(),
+		now:      func() time.Time { return time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC) },
+	}
+
+	got, err := m.Fetch(context.Background(), "familiar/gitlab#token")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if got.Value != "glpat-aws" {
+		t.Errorf("Fetch() = %q, want glpat-aws", got.Value)
+	}
+	got, err = m.Fetch(context.Background(), "familiar/gitlab")
+	if err != nil || got.Value != `{"token":"glpat-aws"}` {
+		t.Errorf("Fetch() without a key = %q, %v; want the whole secret", got.Value, err)
+	}
+
+	if _, err := m.Fetch(context.Background(), "familiar/missing"); err == nil || !strings.Contains(err.Error(), "ResourceNotFoundException") {
+		t.Errorf("Fetch() error = %v, want the service error", err)
+	}
+}
@@ -0,0 +1,124 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gcpMetadataTokenURL returns an access token for the instance's service
+// account on GCE, GKE with workload identity, and Cloud Run.
+const gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// GCPSecretManager fetches secrets from GCP Secret Manager. References
+// are "projects/<project>/secrets/<name>[/versions/<version>][#key]",
+// defaulting to the latest version, the key selecting a field of a JSON
+// secret.
+type GCPSecretManager struct {
+	endpoint string
+	tokenURL string
+	token    string // fixed access token, if given
+	client   *http.Client
+	now      func() time.Time
+
+	mu           sync.Mutex
+	cachedToken  string
+	tokenExpires time.Time
+}
+
+// GCPSecretManagerFromEnv configures Secret Manager to authenticate with
+// GOOGLE_OAUTH_ACCESS_TOKEN if set, or else the metadata server.
+func GCPSecretManagerFromEnv() *GCPSecretManager {
+	return &GCPSecretManager{
+		endpoint: "https://secretmanager.googleapis.com",
+		tokenURL: gcpMetadataTokenURL,
+		token:    os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		now:      time.Now,
+	}
+}
+
+// Fetch implements Fetcher.
+func (m *GCPSecretManager) Fetch(ctx context.Context, ref string) (Secret, error) {
+	name, key := splitKey(ref)
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+	token, err := m.accessToken(ctx)
+	if err != nil {
+		return Secret{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.endpoint+"/v1/"+name+":access", nil)
+	if err != nil {
+		return Secret{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	var out struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := m.getJSON(req, &out); err != nil {
+		return Secret{}, fmt.Errorf("accessing %s: %w", name, err)
+	}
+	data, err := base64.StdEncoding.DecodeString(out.Payload.Data)
+	if err != nil {
+		return Secret{}, fmt.Errorf("decoding %s: %w", name, err)
+	}
+	value, err := jsonField(string(data), key)
+	if err != nil {
+		return Secret{}, fmt.Errorf("secret manager secret %s: %w", name, err)
+	}
+	return Secret{Value: value}, nil
+}
+
+// accessToken returns the fixed token, or a metadata server token,
+// refreshed a minute before it expires.
+func (m *GCPSecretManager) accessToken(ctx context.Context) (string, error) {
+	if m.token != "" {
+		return m.token, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cachedToken != "" && m.now().Before(m.tokenExpires) {
+		return m.cachedToken, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.tokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := m.getJSON(req, &out); err != nil {
+		return "", fmt.Errorf("getting a GCP access token (set GOOGLE_OAUTH_ACCESS_TOKEN outside GCP): %w", err)
+	}
+	m.cachedToken = out.AccessToken
+	m.tokenExpires = m.now().Add(time.Duration(out.ExpiresIn)*time.Second - time.Minute)
+	return m.cachedToken, nil
+}
+
+// getJSON sends req and decodes its JSON response into out.
+func (m *GCPSecretManager) getJSON(req *http.Request, out any) error {
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,56 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGCPSecretManager_Fetch(t *testing.T) {
+	tokenRequests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				t.Errorf("Metadata-Flavor = %q, want Google", r.Header.Get("Metadata-Flavor"))
+			}
+			tokenRequests++
+			w.Write([]byte(`{"access_token":"ya29.token","expires_in":3600}`))
+		case "/v1/projects/p/secrets/gitlab-token/versions/latest:access":
+			if r.Header.Get("Authorization") != "Bearer ya29.token" {
+				t.Errorf("Authorization = %q, want the metadata token", r.Header.Get("Authorization"))
+			}
+			data := base64.StdEncoding.EncodeToString([]byte("glpat-gcp"))
+			w.Write([]byte(`{"payload":{"data":"` + data + `"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	m := &GCPSecretManager{
+		endpoint: srv.URL,
+		tokenURL: srv.URL + "/token",
+		client:   srv.Client(),
+		now:      time.Now,
+	}
+	for range 2 {
+		got, err := m.Fetch(context.Background(), "projects/p/secrets/gitlab-token")
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		if got.Value != "glpat-gcp" {
+			t.Errorf("Fetch() = %q, want glpat-gcp", got.Value)
+		}
+	}
+	if tokenRequests != 1 {
+		t.Errorf("token requests = %d, want the token reused", tokenRequests)
+	}
+
+	if _, err := m.Fetch(context.Background(), "projects/p/secrets/missing/versions/3"); err == nil {
+		t.Error("Fetch() of a missing secret should fail")
+	}
+}
@@ -0,0 +1,157 @@
+// Package secrets resolves ${vault:...}, ${aws-sm:...}, and ${gcp-sm:...}
+// config references from HashiCorp Vault, AWS Secrets Manager, and GCP
+// Secret Manager, and decrypts age-encrypted ${enc:...} values. Importing
+// it registers the resolvers with the config package; each is configured
+// from its service's standard environment variables the first time it is
+// used.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/config"
+)
+
+// defaultTTL is how long a secret is cached when its source gives no lease.
+const defaultTTL = 5 * time.Minute
+
+func init() {
+	config.RegisterResolver("vault", NewCache(lazy(func() (Fetcher, error) { return VaultFromEnv() }), defaultTTL))
+	config.RegisterResolver("aws-sm", NewCache(lazy(func() (Fetcher, error) { return AWSSecretsManagerFromEnv() }), defaultTTL))
+	config.RegisterResolver("gcp-sm", NewCache(lazy(func() (Fetcher, error) { return GCPSecretManagerFromEnv(), nil }), defaultTTL))
+	config.RegisterResolver("enc", NewCache(lazy(func() (Fetcher, error) { return AgeFromEnv() }), defaultTTL))
+}
+
+// credentialEnv names the variables holding the secret stores' own
+// credentials.
+var credentialEnv = []string{"VAULT_TOKEN", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "GOOGLE_OAUTH_ACCESS_TOKEN"}
+
+// Credentials returns the secret stores' own credentials set in the
+// environment, including the Vault token file's contents, so they can be
+// kept from agents.
+func Credentials() []string {
+	var creds []string
+	for _, name := range credentialEnv {
+		if v := os.Getenv(name); v != "" {
+			creds = append(creds, v)
+		}
+	}
+	if path := os.Getenv("VAULT_TOKEN_FILE"); path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			creds = append(creds, strings.TrimSpace(string(data)))
+		}
+	}
+	return creds
+}
+
+// Secret is a fetched secret value and how long it may be cached; zero
+// means the cache's default.
+type Secret struct {
+	Value string
+	TTL   time.Duration
+}
+
+// Fetcher fetches one secret from a secret store.
+type Fetcher interface {
+	Fetch(ctx context.Context, ref string) (Secret, error)
+}
+
+// Cache is a config.Resolver that keeps fetched secrets until their TTL
+// expires, so config reloads don't refetch every secret. If a refetch
+// fails, the expired value is kept and the error logged.
+type Cache struct {
+	fetcher Fetcher
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value   string
+	expires time.Time
+}
+
+// NewCache returns a Cache over fetcher with the given default TTL.
+func NewCache(fetcher Fetcher, ttl time.Duration) *Cache {
+	return &Cache{fetcher: fetcher, ttl: ttl, now: time.Now, entries: make(map[string]cacheEntry)}
+}
+
+// Resolve implements config.Resolver.
+func (c *Cache) Resolve(ctx context.Context, ref string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, cached := c.entries[ref]
+	if cached && c.now().Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	secret, err := c.fetcher.Fetch(ctx, ref)
+	if err != nil {
+		if cached {
+			slog.Warn("failed to refresh secret; keeping the cached value", "ref", ref, "error", err)
+			return entry.value, nil
+		}
+		return "", err
+	}
+	ttl := secret.TTL
+	if ttl <= 0 {
+		ttl = c.ttl
+	}
+	c.entries[ref] = cacheEntry{value: secret.Value, expires: c.now().Add(ttl)}
+	return secret.Value, nil
+}
+
+// lazyFetcher builds its Fetcher on first use, after .env files have been
+// loaded into the environment.
+type lazyFetcher struct {
+	build   func() (Fetcher, error)
+	once    sync.Once
+	fetcher Fetcher
+	err     error
+}
+
+func lazy(build func() (Fetcher, error)) *lazyFetcher {
+	return &lazyFetcher{build: build}
+}
+
+func (l *lazyFetcher) Fetch(ctx context.Context, ref string) (Secret, error) {
+	l.once.Do(func() { l.fetcher, l.err = l.build() })
+	if l.err != nil {
+		return Secret{}, l.err
+	}
+	return l.fetcher.Fetch(ctx, ref)
+}
+
+// splitKey splits "name#key" into the secret's name and the JSON key to
+// extract from it, if any.
+func splitKey(ref string) (name, key string) {
+	name, key, _ = strings.Cut(ref, "#")
+	return name, key
+}
+
+// jsonField returns the string field key of a JSON object secret, or the
+// whole secret when key is empty.
+func jsonField(secret, key string) (string, error) {
+	if key == "" {
+		return secret, nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, so has no key %q", key)
+	}
+	value, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("secret has no string key %q", key)
+	}
+	return value, nil
+}
@@ -0,0 +1,111 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+type mockFetcher struct {
+	secret Secret
+	err    error
+	calls  int
+}
+
+func (m *mockFetcher) Fetch(ctx context.Context, ref string) (Secret, error) {
+	m.calls++
+	return m.secret, m.err
+}
+
+func TestCache(t *testing.T) {
+	now := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	fetcher := &mockFetcher{secret: Secret{Value: "v1"}}
+	cache := NewCache(fetcher, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	resolve := func() string {
+		t.Helper()
+		value, err := cache.Resolve(context.Background(), "secret/familiar#token")
+		if err != nil {
+			t.Fatalf("Resolve() error = %v", err)
+		}
+		return value
+	}
+
+	if got := resolve(); got != "v1" || fetcher.calls != 1 {
+		t.Fatalf("Resolve() = %q after %d fetches, want v1 after 1", got, fetcher.calls)
+	}
+	fetcher.secret = Secret{Value: "v2", TTL: time.Hour}
+	if got := resolve(); got != "v1" || fetcher.calls != 1 {
+		t.Errorf("Resolve() = %q after %d fetches, want the cached v1", got, fetcher.calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if got := resolve(); got != "v2" || fetcher.calls != 2 {
+		t.Errorf("Resolve() = %q after %d fetches, want v2 once the default TTL expires", got, fetcher.calls)
+	}
+
+	// The secret's own TTL applies, and a failed refresh keeps the old value
+	now = now.Add(30 * time.Minute)
+	resolve()
+	if fetcher.calls != 2 {
+		t.Errorf("fetches = %d within the secret's TTL, want 2", fetcher.calls)
+	}
+	now = now.Add(time.Hour)
+	fetcher.err = errors.New("vault sealed")
+	if got := resolve(); got != "v2" {
+		t.Errorf("Resolve() = %q when refresh fails, want the stale v2", got)
+	}
+
+	if _, err := cache.Resolve(context.Background(), "secret/other#token"); err == nil {
+		t.Error("Resolve() of an uncached secret should fail when the fetch does")
+	}
+}
+
+func TestJSONField(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{name: "whole secret", secret: "plain", want: "plain"},
+		{name: "field", secret: `{"token":"abc","user":"bot"}`, key: "token", want: "abc"},
+		{name: "missing field", secret: `{"user":"bot"}`, key: "token", wantErr: true},
+		{name: "not JSON", secret: "plain", key: "token", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jsonField(tt.secret, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("jsonField() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("jsonField() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "vault-token")
+	if err := os.WriteFile(tokenFile, []byte("s.from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VAULT_TOKEN", "s.vault")
+	t.Setenv("VAULT_TOKEN_FILE", tokenFile)
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "aws-secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "ya29.token")
+
+	got := Credentials()
+	want := []string{"s.vault", "aws-secret", "ya29.token", "s.from-file"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Credentials() = %v, want %v", got, want)
+	}
+}
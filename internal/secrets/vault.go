@@ -0,0 +1,169 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Vault fetches secrets from HashiCorp Vault's KV engine. References are
+// "<mount>/<path>#<key>", e.g. "secret/familiar#gitlab_token"; both KV
+// version 1 and 2 mounts work.
+type Vault struct {
+	addr      string
+	token     string // fixed token, or
+	tokenFile string // file re-read on each request, as written by Vault Agent
+	namespace string
+	client    *http.Client
+
+	renewOnce sync.Once
+}
+
+// VaultFromEnv configures Vault from VAULT_ADDR, VAULT_TOKEN or
+// VAULT_TOKEN_FILE, and VAULT_NAMESPACE. A fixed VAULT_TOKEN is renewed in
+// the background before it expires.
+func VaultFromEnv() (*Vault, error) {
+	v := &Vault{
+		addr:      strings.TrimRight(os.Getenv("VAULT_ADDR"), "/"),
+		token:     os.Getenv("VAULT_TOKEN"),
+		tokenFile: os.Getenv("VAULT_TOKEN_FILE"),
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+	if v.addr == "" {
+		return nil, errors.New("vault references need VAULT_ADDR")
+	}
+	if v.token == "" && v.tokenFile == "" {
+		return nil, errors.New("vault references need VAULT_TOKEN or VAULT_TOKEN_FILE")
+	}
+	return v, nil
+}
+
+// Fetch implements Fetcher. The secret's lease duration, if any, becomes
+// its TTL.
+func (v *Vault) Fetch(ctx context.Context, ref string) (Secret, error) {
+	if v.token != "" {
+		v.renewOnce.Do(func() { go v.renewLoop(context.Background()) })
+	}
+
+	path, key := splitKey(ref)
+	if key == "" {
+		return Secret{}, fmt.Errorf("vault reference %q needs a #key", ref)
+	}
+	mount, rest, _ := strings.Cut(path, "/")
+
+	// Try KV version 2 first, then version 1
+	var resp vaultSecret
+	err := v.call(ctx, http.MethodGet, "/v1/"+mount+"/data/"+rest, &resp)
+	if errors.Is(err, errVaultNotFound) {
+		err = v.call(ctx, http.MethodGet, "/v1/"+path, &resp)
+	}
+	if err != nil {
+		return Secret{}, err
+	}
+
+	data := resp.Data
+	if nested, ok := data["data"].(map[string]any); ok {
+		data = nested
+	}
+	value, ok := data[key].(string)
+	if !ok {
+		return Secret{}, fmt.Errorf("vault secret %s has no string key %q", path, key)
+	}
+	return Secret{Value: value, TTL: time.Duration(resp.LeaseDuration) * time.Second}, nil
+}
+
+type vaultSecret struct {
+	LeaseDuration int            `json:"lease_duration"`
+	Data          map[string]any `json:"data"`
+}
+
+var errVaultNotFound = errors.New("not found")
+
+// call sends a request to Vault and decodes the JSON response into out.
+func (v *Vault) call(ctx context.Context, method, path string, out any) error {
+	token := v.token
+	if token == "" {
+		data, err := os.ReadFile(v.tokenFile)
+		if err != nil {
+			return fmt.Errorf("reading vault token: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, v.addr+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errVaultNotFound
+	case resp.StatusCode/100 != 2:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("vault %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// renewLoop renews the token at half its remaining lifetime for as long as
+// Vault allows. Non-renewable and non-expiring tokens end the loop.
+func (v *Vault) renewLoop(ctx context.Context) {
+	ttl, err := v.renew(ctx, http.MethodGet, "/v1/auth/token/lookup-self")
+	for err == nil && ttl > 0 {
+		select {
+		case <-time.After(ttl / 2):
+		case <-ctx.Done():
+			return
+		}
+		ttl, err = v.renew(ctx, http.MethodPost, "/v1/auth/token/renew-self")
+	}
+	if err != nil {
+		slog.Warn("vault token renewal stopped", "error", err)
+	}
+}
+
+// renew looks up or renews the token, returning how long it stays valid,
+// or zero if it can't be renewed or doesn't expire.
+func (v *Vault) renew(ctx context.Context, method, path string) (time.Duration, error) {
+	var resp struct {
+		Data struct { // lookup-self
+			TTL       int  `json:"ttl"`
+			Renewable bool `json:"renewable"`
+		} `json:"data"`
+		Auth struct { // renew-self
+			LeaseDuration int  `json:"lease_duration"`
+			Renewable     bool `json:"renewable"`
+		} `json:"auth"`
+	}
+	if err := v.call(ctx, method, path, &resp); err != nil {
+		return 0, err
+	}
+	if method == http.MethodGet {
+		if !resp.Data.Renewable {
+			return 0, nil
+		}
+		return time.Duration(resp.Data.TTL) * time.Second, nil
+	}
+	if !resp.Auth.Renewable {
+		return 0, nil
+	}
+	return time.Duration(resp.Auth.LeaseDuration) * time.Second, nil
+}
@@ -0,0 +1,107 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVault_Fetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			t.Errorf("X-Vault-Token = %q, want s.token", r.Header.Get("X-Vault-Token"))
+		}
+		if r.Header.Get("X-Vault-Namespace") != "team" {
+			t.Errorf("X-Vault-Namespace = %q, want team", r.Header.Get("X-Vault-Namespace"))
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/familiar": // KV v2
+			w.Write([]byte(`{"data":{"data":{"gitlab_token":"glpat-v2"},"metadata":{}}}`))
+		case "/v1/kv1/familiar": // KV v1, with a lease
+			w.Write([]byte(`{"lease_duration":600,"data":{"gitlab_token":"glpat-v1"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("s.token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	v := &Vault{addr: srv.URL, tokenFile: tokenFile, namespace: "team", client: srv.Client()}
+
+	tests := []struct {
+		ref     string
+		want    Secret
+		wantErr string
+	}{
+		{ref: "secret/familiar#gitlab_token", want: Secret{Value: "glpat-v2"}},
+		{ref: "kv1/familiar#gitlab_token", want: Secret{Value: "glpat-v1", TTL: 10 * time.Minute}},
+		{ref: "secret/familiar#github_token", wantErr: `no string key "github_token"`},
+		{ref: "secret/familiar", wantErr: "needs a #key"},
+		{ref: "secret/missing#token", wantErr: "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := v.Fetch(context.Background(), tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Fetch() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Fetch() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVault_Renew(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/token/lookup-self":
+			w.Write([]byte(`{"data":{"ttl":3600,"renewable":true}}`))
+		case "/v1/auth/token/renew-self":
+			if r.Method != http.MethodPost {
+				t.Errorf("renew-self method = %s, want POST", r.Method)
+			}
+			w.Write([]byte(`{"auth":{"lease_duration":7200,"renewable":true}}`))
+		}
+	}))
+	defer srv.Close()
+	v := &Vault{addr: srv.URL, token: "s.token", client: srv.Client()}
+
+	if ttl, err := v.renew(context.Background(), http.MethodGet, "/v1/auth/token/lookup-self"); err != nil || ttl != time.Hour {
+		t.Errorf("lookup-self = %v, %v; want 1h", ttl, err)
+	}
+	if ttl, err := v.renew(context.Background(), http.MethodPost, "/v1/auth/token/renew-self"); err != nil || ttl != 2*time.Hour {
+		t.Errorf("renew-self = %v, %v; want 2h", ttl, err)
+	}
+}
+
+func TestVaultFromEnv(t *testing.T) {
+	t.Setenv("VAULT_ADDR", "")
+	t.Setenv("VAULT_TOKEN", "s.token")
+	if _, err := VaultFromEnv(); err == nil {
+		t.Error("VaultFromEnv() without VAULT_ADDR should fail")
+	}
+
+	t.Setenv("VAULT_ADDR", "https://vault.example.com/")
+	v, err := VaultFromEnv()
+	if err != nil {
+		t.Fatalf("VaultFromEnv() error = %v", err)
+	}
+	if v.addr != "https://vault.example.com" {
+		t.Errorf("addr = %q, want the trailing slash trimmed", v.addr)
+	}
+}
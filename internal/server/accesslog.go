@@ -0,0 +1,71 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/webhook"
+)
+
+// deliveryHeaders carry the provider's ID for a webhook delivery, shown in
+// its delivery log.
+var deliveryHeaders = []string{"X-Gitlab-Event-UUID", "X-GitHub-Delivery"}
+
+// accessLog logs each request's method, path, status, duration, remote
+// address, and webhook delivery and correlation IDs, except for paths in
+// exclude.
+func accessLog(next http.Handler, exclude []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(exclude, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration", time.Since(start).Round(time.Millisecond),
+			"remote", r.RemoteAddr,
+		}
+		for _, h := range deliveryHeaders {
+			if id := r.Header.Get(h); id != "" {
+				attrs = append(attrs, "delivery_id", id)
+				break
+			}
+		}
+		if id := w.Header().Get(webhook.CorrelationHeader); id != "" {
+			attrs = append(attrs, "correlation_id", id)
+		}
+		slog.Info("http request", attrs...)
+	})
+}
+
+// statusRecorder captures the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Flush keeps streaming responses, such as agent logs, streaming.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/webhook"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer slog.SetDefault(prev)
+
+	h := accessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(webhook.CorrelationHeader, "corr-1")
+		w.WriteHeader(http.StatusUnauthorized)
+	}), []string{"/health"})
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   []string
+	}{
+		{
+			name:   "gitlab delivery",
+			path:   "/webhook/gitlab",
+			header: "X-Gitlab-Event-UUID",
+			want:   []string{"method=POST", "path=/webhook/gitlab", "status=401", "delivery_id=abc-123", "correlation_id=corr-1", "remote=", "duration="},
+		},
+		{
+			name:   "github delivery",
+			path:   "/webhook/github",
+			header: "X-GitHub-Delivery",
+			want:   []string{"path=/webhook/github", "delivery_id=abc-123"},
+		},
+		{name: "excluded", path: "/health"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, "abc-123")
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			out := buf.String()
+			if tt.want == nil && out != "" {
+				t.Errorf("excluded path logged: %s", out)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("log missing %q: %s", want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_AccessLogDisabled(t *testing.T) {
+	srv := New(&config.Config{})
+	if _, ok := srv.Handler().(*http.ServeMux); !ok {
+		t.Errorf("Handler() = %T, want the bare mux when access logging is off", srv.Handler())
+	}
+}
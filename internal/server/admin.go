@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/drewdunne/familiar/internal/agent"
+)
+
+// ConcurrencyController reads and adjusts agent concurrency limits at runtime.
+type ConcurrencyController interface {
+	Limits() (maxAgents, queueSize int)
+	SetLimits(maxAgents, queueSize int) error
+	ActiveCount() int
+	QueueLength() int
+}
+
+// AgentStatsReporter reports the latest resource sample of running agents.
+type AgentStatsReporter interface {
+	ResourceStats() []agent.ResourceStats
+}
+
+// ConcurrencyResponse represents the admin concurrency endpoint payload.
+type ConcurrencyResponse struct {
+	MaxAgents int `json:"max_agents"`
+	QueueSize int `json:"queue_size"`
+	Active    int `json:"active"`
+	Queued    int `json:"queued"`
+}
+
+// concurrencyUpdate is the request body for changing limits.
+// Omitted fields keep their current value.
+type concurrencyUpdate struct {
+	MaxAgents *int `json:"max_agents"`
+	QueueSize *int `json:"queue_size"`
+}
+
+// requireAdmin rejects requests without the configured admin token as a
+// bearer token, unless they present a verified client certificate.
+// Allowed cross-origin callers get CORS headers.
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return s.allowCORS(s.requireAuth(s.cfg.Server.AdminToken, false, next))
+}
+
+// requireAdminPage is requireAdmin for the read-only pages browsers open,
+// which also take the token as the password of HTTP basic auth so browsers
+// can prompt for it. Browsers resend cached basic credentials on cross-site
+// form posts, so the rest of the admin API accepts bearer tokens only.
+func (s *Server) requireAdminPage(next http.HandlerFunc) http.HandlerFunc {
+	return s.allowCORS(s.requireAuth(s.cfg.Server.AdminToken, true, next))
+}
+
+// handleConcurrency reports (GET) or changes (PUT) agent concurrency limits.
+func (s *Server) handleConcurrency(w http.ResponseWriter, r *http.Request) {
+	if s.concurrency == nil {
+		http.Error(w, "concurrency control not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var update concurrencyUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+
+		maxAgents, queueSize := s.concurrency.Limits()
+		if update.MaxAgents != nil {
+			maxAgents = *update.MaxAgents
+		}
+		if update.QueueSize != nil {
+			queueSize = *update.QueueSize
+		}
+		if err := s.concurrency.SetLimits(maxAgents, queueSize); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		slog.Info("concurrency limits changed via admin API", "max_agents", maxAgents, "queue_size", queueSize)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	maxAgents, queueSize := s.concurrency.Limits()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ConcurrencyResponse{
+		MaxAgents: maxAgents,
+		QueueSize: queueSize,
+		Active:    s.concurrency.ActiveCount(),
+		Queued:    s.concurrency.QueueLength(),
+	})
+}
+
+// handleAgentStats reports CPU and memory usage of running agents.
+func (s *Server) handleAgentStats(w http.ResponseWriter, r *http.Request) {
+	if s.agentStats == nil {
+		http.Error(w, "agent stats not available", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats := s.agentStats.ResourceStats()
+	if stats == nil {
+		stats = []agent.ResourceStats{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
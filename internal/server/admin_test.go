@@ -0,0 +1,252 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drewdunne/familiar/internal/agent"
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/docker"
+)
+
+type mockConcurrency struct {
+	maxAgents int
+	queueSize int
+	active    int
+	queued    int
+}
+
+func (m *mockConcurrency) Limits() (int, int) { return m.maxAgents, m.queueSize }
+
+func (m *mockConcurrency) SetLimits(maxAgents, queueSize int) error {
+	if maxAgents < 1 || queueSize < 1 {
+		return errors.New("limits must be at least 1")
+	}
+	m.maxAgents, m.queueSize = maxAgents, queueSize
+	return nil
+}
+
+func (m *mockConcurrency) ActiveCount() int { return m.active }
+func (m *mockConcurrency) QueueLength() int { return m.queued }
+
+type mockAgentStats struct {
+	stats []agent.ResourceStats
+}
+
+func (m *mockAgentStats) ResourceStats() []agent.ResourceStats { return m.stats }
+
+func adminConfig() *config.Config {
+	return &config.Config{
+		Server: config.ServerConfig{
+			Host:       "127.0.0.1",
+			Port:       8080,
+			AdminToken: "admin-secret",
+		},
+	}
+}
+
+func adminRequest(method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	return req
+}
+
+func TestAdmin_DisabledWithoutToken(t *testing.T) {
+	cfg := adminConfig()
+	cfg.Server.AdminToken = ""
+	srv := NewWithRouter(cfg, nil, WithConcurrency(&mockConcurrency{}))
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, adminRequest(http.MethodGet, "/admin/concurrency", ""))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /admin/concurrency status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestAdmin_RequiresToken(t *testing.T) {
+	srv := NewWithRouter(adminConfig(), nil, WithConcurrency(&mockConcurrency{}))
+
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"missing", ""},
+		{"wrong token", "Bearer nope"},
+		{"not bearer", "admin-secret"},
+		{"wrong basic password", "Basic YWRtaW46bm9wZQ=="}, // admin:nope
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/concurrency", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestAdmin_BasicAuthOnlyForPages(t *testing.T) {
+	srv := NewWithRouter(adminConfig(), nil, WithConcurrency(&mockConcurrency{maxAgents: 1, queueSize: 1}))
+
+	tests := []struct {
+		method     string
+		path       string
+		authorized bool
+	}{
+		{http.MethodGet, "/admin/status", true},
+		{http.MethodGet, "/admin/logs", true},
+		{http.MethodPost, "/admin/status", false},
+		{http.MethodGet, "/admin/concurrency", false},
+		{http.MethodPost, "/admin/cleanup", false},
+		{http.MethodPost, "/admin/reload", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.SetBasicAuth("anyone", "admin-secret")
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, req)
+
+			if authorized := rec.Code != http.StatusUnauthorized; authorized != tt.authorized {
+				t.Errorf("status = %d, want authorized %v", rec.Code, tt.authorized)
+			}
+		})
+	}
+}
+
+func TestAdmin_GetConcurrency(t *testing.T) {
+	ctrl := &mockConcurrency{maxAgents: 5, queueSize: 20, active: 2, queued: 1}
+	srv := NewWithRouter(adminConfig(), nil, WithConcurrency(ctrl))
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, adminRequest(http.MethodGet, "/admin/concurrency", ""))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var resp ConcurrencyResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	want := ConcurrencyResponse{MaxAgents: 5, QueueSize: 20, Active: 2, Queued: 1}
+	if resp != want {
+		t.Errorf("response = %+v, want %+v", resp, want)
+	}
+}
+
+func TestAdmin_PutConcurrency(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantMax    int
+		wantQueue  int
+	}{
+		{"both fields", `{"max_agents": 2, "queue_size": 50}`, http.StatusOK, 2, 50},
+		{"partial update", `{"max_agents": 10}`, http.StatusOK, 10, 20},
+		{"invalid value", `{"max_agents": 0}`, http.StatusBadRequest, 5, 20},
+		{"malformed body", `{`, http.StatusBadRequest, 5, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := &mockConcurrency{maxAgents: 5, queueSize: 20}
+			srv := NewWithRouter(adminConfig(), nil, WithConcurrency(ctrl))
+
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, adminRequest(http.MethodPut, "/admin/concurrency", tt.body))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if ctrl.maxAgents != tt.wantMax || ctrl.queueSize != tt.wantQueue {
+				t.Errorf("limits = (%d, %d), want (%d, %d)", ctrl.maxAgents, ctrl.queueSize, tt.wantMax, tt.wantQueue)
+			}
+		})
+	}
+}
+
+func TestAdmin_ConcurrencyUnavailable(t *testing.T) {
+	srv := NewWithRouter(adminConfig(), nil)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, adminRequest(http.MethodGet, "/admin/concurrency", ""))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestAdmin_ConcurrencyMethodNotAllowed(t *testing.T) {
+	srv := NewWithRouter(adminConfig(), nil, WithConcurrency(&mockConcurrency{}))
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, adminRequest(http.MethodDelete, "/admin/concurrency", ""))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestAdmin_AgentStats(t *testing.T) {
+	tests := []struct {
+		name      string
+		stats     []agent.ResourceStats
+		wantCount int
+	}{
+		{"no agents", nil, 0},
+		{
+			name: "running agents",
+			stats: []agent.ResourceStats{
+				{ID: "agent-1", Repo: "owner/repo", Stats: docker.ContainerStats{CPUPercent: 12.5, MemoryBytes: 1024}, PeakMemoryBytes: 2048},
+				{ID: "agent-2", Repo: "owner/other", Stats: docker.ContainerStats{MemoryBytes: 512}, PeakMemoryBytes: 512},
+			},
+			wantCount: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewWithRouter(adminConfig(), nil, WithAgentStats(&mockAgentStats{stats: tt.stats}))
+
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, adminRequest(http.MethodGet, "/admin/agents/stats", ""))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			var got []agent.ResourceStats
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if len(got) != tt.wantCount {
+				t.Fatalf("got %d entries, want %d", len(got), tt.wantCount)
+			}
+			if tt.wantCount > 0 && (got[0].ID != "agent-1" || got[0].Stats.CPUPercent != 12.5 || got[0].PeakMemoryBytes != 2048) {
+				t.Errorf("first entry = %+v", got[0])
+			}
+		})
+	}
+}
+
+func TestAdmin_AgentStatsUnavailable(t *testing.T) {
+	srv := NewWithRouter(adminConfig(), nil)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, adminRequest(http.MethodGet, "/admin/agents/stats", ""))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/agent"
+)
+
+// AgentLogStreamer streams a running agent's container output.
+type AgentLogStreamer interface {
+	GetSession(sessionID string) (*agent.Session, bool)
+	StreamLogs(ctx context.Context, sessionID string, follow bool, w io.Writer) error
+}
+
+// WithAgentLogs exposes running agents' live output via the admin API.
+func WithAgentLogs(logs AgentLogStreamer) Option {
+	return func(s *Server) {
+		s.agentLogs = logs
+	}
+}
+
+// handleAgentLogs streams an agent's container output. With ?follow=true it
+// keeps streaming until the agent stops or the client disconnects. Clients
+// that accept text/event-stream get one SSE event per line, ending with an
+// "end" event; others get chunked plain text.
+func (s *Server) handleAgentLogs(w http.ResponseWriter, r *http.Request) {
+	if s.agentLogs == nil {
+		http.Error(w, "agent logs not available", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	follow := false
+	if v := r.URL.Query().Get("follow"); v != "" {
+		var err error
+		if follow, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "invalid follow flag", http.StatusBadRequest)
+			return
+		}
+	}
+
+	id := r.PathValue("id")
+	if _, ok := s.agentLogs.GetSession(id); !ok {
+		http.Error(w, "agent not found", http.StatusNotFound)
+		return
+	}
+
+	// A followed stream lasts as long as the agent, so lift the write timeout
+	if follow {
+		http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	}
+
+	sse := strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+	if sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	out := &logStreamWriter{w: w, sse: sse}
+	out.flush()
+	err := s.agentLogs.StreamLogs(r.Context(), id, follow, out)
+	if err != nil && r.Context().Err() == nil {
+		slog.Warn("agent log stream failed", "agent_id", id, "error", err)
+	}
+	out.close(err)
+}
+
+// logStreamWriter forwards log output to an HTTP response, flushing after
+// every write so clients see output as it happens.
+type logStreamWriter struct {
+	w       http.ResponseWriter
+	sse     bool
+	partial []byte // SSE only: output after the last newline
+}
+
+func (l *logStreamWriter) Write(p []byte) (int, error) {
+	if !l.sse {
+		n, err := l.w.Write(p)
+		l.flush()
+		return n, err
+	}
+
+	l.partial = append(l.partial, p...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			break
+		}
+		if err := l.event("", l.partial[:i]); err != nil {
+			return 0, err
+		}
+		l.partial = l.partial[i+1:]
+	}
+	l.flush()
+	return len(p), nil
+}
+
+// close ends the stream, reporting err to SSE clients.
+func (l *logStreamWriter) close(err error) {
+	if !l.sse {
+		return
+	}
+	if len(l.partial) > 0 {
+		l.event("", l.partial)
+		l.partial = nil
+	}
+	if err != nil {
+		l.event("error", []byte(err.Error()))
+	}
+	l.event("end", nil)
+	l.flush()
+}
+
+// event writes one SSE event. Carriage returns would end the data line
+// early, so they are dropped.
+func (l *logStreamWriter) event(name string, data []byte) error {
+	if name != "" {
+		if _, err := fmt.Fprintf(l.w, "event: %s\n", name); err != nil {
+			return err
+		}
+	}
+	data = bytes.ReplaceAll(data, []byte("\r"), nil)
+	_, err := fmt.Fprintf(l.w, "data: %s\n\n", data)
+	return err
+}
+
+func (l *logStreamWriter) flush() {
+	if f, ok := l.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
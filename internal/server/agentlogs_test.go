@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drewdunne/familiar/internal/agent"
+)
+
+type mockAgentLogs struct {
+	sessions map[string]bool
+	chunks   []string
+	err      error
+	follow   bool
+}
+
+func (m *mockAgentLogs) GetSession(id string) (*agent.Session, bool) {
+	if !m.sessions[id] {
+		return nil, false
+	}
+	return &agent.Session{ID: id}, true
+}
+
+func (m *mockAgentLogs) StreamLogs(_ context.Context, _ string, follow bool, w io.Writer) error {
+	m.follow = follow
+	for _, chunk := range m.chunks {
+		if _, err := io.WriteString(w, chunk); err != nil {
+			return err
+		}
+	}
+	return m.err
+}
+
+func TestAgentLogs_Stream(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		accept     string
+		logs       *mockAgentLogs
+		wantStatus int
+		wantType   string
+		wantBody   string
+		wantFollow bool
+	}{
+		{
+			name:       "plain text",
+			path:       "/agents/agent-1/logs",
+			logs:       &mockAgentLogs{sessions: map[string]bool{"agent-1": true}, chunks: []string{"line 1\nline", " 2\n"}},
+			wantStatus: http.StatusOK,
+			wantType:   "text/plain; charset=utf-8",
+			wantBody:   "line 1\nline 2\n",
+		},
+		{
+			name:       "follow flag",
+			path:       "/agents/agent-1/logs?follow=true",
+			logs:       &mockAgentLogs{sessions: map[string]bool{"agent-1": true}},
+			wantStatus: http.StatusOK,
+			wantType:   "text/plain; charset=utf-8",
+			wantFollow: true,
+		},
+		{
+			name:       "server-sent events",
+			path:       "/agents/agent-1/logs",
+			accept:     "text/event-stream",
+			logs:       &mockAgentLogs{sessions: map[string]bool{"agent-1": true}, chunks: []string{"line 1\r\nline", " 2\ntail"}},
+			wantStatus: http.StatusOK,
+			wantType:   "text/event-stream",
+			wantBody:   "data: line 1\n\ndata: line 2\n\ndata: tail\n\nevent: end\ndata: \n\n",
+		},
+		{
+			name:       "server-sent events with error",
+			path:       "/agents/agent-1/logs",
+			accept:     "text/event-stream",
+			logs:       &mockAgentLogs{sessions: map[string]bool{"agent-1": true}, err: errors.New("container gone")},
+			wantStatus: http.StatusOK,
+			wantType:   "text/event-stream",
+			wantBody:   "event: error\ndata: container gone\n\nevent: end\ndata: \n\n",
+		},
+		{
+			name:       "unknown agent",
+			path:       "/agents/nope/logs",
+			logs:       &mockAgentLogs{},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "invalid follow flag",
+			path:       "/agents/agent-1/logs?follow=maybe",
+			logs:       &mockAgentLogs{sessions: map[string]bool{"agent-1": true}},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewWithRouter(adminConfig(), nil, WithAgentLogs(tt.logs))
+
+			req := adminRequest(http.MethodGet, tt.path, "")
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+			if got := rec.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+			if tt.logs.follow != tt.wantFollow {
+				t.Errorf("follow = %v, want %v", tt.logs.follow, tt.wantFollow)
+			}
+		})
+	}
+}
+
+func TestAgentLogs_RequiresToken(t *testing.T) {
+	logs := &mockAgentLogs{sessions: map[string]bool{"agent-1": true}}
+	srv := NewWithRouter(adminConfig(), nil, WithAgentLogs(logs))
+
+	req := httptest.NewRequest(http.MethodGet, "/agents/agent-1/logs", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
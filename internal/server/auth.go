@@ -0,0 +1,47 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// requireAuth rejects requests that present neither token as a bearer
+// token nor a client certificate verified against server.tls.client_ca_file,
+// unless they arrived on the admin socket.
+// With allowBasic, the token is also accepted as the password of HTTP basic
+// auth on GET and HEAD requests so browsers can prompt for it. An empty token
+// accepts certificates only.
+func (s *Server) requireAuth(token string, allowBasic bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		basic := allowBasic && (r.Method == http.MethodGet || r.Method == http.MethodHead)
+		if fromAdminSocket(r) || verifiedClientCert(r) || tokenMatches(r, token, basic) {
+			next(w, r)
+			return
+		}
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Familiar admin"`)
+		} else {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="Familiar"`)
+		}
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}
+}
+
+// verifiedClientCert reports whether the request came with a client
+// certificate the TLS handshake verified.
+func verifiedClientCert(r *http.Request) bool {
+	return r.TLS != nil && len(r.TLS.VerifiedChains) > 0
+}
+
+// tokenMatches reports whether the request carries token.
+func tokenMatches(r *http.Request, token string, allowBasic bool) bool {
+	if token == "" {
+		return false
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok && allowBasic {
+		_, got, ok = r.BasicAuth()
+	}
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drewdunne/familiar/internal/config"
+)
+
+func TestRequireAuth(t *testing.T) {
+	srv := New(&config.Config{})
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+
+	tests := []struct {
+		name       string
+		method     string
+		token      string
+		allowBasic bool
+		header     string
+		basicPass  string
+		tls        *tls.ConnectionState
+		want       int
+	}{
+		{name: "bearer", token: "secret", header: "Bearer secret", want: http.StatusOK},
+		{name: "wrong bearer", token: "secret", header: "Bearer nope", want: http.StatusUnauthorized},
+		{name: "basic allowed", token: "secret", allowBasic: true, basicPass: "secret", want: http.StatusOK},
+		{name: "basic not allowed", token: "secret", basicPass: "secret", want: http.StatusUnauthorized},
+		{name: "basic on post", method: http.MethodPost, token: "secret", allowBasic: true, basicPass: "secret", want: http.StatusUnauthorized},
+		{name: "bearer on post", method: http.MethodPost, token: "secret", allowBasic: true, header: "Bearer secret", want: http.StatusOK},
+		{name: "client cert", token: "secret", tls: verified, want: http.StatusOK},
+		{name: "unverified tls", token: "secret", tls: &tls.ConnectionState{}, want: http.StatusUnauthorized},
+		{name: "cert only rejects empty bearer", header: "Bearer ", want: http.StatusUnauthorized},
+		{name: "cert only accepts cert", tls: verified, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/admin/queue", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.basicPass != "" {
+				req.SetBasicAuth("admin", tt.basicPass)
+			}
+			req.TLS = tt.tls
+
+			rec := httptest.NewRecorder()
+			srv.requireAuth(tt.token, tt.allowBasic, ok)(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("missing WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestAdmin_EnabledByClientCA(t *testing.T) {
+	cfg := &config.Config{Server: config.ServerConfig{TLS: config.TLSConfig{ClientCAFile: "ca.pem"}}}
+	srv := NewWithRouter(cfg, nil, WithQueue(&mockQueueControl{}))
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/queue", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("without a certificate status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("with a verified certificate status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,368 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/agent"
+	"github.com/drewdunne/familiar/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// SessionController lists and stops running agent sessions.
+type SessionController interface {
+	ActiveSessions() []agent.Session
+	Terminate(ctx context.Context, sessionID, reason string) error
+}
+
+// QueueController inspects and empties the spawn queue.
+type QueueController interface {
+	Queued() []agent.QueuedRequest
+	Drain() []agent.SpawnRequest
+}
+
+// Cleaner runs log and worktree cleanup on demand.
+type Cleaner interface {
+	Cleanup(ctx context.Context) (CleanupResult, error)
+}
+
+// Reloader re-reads and applies the config file.
+type Reloader interface {
+	Reload(ctx context.Context) error
+}
+
+// ConfigSource returns the config in effect, which changes on reload.
+type ConfigSource interface {
+	Current() *config.Config
+}
+
+// CleanupResult reports what an on-demand cleanup removed.
+type CleanupResult struct {
+	LogsDeleted     int `json:"logs_deleted"`
+	WorktreesPruned int `json:"worktrees_pruned"`
+}
+
+// WithSessions exposes running agent sessions via the admin API.
+func WithSessions(c SessionController) Option {
+	return func(s *Server) {
+		s.sessions = c
+	}
+}
+
+// WithQueue exposes the spawn queue via the admin API.
+func WithQueue(q QueueController) Option {
+	return func(s *Server) {
+		s.queue = q
+	}
+}
+
+// WithCleaner lets the admin API trigger cleanup.
+func WithCleaner(c Cleaner) Option {
+	return func(s *Server) {
+		s.cleaner = c
+	}
+}
+
+// WithReloader lets the admin API reload the config file.
+func WithReloader(r Reloader) Option {
+	return func(s *Server) {
+		s.reloader = r
+	}
+}
+
+// WithConfigSource lets the admin API show the config as last reloaded,
+// rather than as the server started with.
+func WithConfigSource(c ConfigSource) Option {
+	return func(s *Server) {
+		s.configSource = c
+	}
+}
+
+// adminStopReason is recorded as the failure reason of sessions stopped via
+// the admin API.
+const adminStopReason = "stopped by an administrator"
+
+// SessionInfo describes a running agent session in the admin API.
+type SessionInfo struct {
+	ID          string    `json:"id"`
+	Repo        string    `json:"repo"`
+	MRNumber    int       `json:"mr"`
+	EventType   string    `json:"event_type"`
+	Persona     string    `json:"persona,omitempty"`
+	Status      string    `json:"status"`
+	Interactive bool      `json:"interactive"`
+	StartedAt   time.Time `json:"started_at"`
+	AgeSeconds  int64     `json:"age_seconds"`
+}
+
+// QueuedInfo describes a queued spawn request in the admin API. Requests
+// start in the order queued, so Position 1 starts next.
+type QueuedInfo struct {
+	ID          string    `json:"id"`
+	Position    int       `json:"position"`
+	Repo        string    `json:"repo"`
+	MRNumber    int       `json:"mr"`
+	EventType   string    `json:"event_type"`
+	Persona     string    `json:"persona,omitempty"`
+	QueuedAt    time.Time `json:"queued_at"`
+	WaitSeconds int64     `json:"wait_seconds"`
+}
+
+// DebouncedInfo describes an event in the admin API whose repeats are
+// dropped until its debounce window ends.
+type DebouncedInfo struct {
+	Provider         string    `json:"provider"`
+	Repo             string    `json:"repo"`
+	MRNumber         int       `json:"mr"`
+	EventType        string    `json:"event_type"`
+	SeenAt           time.Time `json:"seen_at"`
+	Until            time.Time `json:"until"`
+	RemainingSeconds int64     `json:"remaining_seconds"`
+	Suppressed       int       `json:"suppressed"`
+}
+
+// maintenanceState is the admin maintenance endpoint payload.
+type maintenanceState struct {
+	Enabled bool `json:"enabled"`
+}
+
+// handleSessions lists running agent sessions (GET).
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if s.sessions == nil {
+		http.Error(w, "session control not available", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessionInfos(s.sessions, time.Now()))
+}
+
+// sessionInfos describes the running sessions as of now.
+func sessionInfos(c SessionController, now time.Time) []SessionInfo {
+	infos := []SessionInfo{}
+	for _, session := range c.ActiveSessions() {
+		infos = append(infos, SessionInfo{
+			ID:          session.ID,
+			Repo:        session.Repo,
+			MRNumber:    session.MRNumber,
+			EventType:   session.EventType,
+			Persona:     session.Persona,
+			Status:      session.Status,
+			Interactive: session.Interactive,
+			StartedAt:   session.StartedAt,
+			AgeSeconds:  int64(now.Sub(session.StartedAt).Seconds()),
+		})
+	}
+	return infos
+}
+
+// handleSession stops a running agent session (DELETE). Its logs are
+// captured and its worktree removed as for any other terminated agent. A
+// session that has already finished is a conflict.
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	if s.sessions == nil {
+		http.Error(w, "session control not available", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		w.Header().Set("Allow", "DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := s.sessions.Terminate(r.Context(), id, adminStopReason); err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, agent.ErrNotRunning) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	slog.Info("agent stopped via admin API", "agent_id", id)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleQueue lists (GET) or drains (DELETE) the spawn queue.
+func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
+	if s.queue == nil {
+		http.Error(w, "queue control not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(queuedInfos(s.queue, time.Now()))
+	case http.MethodDelete:
+		drained := s.queue.Drain()
+		slog.Info("agent queue drained via admin API", "dropped", len(drained))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"drained": len(drained)})
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// queuedInfos describes the queued spawn requests as of now.
+func queuedInfos(q QueueController, now time.Time) []QueuedInfo {
+	infos := []QueuedInfo{}
+	for i, queued := range q.Queued() {
+		infos = append(infos, QueuedInfo{
+			ID:          queued.Request.ID,
+			Position:    i + 1,
+			Repo:        queued.Request.Repo,
+			MRNumber:    queued.Request.MRNumber,
+			EventType:   queued.Request.EventType,
+			Persona:     queued.Request.Persona,
+			QueuedAt:    queued.QueuedAt,
+			WaitSeconds: int64(now.Sub(queued.QueuedAt).Seconds()),
+		})
+	}
+	return infos
+}
+
+// handleDebounced lists the events whose repeats are being dropped (GET).
+func (s *Server) handleDebounced(w http.ResponseWriter, r *http.Request) {
+	if s.eventRouter == nil {
+		http.Error(w, "event routing not available", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	now := time.Now()
+	infos := []DebouncedInfo{}
+	for _, e := range s.eventRouter.Debounced() {
+		infos = append(infos, DebouncedInfo{
+			Provider:         e.Provider,
+			Repo:             e.Repo,
+			MRNumber:         e.MRNumber,
+			EventType:        string(e.Type),
+			SeenAt:           e.SeenAt,
+			Until:            e.Until,
+			RemainingSeconds: int64(e.Until.Sub(now).Seconds()),
+			Suppressed:       e.Suppressed,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(infos)
+}
+
+// handleMaintenance reports (GET) or toggles (PUT) maintenance mode, in
+// which webhook deliveries are turned away.
+func (s *Server) handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var update maintenanceState
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		s.maintenance.Store(update.Enabled)
+		slog.Info("maintenance mode changed via admin API", "enabled", update.Enabled)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(maintenanceState{Enabled: s.maintenance.Load()})
+}
+
+// handleCleanup runs log and worktree cleanup now (POST).
+func (s *Server) handleCleanup(w http.ResponseWriter, r *http.Request) {
+	if s.cleaner == nil {
+		http.Error(w, "cleanup not available", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, err := s.cleaner.Cleanup(r.Context())
+	if err != nil {
+		slog.Warn("cleanup via admin API failed", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	slog.Info("cleanup run via admin API", "logs_deleted", result.LogsDeleted, "worktrees_pruned", result.WorktreesPruned)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleReload reloads the config file (POST), as SIGHUP does.
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if s.reloader == nil {
+		http.Error(w, "config reload not available", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := s.reloader.Reload(r.Context()); err != nil {
+		slog.Error("config reload via admin API failed", "error", err)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleConfig shows the config in effect as YAML (GET), with secrets
+// masked. Repos' overrides aren't applied.
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cfg := s.cfg
+	if s.configSource != nil {
+		cfg = s.configSource.Current()
+	}
+	effective, err := config.NewEffective(cfg, &config.RepoConfig{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data, err := yaml.Marshal(effective)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(data)
+}
+
+// rejectInMaintenance turns webhook deliveries away with 503 while
+// maintenance mode is on, so providers can redeliver them afterwards.
+func (s *Server) rejectInMaintenance(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.maintenance.Load() {
+			http.Error(w, "familiar is in maintenance mode", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,311 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/agent"
+	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/event"
+	"github.com/drewdunne/familiar/internal/intent"
+)
+
+type mockSessions struct {
+	sessions   []agent.Session
+	terminated map[string]string
+}
+
+func (m *mockSessions) ActiveSessions() []agent.Session { return m.sessions }
+
+func (m *mockSessions) Terminate(ctx context.Context, sessionID, reason string) error {
+	for _, s := range m.sessions {
+		if s.ID == sessionID {
+			if s.Status != "running" {
+				return fmt.Errorf("%w: %s", agent.ErrNotRunning, sessionID)
+			}
+			m.terminated[sessionID] = reason
+			return nil
+		}
+	}
+	return fmt.Errorf("session not found: %s", sessionID)
+}
+
+type mockQueueControl struct {
+	queued []agent.QueuedRequest
+}
+
+func (m *mockQueueControl) Queued() []agent.QueuedRequest { return m.queued }
+
+func (m *mockQueueControl) Drain() []agent.SpawnRequest {
+	drained := make([]agent.SpawnRequest, len(m.queued))
+	for i, q := range m.queued {
+		drained[i] = q.Request
+	}
+	m.queued = nil
+	return drained
+}
+
+type mockCleaner struct {
+	result CleanupResult
+	err    error
+}
+
+func (m *mockCleaner) Cleanup(ctx context.Context) (CleanupResult, error) { return m.result, m.err }
+
+func TestAdmin_Sessions(t *testing.T) {
+	sessions := &mockSessions{
+		sessions: []agent.Session{
+			{ID: "agent-1", Repo: "owner/repo", MRNumber: 7, EventType: "mr_comment", Status: "running", StartedAt: time.Now().Add(-90 * time.Second)},
+			{ID: "agent-2", Repo: "owner/repo", MRNumber: 8, EventType: "mr_comment", Status: "completed", StartedAt: time.Now().Add(-time.Hour)},
+		},
+		terminated: make(map[string]string),
+	}
+	srv := NewWithRouter(adminConfig(), nil, WithSessions(sessions))
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, adminRequest(http.MethodGet, "/admin/sessions", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /admin/sessions status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var infos []SessionInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &infos); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(infos) != 2 || infos[0].ID != "agent-1" || infos[0].Repo != "owner/repo" || infos[0].MRNumber != 7 {
+		t.Fatalf("sessions = %+v, want agent-1 on owner/repo!7", infos)
+	}
+	if infos[0].AgeSeconds < 90 {
+		t.Errorf("AgeSeconds = %d, want at least 90", infos[0].AgeSeconds)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		want int
+	}{
+		{"running session", "agent-1", http.StatusAccepted},
+		{"unknown session", "nope", http.StatusNotFound},
+		{"finished session", "agent-2", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, adminRequest(http.MethodDelete, "/admin/sessions/"+tt.id, ""))
+			if rec.Code != tt.want {
+				t.Errorf("DELETE /admin/sessions/%s status = %d, want %d", tt.id, rec.Code, tt.want)
+			}
+		})
+	}
+	if sessions.terminated["agent-1"] != adminStopReason {
+		t.Errorf("terminated = %v, want agent-1 stopped with %q", sessions.terminated, adminStopReason)
+	}
+}
+
+func TestAdmin_Queue(t *testing.T) {
+	queue := &mockQueueControl{queued: []agent.QueuedRequest{
+		{Request: agent.SpawnRequest{ID: "agent-2", Repo: "owner/repo", MRNumber: 3, Prompt: "secret prompt"}, QueuedAt: time.Now()},
+	}}
+	srv := NewWithRouter(adminConfig(), nil, WithQueue(queue))
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, adminRequest(http.MethodGet, "/admin/queue", ""))
+	var infos []QueuedInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &infos); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(infos) != 1 || infos[0].ID != "agent-2" || infos[0].MRNumber != 3 || infos[0].Position != 1 {
+		t.Errorf("queue = %+v, want agent-2 first", infos)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, adminRequest(http.MethodDelete, "/admin/queue", ""))
+	var resp map[string]int
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if resp["drained"] != 1 {
+		t.Errorf("drained = %d, want 1", resp["drained"])
+	}
+	if len(queue.queued) != 0 {
+		t.Errorf("queue still holds %d requests after drain", len(queue.queued))
+	}
+}
+
+func TestAdmin_Debounced(t *testing.T) {
+	cfg := adminConfig()
+	cfg.Events.MRUpdated = true
+	cfg.Agents.DebounceSeconds = 60
+	router := event.NewRouter(cfg, func(context.Context, *event.Event, *config.MergedConfig, *intent.ParsedIntent) error {
+		return nil
+	}, nil)
+	evt := &event.Event{Type: event.TypeMRUpdated, Provider: "gitlab", RepoOwner: "owner", RepoName: "repo", MRNumber: 7}
+	router.Route(context.Background(), evt)
+	router.Route(context.Background(), evt)
+	srv := NewWithRouter(cfg, router)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, adminRequest(http.MethodGet, "/admin/debounced", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /admin/debounced status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var infos []DebouncedInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &infos); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(infos) != 1 || infos[0].Repo != "owner/repo" || infos[0].MRNumber != 7 || infos[0].Suppressed != 1 {
+		t.Fatalf("debounced = %+v, want owner/repo !7 with 1 suppressed", infos)
+	}
+	if infos[0].RemainingSeconds <= 0 || infos[0].RemainingSeconds > 60 {
+		t.Errorf("remaining_seconds = %d, want within the 60s window", infos[0].RemainingSeconds)
+	}
+}
+
+func TestAdmin_Maintenance(t *testing.T) {
+	cfg := adminConfig()
+	cfg.Providers.GitLab.WebhookSecret = "hook-secret"
+	srv := NewWithRouter(cfg, nil)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, adminRequest(http.MethodPut, "/admin/maintenance", `{"enabled": true}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT /admin/maintenance status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhook/gitlab", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("webhook in maintenance status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, adminRequest(http.MethodPut, "/admin/maintenance", `{"enabled": false}`))
+	var state maintenanceState
+	if err := json.Unmarshal(rec.Body.Bytes(), &state); err != nil || state.Enabled {
+		t.Errorf("maintenance state = %+v (err %v), want disabled", state, err)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhook/gitlab", nil))
+	if rec.Code == http.StatusServiceUnavailable {
+		t.Error("webhook should be accepted again after maintenance ends")
+	}
+}
+
+func TestAdmin_Cleanup(t *testing.T) {
+	tests := []struct {
+		name    string
+		cleaner *mockCleaner
+		method  string
+		want    int
+	}{
+		{"runs", &mockCleaner{result: CleanupResult{LogsDeleted: 4, WorktreesPruned: 1}}, http.MethodPost, http.StatusOK},
+		{"fails", &mockCleaner{err: errors.New("disk gone")}, http.MethodPost, http.StatusInternalServerError},
+		{"wrong method", &mockCleaner{}, http.MethodGet, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewWithRouter(adminConfig(), nil, WithCleaner(tt.cleaner))
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, adminRequest(tt.method, "/admin/cleanup", ""))
+			if rec.Code != tt.want {
+				t.Fatalf("%s /admin/cleanup status = %d, want %d", tt.method, rec.Code, tt.want)
+			}
+			if tt.want == http.StatusOK {
+				var result CleanupResult
+				if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || result != tt.cleaner.result {
+					t.Errorf("result = %+v (err %v), want %+v", result, err, tt.cleaner.result)
+				}
+			}
+		})
+	}
+}
+
+type mockReloader struct {
+	calls int
+	err   error
+}
+
+func (m *mockReloader) Reload(ctx context.Context) error {
+	m.calls++
+	return m.err
+}
+
+func TestAdmin_Reload(t *testing.T) {
+	tests := []struct {
+		name     string
+		reloader *mockReloader
+		method   string
+		want     int
+	}{
+		{"reloads", &mockReloader{}, http.MethodPost, http.StatusNoContent},
+		{"invalid config", &mockReloader{err: errors.New("bad yaml")}, http.MethodPost, http.StatusUnprocessableEntity},
+		{"wrong method", &mockReloader{}, http.MethodGet, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewWithRouter(adminConfig(), nil, WithReloader(tt.reloader))
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, adminRequest(tt.method, "/admin/reload", ""))
+			if rec.Code != tt.want {
+				t.Errorf("%s /admin/reload status = %d, want %d", tt.method, rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdmin_ControlUnavailable(t *testing.T) {
+	srv := NewWithRouter(&config.Config{Server: config.ServerConfig{AdminToken: "admin-secret"}}, nil)
+	for _, path := range []string{"/admin/sessions", "/admin/queue", "/admin/debounced"} {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, adminRequest(http.MethodGet, path, ""))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("GET %s status = %d, want %d", path, rec.Code, http.StatusServiceUnavailable)
+		}
+	}
+}
+
+type mockConfigSource struct{ cfg *config.Config }
+
+func (m mockConfigSource) Current() *config.Config { return m.cfg }
+
+func TestAdmin_Config(t *testing.T) {
+	reloaded := adminConfig()
+	reloaded.BotUsername = "reloaded-bot"
+	reloaded.Providers.GitLab.Token = "glpat-secret"
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		method  string
+		want    int
+		wantBot string
+	}{
+		{"startup config", nil, http.MethodGet, http.StatusOK, "bot_username: \"\""},
+		{"reloaded config", []Option{WithConfigSource(mockConfigSource{reloaded})}, http.MethodGet, http.StatusOK, "bot_username: reloaded-bot"},
+		{"wrong method", nil, http.MethodPost, http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewWithRouter(adminConfig(), nil, tt.opts...)
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, adminRequest(tt.method, "/admin/config", ""))
+			if rec.Code != tt.want {
+				t.Fatalf("%s /admin/config status = %d, want %d", tt.method, rec.Code, tt.want)
+			}
+			body := rec.Body.String()
+			if !strings.Contains(body, tt.wantBot) {
+				t.Errorf("body = %s, want it to contain %q", body, tt.wantBot)
+			}
+			if strings.Contains(body, "admin-secret") || strings.Contains(body, "glpat-secret") {
+				t.Errorf("body = %s, want secrets masked", body)
+			}
+		})
+	}
+}
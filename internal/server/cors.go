@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// corsAllowedHeaders are the request headers the admin API reads.
+const corsAllowedHeaders = "Authorization, Content-Type"
+
+// corsMaxAge is how long, in seconds, browsers may cache a preflight.
+const corsMaxAge = "600"
+
+// allowCORS adds CORS headers for origins in server.cors.allowed_origins
+// and answers their preflight requests, which carry no credentials and so
+// must not reach authentication. Other requests pass through unchanged.
+func (s *Server) allowCORS(next http.HandlerFunc) http.HandlerFunc {
+	cors := s.cfg.Server.CORS
+	if len(cors.AllowedOrigins) == 0 {
+		return next
+	}
+	anyOrigin := slices.Contains(cors.AllowedOrigins, "*")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !anyOrigin && !slices.Contains(cors.AllowedOrigins, origin) {
+			next(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		if anyOrigin {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", strings.Join(cors.AllowedMethods, ", "))
+			h.Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			h.Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	const dashboard = "https://dash.example.com"
+
+	preflight := func(origin string) *http.Request {
+		req := httptest.NewRequest(http.MethodOptions, "/admin/sessions", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", "DELETE")
+		return req
+	}
+	withOrigin := func(req *http.Request, origin string) *http.Request {
+		req.Header.Set("Origin", origin)
+		return req
+	}
+
+	tests := []struct {
+		name            string
+		origins         []string
+		req             *http.Request
+		wantStatus      int
+		wantAllowOrigin string
+		wantCredentials bool
+		wantMethods     string
+	}{
+		{
+			name:            "preflight from allowed origin skips auth",
+			origins:         []string{dashboard},
+			req:             preflight(dashboard),
+			wantStatus:      http.StatusNoContent,
+			wantAllowOrigin: dashboard,
+			wantCredentials: true,
+			wantMethods:     "GET, POST, PUT, DELETE",
+		},
+		{
+			name:       "preflight from other origin",
+			origins:    []string{dashboard},
+			req:        preflight("https://evil.example.com"),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:            "authorized request from allowed origin",
+			origins:         []string{dashboard},
+			req:             withOrigin(adminRequest(http.MethodGet, "/admin/maintenance", ""), dashboard),
+			wantStatus:      http.StatusOK,
+			wantAllowOrigin: dashboard,
+			wantCredentials: true,
+		},
+		{
+			name:            "unauthorized request from allowed origin",
+			origins:         []string{dashboard},
+			req:             withOrigin(httptest.NewRequest(http.MethodGet, "/admin/maintenance", nil), dashboard),
+			wantStatus:      http.StatusUnauthorized,
+			wantAllowOrigin: dashboard,
+			wantCredentials: true,
+		},
+		{
+			name:            "any origin without credentials",
+			origins:         []string{"*"},
+			req:             withOrigin(adminRequest(http.MethodGet, "/admin/maintenance", ""), dashboard),
+			wantStatus:      http.StatusOK,
+			wantAllowOrigin: "*",
+		},
+		{
+			name:            "status endpoints",
+			origins:         []string{dashboard},
+			req:             withOrigin(httptest.NewRequest(http.MethodGet, "/metrics", nil), dashboard),
+			wantStatus:      http.StatusOK,
+			wantAllowOrigin: dashboard,
+			wantCredentials: true,
+		},
+		{
+			name:       "disabled",
+			req:        preflight(dashboard),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := adminConfig()
+			cfg.Server.CORS.AllowedOrigins = tt.origins
+			cfg.Server.CORS.AllowedMethods = []string{"GET", "POST", "PUT", "DELETE"}
+			srv := NewWithRouter(cfg, nil)
+
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, tt.req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			h := rec.Header()
+			if got := h.Get("Access-Control-Allow-Origin"); got != tt.wantAllowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantAllowOrigin)
+			}
+			if got := h.Get("Access-Control-Allow-Credentials") == "true"; got != tt.wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %v, want %v", got, tt.wantCredentials)
+			}
+			if got := h.Get("Access-Control-Allow-Methods"); got != tt.wantMethods {
+				t.Errorf("Access-Control-Allow-Methods = %q, want %q", got, tt.wantMethods)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/docker"
+)
+
+// DependencyCheck probes a dependency for /health, returning why it is
+// unusable.
+type DependencyCheck func(ctx context.Context) error
+
+// DependencyHealth is a dependency check's result in /health.
+type DependencyHealth struct {
+	OK        bool      `json:"ok"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// healthCheckTimeout bounds each dependency check.
+const healthCheckTimeout = 5 * time.Second
+
+// healthCacheTTL is how long dependency results are reused, so frequent
+// polling doesn't spend provider API rate limits.
+const healthCacheTTL = 30 * time.Second
+
+// WithHealthCheck adds a dependency check reported in /health under name.
+// A failing check degrades the status. Replaces the built-in docker check
+// when name is "docker".
+func WithHealthCheck(name string, check DependencyCheck) Option {
+	return func(s *Server) {
+		s.healthChecks[name] = check
+	}
+}
+
+// BuildInfo identifies the running build in /health.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// WithBuildInfo reports the running build in /health.
+func WithBuildInfo(info BuildInfo) Option {
+	return func(s *Server) {
+		s.build = &info
+	}
+}
+
+// dependencyHealth runs the dependency checks concurrently, or returns
+// their results from the last run if it is recent.
+func (s *Server) dependencyHealth(ctx context.Context) map[string]DependencyHealth {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	if s.healthResults != nil && time.Since(s.healthCheckedAt) < healthCacheTTL {
+		return s.healthResults
+	}
+
+	results := make(map[string]DependencyHealth, len(s.healthChecks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range s.healthChecks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			result := DependencyHealth{OK: true, CheckedAt: time.Now()}
+			if err := check(checkCtx); err != nil {
+				result = DependencyHealth{Error: err.Error(), CheckedAt: result.CheckedAt}
+			}
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	s.healthResults = results
+	s.healthCheckedAt = time.Now()
+	return results
+}
+
+// pingDocker checks the Docker daemon named by the process environment.
+// Used when no docker check is configured.
+func pingDocker(ctx context.Context) error {
+	client, err := docker.NewClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return client.Ping(ctx)
+}
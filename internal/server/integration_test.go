@@ -414,7 +414,7 @@ func TestIntegration_MetricsAccumulation(t *testing.T) {
 	metrics.AgentSpawned()
 	metrics.AgentSpawned()
 	metrics.AgentCompleted()
-	metrics.AgentFailed()
+	metrics.AgentFailed(metrics.FailureTimeout)
 
 	// Check metrics endpoint
 	resp, err := http.Get(baseURL + "/metrics")
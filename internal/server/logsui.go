@@ -0,0 +1,229 @@
+package server
+
+import (
+	"bufio"
+	_ "embed"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/drewdunne/familiar/internal/logging"
+)
+
+// maxViewLines bounds how much of a log the UI renders.
+const maxViewLines = 20000
+
+// maxRecentLogs bounds how many index entries the UI lists.
+const maxRecentLogs = 100
+
+// LogIndex looks up agent logs and their outcomes.
+type LogIndex interface {
+	Query(q logging.IndexQuery) ([]logging.IndexEntry, error)
+}
+
+// WithLogIndex lists recent agents, filterable by repo, event type, and
+// outcome, in the log browser, and shows each run's outcome.
+func WithLogIndex(idx LogIndex) Option {
+	return func(s *Server) {
+		s.logIndex = idx
+	}
+}
+
+//go:embed logsui.html
+var logsUIHTML string
+
+var logsUITemplate = template.Must(template.New("logs").Parse(logsUIHTML))
+
+// logsPage is the data for one page of the log browser.
+type logsPage struct {
+	Title     string
+	Crumbs    []crumb
+	Base      string // URL of the current page, for relative links
+	Repos     []string
+	MRs       []int
+	Logs      []logging.LogFile
+	Outcomes  map[string]string // agent ID -> outcome, from the index
+	Indexed   bool
+	Filter    logging.IndexQuery
+	Recent    []recentLog
+	Lines     []logLine
+	Query     string
+	Truncated bool
+}
+
+type crumb struct {
+	Name string
+	URL  string
+}
+
+// recentLog is an index entry with a link to its log.
+type recentLog struct {
+	logging.IndexEntry
+	URL string
+}
+
+type logLine struct {
+	Number int
+	Text   string
+}
+
+// handleLogsUI serves a read-only browser for the agent log directory:
+// repos, then MRs, then an MR's agent logs, then one log with search.
+func (s *Server) handleLogsUI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	baseDir := s.cfg.Logging.Dir
+	if baseDir == "" {
+		http.Error(w, "agent logs not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	owner, repo, mrStr, file := r.PathValue("owner"), r.PathValue("repo"), r.PathValue("mr"), r.PathValue("file")
+	for _, segment := range []string{owner, repo, mrStr, file} {
+		if segment != "" && (!filepath.IsLocal(segment) || strings.ContainsAny(segment, `/\`)) {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	page := logsPage{}
+	if owner == "" {
+		repos, err := logging.ListRepos(baseDir)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		page.Repos = repos
+		if s.logIndex != nil {
+			page.Indexed = true
+			page.Filter = logging.IndexQuery{
+				Repo:      r.URL.Query().Get("repo"),
+				EventType: r.URL.Query().Get("event"),
+				Outcome:   r.URL.Query().Get("outcome"),
+				Limit:     maxRecentLogs,
+			}
+			page.Recent, err = recentLogs(s.logIndex, baseDir, page.Filter)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		s.renderLogsUI(w, page)
+		return
+	}
+
+	page.Title = owner + "/" + repo
+	page.Base = "/admin/logs/" + owner + "/" + repo
+	page.Crumbs = []crumb{{Name: owner + "/" + repo, URL: page.Base}}
+	if mrStr == "" {
+		mrs, err := logging.ListMRs(baseDir, owner, repo)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		page.MRs = mrs
+		s.renderLogsUI(w, page)
+		return
+	}
+
+	mr, err := strconv.Atoi(mrStr)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	page.Title += " MR " + mrStr
+	page.Base += "/" + mrStr
+	page.Crumbs = append(page.Crumbs, crumb{Name: "MR " + mrStr, URL: page.Base})
+	if file == "" {
+		logs, err := logging.ListLogs(baseDir, owner, repo, mr)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		page.Logs = logs
+		if s.logIndex != nil {
+			entries, err := s.logIndex.Query(logging.IndexQuery{Repo: owner + "/" + repo, MR: mr})
+			if err != nil {
+				slog.Warn("failed to query log index", "error", err)
+			}
+			page.Outcomes = make(map[string]string, len(entries))
+			for _, e := range entries {
+				page.Outcomes[e.AgentID] = e.Outcome
+			}
+		}
+		s.renderLogsUI(w, page)
+		return
+	}
+
+	rc, err := logging.OpenLog(filepath.Join(baseDir, owner, repo, mrStr, file))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer rc.Close()
+
+	if r.URL.Query().Get("raw") != "" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		io.Copy(w, rc)
+		return
+	}
+
+	page.Title = file
+	page.Crumbs = append(page.Crumbs, crumb{Name: file, URL: page.Base + "/" + file})
+	page.Query = r.URL.Query().Get("q")
+	page.Lines, page.Truncated = readLogLines(rc, page.Query)
+	s.renderLogsUI(w, page)
+}
+
+// recentLogs returns the index entries matching q with links to their logs.
+func recentLogs(idx LogIndex, baseDir string, q logging.IndexQuery) ([]recentLog, error) {
+	entries, err := idx.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	recent := make([]recentLog, 0, len(entries))
+	for _, e := range entries {
+		rel, err := filepath.Rel(baseDir, e.Path)
+		if err != nil || !filepath.IsLocal(rel) {
+			continue
+		}
+		recent = append(recent, recentLog{IndexEntry: e, URL: "/admin/logs/" + filepath.ToSlash(rel)})
+	}
+	return recent, nil
+}
+
+// readLogLines returns the log's lines, or only those containing query
+// (case-insensitively) when it is set, up to maxViewLines.
+func readLogLines(r io.Reader, query string) ([]logLine, bool) {
+	query = strings.ToLower(query)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var lines []logLine
+	for n := 1; scanner.Scan(); n++ {
+		text := scanner.Text()
+		if query != "" && !strings.Contains(strings.ToLower(text), query) {
+			continue
+		}
+		if len(lines) == maxViewLines {
+			return lines, true
+		}
+		lines = append(lines, logLine{Number: n, Text: text})
+	}
+	return lines, false
+}
+
+func (s *Server) renderLogsUI(w http.ResponseWriter, page logsPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := logsUITemplate.Execute(w, page); err != nil {
+		slog.Warn("failed to render logs UI", "error", err)
+	}
+}
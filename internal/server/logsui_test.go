@@ -0,0 +1,193 @@
+package server
+
+import (
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/logging"
+)
+
+// logsUIServer returns a server browsing a log directory holding one plain
+// and one gzipped agent log for owner/repo MR 7.
+func logsUIServer(t *testing.T) *Server {
+	t.Helper()
+	baseDir := t.TempDir()
+	dir := filepath.Join(baseDir, "owner", "repo", "7")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	plain := "cloning repo\nrunning <tests>\nall tests passed\n"
+	if err := os.WriteFile(filepath.Join(dir, "2026-01-15T10-30-00-mr_opened-agent-1.log"), []byte(plain), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dir, "2026-01-01T09-00-00-mention-agent-0.log.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(f)
+	zw.Write([]byte("archived output\n"))
+	zw.Close()
+	f.Close()
+
+	cfg := adminConfig()
+	cfg.Logging.Dir = baseDir
+	return NewWithRouter(cfg, nil)
+}
+
+func TestLogsUI_Pages(t *testing.T) {
+	srv := logsUIServer(t)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		want       []string
+		notWant    []string
+	}{
+		{name: "repos", path: "/admin/logs", wantStatus: http.StatusOK, want: []string{`href="/admin/logs/owner/repo"`}},
+		{name: "MRs", path: "/admin/logs/owner/repo", wantStatus: http.StatusOK, want: []string{"MR 7"}},
+		{
+			name:       "agent logs",
+			path:       "/admin/logs/owner/repo/7",
+			wantStatus: http.StatusOK,
+			want:       []string{"agent-1", "mr_opened", "agent-0", "mention"},
+		},
+		{
+			name:       "log",
+			path:       "/admin/logs/owner/repo/7/2026-01-15T10-30-00-mr_opened-agent-1.log",
+			wantStatus: http.StatusOK,
+			want:       []string{"cloning repo", "running &lt;tests&gt;", "all tests passed"},
+		},
+		{
+			name:       "search",
+			path:       "/admin/logs/owner/repo/7/2026-01-15T10-30-00-mr_opened-agent-1.log?q=TESTS",
+			wantStatus: http.StatusOK,
+			want:       []string{"2 matching lines", "running &lt;tests&gt;", "all tests passed"},
+			notWant:    []string{"cloning repo"},
+		},
+		{
+			name:       "compressed log",
+			path:       "/admin/logs/owner/repo/7/2026-01-01T09-00-00-mention-agent-0.log.gz",
+			wantStatus: http.StatusOK,
+			want:       []string{"archived output"},
+		},
+		{
+			name:       "raw",
+			path:       "/admin/logs/owner/repo/7/2026-01-15T10-30-00-mr_opened-agent-1.log?raw=1",
+			wantStatus: http.StatusOK,
+			want:       []string{"running <tests>\n"},
+		},
+		{name: "missing log", path: "/admin/logs/owner/repo/7/nope.log", wantStatus: http.StatusNotFound},
+		{name: "missing repo", path: "/admin/logs/owner/other", wantStatus: http.StatusNotFound},
+		{name: "escaping the log directory", path: "/admin/logs/owner/repo/7/..%2F..%2F..%2Fsecret", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, adminRequest(http.MethodGet, tt.path, ""))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			body := rec.Body.String()
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("body missing %q:\n%s", want, body)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(body, notWant) {
+					t.Errorf("body should not contain %q:\n%s", notWant, body)
+				}
+			}
+		})
+	}
+}
+
+func TestLogsUI_RequiresToken(t *testing.T) {
+	srv := logsUIServer(t)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/logs", nil))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec.Header().Get("WWW-Authenticate") == "" {
+		t.Error("WWW-Authenticate should be set so browsers prompt for the token")
+	}
+}
+
+type mockLogIndex struct {
+	entries []logging.IndexEntry
+	queries []logging.IndexQuery
+}
+
+func (m *mockLogIndex) Query(q logging.IndexQuery) ([]logging.IndexEntry, error) {
+	m.queries = append(m.queries, q)
+	return m.entries, nil
+}
+
+func TestLogsUI_Index(t *testing.T) {
+	baseDir := t.TempDir()
+	dir := filepath.Join(baseDir, "owner", "repo", "7")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	name := "2026-01-15T10-30-00-mr_opened-agent-1.log"
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("output\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	idx := &mockLogIndex{entries: []logging.IndexEntry{{
+		AgentID:   "agent-1",
+		Repo:      "owner/repo",
+		MR:        7,
+		EventType: "mr_opened",
+		StartedAt: time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC),
+		Outcome:   logging.OutcomeFailed,
+		Reason:    "auth",
+		Path:      filepath.Join(dir, name),
+	}}}
+	cfg := adminConfig()
+	cfg.Logging.Dir = baseDir
+	srv := NewWithRouter(cfg, nil, WithLogIndex(idx))
+
+	t.Run("recent agents", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, adminRequest(http.MethodGet, "/admin/logs?repo=owner/repo&outcome=failed", ""))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		body := rec.Body.String()
+		for _, want := range []string{"Recent agents", `href="/admin/logs/owner/repo/7/` + name + `"`, "failed: auth", `value="failed" selected`} {
+			if !strings.Contains(body, want) {
+				t.Errorf("body missing %q:\n%s", want, body)
+			}
+		}
+		q := idx.queries[len(idx.queries)-1]
+		if q.Repo != "owner/repo" || q.Outcome != logging.OutcomeFailed || q.Limit != maxRecentLogs {
+			t.Errorf("query = %+v, want repo and outcome filters", q)
+		}
+	})
+
+	t.Run("MR outcomes", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, adminRequest(http.MethodGet, "/admin/logs/owner/repo/7", ""))
+
+		if body := rec.Body.String(); !strings.Contains(body, `<td class="failed">failed</td>`) {
+			t.Errorf("body missing the run's outcome:\n%s", body)
+		}
+		q := idx.queries[len(idx.queries)-1]
+		if q.Repo != "owner/repo" || q.MR != 7 {
+			t.Errorf("query = %+v, want owner/repo MR 7", q)
+		}
+	})
+}
@@ -3,55 +3,107 @@ package server
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
-	"os/exec"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/docker"
 	"github.com/drewdunne/familiar/internal/event"
 	"github.com/drewdunne/familiar/internal/metrics"
+	"github.com/drewdunne/familiar/internal/tracing"
 	"github.com/drewdunne/familiar/internal/webhook"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // HealthResponse represents the health check response structure.
 type HealthResponse struct {
 	Status string                 `json:"status"`
+	Build  *BuildInfo             `json:"build,omitempty"`
 	Checks map[string]interface{} `json:"checks"`
 }
 
 // Server is the HTTP server for Familiar.
 type Server struct {
-	cfg             *config.Config
-	mux             *http.ServeMux
-	httpServer      *httpServer
-	httpServerMu    sync.RWMutex  // protects httpServer pointer
-	ready           chan struct{} // closed when server is ready to accept connections
-	dockerAvailable bool
-	eventRouter     *event.Router
+	cfg          *config.Config
+	mux          *http.ServeMux
+	opsMux       *http.ServeMux // nil when ops endpoints share mux
+	httpServer   *httpServer
+	httpServerMu sync.RWMutex  // protects httpServer pointer
+	ready        chan struct{} // closed when server is ready to accept connections
+	eventRouter  *event.Router
+	concurrency  ConcurrencyController
+	images       ImageStatusReporter
+	agentStats   AgentStatsReporter
+	agentLogs    AgentLogStreamer
+	logIndex     LogIndex
+	sessions     SessionController
+	queue        QueueController
+	cleaner      Cleaner
+	reloader     Reloader
+	configSource ConfigSource
+	maintenance  atomic.Bool // webhooks are rejected while set
+	build        *BuildInfo
+
+	shutdownHooks []func(ctx context.Context)
+
+	healthChecks    map[string]DependencyCheck
+	healthMu        sync.Mutex // serializes dependency checks
+	healthResults   map[string]DependencyHealth
+	healthCheckedAt time.Time
+}
+
+// ImageStatusReporter reports whether agent images are available locally.
+type ImageStatusReporter interface {
+	Ready() bool
+	Status() []docker.ImageStatus
+}
+
+// Option configures the server.
+type Option func(*Server)
+
+// WithConcurrency exposes runtime concurrency controls via the admin API.
+func WithConcurrency(c ConcurrencyController) Option {
+	return func(s *Server) {
+		s.concurrency = c
+	}
+}
+
+// WithImages reports agent image pull status in /health and holds off
+// webhook events until the images are ready.
+func WithImages(images ImageStatusReporter) Option {
+	return func(s *Server) {
+		s.images = images
+	}
+}
+
+// WithAgentStats exposes running agents' resource usage via the admin API.
+func WithAgentStats(stats AgentStatsReporter) Option {
+	return func(s *Server) {
+		s.agentStats = stats
+	}
 }
 
 // New creates a new Server with the given config.
 func New(cfg *config.Config) *Server {
-	s := &Server{
-		cfg:             cfg,
-		mux:             http.NewServeMux(),
-		ready:           make(chan struct{}),
-		dockerAvailable: checkDockerAvailable(),
-	}
-	s.routes()
-	return s
+	return NewWithRouter(cfg, nil)
 }
 
 // NewWithRouter creates a new Server with an injected event router.
 // This allows dependency injection for testing and custom event handling.
-func NewWithRouter(cfg *config.Config, router *event.Router) *Server {
+func NewWithRouter(cfg *config.Config, router *event.Router, opts ...Option) *Server {
 	s := &Server{
-		cfg:             cfg,
-		mux:             http.NewServeMux(),
-		ready:           make(chan struct{}),
-		dockerAvailable: checkDockerAvailable(),
-		eventRouter:     router,
+		cfg:          cfg,
+		mux:          http.NewServeMux(),
+		ready:        make(chan struct{}),
+		eventRouter:  router,
+		healthChecks: map[string]DependencyCheck{"docker": pingDocker},
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
 	s.routes()
 	return s
@@ -62,22 +114,66 @@ func (s *Server) Ready() <-chan struct{} {
 	return s.ready
 }
 
-// checkDockerAvailable checks if Docker is available on the system.
-func checkDockerAvailable() bool {
-	cmd := exec.Command("docker", "info")
-	err := cmd.Run()
-	return err == nil
-}
-
 // Handler returns the HTTP handler for the server.
 func (s *Server) Handler() http.Handler {
-	return s.mux
+	return s.logRequests(s.mux)
+}
+
+// OpsHandler returns the handler for /health and /metrics when
+// server.ops_addr moves them off the main listener, or nil when they are
+// served by Handler.
+func (s *Server) OpsHandler() http.Handler {
+	if s.opsMux == nil {
+		return nil
+	}
+	return s.logRequests(s.opsMux)
+}
+
+// logRequests wraps h with access logging if it is enabled.
+func (s *Server) logRequests(h http.Handler) http.Handler {
+	if !s.cfg.Logging.Access.Enabled {
+		return h
+	}
+	return accessLog(h, s.cfg.Logging.Access.Exclude)
 }
 
 // routes sets up the HTTP routes.
 func (s *Server) routes() {
-	s.mux.HandleFunc("/health", s.handleHealth)
-	s.mux.HandleFunc("/metrics", s.handleMetrics)
+	ops := s.mux
+	if s.cfg.Server.OpsAddr != "" {
+		s.opsMux = http.NewServeMux()
+		ops = s.opsMux
+	}
+	ops.HandleFunc("/health", s.requireOps(s.handleHealth))
+	ops.HandleFunc("/metrics", s.requireOps(s.handleMetrics))
+	ops.HandleFunc("/metrics/prometheus", s.requireOps(s.handlePrometheusMetrics))
+
+	// Admin API (only when a token, client certificates, or the admin socket
+	// are configured)
+	if s.cfg.Server.AdminToken != "" || s.cfg.Server.TLS.ClientCAFile != "" || s.cfg.Server.AdminSocket != "" {
+		s.mux.HandleFunc("/admin/concurrency", s.requireAdmin(s.handleConcurrency))
+		s.mux.HandleFunc("/admin/agents/stats", s.requireAdmin(s.handleAgentStats))
+		s.mux.HandleFunc("/admin/sessions", s.requireAdmin(s.handleSessions))
+		s.mux.HandleFunc("/admin/sessions/{id}", s.requireAdmin(s.handleSession))
+		s.mux.HandleFunc("/admin/queue", s.requireAdmin(s.handleQueue))
+		s.mux.HandleFunc("/admin/debounced", s.requireAdmin(s.handleDebounced))
+		s.mux.HandleFunc("/admin/maintenance", s.requireAdmin(s.handleMaintenance))
+		s.mux.HandleFunc("/admin/cleanup", s.requireAdmin(s.handleCleanup))
+		s.mux.HandleFunc("/admin/reload", s.requireAdmin(s.handleReload))
+		s.mux.HandleFunc("/admin/config", s.requireAdmin(s.handleConfig))
+		s.mux.HandleFunc("/admin/status", s.requireAdminPage(s.handleStatus))
+		s.mux.HandleFunc("/agents/{id}/logs", s.requireAdmin(s.handleAgentLogs))
+
+		// Read-only browser for the agent log directory
+		for _, pattern := range []string{
+			"/admin/logs",
+			"/admin/logs/{owner}/{repo}",
+			"/admin/logs/{owner}/{repo}/{mr}",
+			"/admin/logs/{owner}/{repo}/{mr}/{file}",
+		} {
+			s.mux.HandleFunc(pattern, s.requireAdminPage(s.handleLogsUI))
+		}
+	}
 
 	// GitHub webhook
 	if s.cfg.Providers.GitHub.WebhookSecret != "" {
@@ -85,7 +181,7 @@ func (s *Server) routes() {
 			s.cfg.Providers.GitHub.WebhookSecret,
 			s.handleGitHubEvent,
 		)
-		s.mux.Handle("/webhook/github", githubHandler)
+		s.mux.Handle("/webhook/github", tracing.Handler("webhook.github", timeWebhook(s.rejectInMaintenance(s.requireImages(githubHandler)))))
 	}
 
 	// GitLab webhook
@@ -94,24 +190,45 @@ func (s *Server) routes() {
 			s.cfg.Providers.GitLab.WebhookSecret,
 			s.handleGitLabEvent,
 		)
-		s.mux.Handle("/webhook/gitlab", gitlabHandler)
+		s.mux.Handle("/webhook/gitlab", tracing.Handler("webhook.gitlab", timeWebhook(s.rejectInMaintenance(s.requireImages(gitlabHandler)))))
 	}
 }
 
-// handleHealth responds with server health status.
+// handleHealth responds with server health status: dependency checks,
+// agent counts, image pulls, and repo cache usage.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	m := metrics.Get()
 	checks := map[string]interface{}{
-		"docker":        s.dockerAvailable,
-		"active_agents": 0, // Would come from spawner if available
+		"active_agents": m.ActiveAgents,
+		"queued_agents": m.QueuedAgents,
 	}
 
 	status := "ok"
-	if !s.dockerAvailable {
-		status = "degraded"
+	for name, result := range s.dependencyHealth(context.WithoutCancel(r.Context())) {
+		checks[name] = result
+		if !result.OK {
+			status = "degraded"
+		}
+	}
+
+	if s.images != nil {
+		checks["images"] = s.images.Status()
+		if !s.images.Ready() {
+			status = "degraded"
+		}
+	}
+
+	if cache := m.RepoCache; cache != nil {
+		check := repoCacheCheck(cache)
+		checks["repo_cache"] = check
+		if check.Warning != "" {
+			status = "degraded"
+		}
 	}
 
 	health := HealthResponse{
 		Status: status,
+		Build:  s.build,
 		Checks: checks,
 	}
 
@@ -119,16 +236,78 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(health)
 }
 
+// cacheWarnFraction is how full the repo cache (against its limit) or its
+// volume may get before /health reports degraded.
+const cacheWarnFraction = 0.9
+
+// RepoCacheHealth summarizes repo cache disk usage in /health.
+type RepoCacheHealth struct {
+	SizeBytes       int64  `json:"size_bytes"`
+	MaxBytes        int64  `json:"max_bytes,omitempty"`
+	Worktrees       int    `json:"worktrees"`
+	VolumeFreeBytes uint64 `json:"volume_free_bytes"`
+	Warning         string `json:"warning,omitempty"`
+}
+
+// repoCacheCheck reports the repo cache's usage, warning when it nears its
+// size limit or its volume is nearly full.
+func repoCacheCheck(c *metrics.RepoCache) RepoCacheHealth {
+	check := RepoCacheHealth{
+		SizeBytes:       c.SizeBytes,
+		MaxBytes:        c.MaxBytes,
+		Worktrees:       c.Worktrees,
+		VolumeFreeBytes: c.VolumeFreeBytes,
+	}
+	switch {
+	case c.VolumeBytes > 0 && float64(c.VolumeBytes-c.VolumeFreeBytes) >= cacheWarnFraction*float64(c.VolumeBytes):
+		check.Warning = fmt.Sprintf("cache volume is %.0f%% full", 100*float64(c.VolumeBytes-c.VolumeFreeBytes)/float64(c.VolumeBytes))
+	case c.MaxBytes > 0 && float64(c.SizeBytes) >= cacheWarnFraction*float64(c.MaxBytes):
+		check.Warning = fmt.Sprintf("cache is at %.0f%% of its size limit", 100*float64(c.SizeBytes)/float64(c.MaxBytes))
+	}
+	return check
+}
+
+// requireOps rejects requests without the configured ops token as a bearer
+// token or a verified client certificate. Without a token, operational
+// endpoints are open. Allowed cross-origin callers get CORS headers.
+func (s *Server) requireOps(next http.HandlerFunc) http.HandlerFunc {
+	if s.cfg.Server.OpsToken == "" {
+		return s.allowCORS(next)
+	}
+	return s.allowCORS(s.requireAuth(s.cfg.Server.OpsToken, false, next))
+}
+
+// requireImages rejects webhook deliveries with 503 until agent images are
+// ready, so providers can redeliver once spawns won't stall on a pull.
+func (s *Server) requireImages(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.images != nil && !s.images.Ready() {
+			http.Error(w, "agent images not ready", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// timeWebhook records how long each webhook request takes to handle.
+func timeWebhook(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		metrics.WebhookHandled(time.Since(start))
+	})
+}
+
 // handleGitHubEvent processes a GitHub webhook event.
-func (s *Server) handleGitHubEvent(event *webhook.GitHubEvent) error {
-	log.Printf("Received GitHub event: %s, action: %s", event.EventType, event.Action)
+func (s *Server) handleGitHubEvent(ctx context.Context, event *webhook.GitHubEvent) error {
+	slog.Info("received GitHub event", "event", event.EventType, "action", event.Action, "correlation_id", event.CorrelationID)
 	// TODO: Route to event processor in future phases
 	return nil
 }
 
 // handleGitLabEvent processes a GitLab webhook event.
-func (s *Server) handleGitLabEvent(glEvent *webhook.GitLabEvent) error {
-	log.Printf("Received GitLab event: %s, kind: %s", glEvent.EventType, glEvent.ObjectKind)
+func (s *Server) handleGitLabEvent(ctx context.Context, glEvent *webhook.GitLabEvent) error {
+	slog.Info("received GitLab event", "event", glEvent.EventType, "kind", glEvent.ObjectKind, "correlation_id", glEvent.CorrelationID)
 
 	// If no router configured, just log and return (backwards compatible)
 	if s.eventRouter == nil {
@@ -136,25 +315,41 @@ func (s *Server) handleGitLabEvent(glEvent *webhook.GitLabEvent) error {
 	}
 
 	// Normalize the webhook event
+	_, span := tracing.Start(ctx, "event.normalize", attribute.String("gitlab.event", glEvent.EventType))
 	normalizedEvent, err := event.NormalizeGitLabEvent(glEvent)
+	tracing.End(span, err)
 	if err != nil {
-		log.Printf("Failed to normalize GitLab event: %v", err)
+		slog.Warn("failed to normalize GitLab event", "correlation_id", glEvent.CorrelationID, "error", err)
 		return nil // Don't fail the webhook, just log
 	}
 
-	// Route the event
-	if err := s.eventRouter.Route(context.Background(), normalizedEvent); err != nil {
-		log.Printf("Failed to route event: %v", err)
+	// Route the event. Agents outlive the request, so keep its trace but
+	// not its cancellation.
+	if err := s.eventRouter.Route(context.WithoutCancel(ctx), normalizedEvent); err != nil {
+		normalizedEvent.Logger().Error("failed to route event", "error", err)
 		return nil // Don't fail the webhook, just log
 	}
 
 	return nil
 }
 
-// handleMetrics responds with current operational metrics.
+// handleMetrics responds with current operational metrics: lifetime totals,
+// or with ?scope=process only what this process has counted.
 func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
-	m := metrics.Get()
+	m := metrics.Totals()
+	if r.URL.Query().Get("scope") == "process" {
+		m = metrics.Get()
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(m)
 }
+
+// handlePrometheusMetrics serves the metrics in the Prometheus text format
+// for scraping.
+func (s *Server) handlePrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metrics.PrometheusContentType)
+	if err := metrics.WritePrometheus(w, metrics.Totals()); err != nil {
+		slog.Warn("failed to write prometheus metrics", "error", err)
+	}
+}
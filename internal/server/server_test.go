@@ -6,12 +6,15 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/drewdunne/familiar/internal/config"
+	"github.com/drewdunne/familiar/internal/docker"
 	"github.com/drewdunne/familiar/internal/event"
 	"github.com/drewdunne/familiar/internal/intent"
 	"github.com/drewdunne/familiar/internal/metrics"
@@ -76,6 +79,117 @@ func TestServer_HealthEndpoint(t *testing.T) {
 	}
 }
 
+func TestServer_HealthEndpoint_AgentCounts(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+	metrics.TrackAgents(func() int { return 3 }, func() int { return 2 })
+
+	srv := New(&config.Config{})
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	var health HealthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+		t.Fatalf("Failed to parse health response: %v", err)
+	}
+	// JSON numbers decode as float64
+	if got := health.Checks["active_agents"]; got != float64(3) {
+		t.Errorf("active_agents = %v, want 3", got)
+	}
+	if got := health.Checks["queued_agents"]; got != float64(2) {
+		t.Errorf("queued_agents = %v, want 2", got)
+	}
+}
+
+func TestServer_OpsAddr(t *testing.T) {
+	srv := New(&config.Config{Server: config.ServerConfig{OpsAddr: "127.0.0.1:9090"}})
+
+	ops := srv.OpsHandler()
+	if ops == nil {
+		t.Fatal("OpsHandler() = nil, want a handler when ops_addr is set")
+	}
+	for _, path := range []string{"/health", "/metrics", "/metrics/prometheus"} {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("main listener GET %s status = %d, want %d", path, rec.Code, http.StatusNotFound)
+		}
+
+		rec = httptest.NewRecorder()
+		ops.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("ops listener GET %s status = %d, want %d", path, rec.Code, http.StatusOK)
+		}
+	}
+
+	if New(&config.Config{}).OpsHandler() != nil {
+		t.Error("OpsHandler() should be nil without ops_addr")
+	}
+}
+
+func TestServer_OpsToken(t *testing.T) {
+	srv := New(&config.Config{Server: config.ServerConfig{OpsToken: "secret"}})
+
+	tests := []struct {
+		name string
+		auth string
+		want int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong", "Bearer nope", http.StatusUnauthorized},
+		{"valid", "Bearer secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, path := range []string{"/health", "/metrics", "/metrics/prometheus"} {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				if tt.auth != "" {
+					req.Header.Set("Authorization", tt.auth)
+				}
+				rec := httptest.NewRecorder()
+				srv.Handler().ServeHTTP(rec, req)
+				if rec.Code != tt.want {
+					t.Errorf("GET %s status = %d, want %d", path, rec.Code, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_MetricsEndpoint_Scope(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	metrics.AgentSpawned()
+	if err := metrics.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	metrics.Reset()
+	if err := metrics.Restore(path); err != nil {
+		t.Fatal(err)
+	}
+	metrics.AgentSpawned()
+
+	srv := New(&config.Config{})
+	for _, tt := range []struct {
+		url  string
+		want uint64
+	}{
+		{"/metrics", 2},
+		{"/metrics?scope=process", 1},
+	} {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		var m metrics.Metrics
+		if err := json.Unmarshal(rec.Body.Bytes(), &m); err != nil {
+			t.Fatalf("GET %s: %v", tt.url, err)
+		}
+		if m.AgentsSpawned != tt.want {
+			t.Errorf("GET %s AgentsSpawned = %d, want %d", tt.url, m.AgentsSpawned, tt.want)
+		}
+	}
+}
+
 func TestServer_HealthEndpoint_ContentType(t *testing.T) {
 	cfg := &config.Config{
 		Server: config.ServerConfig{
@@ -107,7 +221,7 @@ func TestServer_HealthEndpoint_DegradedStatus(t *testing.T) {
 
 	srv := New(cfg)
 	// Simulate Docker being unavailable
-	srv.dockerAvailable = false
+	srv.healthChecks["docker"] = func(context.Context) error { return errors.New("docker down") }
 
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
 	rec := httptest.NewRecorder()
@@ -127,9 +241,9 @@ func TestServer_HealthEndpoint_DegradedStatus(t *testing.T) {
 		t.Errorf("GET /health status = %q, want 'degraded' when Docker unavailable", health.Status)
 	}
 
-	dockerCheck, ok := health.Checks["docker"].(bool)
-	if !ok || dockerCheck {
-		t.Error("GET /health docker check should be false when Docker unavailable")
+	dockerCheck, ok := health.Checks["docker"].(map[string]interface{})
+	if !ok || dockerCheck["ok"] != false || dockerCheck["error"] != "docker down" {
+		t.Errorf("GET /health docker check = %v, want not ok with the ping error", health.Checks["docker"])
 	}
 }
 
@@ -143,7 +257,7 @@ func TestServer_HealthEndpoint_OkStatus(t *testing.T) {
 
 	srv := New(cfg)
 	// Simulate Docker being available
-	srv.dockerAvailable = true
+	srv.healthChecks["docker"] = dockerUp
 
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
 	rec := httptest.NewRecorder()
@@ -159,9 +273,135 @@ func TestServer_HealthEndpoint_OkStatus(t *testing.T) {
 		t.Errorf("GET /health status = %q, want 'ok' when Docker available", health.Status)
 	}
 
-	dockerCheck, ok := health.Checks["docker"].(bool)
-	if !ok || !dockerCheck {
-		t.Error("GET /health docker check should be true when Docker available")
+	dockerCheck, ok := health.Checks["docker"].(map[string]interface{})
+	if !ok || dockerCheck["ok"] != true {
+		t.Errorf("GET /health docker check = %v, want ok", health.Checks["docker"])
+	}
+}
+
+// dockerUp is a docker health check that always passes.
+func dockerUp(context.Context) error { return nil }
+
+func TestServer_HealthEndpoint_DependencyChecks(t *testing.T) {
+	calls := 0
+	srv := NewWithRouter(&config.Config{}, nil,
+		WithHealthCheck("docker", dockerUp),
+		WithHealthCheck("provider_gitlab", func(context.Context) error {
+			calls++
+			return errors.New("401 Unauthorized")
+		}),
+	)
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		var health HealthResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+			t.Fatalf("Failed to parse health response: %v", err)
+		}
+		if health.Status != "degraded" {
+			t.Errorf("status = %q, want degraded when a dependency check fails", health.Status)
+		}
+		check, ok := health.Checks["provider_gitlab"].(map[string]interface{})
+		if !ok || check["ok"] != false || check["error"] != "401 Unauthorized" {
+			t.Errorf("provider_gitlab check = %v, want not ok with its error", health.Checks["provider_gitlab"])
+		}
+	}
+
+	// Results are reused within the cache TTL
+	if calls != 1 {
+		t.Errorf("check ran %d times, want 1", calls)
+	}
+}
+
+type mockImages struct {
+	ready  bool
+	status []docker.ImageStatus
+}
+
+func (m *mockImages) Ready() bool                  { return m.ready }
+func (m *mockImages) Status() []docker.ImageStatus { return m.status }
+
+func TestServer_HealthEndpoint_ImageStatus(t *testing.T) {
+	cfg := &config.Config{}
+	images := &mockImages{
+		ready:  false,
+		status: []docker.ImageStatus{{Image: "familiar-agent:latest", State: docker.ImagePulling}},
+	}
+
+	srv := NewWithRouter(cfg, nil, WithImages(images))
+	srv.healthChecks["docker"] = dockerUp
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	var health HealthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+		t.Fatalf("Failed to parse health response: %v", err)
+	}
+
+	if health.Status != "degraded" {
+		t.Errorf("GET /health status = %q, want 'degraded' while images are pulling", health.Status)
+	}
+	if !strings.Contains(rec.Body.String(), `"state":"pulling"`) {
+		t.Errorf("GET /health body = %s, want image pull state", rec.Body.String())
+	}
+}
+
+func TestServer_HealthEndpoint_BuildInfo(t *testing.T) {
+	build := BuildInfo{Version: "1.2.3", Commit: "abc1234", BuildDate: "2026-01-02T03:04:05Z", GoVersion: "go1.24.0"}
+	srv := NewWithRouter(&config.Config{}, nil, WithBuildInfo(build))
+	srv.healthChecks["docker"] = dockerUp
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	var health HealthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+		t.Fatalf("Failed to parse health response: %v", err)
+	}
+	if health.Build == nil || *health.Build != build {
+		t.Errorf("GET /health build = %+v, want %+v", health.Build, build)
+	}
+
+	// Left out when the server wasn't told its build
+	srv = NewWithRouter(&config.Config{}, nil)
+	srv.healthChecks["docker"] = dockerUp
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if strings.Contains(rec.Body.String(), `"build"`) {
+		t.Errorf("GET /health body = %s, want no build without WithBuildInfo", rec.Body.String())
+	}
+}
+
+func TestServer_Webhook_WaitsForImages(t *testing.T) {
+	cfg := &config.Config{
+		Providers: config.ProvidersConfig{
+			GitLab: config.GitLabConfig{WebhookSecret: "test-secret"},
+		},
+	}
+	images := &mockImages{ready: false}
+	srv := NewWithRouter(cfg, nil, WithImages(images))
+
+	send := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/webhook/gitlab", strings.NewReader(`{"object_kind":"merge_request"}`))
+		req.Header.Set("X-Gitlab-Token", "test-secret")
+		req.Header.Set("X-Gitlab-Event", "Merge Request Hook")
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := send(); code != http.StatusServiceUnavailable {
+		t.Errorf("webhook before images ready status = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	images.ready = true
+	if code := send(); code != http.StatusOK {
+		t.Errorf("webhook after images ready status = %d, want %d", code, http.StatusOK)
 	}
 }
 
@@ -434,3 +674,81 @@ func TestServer_GitLabWebhook_RoutesToEventHandler(t *testing.T) {
 		t.Errorf("CommentBody = %s, want 'Please fix this bug'", receivedEvent.CommentBody)
 	}
 }
+
+func TestRepoCacheCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		cache       metrics.RepoCache
+		wantWarning string
+	}{
+		{
+			name:  "plenty of room",
+			cache: metrics.RepoCache{SizeBytes: 100, MaxBytes: 1000, VolumeBytes: 10000, VolumeFreeBytes: 5000},
+		},
+		{
+			name:        "near size limit",
+			cache:       metrics.RepoCache{SizeBytes: 950, MaxBytes: 1000, VolumeBytes: 10000, VolumeFreeBytes: 5000},
+			wantWarning: "cache is at 95% of its size limit",
+		},
+		{
+			name:        "volume nearly full",
+			cache:       metrics.RepoCache{SizeBytes: 100, VolumeBytes: 10000, VolumeFreeBytes: 500},
+			wantWarning: "cache volume is 95% full",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := repoCacheCheck(&tt.cache)
+			if got.Warning != tt.wantWarning {
+				t.Errorf("Warning = %q, want %q", got.Warning, tt.wantWarning)
+			}
+			if got.SizeBytes != tt.cache.SizeBytes || got.VolumeFreeBytes != tt.cache.VolumeFreeBytes {
+				t.Errorf("check = %+v, want the sampled sizes", got)
+			}
+		})
+	}
+}
+
+func TestServer_HealthEndpoint_RepoCacheWarningDegrades(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+	metrics.RepoCacheSampled(metrics.RepoCache{SizeBytes: 990, MaxBytes: 1000})
+
+	srv := New(&config.Config{})
+	srv.healthChecks["docker"] = dockerUp
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	var health HealthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+		t.Fatalf("Failed to parse health response: %v", err)
+	}
+	if health.Status != "degraded" {
+		t.Errorf("status = %q, want degraded when the repo cache is nearly full", health.Status)
+	}
+	if _, ok := health.Checks["repo_cache"]; !ok {
+		t.Error("health checks should include repo_cache")
+	}
+}
+
+func TestServer_PrometheusMetricsEndpoint(t *testing.T) {
+	srv := New(&config.Config{})
+
+	metrics.Reset()
+	metrics.AgentSpawned()
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics/prometheus", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics/prometheus status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != metrics.PrometheusContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, metrics.PrometheusContentType)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "familiar_agents_spawned_total 1\n") {
+		t.Errorf("body missing spawned counter:\n%s", body)
+	}
+}
@@ -2,8 +2,9 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
@@ -11,8 +12,49 @@ import (
 	"sync"
 	"syscall"
 	"time"
+
+	"github.com/drewdunne/familiar/internal/config"
 )
 
+// shutdownTimeout bounds how long in-flight requests, and then shutdown
+// hooks, get to finish once a shutdown signal arrives.
+const shutdownTimeout = 30 * time.Second
+
+// WithShutdownHook runs fn once the server has stopped accepting requests,
+// in the order hooks were added, so work started by webhooks can be
+// released before the process exits.
+func WithShutdownHook(fn func(ctx context.Context)) Option {
+	return func(s *Server) {
+		s.shutdownHooks = append(s.shutdownHooks, fn)
+	}
+}
+
+// runShutdownHooks runs the shutdown hooks with their own timeout, so a
+// slow HTTP drain doesn't leave them no time.
+func (s *Server) runShutdownHooks() {
+	if len(s.shutdownHooks) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, hook := range s.shutdownHooks {
+		hook(ctx)
+	}
+}
+
+// NewHTTPServer returns an http.Server for handler with the configured
+// timeouts applied.
+func NewHTTPServer(addr string, handler http.Handler, timeouts config.HTTPTimeoutsConfig) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: time.Duration(timeouts.ReadHeaderSeconds) * time.Second,
+		ReadTimeout:       time.Duration(timeouts.ReadSeconds) * time.Second,
+		WriteTimeout:      time.Duration(timeouts.WriteSeconds) * time.Second,
+		IdleTimeout:       time.Duration(timeouts.IdleSeconds) * time.Second,
+	}
+}
+
 // httpServer holds the HTTP server instance and its listener.
 type httpServer struct {
 	server   *http.Server
@@ -69,16 +111,22 @@ func (s *Server) Addr() string {
 func (s *Server) ListenAndServeWithShutdown() error {
 	addr := fmt.Sprintf("%s:%d", s.cfg.Server.Host, s.cfg.Server.Port)
 
+	tlsCfg, err := TLSConfig(s.cfg.Server.TLS)
+	if err != nil {
+		return err
+	}
+
 	// Create listener first so we know the actual address (important for port 0)
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
 		return fmt.Errorf("failed to listen: %w", err)
 	}
+	if tlsCfg != nil {
+		listener = tls.NewListener(listener, tlsCfg)
+	}
 
 	hs := &httpServer{
-		server: &http.Server{
-			Handler: s.Handler(),
-		},
+		server:   NewHTTPServer(addr, s.Handler(), s.cfg.Server.Timeouts),
 		listener: listener,
 	}
 
@@ -101,7 +149,7 @@ func (s *Server) ListenAndServeWithShutdown() error {
 		serverDone <- nil
 	}()
 
-	log.Printf("Server started on %s", listener.Addr().String())
+	slog.Info("server started", "addr", listener.Addr().String())
 
 	// Signal that server is ready
 	close(s.ready)
@@ -109,28 +157,31 @@ func (s *Server) ListenAndServeWithShutdown() error {
 	// Wait for shutdown signal or programmatic shutdown
 	select {
 	case sig := <-shutdown:
-		log.Printf("Received signal %v, initiating shutdown...", sig)
+		slog.Info("received signal, initiating shutdown", "signal", sig)
 	case err := <-serverDone:
 		// Server stopped on its own (error or shutdown called)
 		if err != nil {
 			return fmt.Errorf("server error: %w", err)
 		}
+		s.runShutdownHooks()
 		return nil
 	}
 
 	// Graceful shutdown with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
 	if err := hs.server.Shutdown(ctx); err != nil {
-		log.Printf("Shutdown error: %v", err)
+		slog.Error("shutdown error", "error", err)
+		s.runShutdownHooks()
 		return err
 	}
 
-	log.Println("Server shutdown complete")
-
 	// Wait for Serve to return
 	<-serverDone
 
+	s.runShutdownHooks()
+	slog.Info("server shutdown complete")
+
 	return nil
 }
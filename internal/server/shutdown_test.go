@@ -2,6 +2,8 @@ package server
 
 import (
 	"context"
+	"io"
+	"net"
 	"net/http"
 	"sync"
 	"syscall"
@@ -287,3 +289,100 @@ func TestServer_ShutdownTimeout(t *testing.T) {
 		t.Errorf("Shutdown() with stuck request error = %v, want %v", err, context.DeadlineExceeded)
 	}
 }
+
+func TestServer_ShutdownHooks(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			Host: "127.0.0.1",
+			Port: 0, // Use any available port
+		},
+	}
+
+	var ran []string
+	hook := func(name string) Option {
+		return WithShutdownHook(func(ctx context.Context) {
+			if ctx.Err() != nil {
+				t.Errorf("hook %s got a done context: %v", name, ctx.Err())
+			}
+			ran = append(ran, name)
+		})
+	}
+	srv := NewWithRouter(cfg, nil, hook("drain"), hook("stop agents"))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServeWithShutdown()
+	}()
+
+	select {
+	case <-srv.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server did not become ready in time")
+	}
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("ListenAndServeWithShutdown() error = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server did not respond to signal in time")
+	}
+	if len(ran) != 2 || ran[0] != "drain" || ran[1] != "stop agents" {
+		t.Errorf("hooks ran = %v, want [drain stop agents]", ran)
+	}
+}
+
+func TestNewHTTPServer_Timeouts(t *testing.T) {
+	hs := NewHTTPServer("127.0.0.1:0", http.NotFoundHandler(), config.HTTPTimeoutsConfig{
+		ReadHeaderSeconds: 10,
+		ReadSeconds:       30,
+		WriteSeconds:      60,
+	})
+
+	if hs.ReadHeaderTimeout != 10*time.Second || hs.ReadTimeout != 30*time.Second || hs.WriteTimeout != time.Minute {
+		t.Errorf("timeouts = %v/%v/%v, want 10s/30s/1m", hs.ReadHeaderTimeout, hs.ReadTimeout, hs.WriteTimeout)
+	}
+	if hs.IdleTimeout != 0 {
+		t.Errorf("IdleTimeout = %v, want 0 (disabled)", hs.IdleTimeout)
+	}
+}
+
+func TestServer_ReadHeaderTimeout(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			Host:     "127.0.0.1",
+			Port:     0, // Use any available port
+			Timeouts: config.HTTPTimeoutsConfig{ReadHeaderSeconds: 1},
+		},
+	}
+	srv := New(cfg)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServeWithShutdown()
+	}()
+	select {
+	case <-srv.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server did not become ready in time")
+	}
+	defer func() {
+		srv.Shutdown(context.Background())
+		<-errCh
+	}()
+
+	// A client that never finishes its headers is disconnected
+	conn, err := net.Dial("tcp", srv.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET /health HTTP/1.1\r\n"))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadAll(conn); err != nil {
+		t.Errorf("connection not closed by the server: %v", err)
+	}
+}
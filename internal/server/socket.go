@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+)
+
+// adminSocketKey marks requests that arrived on the admin socket.
+type adminSocketKey struct{}
+
+// AdminSocketHandler returns the handler for server.admin_socket. Requests
+// on the socket skip authentication, so who may connect is decided by the
+// socket file's permissions.
+func (s *Server) AdminSocketHandler() http.Handler {
+	h := s.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), adminSocketKey{}, true)))
+	})
+}
+
+// fromAdminSocket reports whether the request arrived on the admin socket.
+func fromAdminSocket(r *http.Request) bool {
+	local, _ := r.Context().Value(adminSocketKey{}).(bool)
+	return local
+}
+
+// ListenUnix listens on a Unix socket at path that only the current user
+// can connect to. A socket left behind by an earlier run is replaced, but
+// any other file at path is an error.
+func ListenUnix(path string) (net.Listener, error) {
+	info, err := os.Lstat(path)
+	switch {
+	case err == nil && info.Mode()&os.ModeSocket == 0:
+		return nil, fmt.Errorf("%s exists and is not a socket", path)
+	case err == nil:
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("removing stale socket: %w", err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("restricting socket permissions: %w", err)
+	}
+	return ln, nil
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drewdunne/familiar/internal/agent"
+	"github.com/drewdunne/familiar/internal/config"
+)
+
+func TestAdminSocket(t *testing.T) {
+	cfg := &config.Config{Server: config.ServerConfig{AdminToken: "secret"}}
+	sessions := &mockSessions{sessions: []agent.Session{{ID: "a1"}}, terminated: map[string]string{}}
+	srv := NewWithRouter(cfg, nil, WithSessions(sessions))
+	path := filepath.Join(t.TempDir(), "admin.sock")
+
+	ln, err := ListenUnix(path)
+	if err != nil {
+		t.Fatalf("ListenUnix() error = %v", err)
+	}
+	hs := &http.Server{Handler: srv.AdminSocketHandler()}
+	go hs.Serve(ln)
+	defer hs.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("socket permissions = %o, want 600", perm)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://familiar/admin/sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("socket request without token: status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestAdminSocket_EnablesAdminAPI(t *testing.T) {
+	cfg := &config.Config{Server: config.ServerConfig{AdminSocket: "/tmp/admin.sock"}}
+	srv := NewWithRouter(cfg, nil, WithSessions(&mockSessions{}))
+
+	// Without a token, the admin API is only reachable over the socket
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/sessions", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("TCP request: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	rec = httptest.NewRecorder()
+	srv.AdminSocketHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/sessions", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("socket request: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestListenUnix_StaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "admin.sock")
+	first, err := ListenUnix(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Closing a Unix listener removes its file; leave it behind as a crash would
+	first.(*net.UnixListener).SetUnlinkOnClose(false)
+	first.Close()
+
+	second, err := ListenUnix(path)
+	if err != nil {
+		t.Fatalf("ListenUnix() over a stale socket: error = %v", err)
+	}
+	second.Close()
+}
+
+func TestListenUnix_NotASocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "admin.sock")
+	if err := os.WriteFile(path, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ListenUnix(path); err == nil {
+		t.Fatal("ListenUnix() over a regular file: expected error")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "keep" {
+		t.Error("regular file was replaced")
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	_ "embed"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/logging"
+)
+
+// maxStatusEvents bounds how many recent runs and failures the status page
+// lists.
+const maxStatusEvents = 20
+
+//go:embed status.html
+var statusHTML string
+
+var statusTemplate = template.Must(template.New("status").Parse(statusHTML))
+
+// statusPage is the data for the status dashboard. Sections whose source
+// is not wired are rendered as unavailable.
+type statusPage struct {
+	Maintenance bool
+	Concurrency *ConcurrencyResponse
+	HasSessions bool
+	Sessions    []SessionInfo
+	HasQueue    bool
+	Queue       []QueuedInfo
+	Indexed     bool
+	Recent      []recentLog
+	Failures    []recentLog
+}
+
+// handleStatus serves a read-only dashboard of running agents, the spawn
+// queue, and recent runs, drawn from the same sources as the admin API.
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	now := time.Now()
+	page := statusPage{Maintenance: s.maintenance.Load()}
+	if s.concurrency != nil {
+		maxAgents, queueSize := s.concurrency.Limits()
+		page.Concurrency = &ConcurrencyResponse{
+			MaxAgents: maxAgents,
+			QueueSize: queueSize,
+			Active:    s.concurrency.ActiveCount(),
+			Queued:    s.concurrency.QueueLength(),
+		}
+	}
+	if s.sessions != nil {
+		page.HasSessions = true
+		page.Sessions = sessionInfos(s.sessions, now)
+	}
+	if s.queue != nil {
+		page.HasQueue = true
+		page.Queue = queuedInfos(s.queue, now)
+	}
+	if s.logIndex != nil {
+		page.Indexed = true
+		var err error
+		page.Recent, err = recentLogs(s.logIndex, s.cfg.Logging.Dir, logging.IndexQuery{Limit: maxStatusEvents})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		page.Failures, err = recentLogs(s.logIndex, s.cfg.Logging.Dir, logging.IndexQuery{Outcome: logging.OutcomeFailed, Limit: maxStatusEvents})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := statusTemplate.Execute(w, page); err != nil {
+		slog.Warn("failed to render status page", "error", err)
+	}
+}
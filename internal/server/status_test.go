@@ -0,0 +1,98 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/agent"
+	"github.com/drewdunne/familiar/internal/logging"
+)
+
+func TestStatus_Page(t *testing.T) {
+	baseDir := t.TempDir()
+	dir := filepath.Join(baseDir, "owner", "repo", "7")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	idx := &mockLogIndex{entries: []logging.IndexEntry{{
+		AgentID:   "agent-0",
+		Repo:      "owner/repo",
+		MR:        7,
+		EventType: "mr_opened",
+		StartedAt: time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC),
+		Outcome:   logging.OutcomeFailed,
+		Reason:    "auth",
+		Path:      filepath.Join(dir, "agent-0.log"),
+	}}}
+	sessions := &mockSessions{sessions: []agent.Session{
+		{ID: "agent-1", Repo: "owner/repo", MRNumber: 7, EventType: "mr_comment", Status: "running", StartedAt: time.Now()},
+	}}
+	queue := &mockQueueControl{queued: []agent.QueuedRequest{
+		{Request: agent.SpawnRequest{ID: "req-2", Repo: "owner/other", MRNumber: 3, EventType: "mention"}, QueuedAt: time.Now()},
+	}}
+	cfg := adminConfig()
+	cfg.Logging.Dir = baseDir
+	srv := NewWithRouter(cfg, nil,
+		WithConcurrency(&mockConcurrency{maxAgents: 2, queueSize: 5, active: 1, queued: 1}),
+		WithSessions(sessions),
+		WithQueue(queue),
+		WithLogIndex(idx),
+	)
+	srv.maintenance.Store(true)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, adminRequest(http.MethodGet, "/admin/status", ""))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"Maintenance mode is on",
+		"1 of 2 agents running",
+		"agent-1",
+		"req-2",
+		`href="/admin/logs/owner/repo/7/agent-0.log"`,
+		`<td class="failed">auth</td>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q:\n%s", want, body)
+		}
+	}
+	if q := idx.queries[len(idx.queries)-1]; q.Outcome != logging.OutcomeFailed {
+		t.Errorf("last query = %+v, want failed runs", q)
+	}
+}
+
+func TestStatus_Unavailable(t *testing.T) {
+	srv := NewWithRouter(adminConfig(), nil)
+
+	tests := []struct {
+		name       string
+		req        *http.Request
+		wantStatus int
+		want       string
+	}{
+		{name: "requires token", req: httptest.NewRequest(http.MethodGet, "/admin/status", nil), wantStatus: http.StatusUnauthorized},
+		{name: "wrong method", req: adminRequest(http.MethodPost, "/admin/status", ""), wantStatus: http.StatusMethodNotAllowed},
+		{name: "nothing wired", req: adminRequest(http.MethodGet, "/admin/status", ""), wantStatus: http.StatusOK, want: "Session information is not available"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, tt.req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("body missing %q:\n%s", tt.want, rec.Body.String())
+			}
+		})
+	}
+}
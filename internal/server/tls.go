@@ -0,0 +1,119 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/config"
+)
+
+// TLSConfig returns a TLS config serving the configured certificate, or nil
+// if TLS isn't configured. With reload set, the certificate is reloaded
+// when its files change, so renewals apply without a restart.
+func TLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	if cfg.CertFile == "" && cfg.KeyFile == "" {
+		if cfg.ClientCAFile != "" {
+			return nil, fmt.Errorf("tls client_ca_file needs cert_file and key_file")
+		}
+		return nil, nil
+	}
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, fmt.Errorf("tls needs both cert_file and key_file")
+	}
+
+	c := &certReloader{certFile: cfg.CertFile, keyFile: cfg.KeyFile}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading tls client ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.ClientCAFile)
+		}
+		// Webhook providers don't present certificates, so they stay optional
+		tlsCfg.ClientCAs = pool
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	if cfg.Reload {
+		tlsCfg.GetCertificate = c.getCertificate
+	} else {
+		tlsCfg.Certificates = []tls.Certificate{*c.cert}
+	}
+	return tlsCfg, nil
+}
+
+// certReloader holds a certificate and reloads it when its files'
+// modification times change.
+type certReloader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time // Latest of the two files' at the last load
+}
+
+// load reads the certificate and key from disk.
+func (c *certReloader) load() error {
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading tls certificate: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	c.modTime = modTime
+	return nil
+}
+
+// latestModTime returns the later of the cert and key modification times.
+func (c *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("reading tls file: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// getCertificate serves the current certificate, reloading it first if its
+// files changed. A failed reload keeps serving the previous certificate.
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	modTime, err := c.latestModTime()
+
+	c.mu.Lock()
+	changed := err == nil && !modTime.Equal(c.modTime)
+	c.mu.Unlock()
+
+	if changed {
+		if err := c.load(); err != nil {
+			slog.Warn("failed to reload tls certificate, keeping the previous one", "error", err)
+		} else {
+			slog.Info("reloaded tls certificate", "cert_file", c.certFile)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cert, nil
+}
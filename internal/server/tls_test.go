@@ -0,0 +1,131 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drewdunne/familiar/internal/config"
+)
+
+// writeCert writes a self-signed certificate for cn and its key to dir.
+func writeCert(t *testing.T, dir, cn string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "familiar")
+
+	tests := []struct {
+		name    string
+		cfg     config.TLSConfig
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "disabled", cfg: config.TLSConfig{}, wantNil: true},
+		{name: "cert only", cfg: config.TLSConfig{CertFile: certFile}, wantErr: true},
+		{name: "missing file", cfg: config.TLSConfig{CertFile: certFile, KeyFile: filepath.Join(dir, "nope.pem")}, wantErr: true},
+		{name: "valid", cfg: config.TLSConfig{CertFile: certFile, KeyFile: keyFile}},
+		{name: "client ca", cfg: config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile}},
+		{name: "client ca not pem", cfg: config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile}, wantErr: true},
+		{name: "client ca without cert", cfg: config.TLSConfig{ClientCAFile: certFile}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TLSConfig(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TLSConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.wantNil || tt.wantErr) {
+				t.Errorf("TLSConfig() = %v, want nil %v", got, tt.wantNil || tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTLSConfig_Reload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "old")
+
+	tlsCfg, err := TLSConfig(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, Reload: true})
+	if err != nil {
+		t.Fatalf("TLSConfig() error = %v", err)
+	}
+
+	commonName := func() string {
+		t.Helper()
+		cert, err := tlsCfg.GetCertificate(nil)
+		if err != nil {
+			t.Fatalf("GetCertificate() error = %v", err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leaf.Subject.CommonName
+	}
+	if got := commonName(); got != "old" {
+		t.Fatalf("CommonName = %q, want %q", got, "old")
+	}
+
+	// Renew, making sure the modification time moves
+	writeCert(t, dir, "new")
+	later := time.Now().Add(time.Minute)
+	for _, path := range []string{certFile, keyFile} {
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := commonName(); got != "new" {
+		t.Errorf("CommonName after renewal = %q, want %q", got, "new")
+	}
+
+	// A broken renewal keeps the previous certificate
+	if err := os.WriteFile(keyFile, []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	later = later.Add(time.Minute)
+	if err := os.Chtimes(keyFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := commonName(); got != "new" {
+		t.Errorf("CommonName after bad renewal = %q, want %q", got, "new")
+	}
+}
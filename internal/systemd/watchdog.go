@@ -0,0 +1,76 @@
+package systemd
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Watchdog pings systemd's watchdog while check passes. When check fails
+// or hangs, pings stop and systemd restarts the service once the watchdog
+// interval passes.
+type Watchdog struct {
+	interval time.Duration // systemd's deadline; pings are sent twice as often
+	check    func(ctx context.Context) error
+	notify   func(state string) (bool, error)
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewWatchdog creates a watchdog for systemd's interval that pings only
+// while check returns nil within half the interval.
+func NewWatchdog(interval time.Duration, check func(ctx context.Context) error) *Watchdog {
+	return &Watchdog{
+		interval: interval,
+		check:    check,
+		notify:   Notify,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start begins pinging in the background.
+func (w *Watchdog) Start() {
+	go func() {
+		ticker := time.NewTicker(w.interval / 2)
+		defer ticker.Stop()
+		for {
+			w.ping()
+			select {
+			case <-ticker.C:
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
+
+// ping runs the check and, if it passes in time, pings the watchdog.
+func (w *Watchdog) ping() {
+	ctx, cancel := context.WithTimeout(context.Background(), w.interval/2)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- w.check(ctx) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			slog.Error("liveness check failed, withholding watchdog ping", "error", err)
+			return
+		}
+	case <-ctx.Done():
+		slog.Error("liveness check hung, withholding watchdog ping", "timeout", w.interval/2)
+		return
+	}
+
+	if _, err := w.notify(StateWatchdog); err != nil {
+		slog.Warn("watchdog ping failed", "error", err)
+	}
+}
+
+// Stop stops pinging. It's safe to call more than once.
+func (w *Watchdog) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+}
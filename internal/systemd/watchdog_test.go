@@ -0,0 +1,61 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWatchdog_PingsWhileHealthy(t *testing.T) {
+	conn := listenNotify(t)
+
+	w := NewWatchdog(100*time.Millisecond, func(context.Context) error { return nil })
+	w.Start()
+	defer w.Stop()
+
+	for range 2 {
+		if got := receive(t, conn); got != StateWatchdog {
+			t.Fatalf("notification = %q, want %q", got, StateWatchdog)
+		}
+	}
+}
+
+func TestWatchdog_WithholdsPing(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(ctx context.Context) error
+	}{
+		{name: "check fails", check: func(context.Context) error { return errors.New("wedged") }},
+		{name: "check hangs", check: func(ctx context.Context) error {
+			<-ctx.Done()
+			time.Sleep(time.Second)
+			return nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pings := make(chan string, 10)
+			w := NewWatchdog(40*time.Millisecond, tt.check)
+			w.notify = func(state string) (bool, error) {
+				pings <- state
+				return true, nil
+			}
+			w.Start()
+			time.Sleep(150 * time.Millisecond)
+			w.Stop()
+
+			if len(pings) != 0 {
+				t.Errorf("sent %d watchdog pings, want none", len(pings))
+			}
+		})
+	}
+}
+
+func TestWatchdog_DoubleStop(t *testing.T) {
+	w := NewWatchdog(time.Second, func(context.Context) error { return nil })
+	w.notify = func(string) (bool, error) { return true, nil }
+	w.Start()
+	w.Stop()
+	w.Stop()
+}
@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// record installs a tracer provider that records ended spans.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return rec
+}
+
+func TestHandler_ContinuesPropagatedTrace(t *testing.T) {
+	rec := record(t)
+
+	var inner trace.SpanContext
+	h := Handler("webhook.test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "event.route")
+		inner = span.SpanContext()
+		span.End()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/test", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ended %d spans, want 2", len(spans))
+	}
+	server := spans[1]
+	if got := server.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID = %s, want the propagated one", got)
+	}
+	if got := server.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("parent span = %s, want the caller's span", got)
+	}
+	if inner.TraceID() != server.SpanContext().TraceID() || spans[0].Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Error("span started from the request context should be a child of the server span")
+	}
+	if server.Status().Code != codes.Error {
+		t.Errorf("status = %v, want error for a 500", server.Status().Code)
+	}
+}
+
+func TestEnd_RecordsError(t *testing.T) {
+	rec := record(t)
+
+	_, ok := Start(context.Background(), "ok")
+	End(ok, nil)
+	_, failed := Start(context.Background(), "failed")
+	End(failed, errors.New("clone failed"))
+
+	spans := rec.Ended()
+	if spans[0].Status().Code != codes.Unset {
+		t.Errorf("ok span status = %v, want unset", spans[0].Status().Code)
+	}
+	if spans[1].Status().Code != codes.Error || spans[1].Status().Description != "clone failed" {
+		t.Errorf("failed span status = %+v, want the error", spans[1].Status())
+	}
+	if len(spans[1].Events()) != 1 {
+		t.Errorf("failed span events = %d, want the recorded error", len(spans[1].Events()))
+	}
+}
+
+func TestWithParent(t *testing.T) {
+	rec := record(t)
+
+	parent, span := Start(context.Background(), "event.route")
+	span.End()
+
+	// A worker context that knows nothing of the event's trace
+	worker, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, child := Start(WithParent(worker, parent), "agent.spawn")
+	child.End()
+
+	spans := rec.Ended()
+	if spans[1].Parent().SpanID() != spans[0].SpanContext().SpanID() {
+		t.Error("span on the worker context should be a child of the parent's span")
+	}
+}
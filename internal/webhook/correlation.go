@@ -0,0 +1,19 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// CorrelationHeader is the response header carrying a webhook's correlation
+// ID, so a delivery in the provider's UI can be matched to Familiar's logs.
+const CorrelationHeader = "X-Familiar-Correlation-ID"
+
+// newCorrelationID returns a random ID identifying one webhook delivery.
+func newCorrelationID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
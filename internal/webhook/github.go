@@ -1,12 +1,15 @@
 package webhook
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"io"
 	"net/http"
+
+	"github.com/drewdunne/familiar/internal/metrics"
 	"strings"
 )
 
@@ -16,10 +19,14 @@ type GitHubEvent struct {
 	Action     string `json:"action"`
 	Number     int    `json:"number"`
 	RawPayload []byte
+
+	// CorrelationID identifies this delivery across Familiar's logs.
+	CorrelationID string
 }
 
-// GitHubEventHandler is called when a valid GitHub webhook is received.
-type GitHubEventHandler func(event *GitHubEvent) error
+// GitHubEventHandler is called when a valid GitHub webhook is received, with
+// the request's context.
+type GitHubEventHandler func(ctx context.Context, event *GitHubEvent) error
 
 // GitHubHandler handles GitHub webhook requests.
 type GitHubHandler struct {
@@ -37,6 +44,8 @@ func NewGitHubHandler(secret string, handler GitHubEventHandler) *GitHubHandler
 
 // ServeHTTP implements http.Handler.
 func (h *GitHubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	metrics.WebhookReceived()
+
 	// Read body
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -58,20 +67,24 @@ func (h *GitHubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Parse event
 	event := &GitHubEvent{
-		EventType:  r.Header.Get("X-GitHub-Event"),
-		RawPayload: body,
+		EventType:     r.Header.Get("X-GitHub-Event"),
+		RawPayload:    body,
+		CorrelationID: newCorrelationID(),
 	}
 	if err := json.Unmarshal(body, event); err != nil {
 		http.Error(w, "failed to parse payload", http.StatusBadRequest)
 		return
 	}
 
+	w.Header().Set(CorrelationHeader, event.CorrelationID)
+
 	// Call handler
-	if err := h.handler(event); err != nil {
+	if err := h.handler(r.Context(), event); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	metrics.WebhookProcessed()
 	w.WriteHeader(http.StatusOK)
 }
 
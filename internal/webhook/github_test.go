@@ -1,6 +1,7 @@
 package webhook
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -9,6 +10,8 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/drewdunne/familiar/internal/metrics"
 )
 
 func TestGitHubHandler_ValidSignature(t *testing.T) {
@@ -20,10 +23,12 @@ func TestGitHubHandler_ValidSignature(t *testing.T) {
 	mac.Write([]byte(payload))
 	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
 
-	handler := NewGitHubHandler(secret, func(event *GitHubEvent) error {
+	var correlationID string
+	handler := NewGitHubHandler(secret, func(ctx context.Context, event *GitHubEvent) error {
 		if event.Action != "opened" {
 			t.Errorf("event.Action = %q, want %q", event.Action, "opened")
 		}
+		correlationID = event.CorrelationID
 		return nil
 	})
 
@@ -37,13 +42,19 @@ func TestGitHubHandler_ValidSignature(t *testing.T) {
 	if rec.Code != http.StatusOK {
 		t.Errorf("status = %d, want %d, body = %s", rec.Code, http.StatusOK, rec.Body.String())
 	}
+	if correlationID == "" {
+		t.Error("event.CorrelationID should be set")
+	}
+	if got := rec.Header().Get(CorrelationHeader); got != correlationID {
+		t.Errorf("%s = %q, want %q", CorrelationHeader, got, correlationID)
+	}
 }
 
 func TestGitHubHandler_InvalidSignature(t *testing.T) {
 	secret := "test-secret"
 	payload := `{"action":"opened","number":1}`
 
-	handler := NewGitHubHandler(secret, func(event *GitHubEvent) error {
+	handler := NewGitHubHandler(secret, func(ctx context.Context, event *GitHubEvent) error {
 		t.Error("handler should not be called with invalid signature")
 		return nil
 	})
@@ -64,7 +75,7 @@ func TestGitHubHandler_MissingSignature(t *testing.T) {
 	secret := "test-secret"
 	payload := `{"action":"opened","number":1}`
 
-	handler := NewGitHubHandler(secret, func(event *GitHubEvent) error {
+	handler := NewGitHubHandler(secret, func(ctx context.Context, event *GitHubEvent) error {
 		t.Error("handler should not be called with missing signature")
 		return nil
 	})
@@ -88,7 +99,7 @@ func TestGitHubHandler_InvalidJSON(t *testing.T) {
 	mac.Write([]byte(payload))
 	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
 
-	handler := NewGitHubHandler(secret, func(event *GitHubEvent) error {
+	handler := NewGitHubHandler(secret, func(ctx context.Context, event *GitHubEvent) error {
 		t.Error("handler should not be called with invalid JSON")
 		return nil
 	})
@@ -113,7 +124,7 @@ func TestGitHubHandler_HandlerError(t *testing.T) {
 	mac.Write([]byte(payload))
 	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
 
-	handler := NewGitHubHandler(secret, func(event *GitHubEvent) error {
+	handler := NewGitHubHandler(secret, func(ctx context.Context, event *GitHubEvent) error {
 		return fmt.Errorf("processing error")
 	})
 
@@ -133,7 +144,7 @@ func TestGitHubHandler_WrongSignatureFormat(t *testing.T) {
 	secret := "test-secret"
 	payload := `{"action":"opened"}`
 
-	handler := NewGitHubHandler(secret, func(event *GitHubEvent) error {
+	handler := NewGitHubHandler(secret, func(ctx context.Context, event *GitHubEvent) error {
 		t.Error("handler should not be called with wrong signature format")
 		return nil
 	})
@@ -150,3 +161,30 @@ func TestGitHubHandler_WrongSignatureFormat(t *testing.T) {
 		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
 	}
 }
+
+func TestGitHubHandler_CountsWebhooks(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+
+	secret := "test-secret"
+	payload := `{"action":"opened","number":1}`
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	handler := NewGitHubHandler(secret, func(ctx context.Context, event *GitHubEvent) error {
+		return nil
+	})
+
+	for _, sig := range []string{signature, "sha256=invalid"} {
+		req := httptest.NewRequest(http.MethodPost, "/webhook/github", strings.NewReader(payload))
+		req.Header.Set("X-Hub-Signature-256", sig)
+		req.Header.Set("X-GitHub-Event", "pull_request")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	m := metrics.Get()
+	if m.WebhooksReceived != 2 || m.WebhooksProcessed != 1 {
+		t.Errorf("webhooks received, processed = %d, %d, want 2, 1", m.WebhooksReceived, m.WebhooksProcessed)
+	}
+}
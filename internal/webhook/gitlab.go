@@ -1,10 +1,13 @@
 package webhook
 
 import (
+	"context"
 	"crypto/subtle"
 	"encoding/json"
 	"io"
 	"net/http"
+
+	"github.com/drewdunne/familiar/internal/metrics"
 )
 
 // GitLabEvent represents a parsed GitLab webhook event.
@@ -15,10 +18,14 @@ type GitLabEvent struct {
 		Action string `json:"action"`
 	} `json:"object_attributes"`
 	RawPayload []byte
+
+	// CorrelationID identifies this delivery across Familiar's logs.
+	CorrelationID string
 }
 
-// GitLabEventHandler is called when a valid GitLab webhook is received.
-type GitLabEventHandler func(event *GitLabEvent) error
+// GitLabEventHandler is called when a valid GitLab webhook is received, with
+// the request's context.
+type GitLabEventHandler func(ctx context.Context, event *GitLabEvent) error
 
 // GitLabHandler handles GitLab webhook requests.
 type GitLabHandler struct {
@@ -36,6 +43,8 @@ func NewGitLabHandler(secret string, handler GitLabEventHandler) *GitLabHandler
 
 // ServeHTTP implements http.Handler.
 func (h *GitLabHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	metrics.WebhookReceived()
+
 	// Read body
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -57,19 +66,23 @@ func (h *GitLabHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Parse event
 	event := &GitLabEvent{
-		EventType:  r.Header.Get("X-Gitlab-Event"),
-		RawPayload: body,
+		EventType:     r.Header.Get("X-Gitlab-Event"),
+		RawPayload:    body,
+		CorrelationID: newCorrelationID(),
 	}
 	if err := json.Unmarshal(body, event); err != nil {
 		http.Error(w, "failed to parse payload", http.StatusBadRequest)
 		return
 	}
 
+	w.Header().Set(CorrelationHeader, event.CorrelationID)
+
 	// Call handler
-	if err := h.handler(event); err != nil {
+	if err := h.handler(r.Context(), event); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	metrics.WebhookProcessed()
 	w.WriteHeader(http.StatusOK)
 }
@@ -1,21 +1,26 @@
 package webhook
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/drewdunne/familiar/internal/metrics"
 )
 
 func TestGitLabHandler_ValidToken(t *testing.T) {
 	secret := "test-secret-token"
 	payload := `{"object_kind":"merge_request","object_attributes":{"action":"open"}}`
 
-	handler := NewGitLabHandler(secret, func(event *GitLabEvent) error {
+	var correlationID string
+	handler := NewGitLabHandler(secret, func(ctx context.Context, event *GitLabEvent) error {
 		if event.ObjectKind != "merge_request" {
 			t.Errorf("event.ObjectKind = %q, want %q", event.ObjectKind, "merge_request")
 		}
+		correlationID = event.CorrelationID
 		return nil
 	})
 
@@ -29,13 +34,19 @@ func TestGitLabHandler_ValidToken(t *testing.T) {
 	if rec.Code != http.StatusOK {
 		t.Errorf("status = %d, want %d, body = %s", rec.Code, http.StatusOK, rec.Body.String())
 	}
+	if correlationID == "" {
+		t.Error("event.CorrelationID should be set")
+	}
+	if got := rec.Header().Get(CorrelationHeader); got != correlationID {
+		t.Errorf("%s = %q, want %q", CorrelationHeader, got, correlationID)
+	}
 }
 
 func TestGitLabHandler_InvalidToken(t *testing.T) {
 	secret := "test-secret-token"
 	payload := `{"object_kind":"merge_request"}`
 
-	handler := NewGitLabHandler(secret, func(event *GitLabEvent) error {
+	handler := NewGitLabHandler(secret, func(ctx context.Context, event *GitLabEvent) error {
 		t.Error("handler should not be called with invalid token")
 		return nil
 	})
@@ -56,7 +67,7 @@ func TestGitLabHandler_MissingToken(t *testing.T) {
 	secret := "test-secret-token"
 	payload := `{"object_kind":"merge_request"}`
 
-	handler := NewGitLabHandler(secret, func(event *GitLabEvent) error {
+	handler := NewGitLabHandler(secret, func(ctx context.Context, event *GitLabEvent) error {
 		t.Error("handler should not be called with missing token")
 		return nil
 	})
@@ -76,7 +87,7 @@ func TestGitLabHandler_InvalidJSON(t *testing.T) {
 	secret := "test-secret-token"
 	payload := `{invalid json`
 
-	handler := NewGitLabHandler(secret, func(event *GitLabEvent) error {
+	handler := NewGitLabHandler(secret, func(ctx context.Context, event *GitLabEvent) error {
 		t.Error("handler should not be called with invalid JSON")
 		return nil
 	})
@@ -97,7 +108,7 @@ func TestGitLabHandler_HandlerError(t *testing.T) {
 	secret := "test-secret-token"
 	payload := `{"object_kind":"merge_request"}`
 
-	handler := NewGitLabHandler(secret, func(event *GitLabEvent) error {
+	handler := NewGitLabHandler(secret, func(ctx context.Context, event *GitLabEvent) error {
 		return fmt.Errorf("processing error")
 	})
 
@@ -112,3 +123,27 @@ func TestGitLabHandler_HandlerError(t *testing.T) {
 		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
 	}
 }
+
+func TestGitLabHandler_CountsWebhooks(t *testing.T) {
+	metrics.Reset()
+	defer metrics.Reset()
+
+	secret := "test-secret-token"
+	payload := `{"object_kind":"merge_request","object_attributes":{"action":"open"}}`
+
+	handler := NewGitLabHandler(secret, func(ctx context.Context, event *GitLabEvent) error {
+		return nil
+	})
+
+	for _, token := range []string{secret, "wrong-token"} {
+		req := httptest.NewRequest(http.MethodPost, "/webhook/gitlab", strings.NewReader(payload))
+		req.Header.Set("X-Gitlab-Token", token)
+		req.Header.Set("X-Gitlab-Event", "Merge Request Hook")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	m := metrics.Get()
+	if m.WebhooksReceived != 2 || m.WebhooksProcessed != 1 {
+		t.Errorf("webhooks received, processed = %d, %d, want 2, 1", m.WebhooksReceived, m.WebhooksProcessed)
+	}
+}
@@ -0,0 +1,234 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Sample event kinds, for checking a deployment's webhook wiring.
+const (
+	SampleMROpened    = "mr_opened" // A merge request was opened
+	SampleNote        = "note"      // A comment on a merge request
+	SampleMention     = "mention"   // A comment mentioning @familiar
+	SampleLineComment = "line"      // A comment on a line of the diff
+)
+
+// SampleKinds lists the sample event kinds.
+var SampleKinds = []string{SampleMROpened, SampleNote, SampleMention, SampleLineComment}
+
+// SampleOptions customizes a sample delivery. Empty fields get defaults.
+type SampleOptions struct {
+	Repo         string // owner/name
+	MR           int
+	Title        string
+	Comment      string
+	File         string // File and Line place a line comment
+	Line         int
+	Author       string
+	SourceBranch string
+	TargetBranch string
+	BaseURL      string // GitLab instance, for the clone URL
+}
+
+// withDefaults fills in the options left empty for kind.
+func (o SampleOptions) withDefaults(kind string) SampleOptions {
+	if o.Repo == "" {
+		o.Repo = "familiar-test/sample"
+	}
+	if o.MR == 0 {
+		o.MR = 1
+	}
+	if o.Title == "" {
+		o.Title = "Familiar test webhook"
+	}
+	if o.Author == "" {
+		o.Author = "familiar-tester"
+	}
+	if o.SourceBranch == "" {
+		o.SourceBranch = "feature"
+	}
+	if o.TargetBranch == "" {
+		o.TargetBranch = "main"
+	}
+	if o.BaseURL == "" {
+		o.BaseURL = "https://gitlab.com"
+	}
+	switch kind {
+	case SampleNote:
+		if o.Comment == "" {
+			o.Comment = "Looks good, thanks."
+		}
+	case SampleMention, SampleLineComment:
+		if o.Comment == "" {
+			o.Comment = "please take a look"
+		}
+		if !strings.Contains(strings.ToLower(o.Comment), "@familiar") {
+			o.Comment = "@familiar " + o.Comment
+		}
+	}
+	if kind == SampleLineComment {
+		if o.File == "" {
+			o.File = "README.md"
+		}
+		if o.Line == 0 {
+			o.Line = 1
+		}
+	}
+	return o
+}
+
+// SampleRequest builds a delivery of a sample event of kind as provider
+// would send it to url, signed with secret.
+func SampleRequest(provider, kind, secret, url string, opts SampleOptions) (*http.Request, error) {
+	if !slices.Contains(SampleKinds, kind) {
+		return nil, fmt.Errorf("unknown event kind %q (want %s)", kind, strings.Join(SampleKinds, ", "))
+	}
+	opts = opts.withDefaults(kind)
+
+	var (
+		eventType string
+		payload   map[string]any
+		err       error
+	)
+	switch provider {
+	case "github":
+		eventType, payload, err = githubSample(kind, opts)
+	case "gitlab":
+		eventType, payload = gitlabSample(kind, opts)
+	default:
+		return nil, fmt.Errorf("unknown provider %q (want github, gitlab)", provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if provider == "github" {
+		req.Header.Set("X-GitHub-Event", eventType)
+		req.Header.Set("X-Hub-Signature-256", GitHubSignature(secret, body))
+	} else {
+		req.Header.Set("X-Gitlab-Event", eventType)
+		req.Header.Set("X-Gitlab-Token", secret)
+	}
+	return req, nil
+}
+
+// GitHubSignature returns the X-Hub-Signature-256 header GitHub sends with
+// payload when signing with secret.
+func GitHubSignature(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func githubSample(kind string, o SampleOptions) (string, map[string]any, error) {
+	repository := map[string]any{
+		"full_name": o.Repo,
+		"clone_url": "https://github.com/" + o.Repo + ".git",
+	}
+	sender := map[string]any{"login": o.Author}
+
+	switch kind {
+	case SampleMROpened:
+		return "pull_request", map[string]any{
+			"action": "opened",
+			"number": o.MR,
+			"pull_request": map[string]any{
+				"number": o.MR,
+				"title":  o.Title,
+				"body":   "Sent by familiar test-webhook.",
+				"head":   map[string]any{"ref": o.SourceBranch, "repo": map[string]any{"full_name": o.Repo}},
+				"base":   map[string]any{"ref": o.TargetBranch, "repo": map[string]any{"full_name": o.Repo}},
+				"user":   sender,
+			},
+			"repository": repository,
+			"sender":     sender,
+		}, nil
+	case SampleLineComment:
+		// Review comments arrive as pull_request_review_comment, which
+		// Familiar doesn't handle
+		return "", nil, fmt.Errorf("line comments are only supported for gitlab")
+	default:
+		return "issue_comment", map[string]any{
+			"action": "created",
+			"issue": map[string]any{
+				"number":       o.MR,
+				"title":        o.Title,
+				"pull_request": map[string]any{"url": fmt.Sprintf("https://api.github.com/repos/%s/pulls/%d", o.Repo, o.MR)},
+			},
+			"comment": map[string]any{
+				"id":   1,
+				"body": o.Comment,
+				"user": sender,
+			},
+			"repository": repository,
+			"sender":     sender,
+		}, nil
+	}
+}
+
+func gitlabSample(kind string, o SampleOptions) (string, map[string]any) {
+	project := map[string]any{
+		"id":                  1,
+		"path_with_namespace": o.Repo,
+		"git_http_url":        strings.TrimSuffix(o.BaseURL, "/") + "/" + o.Repo + ".git",
+	}
+	user := map[string]any{"username": o.Author}
+
+	if kind == SampleMROpened {
+		return "Merge Request Hook", map[string]any{
+			"object_kind": "merge_request",
+			"object_attributes": map[string]any{
+				"id":                1,
+				"iid":               o.MR,
+				"title":             o.Title,
+				"description":       "Sent by familiar test-webhook.",
+				"source_branch":     o.SourceBranch,
+				"target_branch":     o.TargetBranch,
+				"source_project_id": 1,
+				"target_project_id": 1,
+				"action":            "open",
+			},
+			"project": project,
+			"user":    user,
+		}
+	}
+
+	attrs := map[string]any{
+		"id":            1,
+		"note":          o.Comment,
+		"noteable_type": "MergeRequest",
+	}
+	if kind == SampleLineComment {
+		attrs["position"] = map[string]any{"new_path": o.File, "new_line": o.Line}
+	}
+	return "Note Hook", map[string]any{
+		"object_kind":       "note",
+		"object_attributes": attrs,
+		"merge_request": map[string]any{
+			"iid":               o.MR,
+			"title":             o.Title,
+			"source_branch":     o.SourceBranch,
+			"target_branch":     o.TargetBranch,
+			"source_project_id": 1,
+			"target_project_id": 1,
+		},
+		"project": project,
+		"user":    user,
+	}
+}
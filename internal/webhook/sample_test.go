@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSampleRequest_Accepted(t *testing.T) {
+	tests := []struct {
+		provider  string
+		kind      string
+		eventType string
+	}{
+		{"github", SampleMROpened, "pull_request"},
+		{"github", SampleMention, "issue_comment"},
+		{"gitlab", SampleMROpened, "Merge Request Hook"},
+		{"gitlab", SampleLineComment, "Note Hook"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider+"/"+tt.kind, func(t *testing.T) {
+			var got string
+			var handler http.Handler
+			if tt.provider == "github" {
+				handler = NewGitHubHandler("secret", func(ctx context.Context, event *GitHubEvent) error {
+					got = event.EventType
+					return nil
+				})
+			} else {
+				handler = NewGitLabHandler("secret", func(ctx context.Context, event *GitLabEvent) error {
+					got = event.EventType
+					return nil
+				})
+			}
+
+			req, err := SampleRequest(tt.provider, tt.kind, "secret", "http://familiar/webhook/"+tt.provider, SampleOptions{})
+			if err != nil {
+				t.Fatalf("SampleRequest() error = %v", err)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d, body = %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+			if got != tt.eventType {
+				t.Errorf("EventType = %q, want %q", got, tt.eventType)
+			}
+		})
+	}
+}
+
+func TestSampleRequest_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		kind     string
+	}{
+		{"unknown provider", "bitbucket", SampleMROpened},
+		{"unknown kind", "gitlab", "push"},
+		{"github line comment", "github", SampleLineComment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := SampleRequest(tt.provider, tt.kind, "secret", "http://familiar", SampleOptions{}); err == nil {
+				t.Error("SampleRequest() expected error")
+			}
+		})
+	}
+}